@@ -46,7 +46,7 @@ type client struct {
 
 var (
 	// make it possible to mock connection ID generation in the tests
-	generateConnectionID           = protocol.GenerateConnectionID
+	generateConnectionID           = func(g ConnectionIDGenerator) (protocol.ConnectionID, error) { return g.GenerateConnectionID() }
 	generateConnectionIDForInitial = protocol.GenerateConnectionIDForInitial
 )
 
@@ -97,6 +97,9 @@ func dialAddrContext(
 	config *Config,
 	use0RTT bool,
 ) (quicSession, error) {
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
 	udpAddr, err := net.ResolveUDPAddr("udp", addr)
 	if err != nil {
 		return nil, err
@@ -105,12 +108,20 @@ func dialAddrContext(
 	if err != nil {
 		return nil, err
 	}
+	if config != nil {
+		if err := setDSCP(udpConn, uint8(config.DSCP)); err != nil {
+			udpConn.Close()
+			return nil, err
+		}
+	}
 	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, use0RTT, true)
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
 // The same PacketConn can be used for multiple calls to Dial and Listen,
 // QUIC connection IDs are used for demultiplexing the different connections.
+// PacketConns are identified by their local address, so PacketConns that don't use UDP
+// must return a unique address from LocalAddr. NewDatagramConn takes care of this.
 // The host parameter is used for SNI.
 // The tls.Config must define an application protocol (using NextProtos).
 func Dial(
@@ -151,6 +162,20 @@ func DialContext(
 	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, false, false)
 }
 
+// DialEarlyContext establishes a new 0-RTT QUIC connection to a server using a net.PacketConn using the provided context.
+// See DialEarly for details.
+// Closing the session doesn't close the net.PacketConn.
+func DialEarlyContext(
+	ctx context.Context,
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+) (EarlySession, error) {
+	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, true, false)
+}
+
 func dialContext(
 	ctx context.Context,
 	pconn net.PacketConn,
@@ -168,7 +193,7 @@ func dialContext(
 		return nil, err
 	}
 	config = populateClientConfig(config, createdPacketConn)
-	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
+	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey, config.StatelessResetKeyFunc, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
@@ -221,18 +246,22 @@ func newClient(
 		}
 	}
 
-	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
+	srcConnID, err := generateConnectionID(config.ConnectionIDGenerator)
 	if err != nil {
 		return nil, err
 	}
-	destConnID, err := generateConnectionIDForInitial()
+	destConnID, err := generateConnectionIDForInitial(config.Rand)
 	if err != nil {
 		return nil, err
 	}
+	var dscp uint8
+	if createdPacketConn {
+		dscp = uint8(config.DSCP)
+	}
 	c := &client{
 		srcConnID:         srcConnID,
 		destConnID:        destConnID,
-		conn:              newSendConn(pconn, remoteAddr),
+		conn:              newSendConn(pconn, remoteAddr, dscp),
 		createdPacketConn: createdPacketConn,
 		use0RTT:           use0RTT,
 		tlsConf:           tlsConf,
@@ -0,0 +1,34 @@
+package quic
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+)
+
+// SerializeSessionState serializes a session state obtained from the tls.Config.ClientSessionCache.
+// Besides the TLS session ticket, the serialized state contains the QUIC-specific state
+// required for 0-RTT (e.g. the server's transport parameters).
+// This allows storing session states across process restarts, by using a ClientSessionCache
+// that persists the serialized state.
+// Address validation tokens are not part of the session state, they are stored in the Config.TokenStore.
+// The serialized state contains secret key material and must be stored securely.
+func SerializeSessionState(state *tls.ClientSessionState) ([]byte, error) {
+	if state == nil {
+		return nil, errors.New("quic: session state is nil")
+	}
+	return qtls.MarshalClientSessionState(state), nil
+}
+
+// DeserializeSessionState parses a session state serialized by SerializeSessionState.
+// The returned session state can be returned from the tls.Config.ClientSessionCache
+// to resume the session, using 0-RTT if the server supports it.
+func DeserializeSessionState(data []byte) (*tls.ClientSessionState, error) {
+	state, err := qtls.UnmarshalClientSessionState(data)
+	if err != nil {
+		return nil, fmt.Errorf("quic: failed to deserialize session state: %s", err)
+	}
+	return state, nil
+}
@@ -1,9 +1,13 @@
 package quic
 
 import (
+	"crypto/rand"
 	"errors"
+	"io"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // Clone clones a Config
@@ -22,6 +26,53 @@ func validateConfig(config *Config) error {
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.InitialMaxIncomingStreams < 0 || config.InitialMaxIncomingStreams > 1<<60 {
+		return errors.New("invalid value for Config.InitialMaxIncomingStreams")
+	}
+	if config.InitialMaxIncomingUniStreams < 0 || config.InitialMaxIncomingUniStreams > 1<<60 {
+		return errors.New("invalid value for Config.InitialMaxIncomingUniStreams")
+	}
+	if config.ActiveConnectionIDLimit == 1 {
+		return errors.New("invalid value for Config.ActiveConnectionIDLimit")
+	}
+	if config.MaxAckRanges < 0 {
+		return errors.New("invalid value for Config.MaxAckRanges")
+	}
+	if config.MaxProbeTimeout < 0 {
+		return errors.New("invalid value for Config.MaxProbeTimeout")
+	}
+	if config.AmplificationFactor < 0 {
+		return errors.New("invalid value for Config.AmplificationFactor")
+	}
+	if config.AmplificationFactor > protocol.DefaultAmplificationFactor && !config.AllowLargeAmplificationFactor {
+		return errors.New("Config.AmplificationFactor larger than 3 requires Config.AllowLargeAmplificationFactor")
+	}
+	if config.DSCP < 0 || config.DSCP > 63 {
+		return errors.New("invalid value for Config.DSCP")
+	}
+	if pa := config.PreferredAddress; pa != nil {
+		if pa.IPv4 == nil && pa.IPv6 == nil {
+			return errors.New("invalid value for Config.PreferredAddress: no address set")
+		}
+		if pa.IPv4 != nil && pa.IPv4.IP.To4() == nil {
+			return errors.New("invalid value for Config.PreferredAddress: not an IPv4 address")
+		}
+		if pa.IPv6 != nil && (pa.IPv6.IP.To16() == nil || pa.IPv6.IP.To4() != nil) {
+			return errors.New("invalid value for Config.PreferredAddress: not an IPv6 address")
+		}
+	}
+	if config.ConnectionIDLength < 0 {
+		return errors.New("invalid value for Config.ConnectionIDLength")
+	}
+	if config.ConnectionIDGenerator != nil {
+		if l := config.ConnectionIDGenerator.ConnectionIDLen(); l < 0 || l > protocol.MaxConnIDLen {
+			return errors.New("invalid connection ID length for Config.ConnectionIDGenerator")
+		}
+	}
+	if config.ConnectionIDGenerator != nil && config.ConnectionIDLength != 0 &&
+		config.ConnectionIDLength != config.ConnectionIDGenerator.ConnectionIDLen() {
+		return errors.New("Config.ConnectionIDLength doesn't match the length of the Config.ConnectionIDGenerator")
+	}
 	return nil
 }
 
@@ -29,11 +80,12 @@ func validateConfig(config *Config) error {
 // it may be called with nil
 func populateServerConfig(config *Config) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 {
+	if config.ConnectionIDLength == 0 && config.ConnectionIDGenerator == nil {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
+	populateConnectionIDGenerator(config)
 	if config.AcceptToken == nil {
-		config.AcceptToken = defaultAcceptToken
+		config.AcceptToken = defaultAcceptToken(config.clock)
 	}
 	return config
 }
@@ -42,12 +94,21 @@ func populateServerConfig(config *Config) *Config {
 // it may be called with nil
 func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	config = populateConfig(config)
-	if config.ConnectionIDLength == 0 && !createdPacketConn {
+	if config.ConnectionIDLength == 0 && config.ConnectionIDGenerator == nil && !createdPacketConn {
 		config.ConnectionIDLength = protocol.DefaultConnectionIDLength
 	}
+	populateConnectionIDGenerator(config)
 	return config
 }
 
+func populateConnectionIDGenerator(config *Config) {
+	if config.ConnectionIDGenerator == nil {
+		config.ConnectionIDGenerator = &randomConnIDGenerator{connIDLen: config.ConnectionIDLength, rand: config.Rand}
+		return
+	}
+	config.ConnectionIDLength = config.ConnectionIDGenerator.ConnectionIDLen()
+}
+
 func populateConfig(config *Config) *Config {
 	if config == nil {
 		config = &Config{}
@@ -64,6 +125,10 @@ func populateConfig(config *Config) *Config {
 	if config.MaxIdleTimeout != 0 {
 		idleTimeout = config.MaxIdleTimeout
 	}
+	initialRTT := config.InitialRTT
+	if initialRTT != 0 && initialRTT < protocol.MinInitialRTT {
+		initialRTT = protocol.MinInitialRTT
+	}
 	maxReceiveStreamFlowControlWindow := config.MaxReceiveStreamFlowControlWindow
 	if maxReceiveStreamFlowControlWindow == 0 {
 		maxReceiveStreamFlowControlWindow = protocol.DefaultMaxReceiveStreamFlowControlWindow
@@ -78,27 +143,126 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingStreams < 0 {
 		maxIncomingStreams = 0
 	}
+	activeConnectionIDLimit := config.ActiveConnectionIDLimit
+	if activeConnectionIDLimit == 0 {
+		activeConnectionIDLimit = protocol.MaxActiveConnectionIDs
+	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges == 0 || maxAckRanges > protocol.MaxNumAckRanges {
+		maxAckRanges = protocol.MaxNumAckRanges
+	}
+	amplificationFactor := config.AmplificationFactor
+	if amplificationFactor == 0 {
+		amplificationFactor = protocol.DefaultAmplificationFactor
+	}
+	maxUDPPayloadSize := config.MaxUDPPayloadSize
+	if maxUDPPayloadSize == 0 {
+		maxUDPPayloadSize = protocol.MaxReceivePacketSize
+	} else if maxUDPPayloadSize < protocol.MinInitialPacketSize {
+		maxUDPPayloadSize = protocol.MinInitialPacketSize
+	} else if maxUDPPayloadSize > protocol.MaxUDPPayloadSize {
+		maxUDPPayloadSize = protocol.MaxUDPPayloadSize
+	}
+	connIDLen := config.ConnectionIDLength
+	if connIDLen > protocol.MaxConnIDLen {
+		connIDLen = protocol.MaxConnIDLen
+	}
 	maxIncomingUniStreams := config.MaxIncomingUniStreams
 	if maxIncomingUniStreams == 0 {
 		maxIncomingUniStreams = protocol.DefaultMaxIncomingUniStreams
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	initialMaxIncomingStreams := config.InitialMaxIncomingStreams
+	if initialMaxIncomingStreams == 0 || initialMaxIncomingStreams > maxIncomingStreams {
+		initialMaxIncomingStreams = maxIncomingStreams
+	}
+	initialMaxIncomingUniStreams := config.InitialMaxIncomingUniStreams
+	if initialMaxIncomingUniStreams == 0 || initialMaxIncomingUniStreams > maxIncomingUniStreams {
+		initialMaxIncomingUniStreams = maxIncomingUniStreams
+	}
+
+	rnd := config.Rand
+	if rnd == nil {
+		rnd = rand.Reader
+	} else if _, ok := rnd.(*lockedReader); !ok {
+		// The reader is used by all connections (and their Go routines) using this config.
+		rnd = &lockedReader{r: rnd}
+	}
+	clock := config.clock
+	if clock == nil {
+		clock = utils.DefaultClock{}
+	}
 
 	return &Config{
-		Versions:                              versions,
-		HandshakeTimeout:                      handshakeTimeout,
-		MaxIdleTimeout:                        idleTimeout,
-		AcceptToken:                           config.AcceptToken,
-		KeepAlive:                             config.KeepAlive,
-		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
-		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
-		MaxIncomingStreams:                    maxIncomingStreams,
-		MaxIncomingUniStreams:                 maxIncomingUniStreams,
-		ConnectionIDLength:                    config.ConnectionIDLength,
-		StatelessResetKey:                     config.StatelessResetKey,
-		TokenStore:                            config.TokenStore,
-		QuicTracer:                            config.QuicTracer,
-		Tracer:                                config.Tracer,
+		Versions:                               versions,
+		DisableVersionNegotiation:              config.DisableVersionNegotiation,
+		HandshakeTimeout:                       handshakeTimeout,
+		HandshakeIdleTimeout:                   config.HandshakeIdleTimeout,
+		MaxIdleTimeout:                         idleTimeout,
+		InitialRTT:                             initialRTT,
+		AcceptToken:                            config.AcceptToken,
+		AddressTokenGenerator:                  config.AddressTokenGenerator,
+		MaxIncomingHandshakesPerSecond:         config.MaxIncomingHandshakesPerSecond,
+		MaxIncomingConnections:                 config.MaxIncomingConnections,
+		Allow0RTT:                              config.Allow0RTT,
+		KeepAlive:                              config.KeepAlive,
+		GREASEQUICBit:                          config.GREASEQUICBit,
+		DisablePacketCoalescing:                config.DisablePacketCoalescing,
+		MaxUDPPayloadSize:                      maxUDPPayloadSize,
+		AllowConnectionMigration:               config.AllowConnectionMigration,
+		DisableActiveMigration:                 config.DisableActiveMigration,
+		RequireAddressValidationOnRebind:       config.RequireAddressValidationOnRebind,
+		PreferredAddress:                       config.PreferredAddress,
+		DisablePathMigrationToPreferredAddress: config.DisablePathMigrationToPreferredAddress,
+		PacketInterceptor:                      config.PacketInterceptor,
+		IncomingPacketInterceptor:              config.IncomingPacketInterceptor,
+		DisableHeaderProtection:                config.DisableHeaderProtection,
+		EnableECN:                              config.EnableECN,
+		DSCP:                                   config.DSCP,
+		EnableACKFrequency:                     config.EnableACKFrequency,
+		MaxSendRate:                            config.MaxSendRate,
+		MaxAckRanges:                           maxAckRanges,
+		MaxProbeTimeout:                        config.MaxProbeTimeout,
+		AmplificationFactor:                    amplificationFactor,
+		AllowLargeAmplificationFactor:          config.AllowLargeAmplificationFactor,
+		CongestionControlFactory:               config.CongestionControlFactory,
+		OnIncomingStream:                       config.OnIncomingStream,
+		StreamWriteCoalesceDelay:               config.StreamWriteCoalesceDelay,
+		MaxStreamIdleTimeout:                   config.MaxStreamIdleTimeout,
+		StreamIdleTimeoutErrorCode:             config.StreamIdleTimeoutErrorCode,
+		OnStreamFlowControlUpdate:              config.OnStreamFlowControlUpdate,
+		MaxReceiveStreamFlowControlWindow:      maxReceiveStreamFlowControlWindow,
+		StreamReceiveWindowFunc:                config.StreamReceiveWindowFunc,
+		MaxReceiveConnectionFlowControlWindow:  maxReceiveConnectionFlowControlWindow,
+		DisableFlowControlAutoTuning:           config.DisableFlowControlAutoTuning,
+		MaxConnectionReceiveBuffer:             config.MaxConnectionReceiveBuffer,
+		MaxIncomingStreams:                     maxIncomingStreams,
+		MaxIncomingUniStreams:                  maxIncomingUniStreams,
+		InitialMaxIncomingStreams:              initialMaxIncomingStreams,
+		InitialMaxIncomingUniStreams:           initialMaxIncomingUniStreams,
+		OnStreamsNeeded:                        config.OnStreamsNeeded,
+		ConnectionIDLength:                     connIDLen,
+		ConnectionIDGenerator:                  config.ConnectionIDGenerator,
+		Rand:                                   rnd,
+		StatelessResetKey:                      config.StatelessResetKey,
+		StatelessResetKeyFunc:                  config.StatelessResetKeyFunc,
+		ActiveConnectionIDLimit:                activeConnectionIDLimit,
+		TokenStore:                             config.TokenStore,
+		QuicTracer:                             config.QuicTracer,
+		Tracer:                                 config.Tracer,
+		clock:                                  clock,
 	}
 }
+
+// A lockedReader serializes the reads from an io.Reader that might not be safe for concurrent use.
+type lockedReader struct {
+	mutex sync.Mutex
+	r     io.Reader
+}
+
+func (r *lockedReader) Read(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.r.Read(p)
+}
@@ -1,13 +1,19 @@
 package quic
 
 import (
+	"bytes"
+	"crypto/rand"
 	"fmt"
+	"io"
 	"net"
 	"reflect"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/quictrace"
 
 	. "github.com/onsi/ginkgo"
@@ -31,6 +37,78 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on invalid values for InitialMaxIncomingStreams", func() {
+			Expect(validateConfig(&Config{InitialMaxIncomingStreams: -1})).To(MatchError("invalid value for Config.InitialMaxIncomingStreams"))
+			Expect(validateConfig(&Config{InitialMaxIncomingStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.InitialMaxIncomingStreams"))
+		})
+
+		It("errors on invalid values for InitialMaxIncomingUniStreams", func() {
+			Expect(validateConfig(&Config{InitialMaxIncomingUniStreams: -1})).To(MatchError("invalid value for Config.InitialMaxIncomingUniStreams"))
+			Expect(validateConfig(&Config{InitialMaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.InitialMaxIncomingUniStreams"))
+		})
+
+		It("errors on too small values for ActiveConnectionIDLimit", func() {
+			Expect(validateConfig(&Config{ActiveConnectionIDLimit: 1})).To(MatchError("invalid value for Config.ActiveConnectionIDLimit"))
+			Expect(validateConfig(&Config{ActiveConnectionIDLimit: 2})).To(Succeed())
+		})
+
+		It("errors on negative values for MaxProbeTimeout", func() {
+			Expect(validateConfig(&Config{MaxProbeTimeout: -time.Second})).To(MatchError("invalid value for Config.MaxProbeTimeout"))
+			Expect(validateConfig(&Config{MaxProbeTimeout: time.Second})).To(Succeed())
+		})
+
+		It("errors on negative values for MaxAckRanges", func() {
+			Expect(validateConfig(&Config{MaxAckRanges: -1})).To(MatchError("invalid value for Config.MaxAckRanges"))
+		})
+
+		It("errors on invalid values for AmplificationFactor", func() {
+			Expect(validateConfig(&Config{AmplificationFactor: -1})).To(MatchError("invalid value for Config.AmplificationFactor"))
+			Expect(validateConfig(&Config{AmplificationFactor: 1})).To(Succeed())
+			Expect(validateConfig(&Config{AmplificationFactor: 3})).To(Succeed())
+		})
+
+		It("requires an explicit opt-in for amplification factors larger than 3", func() {
+			Expect(validateConfig(&Config{AmplificationFactor: 4})).To(MatchError("Config.AmplificationFactor larger than 3 requires Config.AllowLargeAmplificationFactor"))
+			Expect(validateConfig(&Config{AmplificationFactor: 4, AllowLargeAmplificationFactor: true})).To(Succeed())
+		})
+
+		It("errors on invalid values for DSCP", func() {
+			Expect(validateConfig(&Config{DSCP: -1})).To(MatchError("invalid value for Config.DSCP"))
+			Expect(validateConfig(&Config{DSCP: 64})).To(MatchError("invalid value for Config.DSCP"))
+			Expect(validateConfig(&Config{DSCP: 63})).To(Succeed())
+		})
+
+		It("errors on invalid preferred addresses", func() {
+			ipv4 := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}
+			ipv6 := &net.UDPAddr{IP: net.ParseIP("::1"), Port: 443}
+			Expect(validateConfig(&Config{PreferredAddress: &PreferredAddress{}})).To(MatchError("invalid value for Config.PreferredAddress: no address set"))
+			Expect(validateConfig(&Config{PreferredAddress: &PreferredAddress{IPv4: ipv6}})).To(MatchError("invalid value for Config.PreferredAddress: not an IPv4 address"))
+			Expect(validateConfig(&Config{PreferredAddress: &PreferredAddress{IPv6: ipv4}})).To(MatchError("invalid value for Config.PreferredAddress: not an IPv6 address"))
+			Expect(validateConfig(&Config{PreferredAddress: &PreferredAddress{IPv4: ipv4}})).To(Succeed())
+			Expect(validateConfig(&Config{PreferredAddress: &PreferredAddress{IPv4: ipv4, IPv6: ipv6}})).To(Succeed())
+		})
+
+		It("errors on negative values for ConnectionIDLength", func() {
+			Expect(validateConfig(&Config{ConnectionIDLength: -1})).To(MatchError("invalid value for Config.ConnectionIDLength"))
+		})
+
+		It("errors on invalid connection ID lengths of the ConnectionIDGenerator", func() {
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 21}})).To(MatchError("invalid connection ID length for Config.ConnectionIDGenerator"))
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 20}})).To(Succeed())
+		})
+
+		It("errors if the ConnectionIDLength doesn't match the ConnectionIDGenerator", func() {
+			Expect(validateConfig(&Config{
+				ConnectionIDLength:    8,
+				ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 5},
+			})).To(MatchError("Config.ConnectionIDLength doesn't match the length of the Config.ConnectionIDGenerator"))
+			Expect(validateConfig(&Config{
+				ConnectionIDLength:    5,
+				ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 5},
+			})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 5}})).To(Succeed())
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -46,30 +124,96 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "Allow0RTT", "CongestionControlFactory", "GetLogWriter", "OnStreamFlowControlUpdate", "OnIncomingStream", "OnStreamsNeeded", "StatelessResetKeyFunc", "StreamReceiveWindowFunc", "PacketInterceptor", "IncomingPacketInterceptor":
 				// Can't compare functions.
+			case "DisableVersionNegotiation":
+				f.Set(reflect.ValueOf(true))
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "ConnectionIDGenerator":
+				f.Set(reflect.ValueOf(&randomConnIDGenerator{connIDLen: 8}))
+			case "Rand":
+				f.Set(reflect.ValueOf(&lockedReader{r: bytes.NewReader([]byte("foobar"))}))
+			case "AddressTokenGenerator":
+				f.Set(reflect.ValueOf(&MockAddressTokenGenerator{}))
 			case "HandshakeTimeout":
 				f.Set(reflect.ValueOf(time.Second))
+			case "MaxIncomingHandshakesPerSecond":
+				f.Set(reflect.ValueOf(100))
+			case "MaxIncomingConnections":
+				f.Set(reflect.ValueOf(1000))
+			case "HandshakeIdleTimeout":
+				f.Set(reflect.ValueOf(2 * time.Second))
 			case "MaxIdleTimeout":
 				f.Set(reflect.ValueOf(time.Hour))
+			case "InitialRTT":
+				f.Set(reflect.ValueOf(50 * time.Millisecond))
+			case "StreamWriteCoalesceDelay":
+				f.Set(reflect.ValueOf(5 * time.Millisecond))
+			case "MaxStreamIdleTimeout":
+				f.Set(reflect.ValueOf(42 * time.Second))
+			case "StreamIdleTimeoutErrorCode":
+				f.Set(reflect.ValueOf(StreamErrorCode(1234)))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
 			case "MaxReceiveStreamFlowControlWindow":
 				f.Set(reflect.ValueOf(uint64(9)))
 			case "MaxReceiveConnectionFlowControlWindow":
 				f.Set(reflect.ValueOf(uint64(10)))
+			case "DisableFlowControlAutoTuning":
+				f.Set(reflect.ValueOf(true))
+			case "MaxConnectionReceiveBuffer":
+				f.Set(reflect.ValueOf(uint64(15)))
 			case "MaxIncomingStreams":
 				f.Set(reflect.ValueOf(int64(11)))
 			case "MaxIncomingUniStreams":
 				f.Set(reflect.ValueOf(int64(12)))
+			case "InitialMaxIncomingStreams":
+				f.Set(reflect.ValueOf(int64(5)))
+			case "InitialMaxIncomingUniStreams":
+				f.Set(reflect.ValueOf(int64(6)))
+			case "ActiveConnectionIDLimit":
+				f.Set(reflect.ValueOf(uint64(7)))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
+			case "GREASEQUICBit":
+				f.Set(reflect.ValueOf(true))
+			case "DisablePacketCoalescing":
+				f.Set(reflect.ValueOf(true))
+			case "AllowConnectionMigration":
+				f.Set(reflect.ValueOf(true))
+			case "DisableActiveMigration":
+				f.Set(reflect.ValueOf(true))
+			case "RequireAddressValidationOnRebind":
+				f.Set(reflect.ValueOf(true))
+			case "PreferredAddress":
+				f.Set(reflect.ValueOf(&PreferredAddress{IPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}}))
+			case "DisablePathMigrationToPreferredAddress":
+				f.Set(reflect.ValueOf(true))
+			case "DisableHeaderProtection":
+				f.Set(reflect.ValueOf(true))
+			case "EnableECN":
+				f.Set(reflect.ValueOf(true))
+			case "EnableACKFrequency":
+				f.Set(reflect.ValueOf(true))
+			case "DSCP":
+				f.Set(reflect.ValueOf(46))
+			case "MaxSendRate":
+				f.Set(reflect.ValueOf(uint64(13)))
+			case "MaxAckRanges":
+				f.Set(reflect.ValueOf(14))
+			case "MaxUDPPayloadSize":
+				f.Set(reflect.ValueOf(protocol.ByteCount(1300)))
+			case "MaxProbeTimeout":
+				f.Set(reflect.ValueOf(10 * time.Second))
+			case "AmplificationFactor":
+				f.Set(reflect.ValueOf(2))
+			case "AllowLargeAmplificationFactor":
+				f.Set(reflect.ValueOf(true))
 			case "QuicTracer":
 				f.Set(reflect.ValueOf(quictrace.NewTracer()))
 			case "Tracer":
@@ -78,6 +222,7 @@ var _ = Describe("Config", func() {
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
 		}
+		c.clock = utils.NewManualClock(time.Now())
 		return c
 	}
 	Context("cloning", func() {
@@ -112,13 +257,41 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAcceptToken bool
+			var calledAcceptToken, calledAllow0RTT, calledOnStreamFlowControlUpdate, calledCongestionControlFactory, calledStreamReceiveWindowFunc bool
+			var calledPacketInterceptor, calledIncomingPacketInterceptor, calledOnIncomingStream, calledOnStreamsNeeded bool
 			c1 := &Config{
-				AcceptToken: func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				AcceptToken:               func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				Allow0RTT:                 func(*logging.TransportParameters) bool { calledAllow0RTT = true; return true },
+				OnStreamFlowControlUpdate: func(StreamID, uint64) { calledOnStreamFlowControlUpdate = true },
+				OnIncomingStream:          func(StreamID, StreamType) error { calledOnIncomingStream = true; return nil },
+				OnStreamsNeeded:           func(StreamType, int64) int64 { calledOnStreamsNeeded = true; return 0 },
+				StreamReceiveWindowFunc:   func(StreamID) uint64 { calledStreamReceiveWindowFunc = true; return 0 },
+				PacketInterceptor:         func([]byte, net.Addr) (bool, []byte) { calledPacketInterceptor = true; return true, nil },
+				IncomingPacketInterceptor: func([]byte, net.Addr) (bool, []byte) { calledIncomingPacketInterceptor = true; return true, nil },
+				CongestionControlFactory: func(*congestion.RTTStats, congestion.ByteCount, congestion.ByteCount) congestion.SendAlgorithm {
+					calledCongestionControlFactory = true
+					return nil
+				},
 			}
 			c2 := populateConfig(c1)
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
 			Expect(calledAcceptToken).To(BeTrue())
+			c2.Allow0RTT(&logging.TransportParameters{})
+			Expect(calledAllow0RTT).To(BeTrue())
+			c2.OnStreamFlowControlUpdate(4, 1337)
+			Expect(calledOnStreamFlowControlUpdate).To(BeTrue())
+			Expect(c2.OnIncomingStream(4, StreamTypeBidi)).To(Succeed())
+			Expect(calledOnIncomingStream).To(BeTrue())
+			c2.OnStreamsNeeded(StreamTypeBidi, 10)
+			Expect(calledOnStreamsNeeded).To(BeTrue())
+			c2.StreamReceiveWindowFunc(4)
+			Expect(calledStreamReceiveWindowFunc).To(BeTrue())
+			c2.PacketInterceptor(nil, &net.UDPAddr{})
+			Expect(calledPacketInterceptor).To(BeTrue())
+			c2.IncomingPacketInterceptor(nil, &net.UDPAddr{})
+			Expect(calledIncomingPacketInterceptor).To(BeTrue())
+			c2.CongestionControlFactory(&congestion.RTTStats{}, 1000, 2000)
+			Expect(calledCongestionControlFactory).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -134,6 +307,40 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxReceiveConnectionFlowControlWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.InitialMaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
+			Expect(c.InitialMaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.ActiveConnectionIDLimit).To(BeEquivalentTo(protocol.MaxActiveConnectionIDs))
+			Expect(c.InitialRTT).To(BeZero())
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+			Expect(c.clock).To(Equal(utils.DefaultClock{}))
+			Expect(c.MaxUDPPayloadSize).To(Equal(protocol.MaxReceivePacketSize))
+			Expect(c.AmplificationFactor).To(Equal(protocol.DefaultAmplificationFactor))
+		})
+
+		It("limits the initial number of incoming streams to the maximum", func() {
+			c := populateConfig(&Config{
+				MaxIncomingStreams:           10,
+				InitialMaxIncomingStreams:    20,
+				MaxIncomingUniStreams:        -1,
+				InitialMaxIncomingUniStreams: 5,
+			})
+			Expect(c.InitialMaxIncomingStreams).To(BeEquivalentTo(10))
+			Expect(c.InitialMaxIncomingUniStreams).To(BeZero())
+		})
+
+		It("clamps the max UDP payload size to the valid range", func() {
+			Expect(populateConfig(&Config{MaxUDPPayloadSize: 1000}).MaxUDPPayloadSize).To(Equal(protocol.ByteCount(1200)))
+			Expect(populateConfig(&Config{MaxUDPPayloadSize: 70000}).MaxUDPPayloadSize).To(Equal(protocol.ByteCount(65527)))
+		})
+
+		It("limits the number of ACK ranges to the number of tracked ranges", func() {
+			c := populateConfig(&Config{MaxAckRanges: protocol.MaxNumAckRanges + 1})
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+		})
+
+		It("increases too small values for the initial RTT", func() {
+			c := populateConfig(&Config{InitialRTT: time.Microsecond})
+			Expect(c.InitialRTT).To(Equal(protocol.MinInitialRTT))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -151,5 +358,59 @@ var _ = Describe("Config", func() {
 			c := populateClientConfig(&Config{}, true)
 			Expect(c.ConnectionIDLength).To(BeZero())
 		})
+
+		It("limits the connection ID length", func() {
+			c := populateServerConfig(&Config{ConnectionIDLength: 25})
+			Expect(c.ConnectionIDLength).To(Equal(protocol.MaxConnIDLen))
+			connID, err := c.ConnectionIDGenerator.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(Equal(protocol.MaxConnIDLen))
+		})
+
+		It("uses a random connection ID generator by default", func() {
+			c := populateServerConfig(&Config{ConnectionIDLength: 6})
+			Expect(c.ConnectionIDGenerator).To(Equal(&randomConnIDGenerator{connIDLen: 6, rand: rand.Reader}))
+			connID, err := c.ConnectionIDGenerator.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(Equal(6))
+		})
+
+		It("uses crypto/rand by default", func() {
+			Expect(populateConfig(&Config{}).Rand).To(Equal(rand.Reader))
+		})
+
+		It("serializes reads from the random source", func() {
+			rnd := bytes.NewReader([]byte("foobar"))
+			c := populateConfig(&Config{Rand: rnd})
+			Expect(c.Rand).To(Equal(&lockedReader{r: rnd}))
+			// the reader isn't wrapped again
+			Expect(populateConfig(c).Rand).To(BeIdenticalTo(c.Rand))
+			b := make([]byte, 6)
+			_, err := io.ReadFull(c.Rand, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("generates connection IDs using the random source", func() {
+			c := populateServerConfig(&Config{
+				ConnectionIDLength: 6,
+				Rand:               bytes.NewReader([]byte("foobar")),
+			})
+			connID, err := c.ConnectionIDGenerator.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(protocol.ConnectionID("foobar")))
+		})
+
+		It("uses the length of the connection ID generator, for the server", func() {
+			gen := &randomConnIDGenerator{connIDLen: 7}
+			c := populateServerConfig(&Config{ConnectionIDGenerator: gen})
+			Expect(c.ConnectionIDGenerator).To(Equal(gen))
+			Expect(c.ConnectionIDLength).To(Equal(7))
+		})
+
+		It("uses the length of the connection ID generator, for the client", func() {
+			c := populateClientConfig(&Config{ConnectionIDGenerator: &randomConnIDGenerator{connIDLen: 0}}, false)
+			Expect(c.ConnectionIDLength).To(BeZero())
+		})
 	})
 })
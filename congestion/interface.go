@@ -0,0 +1,55 @@
+// Package congestion defines the interface that congestion controllers used by quic-go need to implement.
+// A custom congestion controller can be configured using the CongestionControlFactory in the quic.Config.
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+type (
+	// A ByteCount is used to count bytes.
+	ByteCount = protocol.ByteCount
+	// The PacketNumber is the packet number of a packet.
+	PacketNumber = protocol.PacketNumber
+	// The RTTStats contain statistics about the round-trip time of a connection.
+	RTTStats = utils.RTTStats
+)
+
+// A SendAlgorithm performs congestion control.
+// All methods are called from the session's run loop, so implementations don't need to be safe for concurrent use.
+type SendAlgorithm interface {
+	// TimeUntilSend returns when the next packet may be sent, taking pacing into account.
+	// The zero value means that a packet may be sent immediately.
+	TimeUntilSend(bytesInFlight ByteCount) time.Time
+	// HasPacingBudget says if the pacer allows sending a packet right now.
+	HasPacingBudget() bool
+	// OnPacketSent is called for every packet sent.
+	// bytesInFlight is the number of bytes in flight before sending this packet.
+	OnPacketSent(sentTime time.Time, bytesInFlight ByteCount, packetNumber PacketNumber, bytes ByteCount, isRetransmittable bool)
+	// CanSend says if the congestion window allows sending more data.
+	CanSend(bytesInFlight ByteCount) bool
+	// MaybeExitSlowStart is called when the RTT estimate is updated after receiving an ACK frame.
+	MaybeExitSlowStart()
+	// OnPacketAcked is called for every packet acknowledged by the peer.
+	OnPacketAcked(number PacketNumber, ackedBytes ByteCount, priorInFlight ByteCount, eventTime time.Time)
+	// OnPacketLost is called for every packet that is declared lost.
+	OnPacketLost(number PacketNumber, lostBytes ByteCount, priorInFlight ByteCount)
+	// OnRetransmissionTimeout is currently not called by quic-go.
+	// Loss detection after a probe timeout is reported using OnPacketLost.
+	OnRetransmissionTimeout(packetsRetransmitted bool)
+}
+
+// A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos.
+// These are used for tracing and logging.
+type SendAlgorithmWithDebugInfos interface {
+	SendAlgorithm
+	// InSlowStart says if the congestion controller is in slow start.
+	InSlowStart() bool
+	// InRecovery says if the congestion controller is in recovery.
+	InRecovery() bool
+	// GetCongestionWindow returns the current congestion window.
+	GetCongestionWindow() ByteCount
+}
@@ -0,0 +1,66 @@
+package quic
+
+import (
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// An oobConn is a net.PacketConn that allows reading and writing out-of-band data,
+// which is needed to access the ECN bits in the IP header and the receive timestamps reported by the kernel.
+// It is implemented by *net.UDPConn.
+type oobConn interface {
+	net.PacketConn
+	SyscallConn() (syscall.RawConn, error)
+	ReadMsgUDP(b, oob []byte) (n, oobn, flags int, addr *net.UDPAddr, err error)
+	WriteMsgUDP(b, oob []byte, addr *net.UDPAddr) (n, oobn int, err error)
+}
+
+// A packetReader reads packets from a net.PacketConn.
+// If supported by the platform, it also returns the ECN bits of the received packet.
+// The receive timestamp is the one reported by the kernel, if available,
+// and otherwise the time when the packet was read from the socket.
+type packetReader interface {
+	ReadPacket([]byte) (int, net.Addr, protocol.ECN, time.Time, error)
+}
+
+func newPacketReader(c net.PacketConn) packetReader {
+	if oc, ok := c.(oobConn); ok && ecnSupported {
+		if rawConn, err := oc.SyscallConn(); err == nil {
+			errECN := enableReceiveECN(rawConn)
+			errTimestamps := enableReceiveTimestamps(rawConn)
+			if errECN == nil || errTimestamps == nil {
+				return &oobPacketReader{conn: oc, oob: make([]byte, 128)}
+			}
+		}
+	}
+	return &basicPacketReader{conn: c}
+}
+
+type basicPacketReader struct {
+	conn net.PacketConn
+}
+
+func (r *basicPacketReader) ReadPacket(b []byte) (int, net.Addr, protocol.ECN, time.Time, error) {
+	n, addr, err := r.conn.ReadFrom(b)
+	return n, addr, protocol.ECNNon, time.Now(), err
+}
+
+type oobPacketReader struct {
+	conn oobConn
+	oob  []byte // not safe for concurrent use, but packets are only read from a single go routine
+}
+
+func (r *oobPacketReader) ReadPacket(b []byte) (int, net.Addr, protocol.ECN, time.Time, error) {
+	n, oobn, _, addr, err := r.conn.ReadMsgUDP(b, r.oob)
+	if err != nil {
+		return 0, nil, protocol.ECNNon, time.Time{}, err
+	}
+	ecn, timestamp := parseControlMessages(r.oob[:oobn])
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return n, addr, ecn, timestamp, nil
+}
@@ -0,0 +1,78 @@
+// +build linux
+
+package quic
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+const ecnSupported = true
+
+const ecnMask = 0x3
+
+// enableReceiveECN makes the kernel report the TOS (for IPv4) and the Traffic Class (for IPv6) of received packets.
+// It succeeds if at least one of the two socket options could be set.
+func enableReceiveECN(c syscall.RawConn) error {
+	var errIPv4, errIPv6 error
+	if err := c.Control(func(fd uintptr) {
+		errIPv4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1)
+		errIPv6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVTCLASS, 1)
+	}); err != nil {
+		return err
+	}
+	if errIPv4 != nil && errIPv6 != nil {
+		return errors.New("activating ECN failed for both IPv4 and IPv6")
+	}
+	return nil
+}
+
+// parseControlMessages parses the ECN bits and the receive timestamp from the out-of-band data of a received packet.
+// The timestamp is zero if the kernel didn't report one.
+func parseControlMessages(oob []byte) (protocol.ECN, time.Time) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return protocol.ECNNon, time.Time{}
+	}
+	ecn := protocol.ECNNon
+	var timestamp time.Time
+	for i := range msgs {
+		msg := &msgs[i]
+		if len(msg.Data) == 0 {
+			continue
+		}
+		switch {
+		case msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TOS:
+			ecn = protocol.ECN(msg.Data[0] & ecnMask)
+		case msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_TCLASS && len(msg.Data) >= 4:
+			ecn = protocol.ECN(*(*int32)(unsafe.Pointer(&msg.Data[0])) & ecnMask)
+		default:
+			if t, ok := parseTimestamp(msg); ok {
+				timestamp = t
+			}
+		}
+	}
+	return ecn, timestamp
+}
+
+// ecnControlMessage creates the control message that sets the ECN bits of an outgoing packet.
+// For packets sent to an IPv4 address, this sets the TOS, for IPv6 the Traffic Class.
+// Since this overrides the value set on the socket, the DSCP needs to be included.
+func ecnControlMessage(ecn protocol.ECN, dscp uint8, isIPv4 bool) []byte {
+	b := make([]byte, syscall.CmsgSpace(4))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[0]))
+	if isIPv4 {
+		h.Level = syscall.IPPROTO_IP
+		h.Type = syscall.IP_TOS
+	} else {
+		h.Level = syscall.IPPROTO_IPV6
+		h.Type = syscall.IPV6_TCLASS
+	}
+	h.SetLen(syscall.CmsgLen(4))
+	*(*int32)(unsafe.Pointer(&b[syscall.CmsgLen(0)])) = int32(dscp)<<2 | int32(ecn)
+	return b
+}
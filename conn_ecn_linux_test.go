@@ -0,0 +1,147 @@
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	for _, v := range []string{"udp4", "udp6"} {
+		network := v
+
+		It("sends and receives ECN-marked packets, using "+network, func() {
+			ip := net.IPv4(127, 0, 0, 1)
+			if network == "udp6" {
+				ip = net.IPv6loopback
+			}
+			server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip("couldn't listen on " + network)
+			}
+			defer server.Close()
+			client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			Expect(err).ToNot(HaveOccurred())
+			defer client.Close()
+
+			reader := newPacketReader(server)
+			Expect(reader).To(BeAssignableToTypeOf(&oobPacketReader{}))
+			c := newSendConn(client, server.LocalAddr(), 0)
+			Expect(c.SupportsECN()).To(BeTrue())
+
+			for _, ecn := range []protocol.ECN{protocol.ECT0, protocol.ECT1, protocol.ECNCE, protocol.ECNNon} {
+				Expect(c.Write([]byte("foobar"), ecn)).To(Succeed())
+				b := make([]byte, 100)
+				n, addr, receivedECN, _, err := reader.ReadPacket(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+				Expect(addr.String()).To(Equal(client.LocalAddr().String()))
+				Expect(receivedECN).To(Equal(ecn))
+			}
+		})
+	}
+
+	for _, v := range []string{"udp4", "udp6"} {
+		network := v
+
+		It("sets the DSCP on ECN-marked and unmarked packets, using "+network, func() {
+			ip := net.IPv4(127, 0, 0, 1)
+			if network == "udp6" {
+				ip = net.IPv6loopback
+			}
+			server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip("couldn't listen on " + network)
+			}
+			defer server.Close()
+			rawConn, err := server.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(enableReceiveECN(rawConn)).To(Succeed())
+			client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			Expect(err).ToNot(HaveOccurred())
+			defer client.Close()
+
+			const dscp = 46 // Expedited Forwarding
+			Expect(setDSCP(client, dscp)).To(Succeed())
+			c := newSendConn(client, server.LocalAddr(), dscp)
+
+			for _, ecn := range []protocol.ECN{protocol.ECT0, protocol.ECNNon} {
+				Expect(c.Write([]byte("foobar"), ecn)).To(Succeed())
+				b := make([]byte, 100)
+				oob := make([]byte, 128)
+				_, oobn, _, _, err := server.ReadMsgUDP(b, oob)
+				Expect(err).ToNot(HaveOccurred())
+				msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+				Expect(err).ToNot(HaveOccurred())
+				Expect(msgs).To(HaveLen(1))
+				var tos int
+				if network == "udp4" {
+					tos = int(msgs[0].Data[0])
+				} else {
+					tos = int(*(*int32)(unsafe.Pointer(&msgs[0].Data[0])))
+				}
+				Expect(tos >> 2).To(Equal(dscp))
+				Expect(protocol.ECN(tos & ecnMask)).To(Equal(ecn))
+			}
+		})
+	}
+
+	for _, v := range []string{"udp4", "udp6"} {
+		network := v
+
+		It("reports kernel receive timestamps, using "+network, func() {
+			ip := net.IPv4(127, 0, 0, 1)
+			if network == "udp6" {
+				ip = net.IPv6loopback
+			}
+			server, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip("couldn't listen on " + network)
+			}
+			defer server.Close()
+			rawConn, err := server.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(enableReceiveTimestamps(rawConn)).To(Succeed())
+			client, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			Expect(err).ToNot(HaveOccurred())
+			defer client.Close()
+
+			before := time.Now()
+			_, err = client.WriteTo([]byte("foobar"), server.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 100)
+			oob := make([]byte, 128)
+			_, oobn, _, _, err := server.ReadMsgUDP(b, oob)
+			Expect(err).ToNot(HaveOccurred())
+			after := time.Now()
+			_, timestamp := parseControlMessages(oob[:oobn])
+			Expect(timestamp.IsZero()).To(BeFalse())
+			// kernel timestamps don't have a monotonic clock reading, so allow for some clock skew
+			Expect(timestamp).To(BeTemporally(">=", before.Round(0).Add(-time.Millisecond)))
+			Expect(timestamp).To(BeTemporally("<=", after.Round(0).Add(time.Millisecond)))
+		})
+	}
+
+	It("falls back to the time when the packet was read, if the kernel doesn't report a timestamp", func() {
+		_, timestamp := parseControlMessages(nil)
+		Expect(timestamp.IsZero()).To(BeTrue())
+		conn := newMockPacketConn()
+		conn.dataToRead <- []byte("foobar")
+		reader := newPacketReader(conn)
+		_, _, _, timestamp, err := reader.ReadPacket(make([]byte, 100))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timestamp).To(BeTemporally("~", time.Now(), time.Second))
+	})
+
+	It("doesn't report ECN marks for connections that don't support reading out-of-band data", func() {
+		Expect(newPacketReader(newMockPacketConn())).To(BeAssignableToTypeOf(&basicPacketReader{}))
+	})
+})
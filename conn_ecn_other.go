@@ -0,0 +1,21 @@
+// +build !linux
+
+package quic
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+const ecnSupported = false
+
+func enableReceiveECN(syscall.RawConn) error {
+	return errors.New("ECN not supported on this platform")
+}
+
+func parseControlMessages([]byte) (protocol.ECN, time.Time) { return protocol.ECNNon, time.Time{} }
+
+func ecnControlMessage(protocol.ECN, uint8, bool) []byte { return nil }
@@ -1,7 +1,9 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -9,12 +11,34 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+// randomConnIDGenerator is the ConnectionIDGenerator used if none is set in the Config.
+type randomConnIDGenerator struct {
+	connIDLen int
+	rand      io.Reader // if nil, crypto/rand is used
+}
+
+var _ ConnectionIDGenerator = &randomConnIDGenerator{}
+
+func (g *randomConnIDGenerator) GenerateConnectionID() (ConnectionID, error) {
+	if g.rand == nil {
+		return protocol.GenerateConnectionID(g.connIDLen)
+	}
+	return protocol.GenerateConnectionIDFrom(g.rand, g.connIDLen)
+}
+
+func (g *randomConnIDGenerator) ConnectionIDLen() int {
+	return g.connIDLen
+}
+
 type connIDGenerator struct {
-	connIDLen  int
+	generator  ConnectionIDGenerator
 	highestSeq uint64
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
+	// the connection ID sent in the preferred_address transport parameter,
+	// until it is added to the packet handler map
+	preferredAddressConnID protocol.ConnectionID
 
 	addConnectionID        func(protocol.ConnectionID)
 	getStatelessResetToken func(protocol.ConnectionID) protocol.StatelessResetToken
@@ -27,6 +51,7 @@ type connIDGenerator struct {
 func newConnIDGenerator(
 	initialConnectionID protocol.ConnectionID,
 	initialClientDestConnID protocol.ConnectionID, // nil for the client
+	generator ConnectionIDGenerator,
 	addConnectionID func(protocol.ConnectionID),
 	getStatelessResetToken func(protocol.ConnectionID) protocol.StatelessResetToken,
 	removeConnectionID func(protocol.ConnectionID),
@@ -35,7 +60,7 @@ func newConnIDGenerator(
 	queueControlFrame func(wire.Frame),
 ) *connIDGenerator {
 	m := &connIDGenerator{
-		connIDLen:              initialConnectionID.Len(),
+		generator:              generator,
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
@@ -50,16 +75,18 @@ func newConnIDGenerator(
 }
 
 func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
-	if m.connIDLen == 0 {
+	if m.generator.ConnectionIDLen() == 0 {
 		return nil
 	}
 	// The active_connection_id_limit transport parameter is the number of
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
-	for i := uint64(1); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	if m.preferredAddressConnID != nil {
+		m.addConnectionID(m.preferredAddressConnID)
+		m.preferredAddressConnID = nil
+	}
+	for i := m.highestSeq + 1; i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -67,6 +94,24 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	return nil
 }
 
+// IssuePreferredAddressConnID issues the connection ID sent in the preferred_address transport parameter.
+// It must be called before any other connection IDs are issued, since this connection ID has the sequence number 1.
+// Since it is called while the session is being created, the connection ID is only added
+// to the packet handler map when SetMaxActiveConnIDs is called.
+func (m *connIDGenerator) IssuePreferredAddressConnID() (protocol.ConnectionID, protocol.StatelessResetToken, error) {
+	if m.highestSeq != 0 {
+		return nil, protocol.StatelessResetToken{}, errors.New("connection IDs were already issued")
+	}
+	connID, err := m.generator.GenerateConnectionID()
+	if err != nil {
+		return nil, protocol.StatelessResetToken{}, err
+	}
+	m.highestSeq = 1
+	m.activeSrcConnIDs[1] = connID
+	m.preferredAddressConnID = connID
+	return connID, m.getStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.ConnectionID) error {
 	if seq > m.highestSeq {
 		return qerr.NewError(qerr.ProtocolViolation, fmt.Sprintf("tried to retire connection ID %d. Highest issued: %d", seq, m.highestSeq))
@@ -92,7 +137,7 @@ func (m *connIDGenerator) issueNewConnID() error {
 	if RetireBugBackwardsCompatibilityMode {
 		return nil
 	}
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := m.generator.GenerateConnectionID()
 	if err != nil {
 		return err
 	}
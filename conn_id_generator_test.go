@@ -10,6 +10,19 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// shardConnIDGenerator encodes a shard identifier into the first byte of the connection ID
+type shardConnIDGenerator struct {
+	shard   byte
+	counter byte
+}
+
+func (g *shardConnIDGenerator) GenerateConnectionID() (ConnectionID, error) {
+	g.counter++
+	return ConnectionID{g.shard, g.counter, 0, 0, 0}, nil
+}
+
+func (g *shardConnIDGenerator) ConnectionIDLen() int { return 5 }
+
 var _ = Describe("Connection ID Generator", func() {
 	var (
 		addedConnIDs       []protocol.ConnectionID
@@ -35,6 +48,7 @@ var _ = Describe("Connection ID Generator", func() {
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			&randomConnIDGenerator{connIDLen: 7},
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
 			connIDToToken,
 			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
@@ -62,6 +76,50 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("issues the connection ID for the preferred address", func() {
+		connID, token, err := g.IssuePreferredAddressConnID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID.Len()).To(Equal(7))
+		Expect(token).To(Equal(connIDToToken(connID)))
+		Expect(addedConnIDs).To(BeEmpty())
+		// The preferred address connection ID counts towards the active_connection_id_limit.
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(addedConnIDs).To(HaveLen(3))
+		Expect(addedConnIDs[0]).To(Equal(connID))
+		Expect(queuedFrames).To(HaveLen(2))
+		for i, f := range queuedFrames {
+			Expect(f.(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(i + 2))
+		}
+		// The peer can retire the preferred address connection ID.
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+	})
+
+	It("refuses to issue the preferred address connection ID after other connection IDs", func() {
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		_, _, err := g.IssuePreferredAddressConnID()
+		Expect(err).To(MatchError("connection IDs were already issued"))
+	})
+
+	It("uses the connection ID generator", func() {
+		g.generator = &shardConnIDGenerator{shard: 0x42}
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{
+			{0x42, 1, 0, 0, 0},
+			{0x42, 2, 0, 0, 0},
+			{0x42, 3, 0, 0, 0},
+		}))
+		Expect(queuedFrames).To(HaveLen(3))
+		Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID).To(Equal(protocol.ConnectionID{0x42, 1, 0, 0, 0}))
+	})
+
+	It("doesn't issue new connection IDs if the connection ID generator uses 0 byte connection IDs", func() {
+		g.generator = &randomConnIDGenerator{connIDLen: 0}
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(addedConnIDs).To(BeEmpty())
+		Expect(queuedFrames).To(BeEmpty())
+	})
+
 	It("doesn't issue new connection IDs in RetireBugBackwardsCompatibilityMode", func() {
 		RetireBugBackwardsCompatibilityMode = true
 		defer func() { RetireBugBackwardsCompatibilityMode = false }()
@@ -1,15 +1,16 @@
 package quic
 
 import (
-	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"io"
 	mrand "math/rand"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 type connIDManager struct {
@@ -19,6 +20,8 @@ type connIDManager struct {
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *protocol.StatelessResetToken
+	// the active_connection_id_limit we sent to the peer
+	activeConnectionIDLimit uint64
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -31,25 +34,32 @@ type connIDManager struct {
 	removeStatelessResetToken func(protocol.StatelessResetToken)
 	retireStatelessResetToken func(protocol.StatelessResetToken)
 	queueControlFrame         func(wire.Frame)
+
+	tracer logging.ConnectionTracer
 }
 
 func newConnIDManager(
 	initialDestConnID protocol.ConnectionID,
+	activeConnectionIDLimit uint64,
 	addStatelessResetToken func(protocol.StatelessResetToken),
 	removeStatelessResetToken func(protocol.StatelessResetToken),
 	retireStatelessResetToken func(protocol.StatelessResetToken),
 	queueControlFrame func(wire.Frame),
+	rand io.Reader, // used to seed the randomization of the connection ID changes
+	tracer logging.ConnectionTracer,
 ) *connIDManager {
 	b := make([]byte, 8)
-	_, _ = rand.Read(b) // ignore the error here. Nothing bad will happen if the seed is not perfectly random.
+	_, _ = io.ReadFull(rand, b) // ignore the error here. Nothing bad will happen if the seed is not perfectly random.
 	seed := int64(binary.BigEndian.Uint64(b))
 	return &connIDManager{
 		activeConnectionID:        initialDestConnID,
+		activeConnectionIDLimit:   activeConnectionIDLimit,
 		addStatelessResetToken:    addStatelessResetToken,
 		removeStatelessResetToken: removeStatelessResetToken,
 		retireStatelessResetToken: retireStatelessResetToken,
 		queueControlFrame:         queueControlFrame,
 		rand:                      mrand.New(mrand.NewSource(seed)),
+		tracer:                    tracer,
 	}
 }
 
@@ -61,7 +71,7 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
 	}
-	if h.queue.Len() >= protocol.MaxActiveConnectionIDs {
+	if uint64(h.queue.Len()) >= h.activeConnectionIDLimit {
 		return qerr.ConnectionIDLimitError
 	}
 	return nil
@@ -71,9 +81,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequence number is smaller than the currently active
 	// connection ID or if it was already retired, send the RETIRE_CONNECTION_ID frame immediately.
 	if f.SequenceNumber < h.activeSequenceNumber || f.SequenceNumber < h.highestRetired {
-		h.queueControlFrame(&wire.RetireConnectionIDFrame{
-			SequenceNumber: f.SequenceNumber,
-		})
+		h.retireConnectionID(f.SequenceNumber)
 		return nil
 	}
 
@@ -86,9 +94,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 				break
 			}
 			next = el.Next()
-			h.queueControlFrame(&wire.RetireConnectionIDFrame{
-				SequenceNumber: el.Value.SequenceNumber,
-			})
+			h.retireConnectionID(el.Value.SequenceNumber)
 			h.queue.Remove(el)
 		}
 		h.highestRetired = f.RetirePriorTo
@@ -118,6 +124,9 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 			ConnectionID:        connID,
 			StatelessResetToken: resetToken,
 		})
+		if h.tracer != nil {
+			h.tracer.NewConnectionIDReceived(seq, connID)
+		}
 		return nil
 	}
 	// insert a new element somewhere in the middle
@@ -137,16 +146,24 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 				ConnectionID:        connID,
 				StatelessResetToken: resetToken,
 			}, el)
+			if h.tracer != nil {
+				h.tracer.NewConnectionIDReceived(seq, connID)
+			}
 			break
 		}
 	}
 	return nil
 }
 
+func (h *connIDManager) retireConnectionID(seq uint64) {
+	h.queueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: seq})
+	if h.tracer != nil {
+		h.tracer.RetiredConnectionID(seq)
+	}
+}
+
 func (h *connIDManager) updateConnectionID() {
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: h.activeSequenceNumber,
-	})
+	h.retireConnectionID(h.activeSequenceNumber)
 	h.highestRetired = utils.MaxUint64(h.highestRetired, h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.retireStatelessResetToken(*h.activeStatelessResetToken)
@@ -161,6 +178,21 @@ func (h *connIDManager) updateConnectionID() {
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
 
+// ChangeConnectionID switches to a new connection ID.
+// It is called when the connection is migrated to a new path,
+// since a connection ID must not be used on more than one path.
+// It returns false if the peer hasn't provided any unused connection IDs.
+func (h *connIDManager) ChangeConnectionID() bool {
+	if h.activeConnectionID.Len() == 0 {
+		return true
+	}
+	if h.queue.Len() == 0 {
+		return false
+	}
+	h.updateConnectionID()
+	return true
+}
+
 func (h *connIDManager) Close() {
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -197,7 +229,7 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 	// For later changes, only change if
 	// 1. The queue of connection IDs is filled more than 50%.
 	// 2. We sent at least PacketsPerConnectionID packets
-	return 2*h.queue.Len() >= protocol.MaxActiveConnectionIDs &&
+	return 2*uint64(h.queue.Len()) >= h.activeConnectionIDLimit &&
 		h.packetsSinceLastChange >= h.packetsPerConnectionID
 }
 
@@ -1,6 +1,11 @@
 package quic
 
 import (
+	"crypto/rand"
+
+	"github.com/golang/mock/gomock"
+
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
@@ -14,6 +19,8 @@ var _ = Describe("Connection ID Manager", func() {
 		tokenAdded    *protocol.StatelessResetToken
 		retiredTokens []protocol.StatelessResetToken
 		removedTokens []protocol.StatelessResetToken
+		receivedSeqs  []uint64 // sequence numbers passed to the tracer
+		retiredSeqs   []uint64 // sequence numbers passed to the tracer
 	)
 	initialConnID := protocol.ConnectionID{0, 0, 0, 0}
 
@@ -22,15 +29,28 @@ var _ = Describe("Connection ID Manager", func() {
 		tokenAdded = nil
 		retiredTokens = nil
 		removedTokens = nil
+		receivedSeqs = nil
+		retiredSeqs = nil
+		tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().NewConnectionIDReceived(gomock.Any(), gomock.Any()).Do(func(seq uint64, _ protocol.ConnectionID) {
+			receivedSeqs = append(receivedSeqs, seq)
+		}).AnyTimes()
+		tracer.EXPECT().RetiredConnectionID(gomock.Any()).Do(func(seq uint64) {
+			retiredSeqs = append(retiredSeqs, seq)
+		}).AnyTimes()
 		m = newConnIDManager(
 			initialConnID,
+			protocol.MaxActiveConnectionIDs,
 			func(token protocol.StatelessResetToken) { tokenAdded = &token },
 			func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
 			func(token protocol.StatelessResetToken) { retiredTokens = append(retiredTokens, token) },
 			func(f wire.Frame,
 			) {
 				frameQueue = append(frameQueue, f)
-			})
+			},
+			rand.Reader,
+			tracer,
+		)
 	})
 
 	get := func() (protocol.ConnectionID, protocol.StatelessResetToken) {
@@ -160,6 +180,34 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(m.Get()).To(Equal(protocol.ConnectionID{3, 4, 5, 6}))
 	})
 
+	It("traces new and retired connection IDs", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 10,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		// duplicates are not traced
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 10,
+			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
+		})).To(Succeed())
+		Expect(receivedSeqs).To(Equal([]uint64{10}))
+		Expect(retiredSeqs).To(BeEmpty())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			RetirePriorTo:  11,
+			SequenceNumber: 12,
+			ConnectionID:   protocol.ConnectionID{2, 3, 4, 5},
+		})).To(Succeed())
+		Expect(receivedSeqs).To(Equal([]uint64{10, 12}))
+		Expect(retiredSeqs).To(Equal([]uint64{10, 0}))
+		// reordered connection IDs are retired immediately
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 5,
+			ConnectionID:   protocol.ConnectionID{3, 4, 5, 6},
+		})).To(Succeed())
+		Expect(receivedSeqs).To(Equal([]uint64{10, 12}))
+		Expect(retiredSeqs).To(Equal([]uint64{10, 0, 5}))
+	})
+
 	It("ignores reordered connection IDs, if their sequence number was already retired", func() {
 		Expect(m.Add(&wire.NewConnectionIDFrame{
 			SequenceNumber: 10,
@@ -235,6 +283,18 @@ var _ = Describe("Connection ID Manager", func() {
 		})).To(MatchError("CONNECTION_ID_LIMIT_ERROR"))
 	})
 
+	It("uses the configured active connection ID limit", func() {
+		m.activeConnectionIDLimit = 2
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ConnectionID{1, 1, 1, 1},
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ConnectionID{2, 2, 2, 2},
+		})).To(MatchError("CONNECTION_ID_LIMIT_ERROR"))
+	})
+
 	It("initiates the first connection ID update as soon as possible", func() {
 		Expect(m.Get()).To(Equal(initialConnID))
 		Expect(m.Add(&wire.NewConnectionIDFrame{
@@ -246,6 +306,30 @@ var _ = Describe("Connection ID Manager", func() {
 
 	})
 
+	It("changes the connection ID when migrating", func() {
+		var s uint8
+		for s = uint8(1); s <= 2; s++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(s),
+				ConnectionID:        protocol.ConnectionID{s, s, s, s},
+				StatelessResetToken: protocol.StatelessResetToken{s, s, s, s, s, s, s, s, s, s, s, s, s, s, s, s},
+			})).To(Succeed())
+		}
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+		Expect(m.ChangeConnectionID()).To(BeTrue())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+		Expect(retiredSeqs).To(ContainElement(uint64(1)))
+		// no unused connection ID left
+		Expect(m.ChangeConnectionID()).To(BeFalse())
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+	})
+
+	It("doesn't need to change zero-length connection IDs when migrating", func() {
+		m.activeConnectionID = nil
+		Expect(m.ChangeConnectionID()).To(BeTrue())
+		Expect(m.Get()).To(BeEmpty())
+	})
+
 	It("initiates subsequent updates when enough packets are sent", func() {
 		var s uint8
 		for s = uint8(1); s < protocol.MaxActiveConnectionIDs; s++ {
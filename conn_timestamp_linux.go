@@ -0,0 +1,66 @@
+// +build linux
+
+package quic
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// flags for SO_TIMESTAMPING, see https://www.kernel.org/doc/Documentation/networking/timestamping.txt
+const (
+	sofTimestampingRxHardware  = 1 << 2
+	sofTimestampingRxSoftware  = 1 << 3
+	sofTimestampingSoftware    = 1 << 4
+	sofTimestampingRawHardware = 1 << 6
+)
+
+// enableReceiveTimestamps makes the kernel report the time when a packet was received.
+// Hardware timestamps are only reported if hardware timestamping was enabled on the network interface,
+// otherwise the kernel reports a software timestamp.
+// Kernels that don't support SO_TIMESTAMPING fall back to SO_TIMESTAMPNS.
+func enableReceiveTimestamps(c syscall.RawConn) error {
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TIMESTAMPING,
+			sofTimestampingRxHardware|sofTimestampingRxSoftware|sofTimestampingSoftware|sofTimestampingRawHardware)
+		if err != nil {
+			err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TIMESTAMPNS, 1)
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// parseTimestamp parses a SCM_TIMESTAMPING or a SCM_TIMESTAMPNS control message.
+// For SCM_TIMESTAMPING, the raw hardware timestamp is preferred over the software timestamp.
+func parseTimestamp(msg *syscall.SocketControlMessage) (time.Time, bool) {
+	if msg.Header.Level != syscall.SOL_SOCKET {
+		return time.Time{}, false
+	}
+	const tsLen = int(unsafe.Sizeof(syscall.Timespec{}))
+	switch msg.Header.Type {
+	case syscall.SCM_TIMESTAMPING:
+		// struct scm_timestamping contains 3 timestamps: the software timestamp,
+		// a deprecated one, and the raw hardware timestamp
+		if len(msg.Data) < 3*tsLen {
+			return time.Time{}, false
+		}
+		ts := (*[3]syscall.Timespec)(unsafe.Pointer(&msg.Data[0]))
+		if ts[2].Sec != 0 || ts[2].Nsec != 0 {
+			return time.Unix(ts[2].Unix()), true
+		}
+		if ts[0].Sec != 0 || ts[0].Nsec != 0 {
+			return time.Unix(ts[0].Unix()), true
+		}
+	case syscall.SCM_TIMESTAMPNS:
+		if len(msg.Data) < tsLen {
+			return time.Time{}, false
+		}
+		ts := (*syscall.Timespec)(unsafe.Pointer(&msg.Data[0]))
+		return time.Unix(ts.Unix()), true
+	}
+	return time.Time{}, false
+}
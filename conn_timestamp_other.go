@@ -0,0 +1,12 @@
+// +build !linux
+
+package quic
+
+import (
+	"errors"
+	"syscall"
+)
+
+func enableReceiveTimestamps(syscall.RawConn) error {
+	return errors.New("receive timestamps not supported on this platform")
+}
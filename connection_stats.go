@@ -0,0 +1,44 @@
+package quic
+
+import (
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// connectionStats holds the counters returned by Session.Stats.
+// The counters are only incremented from the run loop, but they are read concurrently,
+// so they have to be accessed atomically.
+// Since all fields are 64 bit values, the struct needs to be allocated separately
+// to guarantee 64 bit alignment on 32 bit platforms.
+type connectionStats struct {
+	packetsSent      uint64
+	bytesSent        uint64
+	packetsReceived  uint64
+	bytesReceived    uint64
+	probePacketsSent uint64
+}
+
+func (s *connectionStats) sentDatagram(numPackets int, size protocol.ByteCount) {
+	atomic.AddUint64(&s.packetsSent, uint64(numPackets))
+	atomic.AddUint64(&s.bytesSent, uint64(size))
+}
+
+func (s *connectionStats) receivedPacket(size protocol.ByteCount) {
+	atomic.AddUint64(&s.packetsReceived, 1)
+	atomic.AddUint64(&s.bytesReceived, uint64(size))
+}
+
+func (s *connectionStats) sentProbePacket() {
+	atomic.AddUint64(&s.probePacketsSent, 1)
+}
+
+func (s *connectionStats) get() ConnectionStats {
+	return ConnectionStats{
+		PacketsSent:      atomic.LoadUint64(&s.packetsSent),
+		BytesSent:        atomic.LoadUint64(&s.bytesSent),
+		PacketsReceived:  atomic.LoadUint64(&s.packetsReceived),
+		BytesReceived:    atomic.LoadUint64(&s.bytesReceived),
+		ProbePacketsSent: atomic.LoadUint64(&s.probePacketsSent),
+	}
+}
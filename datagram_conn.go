@@ -0,0 +1,110 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+var datagramConnCounter uint64
+
+// A datagramAddr is the synthetic address of a datagramConn.
+type datagramAddr struct {
+	id     uint64
+	remote bool
+}
+
+var _ net.Addr = &datagramAddr{}
+
+func (a *datagramAddr) Network() string { return "datagram" }
+
+func (a *datagramAddr) String() string {
+	if a.remote {
+		return fmt.Sprintf("datagram-%d-remote", a.id)
+	}
+	return fmt.Sprintf("datagram-%d-local", a.id)
+}
+
+type deadlineSetter interface {
+	SetDeadline(time.Time) error
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+}
+
+// errDeadlinesNotSupported is returned when setting a deadline on a transport that doesn't support deadlines.
+var errDeadlinesNotSupported = errors.New("the transport doesn't support deadlines")
+
+type datagramConn struct {
+	transport  io.ReadWriteCloser
+	localAddr  net.Addr
+	remoteAddr net.Addr
+}
+
+var _ net.PacketConn = &datagramConn{}
+
+// NewDatagramConn wraps a message-oriented transport into a net.PacketConn,
+// which can be passed to Dial and Listen to run QUIC over transports other than UDP,
+// for example an overlay network or an existing message channel.
+// Every call to Write must send a single datagram, and every call to Read must return a single datagram.
+// Datagrams may be dropped or reordered, but never be split or merged.
+// The transport must be safe for concurrent use by a reader and one or more writers.
+//
+// The net.PacketConn is connected to a single peer:
+// all packets are sent to that peer, no matter which address is passed to WriteTo.
+// The local address and the address returned by ReadFrom are synthetic, and unique for every call to NewDatagramConn.
+// Connections are identified by their connection IDs, so QUIC doesn't depend on meaningful addresses.
+//
+// If the transport has SetDeadline, SetReadDeadline and SetWriteDeadline methods, they are used to implement deadlines.
+// Session.MigratePath requires read deadlines.
+func NewDatagramConn(transport io.ReadWriteCloser) net.PacketConn {
+	id := atomic.AddUint64(&datagramConnCounter, 1)
+	return &datagramConn{
+		transport:  transport,
+		localAddr:  &datagramAddr{id: id},
+		remoteAddr: &datagramAddr{id: id, remote: true},
+	}
+}
+
+func (c *datagramConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, err := c.transport.Read(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, c.remoteAddr, nil
+}
+
+func (c *datagramConn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	return c.transport.Write(b)
+}
+
+func (c *datagramConn) Close() error {
+	return c.transport.Close()
+}
+
+func (c *datagramConn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *datagramConn) SetDeadline(t time.Time) error {
+	if ds, ok := c.transport.(deadlineSetter); ok {
+		return ds.SetDeadline(t)
+	}
+	return errDeadlinesNotSupported
+}
+
+func (c *datagramConn) SetReadDeadline(t time.Time) error {
+	if ds, ok := c.transport.(deadlineSetter); ok {
+		return ds.SetReadDeadline(t)
+	}
+	return errDeadlinesNotSupported
+}
+
+func (c *datagramConn) SetWriteDeadline(t time.Time) error {
+	if ds, ok := c.transport.(deadlineSetter); ok {
+		return ds.SetWriteDeadline(t)
+	}
+	return errDeadlinesNotSupported
+}
@@ -0,0 +1,95 @@
+package quic
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type datagramTransport struct {
+	closed  bool
+	read    [][]byte
+	written [][]byte
+}
+
+func (t *datagramTransport) Read(b []byte) (int, error) {
+	if len(t.read) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, t.read[0])
+	t.read = t.read[1:]
+	return n, nil
+}
+
+func (t *datagramTransport) Write(b []byte) (int, error) {
+	t.written = append(t.written, append([]byte{}, b...))
+	return len(b), nil
+}
+
+func (t *datagramTransport) Close() error {
+	t.closed = true
+	return nil
+}
+
+var _ = Describe("Datagram Conn", func() {
+	It("reads datagrams", func() {
+		transport := &datagramTransport{read: [][]byte{[]byte("foo"), []byte("bar")}}
+		conn := NewDatagramConn(transport)
+		b := make([]byte, 10)
+		n, addr, err := conn.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("foo")))
+		Expect(addr).ToNot(Equal(conn.LocalAddr()))
+		n, addr2, err := conn.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("bar")))
+		Expect(addr2).To(Equal(addr))
+		_, _, err = conn.ReadFrom(b)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("writes datagrams, independent of the address", func() {
+		transport := &datagramTransport{}
+		conn := NewDatagramConn(transport)
+		_, err := conn.WriteTo([]byte("foo"), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = conn.WriteTo([]byte("bar"), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(transport.written).To(Equal([][]byte{[]byte("foo"), []byte("bar")}))
+	})
+
+	It("closes the transport", func() {
+		transport := &datagramTransport{}
+		Expect(NewDatagramConn(transport).Close()).To(Succeed())
+		Expect(transport.closed).To(BeTrue())
+	})
+
+	It("uses unique addresses", func() {
+		conn1 := NewDatagramConn(&datagramTransport{})
+		conn2 := NewDatagramConn(&datagramTransport{})
+		Expect(conn1.LocalAddr().Network()).To(Equal("datagram"))
+		Expect(conn1.LocalAddr().String()).ToNot(Equal(conn2.LocalAddr().String()))
+	})
+
+	It("uses the deadlines of the transport", func() {
+		c1, c2 := net.Pipe()
+		defer c2.Close()
+		conn := NewDatagramConn(c1)
+		Expect(conn.SetReadDeadline(time.Now().Add(-time.Second))).To(Succeed())
+		_, _, err := conn.ReadFrom(make([]byte, 10))
+		var nerr net.Error
+		Expect(errors.As(err, &nerr)).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeTrue())
+	})
+
+	It("errors when setting deadlines on transports that don't support them", func() {
+		conn := NewDatagramConn(&datagramTransport{})
+		Expect(conn.SetDeadline(time.Now())).To(MatchError(errDeadlinesNotSupported))
+		Expect(conn.SetReadDeadline(time.Now())).To(MatchError(errDeadlinesNotSupported))
+		Expect(conn.SetWriteDeadline(time.Now())).To(MatchError(errDeadlinesNotSupported))
+	})
+})
@@ -0,0 +1,25 @@
+package quic
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// setDSCP sets the DSCP on all packets sent on the UDP socket.
+// The DSCP occupies the upper 6 bits of the IPv4 TOS and the IPv6 Traffic Class.
+// Since a socket listening on an unspecified address might be a dual-stack socket,
+// both socket options are set. It succeeds if at least one of the two could be set.
+func setDSCP(c net.PacketConn, dscp uint8) error {
+	if dscp == 0 {
+		return nil
+	}
+	errIPv4 := ipv4.NewPacketConn(c).SetTOS(int(dscp) << 2)
+	errIPv6 := ipv6.NewPacketConn(c).SetTrafficClass(int(dscp) << 2)
+	if errIPv4 != nil && errIPv6 != nil {
+		return fmt.Errorf("setting the DSCP failed: %s (IPv4), %s (IPv6)", errIPv4, errIPv6)
+	}
+	return nil
+}
@@ -16,6 +16,8 @@ type frameSorter struct {
 	queue   map[protocol.ByteCount]frameSorterEntry
 	readPos protocol.ByteCount
 	gaps    *utils.ByteIntervalList
+	// bufferedBytes is the size of the buffers of all frames in the queue
+	bufferedBytes protocol.ByteCount
 }
 
 var errDuplicateStreamData = errors.New("duplicate stream data")
@@ -79,7 +81,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
-			delete(s.queue, pos)
+			s.deleteEntry(pos, oldEntry)
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -161,9 +163,15 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 	}
 
 	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.bufferedBytes += protocol.ByteCount(cap(data))
 	return nil
 }
 
+func (s *frameSorter) deleteEntry(pos protocol.ByteCount, entry frameSorterEntry) {
+	delete(s.queue, pos)
+	s.bufferedBytes -= protocol.ByteCount(cap(entry.Data))
+}
+
 func (s *frameSorter) findStartGap(offset protocol.ByteCount) (*utils.ByteIntervalElement, bool) {
 	for gap := s.gaps.Front(); gap != nil; gap = gap.Next() {
 		if offset >= gap.Value.Start && offset <= gap.Value.End {
@@ -196,7 +204,7 @@ func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 			break
 		}
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
-		delete(s.queue, pos)
+		s.deleteEntry(pos, oldEntry)
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
@@ -209,7 +217,7 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	if !ok {
 		return s.readPos, nil, nil
 	}
-	delete(s.queue, s.readPos)
+	s.deleteEntry(s.readPos, entry)
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
 	if s.gaps.Front().Value.End <= s.readPos {
@@ -218,6 +226,12 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	return offset, entry.Data, entry.DoneCb
 }
 
+// BufferedBytes returns the size of the buffers used by all queued frames.
+// This can be larger than the amount of data queued, since frames might keep the whole packet buffer alive.
+func (s *frameSorter) BufferedBytes() protocol.ByteCount {
+	return s.bufferedBytes
+}
+
 // HasMoreData says if there is any more data queued at *any* offset.
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
@@ -123,6 +123,24 @@ var _ = Describe("frame sorter", func() {
 		Expect(s.HasMoreData()).To(BeFalse())
 	})
 
+	It("counts the buffered bytes", func() {
+		Expect(s.BufferedBytes()).To(BeZero())
+		Expect(s.Push(make([]byte, 3, 10), 3, nil)).To(Succeed())
+		Expect(s.BufferedBytes()).To(Equal(protocol.ByteCount(10)))
+		Expect(s.Push(make([]byte, 3, 20), 0, nil)).To(Succeed())
+		Expect(s.BufferedBytes()).To(Equal(protocol.ByteCount(30)))
+		// duplicate data doesn't change the buffered bytes
+		Expect(s.Push(make([]byte, 3, 20), 0, nil)).To(Succeed())
+		Expect(s.BufferedBytes()).To(Equal(protocol.ByteCount(30)))
+		// replace the frame at offset 3 with a longer frame
+		Expect(s.Push(make([]byte, 5, 15), 3, nil)).To(Succeed())
+		Expect(s.BufferedBytes()).To(Equal(protocol.ByteCount(35)))
+		s.Pop()
+		Expect(s.BufferedBytes()).To(Equal(protocol.ByteCount(15)))
+		s.Pop()
+		Expect(s.BufferedBytes()).To(BeZero())
+	})
+
 	Context("Gap handling", func() {
 		var dataCounter uint8
 
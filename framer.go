@@ -16,6 +16,7 @@ type framer interface {
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
+	SetStreamPriority(protocol.StreamID, uint8)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
 }
 
@@ -27,6 +28,8 @@ type framerI struct {
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   []protocol.StreamID
+	// priorities only contains streams with a non-zero weight
+	priorities map[protocol.StreamID]uint8
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -41,6 +44,7 @@ func newFramer(
 	return &framerI{
 		streamGetter:  streamGetter,
 		activeStreams: make(map[protocol.StreamID]struct{}),
+		priorities:    make(map[protocol.StreamID]uint8),
 		version:       v,
 	}
 }
@@ -90,6 +94,34 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	f.mutex.Unlock()
 }
 
+func (f *framerI) SetStreamPriority(id protocol.StreamID, weight uint8) {
+	f.mutex.Lock()
+	if weight == 0 {
+		delete(f.priorities, id)
+	} else {
+		f.priorities[id] = weight
+	}
+	f.mutex.Unlock()
+}
+
+// nextStream returns the index of the stream in the first n entries of the stream queue that should be served next.
+// This is the first stream with the highest weight.
+// Since streams are re-queued at the end, streams with the same weight are served round-robin.
+func (f *framerI) nextStream(n int) int {
+	if len(f.priorities) == 0 {
+		return 0
+	}
+	var index int
+	var maxWeight uint8
+	for i, id := range f.streamQueue[:n] {
+		if weight := f.priorities[id]; weight > maxWeight {
+			index = i
+			maxWeight = weight
+		}
+	}
+	return index
+}
+
 func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
 	var length protocol.ByteCount
 	var lastFrame *ackhandler.Frame
@@ -100,8 +132,15 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		// Streams that were re-queued during this call are appended at the end of the queue.
+		// Don't consider them, so that we only dequeue data from each stream once per packet.
+		index := f.nextStream(numActiveStreams - i)
+		id := f.streamQueue[index]
+		if index == 0 {
+			f.streamQueue = f.streamQueue[1:]
+		} else {
+			f.streamQueue = append(f.streamQueue[:index], f.streamQueue[index+1:]...)
+		}
 		// This should never return an error. Better check it anyway.
 		// The stream will only be in the streamQueue, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
@@ -105,6 +105,7 @@ func main() {
 		runner,
 		config,
 		false,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
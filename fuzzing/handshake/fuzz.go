@@ -215,6 +215,7 @@ func Fuzz(data []byte) int {
 			NextProtos:   []string{alpn},
 		},
 		enable0RTTServer,
+		nil,
 		utils.NewRTTStats(),
 		nil,
 		utils.DefaultLogger.WithPrefix("server"),
@@ -9,6 +9,7 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 func Fuzz(data []byte) int {
@@ -17,7 +18,7 @@ func Fuzz(data []byte) int {
 	}
 	seed := binary.BigEndian.Uint64(data[:8])
 	data = data[8:]
-	tg, err := handshake.NewTokenGenerator(rand.New(rand.NewSource(int64(seed))))
+	tg, err := handshake.NewTokenGenerator(rand.New(rand.NewSource(int64(seed))), utils.DefaultClock{})
 	if err != nil {
 		panic(err)
 	}
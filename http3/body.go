@@ -3,6 +3,7 @@ package http3
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/lucas-clemente/quic-go"
 )
@@ -18,6 +19,12 @@ type body struct {
 	reqDoneClosed bool
 
 	onFrameError func()
+	// only set for the http.Response
+	// It is called for a HEADERS frame following the body, i.e. for the trailers,
+	// and must read the header block from the stream.
+	onTrailers func(*headersFrame) error
+	// set when the HEADERS frame containing the trailers was received, no frames may follow it
+	receivedTrailers bool
 
 	bytesRemainingInFrame uint64
 }
@@ -31,10 +38,11 @@ func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	}
 }
 
-func newResponseBody(str quic.Stream, done chan<- struct{}, onFrameError func()) *body {
+func newResponseBody(str quic.Stream, done chan<- struct{}, onFrameError func(), onTrailers func(*headersFrame) error) *body {
 	return &body{
 		str:          str,
 		onFrameError: onFrameError,
+		onTrailers:   onTrailers,
 		reqDone:      done,
 	}
 }
@@ -55,9 +63,23 @@ func (r *body) readImpl(b []byte) (int, error) {
 			if err != nil {
 				return 0, err
 			}
+			if r.receivedTrailers {
+				r.onFrameError()
+				return 0, fmt.Errorf("peer sent a frame after the trailers: %T", frame)
+			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				r.receivedTrailers = true
+				if r.onTrailers != nil {
+					if err := r.onTrailers(f); err != nil {
+						return 0, err
+					}
+					continue
+				}
+				// skip HEADERS frames (i.e. trailers), including the header block
+				if _, err := io.CopyN(ioutil.Discard, r.str, int64(f.Length)); err != nil {
+					return 0, err
+				}
 				continue
 			case *dataFrame:
 				r.bytesRemainingInFrame = f.Length
@@ -65,7 +87,8 @@ func (r *body) readImpl(b []byte) (int, error) {
 			default:
 				r.onFrameError()
 				// parseNextFrame skips over unknown frame types
-				// Therefore, this condition is only entered when we parsed another known frame type.
+				// Therefore, this condition is only entered when we parsed another known frame type,
+				// or a frame type that is not allowed on a request stream (e.g. a reserved frame type).
 				return 0, fmt.Errorf("peer sent an unexpected frame: %T", f)
 			}
 		}
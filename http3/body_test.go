@@ -2,12 +2,15 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -68,7 +71,7 @@ var _ = Describe("Body", func() {
 					rb = newRequestBody(str, errorCb)
 				case bodyTypeResponse:
 					reqDone = make(chan struct{})
-					rb = newResponseBody(str, reqDone, errorCb)
+					rb = newResponseBody(str, reqDone, errorCb, nil)
 				}
 			})
 
@@ -130,16 +133,52 @@ var _ = Describe("Body", func() {
 				Expect(b[:n]).To(Equal([]byte("bar")))
 			})
 
-			It("skips HEADERS frames", func() {
+			It("skips HEADERS frames, including the header block", func() {
+				buf.Write(getDataFrame([]byte("foobar")))
+				(&headersFrame{Length: 6}).Write(buf)
+				buf.Write([]byte("lorem."))
+				data, err := ioutil.ReadAll(rb)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(errorCbCalled).To(BeFalse())
+			})
+
+			It("errors on DATA frames following the trailers, and calls the error callback", func() {
 				buf.Write(getDataFrame([]byte("foo")))
-				(&headersFrame{Length: 10}).Write(buf)
-				buf.Write(make([]byte, 10))
+				(&headersFrame{Length: 6}).Write(buf)
+				buf.Write([]byte("lorem."))
 				buf.Write(getDataFrame([]byte("bar")))
-				b := make([]byte, 6)
-				n, err := io.ReadFull(rb, b)
+				_, err := ioutil.ReadAll(rb)
+				Expect(err).To(MatchError("peer sent a frame after the trailers: *http3.dataFrame"))
+				Expect(errorCbCalled).To(BeTrue())
+			})
+
+			It("errors on HEADERS frames following the trailers, and calls the error callback", func() {
+				(&headersFrame{Length: 6}).Write(buf)
+				buf.Write([]byte("lorem."))
+				(&headersFrame{Length: 6}).Write(buf)
+				buf.Write([]byte("ipsum."))
+				_, err := rb.Read([]byte{0})
+				Expect(err).To(MatchError("peer sent a frame after the trailers: *http3.headersFrame"))
+				Expect(errorCbCalled).To(BeTrue())
+			})
+
+			It("skips unknown frames interleaved with DATA frames", func() {
+				writeGreaseFrame := func(l int) {
+					utils.WriteVarInt(buf, 0x1f*1337+0x21) // a reserved frame type
+					utils.WriteVarInt(buf, uint64(l))
+					buf.Write(bytes.Repeat([]byte{0x42}, l))
+				}
+				writeGreaseFrame(10)
+				buf.Write(getDataFrame([]byte("foo")))
+				writeGreaseFrame(0)
+				writeGreaseFrame(100)
+				buf.Write(getDataFrame([]byte("bar")))
+				writeGreaseFrame(5)
+				data, err := ioutil.ReadAll(rb)
 				Expect(err).ToNot(HaveOccurred())
-				Expect(n).To(Equal(6))
-				Expect(b).To(Equal([]byte("foobar")))
+				Expect(data).To(Equal([]byte("foobar")))
+				Expect(errorCbCalled).To(BeFalse())
 			})
 
 			It("errors when it can't parse the frame", func() {
@@ -155,6 +194,36 @@ var _ = Describe("Body", func() {
 				Expect(errorCbCalled).To(BeTrue())
 			})
 
+			for _, f := range []struct {
+				name      string
+				frameType uint64
+				expected  frame
+			}{
+				{name: "CANCEL_PUSH", frameType: 0x3, expected: &cancelPushFrame{}},
+				{name: "PUSH_PROMISE", frameType: 0x5, expected: &pushPromiseFrame{}},
+				{name: "DUPLICATE_PUSH", frameType: 0xe, expected: &duplicatePushFrame{}},
+				{name: "reserved (0x2)", frameType: 0x2, expected: &reservedFrame{Type: 0x2}},
+				{name: "reserved (0x6)", frameType: 0x6, expected: &reservedFrame{Type: 0x6}},
+				{name: "reserved (0x8)", frameType: 0x8, expected: &reservedFrame{Type: 0x8}},
+				{name: "reserved (0x9)", frameType: 0x9, expected: &reservedFrame{Type: 0x9}},
+			} {
+				f := f
+
+				It(fmt.Sprintf("errors on %s frames, and calls the error callback", f.name), func() {
+					buf.Write(getDataFrame([]byte("foo")))
+					utils.WriteVarInt(buf, f.frameType)
+					utils.WriteVarInt(buf, 1)
+					buf.WriteByte(0)
+					data := make([]byte, 3)
+					_, err := io.ReadFull(rb, data)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foo")))
+					_, err = rb.Read([]byte{0})
+					Expect(err).To(MatchError(fmt.Sprintf("peer sent an unexpected frame: %T", f.expected)))
+					Expect(errorCbCalled).To(BeTrue())
+				})
+			}
+
 			if bodyType == bodyTypeResponse {
 				It("closes the reqDone channel when Read errors", func() {
 					buf.Write([]byte("invalid"))
@@ -183,6 +252,30 @@ var _ = Describe("Body", func() {
 					Expect(reqDone).To(BeClosed())
 					Expect(rb.Close()).To(Succeed())
 				})
+
+				It("passes the trailers to the callback", func() {
+					var trailers []*headersFrame
+					rb.onTrailers = func(hf *headersFrame) error {
+						trailers = append(trailers, hf)
+						_, err := io.CopyN(ioutil.Discard, str, int64(hf.Length))
+						return err
+					}
+					buf.Write(getDataFrame([]byte("foobar")))
+					(&headersFrame{Length: 6}).Write(buf)
+					buf.Write([]byte("lorem."))
+					data, err := ioutil.ReadAll(rb)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foobar")))
+					Expect(trailers).To(Equal([]*headersFrame{{Length: 6}}))
+				})
+
+				It("returns the error from the trailers callback", func() {
+					rb.onTrailers = func(*headersFrame) error { return errors.New("invalid trailers") }
+					(&headersFrame{Length: 6}).Write(buf)
+					_, err := rb.Read([]byte{0})
+					Expect(err).To(MatchError("invalid trailers"))
+					Expect(reqDone).To(BeClosed())
+				})
 			}
 		})
 	}
@@ -2,13 +2,20 @@ package http3
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -29,9 +36,37 @@ var defaultQuicConfig = &quic.Config{
 
 var dialAddr = quic.DialAddrEarly
 
+// max1xxResponses is the number of informational responses accepted before the final response,
+// unless they're passed to a httptrace.ClientTrace. This is the same limit that net/http uses.
+const max1xxResponses = 5
+
+// A sessionClosedError is returned by the client when a request failed because the QUIC session was closed.
+type sessionClosedError struct {
+	err error
+	// requestSent is set if (parts of) the request might have been sent before the session was closed
+	requestSent bool
+}
+
+func (e *sessionClosedError) Error() string { return e.err.Error() }
+func (e *sessionClosedError) Unwrap() error { return e.err }
+
+// errResponseHeaderTimeout is returned when the response headers aren't received within the ResponseHeaderTimeout.
+var errResponseHeaderTimeout net.Error = &responseHeaderTimeoutError{}
+
+type responseHeaderTimeoutError struct{}
+
+func (responseHeaderTimeoutError) Error() string   { return "http3: timeout awaiting response headers" }
+func (responseHeaderTimeoutError) Timeout() bool   { return true }
+func (responseHeaderTimeoutError) Temporary() bool { return true }
+
 type roundTripperOpts struct {
 	DisableCompression bool
 	MaxHeaderBytes     int64
+	AdditionalSettings map[uint64]uint64
+	OnSettings         func(map[uint64]uint64)
+	OnHeaderStats      func(HeaderStats)
+
+	ResponseHeaderTimeout time.Duration
 }
 
 // client is a HTTP3 client doing requests
@@ -50,6 +85,8 @@ type client struct {
 
 	hostname string
 	session  quic.EarlySession
+	// closed is set when dialing failed, or when the QUIC session was closed
+	closed utils.AtomicBool
 
 	logger utils.Logger
 }
@@ -77,7 +114,7 @@ func newClient(
 	return &client{
 		hostname:      authorityAddr("https", hostname),
 		tlsConf:       tlsConf,
-		requestWriter: newRequestWriter(logger),
+		requestWriter: newRequestWriter(opts.OnHeaderStats, logger),
 		decoder:       qpack.NewDecoder(func(hf qpack.HeaderField) {}),
 		config:        quicConfig,
 		opts:          opts,
@@ -94,6 +131,7 @@ func (c *client) dial() error {
 		c.session, err = dialAddr(c.hostname, c.tlsConf, c.config)
 	}
 	if err != nil {
+		c.closed.Set(true)
 		return err
 	}
 
@@ -101,6 +139,7 @@ func (c *client) dial() error {
 	go func() {
 		if err := c.setupSession(); err != nil {
 			c.logger.Debugf("Setting up session failed: %s", err)
+			c.closed.Set(true)
 			c.session.CloseWithError(quic.ErrorCode(errorInternalError), "")
 		}
 	}()
@@ -118,21 +157,82 @@ func (c *client) setupSession() error {
 	// write the type byte
 	buf.Write([]byte{0x0})
 	// send the SETTINGS frame
-	(&settingsFrame{}).Write(buf)
+	(&settingsFrame{settings: c.opts.AdditionalSettings}).Write(buf)
 	if _, err := str.Write(buf.Bytes()); err != nil {
 		return err
 	}
 
+	go c.handleUnidirectionalStreams()
 	return nil
 }
 
+func (c *client) handleUnidirectionalStreams() {
+	var rcvdControlStream int32 // used atomically
+	for {
+		str, err := c.session.AcceptUniStream(context.Background())
+		if err != nil {
+			c.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			c.closed.Set(true)
+			return
+		}
+
+		go func(str quic.ReceiveStream) {
+			streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+			if err != nil {
+				c.logger.Debugf("Reading stream type on stream %d failed: %s", str.StreamID(), err)
+				return
+			}
+			switch streamType {
+			case streamTypeControlStream:
+				// The peer must only open a single control stream.
+				if !atomic.CompareAndSwapInt32(&rcvdControlStream, 0, 1) {
+					c.session.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+					return
+				}
+			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+				// Our QPACK implementation doesn't use the dynamic table yet.
+				// We don't need to read these streams, since the peer can't insert any entries.
+				return
+			case streamTypePushStream:
+				// We never send a MAX_PUSH_ID frame, so the server is not allowed to push.
+				c.session.CloseWithError(quic.ErrorCode(errorIDError), "")
+				return
+			default:
+				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+				return
+			}
+			f, err := parseNextFrame(str)
+			if err != nil {
+				c.session.CloseWithError(quic.ErrorCode(errorFrameError), "")
+				return
+			}
+			sf, ok := f.(*settingsFrame)
+			if !ok {
+				c.session.CloseWithError(quic.ErrorCode(errorMissingSettings), "")
+				return
+			}
+			if c.opts.OnSettings != nil {
+				c.opts.OnSettings(sf.settings)
+			}
+			// We don't make use of any other frames sent on the control stream (e.g. GOAWAY) yet.
+		}(str)
+	}
+}
+
 func (c *client) Close() error {
+	c.closed.Set(true)
 	if c.session == nil {
 		return nil
 	}
 	return c.session.CloseWithError(quic.ErrorCode(errorNoError), "")
 }
 
+// isClosed says if the client can't be used for new requests any more,
+// either because dialing failed, or because the QUIC session was closed.
+func (c *client) isClosed() bool {
+	return c.closed.Get()
+}
+
 func (c *client) maxHeaderBytes() uint64 {
 	if c.opts.MaxHeaderBytes <= 0 {
 		return defaultMaxResponseHeaderBytes
@@ -171,7 +271,7 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	str, err := c.session.OpenStreamSync(req.Context())
 	if err != nil {
-		return nil, err
+		return nil, c.checkSessionClosed(err, false)
 	}
 
 	// Request Cancellation:
@@ -198,28 +298,92 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 			if rerr.err != nil {
 				reason = rerr.err.Error()
 			}
+			c.closed.Set(true)
 			c.session.CloseWithError(quic.ErrorCode(rerr.connErr), reason)
+			return nil, rerr.err
 		}
+		return nil, c.checkSessionClosed(rerr.err, true)
 	}
-	return rsp, rerr.err
+	return rsp, nil
 }
 
-func (c *client) doRequest(
-	req *http.Request,
-	str quic.Stream,
-	reqDone chan struct{},
-) (*http.Response, requestError) {
-	var requestGzip bool
-	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
-		requestGzip = true
+// readTrailers reads the header block of the HEADERS frame following the response body,
+// and adds the trailers to the response.
+func (c *client) readTrailers(str quic.Stream, hf *headersFrame, res *http.Response) error {
+	if hf.Length > c.maxHeaderBytes() {
+		return fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, c.maxHeaderBytes())
 	}
-	if err := c.requestWriter.WriteRequest(str, req, requestGzip); err != nil {
-		return nil, newStreamError(errorInternalError, err)
+	headerBlock := make([]byte, hf.Length)
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		return err
+	}
+	hfs, err := c.decoder.DecodeFull(headerBlock)
+	if err != nil {
+		return err
+	}
+	if c.opts.OnHeaderStats != nil {
+		c.opts.OnHeaderStats(HeaderStats{
+			StreamID:         str.StreamID(),
+			CompressedSize:   len(headerBlock),
+			UncompressedSize: headerListSize(hfs),
+		})
+	}
+	if res.Trailer == nil {
+		res.Trailer = http.Header{}
+	}
+	for _, hf := range hfs {
+		if strings.HasPrefix(hf.Name, ":") {
+			return fmt.Errorf("invalid pseudo header in trailers: %s", hf.Name)
+		}
+		res.Trailer.Add(hf.Name, hf.Value)
+	}
+	return nil
+}
+
+// responseHeaderError is called when reading the response headers failed.
+// If the ResponseHeaderTimeout expired, the request stream is reset.
+func (c *client) responseHeaderError(str quic.Stream, code errorCode, err error) requestError {
+	var nerr net.Error
+	if c.opts.ResponseHeaderTimeout > 0 && errors.As(err, &nerr) && nerr.Timeout() {
+		str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+		return newStreamError(errorRequestCanceled, errResponseHeaderTimeout)
+	}
+	return newStreamError(code, err)
+}
+
+// checkSessionClosed checks if a request failed because the QUIC session was closed.
+// In that case, the client is marked as closed, and a sessionClosedError is returned,
+// such that the RoundTripper can decide if the request can be retried on a new connection.
+func (c *client) checkSessionClosed(err error, requestSent bool) error {
+	if !c.isSessionClosed(err) {
+		return err
+	}
+	c.closed.Set(true)
+	return &sessionClosedError{err: err, requestSent: requestSent}
+}
+
+// isSessionClosed says if err was caused by the QUIC session being closed.
+// Not every cause is reported as a quic.TransportError with a matching error code
+// (e.g. a stateless reset or a handshake timeout), so the session's context is checked as well.
+func (c *client) isSessionClosed(err error) bool {
+	// When the session is closed, all streams return the error the session was closed with.
+	var transportErr *quic.TransportError
+	if errors.As(err, &transportErr) || errors.Is(err, quic.ErrHandshakeTimeout) {
+		return true
+	}
+	select {
+	case <-c.session.Context().Done():
+		return true
+	default:
+		return false
 	}
+}
 
+// readResponseHeaders reads and decodes a HEADERS frame containing the response headers.
+func (c *client) readResponseHeaders(str quic.Stream) ([]qpack.HeaderField, requestError) {
 	frame, err := parseNextFrame(str)
 	if err != nil {
-		return nil, newStreamError(errorFrameError, err)
+		return nil, c.responseHeaderError(str, errorFrameError, err)
 	}
 	hf, ok := frame.(*headersFrame)
 	if !ok {
@@ -230,34 +394,101 @@ func (c *client) doRequest(
 	}
 	headerBlock := make([]byte, hf.Length)
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
-		return nil, newStreamError(errorRequestIncomplete, err)
+		return nil, c.responseHeaderError(str, errorRequestIncomplete, err)
 	}
 	hfs, err := c.decoder.DecodeFull(headerBlock)
 	if err != nil {
 		// TODO: use the right error code
 		return nil, newConnError(errorGeneralProtocolError, err)
 	}
+	if c.opts.OnHeaderStats != nil {
+		c.opts.OnHeaderStats(HeaderStats{
+			StreamID:         str.StreamID(),
+			CompressedSize:   len(headerBlock),
+			UncompressedSize: headerListSize(hfs),
+		})
+	}
+	return hfs, requestError{}
+}
 
-	res := &http.Response{
-		Proto:      "HTTP/3",
-		ProtoMajor: 3,
-		Header:     http.Header{},
+func (c *client) doRequest(
+	req *http.Request,
+	str quic.Stream,
+	reqDone chan struct{},
+) (*http.Response, requestError) {
+	var requestGzip bool
+	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
+		requestGzip = true
 	}
-	for _, hf := range hfs {
-		switch hf.Name {
-		case ":status":
-			status, err := strconv.Atoi(hf.Value)
-			if err != nil {
-				return nil, newStreamError(errorGeneralProtocolError, errors.New("malformed non-numeric status pseudo header"))
+	if err := c.requestWriter.WriteRequest(str, req, requestGzip); err != nil {
+		return nil, newStreamError(errorInternalError, err)
+	}
+
+	if c.opts.ResponseHeaderTimeout > 0 {
+		str.SetReadDeadline(time.Now().Add(c.opts.ResponseHeaderTimeout))
+	}
+	trace := httptrace.ContextClientTrace(req.Context())
+	traceGot1xx := trace != nil && trace.Got1xxResponse != nil
+	var num1xx int
+	var res *http.Response
+	for {
+		hfs, rerr := c.readResponseHeaders(str)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+		res = &http.Response{
+			Proto:      "HTTP/3",
+			ProtoMajor: 3,
+			Header:     http.Header{},
+		}
+		for _, hf := range hfs {
+			switch hf.Name {
+			case ":status":
+				status, err := strconv.Atoi(hf.Value)
+				if err != nil {
+					return nil, newStreamError(errorGeneralProtocolError, errors.New("malformed non-numeric status pseudo header"))
+				}
+				res.StatusCode = status
+				res.Status = hf.Value + " " + http.StatusText(status)
+			default:
+				res.Header.Add(hf.Name, hf.Value)
+			}
+		}
+		// Informational responses, e.g. 103 Early Hints, precede the final response.
+		if !isInformationalStatus(res.StatusCode) {
+			break
+		}
+		if !traceGot1xx {
+			num1xx++
+			if num1xx > max1xxResponses {
+				return nil, newStreamError(errorExcessiveLoad, errors.New("too many 1xx informational responses"))
+			}
+			continue
+		}
+		if err := trace.Got1xxResponse(res.StatusCode, textproto.MIMEHeader(res.Header)); err != nil {
+			return nil, newStreamError(errorRequestCanceled, err)
+		}
+	}
+	if c.opts.ResponseHeaderTimeout > 0 {
+		// The timeout only applies to the response headers, not to the body.
+		str.SetReadDeadline(time.Time{})
+	}
+	// Announced trailers are added with a nil value, like net/http does.
+	for _, v := range res.Header.Values("Trailer") {
+		for _, key := range strings.Split(v, ",") {
+			if key = strings.TrimSpace(key); key == "" {
+				continue
+			}
+			if res.Trailer == nil {
+				res.Trailer = http.Header{}
 			}
-			res.StatusCode = status
-			res.Status = hf.Value + " " + http.StatusText(status)
-		default:
-			res.Header.Add(hf.Name, hf.Value)
+			res.Trailer[http.CanonicalHeaderKey(key)] = nil
 		}
 	}
 	respBody := newResponseBody(str, reqDone, func() {
 		c.session.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+	}, func(hf *headersFrame) error {
+		return c.readTrailers(str, hf, res)
 	})
 	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
 		res.Header.Del("Content-Encoding")
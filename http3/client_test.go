@@ -8,19 +8,28 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
-	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/marten-seemann/qpack"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
 var _ = Describe("Client", func() {
 	var (
 		client       *client
@@ -128,6 +137,7 @@ var _ = Describe("Client", func() {
 		}
 		_, err := client.RoundTrip(req)
 		Expect(err).To(MatchError(testErr))
+		Expect(client.isClosed()).To(BeTrue())
 	})
 
 	It("errors if it can't open a stream", func() {
@@ -135,6 +145,7 @@ var _ = Describe("Client", func() {
 		client = newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		session := mockquic.NewMockEarlySession(mockCtrl)
 		session.EXPECT().OpenUniStream().Return(nil, testErr).MaxTimes(1)
+		session.EXPECT().Context().Return(context.Background()).AnyTimes()
 		session.EXPECT().HandshakeComplete().Return(handshakeCtx).MaxTimes(1)
 		session.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr).MaxTimes(1)
 		session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
@@ -146,12 +157,153 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("marks the client as closed when a request fails because the session was closed", func() {
+		closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+		client = newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		session := mockquic.NewMockEarlySession(mockCtrl)
+		session.EXPECT().OpenUniStream().Return(nil, closeErr).MaxTimes(1)
+		session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+		session.EXPECT().OpenStreamSync(context.Background()).Return(nil, closeErr)
+		session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
+		dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+			return session, nil
+		}
+		_, err := client.RoundTrip(req)
+		Expect(err).To(BeAssignableToTypeOf(&sessionClosedError{}))
+		Expect(errors.Unwrap(err)).To(Equal(closeErr))
+		Expect(client.isClosed()).To(BeTrue())
+	})
+
+	It("marks the client as closed when the session was closed by a stateless reset", func() {
+		// a stateless reset isn't reported as a quic.TransportError
+		resetErr := errors.New("received a stateless reset")
+		client = newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		session := mockquic.NewMockEarlySession(mockCtrl)
+		session.EXPECT().OpenUniStream().Return(nil, resetErr).MaxTimes(1)
+		session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+		session.EXPECT().OpenStreamSync(context.Background()).Return(nil, resetErr)
+		session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		session.EXPECT().Context().Return(ctx)
+		dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+			return session, nil
+		}
+		_, err := client.RoundTrip(req)
+		Expect(err).To(BeAssignableToTypeOf(&sessionClosedError{}))
+		Expect(errors.Unwrap(err)).To(Equal(resetErr))
+		Expect(client.isClosed()).To(BeTrue())
+	})
+
 	It("closes correctly if session was not created", func() {
 		client = newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		err := client.Close()
 		Expect(err).ToNot(HaveOccurred())
 	})
 
+	Context("control stream handling", func() {
+		var (
+			sess     *mockquic.MockEarlySession
+			settings chan map[uint64]uint64
+		)
+
+		getUniStream := func(data []byte) *mockquic.MockStream {
+			buf := bytes.NewBuffer(data)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			return str
+		}
+
+		BeforeEach(func() {
+			settings = make(chan map[uint64]uint64, 1)
+			client = newClient("localhost:1337", nil, &roundTripperOpts{
+				AdditionalSettings: map[uint64]uint64{0x42: 1337},
+				OnSettings:         func(s map[uint64]uint64) { settings <- s },
+			}, nil, nil)
+			sess = mockquic.NewMockEarlySession(mockCtrl)
+			client.session = sess
+		})
+
+		runUniStreams := func(strs ...quic.ReceiveStream) {
+			for _, str := range strs {
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+			}
+			done := make(chan struct{})
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				close(done)
+				return nil, errors.New("done")
+			})
+			client.handleUnidirectionalStreams()
+			Eventually(done).Should(BeClosed())
+		}
+
+		It("sends the additional settings", func() {
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStrData := &bytes.Buffer{}
+			controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(controlStrData.Write)
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+			Expect(client.setupSession()).To(Succeed())
+			streamType, err := controlStrData.ReadByte()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+			frame, err := parseNextFrame(controlStrData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&settingsFrame{settings: map[uint64]uint64{0x42: 1337}}))
+		})
+
+		It("passes the server's settings to the callback, including GREASE settings", func() {
+			buf := bytes.NewBuffer([]byte{streamTypeControlStream})
+			// 0x1f * N + 0x21 is reserved for GREASE
+			(&settingsFrame{settings: map[uint64]uint64{0x1337: 42, 0x1f*5 + 0x21: 7}}).Write(buf)
+			runUniStreams(getUniStream(buf.Bytes()))
+			Eventually(settings).Should(Receive(Equal(map[uint64]uint64{0x1337: 42, 0x1f*5 + 0x21: 7})))
+		})
+
+		It("closes the connection when the first frame on the control stream is not a SETTINGS frame", func() {
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorMissingSettings), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+			buf := bytes.NewBuffer([]byte{streamTypeControlStream})
+			(&goAwayFrame{StreamID: 4}).Write(buf)
+			runUniStreams(getUniStream(buf.Bytes()))
+			Eventually(closed).Should(BeClosed())
+			Expect(settings).ToNot(Receive())
+		})
+
+		It("closes the connection when the server opens a second control stream", func() {
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream").Do(func(quic.ErrorCode, string) { close(closed) })
+			buf := bytes.NewBuffer([]byte{streamTypeControlStream})
+			(&settingsFrame{}).Write(buf)
+			runUniStreams(getUniStream(buf.Bytes()), getUniStream(buf.Bytes()))
+			Eventually(closed).Should(BeClosed())
+			Eventually(settings).Should(Receive())
+		})
+
+		It("closes the connection when the server opens a push stream", func() {
+			closed := make(chan struct{})
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+			runUniStreams(getUniStream([]byte{streamTypePushStream}))
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("ignores QPACK streams", func() {
+			runUniStreams(
+				getUniStream([]byte{streamTypeQPACKEncoderStream}),
+				getUniStream([]byte{streamTypeQPACKDecoderStream}),
+			)
+		})
+
+		It("cancels reading of unknown streams", func() {
+			cancelled := make(chan struct{})
+			str := getUniStream([]byte{0x21})
+			str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError)).Do(func(quic.ErrorCode) { close(cancelled) })
+			runUniStreams(str)
+			Eventually(cancelled).Should(BeClosed())
+		})
+	})
+
 	Context("Doing requests", func() {
 		var (
 			request *http.Request
@@ -185,6 +337,8 @@ var _ = Describe("Client", func() {
 			str = mockquic.NewMockStream(mockCtrl)
 			sess = mockquic.NewMockEarlySession(mockCtrl)
 			sess.EXPECT().OpenUniStream().Return(controlStr, nil).MaxTimes(1)
+			sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 			dialAddr = func(hostname string, _ *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
 				return sess, nil
 			}
@@ -214,7 +368,7 @@ var _ = Describe("Client", func() {
 
 		It("returns a response", func() {
 			rspBuf := &bytes.Buffer{}
-			rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+			rw := newTestResponseWriter(rspBuf)
 			rw.WriteHeader(418)
 			rw.Flush()
 
@@ -234,6 +388,218 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		Context("response header timeout", func() {
+			BeforeEach(func() {
+				client.opts.ResponseHeaderTimeout = 100 * time.Millisecond
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+			})
+
+			It("resets the stream if the response headers don't arrive in time", func() {
+				var deadline time.Time
+				str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { deadline = t })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					Expect(deadline).To(BeTemporally("~", time.Now().Add(100*time.Millisecond), 50*time.Millisecond))
+					return 0, &timeoutError{}
+				})
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("http3: timeout awaiting response headers"))
+				Expect(err.(net.Error).Timeout()).To(BeTrue())
+			})
+
+			It("doesn't apply the timeout to the response body", func() {
+				rspBuf := &bytes.Buffer{}
+				rw := newTestResponseWriter(rspBuf)
+				rw.WriteHeader(200)
+				rw.Flush()
+				gomock.InOrder(
+					str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { Expect(t).ToNot(BeZero()) }),
+					str.EXPECT().SetReadDeadline(time.Time{}),
+				)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+			})
+		})
+
+		It("skips informational responses", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newTestResponseWriter(rspBuf)
+			rw.Header().Set("Link", "</style.css>; rel=preload")
+			rw.WriteHeader(http.StatusEarlyHints)
+			rw.Header().Del("Link")
+			rw.WriteHeader(http.StatusTeapot)
+			rw.Flush()
+
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			var informational []int
+			trace := &httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					informational = append(informational, code)
+					Expect(header.Get("Link")).To(Equal("</style.css>; rel=preload"))
+					return nil
+				},
+			}
+			rsp, err := client.RoundTrip(request.WithContext(httptrace.WithClientTrace(context.Background(), trace)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(418))
+			Expect(rsp.Header).ToNot(HaveKey("Link"))
+			Expect(informational).To(Equal([]int{103}))
+		})
+
+		Context("limiting informational responses", func() {
+			// expectInformationalResponses makes the server send n 103 responses, followed by the final response
+			expectInformationalResponses := func(n int) {
+				rspBuf := &bytes.Buffer{}
+				rw := newTestResponseWriter(rspBuf)
+				for i := 0; i < n; i++ {
+					rw.WriteHeader(http.StatusEarlyHints)
+				}
+				rw.WriteHeader(http.StatusTeapot)
+				rw.Flush()
+
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil),
+				)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return rspBuf.Read(p)
+				}).AnyTimes()
+			}
+
+			It("accepts up to 5 informational responses", func() {
+				expectInformationalResponses(5)
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(418))
+			})
+
+			It("errors when receiving too many informational responses", func() {
+				expectInformationalResponses(6)
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorExcessiveLoad))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("too many 1xx informational responses"))
+			})
+
+			It("doesn't limit informational responses passed to the client trace", func() {
+				expectInformationalResponses(10)
+				var num int
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(int, textproto.MIMEHeader) error {
+						num++
+						return nil
+					},
+				}
+				rsp, err := client.RoundTrip(request.WithContext(httptrace.WithClientTrace(context.Background(), trace)))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(418))
+				Expect(num).To(Equal(10))
+			})
+		})
+
+		It("reads trailers", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newTestResponseWriter(rspBuf)
+			rw.Header().Set("Trailer", "Grpc-Status")
+			rw.Write([]byte("foobar"))
+			rw.Header().Set("Grpc-Status", "0")
+			rw.writeTrailers()
+			rw.Flush()
+
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.Trailer).To(Equal(http.Header{"Grpc-Status": nil}))
+			body, err := ioutil.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal([]byte("foobar")))
+			Expect(rsp.Trailer).To(Equal(http.Header{"Grpc-Status": []string{"0"}}))
+		})
+
+		It("rejects pseudo headers in trailers", func() {
+			rspBuf := &bytes.Buffer{}
+			rw := newTestResponseWriter(rspBuf)
+			rw.WriteHeader(200)
+			rw.Flush()
+			var headers bytes.Buffer
+			Expect(qpack.NewEncoder(&headers).WriteField(qpack.HeaderField{Name: ":status", Value: "200"})).To(Succeed())
+			(&headersFrame{Length: uint64(headers.Len())}).Write(rspBuf)
+			rspBuf.Write(headers.Bytes())
+
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+			)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			rsp, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ioutil.ReadAll(rsp.Body)
+			Expect(err).To(MatchError("invalid pseudo header in trailers: :status"))
+		})
+
+		It("reports header stats for the request and the response", func() {
+			var stats []HeaderStats
+			client.opts.OnHeaderStats = func(s HeaderStats) { stats = append(stats, s) }
+			client.requestWriter = newRequestWriter(client.opts.OnHeaderStats, client.logger)
+			rspBuf := &bytes.Buffer{}
+			rw := newTestResponseWriter(rspBuf)
+			rw.WriteHeader(418)
+			rw.Flush()
+			rspLen := rspBuf.Len()
+
+			gomock.InOrder(
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+			)
+			str.EXPECT().StreamID().Return(quic.StreamID(8)).AnyTimes()
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return rspBuf.Read(p)
+			}).AnyTimes()
+			_, err := client.RoundTrip(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(HaveLen(2))
+			Expect(stats[0].StreamID).To(Equal(quic.StreamID(8)))
+			Expect(stats[0].Sent).To(BeTrue())
+			Expect(stats[1]).To(Equal(HeaderStats{
+				StreamID:         8,
+				CompressedSize:   rspLen - 2, // the HEADERS frame header has a length of 2 bytes
+				UncompressedSize: len(":status") + len("418") + 32,
+			}))
+		})
+
 		Context("validating the address", func() {
 			It("refuses to do requests for the wrong host", func() {
 				req, err := http.NewRequest("https", "https://quic.clemente.io:1336/foobar.html", nil)
@@ -382,7 +748,7 @@ var _ = Describe("Client", func() {
 
 			It("cancels a request after the response arrived", func() {
 				rspBuf := &bytes.Buffer{}
-				rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+				rw := newTestResponseWriter(rspBuf)
 				rw.WriteHeader(418)
 				rw.Flush()
 
@@ -452,7 +818,7 @@ var _ = Describe("Client", func() {
 			It("decompresses the response", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 				buf := &bytes.Buffer{}
-				rw := newResponseWriter(buf, utils.DefaultLogger)
+				rw := newTestResponseWriter(buf)
 				rw.Header().Set("Content-Encoding", "gzip")
 				gz := gzip.NewWriter(rw)
 				gz.Write([]byte("gzipped response"))
@@ -477,7 +843,7 @@ var _ = Describe("Client", func() {
 			It("only decompresses the response if the response contains the right content-encoding header", func() {
 				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 				buf := &bytes.Buffer{}
-				rw := newResponseWriter(buf, utils.DefaultLogger)
+				rw := newTestResponseWriter(buf)
 				rw.Write([]byte("not gzipped"))
 				rw.Flush()
 				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
@@ -10,6 +10,14 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
+// stream types of unidirectional streams
+const (
+	streamTypeControlStream      = 0x0
+	streamTypePushStream         = 0x1
+	streamTypeQPACKEncoderStream = 0x2
+	streamTypeQPACKDecoderStream = 0x3
+)
+
 type byteReader interface {
 	io.ByteReader
 	io.Reader
@@ -32,38 +40,52 @@ func parseNextFrame(b io.Reader) (frame, error) {
 	if !ok {
 		br = &byteReaderImpl{b}
 	}
-	t, err := utils.ReadVarInt(br)
-	if err != nil {
-		return nil, err
-	}
-	l, err := utils.ReadVarInt(br)
-	if err != nil {
-		return nil, err
-	}
+	for {
+		t, err := utils.ReadVarInt(br)
+		if err != nil {
+			return nil, err
+		}
+		l, err := utils.ReadVarInt(br)
+		if err != nil {
+			return nil, err
+		}
 
-	switch t {
-	case 0x0:
-		return &dataFrame{Length: l}, nil
-	case 0x1:
-		return &headersFrame{Length: l}, nil
-	case 0x4:
-		return parseSettingsFrame(br, l)
-	case 0x3: // CANCEL_PUSH
-		fallthrough
-	case 0x5: // PUSH_PROMISE
-		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
-	case 0xe: // DUPLICATE_PUSH
-		fallthrough
-	default:
-		// skip over unknown frames
+		switch t {
+		case 0x0:
+			return &dataFrame{Length: l}, nil
+		case 0x1:
+			return &headersFrame{Length: l}, nil
+		case 0x3:
+			return parseCancelPushFrame(br, l)
+		case 0x4:
+			return parseSettingsFrame(br, l)
+		case 0x5:
+			// We never send a MAX_PUSH_ID frame, so we don't need to parse the PUSH_PROMISE.
+			if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
+				return nil, err
+			}
+			return &pushPromiseFrame{}, nil
+		case 0x7:
+			return parseGoAwayFrame(br, l)
+		case 0xd:
+			return parseMaxPushIDFrame(br, l)
+		case 0xe:
+			if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
+				return nil, err
+			}
+			return &duplicatePushFrame{}, nil
+		case 0x2, 0x6, 0x8, 0x9:
+			// These frame types are reserved, since they were used in HTTP/2.
+			// Receiving them is a connection error of type H3_FRAME_UNEXPECTED.
+			if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
+				return nil, err
+			}
+			return &reservedFrame{Type: t}, nil
+		}
+		// Skip over unknown frames (including reserved frame types used for greasing).
 		if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
 			return nil, err
 		}
-		return parseNextFrame(b)
 	}
 }
 
@@ -85,6 +107,9 @@ func (f *headersFrame) Write(b *bytes.Buffer) {
 	utils.WriteVarInt(b, f.Length)
 }
 
+// settingExtendedConnect is SETTINGS_ENABLE_CONNECT_PROTOCOL, see https://tools.ietf.org/html/rfc8441#section-3
+const settingExtendedConnect = 0x8
+
 type settingsFrame struct {
 	settings map[uint64]uint64
 }
@@ -131,3 +156,104 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		utils.WriteVarInt(b, val)
 	}
 }
+
+type goAwayFrame struct {
+	StreamID protocol.StreamID
+}
+
+func parseGoAwayFrame(r io.Reader, l uint64) (*goAwayFrame, error) {
+	if l > 8 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := utils.ReadVarInt(b)
+	if err != nil || b.Len() > 0 {
+		return nil, fmt.Errorf("unexpected size for GOAWAY frame: %d", l)
+	}
+	return &goAwayFrame{StreamID: protocol.StreamID(id)}, nil
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x7)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(uint64(f.StreamID))))
+	utils.WriteVarInt(b, uint64(f.StreamID))
+}
+
+type maxPushIDFrame struct {
+	PushID uint64
+}
+
+func parseMaxPushIDFrame(r io.Reader, l uint64) (*maxPushIDFrame, error) {
+	if l > 8 {
+		return nil, fmt.Errorf("unexpected size for MAX_PUSH_ID frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := utils.ReadVarInt(b)
+	if err != nil || b.Len() > 0 {
+		return nil, fmt.Errorf("unexpected size for MAX_PUSH_ID frame: %d", l)
+	}
+	return &maxPushIDFrame{PushID: id}, nil
+}
+
+func (f *maxPushIDFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0xd)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+type cancelPushFrame struct {
+	PushID uint64
+}
+
+func parseCancelPushFrame(r io.Reader, l uint64) (*cancelPushFrame, error) {
+	if l > 8 {
+		return nil, fmt.Errorf("unexpected size for CANCEL_PUSH frame: %d", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	b := bytes.NewReader(buf)
+	id, err := utils.ReadVarInt(b)
+	if err != nil || b.Len() > 0 {
+		return nil, fmt.Errorf("unexpected size for CANCEL_PUSH frame: %d", l)
+	}
+	return &cancelPushFrame{PushID: id}, nil
+}
+
+func (f *cancelPushFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x3)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+// A pushPromiseFrame is only returned to signal that a PUSH_PROMISE was received.
+// Its payload is skipped.
+type pushPromiseFrame struct{}
+
+// A duplicatePushFrame is only returned to signal that a DUPLICATE_PUSH was received.
+// Its payload is skipped.
+type duplicatePushFrame struct{}
+
+// A reservedFrame is a frame of one of the types reserved for frames used in HTTP/2.
+// Its payload is skipped.
+type reservedFrame struct {
+	Type uint64
+}
@@ -29,6 +29,72 @@ var _ = Describe("Frames", func() {
 		Expect(frame.(*dataFrame).Length).To(Equal(uint64(0x1234)))
 	})
 
+	It("skips multiple unknown frames", func() {
+		var data []byte
+		for i := 0; i < 10; i++ {
+			data = appendVarInt(data, 0x1f*uint64(i)+0x21) // reserved frame types, used for greasing
+			data = appendVarInt(data, uint64(i))
+			data = append(data, make([]byte, i)...)
+		}
+		buf := bytes.NewBuffer(data)
+		(&dataFrame{Length: 0x1234}).Write(buf)
+		frame, err := parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&dataFrame{Length: 0x1234}))
+	})
+
+	It("parses PUSH_PROMISE and DUPLICATE_PUSH frames, skipping their payload", func() {
+		var data []byte
+		for _, t := range []uint64{0x5 /* PUSH_PROMISE */, 0xe /* DUPLICATE_PUSH */} {
+			data = appendVarInt(data, t)
+			data = appendVarInt(data, 20)
+			data = append(data, make([]byte, 20)...)
+		}
+		buf := bytes.NewBuffer(data)
+		(&headersFrame{Length: 0x42}).Write(buf)
+		frame, err := parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&pushPromiseFrame{}))
+		frame, err = parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&duplicatePushFrame{}))
+		frame, err = parseNextFrame(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&headersFrame{Length: 0x42}))
+	})
+
+	It("doesn't skip frames of the types reserved for HTTP/2 frames", func() {
+		for _, t := range []uint64{0x2, 0x6, 0x8, 0x9} {
+			data := appendVarInt(nil, t)
+			data = appendVarInt(data, 10)
+			data = append(data, make([]byte, 10)...)
+			buf := bytes.NewBuffer(data)
+			(&dataFrame{Length: 0x42}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&reservedFrame{Type: t}))
+			frame, err = parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 0x42}))
+		}
+	})
+
+	It("errors on EOF when skipping the payload of a reserved frame", func() {
+		data := appendVarInt(nil, 0x6)
+		data = appendVarInt(data, 10)
+		data = append(data, make([]byte, 9)...)
+		_, err := parseNextFrame(bytes.NewReader(data))
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("errors on EOF when skipping unknown frames", func() {
+		data := appendVarInt(nil, 0x21)
+		data = appendVarInt(data, 0x42)
+		data = append(data, make([]byte, 0x41)...)
+		_, err := parseNextFrame(bytes.NewReader(data))
+		Expect(err).To(MatchError(io.EOF))
+	})
+
 	Context("DATA frames", func() {
 		It("parses", func() {
 			data := appendVarInt(nil, 0) // type byte
@@ -133,4 +199,121 @@ var _ = Describe("Frames", func() {
 			}
 		})
 	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)))
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 1337}))
+		})
+
+		It("rejects frames with an invalid length", func() {
+			data := appendVarInt(nil, 7) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)+1))
+			data = appendVarInt(data, 1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for GOAWAY frame: 3"))
+		})
+
+		It("writes", func() {
+			f := &goAwayFrame{StreamID: 0xdeadbeef}
+			buf := &bytes.Buffer{}
+			f.Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("CANCEL_PUSH frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0x3) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)))
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 1337}))
+		})
+
+		It("rejects frames with an invalid length", func() {
+			data := appendVarInt(nil, 0x3) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)+1))
+			data = appendVarInt(data, 1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for CANCEL_PUSH frame: 3"))
+		})
+
+		It("writes", func() {
+			f := &cancelPushFrame{PushID: 0xdeadbeef}
+			buf := &bytes.Buffer{}
+			f.Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&cancelPushFrame{PushID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("MAX_PUSH_ID frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)))
+			data = appendVarInt(data, 1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 1337}))
+		})
+
+		It("rejects frames with an invalid length", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, uint64(utils.VarIntLen(1337)+1))
+			data = appendVarInt(data, 1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected size for MAX_PUSH_ID frame: 3"))
+		})
+
+		It("writes", func() {
+			f := &maxPushIDFrame{PushID: 0xdeadbeef}
+			buf := &bytes.Buffer{}
+			f.Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 0xdeadbeef}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				_, err := parseNextFrame(bytes.NewReader(data[:i]))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
 })
@@ -0,0 +1,31 @@
+package http3
+
+import (
+	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+)
+
+// HeaderStats are statistics about a header block sent or received on a request stream.
+// Since the QPACK encoder only uses the static table, no statistics about the dynamic table are collected.
+type HeaderStats struct {
+	StreamID quic.StreamID
+	// Sent is true for header blocks sent, and false for header blocks received.
+	Sent bool
+	// CompressedSize is the size of the QPACK-encoded header block, i.e. the payload of the HEADERS frame.
+	CompressedSize int
+	// UncompressedSize is the size of the header list, calculated as described in RFC 7541, section 4.1:
+	// the sum of the length of name and value of every header field, plus an overhead of 32 bytes per field.
+	UncompressedSize int
+}
+
+func headerListSize(hfs []qpack.HeaderField) int {
+	var size int
+	for _, hf := range hfs {
+		size += headerFieldSize(hf)
+	}
+	return size
+}
+
+func headerFieldSize(hf qpack.HeaderField) int {
+	return len(hf.Name) + len(hf.Value) + 32
+}
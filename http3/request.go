@@ -12,7 +12,7 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
 	for _, h := range headers {
@@ -23,6 +23,10 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":protocol":
+			protocol = h.Value
+		case ":scheme":
+			scheme = h.Value
 		case "content-length":
 			contentLengthStr = h.Value
 		default:
@@ -38,10 +42,18 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}
 
 	isConnect := method == http.MethodConnect
-	if isConnect {
+	// Extended CONNECT, see https://tools.ietf.org/html/rfc8441#section-4
+	isExtendedConnect := isConnect && protocol != ""
+	if isExtendedConnect {
+		if scheme == "" || path == "" || authority == "" {
+			return nil, errors.New("extended CONNECT: :scheme, :path and :authority must not be empty")
+		}
+	} else if isConnect {
 		if path != "" || authority == "" {
 			return nil, errors.New(":path must be empty and :authority must not be empty")
 		}
+	} else if protocol != "" {
+		return nil, errors.New(":protocol must be empty for methods other than CONNECT")
 	} else if len(path) == 0 || len(authority) == 0 || len(method) == 0 {
 		return nil, errors.New(":path, :authority and :method must not be empty")
 	}
@@ -50,7 +62,7 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	var requestURI string
 	var err error
 
-	if isConnect {
+	if isConnect && !isExtendedConnect {
 		u = &url.URL{Host: authority}
 		requestURI = authority
 	} else {
@@ -69,10 +81,18 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		}
 	}
 
+	proto := "HTTP/3"
+	if isExtendedConnect {
+		// The protocol that the request stream is used for, e.g. "webtransport" or "websocket".
+		proto = protocol
+		u.Scheme = scheme
+		u.Host = authority
+	}
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
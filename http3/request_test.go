@@ -137,6 +137,45 @@ var _ = Describe("Request", func() {
 		Expect(err).To(MatchError(":path must be empty and :authority must not be empty"))
 	})
 
+	It("handles Extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":path", Value: "/foo?val=1337"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal(http.MethodConnect))
+		Expect(req.Proto).To(Equal("webtransport"))
+		Expect(req.URL.String()).To(Equal("https://quic.clemente.io/foo?val=1337"))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+		Expect(req.RequestURI).To(Equal("/foo?val=1337"))
+	})
+
+	It("errors with missing scheme in Extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodConnect},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("extended CONNECT: :scheme, :path and :authority must not be empty"))
+	})
+
+	It("errors with a :protocol pseudo header for methods other than CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":protocol", Value: "webtransport"},
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: http.MethodGet},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":protocol must be empty for methods other than CONNECT"))
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
@@ -25,27 +25,35 @@ type requestWriter struct {
 	encoder   *qpack.Encoder
 	headerBuf *bytes.Buffer
 
+	onHeaderStats func(HeaderStats)
+
 	logger utils.Logger
 }
 
-func newRequestWriter(logger utils.Logger) *requestWriter {
+func newRequestWriter(onHeaderStats func(HeaderStats), logger utils.Logger) *requestWriter {
 	headerBuf := &bytes.Buffer{}
 	encoder := qpack.NewEncoder(headerBuf)
 	return &requestWriter{
-		encoder:   encoder,
-		headerBuf: headerBuf,
-		logger:    logger,
+		encoder:       encoder,
+		headerBuf:     headerBuf,
+		onHeaderStats: onHeaderStats,
+		logger:        logger,
 	}
 }
 
 func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bool) error {
 	buf := &bytes.Buffer{}
-	if err := w.writeHeaders(buf, req, gzip); err != nil {
+	stats, err := w.writeHeaders(buf, req, gzip)
+	if err != nil {
 		return err
 	}
 	if _, err := str.Write(buf.Bytes()); err != nil {
 		return err
 	}
+	if w.onHeaderStats != nil {
+		stats.StreamID = str.StreamID()
+		w.onHeaderStats(stats)
+	}
 	// TODO: add support for trailers
 	if req.Body == nil {
 		str.Close()
@@ -90,38 +98,47 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bo
 	return nil
 }
 
-func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) error {
+// writeHeaders writes the HEADERS frame.
+// The returned HeaderStats don't have the stream ID set.
+func (w *requestWriter) writeHeaders(wr io.Writer, req *http.Request, gzip bool) (HeaderStats, error) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	defer w.encoder.Close()
 
-	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
-		return err
+	hlSize, err := w.encodeHeaders(req, gzip, "", actualContentLength(req))
+	if err != nil {
+		return HeaderStats{}, err
 	}
 
+	stats := HeaderStats{
+		Sent:             true,
+		CompressedSize:   w.headerBuf.Len(),
+		UncompressedSize: int(hlSize),
+	}
 	buf := &bytes.Buffer{}
 	hf := headersFrame{Length: uint64(w.headerBuf.Len())}
 	hf.Write(buf)
 	if _, err := wr.Write(buf.Bytes()); err != nil {
-		return err
+		return HeaderStats{}, err
 	}
 	if _, err := wr.Write(w.headerBuf.Bytes()); err != nil {
-		return err
+		return HeaderStats{}, err
 	}
 	w.headerBuf.Reset()
-	return nil
+	return stats, nil
 }
 
 // copied from net/transport.go
 
-func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) error {
+// encodeHeaders encodes the headers, and returns the size of the header list.
+func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) (uint64, error) {
 	host := req.Host
 	if host == "" {
 		host = req.URL.Host
 	}
 	host, err := httpguts.PunycodeHostPort(host)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	var path string
@@ -132,9 +149,9 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 			path = strings.TrimPrefix(path, req.URL.Scheme+"://"+host)
 			if !validPseudoPath(path) {
 				if req.URL.Opaque != "" {
-					return fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
+					return 0, fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
 				} else {
-					return fmt.Errorf("invalid request :path %q", orig)
+					return 0, fmt.Errorf("invalid request :path %q", orig)
 				}
 			}
 		}
@@ -145,11 +162,11 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// continue to reuse the hpack encoder for future requests)
 	for k, vv := range req.Header {
 		if !httpguts.ValidHeaderFieldName(k) {
-			return fmt.Errorf("invalid HTTP header name %q", k)
+			return 0, fmt.Errorf("invalid HTTP header name %q", k)
 		}
 		for _, v := range vv {
 			if !httpguts.ValidHeaderFieldValue(v) {
-				return fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
+				return 0, fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
 			}
 		}
 	}
@@ -242,7 +259,7 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		// }
 	})
 
-	return nil
+	return hlSize, nil
 }
 
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
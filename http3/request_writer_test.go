@@ -9,6 +9,7 @@ import (
 	"github.com/marten-seemann/qpack"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
@@ -48,7 +49,7 @@ var _ = Describe("Request Writer", func() {
 	}
 
 	BeforeEach(func() {
-		rw = newRequestWriter(utils.DefaultLogger)
+		rw = newRequestWriter(nil, utils.DefaultLogger)
 		strBuf = &bytes.Buffer{}
 		str = mockquic.NewMockStream(mockCtrl)
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
@@ -69,6 +70,34 @@ var _ = Describe("Request Writer", func() {
 		Expect(headerFields).ToNot(HaveKey("accept-encoding"))
 	})
 
+	It("reports header stats", func() {
+		var stats []HeaderStats
+		rw = newRequestWriter(func(s HeaderStats) { stats = append(stats, s) }, utils.DefaultLogger)
+		str.EXPECT().StreamID().Return(quic.StreamID(8)).AnyTimes()
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/index.html", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		Expect(stats).To(HaveLen(1))
+		Expect(stats[0].StreamID).To(Equal(quic.StreamID(8)))
+		Expect(stats[0].Sent).To(BeTrue())
+
+		frame, err := parseNextFrame(strBuf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats[0].CompressedSize).To(BeEquivalentTo(frame.(*headersFrame).Length))
+		data := make([]byte, frame.(*headersFrame).Length)
+		_, err = io.ReadFull(strBuf, data)
+		Expect(err).ToNot(HaveOccurred())
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		Expect(err).ToNot(HaveOccurred())
+		var size int
+		for _, hf := range hfs {
+			size += len(hf.Name) + len(hf.Value) + 32
+		}
+		Expect(stats[0].UncompressedSize).To(Equal(size))
+		Expect(stats[0].UncompressedSize).To(BeNumerically(">", stats[0].CompressedSize))
+	})
+
 	It("writes a POST request", func() {
 		closed := make(chan struct{})
 		str.EXPECT().Close().Do(func() { close(closed) })
@@ -3,33 +3,77 @@ package http3
 import (
 	"bufio"
 	"bytes"
-	"io"
+	"context"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 )
 
+// DataStreamer lets the caller take over the stream of a request.
+// This can be used to implement protocols on top of Extended CONNECT requests, e.g. WebTransport.
+// After a call to DataStream, the HTTP server library will not do anything else with the stream.
+// It becomes the caller's responsibility to manage and close the stream.
+// The response headers are written before the stream is returned, if they haven't been written yet.
+// After a call to DataStream, the original Request.Body must not be used.
+type DataStreamer interface {
+	DataStream() quic.Stream
+}
+
+// A StreamCreator opens streams on the QUIC session that an HTTP/3 request was received on.
+type StreamCreator interface {
+	OpenStream() (quic.Stream, error)
+	OpenStreamSync(context.Context) (quic.Stream, error)
+	OpenUniStream() (quic.SendStream, error)
+	OpenUniStreamSync(context.Context) (quic.SendStream, error)
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+}
+
+var _ StreamCreator = quic.Session(nil)
+
+// Hijacker allows opening streams on the QUIC session that a request was received on.
+// Streams opened this way are not handled by the HTTP server library.
+type Hijacker interface {
+	StreamCreator() StreamCreator
+}
+
 type responseWriter struct {
-	stream *bufio.Writer
+	dataStream     quic.Stream
+	dataStreamUsed bool
+	sess           quic.Session
+	stream         *bufio.Writer
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	// the trailers announced in the Trailer header, in canonical form
+	trailers []string
+
+	onHeaderStats func(HeaderStats)
 
 	logger utils.Logger
 }
 
-var _ http.ResponseWriter = &responseWriter{}
-var _ http.Flusher = &responseWriter{}
+var (
+	_ http.ResponseWriter = &responseWriter{}
+	_ http.Flusher        = &responseWriter{}
+	_ DataStreamer        = &responseWriter{}
+	_ Hijacker            = &responseWriter{}
+)
 
-func newResponseWriter(stream io.Writer, logger utils.Logger) *responseWriter {
+func newResponseWriter(stream quic.Stream, sess quic.Session, onHeaderStats func(HeaderStats), logger utils.Logger) *responseWriter {
 	return &responseWriter{
-		header: http.Header{},
-		stream: bufio.NewWriter(stream),
-		logger: logger,
+		header:        http.Header{},
+		dataStream:    stream,
+		sess:          sess,
+		stream:        bufio.NewWriter(stream),
+		onHeaderStats: onHeaderStats,
+		logger:        logger,
 	}
 }
 
@@ -41,18 +85,45 @@ func (w *responseWriter) WriteHeader(status int) {
 	if w.headerWritten {
 		return
 	}
-	w.headerWritten = true
-	w.status = status
+	// Informational responses can be sent multiple times before the final response.
+	informational := isInformationalStatus(status)
+	if !informational {
+		w.headerWritten = true
+		w.status = status
+
+		for _, v := range w.header.Values("Trailer") {
+			for _, key := range strings.Split(v, ",") {
+				if key = strings.TrimSpace(key); key != "" {
+					w.trailers = append(w.trailers, http.CanonicalHeaderKey(key))
+				}
+			}
+		}
+	}
 
 	var headers bytes.Buffer
 	enc := qpack.NewEncoder(&headers)
-	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
+	statusField := qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)}
+	enc.WriteField(statusField)
+	hlSize := headerFieldSize(statusField)
 
 	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) || w.isTrailer(k) {
+			continue
+		}
 		for index := range v {
-			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+			hf := qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]}
+			enc.WriteField(hf)
+			hlSize += headerFieldSize(hf)
 		}
 	}
+	if w.onHeaderStats != nil {
+		w.onHeaderStats(HeaderStats{
+			StreamID:         w.dataStream.StreamID(),
+			Sent:             true,
+			CompressedSize:   headers.Len(),
+			UncompressedSize: hlSize,
+		})
+	}
 
 	buf := &bytes.Buffer{}
 	(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
@@ -63,6 +134,63 @@ func (w *responseWriter) WriteHeader(status int) {
 	if _, err := w.stream.Write(headers.Bytes()); err != nil {
 		w.logger.Errorf("could not write header frame payload: %s", err.Error())
 	}
+	// send informational responses right away, e.g. to let the client preload resources
+	if informational {
+		w.Flush()
+	}
+}
+
+func (w *responseWriter) isTrailer(key string) bool {
+	for _, k := range w.trailers {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTrailers sends the trailers in a HEADERS frame after the response body.
+// Like net/http, trailers are either announced in the Trailer header before the headers are written,
+// or set using header keys prefixed with http.TrailerPrefix.
+func (w *responseWriter) writeTrailers() {
+	var headers bytes.Buffer
+	enc := qpack.NewEncoder(&headers)
+	var hlSize int
+	writeField := func(key string, values []string) {
+		for _, v := range values {
+			hf := qpack.HeaderField{Name: strings.ToLower(key), Value: v}
+			enc.WriteField(hf)
+			hlSize += headerFieldSize(hf)
+		}
+	}
+	for _, k := range w.trailers {
+		writeField(k, w.header[k])
+	}
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			writeField(strings.TrimPrefix(k, http.TrailerPrefix), v)
+		}
+	}
+	if headers.Len() == 0 {
+		return
+	}
+	if w.onHeaderStats != nil {
+		w.onHeaderStats(HeaderStats{
+			StreamID:         w.dataStream.StreamID(),
+			Sent:             true,
+			CompressedSize:   headers.Len(),
+			UncompressedSize: hlSize,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+	if _, err := w.stream.Write(buf.Bytes()); err != nil {
+		w.logger.Errorf("could not write trailers frame: %s", err.Error())
+	}
+	if _, err := w.stream.Write(headers.Bytes()); err != nil {
+		w.logger.Errorf("could not write trailers frame payload: %s", err.Error())
+	}
 }
 
 func (w *responseWriter) Write(p []byte) (int, error) {
@@ -87,6 +215,29 @@ func (w *responseWriter) Flush() {
 	}
 }
 
+func (w *responseWriter) usedDataStream() bool {
+	return w.dataStreamUsed
+}
+
+func (w *responseWriter) DataStream() quic.Stream {
+	if !w.headerWritten {
+		w.WriteHeader(200)
+	}
+	w.Flush()
+	w.dataStreamUsed = true
+	return w.dataStream
+}
+
+func (w *responseWriter) StreamCreator() StreamCreator {
+	return w.sess
+}
+
+// isInformationalStatus says if the status code is used for an informational (1xx) response.
+// Like net/http, 101 (Switching Protocols) is treated as a final response.
+func isInformationalStatus(status int) bool {
+	return status >= 100 && status <= 199 && status != http.StatusSwitchingProtocols
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
@@ -5,6 +5,9 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 
@@ -12,6 +15,13 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// newTestResponseWriter creates a responseWriter that writes to w
+func newTestResponseWriter(w io.Writer) *responseWriter {
+	str := mockquic.NewMockStream(mockCtrl)
+	str.EXPECT().Write(gomock.Any()).DoAndReturn(w.Write).AnyTimes()
+	return newResponseWriter(str, nil, nil, utils.DefaultLogger)
+}
+
 var _ = Describe("Response Writer", func() {
 	var (
 		rw     *responseWriter
@@ -20,7 +30,7 @@ var _ = Describe("Response Writer", func() {
 
 	BeforeEach(func() {
 		strBuf = &bytes.Buffer{}
-		rw = newResponseWriter(strBuf, utils.DefaultLogger)
+		rw = newTestResponseWriter(strBuf)
 	})
 
 	decodeHeader := func(str io.Reader) map[string][]string {
@@ -61,6 +71,85 @@ var _ = Describe("Response Writer", func() {
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"418"}))
 	})
 
+	It("sends informational responses before the final response", func() {
+		rw.Header().Add("Link", "</style.css>; rel=preload; as=style")
+		rw.WriteHeader(http.StatusEarlyHints)
+		// informational responses are sent right away
+		Expect(strBuf.Len()).ToNot(BeZero())
+		rw.Header().Add("Link", "</script.js>; rel=preload; as=script")
+		rw.WriteHeader(http.StatusEarlyHints)
+		rw.Write([]byte("foobar"))
+		rw.WriteHeader(http.StatusTeapot) // ignored, the final response was already sent
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"103"}))
+		Expect(fields).To(HaveKeyWithValue("link", []string{"</style.css>; rel=preload; as=style"}))
+		fields = decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"103"}))
+		Expect(fields["link"]).To(HaveLen(2))
+		fields = decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+	})
+
+	Context("trailers", func() {
+		It("writes trailers announced in the Trailer header", func() {
+			rw.Header().Set("Trailer", "Grpc-Status, grpc-message")
+			rw.Header().Set("Grpc-Status", "1") // overwritten below
+			rw.WriteHeader(http.StatusOK)
+			rw.Write([]byte("foobar"))
+			rw.Header().Set("Grpc-Status", "0")
+			rw.Header().Set("Grpc-Message", "ok")
+			rw.writeTrailers()
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("trailer", []string{"Grpc-Status, grpc-message"}))
+			Expect(fields).ToNot(HaveKey("grpc-status"))
+			Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+			fields = decodeHeader(strBuf)
+			Expect(fields).To(HaveLen(2))
+			Expect(fields).To(HaveKeyWithValue("grpc-status", []string{"0"}))
+			Expect(fields).To(HaveKeyWithValue("grpc-message", []string{"ok"}))
+		})
+
+		It("writes trailers set using the TrailerPrefix", func() {
+			rw.Header().Set(http.TrailerPrefix+"Foo", "bar")
+			rw.WriteHeader(http.StatusOK)
+			rw.Header().Set(http.TrailerPrefix+"Lorem", "ipsum")
+			rw.writeTrailers()
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveLen(1))
+			fields = decodeHeader(strBuf)
+			Expect(fields).To(HaveLen(2))
+			Expect(fields).To(HaveKeyWithValue("foo", []string{"bar"}))
+			Expect(fields).To(HaveKeyWithValue("lorem", []string{"ipsum"}))
+		})
+
+		It("doesn't write a HEADERS frame if there are no trailers", func() {
+			rw.Header().Set("Trailer", "Foo")
+			rw.WriteHeader(http.StatusOK)
+			decodeHeader(strBuf)
+			rw.writeTrailers()
+			rw.Flush()
+			Expect(strBuf.Len()).To(BeZero())
+		})
+	})
+
+	It("reports header stats", func() {
+		var stats []HeaderStats
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
+		str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+		rw = newResponseWriter(str, nil, func(s HeaderStats) { stats = append(stats, s) }, utils.DefaultLogger)
+		rw.Header().Add("content-length", "42")
+		rw.WriteHeader(http.StatusTeapot)
+		rw.Flush()
+		Expect(stats).To(Equal([]HeaderStats{{
+			StreamID:         4,
+			Sent:             true,
+			CompressedSize:   strBuf.Len() - 2, // the HEADERS frame header has a length of 2 bytes
+			UncompressedSize: len(":status") + len("418") + 32 + len("content-length") + len("42") + 32,
+		}}))
+	})
+
 	It("writes headers", func() {
 		rw.Header().Add("content-length", "42")
 		rw.WriteHeader(http.StatusTeapot)
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
 
@@ -19,6 +20,20 @@ type roundTripCloser interface {
 	io.Closer
 }
 
+// A hostClient sends requests to a single host, using a single QUIC connection.
+type hostClient interface {
+	roundTripCloser
+	// isClosed says if the connection can't be used for new requests any more.
+	isClosed() bool
+}
+
+// A pooledClient is a hostClient in the RoundTripper's connection pool.
+type pooledClient struct {
+	hostClient
+	// the number of requests waiting for a response, protected by the RoundTripper's mutex
+	requests int
+}
+
 // RoundTripper implements the http.RoundTripper interface
 type RoundTripper struct {
 	mutex sync.Mutex
@@ -51,7 +66,38 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
-	clients map[string]roundTripCloser
+	// AdditionalSettings specifies additional HTTP/3 settings.
+	// It is invalid to specify any settings defined by the HTTP/3 draft and the QPACK draft.
+	AdditionalSettings map[uint64]uint64
+
+	// OnSettings is called when the SETTINGS frame is received on the server's control stream.
+	// The map contains all settings sent by the server, including unknown and reserved (GREASE) settings.
+	OnSettings func(map[uint64]uint64)
+
+	// OnHeaderStats is called for every header block sent and received on a request stream.
+	// It may be called concurrently for different requests.
+	OnHeaderStats func(HeaderStats)
+
+	// ResponseHeaderTimeout, if non-zero, specifies the amount of time to wait for the
+	// server's response headers after sending the request headers.
+	// It doesn't limit the time it takes to read the response body.
+	// When it expires, the request stream is reset and a timeout error (a net.Error) is returned.
+	ResponseHeaderTimeout time.Duration
+
+	// MaxConnsPerHost limits the number of QUIC connections used for requests to a single host.
+	// HTTP/3 multiplexes concurrent requests as streams on one connection,
+	// so zero means that only a single connection is used.
+	// If larger, a new connection is established when all existing connections
+	// are busy, i.e. when they all have requests waiting for a response.
+	MaxConnsPerHost int
+
+	// DisableRetry, if true, disables retrying requests when the QUIC connection
+	// is closed before the response was received.
+	// Otherwise, like net/http's Transport, a request is retried once on a new connection
+	// if it wasn't sent yet, or if it is idempotent, as long as the request body can be rewound.
+	DisableRetry bool
+
+	clients map[string][]*pooledClient
 }
 
 // RoundTripOpt are options for the Transport.RoundTripOpt method.
@@ -105,11 +151,75 @@ func (r *RoundTripper) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.
 	}
 
 	hostname := authorityAddr("https", hostnameFromRequest(req))
-	cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+	var retried bool
+	for {
+		cl, err := r.getClient(hostname, opt.OnlyCachedConn)
+		if err != nil {
+			return nil, err
+		}
+		rsp, err := cl.RoundTrip(req)
+		r.releaseClient(cl)
+		closedErr, ok := err.(*sessionClosedError)
+		if !ok {
+			return rsp, err
+		}
+		// The connection was closed while the request was in flight.
+		if retried || !r.canRetry(req, closedErr) {
+			return nil, closedErr.err
+		}
+		newReq, rerr := rewindRequest(req, closedErr.requestSent)
+		if rerr != nil {
+			return nil, closedErr.err
+		}
+		req = newReq
+		retried = true
+	}
+}
+
+func (r *RoundTripper) canRetry(req *http.Request, err *sessionClosedError) bool {
+	if r.DisableRetry {
+		return false
+	}
+	// The retry uses the same context, so it's subject to the same deadline.
+	if req.Context().Err() != nil {
+		return false
+	}
+	return !err.requestSent || isReplayable(req)
+}
+
+// isReplayable says if a request can be sent again, after it might have reached the server already.
+// This is the same logic that net/http uses.
+func isReplayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	// The Idempotency-Key header, while non-standard, is used by net/http to mark requests as idempotent.
+	if _, ok := req.Header["Idempotency-Key"]; ok {
+		return true
+	}
+	if _, ok := req.Header["X-Idempotency-Key"]; ok {
+		return true
+	}
+	return false
+}
+
+// rewindRequest returns a request that can be sent again, after a failed attempt.
+// If the body was (partially) read, it has to be obtained again using GetBody.
+func rewindRequest(req *http.Request, bodyRead bool) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody || !bodyRead {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("http3: request body can't be rewound")
+	}
+	body, err := req.GetBody()
 	if err != nil {
 		return nil, err
 	}
-	return cl.RoundTrip(req)
+	newReq := req.Clone(req.Context())
+	newReq.Body = body
+	return newReq, nil
 }
 
 // RoundTrip does a round trip.
@@ -117,41 +227,78 @@ func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
-func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTripper, error) {
+// getClient returns the least busy client for a host.
+// Concurrent requests share the client, and therefore wait for the same handshake,
+// unless MaxConnsPerHost allows establishing another connection.
+// The caller must call releaseClient once the request completed.
+func (r *RoundTripper) getClient(hostname string, onlyCached bool) (*pooledClient, error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
 
 	if r.clients == nil {
-		r.clients = make(map[string]roundTripCloser)
+		r.clients = make(map[string][]*pooledClient)
 	}
 
-	client, ok := r.clients[hostname]
-	if !ok {
-		if onlyCached {
-			return nil, ErrNoCachedConn
+	// remove clients that can't be used any more
+	clients := r.clients[hostname][:0]
+	for _, cl := range r.clients[hostname] {
+		if !cl.isClosed() {
+			clients = append(clients, cl)
+		}
+	}
+	var client *pooledClient
+	for _, cl := range clients {
+		if client == nil || cl.requests < client.requests {
+			client = cl
 		}
-		client = newClient(
+	}
+	if client == nil && onlyCached {
+		return nil, ErrNoCachedConn
+	}
+	if client == nil || (client.requests > 0 && len(clients) < r.maxConnsPerHost() && !onlyCached) {
+		client = &pooledClient{hostClient: newClient(
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
+				DisableCompression:    r.DisableCompression,
+				MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+				AdditionalSettings:    r.AdditionalSettings,
+				OnSettings:            r.OnSettings,
+				OnHeaderStats:         r.OnHeaderStats,
+				ResponseHeaderTimeout: r.ResponseHeaderTimeout,
 			},
 			r.QuicConfig,
 			r.Dial,
-		)
-		r.clients[hostname] = client
+		)}
+		clients = append(clients, client)
 	}
+	r.clients[hostname] = clients
+	client.requests++
 	return client, nil
 }
 
+func (r *RoundTripper) releaseClient(client *pooledClient) {
+	r.mutex.Lock()
+	client.requests--
+	r.mutex.Unlock()
+}
+
+func (r *RoundTripper) maxConnsPerHost() int {
+	if r.MaxConnsPerHost <= 0 {
+		return 1
+	}
+	return r.MaxConnsPerHost
+}
+
 // Close closes the QUIC connections that this RoundTripper has used
 func (r *RoundTripper) Close() error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	for _, client := range r.clients {
-		if err := client.Close(); err != nil {
-			return err
+	for _, clients := range r.clients {
+		for _, client := range clients {
+			if err := client.Close(); err != nil {
+				return err
+			}
 		}
 	}
 	r.clients = nil
@@ -12,6 +12,7 @@ import (
 	"github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -27,8 +28,9 @@ func (m *mockClient) Close() error {
 	m.closed = true
 	return nil
 }
+func (m *mockClient) isClosed() bool { return m.closed }
 
-var _ roundTripCloser = &mockClient{}
+var _ hostClient = &mockClient{}
 
 type mockBody struct {
 	reader   bytes.Reader
@@ -76,10 +78,16 @@ var _ = Describe("RoundTripper", func() {
 	})
 
 	Context("dialing hosts", func() {
-		origDialAddr := dialAddr
+		var (
+			origDialAddr = dialAddr
+			closeSession context.CancelFunc // makes the session's context done, as when it's closed
+		)
 
 		BeforeEach(func() {
 			session = mockquic.NewMockEarlySession(mockCtrl)
+			var sessionCtx context.Context
+			sessionCtx, closeSession = context.WithCancel(context.Background())
+			session.EXPECT().Context().Return(sessionCtx).AnyTimes()
 			origDialAddr = dialAddr
 			dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
 				// return an error when trying to open a stream
@@ -152,6 +160,247 @@ var _ = Describe("RoundTripper", func() {
 			Eventually(closed).Should(BeClosed())
 		})
 
+		It("dials a new connection if dialing failed", func() {
+			var dialed int
+			dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
+				dialed++
+				return nil, errors.New("handshake error")
+			}
+			_, err := rt.RoundTrip(req1)
+			Expect(err).To(MatchError("handshake error"))
+			_, err = rt.RoundTrip(req1)
+			Expect(err).To(MatchError("handshake error"))
+			Expect(dialed).To(Equal(2))
+			Expect(rt.clients).To(HaveLen(1))
+			Expect(rt.clients["www.example.org:443"]).To(HaveLen(1))
+		})
+
+		It("coalesces dials for concurrent requests", func() {
+			dialing := make(chan struct{}, 2)
+			dialErr := make(chan error)
+			dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
+				dialing <- struct{}{}
+				return nil, <-dialErr
+			}
+			errChan := make(chan error, 2)
+			for i := 0; i < 2; i++ {
+				go func() {
+					defer GinkgoRecover()
+					_, err := rt.RoundTrip(req1)
+					errChan <- err
+				}()
+			}
+			Eventually(dialing).Should(Receive())
+			Consistently(dialing).ShouldNot(Receive())
+			Consistently(errChan).ShouldNot(Receive())
+			dialErr <- errors.New("handshake error")
+			Eventually(errChan).Should(Receive(MatchError("handshake error")))
+			Eventually(errChan).Should(Receive(MatchError("handshake error")))
+		})
+
+		It("establishes more connections if MaxConnsPerHost allows it", func() {
+			rt.MaxConnsPerHost = 2
+			dialing := make(chan struct{}, 3)
+			dialErr := make(chan error, 3)
+			dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
+				dialing <- struct{}{}
+				return nil, <-dialErr
+			}
+			errChan := make(chan error, 3)
+			for i := 0; i < 3; i++ {
+				go func() {
+					defer GinkgoRecover()
+					_, err := rt.RoundTrip(req1)
+					errChan <- err
+				}()
+			}
+			Eventually(dialing).Should(Receive())
+			Eventually(dialing).Should(Receive())
+			Consistently(dialing).ShouldNot(Receive())
+			for i := 0; i < 3; i++ {
+				dialErr <- errors.New("handshake error")
+			}
+			for i := 0; i < 3; i++ {
+				Eventually(errChan).Should(Receive(MatchError("handshake error")))
+			}
+		})
+
+		Context("retrying requests", func() {
+			var (
+				session2 *mockquic.MockEarlySession
+				dialed   int
+			)
+
+			BeforeEach(func() {
+				dialed = 0
+				session2 = mockquic.NewMockEarlySession(mockCtrl)
+				session2.EXPECT().Context().Return(context.Background()).AnyTimes()
+				dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.EarlySession, error) {
+					dialed++
+					if dialed == 1 {
+						return session, nil
+					}
+					return session2, nil
+				}
+				session.EXPECT().OpenUniStream().Return(nil, errors.New("test done")).MaxTimes(1)
+				session.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
+				session2.EXPECT().OpenUniStream().Return(nil, errors.New("test done")).MaxTimes(1)
+				session2.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).MaxTimes(1)
+				session.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			})
+
+			It("retries a request if the connection was closed", func() {
+				testErr := errors.New("test err")
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, qerr.NewError(qerr.InternalError, "connection closed"))
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(2))
+				Expect(rt.clients["www.example.org:443"]).To(HaveLen(1))
+			})
+
+			It("only retries once", func() {
+				closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, closeErr)
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, closeErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(Equal(closeErr))
+				Expect(dialed).To(Equal(2))
+			})
+
+			It("retries non-idempotent requests that weren't sent", func() {
+				testErr := errors.New("test err")
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, qerr.NewError(qerr.InternalError, "connection closed"))
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				req, err := http.NewRequest("POST", "https://www.example.org/upload", &mockBody{})
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(2))
+			})
+
+			// expectRequestSent makes the first session fail while the request is being sent
+			expectRequestSent := func(closeErr error) {
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Write(gomock.Any()).Return(0, closeErr)
+				str.EXPECT().CancelWrite(gomock.Any())
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			}
+
+			It("retries idempotent requests that were sent", func() {
+				testErr := errors.New("test err")
+				expectRequestSent(qerr.NewError(qerr.InternalError, "connection closed"))
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(2))
+			})
+
+			It("doesn't retry non-idempotent requests that were sent", func() {
+				closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+				expectRequestSent(closeErr)
+				req, err := http.NewRequest("POST", "https://www.example.org/upload", nil)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(Equal(closeErr))
+				Expect(dialed).To(Equal(1))
+			})
+
+			It("retries requests that are marked as idempotent, rewinding the body", func() {
+				testErr := errors.New("test err")
+				expectRequestSent(qerr.NewError(qerr.InternalError, "connection closed"))
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				req, err := http.NewRequest("POST", "https://www.example.org/upload", bytes.NewReader([]byte("foobar")))
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Idempotency-Key", "1337")
+				var rewound bool
+				getBody := req.GetBody
+				req.GetBody = func() (io.ReadCloser, error) {
+					rewound = true
+					return getBody()
+				}
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(2))
+				Expect(rewound).To(BeTrue())
+			})
+
+			It("doesn't retry requests if the body can't be rewound", func() {
+				closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+				expectRequestSent(closeErr)
+				req, err := http.NewRequest("PUT", "https://www.example.org/upload", &mockBody{})
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("Idempotency-Key", "1337")
+				Expect(req.GetBody).To(BeNil())
+				_, err = rt.RoundTrip(req)
+				Expect(err).To(Equal(closeErr))
+				Expect(dialed).To(Equal(1))
+			})
+
+			It("doesn't retry if the request context is done", func() {
+				closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+				ctx, cancel := context.WithCancel(context.Background())
+				session.EXPECT().OpenStreamSync(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					cancel()
+					return nil, closeErr
+				})
+				_, err := rt.RoundTrip(req1.WithContext(ctx))
+				Expect(err).To(Equal(closeErr))
+				Expect(dialed).To(Equal(1))
+			})
+
+			It("doesn't retry if retries are disabled", func() {
+				rt.DisableRetry = true
+				closeErr := qerr.NewError(qerr.InternalError, "connection closed")
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, closeErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(Equal(closeErr))
+				Expect(dialed).To(Equal(1))
+			})
+
+			It("retries a request if the connection was closed by a stateless reset", func() {
+				testErr := errors.New("test err")
+				resetErr := errors.New("received a stateless reset")
+				session.EXPECT().OpenStreamSync(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					closeSession()
+					return nil, resetErr
+				})
+				session2.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				session2.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(2))
+			})
+
+			It("returns the original error if retries are disabled and the connection was closed by a stateless reset", func() {
+				rt.DisableRetry = true
+				resetErr := errors.New("received a stateless reset")
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+					closeSession()
+					return 0, resetErr
+				})
+				str.EXPECT().CancelWrite(gomock.Any())
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(Equal(resetErr))
+				Expect(dialed).To(Equal(1))
+			})
+
+			It("doesn't retry if the error is unrelated to the connection", func() {
+				testErr := errors.New("test err")
+				session.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+				_, err := rt.RoundTrip(req1)
+				Expect(err).To(MatchError(testErr))
+				Expect(dialed).To(Equal(1))
+			})
+		})
+
 		It("doesn't create new clients if RoundTripOpt.OnlyCachedConn is set", func() {
 			req, err := http.NewRequest("GET", "https://quic.clemente.io/foobar.html", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -224,9 +473,9 @@ var _ = Describe("RoundTripper", func() {
 
 	Context("closing", func() {
 		It("closes", func() {
-			rt.clients = make(map[string]roundTripCloser)
+			rt.clients = make(map[string][]*pooledClient)
 			cl := &mockClient{}
-			rt.clients["foo.bar"] = cl
+			rt.clients["foo.bar"] = []*pooledClient{{hostClient: cl}}
 			err := rt.Close()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(len(rt.clients)).To(BeZero())
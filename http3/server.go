@@ -27,6 +27,9 @@ var (
 
 const nextProtoH3 = "h3-29"
 
+// shutdownPollInterval is how often we check if all requests have completed, when shutting down gracefully.
+const shutdownPollInterval = 50 * time.Millisecond
+
 // contextKey is a value for use with context.WithValue. It's used as
 // a pointer so it fits in an interface{} without allocation.
 type contextKey struct {
@@ -49,6 +52,10 @@ type requestError struct {
 	connErr   errorCode
 }
 
+// errHijacked is returned by handleRequest when the handler took over the stream,
+// see DataStreamer.
+var errHijacked = errors.New("hijacked")
+
 func newStreamError(code errorCode, err error) requestError {
 	return requestError{err: err, streamErr: code}
 }
@@ -65,16 +72,46 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
+	// AdditionalSettings specifies additional HTTP/3 settings.
+	// It is invalid to specify any settings defined by the HTTP/3 draft and the QPACK draft.
+	// The server always sends SETTINGS_ENABLE_CONNECT_PROTOCOL, enabling Extended CONNECT (RFC 8441).
+	// Extended CONNECT requests are passed to the Handler, with the Proto field set to the value of the :protocol pseudo-header.
+	// The Handler can take over the request stream using the DataStreamer interface.
+	AdditionalSettings map[uint64]uint64
+
+	// OnSettings is called when the SETTINGS frame is received on a client's control stream.
+	// The map contains all settings sent by the client, including unknown and reserved (GREASE) settings.
+	// It may be called concurrently for different connections.
+	OnSettings func(map[uint64]uint64)
+
+	// OnHeaderStats is called for every header block received and sent on a request stream.
+	// It may be called concurrently for different requests.
+	OnHeaderStats func(HeaderStats)
+
 	port uint32 // used atomically
 
-	mutex     sync.Mutex
-	listeners map[*quic.EarlyListener]struct{}
-	closed    utils.AtomicBool
+	mutex          sync.Mutex
+	listeners      map[*quic.EarlyListener]struct{}
+	sessions       map[*serverSession]struct{}
+	activeRequests int
+	shuttingDown   bool
+	closed         utils.AtomicBool
 
 	loggerOnce sync.Once
 	logger     utils.Logger
 }
 
+// serverSession is a QUIC session handled by the server.
+type serverSession struct {
+	quic.EarlySession
+	controlStr quic.SendStream
+
+	// Both fields are protected by the server's mutex.
+	// nextStreamID is the stream ID following the highest request stream accepted so far.
+	nextStreamID quic.StreamID
+	goAwaySent   bool
+}
+
 // ListenAndServe listens on the UDP address s.Addr and calls s.Handler to handle HTTP/3 requests on incoming connections.
 func (s *Server) ListenAndServe() error {
 	if s.Server == nil {
@@ -177,8 +214,72 @@ func (s *Server) removeListener(l *quic.EarlyListener) {
 	s.mutex.Unlock()
 }
 
+// addSession adds a session to the set of active sessions.
+// It returns true if the server is already shutting down.
+func (s *Server) addSession(sess *serverSession) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[*serverSession]struct{})
+	}
+	s.sessions[sess] = struct{}{}
+	return s.shuttingDown
+}
+
+func (s *Server) removeSession(sess *serverSession) {
+	s.mutex.Lock()
+	delete(s.sessions, sess)
+	s.mutex.Unlock()
+}
+
+// startRequest is called for every request stream accepted on a session.
+// It returns false if the request has to be rejected, because we already sent a GOAWAY frame
+// that didn't include this stream.
+func (s *Server) startRequest(sess *serverSession, id quic.StreamID) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if id >= sess.nextStreamID {
+		if sess.goAwaySent {
+			return false
+		}
+		sess.nextStreamID = id + 4
+	}
+	s.activeRequests++
+	return true
+}
+
+func (s *Server) finishRequest() {
+	s.mutex.Lock()
+	s.activeRequests--
+	s.mutex.Unlock()
+}
+
+func (s *Server) numActiveRequests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.activeRequests
+}
+
+// sendGoAway sends a GOAWAY frame on the control stream.
+// All requests accepted so far will be processed, all later requests will be rejected.
+func (s *Server) sendGoAway(sess *serverSession) {
+	s.mutex.Lock()
+	if sess.goAwaySent {
+		s.mutex.Unlock()
+		return
+	}
+	sess.goAwaySent = true
+	id := sess.nextStreamID
+	s.mutex.Unlock()
+
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{StreamID: id}).Write(buf)
+	if _, err := sess.controlStr.Write(buf.Bytes()); err != nil {
+		s.logger.Debugf("Sending GOAWAY frame failed: %s", err)
+	}
+}
+
 func (s *Server) handleConn(sess quic.EarlySession) {
-	// TODO: accept control streams
 	decoder := qpack.NewDecoder(nil)
 
 	// send a SETTINGS frame
@@ -187,10 +288,23 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 		s.logger.Debugf("Opening the control stream failed.")
 		return
 	}
+	settings := make(map[uint64]uint64, len(s.AdditionalSettings)+1)
+	for id, val := range s.AdditionalSettings {
+		settings[id] = val
+	}
+	settings[settingExtendedConnect] = 1
 	buf := bytes.NewBuffer([]byte{0})
-	(&settingsFrame{}).Write(buf)
+	(&settingsFrame{settings: settings}).Write(buf)
 	str.Write(buf.Bytes())
 
+	serverSess := &serverSession{EarlySession: sess, controlStr: str}
+	if shuttingDown := s.addSession(serverSess); shuttingDown {
+		s.sendGoAway(serverSess)
+	}
+	defer s.removeSession(serverSess)
+
+	go s.handleUnidirectionalStreams(sess)
+
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
 	for {
@@ -199,10 +313,20 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			s.logger.Debugf("Accepting stream failed: %s", err)
 			return
 		}
+		if !s.startRequest(serverSess, str.StreamID()) {
+			s.logger.Debugf("Rejecting request on stream %d, since the server is shutting down", str.StreamID())
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
 		go func() {
+			defer s.finishRequest()
 			rerr := s.handleRequest(sess, str, decoder, func() {
 				sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 			})
+			if rerr.err == errHijacked {
+				return
+			}
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
 				s.logger.Debugf("Handling request failed: %s", err)
 				if rerr.streamErr != 0 {
@@ -222,6 +346,93 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 }
 
+func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
+	var rcvdControlStream int32 // used atomically
+	for {
+		str, err := sess.AcceptUniStream(context.Background())
+		if err != nil {
+			s.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			return
+		}
+
+		go func(str quic.ReceiveStream) {
+			streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+			if err != nil {
+				s.logger.Debugf("Reading stream type on stream %d failed: %s", str.StreamID(), err)
+				return
+			}
+			switch streamType {
+			case streamTypeControlStream:
+				// The peer must only open a single control stream.
+				if !atomic.CompareAndSwapInt32(&rcvdControlStream, 0, 1) {
+					sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+					return
+				}
+			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+				// Our QPACK implementation doesn't use the dynamic table yet.
+				// We don't need to read these streams, since the peer can't insert any entries.
+				return
+			case streamTypePushStream:
+				// only servers can push
+				sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "")
+				return
+			default:
+				str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+				return
+			}
+			if rerr := s.handleControlStream(str); rerr.connErr != 0 {
+				var reason string
+				if rerr.err != nil {
+					reason = rerr.err.Error()
+				}
+				sess.CloseWithError(quic.ErrorCode(rerr.connErr), reason)
+			}
+		}(str)
+	}
+}
+
+// handleControlStream reads the frames on the client's control stream.
+// The control stream is never closed during the lifetime of the connection,
+// so this function only returns when an error occurs.
+func (s *Server) handleControlStream(str quic.ReceiveStream) requestError {
+	f, err := parseNextFrame(str)
+	if err != nil {
+		return newConnError(errorFrameError, err)
+	}
+	sf, ok := f.(*settingsFrame)
+	if !ok {
+		return newConnError(errorMissingSettings, errors.New("expected first frame to be a SETTINGS frame"))
+	}
+	if s.OnSettings != nil {
+		s.OnSettings(sf.settings)
+	}
+	// We never push, so we just validate the MAX_PUSH_ID the client sends us.
+	var maxPushID uint64
+	var receivedMaxPushID bool
+	for {
+		f, err := parseNextFrame(str)
+		if err != nil {
+			// This also happens when the session is closed.
+			s.logger.Debugf("Reading the control stream failed: %s", err)
+			return requestError{}
+		}
+		switch f := f.(type) {
+		case *maxPushIDFrame:
+			if receivedMaxPushID && f.PushID < maxPushID {
+				return newConnError(errorIDError, fmt.Errorf("MAX_PUSH_ID reduced from %d to %d", maxPushID, f.PushID))
+			}
+			maxPushID = f.PushID
+			receivedMaxPushID = true
+		case *goAwayFrame:
+			// We don't push, so there's nothing to do when the client sends a GOAWAY frame.
+		case *cancelPushFrame:
+			// We don't push, so there's no push to cancel.
+		default:
+			return newConnError(errorFrameUnexpected, fmt.Errorf("unexpected frame on the control stream: %T", f))
+		}
+	}
+}
+
 func (s *Server) maxHeaderBytes() uint64 {
 	if s.Server.MaxHeaderBytes <= 0 {
 		return http.DefaultMaxHeaderBytes
@@ -250,6 +461,13 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		// TODO: use the right error code
 		return newConnError(errorGeneralProtocolError, err)
 	}
+	if s.OnHeaderStats != nil {
+		s.OnHeaderStats(HeaderStats{
+			StreamID:         str.StreamID(),
+			CompressedSize:   len(headerBlock),
+			UncompressedSize: headerListSize(hfs),
+		})
+	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		// TODO: use the right error code
@@ -269,8 +487,7 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	ctx = context.WithValue(ctx, ServerContextKey, s)
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
 	req = req.WithContext(ctx)
-	responseWriter := newResponseWriter(str, s.logger)
-	defer responseWriter.Flush()
+	responseWriter := newResponseWriter(str, sess, s.OnHeaderStats, s.logger)
 	handler := s.Handler
 	if handler == nil {
 		handler = http.DefaultServeMux
@@ -291,11 +508,20 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		handler.ServeHTTP(responseWriter, req)
 	}()
 
+	if responseWriter.usedDataStream() {
+		return requestError{err: errHijacked}
+	}
+
 	if panicked {
 		responseWriter.WriteHeader(500)
 	} else {
 		responseWriter.WriteHeader(200)
+		// Responses that can't have a body can't have trailers either.
+		if bodyAllowedForStatus(responseWriter.status) {
+			responseWriter.writeTrailers()
+		}
 	}
+	responseWriter.Flush()
 
 	// If the EOF was read by the handler, CancelRead() is a no-op.
 	str.CancelRead(quic.ErrorCode(errorNoError))
@@ -321,9 +547,47 @@ func (s *Server) Close() error {
 
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
+// See Shutdown for details.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// Shutdown shuts down the server gracefully, without interrupting any active requests.
+// It sends a GOAWAY frame on all connections, and rejects all requests that are not covered by the GOAWAY frame.
+// Requests on connections accepted after Shutdown was called are rejected as well.
+// Once all active requests have completed, the server is closed (see Close).
+// If the context expires before that, the server is closed immediately, and the context's error is returned.
+// Shutdown in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.closed.Set(true)
+
+	s.mutex.Lock()
+	s.shuttingDown = true
+	sessions := make([]*serverSession, 0, len(s.sessions))
+	for sess := range s.sessions {
+		sessions = append(sessions, sess)
+	}
+	s.mutex.Unlock()
+
+	for _, sess := range sessions {
+		s.sendGoAway(sess)
+	}
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		if s.numActiveRequests() == 0 {
+			return s.Close()
+		}
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
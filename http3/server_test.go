@@ -9,6 +9,8 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -79,7 +81,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			closed := make(chan struct{})
 			str.EXPECT().Close().Do(func() { close(closed) })
-			rw := newRequestWriter(utils.DefaultLogger)
+			rw := newRequestWriter(nil, utils.DefaultLogger)
 			Expect(rw.WriteRequest(str, req, false)).To(Succeed())
 			Eventually(closed).Should(BeClosed())
 			return buf.Bytes()
@@ -149,6 +151,79 @@ var _ = Describe("Server", func() {
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
 		})
 
+		It("sends trailers after the response body", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(http.TrailerPrefix+"Foo", "bar")
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Expect(decodeHeader(responseBuf)).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(decodeHeader(responseBuf)).To(HaveKeyWithValue("foo", []string{"bar"}))
+		})
+
+		for _, status := range []int{http.StatusNoContent, http.StatusNotModified} {
+			status := status
+
+			It(fmt.Sprintf("doesn't send trailers for status %d", status), func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set(http.TrailerPrefix+"Foo", "bar")
+					w.WriteHeader(status)
+				})
+
+				responseBuf := &bytes.Buffer{}
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return responseBuf.Write(p)
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				serr := s.handleRequest(sess, str, qpackDecoder, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(decodeHeader(responseBuf)).To(HaveKeyWithValue(":status", []string{strconv.Itoa(status)}))
+				Expect(responseBuf.Len()).To(BeZero())
+			})
+		}
+
+		It("reports header stats for the request and the response", func() {
+			var stats []HeaderStats
+			s.OnHeaderStats = func(st HeaderStats) { stats = append(stats, st) }
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			reqData := encodeRequest(exampleGetRequest)
+			setRequest(reqData)
+			responseBuf := &bytes.Buffer{}
+			str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			Expect(stats).To(HaveLen(2))
+			Expect(stats[0].StreamID).To(Equal(quic.StreamID(4)))
+			Expect(stats[0].Sent).To(BeFalse())
+			Expect(stats[0].CompressedSize).To(Equal(len(reqData) - 2)) // the HEADERS frame header has a length of 2 bytes
+			Expect(stats[0].UncompressedSize).To(BeNumerically(">", stats[0].CompressedSize))
+			Expect(stats[1]).To(Equal(HeaderStats{
+				StreamID:         4,
+				Sent:             true,
+				CompressedSize:   responseBuf.Len() - 2,
+				UncompressedSize: len(":status") + len("200") + 32,
+			}))
+		})
+
 		It("handles a panicking handler", func() {
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				panic("foobar")
@@ -168,6 +243,84 @@ var _ = Describe("Server", func() {
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
 		})
 
+		Context("Extended CONNECT", func() {
+			encodeExtendedConnect := func() []byte {
+				headers := &bytes.Buffer{}
+				enc := qpack.NewEncoder(headers)
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":method", Value: http.MethodConnect})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":protocol", Value: "webtransport"})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":scheme", Value: "https"})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":authority", Value: "www.example.com"})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":path", Value: "/wt"})).To(Succeed())
+				buf := &bytes.Buffer{}
+				(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+				buf.Write(headers.Bytes())
+				return buf.Bytes()
+			}
+
+			It("passes Extended CONNECT requests to the handler", func() {
+				requestChan := make(chan *http.Request, 1)
+				s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					requestChan <- r
+				})
+				setRequest(encodeExtendedConnect())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+				var req *http.Request
+				Eventually(requestChan).Should(Receive(&req))
+				Expect(req.Method).To(Equal(http.MethodConnect))
+				Expect(req.Proto).To(Equal("webtransport"))
+				Expect(req.Host).To(Equal("www.example.com"))
+				Expect(req.URL.Path).To(Equal("/wt"))
+			})
+
+			It("lets the handler take over the stream", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("foo", "bar")
+					str := w.(DataStreamer).DataStream()
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				})
+				responseBuf := &bytes.Buffer{}
+				setRequest(encodeExtendedConnect())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return responseBuf.Write(p)
+				}).AnyTimes()
+				// the stream is neither closed nor canceled
+
+				Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{err: errHijacked}))
+				hfs := decodeHeader(responseBuf)
+				Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+				Expect(hfs).To(HaveKeyWithValue("foo", []string{"bar"}))
+				Expect(responseBuf.String()).To(Equal("foobar"))
+			})
+
+			It("lets the handler open streams on the session", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					newStr := mockquic.NewMockStream(mockCtrl)
+					sess.EXPECT().OpenStream().Return(newStr, nil)
+					str, err := w.(Hijacker).StreamCreator().OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str).To(Equal(newStr))
+				})
+				setRequest(encodeExtendedConnect())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+				Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			})
+		})
+
 		Context("stream- and connection-level errors", func() {
 			var sess *mockquic.MockEarlySession
 
@@ -177,10 +330,12 @@ var _ = Describe("Server", func() {
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
 				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
 				sess.EXPECT().RemoteAddr().Return(addr).AnyTimes()
 				sess.EXPECT().LocalAddr().AnyTimes()
+				str.EXPECT().StreamID().AnyTimes()
 			})
 
 			It("cancels reading when client sends a body in GET request", func() {
@@ -268,6 +423,37 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			for _, t := range []uint64{
+				0x3,                // CANCEL_PUSH
+				0x5,                // PUSH_PROMISE
+				0xe,                // DUPLICATE_PUSH
+				0x2, 0x6, 0x8, 0x9, // reserved frame types, used in HTTP/2
+			} {
+				frameType := t
+
+				It(fmt.Sprintf("closes the connection with H3_FRAME_UNEXPECTED when the client sends a frame of type %#x on a request stream", frameType), func() {
+					handlerCalled := make(chan struct{})
+					s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						close(handlerCalled)
+					})
+
+					buf := &bytes.Buffer{}
+					utils.WriteVarInt(buf, frameType)
+					utils.WriteVarInt(buf, 1)
+					buf.WriteByte(0)
+					setRequest(buf.Bytes())
+					str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+						return len(p), nil
+					}).AnyTimes()
+
+					done := make(chan struct{})
+					sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any()).Do(func(quic.ErrorCode, string) { close(done) })
+					s.handleConn(sess)
+					Eventually(done).Should(BeClosed())
+					Expect(handlerCalled).ToNot(BeClosed())
+				})
+			}
+
 			It("closes the connection when the first frame is not a HEADERS frame", func() {
 				handlerCalled := make(chan struct{})
 				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -292,6 +478,287 @@ var _ = Describe("Server", func() {
 			})
 		})
 
+		Context("shutting down gracefully", func() {
+			var (
+				controlStr     *mockquic.MockStream
+				controlStrData *bytes.Buffer
+				controlStrMtx  sync.Mutex
+			)
+
+			BeforeEach(func() {
+				controlStrData = &bytes.Buffer{}
+				controlStr = mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					controlStrMtx.Lock()
+					defer controlStrMtx.Unlock()
+					return controlStrData.Write(p)
+				}).AnyTimes()
+				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+			})
+
+			// getGoAwayFrame parses the control stream, and returns the GOAWAY frame, if one was sent
+			getGoAwayFrame := func() *goAwayFrame {
+				controlStrMtx.Lock()
+				defer controlStrMtx.Unlock()
+				r := bytes.NewReader(controlStrData.Bytes())
+				streamType, err := r.ReadByte()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeZero())
+				frame, err := parseNextFrame(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(BeAssignableToTypeOf(&settingsFrame{}))
+				if r.Len() == 0 {
+					return nil
+				}
+				frame, err = parseNextFrame(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(BeAssignableToTypeOf(&goAwayFrame{}))
+				return frame.(*goAwayFrame)
+			}
+
+			expectRejectedRequest := func(id quic.StreamID) *mockquic.MockStream {
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().StreamID().Return(id).AnyTimes()
+				str.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+				return str
+			}
+
+			It("sends additional settings", func() {
+				s.AdditionalSettings = map[uint64]uint64{0x1337: 42}
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				s.handleConn(sess)
+				controlStrMtx.Lock()
+				defer controlStrMtx.Unlock()
+				r := bytes.NewReader(controlStrData.Bytes())
+				streamType, err := r.ReadByte()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeZero())
+				frame, err := parseNextFrame(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&settingsFrame{settings: map[uint64]uint64{0x1337: 42, settingExtendedConnect: 1}}))
+				Expect(s.AdditionalSettings).To(HaveLen(1))
+			})
+
+			It("sends a GOAWAY frame, rejects new requests and waits for active requests to complete", func() {
+				handlerCalled := make(chan struct{})
+				unblockHandler := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(handlerCalled)
+					<-unblockHandler
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(quic.ErrorCode(errorNoError))
+				str.EXPECT().Close()
+
+				acceptStr := make(chan quic.Stream, 1)
+				acceptStr <- str
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					str, ok := <-acceptStr
+					if !ok {
+						return nil, errors.New("done")
+					}
+					return str, nil
+				}).AnyTimes()
+				defer close(acceptStr)
+				go s.handleConn(sess)
+				Eventually(handlerCalled).Should(BeClosed())
+
+				shutdownErr := make(chan error, 1)
+				go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+				Eventually(getGoAwayFrame).Should(Equal(&goAwayFrame{StreamID: 8}))
+				acceptStr <- expectRejectedRequest(8)
+				acceptStr <- expectRejectedRequest(12)
+				Consistently(shutdownErr).ShouldNot(Receive())
+				close(unblockHandler)
+				Eventually(shutdownErr).Should(Receive(BeNil()))
+			})
+
+			It("returns the context's error if requests don't complete in time", func() {
+				unblockHandler := make(chan struct{})
+				defer close(unblockHandler)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-unblockHandler
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().StreamID().AnyTimes()
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				// the handler is only unblocked when this test finishes
+				str.EXPECT().CancelRead(quic.ErrorCode(errorNoError)).MaxTimes(1)
+				str.EXPECT().Close().MaxTimes(1)
+				sessClosed := make(chan struct{})
+				defer close(sessClosed)
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+					<-sessClosed
+					return nil, errors.New("done")
+				})
+				go s.handleConn(sess)
+				Eventually(s.numActiveRequests).Should(Equal(1))
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				Expect(s.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+				Expect(getGoAwayFrame()).To(Equal(&goAwayFrame{StreamID: 4}))
+			})
+
+			It("rejects all requests on sessions accepted after shutting down", func() {
+				Expect(s.Shutdown(context.Background())).To(Succeed())
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(expectRejectedRequest(0), nil)
+				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				s.handleConn(sess)
+				Expect(getGoAwayFrame()).To(Equal(&goAwayFrame{StreamID: 0}))
+			})
+		})
+
+		Context("handling unidirectional streams", func() {
+			getUniStream := func(data []byte) *mockquic.MockStream {
+				buf := bytes.NewBuffer(data)
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().StreamID().AnyTimes()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				return str
+			}
+
+			// getControlStream returns a control stream, after the stream type was already read
+			getControlStream := func(frames ...interface{ Write(*bytes.Buffer) }) *mockquic.MockStream {
+				buf := &bytes.Buffer{}
+				for _, f := range frames {
+					f.Write(buf)
+				}
+				return getUniStream(buf.Bytes())
+			}
+
+			runUniStreams := func(strs ...quic.ReceiveStream) {
+				for _, str := range strs {
+					sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+				}
+				done := make(chan struct{})
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					close(done)
+					return nil, errors.New("done")
+				})
+				s.handleUnidirectionalStreams(sess)
+				Eventually(done).Should(BeClosed())
+			}
+
+			It("passes the client's settings to the callback", func() {
+				var settings map[uint64]uint64
+				s.OnSettings = func(s map[uint64]uint64) { settings = s }
+				// 0x1f * N + 0x21 is reserved for GREASE
+				Expect(s.handleControlStream(getControlStream(
+					&settingsFrame{settings: map[uint64]uint64{0x1337: 42, 0x1f*3 + 0x21: 1}},
+				))).To(Equal(requestError{}))
+				Expect(settings).To(Equal(map[uint64]uint64{0x1337: 42, 0x1f*3 + 0x21: 1}))
+			})
+
+			It("accepts MAX_PUSH_ID frames", func() {
+				Expect(s.handleControlStream(getControlStream(
+					&settingsFrame{},
+					&maxPushIDFrame{PushID: 10},
+					&maxPushIDFrame{PushID: 20},
+				))).To(Equal(requestError{}))
+			})
+
+			It("accepts CANCEL_PUSH frames", func() {
+				Expect(s.handleControlStream(getControlStream(
+					&settingsFrame{},
+					&cancelPushFrame{PushID: 10},
+				))).To(Equal(requestError{}))
+			})
+
+			It("errors on frames of the types reserved for HTTP/2 frames", func() {
+				buf := &bytes.Buffer{}
+				(&settingsFrame{}).Write(buf)
+				utils.WriteVarInt(buf, 0x6)
+				utils.WriteVarInt(buf, 0)
+				rerr := s.handleControlStream(getUniStream(buf.Bytes()))
+				Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+				Expect(rerr.err).To(MatchError("unexpected frame on the control stream: *http3.reservedFrame"))
+			})
+
+			It("errors on PUSH_PROMISE frames", func() {
+				buf := &bytes.Buffer{}
+				(&settingsFrame{}).Write(buf)
+				utils.WriteVarInt(buf, 0x5)
+				utils.WriteVarInt(buf, 0)
+				rerr := s.handleControlStream(getUniStream(buf.Bytes()))
+				Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+			})
+
+			It("errors when the MAX_PUSH_ID is reduced", func() {
+				rerr := s.handleControlStream(getControlStream(
+					&settingsFrame{},
+					&maxPushIDFrame{PushID: 20},
+					&maxPushIDFrame{PushID: 10},
+				))
+				Expect(rerr.connErr).To(Equal(errorIDError))
+				Expect(rerr.err).To(MatchError("MAX_PUSH_ID reduced from 20 to 10"))
+			})
+
+			It("errors when the first frame on the control stream is not a SETTINGS frame", func() {
+				rerr := s.handleControlStream(getControlStream(&maxPushIDFrame{PushID: 10}))
+				Expect(rerr.connErr).To(Equal(errorMissingSettings))
+			})
+
+			It("errors when a second SETTINGS frame is received", func() {
+				rerr := s.handleControlStream(getControlStream(&settingsFrame{}, &settingsFrame{}))
+				Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+			})
+
+			It("closes the connection when the control stream contains an invalid frame", func() {
+				closed := make(chan struct{})
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+				buf := bytes.NewBuffer([]byte{streamTypeControlStream})
+				(&settingsFrame{}).Write(buf)
+				(&headersFrame{}).Write(buf)
+				runUniStreams(getUniStream(buf.Bytes()))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("closes the connection when the client opens a second control stream", func() {
+				closed := make(chan struct{})
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream").Do(func(quic.ErrorCode, string) { close(closed) })
+				// The first control stream is closed with an H3_FRAME_ERROR, since it ends after the SETTINGS frame.
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameError), gomock.Any()).AnyTimes()
+				buf := bytes.NewBuffer([]byte{streamTypeControlStream})
+				(&settingsFrame{}).Write(buf)
+				runUniStreams(getUniStream(buf.Bytes()), getUniStream(buf.Bytes()))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("closes the connection when the client opens a push stream", func() {
+				closed := make(chan struct{})
+				sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), gomock.Any()).Do(func(quic.ErrorCode, string) { close(closed) })
+				runUniStreams(getUniStream([]byte{streamTypePushStream}))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("ignores QPACK streams", func() {
+				runUniStreams(
+					getUniStream([]byte{streamTypeQPACKEncoderStream}),
+					getUniStream([]byte{streamTypeQPACKDecoderStream}),
+				)
+			})
+
+			It("cancels reading on streams of unknown type", func() {
+				str := getUniStream([]byte{0x21})
+				canceled := make(chan struct{})
+				str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError)).Do(func(quic.ErrorCode) { close(canceled) })
+				runUniStreams(str)
+				Eventually(canceled).Should(BeClosed())
+			})
+		})
+
 		It("resets the stream when the body of POST request is not read, and the request handler replaces the request.Body", func() {
 			handlerCalled := make(chan struct{})
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,75 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK frequency", func() {
+	transfer := func(serverConf, clientConf *quic.Config) (clientSess, serverSess quic.Session) {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(serverConf))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSessChan <- sess
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(clientConf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+		return sess, serverSess
+	}
+
+	It("transfers data when both endpoints enable the extension", func() {
+		sess, serverSess := transfer(
+			&quic.Config{EnableACKFrequency: true},
+			&quic.Config{EnableACKFrequency: true},
+		)
+		defer sess.CloseWithError(0, "")
+		Expect(sess.ConnectionState().RemoteTransportParameters.MinAckDelay).ToNot(BeNil())
+		Expect(serverSess.ConnectionState().RemoteTransportParameters.MinAckDelay).ToNot(BeNil())
+	})
+
+	It("transfers data when only one endpoint enables the extension", func() {
+		sess, serverSess := transfer(
+			&quic.Config{EnableACKFrequency: true},
+			&quic.Config{},
+		)
+		defer sess.CloseWithError(0, "")
+		Expect(sess.ConnectionState().RemoteTransportParameters.MinAckDelay).ToNot(BeNil())
+		Expect(serverSess.ConnectionState().RemoteTransportParameters.MinAckDelay).To(BeNil())
+	})
+})
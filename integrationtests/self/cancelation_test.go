@@ -12,6 +12,7 @@ import (
 	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -45,7 +46,11 @@ var _ = Describe("Stream Cancelations", func() {
 						str, err := sess.OpenUniStreamSync(context.Background())
 						Expect(err).ToNot(HaveOccurred())
 						if _, err := str.Write(PRData); err != nil {
-							Expect(err).To(MatchError(fmt.Sprintf("stream %d was reset with error code %d", str.StreamID(), str.StreamID())))
+							Expect(err).To(Equal(&quic.StreamError{
+								StreamID:  str.StreamID(),
+								ErrorCode: quic.StreamErrorCode(str.StreamID()),
+								Remote:    true,
+							}))
 							atomic.AddInt32(&canceledCounter, 1)
 							return
 						}
@@ -260,6 +265,50 @@ var _ = Describe("Stream Cancelations", func() {
 			clientCanceledStreams := runClient(server)
 			Expect(clientCanceledStreams).To(Equal(atomic.LoadInt32(&canceledCounter)))
 		})
+
+		It("doesn't send buffered data after canceling a stream", func() {
+			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			// The client doesn't read from the stream until the server canceled it.
+			// The server can only send as much data as the client's flow control window allows,
+			// the rest of the data is buffered in the Write call.
+			data := GeneratePRData(3 * protocol.InitialMaxStreamData)
+			canceled := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				sess, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.OpenUniStreamSync(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					time.Sleep(100 * time.Millisecond)
+					str.CancelWrite(1234)
+				}()
+				n, err := str.Write(data)
+				Expect(err).To(MatchError(fmt.Sprintf("stream %d canceled with error code 1234", str.StreamID())))
+				Expect(n).To(BeNumerically("<=", protocol.InitialMaxStreamData))
+				close(canceled)
+			}()
+
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(canceled).Should(BeClosed())
+			received, err := ioutil.ReadAll(str)
+			Expect(err).To(MatchError(fmt.Sprintf("stream %d was reset with error code 1234", str.StreamID())))
+			Expect(len(received)).To(BeNumerically("<=", protocol.InitialMaxStreamData))
+			// If the final size in the RESET_STREAM frame was wrong, the client would have closed the session.
+			Consistently(sess.Context().Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+		})
 	})
 
 	Context("canceling both read and write side", func() {
@@ -0,0 +1,65 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Closing", func() {
+	// runServer accepts a single session, and closes it using the close function
+	runServer := func(close func(quic.Session)) quic.Listener {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			close(sess)
+		}()
+		return ln
+	}
+
+	// dialAndWaitForClose dials the server and returns the error that the session was closed with
+	dialAndWaitForClose := func(ln quic.Listener) *quic.TransportError {
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = sess.AcceptStream(context.Background())
+		Expect(err).To(HaveOccurred())
+		var transportErr *quic.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeTrue())
+		return transportErr
+	}
+
+	It("closes with an application error", func() {
+		ln := runServer(func(sess quic.Session) { sess.CloseWithError(0x1337, "application error") })
+		defer ln.Close()
+		err := dialAndWaitForClose(ln)
+		Expect(err.IsApplicationError()).To(BeTrue())
+		Expect(err.ErrorCode).To(BeEquivalentTo(0x1337))
+		Expect(err.ErrorMessage).To(Equal("application error"))
+	})
+
+	It("closes with a transport error", func() {
+		ln := runServer(func(sess quic.Session) {
+			sess.CloseWithTransportError(quic.TransportErrorCode(0xa), 0x8, "transport error")
+		})
+		defer ln.Close()
+		err := dialAndWaitForClose(ln)
+		Expect(err.IsApplicationError()).To(BeFalse())
+		Expect(err.ErrorCode).To(Equal(quic.TransportErrorCode(0xa)))
+		Expect(err.FrameType).To(BeEquivalentTo(0x8))
+		Expect(err.ErrorMessage).To(Equal("transport error"))
+	})
+})
@@ -0,0 +1,89 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/congestion"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fixedWindowSender is a very simple congestion controller with a fixed congestion window.
+type fixedWindowSender struct {
+	congestion.SendAlgorithm // embedded, so we don't have to implement all methods
+
+	window       congestion.ByteCount
+	packetsSent  int32
+	packetsAcked int32
+}
+
+var _ congestion.SendAlgorithm = &fixedWindowSender{}
+
+func (s *fixedWindowSender) TimeUntilSend(congestion.ByteCount) time.Time { return time.Time{} }
+func (s *fixedWindowSender) HasPacingBudget() bool                        { return true }
+func (s *fixedWindowSender) CanSend(bytesInFlight congestion.ByteCount) bool {
+	return bytesInFlight < s.window
+}
+func (s *fixedWindowSender) MaybeExitSlowStart() {}
+func (s *fixedWindowSender) OnPacketSent(time.Time, congestion.ByteCount, congestion.PacketNumber, congestion.ByteCount, bool) {
+	atomic.AddInt32(&s.packetsSent, 1)
+}
+func (s *fixedWindowSender) OnPacketAcked(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount, time.Time) {
+	atomic.AddInt32(&s.packetsAcked, 1)
+}
+func (s *fixedWindowSender) OnPacketLost(congestion.PacketNumber, congestion.ByteCount, congestion.ByteCount) {
+}
+
+var _ = Describe("Congestion Control", func() {
+	It("uses a custom congestion controller", func() {
+		senderChan := make(chan *fixedWindowSender, 1)
+		conf := getQuicConfig(&quic.Config{
+			CongestionControlFactory: func(_ *congestion.RTTStats, initialCWND, maxCWND congestion.ByteCount) congestion.SendAlgorithm {
+				Expect(initialCWND).To(BeNumerically(">", 0))
+				Expect(maxCWND).To(BeNumerically(">=", initialCWND))
+				sender := &fixedWindowSender{window: 10 * 1252}
+				senderChan <- sender
+				return sender
+			},
+		})
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+		var sender *fixedWindowSender
+		Expect(senderChan).To(Receive(&sender))
+		Expect(atomic.LoadInt32(&sender.packetsSent)).To(BeNumerically(">", len(PRData)/1252))
+		Expect(atomic.LoadInt32(&sender.packetsAcked)).To(BeNumerically(">", 0))
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net"
+	"sync"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -73,6 +74,29 @@ var _ = Describe("Connection ID lengths tests", func() {
 		runClient(ln.Addr(), clientConf)
 	})
 
+	It("routes packets to the right session when multiple clients use 0-byte connection IDs", func() {
+		serverConf := getQuicConfig(&quic.Config{
+			ConnectionIDLength: randomConnIDLen(),
+			Versions:           []protocol.VersionNumber{protocol.VersionTLS},
+		})
+		ln := runServer(serverConf)
+		defer ln.Close()
+
+		const numClients = 5
+		var wg sync.WaitGroup
+		wg.Add(numClients)
+		for i := 0; i < numClients; i++ {
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				runClient(ln.Addr(), getQuicConfig(&quic.Config{
+					Versions: []protocol.VersionNumber{protocol.VersionTLS},
+				}))
+			}()
+		}
+		wg.Wait()
+	})
+
 	It("downloads a file when both client and server use a random connection ID length", func() {
 		serverConf := getQuicConfig(&quic.Config{
 			ConnectionIDLength: randomConnIDLen(),
@@ -0,0 +1,71 @@
+package self_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagram Transports", func() {
+	// runServer runs a server that sends PRData on a new stream for every session
+	runServer := func(conn net.PacketConn) quic.Listener {
+		ln, err := quic.Listen(conn, getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			for {
+				sess, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					str, err := sess.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+		return ln
+	}
+
+	dial := func(conn, serverConn net.PacketConn) {
+		sess, err := quic.Dial(conn, serverConn.LocalAddr(), "localhost", getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+	}
+
+	It("runs QUIC over multiple transports at the same time", func() {
+		const num = 3
+		done := make(chan struct{}, num)
+		for i := 0; i < num; i++ {
+			clientEnd, serverEnd := net.Pipe()
+			clientConn := quic.NewDatagramConn(clientEnd)
+			defer clientConn.Close()
+			serverConn := quic.NewDatagramConn(serverEnd)
+			ln := runServer(serverConn)
+			defer ln.Close()
+			go func() {
+				defer GinkgoRecover()
+				dial(clientConn, serverConn)
+				done <- struct{}{}
+			}()
+		}
+		for i := 0; i < num; i++ {
+			Eventually(done, 5*time.Second).Should(Receive())
+		}
+	})
+})
@@ -0,0 +1,71 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"runtime"
+
+	"github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" {
+			Skip("ECN is only supported on Linux")
+		}
+	})
+
+	It("reports the ECN counts of packets marked with ECT(0)", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{EnableECN: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			serverSessChan <- sess
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableECN: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		// The server reported the packets we sent with ECT(0), and vice versa.
+		Expect(sess.ConnectionState().ECT0).ToNot(BeZero())
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+		Expect(serverSess.ConnectionState().ECT0).ToNot(BeZero())
+		for _, cs := range []quic.ConnectionState{sess.ConnectionState(), serverSess.ConnectionState()} {
+			Expect(cs.ECT1).To(BeZero())
+			Expect(cs.ECNCE).To(BeZero())
+		}
+	})
+})
@@ -153,6 +153,6 @@ var _ = Describe("Handshake RTT tests", func() {
 			clientConfig,
 		)
 		Expect(err).To(HaveOccurred())
-		Expect(err.Error()).To(ContainSubstring("Handshake did not complete in time"))
+		Expect(err).To(MatchError(quic.ErrHandshakeTimeout))
 	})
 })
@@ -3,9 +3,12 @@ package self_test
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
 	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
@@ -46,6 +49,19 @@ func (c *tokenStore) Pop(key string) *quic.ClientToken {
 	return c.store.Pop(key)
 }
 
+func (c *tokenStore) Invalidate(key string) {
+	c.store.Invalidate(key)
+}
+
+type tlsAlertError struct {
+	alert uint8
+}
+
+var _ quic.HandshakeError = &tlsAlertError{}
+
+func (e *tlsAlertError) Error() string   { return "rejected" }
+func (e *tlsAlertError) TLSAlert() uint8 { return e.alert }
+
 var _ = Describe("Handshake tests", func() {
 	var (
 		server        quic.Listener
@@ -129,6 +145,21 @@ var _ = Describe("Handshake tests", func() {
 				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
 				Expect(sess.CloseWithError(0, "")).To(Succeed())
 			})
+
+			It("fails if the client disabled version negotiation", func() {
+				serverConfig.Versions = supportedVersions
+				runServer(getTLSConfig())
+				defer server.Close()
+				_, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:                  []protocol.VersionNumber{7, protocol.SupportedVersions[0]},
+						DisableVersionNegotiation: true,
+					}),
+				)
+				Expect(err).To(MatchError(quic.ErrVersionNegotiationReceived))
+			})
 		})
 	}
 
@@ -176,6 +207,46 @@ var _ = Describe("Handshake tests", func() {
 		}
 	})
 
+	Context("using different key exchange groups", func() {
+		for n, id := range map[string]tls.CurveID{
+			"X25519":    tls.X25519,
+			"CurveP256": tls.CurveP256,
+			"CurveP384": tls.CurveP384,
+		} {
+			name := n
+			curveID := id
+
+			// The client sends a key share for X25519, so all other groups require a HelloRetryRequest.
+			It(fmt.Sprintf("using %s", name), func() {
+				tlsConf := getTLSConfig()
+				tlsConf.CurvePreferences = []tls.CurveID{curveID}
+				ln, err := quic.ListenAddr("localhost:0", tlsConf, serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				serverSessChan := make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+				}()
+
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					nil,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				Expect(sess.ConnectionState().KeyExchangeGroup).To(Equal(curveID))
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+				Expect(serverSess.ConnectionState().KeyExchangeGroup).To(Equal(curveID))
+			})
+		}
+	})
+
 	Context("Certificate validation", func() {
 		for _, v := range protocol.SupportedVersions {
 			version := v
@@ -218,14 +289,11 @@ var _ = Describe("Handshake tests", func() {
 					Expect(err).To(MatchError("CRYPTO_ERROR: x509: cannot validate certificate for 127.0.0.1 because it doesn't contain any IP SANs"))
 				})
 
-				It("fails the handshake if the client fails to provide the requested client cert", func() {
-					tlsConf := getTLSConfig()
-					tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
-					runServer(tlsConf)
-
+				// dialAndGetHandshakeError dials the server and returns the error caused by the server rejecting the client's certificate.
+				dialAndGetHandshakeError := func(tlsConf *tls.Config) error {
 					sess, err := quic.DialAddr(
 						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
-						getTLSClientConfig(),
+						tlsConf,
 						clientConfig,
 					)
 					// Usually, the error will occur after the client already finished the handshake.
@@ -240,7 +308,74 @@ var _ = Describe("Handshake tests", func() {
 						}()
 						Eventually(errChan).Should(Receive(&err))
 					}
-					Expect(err).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
+					return err
+				}
+
+				It("fails the handshake if the client fails to provide the requested client cert", func() {
+					tlsConf := getTLSConfig()
+					tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+					runServer(tlsConf)
+					Expect(dialAndGetHandshakeError(getTLSClientConfig())).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
+				})
+
+				Context("requiring client certs in GetConfigForClient", func() {
+					var verifyErr error
+					var verifiedCerts chan []*x509.Certificate
+
+					BeforeEach(func() {
+						verifyErr = nil
+						verifiedCerts = make(chan []*x509.Certificate, 1)
+						tlsConf := getTLSConfig()
+						tlsConf.GetConfigForClient = func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
+							conf := getTLSConfig()
+							if ch.ServerName == "localhost" {
+								conf.ClientAuth = tls.RequireAndVerifyClientCert
+								conf.ClientCAs = getTLSClientConfig().RootCAs
+								conf.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+									verifiedCerts <- chains[0]
+									return verifyErr
+								}
+							}
+							return conf, nil
+						}
+						runServer(tlsConf)
+					})
+
+					getTLSClientConfigWithCert := func() *tls.Config {
+						conf := getTLSClientConfig()
+						conf.Certificates = getTLSConfig().Certificates
+						return conf
+					}
+
+					It("fails the handshake if the client doesn't provide a cert", func() {
+						Expect(dialAndGetHandshakeError(getTLSClientConfig())).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
+						Expect(verifiedCerts).ToNot(Receive())
+					})
+
+					It("accepts the client cert", func() {
+						sess, err := quic.DialAddr(
+							fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+							getTLSClientConfigWithCert(),
+							clientConfig,
+						)
+						Expect(err).ToNot(HaveOccurred())
+						var chain []*x509.Certificate
+						Eventually(verifiedCerts).Should(Receive(&chain))
+						Expect(chain[0].DNSNames).To(ContainElement("localhost"))
+						sess.CloseWithError(0, "")
+					})
+
+					It("fails the handshake if VerifyPeerCertificate rejects the client cert", func() {
+						verifyErr = errors.New("rejected")
+						Expect(dialAndGetHandshakeError(getTLSClientConfigWithCert())).To(MatchError("CRYPTO_ERROR: tls: bad certificate"))
+						Eventually(verifiedCerts).Should(Receive())
+					})
+
+					It("uses the TLS alert of a HandshakeError returned by VerifyPeerCertificate", func() {
+						verifyErr = &tlsAlertError{alert: 49} // access_denied
+						Expect(dialAndGetHandshakeError(getTLSClientConfigWithCert())).To(MatchError("CRYPTO_ERROR: tls: access denied"))
+						Eventually(verifiedCerts).Should(Receive())
+					})
 				})
 
 				It("uses the ServerName in the tls.Config", func() {
@@ -254,6 +389,39 @@ var _ = Describe("Handshake tests", func() {
 					)
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("uses the ServerName for SNI and certificate validation, independent of the dialed host", func() {
+					sniChan := make(chan string, 1)
+					serverTLSConf := getTLSConfigForExampleCom()
+					serverTLSConf.GetConfigForClient = func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+						sniChan <- chi.ServerName
+						return nil, nil
+					}
+					runServer(serverTLSConf)
+					tlsConf := getTLSClientConfig()
+					tlsConf.ServerName = "example.com"
+					sess, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+						tlsConf,
+						clientConfig,
+					)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sniChan).To(Receive(Equal("example.com")))
+					Expect(sess.ConnectionState().PeerCertificates[0].DNSNames).To(Equal([]string{"example.com"}))
+					Expect(sess.ConnectionState().ServerName).To(Equal("example.com"))
+				})
+
+				It("errors if the certificate isn't valid for the ServerName, even if it is valid for the dialed host", func() {
+					runServer(getTLSConfig())
+					tlsConf := getTLSClientConfig()
+					tlsConf.ServerName = "example.com"
+					_, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+						tlsConf,
+						clientConfig,
+					)
+					Expect(err).To(MatchError("CRYPTO_ERROR: x509: certificate is valid for localhost, not example.com"))
+				})
 			})
 		}
 	})
@@ -359,6 +527,113 @@ var _ = Describe("Handshake tests", func() {
 
 	})
 
+	It("rejects new connection attempts if the maximum number of connections is reached", func() {
+		serverConfig.MaxIncomingConnections = 2
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		go func() {
+			defer GinkgoRecover()
+			for {
+				if _, err := server.Accept(context.Background()); err != nil {
+					return
+				}
+			}
+		}()
+
+		dial := func() (quic.Session, error) {
+			return quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+		}
+
+		firstSess, err := dial()
+		Expect(err).ToNot(HaveOccurred())
+		sess, err := dial()
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+
+		_, err = dial()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ConnectionRefused))
+
+		// closing one of the sessions frees up a slot
+		Expect(firstSess.CloseWithError(0, "")).To(Succeed())
+		Eventually(func() error {
+			sess, err := dial()
+			if err == nil {
+				sess.CloseWithError(0, "")
+			}
+			return err
+		}).Should(Succeed())
+	})
+
+	It("confirms the handshake after completing it", func() {
+		ln, err := quic.ListenAddrEarly("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		serverConfirmed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			<-sess.HandshakeComplete().Done()
+			// the server confirms the handshake as soon as it completes
+			Eventually(sess.HandshakeConfirmed().Done()).Should(BeClosed())
+			Expect(sess.ConnectionState().HandshakeConfirmed).To(BeTrue())
+			close(serverConfirmed)
+		}()
+
+		sess, err := quic.DialAddrEarly(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		Expect(sess.ConnectionState().HandshakeComplete).To(BeTrue())
+		// the client confirms the handshake when it receives the HANDSHAKE_DONE frame
+		Eventually(sess.HandshakeConfirmed().Done()).Should(BeClosed())
+		Expect(sess.ConnectionState().HandshakeConfirmed).To(BeTrue())
+		Eventually(serverConfirmed).Should(BeClosed())
+	})
+
+	It("transfers data when both endpoints disable header protection", func() {
+		os.Setenv("QUIC_GO_DISABLE_HEADER_PROTECTION", "1")
+		defer os.Setenv("QUIC_GO_DISABLE_HEADER_PROTECTION", "")
+
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{DisableHeaderProtection: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{DisableHeaderProtection: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+	})
+
 	Context("ALPN", func() {
 		It("negotiates an application protocol", func() {
 			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
@@ -460,6 +735,34 @@ var _ = Describe("Handshake tests", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("reports if a Retry was performed", func() {
+			for _, doRetry := range []bool{true, false} {
+				serverConfig.AcceptToken = func(_ net.Addr, token *quic.Token) bool {
+					return !doRetry || token != nil
+				}
+				server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+				Expect(err).ToNot(HaveOccurred())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sess.ConnectionState().UsedRetry).To(Equal(doRetry))
+				}()
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(nil),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.ConnectionState().UsedRetry).To(Equal(doRetry))
+				Eventually(done).Should(BeClosed())
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Expect(server.Close()).To(Succeed())
+			}
+		})
+
 		It("rejects invalid Retry token with the INVALID_TOKEN error", func() {
 			tokenChan := make(chan *quic.Token, 10)
 			serverConfig.AcceptToken = func(addr net.Addr, token *quic.Token) bool {
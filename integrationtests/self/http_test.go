@@ -6,11 +6,14 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
 	"time"
 
@@ -24,11 +27,6 @@ import (
 	"github.com/onsi/gomega/gbytes"
 )
 
-type streamCancelError interface {
-	Canceled() bool
-	ErrorCode() protocol.ApplicationErrorCode
-}
-
 var _ = Describe("HTTP tests", func() {
 	var (
 		mux            *http.ServeMux
@@ -161,6 +159,53 @@ var _ = Describe("HTTP tests", func() {
 				Expect(resp.Header.Get("lorem")).To(Equal("ipsum"))
 			})
 
+			It("sets and gets response trailers", func() {
+				mux.HandleFunc("/trailers", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("Trailer", "Grpc-Status")
+					w.Write(PRData)
+					w.Header().Set("Grpc-Status", "0")
+					w.Header().Set(http.TrailerPrefix+"Grpc-Message", "ok")
+				})
+
+				resp, err := client.Get("https://localhost:" + port + "/trailers")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(Equal(PRData))
+				Expect(resp.Trailer.Get("Grpc-Status")).To(Equal("0"))
+				Expect(resp.Trailer.Get("Grpc-Message")).To(Equal("ok"))
+			})
+
+			It("receives informational responses", func() {
+				mux.HandleFunc("/early-hints", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+					w.WriteHeader(http.StatusEarlyHints)
+					w.Header().Del("Link")
+					w.Write([]byte("foobar"))
+				})
+
+				var hints []string
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						Expect(code).To(Equal(http.StatusEarlyHints))
+						hints = append(hints, header.Get("Link"))
+						return nil
+					},
+				}
+				req, err := http.NewRequest(http.MethodGet, "https://localhost:"+port+"/early-hints", nil)
+				Expect(err).ToNot(HaveOccurred())
+				resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(context.Background(), trace)))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+				Expect(hints).To(Equal([]string{"</style.css>; rel=preload; as=style"}))
+				body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 3*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(Equal([]byte("foobar")))
+			})
+
 			It("downloads a small file", func() {
 				resp, err := client.Get("https://localhost:" + port + "/prdata")
 				Expect(err).ToNot(HaveOccurred())
@@ -260,10 +305,10 @@ var _ = Describe("HTTP tests", func() {
 					for {
 						if _, err := w.Write([]byte("foobar")); err != nil {
 							Expect(r.Context().Done()).To(BeClosed())
-							serr, ok := err.(streamCancelError)
-							Expect(ok).To(BeTrue())
-							Expect(serr.Canceled()).To(BeTrue())
-							Expect(serr.ErrorCode()).To(BeEquivalentTo(0x10c))
+							var serr *quic.StreamError
+							Expect(errors.As(err, &serr)).To(BeTrue())
+							Expect(serr.Remote).To(BeTrue())
+							Expect(serr.ErrorCode).To(BeEquivalentTo(0x10c))
 							return
 						}
 					}
@@ -282,6 +327,63 @@ var _ = Describe("HTTP tests", func() {
 				Expect(err).To(HaveOccurred())
 			})
 
+			It("times out if the response headers don't arrive in time", func() {
+				unblockHandler := make(chan struct{})
+				defer close(unblockHandler)
+				mux.HandleFunc("/stall", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					select {
+					case <-unblockHandler:
+					case <-r.Context().Done():
+					}
+				})
+				client.Transport.(*http3.RoundTripper).ResponseHeaderTimeout = 100 * time.Millisecond
+
+				start := time.Now()
+				_, err := client.Get("https://localhost:" + port + "/stall")
+				Expect(err).To(HaveOccurred())
+				var nerr net.Error
+				Expect(errors.As(err, &nerr)).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+				// the timeout doesn't affect subsequent requests that complete in time
+				resp, err := client.Get("https://localhost:" + port + "/hello")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(200))
+			})
+
+			It("finishes active requests when shutting down gracefully", func() {
+				handlerCalled := make(chan struct{})
+				unblockHandler := make(chan struct{})
+				mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+					defer GinkgoRecover()
+					close(handlerCalled)
+					<-unblockHandler
+					w.Write(PRData) // don't check the error here. Stream may be reset.
+				})
+
+				respChan := make(chan *http.Response, 1)
+				go func() {
+					defer GinkgoRecover()
+					resp, err := client.Get("https://localhost:" + port + "/slow")
+					Expect(err).ToNot(HaveOccurred())
+					respChan <- resp
+				}()
+				Eventually(handlerCalled).Should(BeClosed())
+
+				shutdownErr := make(chan error, 1)
+				go func() { shutdownErr <- server.Shutdown(context.Background()) }()
+				Consistently(shutdownErr).ShouldNot(Receive())
+				close(unblockHandler)
+				var resp *http.Response
+				Eventually(respChan).Should(Receive(&resp))
+				Expect(resp.StatusCode).To(Equal(200))
+				body, err := ioutil.ReadAll(gbytes.TimeoutReader(resp.Body, 5*time.Second))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(Equal(PRData))
+				Eventually(shutdownErr).Should(Receive(BeNil()))
+			})
+
 			It("allows streamed HTTP requests", func() {
 				done := make(chan struct{})
 				mux.HandleFunc("/echoline", func(w http.ResponseWriter, r *http.Request) {
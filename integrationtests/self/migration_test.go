@@ -0,0 +1,338 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Migration", func() {
+	var (
+		server quic.Listener
+		// the remote address of the server's session, sent every time a stream was echoed
+		serverAddrs chan net.Addr
+		serverSess  chan quic.Session
+	)
+
+	runServerOnConn := func(conn net.PacketConn, conf *quic.Config) {
+		var err error
+		server, err = quic.Listen(conn, getTLSConfig(), conf)
+		Expect(err).ToNot(HaveOccurred())
+		serverAddrs = make(chan net.Addr, 10)
+		serverSess = make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSess <- sess
+			for {
+				str, err := sess.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				_, err = io.Copy(str, str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				serverAddrs <- sess.RemoteAddr()
+			}
+		}()
+	}
+
+	runServer := func(conf *quic.Config) {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		conn, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		runServerOnConn(conn, conf)
+	}
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	dialWithConfig := func(conf *quic.Config) quic.Session {
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(conf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return sess
+	}
+
+	dial := func() quic.Session { return dialWithConfig(nil) }
+
+	port := func(addr net.Addr) int { return addr.(*net.UDPAddr).Port }
+
+	// echo echoes data on a new stream, and returns the port that the server saw the client's packets coming from
+	echo := func(sess quic.Session) int {
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		var addr net.Addr
+		Eventually(serverAddrs).Should(Receive(&addr))
+		return port(addr)
+	}
+
+	// migratePath retries as long as the handshake is not yet confirmed
+	migratePath := func(ctx context.Context, sess quic.Session, conn net.PacketConn) error {
+		var err error
+		Eventually(func() bool {
+			err = sess.MigratePath(ctx, conn)
+			return err != quic.ErrHandshakeNotConfirmed
+		}).Should(BeTrue())
+		return err
+	}
+
+	It("migrates to a new path", func() {
+		runServer(getQuicConfig(&quic.Config{AllowConnectionMigration: true}))
+		sess := dial()
+		defer sess.CloseWithError(0, "")
+		oldAddr := sess.LocalAddr()
+		Expect(echo(sess)).To(Equal(port(oldAddr)))
+
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(migratePath(context.Background(), sess, conn)).To(Succeed())
+		Expect(port(sess.LocalAddr())).To(Equal(port(conn.LocalAddr())))
+		path := sess.CurrentPath()
+		Expect(port(path.LocalAddr)).To(Equal(port(conn.LocalAddr())))
+		Expect(path.Validated).To(BeTrue())
+		// The server switches to the new path once it receives a non-probing packet on that path.
+		Expect(echo(sess)).To(Equal(port(conn.LocalAddr())))
+		Expect(echo(sess)).To(Equal(port(conn.LocalAddr())))
+	})
+
+	It("doesn't migrate if the server disabled active migration", func() {
+		runServer(getQuicConfig(nil))
+		sess := dial()
+		defer sess.CloseWithError(0, "")
+		echo(sess)
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(migratePath(context.Background(), sess, conn)).To(MatchError("the peer disabled active connection migration"))
+	})
+
+	It("doesn't migrate if the server disabled active migration, even if it allows connection migration", func() {
+		runServer(getQuicConfig(&quic.Config{
+			AllowConnectionMigration: true,
+			DisableActiveMigration:   true,
+		}))
+		sess := dial()
+		defer sess.CloseWithError(0, "")
+		echo(sess)
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		Expect(migratePath(context.Background(), sess, conn)).To(MatchError("the peer disabled active connection migration"))
+		Expect(port(sess.LocalAddr())).ToNot(Equal(port(conn.LocalAddr())))
+	})
+
+	It("stays on the old path if path validation fails", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		newPort := port(conn.LocalAddr())
+		runServer(getQuicConfig(&quic.Config{
+			AllowConnectionMigration: true,
+			// drop all packets sent on the new path
+			IncomingPacketInterceptor: func(data []byte, addr net.Addr) (bool, []byte) {
+				return port(addr) != newPort, nil
+			},
+		}))
+
+		sess := dial()
+		defer sess.CloseWithError(0, "")
+		oldAddr := sess.LocalAddr()
+		echo(sess)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+		defer cancel()
+		Expect(migratePath(ctx, sess, conn)).To(MatchError(context.DeadlineExceeded))
+		Expect(sess.LocalAddr()).To(Equal(oldAddr))
+		Expect(echo(sess)).To(Equal(port(oldAddr)))
+	})
+
+	It("handles NAT rebindings", func() {
+		runServer(getQuicConfig(&quic.Config{AllowConnectionMigration: true}))
+		conn := newRebindingConn()
+		defer conn.Close()
+		sess, err := quic.Dial(conn, server.Addr(), "localhost", getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		var ssess quic.Session
+		Eventually(serverSess).Should(Receive(&ssess))
+		Expect(echo(sess)).To(Equal(conn.port()))
+
+		oldPort := conn.port()
+		conn.rebind()
+		Expect(conn.port()).ToNot(Equal(oldPort))
+		// The server switches to the new address right away, and validates it.
+		Expect(echo(sess)).To(Equal(conn.port()))
+		Eventually(func() bool { return ssess.CurrentPath().Validated }).Should(BeTrue())
+		Expect(port(ssess.CurrentPath().RemoteAddr)).To(Equal(conn.port()))
+		Expect(echo(sess)).To(Equal(conn.port()))
+	})
+
+	It("validates the new address after a NAT rebinding, if required", func() {
+		runServer(getQuicConfig(&quic.Config{RequireAddressValidationOnRebind: true}))
+		conn := newRebindingConn()
+		defer conn.Close()
+		sess, err := quic.Dial(conn, server.Addr(), "localhost", getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		var ssess quic.Session
+		Eventually(serverSess).Should(Receive(&ssess))
+		Expect(echo(sess)).To(Equal(conn.port()))
+
+		conn.rebind()
+		// The server only switches to the new address once it validated it.
+		Eventually(func() int {
+			echo(sess)
+			return port(ssess.RemoteAddr())
+		}).Should(Equal(conn.port()))
+		Expect(ssess.CurrentPath().Validated).To(BeTrue())
+		Expect(echo(sess)).To(Equal(conn.port()))
+	})
+
+	Context("preferred address", func() {
+		// runPreferredAddressServer runs a server that listens on all interfaces,
+		// and advertises 127.0.0.2 as its preferred address.
+		runPreferredAddressServer := func() *net.UDPAddr {
+			conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			preferredAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2).To4(), Port: port(conn.LocalAddr())}
+			runServerOnConn(conn, getQuicConfig(&quic.Config{
+				PreferredAddress: &quic.PreferredAddress{IPv4: preferredAddr},
+			}))
+			return preferredAddr
+		}
+
+		It("migrates to the server's preferred address", func() {
+			preferredAddr := runPreferredAddressServer()
+			sess := dial()
+			defer sess.CloseWithError(0, "")
+			Expect(sess.RemoteAddr().(*net.UDPAddr).IP.Equal(preferredAddr.IP)).To(BeFalse())
+			Eventually(func() net.Addr { return sess.RemoteAddr() }).Should(Equal(preferredAddr))
+			Expect(echo(sess)).To(Equal(port(sess.LocalAddr())))
+		})
+
+		It("doesn't migrate to the server's preferred address if disabled", func() {
+			runPreferredAddressServer()
+			sess := dialWithConfig(&quic.Config{DisablePathMigrationToPreferredAddress: true})
+			defer sess.CloseWithError(0, "")
+			remoteAddr := sess.RemoteAddr()
+			echo(sess)
+			Consistently(func() net.Addr { return sess.RemoteAddr() }, 100*time.Millisecond).Should(Equal(remoteAddr))
+		})
+	})
+})
+
+// A rebindingConn is a net.PacketConn that simulates a NAT rebinding:
+// After rebind is called, packets are sent from a new local port.
+// Packets sent to the old port are still received.
+type rebindingConn struct {
+	mutex sync.Mutex
+	conn  *net.UDPConn
+	conns []*net.UDPConn
+
+	packets chan rebindingConnPacket
+	closed  chan struct{}
+}
+
+type rebindingConnPacket struct {
+	data []byte
+	addr net.Addr
+}
+
+var _ net.PacketConn = &rebindingConn{}
+
+func newRebindingConn() *rebindingConn {
+	c := &rebindingConn{
+		packets: make(chan rebindingConnPacket, 100),
+		closed:  make(chan struct{}),
+	}
+	c.rebind()
+	return c
+}
+
+func (c *rebindingConn) rebind() {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	Expect(err).ToNot(HaveOccurred())
+	c.mutex.Lock()
+	c.conn = conn
+	c.conns = append(c.conns, conn)
+	c.mutex.Unlock()
+	go func() {
+		for {
+			data := make([]byte, 1500)
+			n, addr, err := conn.ReadFrom(data)
+			if err != nil {
+				return
+			}
+			select {
+			case c.packets <- rebindingConnPacket{data: data[:n], addr: addr}:
+			case <-c.closed:
+				return
+			}
+		}
+	}()
+}
+
+func (c *rebindingConn) current() *net.UDPConn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conn
+}
+
+func (c *rebindingConn) port() int { return c.current().LocalAddr().(*net.UDPAddr).Port }
+
+func (c *rebindingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.addr, nil
+	case <-c.closed:
+		return 0, nil, errors.New("closed")
+	}
+}
+
+func (c *rebindingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.current().WriteTo(b, addr)
+}
+
+func (c *rebindingConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+	close(c.closed)
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+func (c *rebindingConn) LocalAddr() net.Addr              { return c.current().LocalAddr() }
+func (c *rebindingConn) SetDeadline(time.Time) error      { return nil }
+func (c *rebindingConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *rebindingConn) SetWriteDeadline(time.Time) error { return nil }
@@ -99,6 +99,42 @@ var _ = Describe("Multiplexing", func() {
 					Eventually(done2, timeout).Should(BeClosed())
 				})
 
+				It("keeps using the conn after closing a session dialed with DialEarlyContext", func() {
+					server := getListener()
+					runServer(server)
+					defer server.Close()
+
+					addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+					Expect(err).ToNot(HaveOccurred())
+					conn, err := net.ListenUDP("udp", addr)
+					Expect(err).ToNot(HaveOccurred())
+					defer conn.Close()
+
+					sess, err := quic.DialEarlyContext(
+						context.Background(),
+						conn,
+						server.Addr(),
+						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+						getTLSClientConfig(),
+						getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+					)
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := ioutil.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(PRData))
+					Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						dial(conn, server.Addr())
+						close(done)
+					}()
+					Eventually(done, 10*time.Second).Should(BeClosed())
+				})
+
 				It("multiplexes connections to different servers", func() {
 					server1 := getListener()
 					runServer(server1)
@@ -3,12 +3,14 @@ package self_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
 	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -111,3 +113,114 @@ var _ = Describe("Packetization", func() {
 		))
 	})
 })
+
+var _ = Describe("Packet Coalescing", func() {
+	// isCoalesced says if a datagram contains more than one QUIC packet
+	isCoalesced := func(data []byte) bool {
+		_, _, rest, err := wire.ParsePacket(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		return len(rest) > 0
+	}
+
+	It("completes the handshake through a middlebox that drops coalesced packets, if coalescing is disabled", func() {
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{DisablePacketCoalescing: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		serverAddr := fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port)
+
+		var numCoalesced uint32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: serverAddr,
+			DropPacket: func(_ quicproxy.Direction, data []byte) bool {
+				if isCoalesced(data) {
+					atomic.AddUint32(&numCoalesced, 1)
+					return true
+				}
+				return false
+			},
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{DisablePacketCoalescing: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+		Expect(atomic.LoadUint32(&numCoalesced)).To(BeZero())
+	})
+})
+
+var _ = Describe("Max UDP Payload Size", func() {
+	It("doesn't send datagrams larger than the max_udp_payload_size of the peer", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRDataLong)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		var maxSize uint32 // the size of the largest datagram sent by the server
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(dir quicproxy.Direction, data []byte) time.Duration {
+				if dir == quicproxy.DirectionOutgoing {
+					for {
+						size := atomic.LoadUint32(&maxSize)
+						if uint32(len(data)) <= size || atomic.CompareAndSwapUint32(&maxSize, size, uint32(len(data))) {
+							break
+						}
+					}
+				}
+				return 0
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{MaxUDPPayloadSize: 1200}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong))
+		Expect(atomic.LoadUint32(&maxSize)).To(BeNumerically("<=", 1200))
+		Expect(atomic.LoadUint32(&maxSize)).To(BeNumerically(">", 1100))
+	})
+})
@@ -89,10 +89,11 @@ var (
 	enableQlog    bool
 	enableMetrics bool
 
-	tlsConfig          *tls.Config
-	tlsConfigLongChain *tls.Config
-	tlsClientConfig    *tls.Config
-	tracer             logging.Tracer
+	tlsConfig           *tls.Config
+	tlsConfigExampleCom *tls.Config
+	tlsConfigLongChain  *tls.Config
+	tlsClientConfig     *tls.Config
+	tracer              logging.Tracer
 )
 
 // read the logfile command line flag
@@ -111,7 +112,7 @@ var _ = BeforeSuite(func() {
 	if err != nil {
 		panic(err)
 	}
-	leafCert, leafPrivateKey, err := generateLeafCert(ca, caPrivateKey)
+	leafCert, leafPrivateKey, err := generateLeafCert(ca, caPrivateKey, "localhost")
 	if err != nil {
 		panic(err)
 	}
@@ -122,6 +123,17 @@ var _ = BeforeSuite(func() {
 		}},
 		NextProtos: []string{alpn},
 	}
+	exampleComCert, exampleComPrivateKey, err := generateLeafCert(ca, caPrivateKey, "example.com")
+	if err != nil {
+		panic(err)
+	}
+	tlsConfigExampleCom = &tls.Config{
+		Certificates: []tls.Certificate{tls.Certificate{
+			Certificate: [][]byte{exampleComCert.Raw},
+			PrivateKey:  exampleComPrivateKey,
+		}},
+		NextProtos: []string{alpn},
+	}
 	tlsConfLongChain, err := generateTLSConfigWithLongCertChain(ca, caPrivateKey)
 	if err != nil {
 		panic(err)
@@ -189,10 +201,10 @@ func generateCA() (*x509.Certificate, *rsa.PrivateKey, error) {
 	return ca, caPrivateKey, nil
 }
 
-func generateLeafCert(ca *x509.Certificate, caPrivateKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey, error) {
+func generateLeafCert(ca *x509.Certificate, caPrivateKey *rsa.PrivateKey, dnsName string) (*x509.Certificate, *rsa.PrivateKey, error) {
 	certTempl := &x509.Certificate{
 		SerialNumber: big.NewInt(1),
-		DNSNames:     []string{"localhost"},
+		DNSNames:     []string{dnsName},
 		NotBefore:    time.Now(),
 		NotAfter:     time.Now().Add(24 * time.Hour),
 		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
@@ -248,7 +260,7 @@ func generateTLSConfigWithLongCertChain(ca *x509.Certificate, caPrivateKey *rsa.
 		lastCA = ca
 		lastCAPrivKey = privKey
 	}
-	leafCert, leafPrivateKey, err := generateLeafCert(lastCA, lastCAPrivKey)
+	leafCert, leafPrivateKey, err := generateLeafCert(lastCA, lastCAPrivKey, "localhost")
 	if err != nil {
 		return nil, err
 	}
@@ -272,6 +284,11 @@ func getTLSConfig() *tls.Config {
 	return tlsConfig.Clone()
 }
 
+// getTLSConfigForExampleCom returns a tls.Config with a certificate that is valid for example.com.
+func getTLSConfigForExampleCom() *tls.Config {
+	return tlsConfigExampleCom.Clone()
+}
+
 func getTLSConfigWithLongCertChain() *tls.Config {
 	return tlsConfigLongChain.Clone()
 }
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net"
 	"sync"
+	"time"
 
 	quic "github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -161,3 +162,163 @@ var _ = Describe("Bidirectional streams", func() {
 		})
 	}
 })
+
+var _ = Describe("Stream flow control", func() {
+	It("uses a larger receive window for selected streams", func() {
+		const window = 700 << 10 // larger than the initial stream flow control window of 512 kB
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				StreamReceiveWindowFunc: func(id quic.StreamID) uint64 {
+					if id == 0 {
+						return window
+					}
+					return 0
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverSess := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverSess <- sess
+		}()
+
+		client, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		data := GeneratePRData(window)
+		written := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(written)
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		// The server doesn't read any data until the client has written all of it.
+		Eventually(written, 5*time.Second).Should(BeClosed())
+
+		var sess quic.Session
+		Eventually(serverSess).Should(Receive(&sess))
+		serverStr, err := sess.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		dataRead, err := ioutil.ReadAll(serverStr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dataRead).To(Equal(data))
+	})
+
+	It("writes to a non-blocking stream", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		dataRead := make(chan []byte, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			dataRead <- data
+		}()
+
+		client, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		str.SetNonBlocking(true)
+		// more data than the initial flow control window allows
+		data := GeneratePRData(2 << 20)
+		toWrite := data
+		var numBlocked int
+		for len(toWrite) > 0 {
+			n, err := str.Write(toWrite)
+			toWrite = toWrite[n:]
+			if err == quic.ErrWouldBlock {
+				numBlocked++
+				select {
+				case <-str.WritableChan():
+				case <-time.After(time.Second):
+					Fail("timed out waiting for the stream to become writable")
+				}
+				continue
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(str.Close()).To(Succeed())
+		Expect(numBlocked).ToNot(BeZero())
+		Eventually(dataRead, 10*time.Second).Should(Receive(Equal(data)))
+	})
+})
+
+var _ = Describe("Stream limits", func() {
+	It("raises the initial stream limit as the peer opens streams", func() {
+		limits := make(chan int64, 10)
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				InitialMaxIncomingStreams: 2,
+				MaxIncomingStreams:        8,
+				OnStreamsNeeded: func(_ quic.StreamType, current int64) int64 {
+					limits <- current
+					return 2 * current
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for {
+				if _, err := sess.AcceptStream(context.Background()); err != nil {
+					return
+				}
+			}
+		}()
+
+		client, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		for i := 0; i < 8; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			str, err := client.OpenStreamSync(ctx)
+			cancel()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err = client.OpenStream()
+		Expect(err).To(HaveOccurred())
+		Expect(err.(net.Error).Temporary()).To(BeTrue())
+		Eventually(limits).Should(Receive(Equal(int64(2))))
+		Eventually(limits).Should(Receive(Equal(int64(4))))
+		Consistently(limits, 50*time.Millisecond).ShouldNot(Receive())
+	})
+})
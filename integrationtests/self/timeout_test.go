@@ -75,6 +75,22 @@ var _ = Describe("Timeout tests", func() {
 		checkTimeoutError(err)
 	})
 
+	It("times out the handshake if no packets are received for the handshake idle timeout", func() {
+		errChan := make(chan error)
+		go func() {
+			_, err := quic.DialAddr(
+				"localhost:12345",
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{HandshakeIdleTimeout: 10 * time.Millisecond}),
+			)
+			errChan <- err
+		}()
+		var err error
+		Eventually(errChan, time.Second).Should(Receive(&err))
+		checkTimeoutError(err)
+		Expect(err).ToNot(MatchError(quic.ErrHandshakeTimeout))
+	})
+
 	It("returns the context error when the context expires", func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
 		defer cancel()
@@ -87,6 +87,7 @@ var _ = Describe("0-RTT", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(data).To(Equal(testdata))
 					Expect(sess.ConnectionState().Used0RTT).To(Equal(expect0RTT))
+					Expect(sess.EarlyDataAccepted()).To(Equal(expect0RTT))
 					close(done)
 				}()
 
@@ -102,6 +103,7 @@ var _ = Describe("0-RTT", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(str.Close()).To(Succeed())
 				Expect(sess.ConnectionState().Used0RTT).To(Equal(expect0RTT))
+				Expect(sess.EarlyDataAccepted()).To(Equal(expect0RTT))
 				Eventually(done).Should(BeClosed())
 			}
 
@@ -128,6 +130,65 @@ var _ = Describe("0-RTT", func() {
 				Expect(num0RTT).ToNot(BeZero())
 			})
 
+			It("reports the maximum 0-RTT size", func() {
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				clientConf := dialAndReceiveSessionTicket(ln, ln.Addr().(*net.UDPAddr).Port)
+				sess, err := quic.DialAddrEarly(
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					clientConf,
+					getQuicConfig(&quic.Config{Versions: []protocol.VersionNumber{version}}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.CloseWithError(0, "")
+				Expect(sess.Max0RTTSize()).To(Equal(protocol.ByteCount(protocol.InitialMaxData)))
+			})
+
+			It("transfers 0-RTT data, using a serialized session state", func() {
+				ln, err := quic.ListenAddrEarly(
+					"localhost:0",
+					getTLSConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:    []protocol.VersionNumber{version},
+						AcceptToken: func(_ net.Addr, _ *quic.Token) bool { return true },
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+
+				proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+				defer proxy.Close()
+
+				clientConf := dialAndReceiveSessionTicket(ln, proxy.LocalPort())
+				// Serialize all session states, and restore them into a new session cache.
+				// This simulates a client that was restarted.
+				cache := clientConf.ClientSessionCache.(*clientSessionCache)
+				restoredCache := newClientSessionCache(make(chan string, 100), make(chan string, 100))
+				cache.mutex.Lock()
+				Expect(cache.cache).ToNot(BeEmpty())
+				for key, state := range cache.cache {
+					data, err := quic.SerializeSessionState(state)
+					Expect(err).ToNot(HaveOccurred())
+					restoredState, err := quic.DeserializeSessionState(data)
+					Expect(err).ToNot(HaveOccurred())
+					restoredCache.cache[key] = restoredState
+				}
+				cache.mutex.Unlock()
+				restoredConf := getTLSClientConfig()
+				restoredConf.ClientSessionCache = restoredCache
+				transfer0RTTData(ln, proxy.LocalPort(), restoredConf, PRData, true)
+
+				num0RTT := atomic.LoadUint32(num0RTTPackets)
+				fmt.Fprintf(GinkgoWriter, "Sent %d 0-RTT packets.", num0RTT)
+				Expect(num0RTT).ToNot(BeZero())
+			})
+
 			// Test that data intended to be sent with 1-RTT protection is not sent in 0-RTT packets.
 			It("waits until a session until the handshake is done", func() {
 				ln, err := quic.ListenAddrEarly(
@@ -2,14 +2,18 @@ package quic
 
 import (
 	"context"
+	"crypto/tls"
 	"io"
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/logging"
 
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
@@ -26,9 +30,56 @@ var RetireBugBackwardsCompatibilityMode bool
 // The StreamID is the ID of a QUIC stream.
 type StreamID = protocol.StreamID
 
+// The StreamType says if a stream is unidirectional or bidirectional.
+type StreamType = protocol.StreamType
+
+const (
+	// StreamTypeUni is the type of unidirectional streams.
+	StreamTypeUni = protocol.StreamTypeUni
+	// StreamTypeBidi is the type of bidirectional streams.
+	StreamTypeBidi = protocol.StreamTypeBidi
+)
+
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
+// A ConnectionID is a QUIC connection ID.
+type ConnectionID = protocol.ConnectionID
+
+// A ByteCount is a number of bytes.
+type ByteCount = protocol.ByteCount
+
+// A ConnectionIDGenerator generates the connection IDs that we issue to the peer.
+// It can be used to encode information into the connection ID,
+// e.g. to identify the socket (when using SO_REUSEPORT) or the server a connection belongs to.
+// This allows a load balancer (or an eBPF program) to route packets to the right listener,
+// even after the peer migrated to a new path.
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// Generated connection IDs must be unique and of the length returned by ConnectionIDLen.
+	GenerateConnectionID() (ConnectionID, error)
+	// ConnectionIDLen returns the length of the connection IDs generated by GenerateConnectionID.
+	// It can be any value between 0 and 20.
+	ConnectionIDLen() int
+}
+
+// An AddressTokenGenerator generates and validates the tokens that a server sends in Retry packets and in NEW_TOKEN frames.
+// Clients use these tokens to prove that they own their address.
+// Using a generator that derives the tokens from a shared key allows every server of a deployment
+// to validate tokens issued by any other server, without sharing any state.
+type AddressTokenGenerator interface {
+	// NewToken generates a new token for the given client address, to be sent in a NEW_TOKEN frame.
+	NewToken(clientAddr net.Addr) ([]byte, error)
+	// ValidateToken says if a token generated by NewToken is valid for the given client address.
+	ValidateToken(token []byte, clientAddr net.Addr) (bool, error)
+	// NewRetryToken generates a new token for the given client address, to be sent in a Retry packet.
+	// The connection IDs must be encoded in the token, since they are needed to verify the transport parameters.
+	NewRetryToken(clientAddr net.Addr, origDestConnID, retrySrcConnID ConnectionID) ([]byte, error)
+	// ValidateRetryToken says if a token generated by NewRetryToken is valid for the given client address.
+	// If it is, it returns the connection IDs that were passed to NewRetryToken.
+	ValidateRetryToken(token []byte, clientAddr net.Addr) (valid bool, origDestConnID, retrySrcConnID ConnectionID, err error)
+}
+
 // A Token can be used to verify the ownership of the client address.
 type Token struct {
 	// IsRetryToken encodes how the client received the token. There are two ways:
@@ -54,12 +105,23 @@ type TokenStore interface {
 	// Put adds a token to the cache with the given key. It might get called
 	// multiple times in a connection.
 	Put(key string, token *ClientToken)
+
+	// Invalidate removes all tokens associated with the given key.
+	// It is called when the server rejected a token, either by sending a Retry
+	// or by closing the connection with an INVALID_TOKEN error.
+	// This usually means that the server rotated the keys used to encrypt tokens,
+	// so the other tokens stored for this server won't be accepted either.
+	Invalidate(key string)
 }
 
 // An ErrorCode is an application-defined error code.
 // Valid values range between 0 and MAX_UINT62.
 type ErrorCode = protocol.ApplicationErrorCode
 
+// A StreamErrorCode is an application-defined error code used to cancel a stream.
+// It is sent to the peer in RESET_STREAM and STOP_SENDING frames.
+type StreamErrorCode = ErrorCode
+
 // Stream is the interface implemented by QUIC streams
 type Stream interface {
 	ReceiveStream
@@ -77,16 +139,18 @@ type ReceiveStream interface {
 	// Read reads data from the stream.
 	// Read can be made to time out and return a net.Error with Timeout() == true
 	// after a fixed time limit; see SetDeadline and SetReadDeadline.
-	// If the stream was canceled by the peer, the error implements the StreamError
-	// interface, and Canceled() == true.
+	// If the stream was canceled (by us or by the peer), the error is a *StreamError.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Reader
+	// ReadAvailable reads the data that is currently available on the stream.
+	// Unlike Read, it never blocks. If no data is available, it returns 0 and a nil error.
+	ReadAvailable([]byte) (int, error)
 	// CancelRead aborts receiving on this stream.
 	// It will ask the peer to stop transmitting stream data.
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
-	CancelRead(ErrorCode)
+	CancelRead(StreamErrorCode)
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
@@ -101,8 +165,7 @@ type SendStream interface {
 	// Write writes data to the stream.
 	// Write can be made to time out and return a net.Error with Timeout() == true
 	// after a fixed time limit; see SetDeadline and SetWriteDeadline.
-	// If the stream was canceled by the peer, the error implements the StreamError
-	// interface, and Canceled() == true.
+	// If the stream was canceled (by us or by the peer), the error is a *StreamError.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
@@ -111,11 +174,12 @@ type SendStream interface {
 	// It must not be called concurrently with Write.
 	// It must not be called after calling CancelWrite.
 	io.Closer
-	// CancelWrite aborts sending on this stream.
-	// Data already written, but not yet delivered to the peer is not guaranteed to be delivered reliably.
+	// CancelWrite aborts sending on this stream, by sending a RESET_STREAM frame.
+	// Data that was written, but not yet sent, is discarded, and lost data is not retransmitted.
+	// The final size of the stream is the amount of data sent before calling CancelWrite.
 	// Write will unblock immediately, and future calls to Write will fail.
 	// When called multiple times or after closing the stream it is a no-op.
-	CancelWrite(ErrorCode)
+	CancelWrite(StreamErrorCode)
 	// The context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
@@ -125,18 +189,175 @@ type SendStream interface {
 	// and any currently-blocked Write call.
 	// Even if write times out, it may return n > 0, indicating that
 	// some of the data was successfully written.
+	// In that case, the first n bytes of p were accepted by the stream and will be sent,
+	// and none of the remaining bytes were. The error satisfies the net.Error interface,
+	// and Timeout() is true. To resume, call Write with p[n:].
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetPriority sets the priority of this stream.
+	// When multiple streams have data to send, data from streams with a higher weight is sent first.
+	// Streams with the same weight are served in a round-robin fashion.
+	// The default weight is 0.
+	// The priority is only a hint for the local send scheduler, it is not sent to the peer.
+	SetPriority(weight uint8)
+	// BufferedBytes returns the number of bytes that were accepted by Write,
+	// but haven't been packed into a packet yet.
+	// Retransmissions of lost data are not included.
+	BufferedBytes() uint64
+	// Flush sends data that is held back due to the Config.StreamWriteCoalesceDelay right away.
+	// It doesn't block until the data is sent.
+	Flush() error
+	// SetNonBlocking puts the stream into non-blocking mode.
+	// In non-blocking mode, Write never blocks. It accepts as much data as can be buffered (about one packet),
+	// and returns ErrWouldBlock if it couldn't accept all of p, e.g. because the stream is blocked by flow control.
+	// Write doesn't retain p after it returns.
+	SetNonBlocking(bool)
+	// WritableChan returns a channel that is signaled when a non-blocking stream can accept more data,
+	// after a Write returned ErrWouldBlock. It is also signaled when the stream is canceled or the session is closed,
+	// such that the next call to Write returns the error.
+	// The channel is edge-triggered: it is only signaled once for every Write that returned ErrWouldBlock.
+	WritableChan() <-chan struct{}
+}
+
+// A PreferredAddress is an address that the server asks clients to migrate to after the handshake.
+// At least one of the IPv4 and the IPv6 address must be set.
+// Clients use the address of the same IP version as the address they used for the handshake.
+type PreferredAddress struct {
+	IPv4 *net.UDPAddr
+	IPv6 *net.UDPAddr
 }
 
-// StreamError is returned by Read and Write when the peer cancels the stream.
-type StreamError interface {
+// A TransportError is returned when the connection is closed with a CONNECTION_CLOSE frame,
+// either by us or by the peer (in that case, Remote is set).
+// For connections closed by the peer, FrameType is the type of the frame that triggered the error,
+// as reported in the CONNECTION_CLOSE frame (0 if unknown).
+// Application errors (sent in a CONNECTION_CLOSE frame of type 0x1d) are reported as a TransportError as well.
+// They can be distinguished using IsApplicationError.
+type TransportError = qerr.QuicError
+
+// A TransportErrorCode is a transport error code, as defined in section 20 of the QUIC transport draft.
+type TransportErrorCode = qerr.ErrorCode
+
+// A StreamError is returned by Read and Write when the stream was canceled.
+type StreamError struct {
+	StreamID StreamID
+	// ErrorCode is the application error code that the stream was canceled with.
+	// For streams canceled by the peer, this is the error code sent in the RESET_STREAM
+	// (when reading) or STOP_SENDING frame (when writing).
+	ErrorCode StreamErrorCode
+	// Remote is set if the stream was canceled by the peer,
+	// and unset if it was canceled locally using CancelRead or CancelWrite.
+	Remote bool
+}
+
+// A HandshakeError can be returned from the callbacks in the tls.Config (e.g. GetCertificate,
+// GetConfigForClient or VerifyPeerCertificate) to fail the handshake with a specific TLS alert.
+// The connection is then closed with the CRYPTO_ERROR corresponding to this alert,
+// instead of the alert chosen by TLS (typically internal_error).
+// Errors wrapping a HandshakeError are also recognized.
+type HandshakeError interface {
 	error
-	Canceled() bool
-	ErrorCode() ErrorCode
+	TLSAlert() uint8
+}
+
+// ConnectionState records basic details about a QUIC connection.
+// Warning: This API should not be considered stable and might change soon.
+type ConnectionState struct {
+	// the state of the TLS handshake
+	handshake.ConnectionState
+	// KeyExchangeGroup is the (EC)DHE group used for the key exchange, e.g. tls.X25519.
+	// It is set as soon as the ServerHello has been sent or received.
+	KeyExchangeGroup tls.CurveID
+	// SmoothedRTT is the smoothed RTT estimate.
+	// It is zero if no RTT sample has been obtained yet.
+	SmoothedRTT time.Duration
+	// RTTVar is the mean deviation of the RTT samples.
+	RTTVar time.Duration
+	// MinRTT is the minimum RTT observed on this connection.
+	MinRTT time.Duration
+	// BandwidthEstimate is the estimated bandwidth of the connection, in bytes per second.
+	// It is the maximum delivery rate measured over the last round trips, excluding times when the application didn't send enough data to utilize the connection.
+	// Until the delivery rate has been measured, it is calculated from the congestion window and the smoothed RTT.
+	// It is zero if no RTT sample has been obtained yet.
+	BandwidthEstimate uint64
+	// MaxIdleTimeout is the idle timeout negotiated with the peer,
+	// i.e. the minimum of the idle timeouts advertised by the two endpoints.
+	// It is zero until the peer's transport parameters have been received.
+	MaxIdleTimeout time.Duration
+	// OriginalDestinationConnectionID is the Destination Connection ID the client used on its first Initial packet.
+	// If a Retry was performed, this is the connection ID used before the Retry.
+	OriginalDestinationConnectionID ConnectionID
+	// HandshakeConfirmed says if the handshake has been confirmed (see section 4.1.2 of the QUIC TLS draft).
+	// The server confirms the handshake when it completes, the client when it receives a HANDSHAKE_DONE frame.
+	// The Handshake keys are discarded at this point.
+	// Whether the TLS handshake has completed is reported by the embedded HandshakeComplete field.
+	HandshakeConfirmed bool
+	// UsedRetry says if a Retry was performed during the handshake,
+	// i.e. if the server requested the client to validate its address.
+	UsedRetry bool
+	// ECT0, ECT1 and ECNCE are the ECN counts reported by the peer in its ACK frames for 1-RTT packets,
+	// i.e. the number of packets it received marked with ECT(0), ECT(1) and ECN-CE, respectively.
+	ECT0, ECT1, ECNCE uint64
+	// RemoteTransportParameters are the transport parameters sent by the peer.
+	// They are nil until the peer's transport parameters have been received.
+	// Transport parameters restored for 0-RTT are not included.
+	RemoteTransportParameters *logging.TransportParameters
+}
+
+// ConnectionStats contains cumulative statistics about a QUIC connection.
+// The counters are collected independently of each other, so they're not guaranteed to be consistent with each other
+// if the snapshot is taken while packets are being sent or received.
+type ConnectionStats struct {
+	// PacketsSent and BytesSent count all QUIC packets sent, including packets carrying retransmitted data.
+	// Coalesced packets are counted individually, the bytes are counted per UDP datagram.
+	PacketsSent, BytesSent uint64
+	// PacketsReceived and BytesReceived count the QUIC packets that were successfully decrypted.
+	PacketsReceived, BytesReceived uint64
+	// PacketsLost and BytesLost count the packets that were declared lost.
+	// QUIC doesn't retransmit packets, but the frames contained in a lost packet are retransmitted in new packets,
+	// so these are the best indicator of the amount of retransmissions.
+	PacketsLost, BytesLost uint64
+	// ProbePacketsSent is the number of probe packets sent when the probe timeout (PTO) expired.
+	ProbePacketsSent uint64
+}
+
+// StreamDirection is the direction of a stream.
+type StreamDirection uint8
+
+const (
+	// StreamDirectionBidirectional is used for bidirectional streams.
+	StreamDirectionBidirectional StreamDirection = iota
+	// StreamDirectionSend is used for unidirectional streams opened by us.
+	StreamDirectionSend
+	// StreamDirectionReceive is used for unidirectional streams opened by the peer.
+	StreamDirectionReceive
+)
+
+// StreamInfo contains information about an open stream.
+type StreamInfo struct {
+	ID        StreamID
+	Direction StreamDirection
+	// BytesSent is the number of bytes of stream data sent, not counting retransmissions.
+	// It is zero for receive-only streams.
+	BytesSent uint64
+	// BytesReceived is the number of bytes of stream data received, i.e. the highest offset received.
+	// It is zero for send-only streams.
+	BytesReceived uint64
 }
 
-type ConnectionState = handshake.ConnectionState
+// PathInfo contains information about the path that a session is currently using.
+type PathInfo struct {
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	// Validated says if the peer proved that it is reachable at RemoteAddr.
+	// The path that the session was established on is considered validated.
+	// After the peer's address changed without a prior path validation (e.g. due to a NAT rebinding),
+	// this is false until the server validated the new address.
+	Validated bool
+	// RTT is the smoothed RTT measured on the path.
+	// It is 0 until an RTT sample was obtained on the path.
+	RTT time.Duration
+}
 
 // A Session is a QUIC connection between two peers.
 type Session interface {
@@ -148,6 +369,12 @@ type Session interface {
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	AcceptUniStream(context.Context) (ReceiveStream, error)
+	// SetAcceptDeadline sets the deadline for AcceptStream and AcceptUniStream,
+	// including calls that are already blocked.
+	// After the deadline has passed, calls return an error that satisfies the net.Error interface,
+	// and Timeout() will be true. A zero value for t means that accepting streams will not time out.
+	// The deadline applies in addition to the context passed to AcceptStream and AcceptUniStream.
+	SetAcceptDeadline(t time.Time)
 	// OpenStream opens a new bidirectional QUIC stream.
 	// There is no signaling to the peer about new streams:
 	// The peer can only accept the stream after data has been sent on the stream.
@@ -173,15 +400,54 @@ type Session interface {
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
+	// It changes when the connection is migrated to a new path.
 	RemoteAddr() net.Addr
+	// CurrentPath returns information about the path that is currently used to send packets.
+	CurrentPath() PathInfo
 	// Close the connection with an error.
 	// The error string will be sent to the peer.
 	CloseWithError(ErrorCode, string) error
+	// CloseWithErrorSync closes the connection with an error, like CloseWithError.
+	// It blocks until the CONNECTION_CLOSE has been written to the underlying connection,
+	// or until the context is cancelled.
+	// If the session isn't running yet, it returns right away.
+	// If the session is closed concurrently, only the first close takes effect.
+	CloseWithErrorSync(context.Context, ErrorCode, string) error
+	// CloseWithTransportError closes the connection with a transport error,
+	// which is sent in a CONNECTION_CLOSE frame of type 0x1c, including the type of the frame that triggered the error.
+	// The peer can distinguish this from CloseWithError (CONNECTION_CLOSE of type 0x1d) using TransportError.IsApplicationError.
+	// This is intended for testing and for gateways that translate errors between protocols.
+	CloseWithTransportError(code TransportErrorCode, frameType uint64, msg string) error
 	// The context is cancelled when the session is closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// SendPing sends a PING frame in a 1-RTT packet, and returns once that packet was handed to the send queue.
+	// This can be used to verify that the peer is still alive, independently of the keep-alive mechanism.
+	// It returns ErrHandshakeNotComplete if the handshake hasn't completed yet.
+	SendPing() error
+	// ForceKeyUpdate initiates a key update. The new keys are used starting with the next 1-RTT packet that is sent.
+	// It returns ErrHandshakeNotConfirmed if the handshake hasn't been confirmed yet,
+	// and ErrKeyUpdateInProgress if the peer hasn't acknowledged a packet sent with the current keys yet.
+	ForceKeyUpdate() error
+	// MigratePath migrates the connection to a new path, using conn to send and receive packets.
+	// The new path is validated using PATH_CHALLENGE and PATH_RESPONSE frames before it is used.
+	// If path validation fails, or if the context is cancelled before it succeeds,
+	// the connection continues to use the old path, and an error is returned.
+	// It returns ErrHandshakeNotConfirmed if the handshake hasn't been confirmed yet.
+	// The application is responsible for closing conn once it is no longer used.
+	// This method is only valid for the client.
+	MigratePath(ctx context.Context, conn net.PacketConn) error
+	// ActiveStreams returns a snapshot of the streams that are currently open, sorted by stream ID.
+	// This includes streams opened by the peer that haven't been accepted yet.
+	ActiveStreams() []StreamInfo
+	// Stats returns cumulative statistics about the connection.
+	// It is cheap to call, and safe to call concurrently with other methods of the session.
+	Stats() ConnectionStats
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
+	// The RTT estimates reflect the values at the time of the call.
+	// It is safe to call it concurrently with other methods of the session.
+	// Once it returns, the application protocol negotiated using ALPN is set in NegotiatedProtocol.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
 }
@@ -197,6 +463,29 @@ type EarlySession interface {
 	// Data sent before completion of the handshake is encrypted with 1-RTT keys.
 	// Note that the client's identity hasn't been verified yet.
 	HandshakeComplete() context.Context
+	// HandshakeConfirmed is canceled when the handshake is confirmed (see section 4.1.2 of the QUIC TLS draft).
+	// For the server, this happens when the handshake completes.
+	// For the client, this happens when it receives the server's HANDSHAKE_DONE frame, some time after completion.
+	// If the session is closed before the handshake is confirmed, the context is not canceled, see Context().
+	HandshakeConfirmed() context.Context
+	// NegotiatedProtocol returns the application protocol negotiated using ALPN.
+	// It blocks until the protocol is known, which happens before completion
+	// of the handshake when 0-RTT is used.
+	// If the handshake fails, it returns an empty string.
+	NegotiatedProtocol() string
+	// EarlyDataAccepted says if 0-RTT was accepted.
+	// It blocks until the handshake completes, since only then it is known if the server accepted 0-RTT.
+	// For the client, it returns false if it didn't attempt 0-RTT, if the server rejected 0-RTT,
+	// or if the handshake fails. When 0-RTT is rejected, all data sent in 0-RTT packets is retransmitted in 1-RTT packets.
+	// TLS doesn't tell the client why the server rejected 0-RTT.
+	// For the server, it returns true if it accepted 0-RTT.
+	EarlyDataAccepted() bool
+	// Max0RTTSize returns the maximum number of bytes of stream data that can be sent in 0-RTT packets,
+	// as allowed by the connection-level flow control limit of the transport parameters
+	// remembered from the previous connection. Data sent on a single stream is additionally
+	// limited by that stream's flow control limit.
+	// It returns 0 if the client didn't restore any transport parameters, and for the server.
+	Max0RTTSize() ByteCount
 }
 
 // Config contains all configuration data needed for a QUIC server or client.
@@ -205,23 +494,61 @@ type Config struct {
 	// If not set, it uses all versions available.
 	// Warning: This API should not be considered stable and will change soon.
 	Versions []VersionNumber
-	// The length of the connection ID in bytes.
-	// It can be 0, or any value between 4 and 18.
+	// DisableVersionNegotiation pins the QUIC version used by the client to the first version in Versions.
+	// A Version Negotiation packet is then treated as a fatal error, and the connection is closed
+	// with ErrVersionNegotiationReceived, instead of attempting to switch to a different version.
+	// Only valid for the client.
+	DisableVersionNegotiation bool
+	// The length of the connection IDs generated by this endpoint, in bytes.
+	// It can be any value between 0 and 20. Larger values are reduced to 20.
+	// All connection IDs issued by this endpoint have the same length.
 	// If not set, the interpretation depends on where the Config is used:
 	// If used for dialing an address, a 0 byte connection ID will be used.
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
+	// A 0 byte connection ID minimizes the per-packet overhead. Incoming packets are then associated with
+	// the connection by the socket they are received on, so the socket can't be shared with other connections.
+	// It also means that the client can't issue new connection IDs to the server,
+	// which makes a connection migration linkable by on-path observers.
+	// If a ConnectionIDGenerator is set, this value can be left unset,
+	// the length is then determined by the ConnectionIDGenerator.
 	ConnectionIDLength int
+	// ConnectionIDGenerator generates the connection IDs used by this endpoint.
+	// If not set, random connection IDs of length ConnectionIDLength are used.
+	// When multiple listeners share a port via SO_REUSEPORT, it can be used to encode a
+	// listener identifier into every connection ID.
+	ConnectionIDGenerator ConnectionIDGenerator
+	// Rand is the source of randomness for values that need to be unpredictable, but are not used as cryptographic keys:
+	// connection IDs (unless a ConnectionIDGenerator is set), the packet numbers that are skipped,
+	// and the data sent in PATH_CHALLENGE frames.
+	// The TLS handshake, stateless reset tokens and address tokens always use crypto/rand.
+	// If not set, crypto/rand.Reader is used.
+	// Setting a deterministic reader can be useful for reproducible tests.
+	// It is shared by all connections using this config, but reads from it are serialized,
+	// so it doesn't need to be safe for concurrent use.
+	Rand io.Reader
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
-	// If the timeout is exceeded, the connection is closed.
+	// This is an absolute limit, measured from the start of the handshake.
+	// If the timeout is exceeded, the connection is closed with ErrHandshakeTimeout.
 	// If this value is zero, the timeout is set to 10 seconds.
 	HandshakeTimeout time.Duration
+	// HandshakeIdleTimeout is the maximum duration that may pass without any incoming network activity
+	// before the handshake has completed. Unlike the HandshakeTimeout, it is reset every time a packet is received.
+	// If the timeout is exceeded, the connection is closed.
+	// If this value is zero, no handshake idle timeout is used.
+	HandshakeIdleTimeout time.Duration
 	// MaxIdleTimeout is the maximum duration that may pass without any incoming network activity.
 	// The actual value for the idle timeout is the minimum of this value and the peer's.
 	// This value only applies after the handshake has completed.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 30 seconds.
 	MaxIdleTimeout time.Duration
+	// InitialRTT is the RTT estimate used before the first RTT sample is taken.
+	// It is used to compute the timeouts for loss recovery during the handshake,
+	// and is replaced as soon as the first RTT sample is taken.
+	// Values smaller than 5ms are increased to 5ms.
+	// If this value is zero, an initial RTT of 100ms is used.
+	InitialRTT time.Duration
 	// AcceptToken determines if a Token is accepted.
 	// It is called with token = nil if the client didn't send a token.
 	// If not set, a default verification function is used:
@@ -230,6 +557,32 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// AddressTokenGenerator generates the tokens sent in Retry packets and in NEW_TOKEN frames, and validates them
+	// when they are used by a client. AcceptToken is not called for tokens accepted by the AddressTokenGenerator.
+	// Since it is not possible to tell if a rejected token was a Retry token, the server responds to rejected tokens with a Retry.
+	// If not set, quic-go encrypts the tokens with a random key, which is only known to this server.
+	// This option is only valid for the server.
+	AddressTokenGenerator AddressTokenGenerator
+	// MaxIncomingHandshakesPerSecond limits the rate at which the server starts new handshakes.
+	// Once the limit is exceeded, clients that haven't validated their address by presenting a token
+	// are asked to do so by sending a Retry. This doesn't require the server to keep any state.
+	// If not set, the rate of handshakes is not limited.
+	// This option is only valid for the server.
+	MaxIncomingHandshakesPerSecond int
+	// MaxIncomingConnections is the maximum number of concurrent connections the server handles.
+	// This includes connections that are still handshaking, and connections that were already accepted.
+	// Once the limit is reached, new connection attempts are refused with a CONNECTION_REFUSED error,
+	// before any state is created for them.
+	// If not set, the number of connections is not limited.
+	// This option is only valid for the server.
+	MaxIncomingConnections int
+	// Allow0RTT is called when a client attempts to resume a session using 0-RTT.
+	// It is passed the transport parameters restored from the session ticket,
+	// and only called if they are compatible with the current transport parameters.
+	// If it returns false, 0-RTT is rejected and the handshake proceeds as a regular 1-RTT handshake.
+	// If not set, 0-RTT is always accepted if the transport parameters are compatible.
+	// This option is only valid for the server, when using ListenEarly.
+	Allow0RTT func(*logging.TransportParameters) bool
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -238,9 +591,29 @@ type Config struct {
 	// MaxReceiveStreamFlowControlWindow is the maximum stream-level flow control window for receiving data.
 	// If this value is zero, it will default to 1 MB for the server and 6 MB for the client.
 	MaxReceiveStreamFlowControlWindow uint64
+	// StreamReceiveWindowFunc is called when a stream that we receive data on is opened (by us or by the peer).
+	// If it returns a value larger than the initial stream-level flow control window (512 kB),
+	// the stream starts with this window, which is announced to the peer in a MAX_STREAM_DATA frame right away.
+	// This can be used to make sure that important streams are never blocked by flow control.
+	// The window is capped at MaxReceiveConnectionFlowControlWindow, and auto-tuning can increase it further.
+	StreamReceiveWindowFunc func(StreamID) uint64
 	// MaxReceiveConnectionFlowControlWindow is the connection-level flow control window for receiving data.
 	// If this value is zero, it will default to 1.5 MB for the server and 15 MB for the client.
 	MaxReceiveConnectionFlowControlWindow uint64
+	// DisableFlowControlAutoTuning disables the auto-tuning of the flow control windows.
+	// The stream-level and connection-level receive windows then never grow beyond their initial values
+	// (512 kB and 768 kB, respectively, or the window returned by StreamReceiveWindowFunc),
+	// which trades throughput on high bandwidth-delay product paths for predictable memory usage.
+	// MaxReceiveStreamFlowControlWindow and MaxReceiveConnectionFlowControlWindow are ignored if set.
+	DisableFlowControlAutoTuning bool
+	// MaxConnectionReceiveBuffer is the maximum amount of memory (in bytes) used for buffering
+	// received stream data that hasn't been read by the application yet, summed over all streams.
+	// This includes data received out of order, which can't be read yet.
+	// Since received data is kept in the buffer of the packet it arrived in, the memory used can be
+	// considerably larger than the amount of data buffered, if the peer sends small STREAM frames.
+	// If the limit is exceeded, the connection is closed with a FLOW_CONTROL_ERROR.
+	// If not set, memory usage is only limited by flow control.
+	MaxConnectionReceiveBuffer uint64
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// Values above 2^60 are invalid.
 	// If not set, it will default to 100.
@@ -251,15 +624,193 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int64
+	// InitialMaxIncomingStreams is the number of concurrent bidirectional streams that a peer is allowed to open
+	// at the beginning of the connection, i.e. the value sent in the initial_max_streams_bidi transport parameter.
+	// The limit is raised (using MAX_STREAMS frames) as the peer uses up streams, up to MaxIncomingStreams.
+	// If not set, or if larger than MaxIncomingStreams, it will default to MaxIncomingStreams.
+	InitialMaxIncomingStreams int64
+	// InitialMaxIncomingUniStreams is the equivalent of InitialMaxIncomingStreams for unidirectional streams.
+	// If not set, or if larger than MaxIncomingUniStreams, it will default to MaxIncomingUniStreams.
+	InitialMaxIncomingUniStreams int64
+	// OnStreamsNeeded is called when the peer has opened all the streams it is currently allowed to open,
+	// and the limit is lower than MaxIncomingStreams (or MaxIncomingUniStreams, respectively).
+	// current is the current limit, and the return value is the new limit.
+	// Values larger than the maximum are reduced to the maximum, values not larger than current leave the limit unchanged.
+	// If not set, the limit is doubled every time.
+	// It is called from the session's run loop, and therefore must not block.
+	OnStreamsNeeded func(t StreamType, current int64) int64
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
+	// StatelessResetKeyFunc returns the key used to generate the stateless reset token for a connection ID.
+	// This allows multiple endpoints (e.g. behind a load balancer) to derive the same tokens from a shared secret.
+	// If set, it takes precedence over the StatelessResetKey, and sending of stateless resets is enabled.
+	// It may be called concurrently from multiple go routines.
+	StatelessResetKeyFunc func(connID []byte) [32]byte
+	// ActiveConnectionIDLimit is the maximum number of connection IDs issued by the peer that we're willing to store.
+	// It is sent to the peer in the active_connection_id_limit transport parameter.
+	// If the peer issues more connection IDs, the connection is closed with a CONNECTION_ID_LIMIT_ERROR.
+	// If not set, it will default to 4. Values smaller than 2 are invalid.
+	ActiveConnectionIDLimit uint64
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// GREASEQUICBit enables greasing of the QUIC bit, as described in RFC 9287.
+	// If enabled, the grease_quic_bit transport parameter is sent, and we accept short header
+	// packets that have the QUIC bit set to 0.
+	// If the peer also sent the transport parameter, the QUIC bit in our short header packets is set randomly.
+	GREASEQUICBit bool
+	// DisablePacketCoalescing disables coalescing of multiple QUIC packets into a single UDP datagram.
+	// Some middleboxes mishandle coalesced packets (e.g. an Initial and a Handshake packet sent in the same datagram).
+	// If set, every QUIC packet is sent in its own datagram, which requires more datagrams to complete the handshake.
+	DisablePacketCoalescing bool
+	// MaxUDPPayloadSize is the maximum size of UDP payloads that we are willing to receive.
+	// It is sent to the peer in the max_udp_payload_size transport parameter.
+	// Once the handshake has completed, datagrams larger than this value are dropped.
+	// Values smaller than 1200 are raised to 1200, and values larger than 65527 are lowered to 65527.
+	// If not set, or if set to a value larger than 1452 (the largest datagram that quic-go is able to receive),
+	// a value of 1452 is advertised.
+	MaxUDPPayloadSize ByteCount
+	// AllowConnectionMigration allows the client to migrate the connection to a new path.
+	// If not set, the disable_active_migration transport parameter is sent, and packets received from a
+	// different address are processed, but never cause the server to switch the path used for sending.
+	// If set, the server also follows address changes that the client didn't validate first (e.g. due to a NAT rebinding),
+	// and validates the new address after switching to it.
+	// This option is only valid for the server.
+	AllowConnectionMigration bool
+	// DisableActiveMigration makes the server send the disable_active_migration transport parameter,
+	// even if AllowConnectionMigration is set. Clients then don't migrate the connection to a new path,
+	// and the server doesn't answer PATH_CHALLENGE frames sent on a new path.
+	// If AllowConnectionMigration is set, the server still follows changes of the client's address
+	// that the client didn't validate first (e.g. due to a NAT rebinding).
+	// This option is only valid for the server.
+	DisableActiveMigration bool
+	// RequireAddressValidationOnRebind makes the server validate a new peer address before using it,
+	// when the peer's address changes without a prior path validation (e.g. due to a NAT rebinding).
+	// Until the new address is validated, packets are still sent to the old address.
+	// If the validation fails, the connection is closed with ErrPathValidationFailed.
+	// This applies independent of AllowConnectionMigration.
+	// This option is only valid for the server.
+	RequireAddressValidationOnRebind bool
+	// PreferredAddress is sent to the client in the preferred_address transport parameter.
+	// Clients migrate to this address after the handshake, validating the path first.
+	// This can be used to move connections from an anycast address to a unicast address.
+	// The server must receive packets sent to this address on the same net.PacketConn,
+	// e.g. by listening on the unspecified address.
+	// A connection ID and a stateless reset token for use with the preferred address are generated for every connection.
+	// This option is only valid for the server.
+	PreferredAddress *PreferredAddress
+	// DisablePathMigrationToPreferredAddress disables migrating to the server's preferred address.
+	// By default, if the server sends a preferred_address transport parameter,
+	// the client validates the path to that address once the handshake is confirmed, and then migrates to it.
+	// This option is only valid for the client.
+	DisablePathMigrationToPreferredAddress bool
+	// PacketInterceptor is called for every UDP datagram sent on a connection, right before it is written to the PacketConn.
+	// It is passed the datagram and the address of the peer.
+	// If it returns false, the datagram is dropped. If it returns a non-nil slice, this slice is sent instead.
+	// The datagram must not be modified in place, and must not be retained after the function returns.
+	// This is intended for fault injection in tests and must not be used in production.
+	PacketInterceptor func(data []byte, addr net.Addr) (forward bool, rewritten []byte)
+	// IncomingPacketInterceptor is called for every UDP datagram received on a connection, before it is processed.
+	// It is passed the datagram and the address of the peer.
+	// If it returns false, the datagram is dropped. If it returns a non-nil slice, this slice is processed instead.
+	// This is intended for fault injection in tests and must not be used in production.
+	IncomingPacketInterceptor func(data []byte, addr net.Addr) (forward bool, rewritten []byte)
+	// DisableHeaderProtection disables header protection for 1-RTT packets.
+	// This makes packet numbers and the key phase visible on the wire, which simplifies debugging using packet captures.
+	// WARNING: This violates the QUIC specification, is insecure, and must never be used in production.
+	// It only takes effect if the QUIC_GO_DISABLE_HEADER_PROTECTION environment variable is set to 1 as well.
+	// Both endpoints need to disable header protection, otherwise all 1-RTT packets are dropped.
+	DisableHeaderProtection bool
+	// EnableECN enables Explicit Congestion Notification (ECN), as described in section 13.4 of the QUIC transport draft.
+	// If enabled, 1-RTT packets are marked with ECT(0), and an increase of the ECN-CE count reported by the peer
+	// is treated as a congestion signal.
+	// If the peer doesn't report ECN counts, or if ECN-marked packets seem to be dropped on the path, ECN is disabled again.
+	// Marking outgoing packets is currently only supported on Linux.
+	// Independent of this setting, ECN marks on received packets are reported to the peer, if supported by the platform.
+	EnableECN bool
+	// DSCP is the Differentiated Services Code Point (RFC 2474) used for all outgoing packets.
+	// It is only applied to the UDP sockets created by DialAddr, DialAddrEarly, ListenAddr and ListenAddrEarly.
+	// When passing a net.PacketConn to Dial or Listen, the application needs to set the IP_TOS / IPV6_TCLASS
+	// socket option itself (e.g. using golang.org/x/net/ipv4 and golang.org/x/net/ipv6).
+	// Valid values are 0 to 63. If this value is zero, the DSCP is not set.
+	DSCP int
+	// EnableACKFrequency enables the ACK frequency extension (draft-ietf-quic-ack-frequency).
+	// If enabled, the min_ack_delay transport parameter is sent, and ACK_FREQUENCY and IMMEDIATE_ACK frames
+	// sent by the peer are honored.
+	// If the peer also supports the extension, we use ACK_FREQUENCY frames to ask the peer to acknowledge
+	// packets less frequently, depending on the measured RTT. This reduces the number of ACKs sent on the return path.
+	EnableACKFrequency bool
+	// MaxSendRate is the maximum rate (in bytes/s) at which packets are sent.
+	// It caps the pacing rate, even if the congestion controller would allow sending faster.
+	// Short bursts (at the beginning of the connection and after idle periods) are still allowed.
+	// If this value is zero, the send rate is only limited by the congestion controller.
+	MaxSendRate uint64
+	// MaxAckRanges is the maximum number of ACK ranges sent in a single ACK frame.
+	// When more packet number ranges have been received, the ranges with the lowest packet numbers are omitted.
+	// This reduces the size of ACK frames on lossy links, at the cost of less precise loss information for the peer.
+	// If not set, or if set to a value larger than 500, at most 500 ACK ranges are sent.
+	MaxAckRanges int
+	// MaxProbeTimeout is the maximum duration of the probe timeout (PTO).
+	// Every time the PTO expires without an acknowledgement being received, the PTO is doubled.
+	// On slow but recovering links, this exponential backoff can grow the PTO to tens of seconds.
+	// If set, the PTO never grows beyond this value.
+	// If this value is zero, the PTO is not capped.
+	MaxProbeTimeout time.Duration
+	// AmplificationFactor limits the amount of data that the server sends before it has validated the client's address.
+	// Until then, it sends at most AmplificationFactor times the number of bytes it received from the client.
+	// Lower values make the server less useful for reflection attacks, at the cost of a potentially slower handshake.
+	// Values larger than 3 weaken the protection against amplification attacks, and require AllowLargeAmplificationFactor to be set.
+	// If not set, a factor of 3 is used, as recommended by the QUIC specification.
+	// This option is only used by the server.
+	AmplificationFactor int
+	// AllowLargeAmplificationFactor allows setting the AmplificationFactor to values larger than 3.
+	// This should only be used in closed networks that are not exposed to spoofed traffic.
+	AllowLargeAmplificationFactor bool
+	// CongestionControlFactory creates the congestion controller for a new connection.
+	// It is passed the connection's RTT statistics, as well as the initial and the maximum congestion window.
+	// If the returned congestion.SendAlgorithm also implements congestion.SendAlgorithmWithDebugInfos,
+	// the debug infos are used for tracing.
+	// MaxSendRate is not applied when using a custom congestion controller.
+	// If not set, Cubic is used.
+	CongestionControlFactory func(rttStats *congestion.RTTStats, initialCongestionWindow, maxCongestionWindow congestion.ByteCount) congestion.SendAlgorithm
+	// OnStreamFlowControlUpdate is called when the peer increases the flow control limit
+	// of a stream that we're sending on (i.e. when it receives a MAX_STREAM_DATA frame
+	// that increases the send window). newWindow is the new maximum offset we're allowed to send.
+	// It is called from the session's run loop, and therefore must not block.
+	OnStreamFlowControlUpdate func(id StreamID, newWindow uint64)
+	// OnIncomingStream is called when the peer opens a new stream, before it is returned by AcceptStream or AcceptUniStream.
+	// If it returns an error, the stream is rejected: quic-go sends a STOP_SENDING frame (and a RESET_STREAM frame
+	// for bidirectional streams), and the stream is never returned by AcceptStream or AcceptUniStream.
+	// If the error is a *StreamError, its ErrorCode is used for these frames, otherwise the error code is 0.
+	// Rejected streams still count towards the stream limit until the peer resets them.
+	// It is called from the session's run loop, and therefore must not block.
+	OnIncomingStream func(StreamID, StreamType) error
+	// StreamWriteCoalesceDelay is the maximum time that small writes on a stream are held back,
+	// such that they can be coalesced with subsequent writes into fewer STREAM frames and packets.
+	// This is similar to Nagle's algorithm in TCP.
+	// Held back data is sent right away when SendStream.Flush or SendStream.Close is called,
+	// or once the held back data doesn't fit into a single packet any more.
+	// If zero, data is sent as soon as possible.
+	StreamWriteCoalesceDelay time.Duration
+	// MaxStreamIdleTimeout is the maximum duration that a stream can be idle.
+	// A stream is idle when no frames are sent or received on it, whether the application
+	// reads from or writes to the stream doesn't matter.
+	// When the timeout expires, the stream is canceled using the StreamIdleTimeoutErrorCode,
+	// i.e. a RESET_STREAM frame is sent for the send side and a STOP_SENDING frame for the receive side.
+	// This is independent of the MaxIdleTimeout, which applies to the whole connection.
+	// If zero, streams can be idle forever.
+	MaxStreamIdleTimeout time.Duration
+	// StreamIdleTimeoutErrorCode is the error code used to cancel streams when the MaxStreamIdleTimeout expires.
+	StreamIdleTimeoutErrorCode StreamErrorCode
 	// QUIC Event Tracer.
 	// Warning: Experimental. This API should not be considered stable and will change soon.
 	QuicTracer quictrace.Tracer
 	Tracer     logging.Tracer
+
+	// clock is the clock used for all timers and timestamps of a connection,
+	// including the receive time of packets, and for the token and rate limit checks of the server.
+	// It can only be set by tests, allowing them to control the passage of time.
+	clock utils.Clock
 }
 
 // A Listener for incoming QUIC connections
@@ -1,22 +1,42 @@
 package ackhandler
 
 import (
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/logging"
 	"github.com/lucas-clemente/quic-go/quictrace"
 )
 
-// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler
+// NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
+// If maxSendRate (in bytes/s) is non-zero, the pacing rate is capped at this value.
+// If maxPTO is non-zero, the PTO (including the exponential backoff) is capped at this value.
+// Before the peer's address is validated, at most amplificationFactor times the number of bytes received are sent.
+// If enableECN is set, 1-RTT packets are marked with ECT(0), until the ECN validation fails.
+// ACK frames contain at most maxAckRanges ACK ranges.
+// If congestionFactory is nil, the default congestion controller is used.
+// The packet numbers that are skipped are chosen using randomness read from rand.
+// All timestamps and timeouts are obtained from the clock.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	maxSendRate protocol.ByteCount,
+	maxPTO time.Duration,
+	amplificationFactor int,
+	enableECN bool,
+	maxAckRanges int,
+	congestionFactory func(*utils.RTTStats, protocol.ByteCount, protocol.ByteCount) congestion.SendAlgorithm,
+	rand io.Reader,
+	clock utils.Clock,
 	traceCallback func(quictrace.Event),
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, traceCallback, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(initialPacketNumber, rttStats, pers, maxSendRate, maxPTO, amplificationFactor, enableECN, congestionFactory, rand, clock, traceCallback, tracer, logger)
+	return sph, newReceivedPacketHandler(sph, maxAckRanges, rttStats, clock, logger, version)
 }
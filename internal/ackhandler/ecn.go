@@ -0,0 +1,137 @@
+package ackhandler
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// If the first numECNTestingPackets ECT(0)-marked packets are all declared lost,
+// we assume that ECN-marked packets are dropped on the path, and disable ECN.
+const numECNTestingPackets = 10
+
+// The ecnTracker decides if outgoing 1-RTT packets are marked with ECT(0),
+// and validates the ECN counts that the peer reports in its ACK frames (see section 13.4.2 of the QUIC transport draft).
+// Only 1-RTT packets are marked, so only ACKs for the application data packet number space are validated.
+type ecnTracker struct {
+	state logging.ECNState
+
+	numSentECT0 uint64
+	// The last ECT(0) packet sent while testing, i.e. the numECNTestingPackets-th ECT(0) packet.
+	// InvalidPacketNumber as long as fewer than numECNTestingPackets were sent.
+	lastTestingPacket protocol.PacketNumber
+	numSentTesting    int
+	numLostTesting    int
+
+	// the ECN counts reported in the last ACK that was used for validation
+	ect0, ecnce uint64
+
+	tracer logging.ConnectionTracer
+	logger utils.Logger
+}
+
+func newECNTracker(tracer logging.ConnectionTracer, logger utils.Logger) *ecnTracker {
+	if tracer != nil {
+		tracer.ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+	}
+	return &ecnTracker{
+		state:             logging.ECNStateTesting,
+		lastTestingPacket: protocol.InvalidPacketNumber,
+		tracer:            tracer,
+		logger:            logger,
+	}
+}
+
+// Mode returns the ECN codepoint that should be used for the next 1-RTT packet.
+func (e *ecnTracker) Mode() protocol.ECN {
+	if e.state == logging.ECNStateFailed {
+		return protocol.ECNNon
+	}
+	return protocol.ECT0
+}
+
+func (e *ecnTracker) SentPacket(p *Packet) {
+	if p.ECN != protocol.ECT0 {
+		return
+	}
+	e.numSentECT0++
+	if e.state == logging.ECNStateTesting && e.numSentTesting < numECNTestingPackets {
+		e.numSentTesting++
+		if e.numSentTesting == numECNTestingPackets {
+			e.lastTestingPacket = p.PacketNumber
+		}
+	}
+}
+
+// LostPacket is called when a packet is declared lost.
+func (e *ecnTracker) LostPacket(p *Packet) {
+	if e.state != logging.ECNStateTesting || p.ECN != protocol.ECT0 {
+		return
+	}
+	if e.lastTestingPacket != protocol.InvalidPacketNumber && p.PacketNumber > e.lastTestingPacket {
+		return
+	}
+	e.numLostTesting++
+	if e.numLostTesting >= numECNTestingPackets {
+		e.failValidation(logging.ECNFailedLostAllTestingPackets)
+	}
+}
+
+// HandleNewlyAcked processes an ACK frame that increased the largest acknowledged packet number.
+// It returns true if the peer reported new ECN-CE marks, i.e. if a congestion event should be signaled.
+func (e *ecnTracker) HandleNewlyAcked(packets []*Packet, ack *wire.AckFrame) bool /* CE marks increased */ {
+	if e.state == logging.ECNStateFailed {
+		return false
+	}
+
+	var newlyAckedECT0 uint64
+	for _, p := range packets {
+		if p.ECN == protocol.ECT0 {
+			newlyAckedECT0++
+		}
+	}
+
+	if ack.ECT0 == 0 && ack.ECT1 == 0 && ack.ECNCE == 0 {
+		if newlyAckedECT0 > 0 {
+			e.failValidation(logging.ECNFailedNoECNCounts)
+		}
+		return false
+	}
+	// The counts are cumulative. Since this function is only called for ACKs that increase the largest acknowledged,
+	// they can't decrease, unless the counts are mangled.
+	if ack.ECT0 < e.ect0 || ack.ECNCE < e.ecnce {
+		e.failValidation(logging.ECNFailedTooFewECNCounts)
+		return false
+	}
+	// We never send packets marked with ECT(1).
+	if ack.ECT1 > 0 || ack.ECT0+ack.ECNCE > e.numSentECT0 {
+		e.failValidation(logging.ECNFailedMoreECNCountsThanSent)
+		return false
+	}
+	newECT0 := ack.ECT0 - e.ect0
+	newECNCE := ack.ECNCE - e.ecnce
+	if newECT0+newECNCE < newlyAckedECT0 {
+		e.failValidation(logging.ECNFailedTooFewECNCounts)
+		return false
+	}
+	e.ect0 = ack.ECT0
+	e.ecnce = ack.ECNCE
+
+	if e.state == logging.ECNStateTesting && newlyAckedECT0 > 0 {
+		e.logger.Debugf("ECN validation successful.")
+		e.state = logging.ECNStateCapable
+		if e.tracer != nil {
+			e.tracer.ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		}
+	}
+	return newECNCE > 0
+}
+
+func (e *ecnTracker) failValidation(trigger logging.ECNStateTrigger) {
+	e.logger.Debugf("ECN validation failed. Disabling ECN.")
+	e.state = logging.ECNStateFailed
+	if e.tracer != nil {
+		e.tracer.ECNStateUpdated(logging.ECNStateFailed, trigger)
+	}
+}
@@ -0,0 +1,141 @@
+package ackhandler
+
+import (
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN tracker", func() {
+	var (
+		ecn    *ecnTracker
+		tracer *mocklogging.MockConnectionTracer
+	)
+
+	getAckFrame := func(largest protocol.PacketNumber, ect0, ecnce uint64) *wire.AckFrame {
+		return &wire.AckFrame{
+			AckRanges: []wire.AckRange{{Smallest: 0, Largest: largest}},
+			ECT0:      ect0,
+			ECNCE:     ecnce,
+		}
+	}
+
+	sendPackets := func(from, to protocol.PacketNumber) []*Packet {
+		var packets []*Packet
+		for pn := from; pn <= to; pn++ {
+			p := &Packet{PacketNumber: pn, ECN: ecn.Mode()}
+			ecn.SentPacket(p)
+			packets = append(packets, p)
+		}
+		return packets
+	}
+
+	BeforeEach(func() {
+		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+		ecn = newECNTracker(tracer, utils.DefaultLogger)
+	})
+
+	It("marks packets with ECT(0)", func() {
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("concludes that the path is ECN capable", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(packets[:5], getAckFrame(4, 5, 0))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+		Expect(ecn.HandleNewlyAcked(packets[5:], getAckFrame(9, 10, 0))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("reports an increase in the ECN-CE count", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(packets[:5], getAckFrame(4, 5, 0))).To(BeFalse())
+		Expect(ecn.HandleNewlyAcked(packets[5:7], getAckFrame(6, 6, 1))).To(BeTrue())
+		Expect(ecn.HandleNewlyAcked(packets[7:], getAckFrame(9, 9, 1))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("fails validation if the ACK doesn't contain ECN counts", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedNoECNCounts)
+		Expect(ecn.HandleNewlyAcked(packets[:5], getAckFrame(4, 0, 0))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+		// once validation failed, ACKs are not validated any more
+		Expect(ecn.HandleNewlyAcked(packets[5:], getAckFrame(9, 0, 20))).To(BeFalse())
+	})
+
+	It("doesn't fail validation if the ACK doesn't acknowledge any ECN-marked packets", func() {
+		packets := []*Packet{{PacketNumber: 0, ECN: protocol.ECNNon}}
+		Expect(ecn.HandleNewlyAcked(packets, getAckFrame(0, 0, 0))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("fails validation if too few packets are reported as ECN-marked", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedTooFewECNCounts)
+		Expect(ecn.HandleNewlyAcked(packets[:5], getAckFrame(4, 3, 1))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails validation if the ECN counts decrease", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(packets[:5], getAckFrame(4, 5, 0))).To(BeFalse())
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedTooFewECNCounts)
+		Expect(ecn.HandleNewlyAcked(packets[5:], getAckFrame(9, 4, 6))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails validation if more packets are reported as ECN-marked than were sent", func() {
+		packets := sendPackets(0, 4)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedMoreECNCountsThanSent)
+		Expect(ecn.HandleNewlyAcked(packets, getAckFrame(4, 6, 0))).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails validation if packets are reported as ECT(1)", func() {
+		packets := sendPackets(0, 4)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedMoreECNCountsThanSent)
+		ack := getAckFrame(4, 5, 0)
+		ack.ECT1 = 1
+		Expect(ecn.HandleNewlyAcked(packets, ack)).To(BeFalse())
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("fails validation if all testing packets are lost", func() {
+		packets := sendPackets(0, 14)
+		for i, p := range packets[:numECNTestingPackets] {
+			if i == numECNTestingPackets-1 {
+				tracer.EXPECT().ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedLostAllTestingPackets)
+			}
+			ecn.LostPacket(p)
+		}
+		Expect(ecn.Mode()).To(Equal(protocol.ECNNon))
+	})
+
+	It("only counts the loss of testing packets", func() {
+		packets := sendPackets(0, 19)
+		for _, p := range packets[1:] {
+			ecn.LostPacket(p)
+		}
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+
+	It("doesn't fail validation when testing packets are lost after the validation succeeded", func() {
+		packets := sendPackets(0, 9)
+		tracer.EXPECT().ECNStateUpdated(logging.ECNStateCapable, logging.ECNTriggerNoTrigger)
+		Expect(ecn.HandleNewlyAcked(packets[:1], getAckFrame(0, 1, 0))).To(BeFalse())
+		for _, p := range packets[1:] {
+			ecn.LostPacket(p)
+		}
+		Expect(ecn.Mode()).To(Equal(protocol.ECT0))
+	})
+})
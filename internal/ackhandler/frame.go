@@ -6,4 +6,6 @@ type Frame struct {
 	wire.Frame // nil if the frame has already been acknowledged in another packet
 	OnLost     func(wire.Frame)
 	OnAcked    func(wire.Frame)
+	// IsRetransmission is set if the frame is sent again, after it was declared lost
+	IsRetransmission bool
 }
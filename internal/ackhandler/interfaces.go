@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/quictrace"
@@ -16,10 +17,13 @@ type Packet struct {
 	Length          protocol.ByteCount
 	EncryptionLevel protocol.EncryptionLevel
 	SendTime        time.Time
+	ECN             protocol.ECN
 
 	includedInBytesInFlight bool
 	declaredLost            bool
 	skippedPacket           bool
+	// only set for ack-eliciting packets
+	bandwidthState congestion.SendState
 }
 
 // SentPacketHandler handles ACKs received for outgoing packets
@@ -31,6 +35,12 @@ type SentPacketHandler interface {
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeConfirmed()
+	// MigratedPath is called when the connection is migrated to a new path.
+	// If the peer's address hasn't been validated on the new path, the anti-amplification limit applies
+	// to the bytes received on the new path, until SetPathValidated is called.
+	// If resetCongestion is set, the congestion controller is reset.
+	MigratedPath(validated, resetCongestion bool)
+	SetPathValidated()
 
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
@@ -40,6 +50,8 @@ type SentPacketHandler interface {
 	TimeUntilSend() time.Time
 	// HasPacingBudget says if the pacer allows sending of a (full size) packet at this moment.
 	HasPacingBudget() bool
+	// ECNMode is the ECN codepoint that should be used for the next 1-RTT packet.
+	ECNMode() protocol.ECN
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -50,8 +62,18 @@ type SentPacketHandler interface {
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
 
+	// OnAppLimited is called when the congestion controller would allow sending, but there's no data to send.
+	OnAppLimited()
+	// BandwidthEstimate returns the bandwidth estimate, calculated from the delivery rate.
+	// If no delivery rate sample has been obtained yet, it is calculated from the congestion window and the smoothed RTT.
+	// It returns 0 if no RTT sample has been obtained yet.
+	BandwidthEstimate() congestion.Bandwidth
+
 	// report some congestion statistics. For tracing only.
 	GetStats() *quictrace.TransportState
+	// PacketsLost returns the number of packets declared lost so far, and the sum of their sizes.
+	// It is safe to call it concurrently with the other methods.
+	PacketsLost() (uint64, protocol.ByteCount)
 }
 
 type sentPacketTracker interface {
@@ -62,9 +84,13 @@ type sentPacketTracker interface {
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
 type ReceivedPacketHandler interface {
 	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
-	ReceivedPacket(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
+	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool) error
 	DropPackets(protocol.EncryptionLevel)
 
 	GetAlarmTimeout() time.Time
 	GetAckFrame(encLevel protocol.EncryptionLevel, onlyIfQueued bool) *wire.AckFrame
+
+	// ACK frequency extension, only applies to the application data packet number space
+	SetAckFrequency(*wire.AckFrequencyFrame)
+	QueueImmediateAck()
 }
@@ -1,7 +1,7 @@
 package ackhandler
 
 import (
-	"crypto/rand"
+	"io"
 	"math"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -12,15 +12,17 @@ import (
 // It is guaranteed to never skip two consecutive packet numbers.
 type packetNumberGenerator struct {
 	averagePeriod protocol.PacketNumber
+	rand          io.Reader
 
 	next       protocol.PacketNumber
 	nextToSkip protocol.PacketNumber
 }
 
-func newPacketNumberGenerator(initial, averagePeriod protocol.PacketNumber) *packetNumberGenerator {
+func newPacketNumberGenerator(initial, averagePeriod protocol.PacketNumber, rand io.Reader) *packetNumberGenerator {
 	g := &packetNumberGenerator{
 		next:          initial,
 		averagePeriod: averagePeriod,
+		rand:          rand,
 	}
 	g.generateNewSkip()
 	return g
@@ -50,11 +52,11 @@ func (p *packetNumberGenerator) generateNewSkip() {
 	p.nextToSkip = p.next + 2 + skip
 }
 
-// getRandomNumber() generates a cryptographically secure random number between 0 and MaxUint16 (= 65535)
+// getRandomNumber() generates a random number between 0 and MaxUint16 (= 65535)
 // The expectation value is 65535/2
 func (p *packetNumberGenerator) getRandomNumber() uint16 {
 	b := make([]byte, 2)
-	rand.Read(b) // ignore the error here
+	io.ReadFull(p.rand, b) // ignore the error here
 
 	num := uint16(b[0])<<8 + uint16(b[1])
 	return num
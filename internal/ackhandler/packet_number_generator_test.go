@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"bytes"
+	"crypto/rand"
 	"math"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -12,14 +14,22 @@ var _ = Describe("Packet Number Generator", func() {
 	var png *packetNumberGenerator
 
 	BeforeEach(func() {
-		png = newPacketNumberGenerator(1, 100)
+		png = newPacketNumberGenerator(1, 100, rand.Reader)
 	})
 
 	It("can be initialized to return any first packet number", func() {
-		png = newPacketNumberGenerator(12345, 100)
+		png = newPacketNumberGenerator(12345, 100, rand.Reader)
 		Expect(png.Pop()).To(Equal(protocol.PacketNumber(12345)))
 	})
 
+	It("uses the random source to determine which packet number to skip", func() {
+		// A random number of 0 means that the packet number after the next one is skipped.
+		png = newPacketNumberGenerator(1, 100, bytes.NewReader(make([]byte, 4)))
+		Expect(png.Pop()).To(Equal(protocol.PacketNumber(1)))
+		Expect(png.Pop()).To(Equal(protocol.PacketNumber(2)))
+		Expect(png.Pop()).To(Equal(protocol.PacketNumber(4)))
+	})
+
 	It("gets 1 as the first packet number", func() {
 		num := png.Pop()
 		Expect(num).To(Equal(protocol.PacketNumber(1)))
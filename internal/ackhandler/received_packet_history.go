@@ -11,13 +11,16 @@ import (
 // It does not store packet contents.
 type receivedPacketHistory struct {
 	ranges *utils.PacketIntervalList
+	// the maximum number of ACK ranges returned by GetAckRanges
+	maxAckRanges int
 
 	deletedBelow protocol.PacketNumber
 }
 
-func newReceivedPacketHistory() *receivedPacketHistory {
+func newReceivedPacketHistory(maxAckRanges int) *receivedPacketHistory {
 	return &receivedPacketHistory{
-		ranges: utils.NewPacketIntervalList(),
+		ranges:       utils.NewPacketIntervalList(),
+		maxAckRanges: maxAckRanges,
 	}
 }
 
@@ -105,15 +108,16 @@ func (h *receivedPacketHistory) DeleteBelow(p protocol.PacketNumber) {
 	}
 }
 
-// GetAckRanges gets a slice of all AckRanges that can be used in an AckFrame
+// GetAckRanges gets a slice of AckRanges that can be used in an AckFrame.
+// If more than maxAckRanges ranges are tracked, only the highest ranges are returned.
 func (h *receivedPacketHistory) GetAckRanges() []wire.AckRange {
 	if h.ranges.Len() == 0 {
 		return nil
 	}
 
-	ackRanges := make([]wire.AckRange, h.ranges.Len())
+	ackRanges := make([]wire.AckRange, utils.Min(h.ranges.Len(), h.maxAckRanges))
 	i := 0
-	for el := h.ranges.Back(); el != nil; el = el.Prev() {
+	for el := h.ranges.Back(); el != nil && i < len(ackRanges); el = el.Prev() {
 		ackRanges[i] = wire.AckRange{Smallest: el.Value.Start, Largest: el.Value.End}
 		i++
 	}
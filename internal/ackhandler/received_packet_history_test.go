@@ -14,7 +14,7 @@ var _ = Describe("receivedPacketHistory", func() {
 	)
 
 	BeforeEach(func() {
-		hist = newReceivedPacketHistory()
+		hist = newReceivedPacketHistory(protocol.MaxNumAckRanges)
 	})
 
 	Context("ranges", func() {
@@ -227,6 +227,20 @@ var _ = Describe("receivedPacketHistory", func() {
 			Expect(ackRanges[1]).To(Equal(wire.AckRange{Smallest: 4, Largest: 6}))
 			Expect(ackRanges[2]).To(Equal(wire.AckRange{Smallest: 1, Largest: 2}))
 		})
+
+		It("only returns the highest ACK ranges when limited", func() {
+			hist = newReceivedPacketHistory(2)
+			Expect(hist.ReceivedPacket(1)).To(BeTrue())
+			Expect(hist.ReceivedPacket(4)).To(BeTrue())
+			Expect(hist.ReceivedPacket(5)).To(BeTrue())
+			Expect(hist.ReceivedPacket(10)).To(BeTrue())
+			ackRanges := hist.GetAckRanges()
+			Expect(ackRanges).To(HaveLen(2))
+			Expect(ackRanges[0]).To(Equal(wire.AckRange{Smallest: 10, Largest: 10}))
+			Expect(ackRanges[1]).To(Equal(wire.AckRange{Smallest: 4, Largest: 5}))
+			// the lower range is still tracked
+			Expect(hist.IsPotentiallyDuplicate(1)).To(BeTrue())
+		})
 	})
 
 	Context("Getting the highest ACK range", func() {
@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"math"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -18,8 +19,17 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
+	ect0, ect1, ecnce uint64
+
 	maxAckDelay time.Duration
 	rttStats    *utils.RTTStats
+	clock       utils.Clock
+
+	// updated by ACK_FREQUENCY frames
+	packetTolerance       int
+	ignoreOrder           bool
+	receivedAckFrequency  bool
+	ackFrequencySeqNumber uint64
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
@@ -34,20 +44,24 @@ type receivedPacketTracker struct {
 }
 
 func newReceivedPacketTracker(
+	maxAckRanges int,
 	rttStats *utils.RTTStats,
+	clock utils.Clock,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:   newReceivedPacketHistory(maxAckRanges),
+		maxAckDelay:     protocol.MaxAckDelay,
+		packetTolerance: packetsBeforeAck,
+		rttStats:        rttStats,
+		clock:           clock,
+		logger:          logger,
+		version:         version,
 	}
 }
 
-func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck bool) {
+func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, rcvTime time.Time, shouldInstigateAck bool) {
 	if packetNumber < h.ignoreBelow {
 		return
 	}
@@ -58,9 +72,20 @@ func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumbe
 		h.largestObservedReceivedTime = rcvTime
 	}
 
-	if isNew := h.packetHistory.ReceivedPacket(packetNumber); isNew && shouldInstigateAck {
+	isNew := h.packetHistory.ReceivedPacket(packetNumber)
+	if isNew && shouldInstigateAck {
 		h.hasNewAck = true
 	}
+	if isNew {
+		switch ecn {
+		case protocol.ECT0:
+			h.ect0++
+		case protocol.ECT1:
+			h.ect1++
+		case protocol.ECNCE:
+			h.ecnce++
+		}
+	}
 	if shouldInstigateAck {
 		h.maybeQueueAck(packetNumber, rcvTime, isMissing)
 	}
@@ -79,6 +104,29 @@ func (h *receivedPacketTracker) IgnoreBelow(p protocol.PacketNumber) {
 	}
 }
 
+// SetAckFrequency applies the parameters of an ACK_FREQUENCY frame.
+// Frames with a sequence number that is not larger than the largest one processed so far are ignored.
+func (h *receivedPacketTracker) SetAckFrequency(f *wire.AckFrequencyFrame) {
+	if h.receivedAckFrequency && f.SequenceNumber <= h.ackFrequencySeqNumber {
+		return
+	}
+	h.receivedAckFrequency = true
+	h.ackFrequencySeqNumber = f.SequenceNumber
+	h.packetTolerance = int(utils.MinUint64(f.PacketTolerance, math.MaxInt32))
+	h.maxAckDelay = f.UpdateMaxAckDelay
+	h.ignoreOrder = f.IgnoreOrder
+	if h.logger.Debug() {
+		h.logger.Debugf("\tUpdating ACK frequency: packet tolerance %d, max ack delay %s, ignore order: %t", h.packetTolerance, h.maxAckDelay, h.ignoreOrder)
+	}
+}
+
+// QueueImmediateAck queues an ACK, as requested by an IMMEDIATE_ACK frame.
+func (h *receivedPacketTracker) QueueImmediateAck() {
+	h.logger.Debugf("\tQueueing ACK because an IMMEDIATE_ACK frame was received.")
+	h.ackQueued = true
+	h.ackAlarm = time.Time{}
+}
+
 // isMissing says if a packet was reported missing in the last ACK.
 func (h *receivedPacketTracker) isMissing(p protocol.PacketNumber) bool {
 	if h.lastAck == nil || p < h.ignoreBelow {
@@ -115,17 +163,17 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	// Send an ACK if this packet was reported missing in an ACK sent before.
 	// Ack decimation with reordering relies on the timer to send an ACK, but if
 	// missing packets we reported in the previous ack, send an ACK immediately.
-	if wasMissing {
+	if wasMissing && !h.ignoreOrder {
 		if h.logger.Debug() {
 			h.logger.Debugf("\tQueueing ACK because packet %d was missing before.", pn)
 		}
 		h.ackQueued = true
 	}
 
-	// send an ACK every 2 ack-eliciting packets
-	if h.ackElicitingPacketsReceivedSinceLastAck >= packetsBeforeAck {
+	// send an ACK every 2 ack-eliciting packets (or as requested by the peer's ACK_FREQUENCY frame)
+	if h.ackElicitingPacketsReceivedSinceLastAck >= h.packetTolerance {
 		if h.logger.Debug() {
-			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using initial threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, packetsBeforeAck)
+			h.logger.Debugf("\tQueueing ACK because packet %d packets were received after the last ACK (using threshold: %d).", h.ackElicitingPacketsReceivedSinceLastAck, h.packetTolerance)
 		}
 		h.ackQueued = true
 	} else if h.ackAlarm.IsZero() {
@@ -136,7 +184,7 @@ func (h *receivedPacketTracker) maybeQueueAck(pn protocol.PacketNumber, rcvTime
 	}
 
 	// Queue an ACK if there are new missing packets to report.
-	if h.hasNewMissingPackets() {
+	if !h.ignoreOrder && h.hasNewMissingPackets() {
 		h.logger.Debugf("\tQueuing ACK because there's a new missing packet to report.")
 		h.ackQueued = true
 	}
@@ -151,7 +199,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	if !h.hasNewAck {
 		return nil
 	}
-	now := time.Now()
+	now := h.clock.Now()
 	if onlyIfQueued {
 		if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 			return nil
@@ -166,6 +214,9 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
 		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		ECT0:      h.ect0,
+		ECT1:      h.ect1,
+		ECNCE:     h.ecnce,
 	}
 
 	h.lastAck = ack
@@ -19,12 +19,12 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(protocol.MaxNumAckRanges, rttStats, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
 		It("saves the time when each packet arrived", func() {
-			tracker.ReceivedPacket(protocol.PacketNumber(3), time.Now(), true)
+			tracker.ReceivedPacket(protocol.PacketNumber(3), protocol.ECNNon, time.Now(), true)
 			Expect(tracker.largestObservedReceivedTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
 		})
 
@@ -32,7 +32,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			now := time.Now()
 			tracker.largestObserved = 3
 			tracker.largestObservedReceivedTime = now.Add(-1 * time.Second)
-			tracker.ReceivedPacket(5, now, true)
+			tracker.ReceivedPacket(5, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(now))
 		})
@@ -42,7 +42,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			timestamp := now.Add(-1 * time.Second)
 			tracker.largestObserved = 5
 			tracker.largestObservedReceivedTime = timestamp
-			tracker.ReceivedPacket(4, now, true)
+			tracker.ReceivedPacket(4, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(timestamp))
 		})
@@ -52,21 +52,21 @@ var _ = Describe("Received Packet Tracker", func() {
 		Context("queueing ACKs", func() {
 			receiveAndAck10Packets := func() {
 				for i := 1; i <= 10; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 				Expect(tracker.ackQueued).To(BeFalse())
 			}
 
 			It("always queues an ACK for the first packet", func() {
-				tracker.ReceivedPacket(1, time.Now(), true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame(true).DelayTime).To(BeNumerically("~", 0, time.Second))
 			})
 
 			It("works with packet number 0", func() {
-				tracker.ReceivedPacket(0, time.Now(), true)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame(true).DelayTime).To(BeNumerically("~", 0, time.Second))
@@ -76,10 +76,10 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAck10Packets()
 				p := protocol.PacketNumber(11)
 				for i := 0; i <= 20; i++ {
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeFalse())
 					p++
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeTrue())
 					p++
 					// dequeue the ACK frame
@@ -90,47 +90,47 @@ var _ = Describe("Received Packet Tracker", func() {
 			It("resets the counter when a non-queued ACK frame is generated", func() {
 				receiveAndAck10Packets()
 				rcvTime := time.Now()
-				tracker.ReceivedPacket(11, rcvTime, true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(false)).ToNot(BeNil())
-				tracker.ReceivedPacket(12, rcvTime, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
-				tracker.ReceivedPacket(13, rcvTime, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.GetAckFrame(false)).ToNot(BeNil())
 			})
 
 			It("only sets the timer when receiving a ack-eliciting packets", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Now(), false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				rcvTime := time.Now().Add(10 * time.Millisecond)
-				tracker.ReceivedPacket(12, rcvTime, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Now(), true)
-				tracker.ReceivedPacket(13, time.Now(), true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
 				ack := tracker.GetAckFrame(true) // ACK: 1-11 and 13, missing: 12
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(tracker.ackQueued).To(BeFalse())
-				tracker.ReceivedPacket(12, time.Now(), true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 			})
 
 			It("doesn't queue an ACK if it was reported missing before, but is below the threshold", func() {
 				receiveAndAck10Packets()
 				// 11 is missing
-				tracker.ReceivedPacket(12, time.Now(), true)
-				tracker.ReceivedPacket(13, time.Now(), true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)
 				ack := tracker.GetAckFrame(true) // ACK: 1-10, 12-13
 				Expect(ack).ToNot(BeNil())
 				// now receive 11
 				tracker.IgnoreBelow(12)
-				tracker.ReceivedPacket(11, time.Now(), false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), false)
 				ack = tracker.GetAckFrame(true)
 				Expect(ack).To(BeNil())
 			})
@@ -140,7 +140,7 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.lastAck.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
 				Expect(tracker.ackQueued).To(BeFalse())
 				tracker.IgnoreBelow(11)
-				tracker.ReceivedPacket(11, time.Now(), true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 			})
 
@@ -149,7 +149,7 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.lastAck.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
 				Expect(tracker.ackQueued).To(BeFalse())
 				tracker.IgnoreBelow(11)
-				tracker.ReceivedPacket(12, time.Now(), true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
 				ack := tracker.GetAckFrame(true)
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 12, Largest: 12}}))
@@ -157,38 +157,99 @@ var _ = Describe("Received Packet Tracker", func() {
 
 			It("doesn't queue an ACK if for non-ack-eliciting packets arriving out-of-order", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Now(), true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
-				tracker.ReceivedPacket(13, time.Now(), false) // receive a non-ack-eliciting packet out-of-order
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), false) // receive a non-ack-eliciting packet out-of-order
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 			})
 
 			It("doesn't queue an ACK if packets arrive out-of-order, but haven't been acknowledged yet", func() {
 				receiveAndAck10Packets()
 				Expect(tracker.lastAck).ToNot(BeNil())
-				tracker.ReceivedPacket(12, time.Now(), false)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 				// 11 is received out-of-order, but this hasn't been reported in an ACK frame yet
-				tracker.ReceivedPacket(11, time.Now(), true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.GetAckFrame(true)).To(BeNil())
 			})
 		})
 
+		Context("ACK frequency", func() {
+			receiveAndAck10Packets := func() {
+				for i := 1; i <= 10; i++ {
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
+				}
+				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+			}
+
+			It("uses the packet tolerance", func() {
+				receiveAndAck10Packets()
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 5, UpdateMaxAckDelay: protocol.MaxAckDelay})
+				for i := 11; i < 15; i++ {
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Now(), true)
+					Expect(tracker.ackQueued).To(BeFalse())
+				}
+				tracker.ReceivedPacket(15, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeTrue())
+			})
+
+			It("uses the max ack delay", func() {
+				receiveAndAck10Packets()
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 2, UpdateMaxAckDelay: 5 * time.Millisecond})
+				rcvTime := time.Now()
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
+				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(5 * time.Millisecond)))
+			})
+
+			It("ignores reordering, if requested", func() {
+				receiveAndAck10Packets()
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{PacketTolerance: 10, UpdateMaxAckDelay: protocol.MaxAckDelay, IgnoreOrder: true})
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)
+				Expect(tracker.ackQueued).To(BeFalse())
+			})
+
+			It("ignores ACK_FREQUENCY frames with old sequence numbers", func() {
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 3, PacketTolerance: 5, UpdateMaxAckDelay: 5 * time.Millisecond})
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 3, PacketTolerance: 7, UpdateMaxAckDelay: 7 * time.Millisecond})
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 2, PacketTolerance: 8, UpdateMaxAckDelay: 8 * time.Millisecond})
+				Expect(tracker.packetTolerance).To(Equal(5))
+				Expect(tracker.maxAckDelay).To(Equal(5 * time.Millisecond))
+				tracker.SetAckFrequency(&wire.AckFrequencyFrame{SequenceNumber: 4, PacketTolerance: 9, UpdateMaxAckDelay: 9 * time.Millisecond})
+				Expect(tracker.packetTolerance).To(Equal(9))
+				Expect(tracker.maxAckDelay).To(Equal(9 * time.Millisecond))
+			})
+
+			It("queues an ACK when receiving an IMMEDIATE_ACK frame", func() {
+				receiveAndAck10Packets()
+				rcvTime := time.Now()
+				tracker.ReceivedPacket(11, protocol.ECNNon, rcvTime, true)
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				tracker.QueueImmediateAck()
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				ack := tracker.GetAckFrame(true)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(11)))
+			})
+		})
+
 		Context("ACK generation", func() {
 			It("generates an ACK for an ack-eliciting packet, if no ACK is queued yet", func() {
-				tracker.ReceivedPacket(1, time.Now(), true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 				// The first packet is always acknowledged.
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 			})
 
 			It("doesn't generate ACK for a non-ack-eliciting packet, if no ACK is queued yet", func() {
-				tracker.ReceivedPacket(1, time.Now(), true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 				// The first packet is always acknowledged.
 				Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 
-				tracker.ReceivedPacket(2, time.Now(), false)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.GetAckFrame(false)).To(BeNil())
-				tracker.ReceivedPacket(3, time.Now(), true)
+				tracker.ReceivedPacket(3, protocol.ECNNon, time.Now(), true)
 				ack := tracker.GetAckFrame(false)
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LowestAcked()).To(Equal(protocol.PacketNumber(1)))
@@ -201,8 +262,8 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates a simple ACK frame", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
-					tracker.ReceivedPacket(2, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(2)))
@@ -211,7 +272,7 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates an ACK for packet number 0", func() {
-					tracker.ReceivedPacket(0, time.Now(), true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(0)))
@@ -220,26 +281,26 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("sets the delay time", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
-					tracker.ReceivedPacket(2, time.Now().Add(-1337*time.Millisecond), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Now().Add(-1337*time.Millisecond), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.DelayTime).To(BeNumerically("~", 1337*time.Millisecond, 50*time.Millisecond))
 				})
 
 				It("uses a 0 delay time if the delay would be negative", func() {
-					tracker.ReceivedPacket(0, time.Now().Add(time.Hour), true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Now().Add(time.Hour), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.DelayTime).To(BeZero())
 				})
 
 				It("saves the last sent ACK", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(tracker.lastAck).To(Equal(ack))
-					tracker.ReceivedPacket(2, time.Now(), true)
+					tracker.ReceivedPacket(2, protocol.ECNNon, time.Now(), true)
 					tracker.ackQueued = true
 					ack = tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
@@ -247,8 +308,8 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("generates an ACK frame with missing packets", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
-					tracker.ReceivedPacket(4, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(4, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(4)))
@@ -259,10 +320,41 @@ var _ = Describe("Received Packet Tracker", func() {
 					}))
 				})
 
+				It("reports the ECN counts", func() {
+					tracker.ReceivedPacket(1, protocol.ECT0, time.Now(), true)
+					tracker.ReceivedPacket(2, protocol.ECT0, time.Now(), true)
+					tracker.ReceivedPacket(3, protocol.ECT1, time.Now(), true)
+					tracker.ReceivedPacket(4, protocol.ECNCE, time.Now(), true)
+					tracker.ReceivedPacket(5, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(4, protocol.ECNCE, time.Now(), true) // duplicate, not counted
+					ack := tracker.GetAckFrame(true)
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.ECT0).To(BeEquivalentTo(2))
+					Expect(ack.ECT1).To(BeEquivalentTo(1))
+					Expect(ack.ECNCE).To(BeEquivalentTo(1))
+				})
+
+				It("doesn't send more than the configured number of ACK ranges", func() {
+					tracker = newReceivedPacketTracker(3, rttStats, utils.DefaultClock{}, utils.DefaultLogger, protocol.VersionWhatever)
+					var ack *wire.AckFrame
+					for i := protocol.PacketNumber(0); i < 10; i++ {
+						tracker.ReceivedPacket(2*i, protocol.ECNNon, time.Now(), true)
+						ack = tracker.GetAckFrame(false)
+						Expect(ack).ToNot(BeNil())
+						Expect(len(ack.AckRanges)).To(BeNumerically("<=", 3))
+						Expect(ack.LargestAcked()).To(Equal(2 * i))
+					}
+					Expect(ack.AckRanges).To(Equal([]wire.AckRange{
+						{Smallest: 18, Largest: 18},
+						{Smallest: 16, Largest: 16},
+						{Smallest: 14, Largest: 14},
+					}))
+				})
+
 				It("generates an ACK for packet number 0 and other packets", func() {
-					tracker.ReceivedPacket(0, time.Now(), true)
-					tracker.ReceivedPacket(1, time.Now(), true)
-					tracker.ReceivedPacket(3, time.Now(), true)
+					tracker.ReceivedPacket(0, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(3, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(3)))
@@ -275,8 +367,8 @@ var _ = Describe("Received Packet Tracker", func() {
 
 				It("doesn't add delayed packets to the packetHistory", func() {
 					tracker.IgnoreBelow(7)
-					tracker.ReceivedPacket(4, time.Now(), true)
-					tracker.ReceivedPacket(10, time.Now(), true)
+					tracker.ReceivedPacket(4, protocol.ECNNon, time.Now(), true)
+					tracker.ReceivedPacket(10, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
@@ -285,7 +377,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 				It("deletes packets from the packetHistory when a lower limit is set", func() {
 					for i := 1; i <= 12; i++ {
-						tracker.ReceivedPacket(protocol.PacketNumber(i), time.Now(), true)
+						tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Now(), true)
 					}
 					tracker.IgnoreBelow(7)
 					// check that the packets were deleted from the receivedPacketHistory by checking the values in an ACK frame
@@ -299,14 +391,14 @@ var _ = Describe("Received Packet Tracker", func() {
 				// TODO: remove this test when dropping support for STOP_WAITINGs
 				It("handles a lower limit of 0", func() {
 					tracker.IgnoreBelow(0)
-					tracker.ReceivedPacket(1337, time.Now(), true)
+					tracker.ReceivedPacket(1337, protocol.ECNNon, time.Now(), true)
 					ack := tracker.GetAckFrame(true)
 					Expect(ack).ToNot(BeNil())
 					Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1337)))
 				})
 
 				It("resets all counters needed for the ACK queueing decision when sending an ACK", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 					tracker.ackAlarm = time.Now().Add(-time.Minute)
 					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
 					Expect(tracker.GetAlarmTimeout()).To(BeZero())
@@ -315,21 +407,21 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 
 				It("doesn't generate an ACK when none is queued and the timer is not set", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Time{}
 					Expect(tracker.GetAckFrame(true)).To(BeNil())
 				})
 
 				It("doesn't generate an ACK when none is queued and the timer has not yet expired", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Now().Add(time.Minute)
 					Expect(tracker.GetAckFrame(true)).To(BeNil())
 				})
 
 				It("generates an ACK when the timer has expired", func() {
-					tracker.ReceivedPacket(1, time.Now(), true)
+					tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
 					tracker.ackQueued = false
 					tracker.ackAlarm = time.Now().Add(-time.Minute)
 					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
@@ -3,6 +3,8 @@ package ackhandler
 import (
 	"errors"
 	"fmt"
+	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -20,8 +22,6 @@ const (
 	timeThreshold = 9.0 / 8
 	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
 	packetThreshold = 3
-	// Before validating the client's address, the server won't send more than 3x bytes than it received.
-	amplificationFactor = 3
 )
 
 type packetNumberSpace struct {
@@ -35,16 +35,21 @@ type packetNumberSpace struct {
 	largestSent  protocol.PacketNumber
 }
 
-func newPacketNumberSpace(initialPN protocol.PacketNumber, rttStats *utils.RTTStats) *packetNumberSpace {
+func newPacketNumberSpace(initialPN protocol.PacketNumber, rttStats *utils.RTTStats, rand io.Reader) *packetNumberSpace {
 	return &packetNumberSpace{
 		history:      newSentPacketHistory(rttStats),
-		pns:          newPacketNumberGenerator(initialPN, protocol.SkipPacketAveragePeriodLength),
+		pns:          newPacketNumberGenerator(initialPN, protocol.SkipPacketAveragePeriodLength, rand),
 		largestSent:  protocol.InvalidPacketNumber,
 		largestAcked: protocol.InvalidPacketNumber,
 	}
 }
 
 type sentPacketHandler struct {
+	// The number of packets declared lost, and the sum of their sizes.
+	// Accessed atomically, and therefore placed at the beginning of the struct, to guarantee 64 bit alignment.
+	numLostPackets uint64
+	numLostBytes   uint64
+
 	initialPackets   *packetNumberSpace
 	handshakePackets *packetNumberSpace
 	appDataPackets   *packetNumberSpace
@@ -54,9 +59,13 @@ type sentPacketHandler struct {
 	peerCompletedAddressValidation bool
 	bytesReceived                  protocol.ByteCount
 	bytesSent                      protocol.ByteCount
+	// Before validating the client's address, the server won't send more than amplificationFactor times the bytes it received.
+	amplificationFactor protocol.ByteCount
 	// Have we validated the peer's address yet?
 	// Always true for the client.
 	peerAddressValidated bool
+	// Set when BlockedByAmplificationLimit was traced, reset when receiving more bytes.
+	tracedAmplificationLimited bool
 
 	handshakeConfirmed bool
 
@@ -68,12 +77,23 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
+	congestion       congestion.SendAlgorithmWithDebugInfos
+	bandwidthSampler congestion.BandwidthSampler
+	rttStats         *utils.RTTStats
+	// used to create a new congestion controller when the connection is migrated
+	congestionFactory func(*utils.RTTStats, protocol.ByteCount, protocol.ByteCount) congestion.SendAlgorithm
+	maxSendRate       protocol.ByteCount
+	// the congestion window that was last passed to the tracer
+	tracedCongestionWindow protocol.ByteCount
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
 	ptoMode  SendMode
+	// The maximum PTO duration. If zero, the PTO is not capped.
+	maxPTO time.Duration
+	// nil if ECN is disabled
+	ecnTracker *ecnTracker
+
 	// The number of PTO probe packets that should be sent.
 	// Only applies to the application-data packet number space.
 	numProbesToSend int
@@ -82,6 +102,9 @@ type sentPacketHandler struct {
 	alarm time.Time
 
 	perspective protocol.Perspective
+	// the source of randomness used for skipping packet numbers
+	rand  io.Reader
+	clock utils.Clock
 
 	traceCallback func(quictrace.Event)
 	tracer        logging.ConnectionTracer
@@ -95,25 +118,40 @@ func newSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	maxSendRate protocol.ByteCount,
+	maxPTO time.Duration,
+	amplificationFactor int,
+	enableECN bool,
+	congestionFactory func(*utils.RTTStats, protocol.ByteCount, protocol.ByteCount) congestion.SendAlgorithm,
+	rand io.Reader,
+	clock utils.Clock,
 	traceCallback func(quictrace.Event),
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
-	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
-		rttStats,
-		true, // use Reno
-		tracer,
-	)
+	bandwidthSampler := congestion.NewBandwidthSampler()
+	congestion := congestion.NewSendAlgorithm(congestionFactory, rttStats, maxSendRate, clock, tracer)
 
+	var ecn *ecnTracker
+	if enableECN {
+		ecn = newECNTracker(tracer, logger)
+	}
 	return &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
 		peerAddressValidated:           pers == protocol.PerspectiveClient,
-		initialPackets:                 newPacketNumberSpace(initialPacketNumber, rttStats),
-		handshakePackets:               newPacketNumberSpace(0, rttStats),
-		appDataPackets:                 newPacketNumberSpace(0, rttStats),
+		amplificationFactor:            protocol.ByteCount(amplificationFactor),
+		initialPackets:                 newPacketNumberSpace(initialPacketNumber, rttStats, rand),
+		handshakePackets:               newPacketNumberSpace(0, rttStats, rand),
+		appDataPackets:                 newPacketNumberSpace(0, rttStats, rand),
+		rand:                           rand,
+		clock:                          clock,
 		rttStats:                       rttStats,
 		congestion:                     congestion,
+		congestionFactory:              congestionFactory,
+		maxSendRate:                    maxSendRate,
+		bandwidthSampler:               bandwidthSampler,
+		maxPTO:                         maxPTO,
+		ecnTracker:                     ecn,
 		perspective:                    pers,
 		traceCallback:                  traceCallback,
 		tracer:                         tracer,
@@ -186,6 +224,7 @@ func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 
 func (h *sentPacketHandler) ReceivedBytes(n protocol.ByteCount) {
 	h.bytesReceived += n
+	h.tracedAmplificationLimited = false
 }
 
 func (h *sentPacketHandler) ReceivedPacket(encLevel protocol.EncryptionLevel) {
@@ -213,6 +252,9 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) {
 	}
 	isAckEliciting := h.sentPacketImpl(packet)
 	h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet, isAckEliciting)
+	if h.ecnTracker != nil && packet.EncryptionLevel == protocol.Encryption1RTT {
+		h.ecnTracker.SentPacket(packet)
+	}
 	if h.tracer != nil && isAckEliciting {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 	}
@@ -248,6 +290,7 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 
 	if isAckEliciting {
 		pnSpace.lastAckElicitingPacketTime = packet.SendTime
+		packet.bandwidthState = h.bandwidthSampler.OnPacketSent(packet.SendTime, h.bytesInFlight)
 		packet.includedInBytesInFlight = true
 		h.bytesInFlight += packet.Length
 		if h.numProbesToSend > 0 {
@@ -267,6 +310,8 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 		return qerr.NewError(qerr.ProtocolViolation, "Received ACK for an unsent packet")
 	}
 
+	// ACKs that arrive out of order can't be used for ECN validation
+	increasedLargestAcked := largestAcked > pnSpace.largestAcked
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
 
 	// Servers complete address validation when a protected packet is received.
@@ -308,15 +353,28 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	for _, p := range lostPackets {
 		h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 	}
+	if h.ecnTracker != nil && encLevel == protocol.Encryption1RTT && increasedLargestAcked {
+		if congested := h.ecnTracker.HandleNewlyAcked(ackedPackets, ack); congested {
+			// An increase of the ECN-CE count is treated like a packet loss.
+			p := ackedPackets[len(ackedPackets)-1]
+			h.congestion.OnPacketLost(p.PacketNumber, 0, priorInFlight)
+		}
+	}
 	for _, p := range ackedPackets {
 		if p.skippedPacket {
 			return fmt.Errorf("received an ACK for skipped packet number: %d (%s)", p.PacketNumber, encLevel)
 		}
+		if p.declaredLost && h.tracer != nil {
+			h.tracer.SpuriousLoss(encLevel, p.PacketNumber)
+		}
 		if p.includedInBytesInFlight && !p.declaredLost {
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
 		}
+		h.bandwidthSampler.OnPacketAcked(rcvTime, p.Length, p.bandwidthState)
 		h.removeFromBytesInFlight(p)
 	}
+	h.bandwidthSampler.OnAckProcessed(h.rttStats.MinRTT())
+	h.maybeTraceCongestionWindow()
 
 	// Reset the pto_count unless the client is unsure if the server has validated the client's address.
 	if h.peerCompletedAddressValidation {
@@ -332,6 +390,18 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return nil
 }
 
+func (h *sentPacketHandler) maybeTraceCongestionWindow() {
+	if h.tracer == nil {
+		return
+	}
+	cwnd := h.congestion.GetCongestionWindow()
+	if cwnd == h.tracedCongestionWindow {
+		return
+	}
+	h.tracedCongestionWindow = cwnd
+	h.tracer.CongestionWindowUpdated(cwnd, h.bytesInFlight)
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -417,10 +487,21 @@ func (h *sentPacketHandler) getLossTimeAndSpace() (time.Time, protocol.Encryptio
 	return lossTime, encLevel
 }
 
+// ptoDuration returns the PTO duration, including the exponential backoff.
+// It is capped at maxPTO, if set.
+func (h *sentPacketHandler) ptoDuration(includeMaxAckDelay bool) time.Duration {
+	pto := h.rttStats.PTO(includeMaxAckDelay) << h.ptoCount
+	// the shift might have overflowed
+	if h.maxPTO > 0 && (pto > h.maxPTO || pto <= 0) {
+		return h.maxPTO
+	}
+	return pto
+}
+
 // same logic as getLossTimeAndSpace, but for lastAckElicitingPacketTime instead of lossTime
 func (h *sentPacketHandler) getPTOTimeAndSpace() (time.Time, protocol.EncryptionLevel) {
 	if !h.hasOutstandingPackets() {
-		t := time.Now().Add(h.rttStats.PTO(false) << h.ptoCount)
+		t := h.clock.Now().Add(h.ptoDuration(false))
 		if h.initialPackets != nil {
 			return t, protocol.EncryptionInitial
 		}
@@ -435,18 +516,18 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (time.Time, protocol.Encryption
 	if h.initialPackets != nil {
 		encLevel = protocol.EncryptionInitial
 		if t := h.initialPackets.lastAckElicitingPacketTime; !t.IsZero() {
-			pto = t.Add(h.rttStats.PTO(false) << h.ptoCount)
+			pto = t.Add(h.ptoDuration(false))
 		}
 	}
 	if h.handshakePackets != nil && !h.handshakePackets.lastAckElicitingPacketTime.IsZero() {
-		t := h.handshakePackets.lastAckElicitingPacketTime.Add(h.rttStats.PTO(false) << h.ptoCount)
+		t := h.handshakePackets.lastAckElicitingPacketTime.Add(h.ptoDuration(false))
 		if pto.IsZero() || (!t.IsZero() && t.Before(pto)) {
 			pto = t
 			encLevel = protocol.EncryptionHandshake
 		}
 	}
 	if h.handshakeConfirmed && !h.appDataPackets.lastAckElicitingPacketTime.IsZero() {
-		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.rttStats.PTO(true) << h.ptoCount)
+		t := h.appDataPackets.lastAckElicitingPacketTime.Add(h.ptoDuration(true))
 		if pto.IsZero() || (!t.IsZero() && t.Before(pto)) {
 			pto = t
 			encLevel = protocol.Encryption1RTT
@@ -557,6 +638,11 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 
 	for _, p := range lostPackets {
 		p.declaredLost = true
+		atomic.AddUint64(&h.numLostPackets, 1)
+		atomic.AddUint64(&h.numLostBytes, uint64(p.Length))
+		if h.ecnTracker != nil && p.EncryptionLevel == protocol.Encryption1RTT {
+			h.ecnTracker.LostPacket(p)
+		}
 		h.queueFramesForRetransmission(p)
 		// the bytes in flight need to be reduced no matter if this packet will be retransmitted
 		h.removeFromBytesInFlight(p)
@@ -604,13 +690,14 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 		}
 		// Early retransmit or time loss detection
 		priorInFlight := h.bytesInFlight
-		lostPackets, err := h.detectLostPackets(time.Now(), encLevel)
+		lostPackets, err := h.detectLostPackets(h.clock.Now(), encLevel)
 		if err != nil {
 			return err
 		}
 		for _, p := range lostPackets {
 			h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 		}
+		h.maybeTraceCongestionWindow()
 		return nil
 	}
 
@@ -687,6 +774,10 @@ func (h *sentPacketHandler) SendMode() SendMode {
 
 	if h.AmplificationWindow() == 0 {
 		h.logger.Debugf("Amplification window limited. Received %d bytes, already sent out %d bytes", h.bytesReceived, h.bytesSent)
+		if h.tracer != nil && !h.tracedAmplificationLimited {
+			h.tracer.BlockedByAmplificationLimit()
+			h.tracedAmplificationLimited = true
+		}
 		return SendNone
 	}
 	// Don't send any packets if we're keeping track of the maximum number of packets.
@@ -726,14 +817,21 @@ func (h *sentPacketHandler) HasPacingBudget() bool {
 	return h.congestion.HasPacingBudget()
 }
 
+func (h *sentPacketHandler) ECNMode() protocol.ECN {
+	if h.ecnTracker == nil {
+		return protocol.ECNNon
+	}
+	return h.ecnTracker.Mode()
+}
+
 func (h *sentPacketHandler) AmplificationWindow() protocol.ByteCount {
 	if h.peerAddressValidated {
 		return protocol.MaxByteCount
 	}
-	if h.bytesSent >= amplificationFactor*h.bytesReceived {
+	if h.bytesSent >= h.amplificationFactor*h.bytesReceived {
 		return 0
 	}
-	return amplificationFactor*h.bytesReceived - h.bytesSent
+	return h.amplificationFactor*h.bytesReceived - h.bytesSent
 }
 
 func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel) bool {
@@ -785,7 +883,7 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	// Only use the Retry to estimate the RTT if we didn't send any retransmission for the Initial.
 	// Otherwise, we don't know which Initial the Retry was sent in response to.
 	if h.ptoCount == 0 {
-		now := time.Now()
+		now := h.clock.Now()
 		h.rttStats.UpdateRTT(now.Sub(firstPacketSendTime), 0, now)
 		if h.logger.Debug() {
 			h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
@@ -794,8 +892,8 @@ func (h *sentPacketHandler) ResetForRetry() error {
 			h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 		}
 	}
-	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop(), h.rttStats)
-	h.appDataPackets = newPacketNumberSpace(h.appDataPackets.pns.Pop(), h.rttStats)
+	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop(), h.rttStats, h.rand)
+	h.appDataPackets = newPacketNumberSpace(h.appDataPackets.pns.Pop(), h.rttStats, h.rand)
 	oldAlarm := h.alarm
 	h.alarm = time.Time{}
 	if h.tracer != nil {
@@ -815,6 +913,41 @@ func (h *sentPacketHandler) SetHandshakeConfirmed() {
 	h.setLossDetectionTimer()
 }
 
+func (h *sentPacketHandler) MigratedPath(validated, resetCongestion bool) {
+	h.peerAddressValidated = validated
+	h.bytesReceived = 0
+	h.bytesSent = 0
+	h.tracedAmplificationLimited = false
+	if resetCongestion {
+		h.congestion = congestion.NewSendAlgorithm(h.congestionFactory, h.rttStats, h.maxSendRate, h.clock, h.tracer)
+		h.bandwidthSampler = congestion.NewBandwidthSampler()
+		h.tracedCongestionWindow = 0
+	}
+}
+
+func (h *sentPacketHandler) SetPathValidated() {
+	h.peerAddressValidated = true
+}
+
+func (h *sentPacketHandler) OnAppLimited() {
+	h.bandwidthSampler.OnAppLimited(h.bytesInFlight)
+}
+
+func (h *sentPacketHandler) BandwidthEstimate() congestion.Bandwidth {
+	if bw := h.bandwidthSampler.BandwidthEstimate(); bw > 0 {
+		return bw
+	}
+	srtt := h.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		return 0
+	}
+	return congestion.BandwidthFromDelta(h.congestion.GetCongestionWindow(), srtt)
+}
+
+func (h *sentPacketHandler) PacketsLost() (uint64, protocol.ByteCount) {
+	return atomic.LoadUint64(&h.numLostPackets), protocol.ByteCount(atomic.LoadUint64(&h.numLostBytes))
+}
+
 func (h *sentPacketHandler) GetStats() *quictrace.TransportState {
 	return &quictrace.TransportState{
 		MinRTT:           h.rttStats.MinRTT(),
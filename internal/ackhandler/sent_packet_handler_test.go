@@ -1,11 +1,14 @@
 package ackhandler
 
 import (
+	"crypto/rand"
 	"fmt"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -27,7 +30,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, rttStats, perspective, nil, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, rttStats, perspective, 0, 0, protocol.DefaultAmplificationFactor, false, nil, rand.Reader, utils.DefaultClock{}, nil, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -442,6 +445,34 @@ var _ = Describe("SentPacketHandler", func() {
 			})
 		})
 
+		It("estimates the bandwidth from the congestion window and the smoothed RTT", func() {
+			Expect(handler.BandwidthEstimate()).To(BeZero())
+			handler.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(10000))
+			Expect(handler.BandwidthEstimate()).To(Equal(100000 * congestion.BytesPerSecond))
+		})
+
+		It("estimates the bandwidth from the delivery rate", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			sendTime := time.Now().Add(-time.Second)
+			for i := 1; i <= 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: protocol.PacketNumber(i), Length: 1000, SendTime: sendTime}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, sendTime.Add(100*time.Millisecond))).To(Succeed())
+			// The congestion window is not used.
+			Expect(handler.BandwidthEstimate()).To(Equal(congestion.BandwidthFromDelta(10000, 100*time.Millisecond)))
+		})
+
+		It("flags samples as application-limited", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			handler.OnAppLimited()
+			Expect(handler.bandwidthSampler.IsAppLimited()).To(BeTrue())
+		})
+
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			rcvTime := time.Now().Add(-5 * time.Second)
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
@@ -457,6 +488,69 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, rcvTime)).To(Succeed())
 		})
 
+		It("traces changes of the congestion window", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+			cwnd := protocol.ByteCount(10000)
+			cong.EXPECT().GetCongestionWindow().DoAndReturn(func() protocol.ByteCount { return cwnd }).AnyTimes()
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3}))
+			tracer.EXPECT().CongestionWindowUpdated(protocol.ByteCount(10000), protocol.ByteCount(2))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			// the congestion window didn't change
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			cwnd = 12000
+			tracer.EXPECT().CongestionWindowUpdated(protocol.ByteCount(12000), protocol.ByteCount(0))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
+		It("treats an increase of the ECN-CE count as a congestion event", func() {
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+			handler.ecnTracker = newECNTracker(nil, utils.DefaultLogger)
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT0))
+			rcvTime := time.Now().Add(-5 * time.Second)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			gomock.InOrder(
+				cong.EXPECT().MaybeExitSlowStart(),
+				cong.EXPECT().OnPacketLost(protocol.PacketNumber(2), protocol.ByteCount(0), protocol.ByteCount(3)),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
+			)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, ECN: protocol.ECT0}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, ECN: protocol.ECT0}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, ECN: protocol.ECT0}))
+			ack := &wire.AckFrame{
+				AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}},
+				ECT0:      1,
+				ECNCE:     1,
+			}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, rcvTime)).To(Succeed())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECT0))
+		})
+
+		It("disables ECN if the peer doesn't report ECN counts", func() {
+			handler.ecnTracker = newECNTracker(nil, utils.DefaultLogger)
+			rcvTime := time.Now().Add(-5 * time.Second)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			cong.EXPECT().MaybeExitSlowStart()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, ECN: protocol.ECT0}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, ECN: protocol.ECT0}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, rcvTime)).To(Succeed())
+			Expect(handler.ECNMode()).To(Equal(protocol.ECNNon))
+		})
+
 		It("doesn't call OnPacketAcked when a retransmitted packet is acked", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
@@ -525,6 +619,38 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendNone))
 		})
 
+		It("traces when it is blocked by the 3x limit", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().GetCongestionWindow().AnyTimes()
+			handler.ReceivedBytes(100)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), true).Times(2)
+			handler.SentPacket(&Packet{
+				Length:          300,
+				EncryptionLevel: protocol.EncryptionInitial,
+				Frames:          []Frame{{Frame: &wire.PingFrame{}}},
+				SendTime:        time.Now(),
+			})
+			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
+			tracer.EXPECT().BlockedByAmplificationLimit()
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			// only trace once, until we receive more bytes
+			Expect(handler.SendMode()).To(Equal(SendNone))
+			handler.ReceivedBytes(100)
+			Expect(handler.SendMode()).To(Equal(SendAny))
+			handler.SentPacket(&Packet{
+				PacketNumber:    1,
+				Length:          300,
+				EncryptionLevel: protocol.EncryptionInitial,
+				Frames:          []Frame{{Frame: &wire.PingFrame{}}},
+				SendTime:        time.Now(),
+			})
+			tracer.EXPECT().BlockedByAmplificationLimit()
+			Expect(handler.SendMode()).To(Equal(SendNone))
+		})
+
 		It("limits the window to 3x the bytes received, to avoid amplification attacks", func() {
 			handler.ReceivedPacket(protocol.EncryptionInitial) // receiving an Initial packet doesn't validate the client's address
 			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(50), gomock.Any(), protocol.ByteCount(50), true)
@@ -538,6 +664,40 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.AmplificationWindow()).To(Equal(protocol.ByteCount(3*100 - 50)))
 		})
 
+		It("uses the configured amplification factor", func() {
+			handler.amplificationFactor = 2
+			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(50), gomock.Any(), protocol.ByteCount(50), true)
+			handler.SentPacket(&Packet{
+				Length:          50,
+				EncryptionLevel: protocol.EncryptionInitial,
+				Frames:          []Frame{{Frame: &wire.PingFrame{}}},
+				SendTime:        time.Now(),
+			})
+			handler.ReceivedBytes(100)
+			Expect(handler.AmplificationWindow()).To(Equal(protocol.ByteCount(2*100 - 50)))
+		})
+
+		It("applies the amplification limit after migrating to an unvalidated path", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			Expect(handler.AmplificationWindow()).To(Equal(protocol.MaxByteCount))
+			handler.MigratedPath(false, false)
+			Expect(handler.congestion).To(Equal(cong))
+			Expect(handler.AmplificationWindow()).To(BeZero())
+			handler.ReceivedBytes(100)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), protocol.ByteCount(50), true)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, Length: 50}))
+			Expect(handler.AmplificationWindow()).To(Equal(protocol.ByteCount(3*100 - 50)))
+			handler.SetPathValidated()
+			Expect(handler.AmplificationWindow()).To(Equal(protocol.MaxByteCount))
+		})
+
+		It("resets the congestion controller when migrating", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			handler.MigratedPath(true, true)
+			Expect(handler.congestion).ToNot(Equal(cong))
+			Expect(handler.AmplificationWindow()).To(Equal(protocol.MaxByteCount))
+		})
+
 		It("allows sending of ACKs when congestion limited", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			cong.EXPECT().CanSend(gomock.Any()).Return(true)
@@ -623,6 +783,27 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(4 * timeout))
 		})
 
+		It("caps the exponential backoff at the maximum PTO", func() {
+			handler.SetHandshakeConfirmed()
+			sendTime := time.Now().Add(-time.Hour)
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: sendTime}))
+			timeout := handler.GetLossDetectionTimeout().Sub(sendTime)
+			handler.maxPTO = 3 * timeout
+			handler.ptoCount = 1
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(2 * timeout))
+			handler.ptoCount = 2
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(3 * timeout))
+			handler.ptoCount = 3
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(3 * timeout))
+			// make sure the cap also applies when the exponential backoff overflows
+			handler.ptoCount = 100
+			handler.setLossDetectionTimer()
+			Expect(handler.GetLossDetectionTimeout().Sub(sendTime)).To(Equal(3 * timeout))
+		})
+
 		It("reset the PTO count when receiving an ACK", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			now := time.Now()
@@ -907,6 +1088,41 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("counts lost packets", func() {
+			num, bytes := handler.PacketsLost()
+			Expect(num).To(BeZero())
+			Expect(bytes).To(BeZero())
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, Length: protocol.ByteCount(i) * 100}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			num, bytes = handler.PacketsLost()
+			Expect(num).To(BeEquivalentTo(3))
+			Expect(bytes).To(Equal(protocol.ByteCount(100 + 200 + 300)))
+		})
+
+		It("traces spurious losses", func() {
+			tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			handler.tracer = tracer
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+			tracer.EXPECT().CongestionWindowUpdated(gomock.Any(), gomock.Any()).AnyTimes()
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			tracer.EXPECT().LostPacket(protocol.Encryption1RTT, gomock.Any(), gomock.Any()).Times(3)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			// packet 2 was only reordered, not lost
+			tracer.EXPECT().SpuriousLoss(protocol.Encryption1RTT, protocol.PacketNumber(2))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}, {Smallest: 2, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
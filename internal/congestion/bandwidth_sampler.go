@@ -0,0 +1,199 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// bandwidthFilterLength is the number of round trips that the maximum bandwidth filter spans.
+const bandwidthFilterLength = 10
+
+// SendState is the state of the bandwidth sampler at the time a packet was sent.
+// It is saved for every ack-eliciting packet sent, and passed back to the sampler when the packet is acknowledged.
+type SendState struct {
+	// the number of bytes delivered when the packet was sent
+	delivered protocol.ByteCount
+	// the time when delivered was last updated
+	deliveredTime time.Time
+	// the send time of the packet that was sent first in the flight the packet belongs to
+	firstSentTime time.Time
+	sentTime      time.Time
+	isAppLimited  bool
+}
+
+// A rateSample is a delivery rate sample, generated from the packets acknowledged by an ACK frame.
+type rateSample struct {
+	priorDelivered protocol.ByteCount
+	sentTime       time.Time
+	sendElapsed    time.Duration
+	ackElapsed     time.Duration
+	isAppLimited   bool
+}
+
+type bandwidthSample struct {
+	bandwidth Bandwidth
+	round     uint64
+}
+
+// The bandwidthSampler implements the delivery rate estimation described in
+// https://tools.ietf.org/html/draft-cheng-iccrg-delivery-rate-estimation-00.
+// The bandwidth estimate is the maximum delivery rate observed over the last bandwidthFilterLength round trips.
+type bandwidthSampler struct {
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	// appLimited is the value of delivered at which the connection stops being application-limited.
+	// It is 0 if the connection is not application-limited.
+	appLimited protocol.ByteCount
+
+	// the sample for the ACK frame currently being processed
+	sample    rateSample
+	hasSample bool
+
+	round              uint64
+	nextRoundDelivered protocol.ByteCount
+	// the best, second best and third best sample in the filter window
+	// see https://github.com/torvalds/linux/blob/master/lib/win_minmax.c
+	estimates [3]bandwidthSample
+}
+
+var _ BandwidthSampler = &bandwidthSampler{}
+
+// NewBandwidthSampler creates a new bandwidth sampler
+func NewBandwidthSampler() *bandwidthSampler {
+	return &bandwidthSampler{}
+}
+
+// OnPacketSent is called when an ack-eliciting packet is sent.
+// bytesInFlight are the bytes in flight before the packet was sent.
+func (s *bandwidthSampler) OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount) SendState {
+	if bytesInFlight == 0 {
+		// Start of a new flight, e.g. after an idle period.
+		s.firstSentTime = sentTime
+		s.deliveredTime = sentTime
+	}
+	return SendState{
+		delivered:     s.delivered,
+		deliveredTime: s.deliveredTime,
+		firstSentTime: s.firstSentTime,
+		sentTime:      sentTime,
+		isAppLimited:  s.appLimited != 0,
+	}
+}
+
+// OnPacketAcked is called for every packet acknowledged by an ACK frame.
+// Once all packets were processed, OnAckProcessed must be called.
+func (s *bandwidthSampler) OnPacketAcked(ackTime time.Time, length protocol.ByteCount, state SendState) {
+	// packets that weren't ack-eliciting don't have a send state
+	if state.sentTime.IsZero() {
+		return
+	}
+	s.delivered += length
+	s.deliveredTime = ackTime
+	// Use the most recently sent packet to generate the rate sample.
+	if !s.hasSample || state.delivered > s.sample.priorDelivered ||
+		(state.delivered == s.sample.priorDelivered && state.sentTime.After(s.sample.sentTime)) {
+		s.hasSample = true
+		s.sample = rateSample{
+			priorDelivered: state.delivered,
+			sentTime:       state.sentTime,
+			sendElapsed:    state.sentTime.Sub(state.firstSentTime),
+			ackElapsed:     ackTime.Sub(state.deliveredTime),
+			isAppLimited:   state.isAppLimited,
+		}
+		s.firstSentTime = state.sentTime
+	}
+}
+
+// OnAckProcessed generates a delivery rate sample from the packets acknowledged by an ACK frame,
+// and updates the bandwidth estimate.
+func (s *bandwidthSampler) OnAckProcessed(minRTT time.Duration) {
+	if !s.hasSample {
+		return
+	}
+	sample := s.sample
+	s.hasSample = false
+	s.sample = rateSample{}
+
+	if s.appLimited != 0 && s.delivered > s.appLimited {
+		s.appLimited = 0
+	}
+	if sample.priorDelivered >= s.nextRoundDelivered {
+		s.round++
+		s.nextRoundDelivered = s.delivered
+	}
+
+	// The sending rate might be higher than the ACK rate, e.g. when the ACKs are compressed,
+	// and vice versa. Use the longer interval to avoid overestimating the bandwidth.
+	interval := sample.sendElapsed
+	if sample.ackElapsed > interval {
+		interval = sample.ackElapsed
+	}
+	// Intervals shorter than the minimum RTT are likely to be caused by ACK compression.
+	if interval <= 0 || interval < minRTT {
+		return
+	}
+	bw := BandwidthFromDelta(s.delivered-sample.priorDelivered, interval)
+	// Application-limited samples underestimate the bandwidth.
+	// They're only taken into account if they exceed the current estimate.
+	if sample.isAppLimited && bw < s.BandwidthEstimate() {
+		return
+	}
+	s.updateFilter(bandwidthSample{bandwidth: bw, round: s.round})
+}
+
+// OnAppLimited is called when the application doesn't have any data to send,
+// although the congestion controller would allow sending.
+// The samples taken until all bytes currently in flight are acknowledged are flagged as application-limited.
+func (s *bandwidthSampler) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	s.appLimited = s.delivered + bytesInFlight
+	if s.appLimited == 0 {
+		s.appLimited = 1
+	}
+}
+
+// IsAppLimited says if the connection is currently application-limited.
+func (s *bandwidthSampler) IsAppLimited() bool {
+	return s.appLimited != 0
+}
+
+// BandwidthEstimate returns the maximum delivery rate observed during the last round trips.
+// It returns 0 if no valid sample has been obtained yet.
+func (s *bandwidthSampler) BandwidthEstimate() Bandwidth {
+	return s.estimates[0].bandwidth
+}
+
+func (s *bandwidthSampler) updateFilter(sample bandwidthSample) {
+	if s.estimates[0].bandwidth == 0 || sample.bandwidth >= s.estimates[0].bandwidth ||
+		sample.round-s.estimates[2].round > bandwidthFilterLength {
+		s.estimates = [3]bandwidthSample{sample, sample, sample}
+		return
+	}
+	if sample.bandwidth >= s.estimates[1].bandwidth {
+		s.estimates[1] = sample
+		s.estimates[2] = sample
+	} else if sample.bandwidth >= s.estimates[2].bandwidth {
+		s.estimates[2] = sample
+	}
+	// Expire the best estimate if it was sampled before the filter window.
+	if sample.round-s.estimates[0].round > bandwidthFilterLength {
+		s.estimates[0] = s.estimates[1]
+		s.estimates[1] = s.estimates[2]
+		s.estimates[2] = sample
+		if sample.round-s.estimates[0].round > bandwidthFilterLength {
+			s.estimates[0] = s.estimates[1]
+			s.estimates[1] = s.estimates[2]
+		}
+		return
+	}
+	// If the second best estimate is (almost) as old as the best, make sure to keep a more recent sample.
+	if s.estimates[1].bandwidth == s.estimates[0].bandwidth && sample.round-s.estimates[1].round > bandwidthFilterLength/4 {
+		s.estimates[1] = sample
+		s.estimates[2] = sample
+		return
+	}
+	if s.estimates[2].bandwidth == s.estimates[1].bandwidth && sample.round-s.estimates[2].round > bandwidthFilterLength/2 {
+		s.estimates[2] = sample
+	}
+}
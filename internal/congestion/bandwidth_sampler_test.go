@@ -0,0 +1,150 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Sampler", func() {
+	const packetSize protocol.ByteCount = 1000
+
+	var (
+		sampler *bandwidthSampler
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		sampler = NewBandwidthSampler()
+		now = time.Now()
+	})
+
+	// sendFlight sends a flight of n packets at once, and acknowledges it after rtt
+	sendFlight := func(n int, rtt time.Duration) {
+		states := make([]SendState, 0, n)
+		for i := 0; i < n; i++ {
+			states = append(states, sampler.OnPacketSent(now, protocol.ByteCount(i)*packetSize))
+		}
+		now = now.Add(rtt)
+		for _, state := range states {
+			sampler.OnPacketAcked(now, packetSize, state)
+		}
+		sampler.OnAckProcessed(rtt)
+	}
+
+	It("doesn't have an estimate before receiving any ACKs", func() {
+		Expect(sampler.BandwidthEstimate()).To(BeZero())
+		sampler.OnPacketSent(now, 0)
+		Expect(sampler.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("calculates the delivery rate", func() {
+		states := make([]SendState, 10)
+		for i := range states {
+			states[i] = sampler.OnPacketSent(now.Add(time.Duration(i)*time.Millisecond), protocol.ByteCount(i)*packetSize)
+		}
+		ackTime := now.Add(100 * time.Millisecond)
+		for _, state := range states {
+			sampler.OnPacketAcked(ackTime, packetSize, state)
+		}
+		sampler.OnAckProcessed(50 * time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(10*packetSize, 100*time.Millisecond)))
+	})
+
+	It("uses the send interval, if it is longer than the ACK interval", func() {
+		first := sampler.OnPacketSent(now, 0)
+		second := sampler.OnPacketSent(now.Add(80*time.Millisecond), packetSize)
+		sampler.OnPacketAcked(now.Add(50*time.Millisecond), packetSize, first)
+		sampler.OnAckProcessed(10 * time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(packetSize, 50*time.Millisecond)))
+		// The second packet was sent 80ms after the first one, but it is acknowledged 60ms after the first one was sent.
+		sampler.OnPacketAcked(now.Add(60*time.Millisecond), packetSize, second)
+		sampler.OnAckProcessed(10 * time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(2*packetSize, 80*time.Millisecond)))
+	})
+
+	It("ignores packets that don't have a send state", func() {
+		state := sampler.OnPacketSent(now, 0)
+		sampler.OnPacketAcked(now.Add(time.Millisecond), packetSize, SendState{})
+		sampler.OnAckProcessed(0)
+		Expect(sampler.BandwidthEstimate()).To(BeZero())
+		sampler.OnPacketAcked(now.Add(10*time.Millisecond), packetSize, state)
+		sampler.OnAckProcessed(0)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(packetSize, 10*time.Millisecond)))
+	})
+
+	It("discards samples with an interval shorter than the min RTT", func() {
+		state := sampler.OnPacketSent(now, 0)
+		sampler.OnPacketAcked(now.Add(10*time.Millisecond), packetSize, state)
+		sampler.OnAckProcessed(20 * time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(BeZero())
+	})
+
+	It("keeps the maximum delivery rate", func() {
+		sendFlight(10, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(10*packetSize, 100*time.Millisecond)))
+		sendFlight(5, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(10*packetSize, 100*time.Millisecond)))
+		sendFlight(20, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(20*packetSize, 100*time.Millisecond)))
+	})
+
+	It("expires the maximum after the filter window", func() {
+		sendFlight(20, 100*time.Millisecond)
+		for i := 0; i < bandwidthFilterLength; i++ {
+			sendFlight(10, 100*time.Millisecond)
+			Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(20*packetSize, 100*time.Millisecond)))
+		}
+		sendFlight(10, 100*time.Millisecond)
+		Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(10*packetSize, 100*time.Millisecond)))
+	})
+
+	Context("application-limited", func() {
+		It("flags packets sent while application-limited", func() {
+			Expect(sampler.IsAppLimited()).To(BeFalse())
+			sampler.OnAppLimited(0)
+			Expect(sampler.IsAppLimited()).To(BeTrue())
+			Expect(sampler.OnPacketSent(now, 0).isAppLimited).To(BeTrue())
+		})
+
+		It("stops being application-limited once the bytes in flight are acknowledged", func() {
+			first := sampler.OnPacketSent(now, 0)
+			second := sampler.OnPacketSent(now, packetSize)
+			sampler.OnAppLimited(2 * packetSize)
+			sampler.OnPacketAcked(now.Add(10*time.Millisecond), packetSize, first)
+			sampler.OnAckProcessed(0)
+			Expect(sampler.IsAppLimited()).To(BeTrue())
+			sampler.OnPacketAcked(now.Add(20*time.Millisecond), packetSize, second)
+			sampler.OnAckProcessed(0)
+			Expect(sampler.IsAppLimited()).To(BeTrue())
+			third := sampler.OnPacketSent(now.Add(20*time.Millisecond), 0)
+			sampler.OnPacketAcked(now.Add(30*time.Millisecond), packetSize, third)
+			sampler.OnAckProcessed(0)
+			Expect(sampler.IsAppLimited()).To(BeFalse())
+		})
+
+		It("excludes application-limited samples that are lower than the estimate", func() {
+			sendFlight(10, 100*time.Millisecond)
+			estimate := sampler.BandwidthEstimate()
+			sampler.OnAppLimited(0)
+			sendFlight(2, 100*time.Millisecond)
+			Expect(sampler.BandwidthEstimate()).To(Equal(estimate))
+			// Application-limited samples don't expire the maximum.
+			for i := 0; i < 2*bandwidthFilterLength; i++ {
+				sampler.OnAppLimited(0)
+				sendFlight(2, 100*time.Millisecond)
+			}
+			Expect(sampler.BandwidthEstimate()).To(Equal(estimate))
+		})
+
+		It("uses application-limited samples that exceed the estimate", func() {
+			sendFlight(10, 100*time.Millisecond)
+			sampler.OnAppLimited(0)
+			sendFlight(20, 100*time.Millisecond)
+			Expect(sampler.BandwidthEstimate()).To(Equal(BandwidthFromDelta(20*packetSize, 100*time.Millisecond)))
+		})
+	})
+})
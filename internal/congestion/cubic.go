@@ -33,7 +33,7 @@ const betaLastMax float32 = 0.85
 
 // Cubic implements the cubic algorithm from TCP
 type Cubic struct {
-	clock Clock
+	clock utils.Clock
 
 	// Number of connections to simulate.
 	numConnections int
@@ -63,7 +63,7 @@ type Cubic struct {
 }
 
 // NewCubic returns a new Cubic instance
-func NewCubic(clock Clock) *Cubic {
+func NewCubic(clock utils.Clock) *Cubic {
 	c := &Cubic{
 		clock:          clock,
 		numConnections: defaultNumConnections,
@@ -24,7 +24,7 @@ type cubicSender struct {
 	rttStats        *utils.RTTStats
 	cubic           *Cubic
 	pacer           *pacer
-	clock           Clock
+	clock           utils.Clock
 
 	reno bool
 
@@ -67,11 +67,12 @@ var _ SendAlgorithm = &cubicSender{}
 var _ SendAlgorithmWithDebugInfos = &cubicSender{}
 
 // NewCubicSender makes a new cubic sender
-func NewCubicSender(clock Clock, rttStats *utils.RTTStats, reno bool, tracer logging.ConnectionTracer) *cubicSender {
-	return newCubicSender(clock, rttStats, reno, initialCongestionWindow, maxCongestionWindow, tracer)
+// If maxSendRate (in bytes/s) is non-zero, the pacing rate is capped at this value.
+func NewCubicSender(clock utils.Clock, rttStats *utils.RTTStats, reno bool, maxSendRate protocol.ByteCount, tracer logging.ConnectionTracer) *cubicSender {
+	return newCubicSender(clock, rttStats, reno, initialCongestionWindow, maxCongestionWindow, maxSendRate, tracer)
 }
 
-func newCubicSender(clock Clock, rttStats *utils.RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow protocol.ByteCount, tracer logging.ConnectionTracer) *cubicSender {
+func newCubicSender(clock utils.Clock, rttStats *utils.RTTStats, reno bool, initialCongestionWindow, initialMaxCongestionWindow, maxSendRate protocol.ByteCount, tracer logging.ConnectionTracer) *cubicSender {
 	c := &cubicSender{
 		rttStats:                   rttStats,
 		largestSentPacketNumber:    protocol.InvalidPacketNumber,
@@ -88,7 +89,7 @@ func newCubicSender(clock Clock, rttStats *utils.RTTStats, reno bool, initialCon
 		reno:                       reno,
 		tracer:                     tracer,
 	}
-	c.pacer = newPacer(c.BandwidthEstimate)
+	c.pacer = newPacer(c.BandwidthEstimate, maxSendRate)
 	if c.tracer != nil {
 		c.lastState = logging.CongestionStateSlowStart
 		c.tracer.UpdatedCongestionState(logging.CongestionStateSlowStart)
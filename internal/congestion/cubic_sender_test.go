@@ -12,22 +12,12 @@ import (
 const initialCongestionWindowPackets = 10
 const defaultWindowTCP = protocol.ByteCount(initialCongestionWindowPackets) * maxDatagramSize
 
-type mockClock time.Time
-
-func (c *mockClock) Now() time.Time {
-	return time.Time(*c)
-}
-
-func (c *mockClock) Advance(d time.Duration) {
-	*c = mockClock(time.Time(*c).Add(d))
-}
-
 const MaxCongestionWindow protocol.ByteCount = 200 * maxDatagramSize
 
 var _ = Describe("Cubic Sender", func() {
 	var (
 		sender            *cubicSender
-		clock             mockClock
+		clock             *utils.ManualClock
 		bytesInFlight     protocol.ByteCount
 		packetNumber      protocol.PacketNumber
 		ackedPacketNumber protocol.PacketNumber
@@ -38,9 +28,9 @@ var _ = Describe("Cubic Sender", func() {
 		bytesInFlight = 0
 		packetNumber = 1
 		ackedPacketNumber = 0
-		clock = mockClock{}
+		clock = utils.NewManualClock(time.Time{})
 		rttStats = utils.NewRTTStats()
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0, nil)
 	})
 
 	SendAvailableSendWindowLen := func(packetLength protocol.ByteCount) int {
@@ -308,7 +298,7 @@ var _ = Describe("Cubic Sender", func() {
 	It("tcp cubic reset epoch on quiescence", func() {
 		const maxCongestionWindow = 50
 		const maxCongestionWindowBytes = maxCongestionWindow * maxDatagramSize
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, nil)
+		sender = newCubicSender(clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindowBytes, 0, nil)
 
 		numSent := SendAvailableSendWindow()
 
@@ -448,7 +438,7 @@ var _ = Describe("Cubic Sender", func() {
 	})
 
 	It("default max cwnd", func() {
-		sender = newCubicSender(&clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow, nil)
+		sender = newCubicSender(clock, rttStats, true /*reno*/, initialCongestionWindowPackets*maxDatagramSize, maxCongestionWindow, 0, nil)
 
 		defaultMaxCongestionWindowPackets := maxCongestionWindow / maxDatagramSize
 		for i := 1; i < int(defaultMaxCongestionWindowPackets); i++ {
@@ -460,7 +450,7 @@ var _ = Describe("Cubic Sender", func() {
 
 	It("limit cwnd increase in congestion avoidance", func() {
 		// Enable Cubic.
-		sender = newCubicSender(&clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		sender = newCubicSender(clock, rttStats, false, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, 0, nil)
 		numSent := SendAvailableSendWindow()
 
 		// Make sure we fall out of slow start.
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -17,13 +19,13 @@ const maxCubicTimeInterval = 30 * time.Millisecond
 
 var _ = Describe("Cubic", func() {
 	var (
-		clock mockClock
+		clock *utils.ManualClock
 		cubic *Cubic
 	)
 
 	BeforeEach(func() {
-		clock = mockClock{}
-		cubic = NewCubic(&clock)
+		clock = utils.NewManualClock(time.Time{})
+		cubic = NewCubic(clock)
 		cubic.SetNumConnections(int(numConnections))
 	})
 
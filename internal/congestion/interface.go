@@ -3,25 +3,22 @@ package congestion
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // A SendAlgorithm performs congestion control
-type SendAlgorithm interface {
-	TimeUntilSend(bytesInFlight protocol.ByteCount) time.Time
-	HasPacingBudget() bool
-	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount, packetNumber protocol.PacketNumber, bytes protocol.ByteCount, isRetransmittable bool)
-	CanSend(bytesInFlight protocol.ByteCount) bool
-	MaybeExitSlowStart()
-	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
-	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
-	OnRetransmissionTimeout(packetsRetransmitted bool)
-}
+type SendAlgorithm = congestion.SendAlgorithm
 
 // A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos
-type SendAlgorithmWithDebugInfos interface {
-	SendAlgorithm
-	InSlowStart() bool
-	InRecovery() bool
-	GetCongestionWindow() protocol.ByteCount
+type SendAlgorithmWithDebugInfos = congestion.SendAlgorithmWithDebugInfos
+
+// A BandwidthSampler estimates the bandwidth from the delivery rate of packets
+type BandwidthSampler interface {
+	OnPacketSent(sentTime time.Time, bytesInFlight protocol.ByteCount) SendState
+	OnPacketAcked(ackTime time.Time, length protocol.ByteCount, state SendState)
+	OnAckProcessed(minRTT time.Duration)
+	OnAppLimited(bytesInFlight protocol.ByteCount)
+	IsAppLimited() bool
+	BandwidthEstimate() Bandwidth
 }
@@ -17,7 +17,10 @@ type pacer struct {
 	getAdjustedBandwidth func() uint64 // in bytes/s
 }
 
-func newPacer(getBandwidth func() Bandwidth) *pacer {
+// newPacer creates a new pacer.
+// If maxSendRate (in bytes/s) is non-zero, the pacing rate never exceeds this value.
+// The initial burst (and bursts after idle periods) are still allowed.
+func newPacer(getBandwidth func() Bandwidth, maxSendRate protocol.ByteCount) *pacer {
 	p := &pacer{getAdjustedBandwidth: func() uint64 {
 		// Bandwidth is in bits/s. We need the value in bytes/s.
 		bw := uint64(getBandwidth() / BytesPerSecond)
@@ -25,7 +28,11 @@ func newPacer(getBandwidth func() Bandwidth) *pacer {
 		// RTT variations then won't result in under-utilization of the congestion window.
 		// Ultimately, this will  result in sending packets as acknowledgments are received rather than when timers fire,
 		// provided the congestion window is fully utilized and acknowledgments arrive at regular intervals.
-		return bw * 5 / 4
+		bw = bw * 5 / 4
+		if maxSendRate > 0 && bw > uint64(maxSendRate) {
+			return uint64(maxSendRate)
+		}
+		return bw
 	}}
 	p.budgetAtLastSent = p.maxBurstSize()
 	return p
@@ -19,7 +19,7 @@ var _ = Describe("Pacer", func() {
 		bandwidth = uint64(packetsPerSecond * maxDatagramSize) // 50 full-size packets per second
 		// The pacer will multiply the bandwidth with 1.25 to achieve a slightly higher pacing speed.
 		// For the tests, cancel out this factor, so we can do the math using the exact bandwidth.
-		p = newPacer(func() Bandwidth { return Bandwidth(bandwidth) * BytesPerSecond * 4 / 5 })
+		p = newPacer(func() Bandwidth { return Bandwidth(bandwidth) * BytesPerSecond * 4 / 5 }, 0)
 	})
 
 	It("allows a burst at the beginning", func() {
@@ -109,4 +109,36 @@ var _ = Describe("Pacer", func() {
 		Expect(p.TimeUntilSend()).To(Equal(t.Add(protocol.MinPacingDelay)))
 		Expect(p.Budget(t.Add(protocol.MinPacingDelay))).To(Equal(protocol.ByteCount(protocol.MinPacingDelay) * maxDatagramSize * 1e6 / 1e9))
 	})
+
+	Context("with a maximum send rate", func() {
+		const maxSendRate = packetsPerSecond * maxDatagramSize / 5 // 10 full-size packets per second
+
+		BeforeEach(func() {
+			p = newPacer(func() Bandwidth { return Bandwidth(bandwidth) * BytesPerSecond * 4 / 5 }, maxSendRate)
+		})
+
+		It("still allows a burst at the beginning", func() {
+			t := time.Now()
+			Expect(p.TimeUntilSend()).To(BeZero())
+			Expect(p.Budget(t)).To(BeEquivalentTo(maxBurstSize))
+		})
+
+		It("paces packets at the maximum send rate", func() {
+			t := time.Now()
+			sendBurst(t)
+			for i := 0; i < 10; i++ {
+				t2 := p.TimeUntilSend()
+				Expect(t2.Sub(t)).To(BeNumerically("~", time.Second/10, time.Nanosecond))
+				p.SentPacket(t2, maxDatagramSize)
+				t = t2
+			}
+		})
+
+		It("doesn't limit the rate if the bandwidth is lower", func() {
+			t := time.Now()
+			sendBurst(t)
+			bandwidth = uint64(5 * maxDatagramSize) // reduce the bandwidth to 5 packet per second
+			Expect(p.TimeUntilSend()).To(Equal(t.Add(time.Second / 5)))
+		})
+	})
 })
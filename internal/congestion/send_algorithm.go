@@ -0,0 +1,38 @@
+package congestion
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// NewSendAlgorithm creates the congestion controller for a connection.
+// If factory is nil, a Cubic sender (in Reno mode) is used, and the pacing rate is capped at maxSendRate.
+// The Cubic sender uses the clock for its time-based logic.
+func NewSendAlgorithm(
+	factory func(rttStats *utils.RTTStats, initialCongestionWindow, maxCongestionWindow protocol.ByteCount) SendAlgorithm,
+	rttStats *utils.RTTStats,
+	maxSendRate protocol.ByteCount,
+	clock utils.Clock,
+	tracer logging.ConnectionTracer,
+) SendAlgorithmWithDebugInfos {
+	if factory == nil {
+		return NewCubicSender(clock, rttStats, true, maxSendRate, tracer)
+	}
+	s := factory(rttStats, initialCongestionWindow, maxCongestionWindow)
+	if sd, ok := s.(SendAlgorithmWithDebugInfos); ok {
+		return sd
+	}
+	return &sendAlgorithmWithoutDebugInfos{SendAlgorithm: s}
+}
+
+// sendAlgorithmWithoutDebugInfos wraps a SendAlgorithm that doesn't expose any debug infos.
+type sendAlgorithmWithoutDebugInfos struct {
+	SendAlgorithm
+}
+
+var _ SendAlgorithmWithDebugInfos = &sendAlgorithmWithoutDebugInfos{}
+
+func (s *sendAlgorithmWithoutDebugInfos) InSlowStart() bool                       { return false }
+func (s *sendAlgorithmWithoutDebugInfos) InRecovery() bool                        { return false }
+func (s *sendAlgorithmWithoutDebugInfos) GetCongestionWindow() protocol.ByteCount { return 0 }
@@ -0,0 +1,59 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type customSendAlgorithm struct {
+	SendAlgorithm // embedded, so we don't have to implement all methods
+
+	packetsSent int
+}
+
+func (a *customSendAlgorithm) OnPacketSent(time.Time, protocol.ByteCount, protocol.PacketNumber, protocol.ByteCount, bool) {
+	a.packetsSent++
+}
+
+type customSendAlgorithmWithDebugInfos struct {
+	SendAlgorithmWithDebugInfos
+}
+
+func (customSendAlgorithmWithDebugInfos) GetCongestionWindow() protocol.ByteCount { return 1337 }
+
+var _ = Describe("Send Algorithm", func() {
+	It("uses a Cubic sender by default", func() {
+		s := NewSendAlgorithm(nil, &utils.RTTStats{}, 0, utils.DefaultClock{}, nil)
+		Expect(s).To(BeAssignableToTypeOf(&cubicSender{}))
+		Expect(s.GetCongestionWindow()).To(Equal(initialCongestionWindow))
+	})
+
+	It("uses the factory", func() {
+		rttStats := &utils.RTTStats{}
+		custom := &customSendAlgorithm{}
+		s := NewSendAlgorithm(func(r *utils.RTTStats, initialCWND, maxCWND protocol.ByteCount) SendAlgorithm {
+			Expect(r).To(Equal(rttStats))
+			Expect(initialCWND).To(Equal(initialCongestionWindow))
+			Expect(maxCWND).To(Equal(maxCongestionWindow))
+			return custom
+		}, rttStats, 0, utils.DefaultClock{}, nil)
+		s.OnPacketSent(time.Now(), 0, 1, 1000, true)
+		Expect(custom.packetsSent).To(Equal(1))
+		Expect(s.InSlowStart()).To(BeFalse())
+		Expect(s.InRecovery()).To(BeFalse())
+		Expect(s.GetCongestionWindow()).To(BeZero())
+	})
+
+	It("uses the debug infos, if the returned SendAlgorithm provides them", func() {
+		s := NewSendAlgorithm(func(*utils.RTTStats, protocol.ByteCount, protocol.ByteCount) SendAlgorithm {
+			return &customSendAlgorithmWithDebugInfos{}
+		}, &utils.RTTStats{}, 0, utils.DefaultClock{}, nil)
+		Expect(s).To(BeAssignableToTypeOf(&customSendAlgorithmWithDebugInfos{}))
+		Expect(s.GetCongestionWindow()).To(Equal(protocol.ByteCount(1337)))
+	})
+})
@@ -12,6 +12,11 @@ import (
 type connectionFlowController struct {
 	baseFlowController
 
+	// maxReceiveBuffer is the maximum number of bytes used for buffering received stream data.
+	// It is zero if there is no limit.
+	maxReceiveBuffer protocol.ByteCount
+	bufferedBytes    protocol.ByteCount
+
 	queueWindowUpdate func()
 }
 
@@ -19,9 +24,11 @@ var _ ConnectionFlowController = &connectionFlowController{}
 
 // NewConnectionFlowController gets a new flow controller for the connection
 // It is created before we receive the peer's transport paramenters, thus it starts with a sendWindow of 0.
+// If maxReceiveBuffer is non-zero, it limits the memory used for buffering received stream data across all streams.
 func NewConnectionFlowController(
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
+	maxReceiveBuffer protocol.ByteCount,
 	queueWindowUpdate func(),
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
@@ -34,6 +41,7 @@ func NewConnectionFlowController(
 			maxReceiveWindowSize: maxReceiveWindow,
 			logger:               logger,
 		},
+		maxReceiveBuffer:  maxReceiveBuffer,
 		queueWindowUpdate: queueWindowUpdate,
 	}
 }
@@ -54,6 +62,25 @@ func (c *connectionFlowController) IncrementHighestReceived(increment protocol.B
 	return nil
 }
 
+// IncrementBufferedBytes adds an increment to the number of bytes used for buffering received stream data
+func (c *connectionFlowController) IncrementBufferedBytes(increment protocol.ByteCount) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.bufferedBytes += increment
+	if c.maxReceiveBuffer > 0 && c.bufferedBytes > c.maxReceiveBuffer {
+		return qerr.NewError(qerr.FlowControlError, fmt.Sprintf("Buffering %d bytes of received stream data, allowed %d bytes", c.bufferedBytes, c.maxReceiveBuffer))
+	}
+	return nil
+}
+
+// DecrementBufferedBytes subtracts a decrement from the number of bytes used for buffering received stream data
+func (c *connectionFlowController) DecrementBufferedBytes(decrement protocol.ByteCount) {
+	c.mutex.Lock()
+	c.bufferedBytes -= decrement
+	c.mutex.Unlock()
+}
+
 func (c *connectionFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.baseFlowController.AddBytesRead(n)
 	c.maybeQueueWindowUpdate()
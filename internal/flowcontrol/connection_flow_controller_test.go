@@ -36,7 +36,7 @@ var _ = Describe("Connection Flow controller", func() {
 			receiveWindow := protocol.ByteCount(2000)
 			maxReceiveWindow := protocol.ByteCount(3000)
 
-			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
+			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, 0, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
 		})
@@ -49,6 +49,27 @@ var _ = Describe("Connection Flow controller", func() {
 			Expect(controller.highestReceived).To(Equal(protocol.ByteCount(1337 + 123)))
 		})
 
+		Context("limiting the receive buffer", func() {
+			It("doesn't limit the number of buffered bytes by default", func() {
+				Expect(controller.IncrementBufferedBytes(1 << 40)).To(Succeed())
+			})
+
+			It("errors when too many bytes are buffered", func() {
+				controller.maxReceiveBuffer = 1000
+				Expect(controller.IncrementBufferedBytes(600)).To(Succeed())
+				Expect(controller.IncrementBufferedBytes(400)).To(Succeed())
+				Expect(controller.IncrementBufferedBytes(1)).To(MatchError("FLOW_CONTROL_ERROR: Buffering 1001 bytes of received stream data, allowed 1000 bytes"))
+			})
+
+			It("allows buffering more bytes after buffered bytes were released", func() {
+				controller.maxReceiveBuffer = 1000
+				Expect(controller.IncrementBufferedBytes(1000)).To(Succeed())
+				controller.DecrementBufferedBytes(300)
+				Expect(controller.IncrementBufferedBytes(300)).To(Succeed())
+				Expect(controller.bufferedBytes).To(Equal(protocol.ByteCount(1000)))
+			})
+		})
+
 		Context("getting window updates", func() {
 			BeforeEach(func() {
 				controller.receiveWindow = 100
@@ -24,6 +24,9 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// UpdateBufferedBytes should be called when the amount of memory used
+	// for buffering received data that wasn't read yet changes.
+	UpdateBufferedBytes(protocol.ByteCount) error
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
@@ -38,4 +41,6 @@ type connectionFlowControllerI interface {
 	EnsureMinimumWindowSize(protocol.ByteCount)
 	// for receiving
 	IncrementHighestReceived(protocol.ByteCount) error
+	IncrementBufferedBytes(protocol.ByteCount) error
+	DecrementBufferedBytes(protocol.ByteCount)
 }
@@ -14,26 +14,37 @@ type streamFlowController struct {
 	streamID protocol.StreamID
 
 	queueWindowUpdate func()
+	// onSendWindowUpdate is called (if set) when the send window is increased
+	onSendWindowUpdate func(protocol.ByteCount)
 
 	connection connectionFlowControllerI
+	// the number of bytes used for buffering received data, as reported by UpdateBufferedBytes
+	bufferedBytes protocol.ByteCount
 
 	receivedFinalOffset bool
+	// set if the receive window is larger than the window advertised in the transport parameters,
+	// until the window update announcing the larger window has been sent
+	initialWindowUpdate bool
 }
 
 var _ StreamFlowController = &streamFlowController{}
 
-// NewStreamFlowController gets a new flow controller for a stream
+// NewStreamFlowController gets a new flow controller for a stream.
+// initialReceiveWindow is the window advertised in the transport parameters.
+// If receiveWindow is larger, a window update is queued right away.
 func NewStreamFlowController(
 	streamID protocol.StreamID,
 	cfc ConnectionFlowController,
+	initialReceiveWindow protocol.ByteCount,
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
 	initialSendWindow protocol.ByteCount,
 	queueWindowUpdate func(protocol.StreamID),
+	onSendWindowUpdate func(protocol.StreamID, protocol.ByteCount),
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) StreamFlowController {
-	return &streamFlowController{
+	c := &streamFlowController{
 		streamID:          streamID,
 		connection:        cfc.(connectionFlowControllerI),
 		queueWindowUpdate: func() { queueWindowUpdate(streamID) },
@@ -41,11 +52,21 @@ func NewStreamFlowController(
 			rttStats:             rttStats,
 			receiveWindow:        receiveWindow,
 			receiveWindowSize:    receiveWindow,
-			maxReceiveWindowSize: maxReceiveWindow,
+			maxReceiveWindowSize: utils.MaxByteCount(receiveWindow, maxReceiveWindow),
 			sendWindow:           initialSendWindow,
 			logger:               logger,
 		},
 	}
+	if onSendWindowUpdate != nil {
+		c.onSendWindowUpdate = func(offset protocol.ByteCount) { onSendWindowUpdate(streamID, offset) }
+	}
+	if receiveWindow > initialReceiveWindow {
+		c.receiveWindow = initialReceiveWindow
+		c.initialWindowUpdate = true
+		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(receiveWindow) * protocol.ConnectionFlowControlMultiplier))
+		c.queueWindowUpdate()
+	}
+	return c
 }
 
 // UpdateHighestReceived updates the highestReceived value, if the offset is higher.
@@ -98,6 +119,29 @@ func (c *streamFlowController) Abandon() {
 	if unread := c.highestReceived - c.bytesRead; unread > 0 {
 		c.connection.AddBytesRead(unread)
 	}
+	c.mutex.Lock()
+	buffered := c.bufferedBytes
+	c.bufferedBytes = 0
+	c.mutex.Unlock()
+	if buffered > 0 {
+		c.connection.DecrementBufferedBytes(buffered)
+	}
+}
+
+func (c *streamFlowController) UpdateBufferedBytes(n protocol.ByteCount) error {
+	c.mutex.Lock()
+	old := c.bufferedBytes
+	c.bufferedBytes = n
+	c.mutex.Unlock()
+
+	if n < old {
+		c.connection.DecrementBufferedBytes(old - n)
+		return nil
+	}
+	if n > old {
+		return c.connection.IncrementBufferedBytes(n - old)
+	}
+	return nil
 }
 
 func (c *streamFlowController) AddBytesSent(n protocol.ByteCount) {
@@ -105,6 +149,18 @@ func (c *streamFlowController) AddBytesSent(n protocol.ByteCount) {
 	c.connection.AddBytesSent(n)
 }
 
+// UpdateSendWindow should be called after receiving a MAX_STREAM_DATA frame.
+// If the send window is increased, the onSendWindowUpdate callback is invoked.
+func (c *streamFlowController) UpdateSendWindow(offset protocol.ByteCount) {
+	if offset <= c.sendWindow {
+		return
+	}
+	c.baseFlowController.UpdateSendWindow(offset)
+	if c.onSendWindowUpdate != nil {
+		c.onSendWindowUpdate(offset)
+	}
+}
+
 func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return utils.MinByteCount(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize())
 }
@@ -127,6 +183,14 @@ func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 		return 0
 	}
 
+	if c.initialWindowUpdate {
+		c.initialWindowUpdate = false
+		c.receiveWindow = c.bytesRead + c.receiveWindowSize
+		offset := c.receiveWindow
+		c.mutex.Unlock()
+		return offset
+	}
+
 	oldWindowSize := c.receiveWindowSize
 	offset := c.baseFlowController.getWindowUpdate()
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
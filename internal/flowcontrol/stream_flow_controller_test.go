@@ -20,7 +20,7 @@ var _ = Describe("Stream Flow controller", func() {
 		rttStats := &utils.RTTStats{}
 		controller = &streamFlowController{
 			streamID:   10,
-			connection: NewConnectionFlowController(1000, 1000, func() {}, rttStats, utils.DefaultLogger).(*connectionFlowController),
+			connection: NewConnectionFlowController(1000, 1000, 0, func() {}, rttStats, utils.DefaultLogger).(*connectionFlowController),
 		}
 		controller.maxReceiveWindowSize = 10000
 		controller.rttStats = rttStats
@@ -35,8 +35,8 @@ var _ = Describe("Stream Flow controller", func() {
 		sendWindow := protocol.ByteCount(4000)
 
 		It("sets the send and receive windows", func() {
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, 0, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, receiveWindow, maxReceiveWindow, sendWindow, nil, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
@@ -50,11 +50,52 @@ var _ = Describe("Stream Flow controller", func() {
 				queued = true
 			}
 
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, 0, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			fc.AddBytesRead(receiveWindow)
 			Expect(queued).To(BeTrue())
 		})
+
+		It("queues a window update if the receive window is larger than the initial window", func() {
+			var queued bool
+			queueWindowUpdate := func(id protocol.StreamID) {
+				Expect(id).To(Equal(protocol.StreamID(5)))
+				queued = true
+			}
+
+			cc := NewConnectionFlowController(1000, 10000, 0, nil, rttStats, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, receiveWindow+100, maxReceiveWindow, sendWindow, queueWindowUpdate, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			Expect(queued).To(BeTrue())
+			Expect(fc.receiveWindow).To(Equal(receiveWindow))
+			Expect(fc.receiveWindowSize).To(Equal(receiveWindow + 100))
+			// the connection-level window is increased accordingly
+			Expect(cc.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(float64(receiveWindow+100) * protocol.ConnectionFlowControlMultiplier)))
+			Expect(fc.GetWindowUpdate()).To(Equal(receiveWindow + 100))
+			Expect(fc.receiveWindow).To(Equal(receiveWindow + 100))
+			Expect(fc.GetWindowUpdate()).To(BeZero())
+			// subsequent window updates work as usual
+			fc.AddBytesRead(receiveWindow)
+			Expect(fc.GetWindowUpdate()).To(Equal(receiveWindow + receiveWindow + 100))
+		})
+
+		It("uses the receive window as the maximum receive window, if it is larger", func() {
+			cc := NewConnectionFlowController(1000, 10000, 0, nil, rttStats, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, 2*maxReceiveWindow, maxReceiveWindow, sendWindow, func(protocol.StreamID) {}, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			Expect(fc.maxReceiveWindowSize).To(Equal(2 * maxReceiveWindow))
+		})
+
+		It("reports send window updates with the correct stream ID", func() {
+			var updated []protocol.ByteCount
+			onSendWindowUpdate := func(id protocol.StreamID, offset protocol.ByteCount) {
+				Expect(id).To(Equal(protocol.StreamID(5)))
+				updated = append(updated, offset)
+			}
+
+			cc := NewConnectionFlowController(0, 0, 0, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, receiveWindow, maxReceiveWindow, sendWindow, nil, onSendWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			fc.UpdateSendWindow(sendWindow + 100)
+			Expect(updated).To(Equal([]protocol.ByteCount{sendWindow + 100}))
+		})
 	})
 
 	Context("receiving data", func() {
@@ -140,6 +181,28 @@ var _ = Describe("Stream Flow controller", func() {
 			})
 		})
 
+		Context("buffered bytes", func() {
+			It("reports changes of the buffered bytes to the connection flow controller", func() {
+				Expect(controller.UpdateBufferedBytes(100)).To(Succeed())
+				Expect(controller.connection.(*connectionFlowController).bufferedBytes).To(Equal(protocol.ByteCount(100)))
+				Expect(controller.UpdateBufferedBytes(150)).To(Succeed())
+				Expect(controller.connection.(*connectionFlowController).bufferedBytes).To(Equal(protocol.ByteCount(150)))
+				Expect(controller.UpdateBufferedBytes(20)).To(Succeed())
+				Expect(controller.connection.(*connectionFlowController).bufferedBytes).To(Equal(protocol.ByteCount(20)))
+			})
+
+			It("errors when the connection flow controller's limit is exceeded", func() {
+				controller.connection.(*connectionFlowController).maxReceiveBuffer = 100
+				Expect(controller.UpdateBufferedBytes(101)).To(MatchError("FLOW_CONTROL_ERROR: Buffering 101 bytes of received stream data, allowed 100 bytes"))
+			})
+
+			It("releases the buffered bytes when the stream is abandoned", func() {
+				Expect(controller.UpdateBufferedBytes(100)).To(Succeed())
+				controller.Abandon()
+				Expect(controller.connection.(*connectionFlowController).bufferedBytes).To(BeZero())
+			})
+		})
+
 		It("saves when data is read", func() {
 			controller.AddBytesRead(200)
 			Expect(controller.bytesRead).To(Equal(protocol.ByteCount(200)))
@@ -227,5 +290,15 @@ var _ = Describe("Stream Flow controller", func() {
 			Expect(blocked).To(BeTrue())
 			Expect(controller.IsNewlyBlocked()).To(BeFalse())
 		})
+
+		It("only reports increases of the send window", func() {
+			var updated []protocol.ByteCount
+			controller.onSendWindowUpdate = func(offset protocol.ByteCount) { updated = append(updated, offset) }
+			controller.UpdateSendWindow(100)
+			controller.UpdateSendWindow(50)
+			controller.UpdateSendWindow(100)
+			controller.UpdateSendWindow(150)
+			Expect(updated).To(Equal([]protocol.ByteCount{100, 150}))
+		})
 	})
 })
@@ -58,7 +58,7 @@ func (m messageType) String() string {
 	}
 }
 
-const clientSessionStateRevision = 3
+const clientSessionStateRevision = 4
 
 type conn struct {
 	localAddr, remoteAddr net.Addr
@@ -92,6 +92,9 @@ type cryptoSetup struct {
 	ourParams  *wire.TransportParameters
 	peerParams *wire.TransportParameters
 	paramsChan <-chan []byte
+	// allow0RTT is only used by the server.
+	// It is called with the transport parameters restored from the session ticket.
+	allow0RTT func(*wire.TransportParameters) bool
 
 	runner handshakeRunner
 
@@ -102,8 +105,15 @@ type cryptoSetup struct {
 	closeChan chan struct{}
 
 	zeroRTTParameters      *wire.TransportParameters
+	zeroRTTProtocol        string // only set for the client, the ALPN restored from the session state
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan *wire.TransportParameters
+	// used0RTT says if 0-RTT was accepted.
+	// The client sets it when offering 0-RTT, and resets it when the server rejects 0-RTT.
+	// It is protected by the mutex.
+	used0RTT bool
+	// the (EC)DHE group selected by the server, taken from the ServerHello, protected by the mutex
+	keyExchangeGroup tls.CurveID
 
 	receivedWriteKey chan struct{}
 	receivedReadKey  chan struct{}
@@ -116,6 +126,15 @@ type cryptoSetup struct {
 
 	rttStats *utils.RTTStats
 
+	// negotiatedProtocolChan is closed as soon as the application protocol is known.
+	// For 0-RTT, this happens before the handshake completes.
+	negotiatedProtocolChan chan struct{}
+	// negotiatedProtocol is overwritten with the protocol from the completed handshake,
+	// which might differ from the one used for 0-RTT if 0-RTT was rejected.
+	// It is protected by the mutex.
+	negotiatedProtocol      string
+	negotiatedProtocolKnown bool
+
 	tracer logging.ConnectionTracer
 	logger utils.Logger
 
@@ -190,6 +209,7 @@ func NewCryptoSetupServer(
 	runner handshakeRunner,
 	tlsConf *tls.Config,
 	enable0RTT bool,
+	allow0RTT func(*wire.TransportParameters) bool,
 	rttStats *utils.RTTStats,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
@@ -207,6 +227,7 @@ func NewCryptoSetupServer(
 		logger,
 		protocol.PerspectiveServer,
 	)
+	cs.allow0RTT = allow0RTT
 	cs.conn = qtls.Server(newConn(localAddr, remoteAddr), cs.tlsConf, cs.extraConf)
 	return cs
 }
@@ -254,6 +275,7 @@ func newCryptoSetup(
 		receivedWriteKey:       make(chan struct{}),
 		writeRecord:            make(chan struct{}, 1),
 		closeChan:              make(chan struct{}),
+		negotiatedProtocolChan: make(chan struct{}),
 	}
 	var maxEarlyData uint32
 	if enable0RTT {
@@ -288,6 +310,10 @@ func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) {
 	h.aead.SetLargestAcked(pn)
 }
 
+func (h *cryptoSetup) ForceKeyUpdate() error {
+	return h.aead.ForceKeyUpdate()
+}
+
 func (h *cryptoSetup) RunHandshake() {
 	// Handle errors that might occur when HandleData() is called.
 	handshakeComplete := make(chan struct{})
@@ -298,6 +324,7 @@ func (h *cryptoSetup) RunHandshake() {
 			handshakeErrChan <- err
 			return
 		}
+		h.setNegotiatedProtocol(h.conn.ConnectionState().NegotiatedProtocol)
 		close(handshakeComplete)
 	}()
 
@@ -313,8 +340,25 @@ func (h *cryptoSetup) RunHandshake() {
 		<-h.handshakeDone
 	case alert := <-h.alertChan:
 		handshakeErr := <-handshakeErrChan
-		h.onError(alert, handshakeErr.Error())
+		h.onError(alertForError(alert, handshakeErr), handshakeErr.Error())
+	}
+}
+
+// A tlsAlertError is an error returned from one of the tls.Config callbacks,
+// that carries the TLS alert that should be sent to the peer.
+type tlsAlertError interface {
+	error
+	TLSAlert() uint8
+}
+
+// alertForError returns the TLS alert that is sent when the handshake fails with err.
+// Errors returned from the tls.Config callbacks can override the alert chosen by TLS.
+func alertForError(alert uint8, err error) uint8 {
+	var aerr tlsAlertError
+	if errors.As(err, &aerr) {
+		return aerr.TLSAlert()
 	}
+	return alert
 }
 
 func (h *cryptoSetup) onError(alert uint8, message string) {
@@ -341,6 +385,9 @@ func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLev
 		h.onError(alertUnexpectedMessage, err.Error())
 		return false
 	}
+	if msgType == typeServerHello {
+		h.setKeyExchangeGroup(data)
+	}
 	h.messageChan <- data
 	if encLevel == protocol.Encryption1RTT {
 		h.handlePostHandshakeMessage()
@@ -499,38 +546,53 @@ func (h *cryptoSetup) marshalDataForSessionState() []byte {
 	buf := &bytes.Buffer{}
 	utils.WriteVarInt(buf, clientSessionStateRevision)
 	utils.WriteVarInt(buf, uint64(h.rttStats.SmoothedRTT().Microseconds()))
+	alpn := h.getNegotiatedProtocol()
+	utils.WriteVarInt(buf, uint64(len(alpn)))
+	buf.WriteString(alpn)
 	h.peerParams.MarshalForSessionTicket(buf)
 	return buf.Bytes()
 }
 
 func (h *cryptoSetup) handleDataFromSessionState(data []byte) {
-	tp, err := h.handleDataFromSessionStateImpl(data)
+	tp, alpn, err := h.handleDataFromSessionStateImpl(data)
 	if err != nil {
 		h.logger.Debugf("Restoring of transport parameters from session ticket failed: %s", err.Error())
 		return
 	}
 	h.zeroRTTParameters = tp
+	h.zeroRTTProtocol = alpn
 }
 
-func (h *cryptoSetup) handleDataFromSessionStateImpl(data []byte) (*wire.TransportParameters, error) {
+func (h *cryptoSetup) handleDataFromSessionStateImpl(data []byte) (*wire.TransportParameters, string, error) {
 	r := bytes.NewReader(data)
 	ver, err := utils.ReadVarInt(r)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	if ver != clientSessionStateRevision {
-		return nil, fmt.Errorf("mismatching version. Got %d, expected %d", ver, clientSessionStateRevision)
+		return nil, "", fmt.Errorf("mismatching version. Got %d, expected %d", ver, clientSessionStateRevision)
 	}
 	rtt, err := utils.ReadVarInt(r)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	alpnLen, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if alpnLen > uint64(r.Len()) {
+		return nil, "", io.EOF
+	}
+	alpn := make([]byte, alpnLen)
+	if _, err := io.ReadFull(r, alpn); err != nil {
+		return nil, "", err
 	}
 	h.rttStats.SetInitialRTT(time.Duration(rtt) * time.Microsecond)
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return &tp, nil
+	return &tp, string(alpn), nil
 }
 
 // only valid for the server
@@ -541,6 +603,7 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 		appData = (&sessionTicket{
 			Parameters: h.ourParams,
 			RTT:        h.rttStats.SmoothedRTT(),
+			ALPN:       h.getNegotiatedProtocol(),
 		}).Marshal()
 	}
 	return h.conn.GetSessionTicket(appData)
@@ -555,13 +618,22 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 		return false
 	}
 	valid := h.ourParams.ValidFor0RTT(t.Parameters)
-	if valid {
-		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
-		h.rttStats.SetInitialRTT(t.RTT)
-	} else {
+	if !valid {
 		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		return false
 	}
-	return valid
+	if h.allow0RTT != nil && !h.allow0RTT(t.Parameters) {
+		h.logger.Debugf("Application rejected 0-RTT.")
+		return false
+	}
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	h.mutex.Lock()
+	h.used0RTT = true
+	h.mutex.Unlock()
+	// qtls only asks us to accept 0-RTT if the ALPN matches the one used on the original connection.
+	h.setNegotiatedProtocol(t.ALPN)
+	return true
 }
 
 // rejected0RTT is called for the client when the server rejects 0-RTT.
@@ -571,8 +643,13 @@ func (h *cryptoSetup) rejected0RTT() {
 	h.mutex.Lock()
 	had0RTTKeys := h.zeroRTTSealer != nil
 	h.zeroRTTSealer = nil
+	h.used0RTT = false
 	h.mutex.Unlock()
 
+	if h.tracer != nil {
+		h.tracer.RejectedEarlyData()
+	}
+
 	if had0RTTKeys {
 		h.runner.DropKeys(protocol.Encryption0RTT)
 	}
@@ -599,7 +676,7 @@ func (h *cryptoSetup) handlePostHandshakeMessage() {
 	}()
 
 	if err := h.conn.HandlePostHandshakeMessage(); err != nil {
-		h.onError(<-alertChan, err.Error())
+		h.onError(alertForError(<-alertChan, err), err.Error())
 	}
 }
 
@@ -712,12 +789,17 @@ func (h *cryptoSetup) WriteRecord(p []byte) (int, error) {
 			h.clientHelloWritten = true
 			if h.zeroRTTSealer != nil && h.zeroRTTParameters != nil {
 				h.logger.Debugf("Doing 0-RTT.")
+				h.used0RTT = true
+				h.setNegotiatedProtocolLocked(h.zeroRTTProtocol)
 				h.clientHelloWrittenChan <- h.zeroRTTParameters
 			} else {
 				h.logger.Debugf("Not doing 0-RTT.")
 				h.clientHelloWrittenChan <- nil
 			}
 		} else {
+			if h.perspective == protocol.PerspectiveServer {
+				h.setKeyExchangeGroupLocked(p)
+			}
 			// We need additional signaling to properly detect HelloRetryRequests.
 			// For servers: when the ServerHello is written.
 			// For clients: when a reply is sent in response to a ServerHello.
@@ -859,3 +941,72 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return qtls.GetConnectionState(h.conn)
 }
+
+func (h *cryptoSetup) setNegotiatedProtocol(proto string) {
+	h.mutex.Lock()
+	h.setNegotiatedProtocolLocked(proto)
+	h.mutex.Unlock()
+}
+
+// must be called after locking the mutex
+func (h *cryptoSetup) setNegotiatedProtocolLocked(proto string) {
+	h.negotiatedProtocol = proto
+	if !h.negotiatedProtocolKnown {
+		h.negotiatedProtocolKnown = true
+		close(h.negotiatedProtocolChan)
+	}
+}
+
+func (h *cryptoSetup) getNegotiatedProtocol() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.negotiatedProtocol
+}
+
+// setKeyExchangeGroup saves the group from a ServerHello or a HelloRetryRequest.
+// If a HelloRetryRequest is sent, the group is overwritten by the following ServerHello.
+func (h *cryptoSetup) setKeyExchangeGroup(serverHello []byte) {
+	h.mutex.Lock()
+	h.setKeyExchangeGroupLocked(serverHello)
+	h.mutex.Unlock()
+}
+
+// must be called after locking the mutex
+func (h *cryptoSetup) setKeyExchangeGroupLocked(serverHello []byte) {
+	if group, ok := parseKeyShareGroup(serverHello); ok {
+		h.keyExchangeGroup = group
+	}
+}
+
+func (h *cryptoSetup) KeyExchangeGroup() tls.CurveID {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.keyExchangeGroup
+}
+
+// NegotiatedProtocol returns the application protocol negotiated using ALPN.
+// It blocks until the protocol is known. When using 0-RTT, this happens before the handshake completes.
+// If the handshake fails, an empty string is returned.
+func (h *cryptoSetup) NegotiatedProtocol() string {
+	select {
+	case <-h.negotiatedProtocolChan:
+		return h.getNegotiatedProtocol()
+	case <-h.handshakeDone:
+	}
+	select {
+	case <-h.negotiatedProtocolChan:
+		return h.getNegotiatedProtocol()
+	default:
+		return ""
+	}
+}
+
+// Used0RTT says if 0-RTT was accepted, which the client only knows after processing the EncryptedExtensions.
+func (h *cryptoSetup) Used0RTT() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.used0RTT
+}
@@ -6,6 +6,8 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -50,6 +52,11 @@ func (s *stream) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+type alertError struct{ alert uint8 }
+
+func (e *alertError) Error() string   { return "alert error" }
+func (e *alertError) TLSAlert() uint8 { return e.alert }
+
 var _ = Describe("Crypto Setup TLS", func() {
 	var clientConf, serverConf *tls.Config
 
@@ -88,6 +95,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -112,6 +120,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("uses the TLS alert of errors returned from the tls.Config callbacks", func() {
+		Expect(alertForError(80, errors.New("foobar"))).To(BeEquivalentTo(80))
+		Expect(alertForError(80, &alertError{alert: 49})).To(BeEquivalentTo(49))
+		Expect(alertForError(80, fmt.Errorf("wrapped: %w", &alertError{alert: 49}))).To(BeEquivalentTo(49))
+	})
+
 	It("errors when a message is received at the wrong encryption level", func() {
 		sErrChan := make(chan error, 1)
 		_, sInitialStream, sHandshakeStream := initStreams()
@@ -128,6 +142,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			testdata.GetTLSConfig(),
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -171,6 +186,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			runner,
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -207,6 +223,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
 			false,
+			nil,
 			&utils.RTTStats{},
 			nil,
 			utils.DefaultLogger.WithPrefix("server"),
@@ -249,6 +266,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			return rttStats
 		}
 
+		// allow0RTT is passed to the server by handshakeWithTLSConf
+		var allow0RTT func(*wire.TransportParameters) bool
+
+		BeforeEach(func() {
+			allow0RTT = nil
+		})
+
 		handshake := func(client CryptoSetup, cChunkChan <-chan chunk,
 			server CryptoSetup, sChunkChan <-chan chunk) {
 			done := make(chan struct{})
@@ -331,6 +355,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				enable0RTT,
+				allow0RTT,
 				serverRTTStats,
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -362,9 +387,35 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
 
+		It("returns the negotiated protocol", func() {
+			_, client, clientErr, server, serverErr := handshakeWithTLSConf(
+				clientConf, serverConf,
+				&utils.RTTStats{}, &utils.RTTStats{},
+				&wire.TransportParameters{}, &wire.TransportParameters{},
+				false,
+			)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.NegotiatedProtocol()).To(Equal("crypto-setup"))
+			Expect(server.NegotiatedProtocol()).To(Equal("crypto-setup"))
+		})
+
+		It("returns the key exchange group", func() {
+			_, client, clientErr, server, serverErr := handshakeWithTLSConf(
+				clientConf, serverConf,
+				&utils.RTTStats{}, &utils.RTTStats{},
+				&wire.TransportParameters{}, &wire.TransportParameters{},
+				false,
+			)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.KeyExchangeGroup()).To(Equal(tls.X25519))
+			Expect(server.KeyExchangeGroup()).To(Equal(tls.X25519))
+		})
+
 		It("performs a HelloRetryRequst", func() {
 			serverConf.CurvePreferences = []tls.CurveID{tls.CurveP384}
-			_, _, clientErr, _, serverErr := handshakeWithTLSConf(
+			_, client, clientErr, server, serverErr := handshakeWithTLSConf(
 				clientConf, serverConf,
 				&utils.RTTStats{}, &utils.RTTStats{},
 				&wire.TransportParameters{}, &wire.TransportParameters{},
@@ -372,6 +423,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.KeyExchangeGroup()).To(Equal(tls.CurveP384))
+			Expect(server.KeyExchangeGroup()).To(Equal(tls.CurveP384))
 		})
 
 		It("handshakes with client auth", func() {
@@ -466,6 +519,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sRunner,
 				serverConf,
 				false,
+				nil,
 				&utils.RTTStats{},
 				nil,
 				utils.DefaultLogger.WithPrefix("server"),
@@ -519,6 +573,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -579,6 +634,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					sRunner,
 					serverConf,
 					false,
+					nil,
 					&utils.RTTStats{},
 					nil,
 					utils.DefaultLogger.WithPrefix("server"),
@@ -735,6 +791,10 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
 				Expect(server.ConnectionState().Used0RTT).To(BeTrue())
 				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
+				Expect(server.Used0RTT()).To(BeTrue())
+				Expect(client.Used0RTT()).To(BeTrue())
+				Expect(server.NegotiatedProtocol()).To(Equal("crypto-setup"))
+				Expect(client.NegotiatedProtocol()).To(Equal("crypto-setup"))
 			})
 
 			It("rejects 0-RTT, whent the transport parameters changed", func() {
@@ -786,6 +846,111 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
 				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
 				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
+				Expect(server.Used0RTT()).To(BeFalse())
+				Expect(client.Used0RTT()).To(BeFalse())
+			})
+
+			It("uses the ALPN from the completed handshake, when 0-RTT is rejected", func() {
+				csc := mocktls.NewMockClientSessionCache(mockCtrl)
+				var state *tls.ClientSessionState
+				receivedSessionTicket := make(chan struct{})
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+					state = css
+					close(receivedSessionTicket)
+				})
+				clientConf.ClientSessionCache = csc
+				clientConf.NextProtos = []string{"crypto-setup", "other"}
+				_, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(receivedSessionTicket).Should(BeClosed())
+				Expect(client.NegotiatedProtocol()).To(Equal("crypto-setup"))
+
+				csc.EXPECT().Get(gomock.Any()).Return(state, true)
+				csc.EXPECT().Put(gomock.Any(), nil)
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
+
+				serverConf.NextProtos = []string{"other"}
+				clientHelloWrittenChan, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				// the client attempted 0-RTT, using the ALPN from the first connection
+				Expect(clientHelloWrittenChan).To(Receive(Not(BeNil())))
+				Expect(client.Used0RTT()).To(BeFalse())
+				Expect(server.Used0RTT()).To(BeFalse())
+				Expect(client.NegotiatedProtocol()).To(Equal("other"))
+				Expect(server.NegotiatedProtocol()).To(Equal("other"))
+			})
+
+			It("rejects 0-RTT, when the application rejects it", func() {
+				csc := mocktls.NewMockClientSessionCache(mockCtrl)
+				var state *tls.ClientSessionState
+				receivedSessionTicket := make(chan struct{})
+				csc.EXPECT().Get(gomock.Any())
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+					state = css
+					close(receivedSessionTicket)
+				})
+				clientConf.ClientSessionCache = csc
+				const clientRTT = 30 * time.Millisecond // RTT as measured by the client. Should be restored.
+				clientOrigRTTStats := newRTTStatsWithRTT(clientRTT)
+				const initialMaxData protocol.ByteCount = 1337
+				clientHelloWrittenChan, client, clientErr, server, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					clientOrigRTTStats, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Eventually(receivedSessionTicket).Should(BeClosed())
+				Expect(server.ConnectionState().DidResume).To(BeFalse())
+				Expect(client.ConnectionState().DidResume).To(BeFalse())
+				Expect(clientHelloWrittenChan).To(Receive(BeNil()))
+
+				csc.EXPECT().Get(gomock.Any()).Return(state, true)
+				csc.EXPECT().Put(gomock.Any(), nil)
+				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
+
+				var restoredParams *wire.TransportParameters
+				allow0RTT = func(tp *wire.TransportParameters) bool {
+					restoredParams = tp
+					return false
+				}
+				clientRTTStats := &utils.RTTStats{}
+				clientHelloWrittenChan, client, clientErr, server, serverErr = handshakeWithTLSConf(
+					clientConf, serverConf,
+					clientRTTStats, &utils.RTTStats{},
+					&wire.TransportParameters{}, &wire.TransportParameters{InitialMaxData: initialMaxData},
+					true,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Expect(clientRTTStats.SmoothedRTT()).To(Equal(clientRTT))
+
+				var tp *wire.TransportParameters
+				Expect(clientHelloWrittenChan).To(Receive(&tp))
+				Expect(tp.InitialMaxData).To(Equal(initialMaxData))
+				Expect(restoredParams).ToNot(BeNil())
+				Expect(restoredParams.InitialMaxData).To(Equal(initialMaxData))
+
+				Expect(server.ConnectionState().DidResume).To(BeTrue())
+				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
+				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
+				Expect(server.Used0RTT()).To(BeFalse())
+				Expect(client.Used0RTT()).To(BeFalse())
 			})
 		})
 	})
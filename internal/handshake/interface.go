@@ -1,6 +1,7 @@
 package handshake
 
 import (
+	"crypto/tls"
 	"errors"
 	"io"
 	"time"
@@ -20,6 +21,9 @@ var (
 	ErrKeysDropped = errors.New("CryptoSetup: keys were already dropped")
 	// ErrDecryptionFailed is returned when the AEAD fails to open the packet.
 	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrKeyUpdateInProgress is returned when a key update is requested,
+	// but the previous key update hasn't been acknowledged by the peer yet.
+	ErrKeyUpdateInProgress = errors.New("key update in progress")
 )
 
 // ConnectionState contains information about the state of the connection.
@@ -77,8 +81,12 @@ type CryptoSetup interface {
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	ForceKeyUpdate() error
 	DropHandshakeKeys()
 	ConnectionState() ConnectionState
+	NegotiatedProtocol() string
+	Used0RTT() bool
+	KeyExchangeGroup() tls.CurveID
 
 	GetInitialOpener() (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
@@ -0,0 +1,57 @@
+package handshake
+
+import (
+	"os"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// By setting this environment variable to 1, header protection can be disabled for 1-RTT packets,
+// if the session was configured with DisableHeaderProtection.
+// This violates the QUIC specification, and is only useful for debugging in a lab environment,
+// where both endpoints disable header protection.
+const disableHeaderProtectionEnv = "QUIC_GO_DISABLE_HEADER_PROTECTION"
+
+// MaybeDisableHeaderProtection returns a CryptoSetup that doesn't apply header protection to 1-RTT packets,
+// if the QUIC_GO_DISABLE_HEADER_PROTECTION environment variable is set to 1.
+// Otherwise, it returns the CryptoSetup unchanged.
+func MaybeDisableHeaderProtection(cs CryptoSetup, logger utils.Logger) CryptoSetup {
+	if os.Getenv(disableHeaderProtectionEnv) != "1" {
+		logger.Errorf("Not disabling header protection, since %s is not set to 1.", disableHeaderProtectionEnv)
+		return cs
+	}
+	logger.Errorf("Disabling header protection for 1-RTT packets. This is insecure and must only be used for debugging.")
+	return &noHeaderProtectionCryptoSetup{CryptoSetup: cs}
+}
+
+type noHeaderProtectionCryptoSetup struct {
+	CryptoSetup
+}
+
+func (cs *noHeaderProtectionCryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
+	opener, err := cs.CryptoSetup.Get1RTTOpener()
+	if err != nil {
+		return nil, err
+	}
+	return &noHeaderProtectionOpener{ShortHeaderOpener: opener}, nil
+}
+
+func (cs *noHeaderProtectionCryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
+	sealer, err := cs.CryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	return &noHeaderProtectionSealer{ShortHeaderSealer: sealer}, nil
+}
+
+type noHeaderProtectionOpener struct {
+	ShortHeaderOpener
+}
+
+func (o *noHeaderProtectionOpener) DecryptHeader([]byte, *byte, []byte) {}
+
+type noHeaderProtectionSealer struct {
+	ShortHeaderSealer
+}
+
+func (s *noHeaderProtectionSealer) EncryptHeader([]byte, *byte, []byte) {}
@@ -0,0 +1,64 @@
+package handshake
+
+import (
+	"crypto/rand"
+	"os"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type oneRTTCryptoSetup struct {
+	CryptoSetup
+	aead *updatableAEAD
+}
+
+func (cs *oneRTTCryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) { return cs.aead, nil }
+func (cs *oneRTTCryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) { return cs.aead, nil }
+
+var _ = Describe("Disabling header protection", func() {
+	var cs *oneRTTCryptoSetup
+
+	BeforeEach(func() {
+		trafficSecret := make([]byte, 16)
+		rand.Read(trafficSecret)
+		aead := newUpdatableAEAD(&utils.RTTStats{}, nil, utils.DefaultLogger)
+		aead.SetReadKey(cipherSuites[0], trafficSecret)
+		aead.SetWriteKey(cipherSuites[0], trafficSecret)
+		cs = &oneRTTCryptoSetup{aead: aead}
+	})
+
+	AfterEach(func() {
+		os.Setenv(disableHeaderProtectionEnv, "")
+	})
+
+	It("doesn't disable header protection if the environment variable is not set", func() {
+		Expect(MaybeDisableHeaderProtection(cs, utils.DefaultLogger)).To(Equal(cs))
+	})
+
+	It("doesn't apply header protection to 1-RTT packets", func() {
+		os.Setenv(disableHeaderProtectionEnv, "1")
+		wrapped := MaybeDisableHeaderProtection(cs, utils.DefaultLogger)
+		Expect(wrapped).ToNot(Equal(cs))
+		sealer, err := wrapped.Get1RTTSealer()
+		Expect(err).ToNot(HaveOccurred())
+		opener, err := wrapped.Get1RTTOpener()
+		Expect(err).ToNot(HaveOccurred())
+		sample := make([]byte, 16)
+		rand.Read(sample)
+		header := []byte{0x45, 0xde, 0xad, 0xbe, 0xef}
+		sealer.EncryptHeader(sample, &header[0], header[1:])
+		Expect(header).To(Equal([]byte{0x45, 0xde, 0xad, 0xbe, 0xef}))
+		opener.DecryptHeader(sample, &header[0], header[1:])
+		Expect(header).To(Equal([]byte{0x45, 0xde, 0xad, 0xbe, 0xef}))
+		// the payload is still encrypted
+		ciphertext := sealer.Seal(nil, []byte("foobar"), 0x1337, header)
+		Expect(string(ciphertext)).ToNot(ContainSubstring("foobar"))
+		plaintext, err := opener.Open(nil, ciphertext, time.Now(), 0x1337, sealer.KeyPhase(), header)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plaintext).To(Equal([]byte("foobar")))
+	})
+})
@@ -0,0 +1,45 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const extensionKeyShare uint16 = 51
+
+// parseKeyShareGroup parses a ServerHello (or a HelloRetryRequest) message,
+// and returns the (EC)DHE group selected by the server in the key_share extension.
+// qtls doesn't expose the negotiated group, so we need to get it from the handshake messages.
+func parseKeyShareGroup(data []byte) (tls.CurveID, bool) {
+	s := cryptobyte.String(data)
+	var msgType uint8
+	var msg cryptobyte.String
+	if !s.ReadUint8(&msgType) || messageType(msgType) != typeServerHello || !s.ReadUint24LengthPrefixed(&msg) {
+		return 0, false
+	}
+	var sessionID, extensions cryptobyte.String
+	if !msg.Skip(2+32) || // legacy_version and random
+		!msg.ReadUint8LengthPrefixed(&sessionID) ||
+		!msg.Skip(2+1) || // cipher_suite and legacy_compression_method
+		!msg.ReadUint16LengthPrefixed(&extensions) {
+		return 0, false
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return 0, false
+		}
+		if extType != extensionKeyShare {
+			continue
+		}
+		// Both the KeyShareEntry of the ServerHello and the selected_group of the HelloRetryRequest start with the group.
+		var group uint16
+		if !extData.ReadUint16(&group) {
+			return 0, false
+		}
+		return tls.CurveID(group), true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	"golang.org/x/crypto/cryptobyte"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ServerHello parsing", func() {
+	getServerHello := func(exts ...[]byte) []byte {
+		b := cryptobyte.NewBuilder(nil)
+		b.AddUint8(uint8(typeServerHello))
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(tls.VersionTLS12)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("session ID")) })
+			b.AddUint16(tls.TLS_AES_128_GCM_SHA256)
+			b.AddUint8(0)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				for _, ext := range exts {
+					b.AddBytes(ext)
+				}
+			})
+		})
+		return b.BytesOrPanic()
+	}
+
+	getExtension := func(extType uint16, data []byte) []byte {
+		b := cryptobyte.NewBuilder(nil)
+		b.AddUint16(extType)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(data) })
+		return b.BytesOrPanic()
+	}
+
+	It("parses the group of the key share", func() {
+		supportedVersions := getExtension(43, []byte{0x3, 0x4})
+		keyShare := getExtension(extensionKeyShare, append([]byte{0x0, 0x1d, 0x0, 0x4}, []byte("key!")...))
+		group, ok := parseKeyShareGroup(getServerHello(supportedVersions, keyShare))
+		Expect(ok).To(BeTrue())
+		Expect(group).To(Equal(tls.X25519))
+	})
+
+	It("parses the selected group of a HelloRetryRequest", func() {
+		group, ok := parseKeyShareGroup(getServerHello(getExtension(extensionKeyShare, []byte{0x0, 0x17})))
+		Expect(ok).To(BeTrue())
+		Expect(group).To(Equal(tls.CurveP256))
+	})
+
+	It("errors if there's no key share", func() {
+		_, ok := parseKeyShareGroup(getServerHello(getExtension(43, []byte{0x3, 0x4})))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("errors on other messages", func() {
+		data := getServerHello(getExtension(extensionKeyShare, []byte{0x0, 0x17}))
+		data[0] = byte(typeEncryptedExtensions)
+		_, ok := parseKeyShareGroup(data)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("errors on truncated messages", func() {
+		data := getServerHello(getExtension(extensionKeyShare, []byte{0x0, 0x17}))
+		for i := 0; i < len(data); i++ {
+			_, ok := parseKeyShareGroup(data[:i])
+			Expect(ok).To(BeFalse())
+		}
+	})
+})
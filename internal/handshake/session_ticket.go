@@ -4,23 +4,27 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
-const sessionTicketRevision = 2
+const sessionTicketRevision = 3
 
 type sessionTicket struct {
 	Parameters *wire.TransportParameters
 	RTT        time.Duration // to be encoded in mus
+	ALPN       string
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := &bytes.Buffer{}
 	utils.WriteVarInt(b, sessionTicketRevision)
 	utils.WriteVarInt(b, uint64(t.RTT.Microseconds()))
+	utils.WriteVarInt(b, uint64(len(t.ALPN)))
+	b.WriteString(t.ALPN)
 	t.Parameters.MarshalForSessionTicket(b)
 	return b.Bytes()
 }
@@ -38,11 +42,23 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if err != nil {
 		return errors.New("failed to read RTT")
 	}
+	alpnLen, err := utils.ReadVarInt(r)
+	if err != nil {
+		return errors.New("failed to read ALPN")
+	}
+	if alpnLen > uint64(r.Len()) {
+		return errors.New("failed to read ALPN")
+	}
+	alpn := make([]byte, alpnLen)
+	if _, err := io.ReadFull(r, alpn); err != nil {
+		return errors.New("failed to read ALPN")
+	}
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
 	}
 	t.Parameters = &tp
 	t.RTT = time.Duration(rtt) * time.Microsecond
+	t.ALPN = string(alpn)
 	return nil
 }
@@ -18,13 +18,15 @@ var _ = Describe("Session Ticket", func() {
 				InitialMaxStreamDataBidiLocal:  1,
 				InitialMaxStreamDataBidiRemote: 2,
 			},
-			RTT: 1337 * time.Microsecond,
+			RTT:  1337 * time.Microsecond,
+			ALPN: "foobar",
 		}
 		var t sessionTicket
 		Expect(t.Unmarshal(ticket.Marshal())).To(Succeed())
 		Expect(t.Parameters.InitialMaxStreamDataBidiLocal).To(BeEquivalentTo(1))
 		Expect(t.Parameters.InitialMaxStreamDataBidiRemote).To(BeEquivalentTo(2))
 		Expect(t.RTT).To(Equal(1337 * time.Microsecond))
+		Expect(t.ALPN).To(Equal("foobar"))
 	})
 
 	It("refuses to unmarshal if the ticket is too short for the revision", func() {
@@ -43,9 +45,20 @@ var _ = Describe("Session Ticket", func() {
 		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read RTT"))
 	})
 
+	It("refuses to unmarshal if the ALPN cannot be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 1337)
+		utils.WriteVarInt(b, 10)
+		b.Write([]byte("foo"))
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read ALPN"))
+	})
+
 	It("refuses to unmarshal if unmarshaling the transport parameters fails", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 1337)
+		utils.WriteVarInt(b, 0)
 		b.Write([]byte("foobar"))
 		err := (&sessionTicket{}).Unmarshal(b.Bytes())
 		Expect(err).To(HaveOccurred())
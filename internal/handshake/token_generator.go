@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 const (
@@ -37,16 +38,19 @@ type token struct {
 // A TokenGenerator generates tokens
 type TokenGenerator struct {
 	tokenProtector tokenProtector
+	clock          utils.Clock
 }
 
-// NewTokenGenerator initializes a new TookenGenerator
-func NewTokenGenerator(rand io.Reader) (*TokenGenerator, error) {
+// NewTokenGenerator initializes a new TookenGenerator.
+// The clock is used to timestamp the tokens.
+func NewTokenGenerator(rand io.Reader, clock utils.Clock) (*TokenGenerator, error) {
 	tokenProtector, err := newTokenProtector(rand)
 	if err != nil {
 		return nil, err
 	}
 	return &TokenGenerator{
 		tokenProtector: tokenProtector,
+		clock:          clock,
 	}, nil
 }
 
@@ -61,7 +65,7 @@ func (g *TokenGenerator) NewRetryToken(
 		RemoteAddr:               encodeRemoteAddr(raddr),
 		OriginalDestConnectionID: origDestConnID,
 		RetrySrcConnectionID:     retrySrcConnID,
-		Timestamp:                time.Now().UnixNano(),
+		Timestamp:                g.clock.Now().UnixNano(),
 	})
 	if err != nil {
 		return nil, err
@@ -73,7 +77,7 @@ func (g *TokenGenerator) NewRetryToken(
 func (g *TokenGenerator) NewToken(raddr net.Addr) ([]byte, error) {
 	data, err := asn1.Marshal(token{
 		RemoteAddr: encodeRemoteAddr(raddr),
-		Timestamp:  time.Now().UnixNano(),
+		Timestamp:  g.clock.Now().UnixNano(),
 	})
 	if err != nil {
 		return nil, err
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,7 +18,7 @@ var _ = Describe("Token Generator", func() {
 
 	BeforeEach(func() {
 		var err error
-		tokenGen, err = NewTokenGenerator(rand.Reader)
+		tokenGen, err = NewTokenGenerator(rand.Reader, utils.DefaultClock{})
 		Expect(err).ToNot(HaveOccurred())
 	})
 
@@ -50,6 +51,17 @@ var _ = Describe("Token Generator", func() {
 		Expect(token.RetrySrcConnectionID.Len()).To(BeZero())
 	})
 
+	It("uses the clock to timestamp tokens", func() {
+		now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		tokenGen, err := NewTokenGenerator(rand.Reader, utils.NewManualClock(now))
+		Expect(err).ToNot(HaveOccurred())
+		tokenEnc, err := tokenGen.NewToken(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337})
+		Expect(err).ToNot(HaveOccurred())
+		token, err := tokenGen.DecodeToken(tokenEnc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token.SentTime).To(BeTemporally("==", now))
+	})
+
 	It("saves the connection ID", func() {
 		tokenEnc, err := tokenGen.NewRetryToken(
 			&net.UDPAddr{},
@@ -57,6 +57,7 @@ type updatableAEAD struct {
 	firstSentWithCurrentKey protocol.PacketNumber
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
+	keyUpdateForced         bool
 	rcvAEAD                 cipher.AEAD
 	sendAEAD                cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
@@ -101,6 +102,7 @@ func (a *updatableAEAD) rollKeys() {
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	a.keyUpdateForced = false
 	a.prevRcvAEAD = a.rcvAEAD
 	a.rcvAEAD = a.nextRcvAEAD
 	a.sendAEAD = a.nextSendAEAD
@@ -241,10 +243,24 @@ func (a *updatableAEAD) updateAllowed() bool {
 		a.largestAcked >= a.firstSentWithCurrentKey
 }
 
+// ForceKeyUpdate makes us initiate a key update when the next packet is sent.
+// It fails if we're not yet allowed to initiate another key update.
+func (a *updatableAEAD) ForceKeyUpdate() error {
+	if a.keyUpdateForced || !a.updateAllowed() {
+		return ErrKeyUpdateInProgress
+	}
+	a.keyUpdateForced = true
+	return nil
+}
+
 func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 	if !a.updateAllowed() {
 		return false
 	}
+	if a.keyUpdateForced {
+		a.logger.Debugf("Forced key update. Initiating key update to the next key phase: %s", a.keyPhase+1)
+		return true
+	}
 	if a.numRcvdWithCurrentKey >= a.keyUpdateInterval {
 		a.logger.Debugf("Received %d packets with current key phase. Initiating key update to the next key phase: %s", a.numRcvdWithCurrentKey, a.keyPhase+1)
 		return true
@@ -269,6 +269,26 @@ var _ = Describe("Updatable AEAD", func() {
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
 						})
 
+						It("initiates a forced key update", func() {
+							// no update allowed before receiving an acknowledgement for the current key phase
+							Expect(server.ForceKeyUpdate()).To(MatchError(ErrKeyUpdateInProgress))
+							server.Seal(nil, msg, 0, ad)
+							Expect(server.ForceKeyUpdate()).To(MatchError(ErrKeyUpdateInProgress))
+							server.SetLargestAcked(0)
+							Expect(server.ForceKeyUpdate()).To(Succeed())
+							Expect(server.ForceKeyUpdate()).To(MatchError(ErrKeyUpdateInProgress))
+							serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							// the next key update is only allowed once a packet sent with the new keys is acknowledged
+							Expect(server.ForceKeyUpdate()).To(MatchError(ErrKeyUpdateInProgress))
+							server.Seal(nil, msg, 1, ad)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							server.SetLargestAcked(1)
+							Expect(server.ForceKeyUpdate()).To(Succeed())
+							serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(2), false)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+						})
+
 						It("drops keys 3 PTOs after a key update", func() {
 							now := time.Now()
 							for i := 0; i < keyUpdateInterval; i++ {
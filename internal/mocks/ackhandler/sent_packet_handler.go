@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	congestion "github.com/lucas-clemente/quic-go/internal/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 	quictrace "github.com/lucas-clemente/quic-go/quictrace"
@@ -52,6 +53,20 @@ func (mr *MockSentPacketHandlerMockRecorder) AmplificationWindow() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AmplificationWindow", reflect.TypeOf((*MockSentPacketHandler)(nil).AmplificationWindow))
 }
 
+// BandwidthEstimate mocks base method
+func (m *MockSentPacketHandler) BandwidthEstimate() congestion.Bandwidth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BandwidthEstimate")
+	ret0, _ := ret[0].(congestion.Bandwidth)
+	return ret0
+}
+
+// BandwidthEstimate indicates an expected call of BandwidthEstimate
+func (mr *MockSentPacketHandlerMockRecorder) BandwidthEstimate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthEstimate", reflect.TypeOf((*MockSentPacketHandler)(nil).BandwidthEstimate))
+}
+
 // DropPackets mocks base method
 func (m *MockSentPacketHandler) DropPackets(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -64,6 +79,20 @@ func (mr *MockSentPacketHandlerMockRecorder) DropPackets(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropPackets", reflect.TypeOf((*MockSentPacketHandler)(nil).DropPackets), arg0)
 }
 
+// ECNMode mocks base method
+func (m *MockSentPacketHandler) ECNMode() protocol.ECN {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ECNMode")
+	ret0, _ := ret[0].(protocol.ECN)
+	return ret0
+}
+
+// ECNMode indicates an expected call of ECNMode
+func (mr *MockSentPacketHandlerMockRecorder) ECNMode() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNMode", reflect.TypeOf((*MockSentPacketHandler)(nil).ECNMode))
+}
+
 // GetLossDetectionTimeout mocks base method
 func (m *MockSentPacketHandler) GetLossDetectionTimeout() time.Time {
 	m.ctrl.T.Helper()
@@ -106,6 +135,30 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// MigratedPath mocks base method
+func (m *MockSentPacketHandler) MigratedPath(arg0, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MigratedPath", arg0, arg1)
+}
+
+// MigratedPath indicates an expected call of MigratedPath
+func (mr *MockSentPacketHandlerMockRecorder) MigratedPath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).MigratedPath), arg0, arg1)
+}
+
+// OnAppLimited mocks base method
+func (m *MockSentPacketHandler) OnAppLimited() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAppLimited")
+}
+
+// OnAppLimited indicates an expected call of OnAppLimited
+func (mr *MockSentPacketHandlerMockRecorder) OnAppLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAppLimited", reflect.TypeOf((*MockSentPacketHandler)(nil).OnAppLimited))
+}
+
 // OnLossDetectionTimeout mocks base method
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -120,6 +173,21 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PacketsLost mocks base method
+func (m *MockSentPacketHandler) PacketsLost() (uint64, protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacketsLost")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(protocol.ByteCount)
+	return ret0, ret1
+}
+
+// PacketsLost indicates an expected call of PacketsLost
+func (mr *MockSentPacketHandlerMockRecorder) PacketsLost() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacketsLost", reflect.TypeOf((*MockSentPacketHandler)(nil).PacketsLost))
+}
+
 // PeekPacketNumber mocks base method
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -241,6 +309,18 @@ func (mr *MockSentPacketHandlerMockRecorder) SetHandshakeConfirmed() *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeConfirmed", reflect.TypeOf((*MockSentPacketHandler)(nil).SetHandshakeConfirmed))
 }
 
+// SetPathValidated mocks base method
+func (m *MockSentPacketHandler) SetPathValidated() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPathValidated")
+}
+
+// SetPathValidated indicates an expected call of SetPathValidated
+func (mr *MockSentPacketHandlerMockRecorder) SetPathValidated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPathValidated", reflect.TypeOf((*MockSentPacketHandler)(nil).SetPathValidated))
+}
+
 // TimeUntilSend mocks base method
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	tls "crypto/tls"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -88,6 +89,20 @@ func (mr *MockCryptoSetupMockRecorder) DropHandshakeKeys() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DropHandshakeKeys", reflect.TypeOf((*MockCryptoSetup)(nil).DropHandshakeKeys))
 }
 
+// ForceKeyUpdate mocks base method
+func (m *MockCryptoSetup) ForceKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceKeyUpdate indicates an expected call of ForceKeyUpdate
+func (mr *MockCryptoSetupMockRecorder) ForceKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceKeyUpdate", reflect.TypeOf((*MockCryptoSetup)(nil).ForceKeyUpdate))
+}
+
 // Get0RTTOpener mocks base method
 func (m *MockCryptoSetup) Get0RTTOpener() (handshake.LongHeaderOpener, error) {
 	m.ctrl.T.Helper()
@@ -237,6 +252,34 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// KeyExchangeGroup mocks base method
+func (m *MockCryptoSetup) KeyExchangeGroup() tls.CurveID {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeyExchangeGroup")
+	ret0, _ := ret[0].(tls.CurveID)
+	return ret0
+}
+
+// KeyExchangeGroup indicates an expected call of KeyExchangeGroup
+func (mr *MockCryptoSetupMockRecorder) KeyExchangeGroup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeyExchangeGroup", reflect.TypeOf((*MockCryptoSetup)(nil).KeyExchangeGroup))
+}
+
+// NegotiatedProtocol mocks base method
+func (m *MockCryptoSetup) NegotiatedProtocol() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NegotiatedProtocol")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NegotiatedProtocol indicates an expected call of NegotiatedProtocol
+func (mr *MockCryptoSetupMockRecorder) NegotiatedProtocol() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedProtocol", reflect.TypeOf((*MockCryptoSetup)(nil).NegotiatedProtocol))
+}
+
 // RunHandshake mocks base method
 func (m *MockCryptoSetup) RunHandshake() {
 	m.ctrl.T.Helper()
@@ -260,3 +303,17 @@ func (mr *MockCryptoSetupMockRecorder) SetLargest1RTTAcked(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLargest1RTTAcked", reflect.TypeOf((*MockCryptoSetup)(nil).SetLargest1RTTAcked), arg0)
 }
+
+// Used0RTT mocks base method
+func (m *MockCryptoSetup) Used0RTT() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Used0RTT")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Used0RTT indicates an expected call of Used0RTT
+func (mr *MockCryptoSetupMockRecorder) Used0RTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Used0RTT", reflect.TypeOf((*MockCryptoSetup)(nil).Used0RTT))
+}
@@ -39,6 +39,18 @@ func (m *MockConnectionTracer) EXPECT() *MockConnectionTracerMockRecorder {
 	return m.recorder
 }
 
+// BlockedByAmplificationLimit mocks base method
+func (m *MockConnectionTracer) BlockedByAmplificationLimit() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BlockedByAmplificationLimit")
+}
+
+// BlockedByAmplificationLimit indicates an expected call of BlockedByAmplificationLimit
+func (mr *MockConnectionTracerMockRecorder) BlockedByAmplificationLimit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockedByAmplificationLimit", reflect.TypeOf((*MockConnectionTracer)(nil).BlockedByAmplificationLimit))
+}
+
 // BufferedPacket mocks base method
 func (m *MockConnectionTracer) BufferedPacket(arg0 protocol.PacketType) {
 	m.ctrl.T.Helper()
@@ -75,6 +87,18 @@ func (mr *MockConnectionTracerMockRecorder) ClosedConnection(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClosedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).ClosedConnection), arg0)
 }
 
+// CongestionWindowUpdated mocks base method
+func (m *MockConnectionTracer) CongestionWindowUpdated(arg0, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CongestionWindowUpdated", arg0, arg1)
+}
+
+// CongestionWindowUpdated indicates an expected call of CongestionWindowUpdated
+func (mr *MockConnectionTracerMockRecorder) CongestionWindowUpdated(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CongestionWindowUpdated", reflect.TypeOf((*MockConnectionTracer)(nil).CongestionWindowUpdated), arg0, arg1)
+}
+
 // DroppedEncryptionLevel mocks base method
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -111,6 +135,18 @@ func (mr *MockConnectionTracerMockRecorder) DroppedPacket(arg0, arg1, arg2 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).DroppedPacket), arg0, arg1, arg2)
 }
 
+// ECNStateUpdated mocks base method
+func (m *MockConnectionTracer) ECNStateUpdated(arg0 logging.ECNState, arg1 logging.ECNStateTrigger) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ECNStateUpdated", arg0, arg1)
+}
+
+// ECNStateUpdated indicates an expected call of ECNStateUpdated
+func (mr *MockConnectionTracerMockRecorder) ECNStateUpdated(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNStateUpdated", reflect.TypeOf((*MockConnectionTracer)(nil).ECNStateUpdated), arg0, arg1)
+}
+
 // LossTimerCanceled mocks base method
 func (m *MockConnectionTracer) LossTimerCanceled() {
 	m.ctrl.T.Helper()
@@ -147,16 +183,52 @@ func (mr *MockConnectionTracerMockRecorder) LostPacket(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LostPacket", reflect.TypeOf((*MockConnectionTracer)(nil).LostPacket), arg0, arg1, arg2)
 }
 
+// NewConnectionIDReceived mocks base method
+func (m *MockConnectionTracer) NewConnectionIDReceived(arg0 uint64, arg1 protocol.ConnectionID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NewConnectionIDReceived", arg0, arg1)
+}
+
+// NewConnectionIDReceived indicates an expected call of NewConnectionIDReceived
+func (mr *MockConnectionTracerMockRecorder) NewConnectionIDReceived(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewConnectionIDReceived", reflect.TypeOf((*MockConnectionTracer)(nil).NewConnectionIDReceived), arg0, arg1)
+}
+
+// PathValidated mocks base method
+func (m *MockConnectionTracer) PathValidated(arg0, arg1 net.Addr) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "PathValidated", arg0, arg1)
+}
+
+// PathValidated indicates an expected call of PathValidated
+func (mr *MockConnectionTracerMockRecorder) PathValidated(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PathValidated", reflect.TypeOf((*MockConnectionTracer)(nil).PathValidated), arg0, arg1)
+}
+
 // ReceivedPacket mocks base method
-func (m *MockConnectionTracer) ReceivedPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 []logging.Frame) {
+func (m *MockConnectionTracer) ReceivedPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 time.Time, arg3 []logging.Frame) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "ReceivedPacket", arg0, arg1, arg2)
+	m.ctrl.Call(m, "ReceivedPacket", arg0, arg1, arg2, arg3)
 }
 
 // ReceivedPacket indicates an expected call of ReceivedPacket
-func (mr *MockConnectionTracerMockRecorder) ReceivedPacket(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockConnectionTracerMockRecorder) ReceivedPacket(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedPacket), arg0, arg1, arg2, arg3)
+}
+
+// ReceivedResetStream mocks base method
+func (m *MockConnectionTracer) ReceivedResetStream(arg0 protocol.StreamID, arg1 protocol.ApplicationErrorCode, arg2 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedResetStream", arg0, arg1, arg2)
+}
+
+// ReceivedResetStream indicates an expected call of ReceivedResetStream
+func (mr *MockConnectionTracerMockRecorder) ReceivedResetStream(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedPacket), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedResetStream", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedResetStream), arg0, arg1, arg2)
 }
 
 // ReceivedRetry mocks base method
@@ -171,6 +243,18 @@ func (mr *MockConnectionTracerMockRecorder) ReceivedRetry(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedRetry", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedRetry), arg0)
 }
 
+// ReceivedStopSending mocks base method
+func (m *MockConnectionTracer) ReceivedStopSending(arg0 protocol.StreamID, arg1 protocol.ApplicationErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedStopSending", arg0, arg1)
+}
+
+// ReceivedStopSending indicates an expected call of ReceivedStopSending
+func (mr *MockConnectionTracerMockRecorder) ReceivedStopSending(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedStopSending", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedStopSending), arg0, arg1)
+}
+
 // ReceivedTransportParameters mocks base method
 func (m *MockConnectionTracer) ReceivedTransportParameters(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
@@ -195,16 +279,52 @@ func (mr *MockConnectionTracerMockRecorder) ReceivedVersionNegotiationPacket(arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedVersionNegotiationPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedVersionNegotiationPacket), arg0, arg1)
 }
 
+// RejectedEarlyData mocks base method
+func (m *MockConnectionTracer) RejectedEarlyData() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RejectedEarlyData")
+}
+
+// RejectedEarlyData indicates an expected call of RejectedEarlyData
+func (mr *MockConnectionTracerMockRecorder) RejectedEarlyData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectedEarlyData", reflect.TypeOf((*MockConnectionTracer)(nil).RejectedEarlyData))
+}
+
+// RetiredConnectionID mocks base method
+func (m *MockConnectionTracer) RetiredConnectionID(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RetiredConnectionID", arg0)
+}
+
+// RetiredConnectionID indicates an expected call of RetiredConnectionID
+func (mr *MockConnectionTracerMockRecorder) RetiredConnectionID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetiredConnectionID", reflect.TypeOf((*MockConnectionTracer)(nil).RetiredConnectionID), arg0)
+}
+
+// SentDatagram mocks base method
+func (m *MockConnectionTracer) SentDatagram(arg0 protocol.ByteCount, arg1 []*wire.ExtendedHeader) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SentDatagram", arg0, arg1)
+}
+
+// SentDatagram indicates an expected call of SentDatagram
+func (mr *MockConnectionTracerMockRecorder) SentDatagram(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).SentDatagram), arg0, arg1)
+}
+
 // SentPacket mocks base method
-func (m *MockConnectionTracer) SentPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 *wire.AckFrame, arg3 []logging.Frame) {
+func (m *MockConnectionTracer) SentPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 *wire.AckFrame, arg3 []logging.Frame, arg4 logging.PacketSendReason) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SentPacket", arg0, arg1, arg2, arg3)
+	m.ctrl.Call(m, "SentPacket", arg0, arg1, arg2, arg3, arg4)
 }
 
 // SentPacket indicates an expected call of SentPacket
-func (mr *MockConnectionTracerMockRecorder) SentPacket(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+func (mr *MockConnectionTracerMockRecorder) SentPacket(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockConnectionTracer)(nil).SentPacket), arg0, arg1, arg2, arg3)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockConnectionTracer)(nil).SentPacket), arg0, arg1, arg2, arg3, arg4)
 }
 
 // SentTransportParameters mocks base method
@@ -231,6 +351,30 @@ func (mr *MockConnectionTracerMockRecorder) SetLossTimer(arg0, arg1, arg2 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLossTimer", reflect.TypeOf((*MockConnectionTracer)(nil).SetLossTimer), arg0, arg1, arg2)
 }
 
+// SetPacingTimer mocks base method
+func (m *MockConnectionTracer) SetPacingTimer(arg0 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacingTimer", arg0)
+}
+
+// SetPacingTimer indicates an expected call of SetPacingTimer
+func (mr *MockConnectionTracerMockRecorder) SetPacingTimer(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacingTimer", reflect.TypeOf((*MockConnectionTracer)(nil).SetPacingTimer), arg0)
+}
+
+// SpuriousLoss mocks base method
+func (m *MockConnectionTracer) SpuriousLoss(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SpuriousLoss", arg0, arg1)
+}
+
+// SpuriousLoss indicates an expected call of SpuriousLoss
+func (mr *MockConnectionTracerMockRecorder) SpuriousLoss(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpuriousLoss", reflect.TypeOf((*MockConnectionTracer)(nil).SpuriousLoss), arg0, arg1)
+}
+
 // StartedConnection mocks base method
 func (m *MockConnectionTracer) StartedConnection(arg0, arg1 net.Addr, arg2 protocol.VersionNumber, arg3, arg4 protocol.ConnectionID) {
 	m.ctrl.T.Helper()
@@ -8,10 +8,12 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
+	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
 
 // MockEarlySession is a mock of EarlySession interface
@@ -67,6 +69,20 @@ func (mr *MockEarlySessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockEarlySession)(nil).AcceptUniStream), arg0)
 }
 
+// ActiveStreams mocks base method
+func (m *MockEarlySession) ActiveStreams() []quic.StreamInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveStreams")
+	ret0, _ := ret[0].([]quic.StreamInfo)
+	return ret0
+}
+
+// ActiveStreams indicates an expected call of ActiveStreams
+func (mr *MockEarlySessionMockRecorder) ActiveStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveStreams", reflect.TypeOf((*MockEarlySession)(nil).ActiveStreams))
+}
+
 // CloseWithError mocks base method
 func (m *MockEarlySession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -81,6 +97,34 @@ func (mr *MockEarlySessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockEarlySession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithErrorSync mocks base method
+func (m *MockEarlySession) CloseWithErrorSync(arg0 context.Context, arg1 protocol.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithErrorSync", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithErrorSync indicates an expected call of CloseWithErrorSync
+func (mr *MockEarlySessionMockRecorder) CloseWithErrorSync(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithErrorSync", reflect.TypeOf((*MockEarlySession)(nil).CloseWithErrorSync), arg0, arg1, arg2)
+}
+
+// CloseWithTransportError mocks base method
+func (m *MockEarlySession) CloseWithTransportError(arg0 qerr.ErrorCode, arg1 uint64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithTransportError", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithTransportError indicates an expected call of CloseWithTransportError
+func (mr *MockEarlySessionMockRecorder) CloseWithTransportError(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithTransportError", reflect.TypeOf((*MockEarlySession)(nil).CloseWithTransportError), arg0, arg1, arg2)
+}
+
 // ConnectionState mocks base method
 func (m *MockEarlySession) ConnectionState() quic.ConnectionState {
 	m.ctrl.T.Helper()
@@ -109,6 +153,48 @@ func (mr *MockEarlySessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockEarlySession)(nil).Context))
 }
 
+// CurrentPath mocks base method
+func (m *MockEarlySession) CurrentPath() quic.PathInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentPath")
+	ret0, _ := ret[0].(quic.PathInfo)
+	return ret0
+}
+
+// CurrentPath indicates an expected call of CurrentPath
+func (mr *MockEarlySessionMockRecorder) CurrentPath() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentPath", reflect.TypeOf((*MockEarlySession)(nil).CurrentPath))
+}
+
+// EarlyDataAccepted mocks base method
+func (m *MockEarlySession) EarlyDataAccepted() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyDataAccepted")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// EarlyDataAccepted indicates an expected call of EarlyDataAccepted
+func (mr *MockEarlySessionMockRecorder) EarlyDataAccepted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyDataAccepted", reflect.TypeOf((*MockEarlySession)(nil).EarlyDataAccepted))
+}
+
+// ForceKeyUpdate mocks base method
+func (m *MockEarlySession) ForceKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceKeyUpdate indicates an expected call of ForceKeyUpdate
+func (mr *MockEarlySessionMockRecorder) ForceKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceKeyUpdate", reflect.TypeOf((*MockEarlySession)(nil).ForceKeyUpdate))
+}
+
 // HandshakeComplete mocks base method
 func (m *MockEarlySession) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -123,6 +209,20 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method
+func (m *MockEarlySession) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed
+func (mr *MockEarlySessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockEarlySession)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -137,6 +237,48 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// Max0RTTSize mocks base method
+func (m *MockEarlySession) Max0RTTSize() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Max0RTTSize")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// Max0RTTSize indicates an expected call of Max0RTTSize
+func (mr *MockEarlySessionMockRecorder) Max0RTTSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Max0RTTSize", reflect.TypeOf((*MockEarlySession)(nil).Max0RTTSize))
+}
+
+// MigratePath mocks base method
+func (m *MockEarlySession) MigratePath(arg0 context.Context, arg1 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigratePath", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigratePath indicates an expected call of MigratePath
+func (mr *MockEarlySessionMockRecorder) MigratePath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratePath", reflect.TypeOf((*MockEarlySession)(nil).MigratePath), arg0, arg1)
+}
+
+// NegotiatedProtocol mocks base method
+func (m *MockEarlySession) NegotiatedProtocol() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NegotiatedProtocol")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NegotiatedProtocol indicates an expected call of NegotiatedProtocol
+func (mr *MockEarlySessionMockRecorder) NegotiatedProtocol() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedProtocol", reflect.TypeOf((*MockEarlySession)(nil).NegotiatedProtocol))
+}
+
 // OpenStream mocks base method
 func (m *MockEarlySession) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -210,3 +352,43 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
+
+// SendPing mocks base method
+func (m *MockEarlySession) SendPing() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPing")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPing indicates an expected call of SendPing
+func (mr *MockEarlySessionMockRecorder) SendPing() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPing", reflect.TypeOf((*MockEarlySession)(nil).SendPing))
+}
+
+// SetAcceptDeadline mocks base method
+func (m *MockEarlySession) SetAcceptDeadline(arg0 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAcceptDeadline", arg0)
+}
+
+// SetAcceptDeadline indicates an expected call of SetAcceptDeadline
+func (mr *MockEarlySessionMockRecorder) SetAcceptDeadline(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAcceptDeadline", reflect.TypeOf((*MockEarlySession)(nil).SetAcceptDeadline), arg0)
+}
+
+// Stats mocks base method
+func (m *MockEarlySession) Stats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockEarlySessionMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockEarlySession)(nil).Stats))
+}
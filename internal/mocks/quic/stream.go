@@ -36,6 +36,20 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method
+func (m *MockStream) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes
+func (mr *MockStreamMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockStream)(nil).BufferedBytes))
+}
+
 // CancelRead mocks base method
 func (m *MockStream) CancelRead(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -88,6 +102,20 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// Flush mocks base method
+func (m *MockStream) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush
+func (mr *MockStreamMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStream)(nil).Flush))
+}
+
 // Read mocks base method
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -103,6 +131,21 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadAvailable mocks base method
+func (m *MockStream) ReadAvailable(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAvailable", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAvailable indicates an expected call of ReadAvailable
+func (mr *MockStreamMockRecorder) ReadAvailable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAvailable", reflect.TypeOf((*MockStream)(nil).ReadAvailable), arg0)
+}
+
 // SetDeadline mocks base method
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -117,6 +160,30 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetNonBlocking mocks base method
+func (m *MockStream) SetNonBlocking(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNonBlocking", arg0)
+}
+
+// SetNonBlocking indicates an expected call of SetNonBlocking
+func (mr *MockStreamMockRecorder) SetNonBlocking(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNonBlocking", reflect.TypeOf((*MockStream)(nil).SetNonBlocking), arg0)
+}
+
+// SetPriority mocks base method
+func (m *MockStream) SetPriority(arg0 byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockStreamMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStream)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -159,6 +226,20 @@ func (mr *MockStreamMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStream)(nil).StreamID))
 }
 
+// WritableChan mocks base method
+func (m *MockStream) WritableChan() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WritableChan")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// WritableChan indicates an expected call of WritableChan
+func (mr *MockStreamMockRecorder) WritableChan() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WritableChan", reflect.TypeOf((*MockStream)(nil).WritableChan))
+}
+
 // Write mocks base method
 func (m *MockStream) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
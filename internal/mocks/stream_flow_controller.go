@@ -113,6 +113,20 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// UpdateBufferedBytes mocks base method
+func (m *MockStreamFlowController) UpdateBufferedBytes(arg0 protocol.ByteCount) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateBufferedBytes", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateBufferedBytes indicates an expected call of UpdateBufferedBytes
+func (mr *MockStreamFlowControllerMockRecorder) UpdateBufferedBytes(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateBufferedBytes", reflect.TypeOf((*MockStreamFlowController)(nil).UpdateBufferedBytes), arg0)
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
@@ -14,22 +14,27 @@ const maxConnectionIDLen = 18
 
 // GenerateConnectionID generates a connection ID using cryptographic random
 func GenerateConnectionID(len int) (ConnectionID, error) {
+	return GenerateConnectionIDFrom(rand.Reader, len)
+}
+
+// GenerateConnectionIDFrom generates a connection ID, reading the random bytes from r.
+func GenerateConnectionIDFrom(r io.Reader, len int) (ConnectionID, error) {
 	b := make([]byte, len)
-	if _, err := rand.Read(b); err != nil {
+	if _, err := io.ReadFull(r, b); err != nil {
 		return nil, err
 	}
 	return ConnectionID(b), nil
 }
 
-// GenerateConnectionIDForInitial generates a connection ID for the Initial packet.
+// GenerateConnectionIDForInitial generates a connection ID for the Initial packet, reading the random bytes from r.
 // It uses a length randomly chosen between 8 and 18 bytes.
-func GenerateConnectionIDForInitial() (ConnectionID, error) {
-	r := make([]byte, 1)
-	if _, err := rand.Read(r); err != nil {
+func GenerateConnectionIDForInitial(r io.Reader) (ConnectionID, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(r, b); err != nil {
 		return nil, err
 	}
-	len := MinConnectionIDLenInitial + int(r[0])%(maxConnectionIDLen-MinConnectionIDLenInitial+1)
-	return GenerateConnectionID(len)
+	len := MinConnectionIDLenInitial + int(b[0])%(maxConnectionIDLen-MinConnectionIDLenInitial+1)
+	return GenerateConnectionIDFrom(r, len)
 }
 
 // ReadConnectionID reads a connection ID of length len from the given io.Reader.
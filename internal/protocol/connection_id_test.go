@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"bytes"
+	"crypto/rand"
 	"io"
 
 	. "github.com/onsi/ginkgo"
@@ -24,10 +25,26 @@ var _ = Describe("Connection ID generation", func() {
 		Expect(c.Len()).To(Equal(5))
 	})
 
+	It("generates connection IDs using the given random source", func() {
+		c, err := GenerateConnectionIDFrom(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6}), 5)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(ConnectionID{1, 2, 3, 4, 5}))
+		_, err = GenerateConnectionIDFrom(bytes.NewReader([]byte{1, 2, 3}), 5)
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	It("generates destination connection IDs using the given random source", func() {
+		// The first byte determines the length: 8 + 3 % 11 = 11 bytes.
+		r := bytes.NewReader(append([]byte{3}, bytes.Repeat([]byte{0x42}, 20)...))
+		c, err := GenerateConnectionIDForInitial(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(ConnectionID(bytes.Repeat([]byte{0x42}, 11))))
+	})
+
 	It("generates random length destination connection IDs", func() {
 		var has8ByteConnID, has18ByteConnID bool
 		for i := 0; i < 1000; i++ {
-			c, err := GenerateConnectionIDForInitial()
+			c, err := GenerateConnectionIDForInitial(rand.Reader)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(c.Len()).To(BeNumerically(">=", 8))
 			Expect(c.Len()).To(BeNumerically("<=", 18))
@@ -95,6 +95,10 @@ const MinRemoteIdleTimeout = 5 * time.Second
 // DefaultIdleTimeout is the default idle timeout
 const DefaultIdleTimeout = 30 * time.Second
 
+// DefaultAmplificationFactor is the factor by which the server may exceed the number of bytes received from the client,
+// before the client's address is validated.
+const DefaultAmplificationFactor = 3
+
 // DefaultHandshakeTimeout is the default timeout for a connection until the crypto handshake succeeds.
 const DefaultHandshakeTimeout = 10 * time.Second
 
@@ -136,7 +140,7 @@ const MinPacingDelay = time.Millisecond
 // if no other value is configured.
 const DefaultConnectionIDLength = 4
 
-// MaxActiveConnectionIDs is the number of connection IDs that we're storing.
+// MaxActiveConnectionIDs is the default number of connection IDs that we're storing.
 const MaxActiveConnectionIDs = 4
 
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
@@ -149,6 +153,10 @@ const PacketsPerConnectionID = 10000
 // AckDelayExponent is the ack delay exponent used when sending ACKs.
 const AckDelayExponent = 3
 
+// MinInitialRTT is the minimum value that can be configured for the initial RTT.
+// Smaller values would lead to spurious retransmissions during the handshake.
+const MinInitialRTT = 5 * time.Millisecond
+
 // Estimated timer granularity.
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
@@ -160,6 +168,14 @@ const MaxAckDelay = 25 * time.Millisecond
 // This is the value that should be advertised to the peer.
 const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 
+// MinAckDelay is the min_ack_delay we advertise when the ACK frequency extension is enabled.
+// It is the smallest max_ack_delay the peer can request using an ACK_FREQUENCY frame.
+const MinAckDelay = TimerGranularity
+
+// AckFrequencyPacketTolerance is the packet tolerance we request from the peer using an ACK_FREQUENCY frame.
+// The peer will only send an ACK after receiving this many ack-eliciting packets (or when the max_ack_delay expires).
+const AckFrequencyPacketTolerance = 10
+
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key udpate.
 const KeyUpdateInterval = 100 * 1000
 
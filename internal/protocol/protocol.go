@@ -34,6 +34,32 @@ func (t PacketType) String() string {
 	}
 }
 
+// ECN is the ECN value
+type ECN uint8
+
+// The ECN codepoints, as defined in RFC 3168.
+const (
+	ECNNon ECN = iota // 00
+	ECT1              // 01
+	ECT0              // 10
+	ECNCE             // 11
+)
+
+func (e ECN) String() string {
+	switch e {
+	case ECNNon:
+		return "Not-ECT"
+	case ECT1:
+		return "ECT(1)"
+	case ECT0:
+		return "ECT(0)"
+	case ECNCE:
+		return "CE"
+	default:
+		return fmt.Sprintf("invalid ECN value: %d", e)
+	}
+}
+
 // A ByteCount in QUIC
 type ByteCount uint64
 
@@ -55,6 +81,9 @@ const MaxReceivePacketSize ByteCount = 1452
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
+// MaxUDPPayloadSize is the largest valid value of the max_udp_payload_size transport parameter.
+const MaxUDPPayloadSize ByteCount = 65527
+
 // MinStatelessResetSize is the minimum size of a stateless reset packet that we send
 const MinStatelessResetSize = 1 /* first byte */ + 20 /* max. conn ID length */ + 4 /* max. packet number length */ + 1 /* min. payload length */ + 16 /* token */
 
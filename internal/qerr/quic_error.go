@@ -10,6 +10,7 @@ type QuicError struct {
 	ErrorCode          ErrorCode
 	FrameType          uint64 // only valid if this not an application error
 	ErrorMessage       string
+	Remote             bool // set if the peer closed the connection with a CONNECTION_CLOSE frame
 	isTimeout          bool
 	isApplicationError bool
 }
@@ -0,0 +1,221 @@
+package qtls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// clientSessionState has the same layout as the tls.ClientSessionState.
+// This allows us to access private fields of the tls.ClientSessionState,
+// in order to serialize it.
+// We check in init() that this conversion actually is safe.
+type clientSessionState struct {
+	sessionTicket      []uint8               // Encrypted ticket used for session resumption with server
+	vers               uint16                // TLS version negotiated for the session
+	cipherSuite        uint16                // Ciphersuite negotiated for the session
+	masterSecret       []byte                // Full handshake MasterSecret, or TLS 1.3 resumption_master_secret
+	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
+	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+	receivedAt         time.Time             // When the session ticket was received from the server
+	ocspResponse       []byte                // Stapled OCSP response presented by the server
+	scts               [][]byte              // SCTs presented by the server
+
+	// TLS 1.3 fields.
+	nonce  []byte    // Ticket nonce sent by the server, to derive PSK
+	useBy  time.Time // Expiration of the ticket lifetime as set by the server
+	ageAdd uint32    // Random obfuscation factor for sending the ticket age
+}
+
+func init() {
+	if !structsEqual(&tls.ClientSessionState{}, &clientSessionState{}) {
+		panic("clientSessionState not compatible with tls.ClientSessionState")
+	}
+}
+
+const clientSessionStateSerializationRevision = 1
+
+// MarshalClientSessionState serializes a tls.ClientSessionState.
+func MarshalClientSessionState(state *tls.ClientSessionState) []byte {
+	s := (*clientSessionState)(unsafe.Pointer(state))
+	b := &bytes.Buffer{}
+	utils.WriteVarInt(b, clientSessionStateSerializationRevision)
+	writeBytes(b, s.sessionTicket)
+	utils.WriteVarInt(b, uint64(s.vers))
+	utils.WriteVarInt(b, uint64(s.cipherSuite))
+	writeBytes(b, s.masterSecret)
+	writeCertificates(b, s.serverCertificates)
+	utils.WriteVarInt(b, uint64(len(s.verifiedChains)))
+	for _, chain := range s.verifiedChains {
+		writeCertificates(b, chain)
+	}
+	writeTime(b, s.receivedAt)
+	writeBytes(b, s.ocspResponse)
+	utils.WriteVarInt(b, uint64(len(s.scts)))
+	for _, sct := range s.scts {
+		writeBytes(b, sct)
+	}
+	writeBytes(b, s.nonce)
+	writeTime(b, s.useBy)
+	utils.WriteVarInt(b, uint64(s.ageAdd))
+	return b.Bytes()
+}
+
+// UnmarshalClientSessionState parses a tls.ClientSessionState serialized by MarshalClientSessionState.
+func UnmarshalClientSessionState(data []byte) (*tls.ClientSessionState, error) {
+	r := bytes.NewReader(data)
+	rev, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, errors.New("failed to read session state revision")
+	}
+	if rev != clientSessionStateSerializationRevision {
+		return nil, fmt.Errorf("unknown session state revision: %d", rev)
+	}
+	s := &clientSessionState{}
+	if s.sessionTicket, err = readBytes(r); err != nil {
+		return nil, errors.New("failed to read session ticket")
+	}
+	vers, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, errors.New("failed to read TLS version")
+	}
+	s.vers = uint16(vers)
+	cipherSuite, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, errors.New("failed to read cipher suite")
+	}
+	s.cipherSuite = uint16(cipherSuite)
+	if s.masterSecret, err = readBytes(r); err != nil {
+		return nil, errors.New("failed to read master secret")
+	}
+	if s.serverCertificates, err = readCertificates(r); err != nil {
+		return nil, fmt.Errorf("failed to read server certificates: %s", err)
+	}
+	numChains, err := utils.ReadVarInt(r)
+	if err != nil || numChains > uint64(r.Len()) {
+		return nil, errors.New("failed to read verified chains")
+	}
+	if numChains > 0 {
+		s.verifiedChains = make([][]*x509.Certificate, numChains)
+		for i := range s.verifiedChains {
+			if s.verifiedChains[i], err = readCertificates(r); err != nil {
+				return nil, fmt.Errorf("failed to read verified chains: %s", err)
+			}
+		}
+	}
+	if s.receivedAt, err = readTime(r); err != nil {
+		return nil, errors.New("failed to read receive time")
+	}
+	if s.ocspResponse, err = readBytes(r); err != nil {
+		return nil, errors.New("failed to read OCSP response")
+	}
+	numSCTs, err := utils.ReadVarInt(r)
+	if err != nil || numSCTs > uint64(r.Len()) {
+		return nil, errors.New("failed to read SCTs")
+	}
+	if numSCTs > 0 {
+		s.scts = make([][]byte, numSCTs)
+		for i := range s.scts {
+			if s.scts[i], err = readBytes(r); err != nil {
+				return nil, errors.New("failed to read SCTs")
+			}
+		}
+	}
+	if s.nonce, err = readBytes(r); err != nil {
+		return nil, errors.New("failed to read nonce")
+	}
+	if s.useBy, err = readTime(r); err != nil {
+		return nil, errors.New("failed to read ticket expiry")
+	}
+	ageAdd, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, errors.New("failed to read ticket age add")
+	}
+	s.ageAdd = uint32(ageAdd)
+	if r.Len() > 0 {
+		return nil, errors.New("trailing data after session state")
+	}
+	return (*tls.ClientSessionState)(unsafe.Pointer(s)), nil
+}
+
+func writeBytes(b *bytes.Buffer, data []byte) {
+	utils.WriteVarInt(b, uint64(len(data)))
+	b.Write(data)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	l, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	if l == 0 {
+		return nil, nil
+	}
+	data := make([]byte, l)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func writeCertificates(b *bytes.Buffer, certs []*x509.Certificate) {
+	utils.WriteVarInt(b, uint64(len(certs)))
+	for _, cert := range certs {
+		writeBytes(b, cert.Raw)
+	}
+}
+
+func readCertificates(r *bytes.Reader) ([]*x509.Certificate, error) {
+	num, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if num > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	if num == 0 {
+		return nil, nil
+	}
+	certs := make([]*x509.Certificate, num)
+	for i := range certs {
+		raw, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		if certs[i], err = x509.ParseCertificate(raw); err != nil {
+			return nil, err
+		}
+	}
+	return certs, nil
+}
+
+// The time is encoded as the number of nanoseconds since the Unix epoch.
+// The zero value of time.Time is encoded as 0.
+func writeTime(b *bytes.Buffer, t time.Time) {
+	if t.IsZero() {
+		utils.WriteVarInt(b, 0)
+		return
+	}
+	utils.WriteVarInt(b, uint64(t.UnixNano()))
+}
+
+func readTime(r *bytes.Reader) (time.Time, error) {
+	ns, err := utils.ReadVarInt(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ns == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, int64(ns)), nil
+}
@@ -0,0 +1,95 @@
+package qtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client Session State", func() {
+	getCertificate := func() *x509.Certificate {
+		cert, err := x509.ParseCertificate(testdata.GetTLSConfig().Certificates[0].Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		return cert
+	}
+
+	It("serializes and deserializes", func() {
+		cert := getCertificate()
+		s := &clientSessionState{
+			sessionTicket:      []byte("ticket"),
+			vers:               tls.VersionTLS13,
+			cipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			masterSecret:       []byte("secret"),
+			serverCertificates: []*x509.Certificate{cert},
+			verifiedChains:     [][]*x509.Certificate{{cert}, {cert, cert}},
+			receivedAt:         time.Unix(0, time.Now().UnixNano()),
+			ocspResponse:       []byte("ocsp"),
+			scts:               [][]byte{[]byte("foo"), []byte("bar")},
+			nonce:              []byte("nonce"),
+			useBy:              time.Unix(0, time.Now().Add(time.Hour).UnixNano()),
+			ageAdd:             1337,
+		}
+		data := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(s)))
+		state, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		s2 := (*clientSessionState)(unsafe.Pointer(state))
+		Expect(s2.sessionTicket).To(Equal(s.sessionTicket))
+		Expect(s2.vers).To(Equal(s.vers))
+		Expect(s2.cipherSuite).To(Equal(s.cipherSuite))
+		Expect(s2.masterSecret).To(Equal(s.masterSecret))
+		Expect(s2.serverCertificates).To(HaveLen(1))
+		Expect(s2.serverCertificates[0].Equal(cert)).To(BeTrue())
+		Expect(s2.verifiedChains).To(HaveLen(2))
+		Expect(s2.verifiedChains[0]).To(HaveLen(1))
+		Expect(s2.verifiedChains[1]).To(HaveLen(2))
+		Expect(s2.verifiedChains[1][1].Equal(cert)).To(BeTrue())
+		Expect(s2.receivedAt).To(BeTemporally("==", s.receivedAt))
+		Expect(s2.ocspResponse).To(Equal(s.ocspResponse))
+		Expect(s2.scts).To(Equal(s.scts))
+		Expect(s2.nonce).To(Equal(s.nonce))
+		Expect(s2.useBy).To(BeTemporally("==", s.useBy))
+		Expect(s2.ageAdd).To(Equal(s.ageAdd))
+	})
+
+	It("serializes and deserializes empty fields", func() {
+		data := MarshalClientSessionState(&tls.ClientSessionState{})
+		state, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*state).To(Equal(tls.ClientSessionState{}))
+	})
+
+	It("rejects an unknown revision", func() {
+		data := MarshalClientSessionState(&tls.ClientSessionState{})
+		data[0] = clientSessionStateSerializationRevision + 1
+		_, err := UnmarshalClientSessionState(data)
+		Expect(err).To(MatchError("unknown session state revision: 2"))
+	})
+
+	It("errors when the data is too short", func() {
+		s := &clientSessionState{
+			sessionTicket:      []byte("ticket"),
+			serverCertificates: []*x509.Certificate{getCertificate()},
+			nonce:              []byte("nonce"),
+			ageAdd:             1337,
+		}
+		data := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(s)))
+		_, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < len(data); i++ {
+			_, err := UnmarshalClientSessionState(data[:i])
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	It("errors on trailing data", func() {
+		data := MarshalClientSessionState(&tls.ClientSessionState{})
+		_, err := UnmarshalClientSessionState(append(data, 0))
+		Expect(err).To(MatchError("trailing data after session state"))
+	})
+})
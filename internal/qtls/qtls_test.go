@@ -4,6 +4,7 @@ package qtls
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"net"
 	"unsafe"
@@ -106,6 +107,25 @@ var _ = Describe("Config", func() {
 			Expect(received).To(BeTrue())
 		})
 
+		It("preserves the client auth settings of the returned Config", func() {
+			var verified bool
+			tlsConf := &tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+					return &tls.Config{
+						ClientAuth:            tls.RequireAndVerifyClientCert,
+						VerifyPeerCertificate: func([][]byte, [][]*x509.Certificate) error { verified = true; return nil },
+					}, nil
+				},
+			}
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil)
+			confForClient, err := qtlsConf.GetConfigForClient(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confForClient.ClientAuth).To(Equal(tls.RequireAndVerifyClientCert))
+			Expect(confForClient.VerifyPeerCertificate).ToNot(BeNil())
+			Expect(confForClient.VerifyPeerCertificate(nil, nil)).To(Succeed())
+			Expect(verified).To(BeTrue())
+		})
+
 		It("returns errors", func() {
 			testErr := errors.New("test")
 			tlsConf := &tls.Config{
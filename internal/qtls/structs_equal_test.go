@@ -1,5 +1,3 @@
-// +build !go1.15
-
 package qtls
 
 import (
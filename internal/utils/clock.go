@@ -0,0 +1,49 @@
+package utils
+
+import "time"
+
+// A Clock provides the current time and creates timers.
+// All time-based logic of a connection uses it, which allows tests and simulations
+// to control the passage of time.
+type Clock interface {
+	Now() time.Time
+	// NewTimer creates a timer that sends the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) ClockTimer
+	// AfterFunc waits for at least duration d and then calls f.
+	// Like for time.AfterFunc, the channel of the returned timer is not used.
+	AfterFunc(d time.Duration, f func()) ClockTimer
+}
+
+// A ClockTimer is a timer created by a Clock.
+// It behaves like a time.Timer.
+type ClockTimer interface {
+	Chan() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// DefaultClock implements the Clock interface using the Go stdlib clock.
+type DefaultClock struct{}
+
+var _ Clock = DefaultClock{}
+
+// Now gets the current time
+func (DefaultClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer creates a time.Timer
+func (DefaultClock) NewTimer(d time.Duration) ClockTimer {
+	return &systemTimer{Timer: time.NewTimer(d)}
+}
+
+// AfterFunc calls time.AfterFunc
+func (DefaultClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	return &systemTimer{Timer: time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t *systemTimer) Chan() <-chan time.Time { return t.C }
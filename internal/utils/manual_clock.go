@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A ManualClock is a Clock that only advances when Advance is called.
+// It is used to run time-based logic deterministically, independent of the wall clock.
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	// the timers that are currently active
+	timers map[*manualTimer]struct{}
+	// used to fire timers with the same deadline in the order they were set
+	nextSeq uint64
+}
+
+var _ Clock = &ManualClock{}
+
+// NewManualClock creates a new ManualClock, starting at the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{
+		now:    now,
+		timers: make(map[*manualTimer]struct{}),
+	}
+}
+
+// Now gets the current time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires when the clock is advanced by at least d.
+func (c *ManualClock) NewTimer(d time.Duration) ClockTimer {
+	t := &manualTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// AfterFunc creates a timer that calls f when the clock is advanced by at least d.
+// f is called synchronously by Advance, unless d is not positive, in which case it is called in a separate Go routine.
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) ClockTimer {
+	t := &manualTimer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Advance advances the clock by d, and fires all timers that expire, in the order of their deadlines.
+// Functions registered using AfterFunc are called after the clock was advanced, without holding any locks.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.now = c.now.Add(d)
+	var expired []*manualTimer
+	for t := range c.timers {
+		if !t.deadline.After(c.now) {
+			expired = append(expired, t)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if expired[i].deadline.Equal(expired[j].deadline) {
+			return expired[i].seq < expired[j].seq
+		}
+		return expired[i].deadline.Before(expired[j].deadline)
+	})
+	var funcs []func()
+	for _, t := range expired {
+		delete(c.timers, t)
+		if t.f != nil {
+			funcs = append(funcs, t.f)
+		} else {
+			t.send(c.now)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, f := range funcs {
+		f()
+	}
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	c     chan time.Time // nil for timers created using AfterFunc
+	f     func()         // nil for timers created using NewTimer
+
+	// deadline and seq are protected by the clock's mutex
+	deadline time.Time
+	seq      uint64
+}
+
+func (t *manualTimer) Chan() <-chan time.Time { return t.c }
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, wasActive := c.timers[t]
+	if d <= 0 {
+		delete(c.timers, t)
+		if t.f != nil {
+			go t.f()
+		} else {
+			t.send(c.now)
+		}
+		return wasActive
+	}
+	t.deadline = c.now.Add(d)
+	t.seq = c.nextSeq
+	c.nextSeq++
+	c.timers[t] = struct{}{}
+	return wasActive
+}
+
+func (t *manualTimer) Stop() bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, wasActive := c.timers[t]
+	delete(c.timers, t)
+	return wasActive
+}
+
+func (t *manualTimer) send(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manual Clock", func() {
+	var (
+		clock *ManualClock
+		start time.Time
+	)
+
+	BeforeEach(func() {
+		start = time.Now()
+		clock = NewManualClock(start)
+	})
+
+	It("only advances when told to", func() {
+		Expect(clock.Now()).To(Equal(start))
+		time.Sleep(time.Millisecond)
+		Expect(clock.Now()).To(Equal(start))
+		clock.Advance(time.Hour)
+		Expect(clock.Now()).To(Equal(start.Add(time.Hour)))
+	})
+
+	It("fires timers when the clock is advanced", func() {
+		t := clock.NewTimer(time.Minute)
+		clock.Advance(time.Minute - time.Nanosecond)
+		Expect(t.Chan()).ToNot(Receive())
+		clock.Advance(time.Nanosecond)
+		Expect(t.Chan()).To(Receive(Equal(start.Add(time.Minute))))
+		clock.Advance(time.Hour)
+		Expect(t.Chan()).ToNot(Receive())
+	})
+
+	It("fires timers that are reset into the past right away", func() {
+		t := clock.NewTimer(time.Minute)
+		Expect(t.Stop()).To(BeTrue())
+		Expect(t.Reset(-time.Second)).To(BeFalse())
+		Expect(t.Chan()).To(Receive(Equal(start)))
+	})
+
+	It("stops timers", func() {
+		t := clock.NewTimer(time.Minute)
+		Expect(t.Stop()).To(BeTrue())
+		Expect(t.Stop()).To(BeFalse())
+		clock.Advance(time.Hour)
+		Expect(t.Chan()).ToNot(Receive())
+	})
+
+	It("resets timers", func() {
+		t := clock.NewTimer(time.Minute)
+		Expect(t.Reset(time.Hour)).To(BeTrue())
+		clock.Advance(time.Minute)
+		Expect(t.Chan()).ToNot(Receive())
+		clock.Advance(time.Hour)
+		Expect(t.Chan()).To(Receive())
+	})
+
+	It("calls functions when the clock is advanced", func() {
+		var called []int
+		clock.AfterFunc(2*time.Minute, func() { called = append(called, 2) })
+		clock.AfterFunc(time.Minute, func() { called = append(called, 1) })
+		t := clock.AfterFunc(time.Minute, func() { called = append(called, 3) })
+		Expect(t.Chan()).To(BeNil())
+		clock.Advance(time.Minute - time.Nanosecond)
+		Expect(called).To(BeEmpty())
+		clock.Advance(time.Hour)
+		// timers are fired in the order of their deadlines
+		Expect(called).To(Equal([]int{1, 3, 2}))
+	})
+
+	It("stops and resets functions", func() {
+		var called int
+		t := clock.AfterFunc(time.Minute, func() { called++ })
+		Expect(t.Stop()).To(BeTrue())
+		clock.Advance(time.Hour)
+		Expect(called).To(BeZero())
+		Expect(t.Reset(time.Minute)).To(BeFalse())
+		clock.Advance(time.Minute)
+		Expect(called).To(Equal(1))
+	})
+
+	It("calls functions that are reset into the past right away", func() {
+		done := make(chan struct{})
+		t := clock.AfterFunc(time.Minute, func() { close(done) })
+		t.Reset(0)
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -84,4 +84,13 @@ var _ = Describe("Timer", func() {
 		t.Stop()
 		Consistently(t.Chan()).ShouldNot(Receive())
 	})
+
+	It("uses the clock", func() {
+		clock := NewManualClock(time.Now())
+		t := NewTimerWithClock(clock)
+		t.Reset(clock.Now().Add(time.Hour))
+		Consistently(t.Chan(), d).ShouldNot(Receive())
+		clock.Advance(time.Hour)
+		Eventually(t.Chan()).Should(Receive())
+	})
 })
@@ -0,0 +1,97 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_FREQUENCY frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...) // sequence number
+			data = append(data, encodeVarInt(0xcafe)...)     // packet tolerance
+			data = append(data, encodeVarInt(1337)...)       // update max ack delay, in µs
+			data = append(data, 1)                           // ignore order
+			b := bytes.NewReader(data)
+			frame, err := parseAckFrequencyFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(frame.PacketTolerance).To(Equal(uint64(0xcafe)))
+			Expect(frame.UpdateMaxAckDelay).To(Equal(1337 * time.Microsecond))
+			Expect(frame.IgnoreOrder).To(BeTrue())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("limits the max ack delay", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(1<<61)...)
+			data = append(data, 0)
+			frame, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.UpdateMaxAckDelay).To(Equal(protocol.MaxMaxAckDelay))
+			Expect(frame.IgnoreOrder).To(BeFalse())
+		})
+
+		It("errors on a packet tolerance of 0", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(0)...)
+			data = append(data, encodeVarInt(1000)...)
+			data = append(data, 0)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid packet tolerance: 0"))
+		})
+
+		It("errors on invalid values for the ignore order field", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(1)...)
+			data = append(data, encodeVarInt(2)...)
+			data = append(data, encodeVarInt(1000)...)
+			data = append(data, 2)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).To(MatchError("invalid ignore order value: 2"))
+		})
+
+		It("errors on EOFs", func() {
+			data := encodeVarInt(0xaf)
+			data = append(data, encodeVarInt(0xdeadbeef)...)
+			data = append(data, encodeVarInt(0xcafe)...)
+			data = append(data, encodeVarInt(1337)...)
+			data = append(data, 1)
+			_, err := parseAckFrequencyFrame(bytes.NewReader(data), versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckFrequencyFrame(bytes.NewReader(data[0:i]), versionIETFFrames)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			frame := &AckFrequencyFrame{
+				SequenceNumber:    0xdecafbad,
+				PacketTolerance:   0x1337,
+				UpdateMaxAckDelay: 0x4242 * time.Microsecond,
+				IgnoreOrder:       true,
+			}
+			b := &bytes.Buffer{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			expected := []byte{0x40, 0xaf}
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, encodeVarInt(0x4242)...)
+			expected = append(expected, 1)
+			Expect(b.Bytes()).To(Equal(expected))
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+		})
+	})
+})
@@ -27,6 +27,10 @@ type ExtendedHeader struct {
 	PacketNumberLen protocol.PacketNumberLen
 	PacketNumber    protocol.PacketNumber
 
+	// ClearQUICBit makes a short header packet being sent with the QUIC bit set to 0.
+	// It must only be used if the peer sent the grease_quic_bit transport parameter.
+	ClearQUICBit bool
+
 	parsedLen protocol.ByteCount
 }
 
@@ -166,6 +170,9 @@ func (h *ExtendedHeader) writeLongHeader(b *bytes.Buffer, _ protocol.VersionNumb
 
 func (h *ExtendedHeader) writeShortHeader(b *bytes.Buffer, _ protocol.VersionNumber) error {
 	typeByte := 0x40 | uint8(h.PacketNumberLen-1)
+	if h.ClearQUICBit {
+		typeByte &^= 0x40
+	}
 	if h.KeyPhase == protocol.KeyPhaseOne {
 		typeByte |= byte(1 << 2)
 	}
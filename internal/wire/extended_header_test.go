@@ -143,6 +143,18 @@ var _ = Describe("Header", func() {
 				}))
 			})
 
+			It("writes a header with the QUIC bit unset", func() {
+				Expect((&ExtendedHeader{
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    0x42,
+					ClearQUICBit:    true,
+				}).Write(buf, versionIETFHeader)).To(Succeed())
+				Expect(buf.Bytes()).To(Equal([]byte{
+					0x0,
+					0x42, // packet number
+				}))
+			})
+
 			It("writes a header with a 2 byte packet number", func() {
 				Expect((&ExtendedHeader{
 					PacketNumberLen: protocol.PacketNumberLen2,
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type frameParser struct {
@@ -25,28 +27,42 @@ func NewFrameParser(v protocol.VersionNumber) FrameParser {
 // It skips PADDING frames.
 func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLevel) (Frame, error) {
 	for r.Len() != 0 {
-		typeByte, _ := r.ReadByte()
-		if typeByte == 0x0 { // PADDING frame
+		// The frame type is a varint. All frame types defined in RFC 9000 fit into a single byte,
+		// but extensions (e.g. the ack-frequency extension) use longer frame types.
+		startLen := r.Len()
+		typ, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, qerr.NewError(qerr.FrameEncodingError, err.Error())
+		}
+		if typ == 0x0 { // PADDING frame
 			continue
 		}
-		r.UnreadByte()
+		typeLen := startLen - r.Len()
+		// The frame type must use the shortest possible encoding.
+		if typeLen != int(utils.VarIntLen(typ)) {
+			return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, typ, "frame type not minimally encoded")
+		}
+		// The frame parsers read the frame type themselves.
+		if _, err := r.Seek(-int64(typeLen), io.SeekCurrent); err != nil {
+			return nil, err
+		}
 
-		f, err := p.parseFrame(r, typeByte, encLevel)
+		f, err := p.parseFrame(r, typ, encLevel)
 		if err != nil {
-			return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
+			return nil, qerr.NewErrorWithFrameType(qerr.FrameEncodingError, typ, err.Error())
 		}
 		return f, nil
 	}
 	return nil, nil
 }
 
-func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protocol.EncryptionLevel) (Frame, error) {
+func (p *frameParser) parseFrame(r *bytes.Reader, typ uint64, encLevel protocol.EncryptionLevel) (Frame, error) {
 	var frame Frame
 	var err error
-	if typeByte&0xf8 == 0x8 {
+	if typ&0xf8 == 0x8 {
 		frame, err = parseStreamFrame(r, p.version)
 	} else {
-		switch typeByte {
+		switch typ {
 		case 0x1:
 			frame, err = parsePingFrame(r, p.version)
 		case 0x2, 0x3:
@@ -87,6 +103,10 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case immediateAckFrameType:
+			frame, err = parseImmediateAckFrame(r, p.version)
+		case ackFrequencyFrameType:
+			frame, err = parseAckFrequencyFrame(r, p.version)
 		default:
 			err = errors.New("unknown frame type")
 		}
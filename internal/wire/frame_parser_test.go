@@ -280,11 +280,54 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame).To(Equal(f))
 	})
 
+	It("unpacks ACK_FREQUENCY frames", func() {
+		f := &AckFrequencyFrame{
+			SequenceNumber:    3,
+			PacketTolerance:   10,
+			UpdateMaxAckDelay: 5 * time.Millisecond,
+			IgnoreOrder:       true,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("unpacks IMMEDIATE_ACK frames", func() {
+		f := &ImmediateAckFrame{}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
 	It("errors on invalid type", func() {
-		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
+		_, err := parser.ParseNext(bytes.NewReader([]byte{0x40, 0x42}), protocol.Encryption1RTT)
 		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x42): unknown frame type"))
 	})
 
+	It("errors on truncated frame types", func() {
+		_, err := parser.ParseNext(bytes.NewReader([]byte{0x40}), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR: EOF"))
+	})
+
+	It("errors on frame types that are not minimally encoded", func() {
+		// a PING frame, encoded using 2 bytes
+		_, err := parser.ParseNext(bytes.NewReader([]byte{0x40, 0x01}), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x1): frame type not minimally encoded"))
+	})
+
+	It("parses frame types that are encoded using 2 bytes", func() {
+		b := &bytes.Buffer{}
+		Expect((&ImmediateAckFrame{}).Write(b, versionIETFFrames)).To(Succeed())
+		Expect(b.Bytes()).To(Equal([]byte{0x40, 0xac}))
+		frame, err := parser.ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&ImmediateAckFrame{}))
+	})
+
 	It("errors on invalid frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:          0x1337,
@@ -318,6 +361,8 @@ var _ = Describe("Frame parsing", func() {
 			&PathResponseFrame{},
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
+			&AckFrequencyFrame{PacketTolerance: 1},
+			&ImmediateAckFrame{},
 		}
 
 		var framesSerialized [][]byte
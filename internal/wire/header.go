@@ -66,7 +66,17 @@ type Header struct {
 // If we understand the version, the packet is header up unto the packet number.
 // Otherwise, only the invariant part of the header is parsed.
 func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* packet data */, []byte /* rest */, error) {
-	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen)
+	return parsePacket(data, shortHeaderConnIDLen, false)
+}
+
+// ParsePacketWithGreasedQUICBit is like ParsePacket, but also accepts short header packets with the QUIC bit unset.
+// It must only be used if the grease_quic_bit transport parameter was sent.
+func ParsePacketWithGreasedQUICBit(data []byte, shortHeaderConnIDLen int) (*Header, []byte, []byte, error) {
+	return parsePacket(data, shortHeaderConnIDLen, true)
+}
+
+func parsePacket(data []byte, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, []byte, []byte, error) {
+	hdr, err := parseHeader(bytes.NewReader(data), shortHeaderConnIDLen, allowGreasedQUICBit)
 	if err != nil {
 		if err == ErrUnsupportedVersion {
 			return hdr, nil, nil, ErrUnsupportedVersion
@@ -90,9 +100,9 @@ func ParsePacket(data []byte, shortHeaderConnIDLen int) (*Header, []byte /* pack
 // For long header packets:
 // * if we understand the version: up to the packet number
 // * if not, only the invariant part of the header
-func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, error) {
 	startLen := b.Len()
-	h, err := parseHeaderImpl(b, shortHeaderConnIDLen)
+	h, err := parseHeaderImpl(b, shortHeaderConnIDLen, allowGreasedQUICBit)
 	if err != nil {
 		return h, err
 	}
@@ -100,7 +110,7 @@ func parseHeader(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
 	return h, err
 }
 
-func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error) {
+func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int, allowGreasedQUICBit bool) (*Header, error) {
 	typeByte, err := b.ReadByte()
 	if err != nil {
 		return nil, err
@@ -112,7 +122,8 @@ func parseHeaderImpl(b *bytes.Reader, shortHeaderConnIDLen int) (*Header, error)
 	}
 
 	if !h.IsLongHeader {
-		if h.typeByte&0x40 == 0 {
+		// The QUIC bit can only be unset if we sent the grease_quic_bit transport parameter.
+		if h.typeByte&0x40 == 0 && !allowGreasedQUICBit {
 			return nil, errors.New("not a QUIC packet")
 		}
 		if err := h.parseShortHeader(b, shortHeaderConnIDLen); err != nil {
@@ -426,6 +426,14 @@ var _ = Describe("Header Parsing", func() {
 			Expect(err).To(MatchError("not a QUIC packet"))
 		})
 
+		It("parses a header with the QUIC bit unset, if greasing the QUIC bit is allowed", func() {
+			connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+			data := append([]byte{0x0}, connID...)
+			hdr, _, _, err := ParsePacketWithGreasedQUICBit(data, 8)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.DestConnectionID).To(Equal(connID))
+		})
+
 		It("errors if the 4th or 5th bit are set", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5}
 			data := append([]byte{0x40 | 0x10 /* set the 4th bit */}, connID...)
@@ -0,0 +1,36 @@
+package wire
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IMMEDIATE_ACK frame", func() {
+	Context("when parsing", func() {
+		It("accepts sample frame", func() {
+			b := bytes.NewReader([]byte{0x40, 0xac})
+			_, err := parseImmediateAckFrame(b, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOFs", func() {
+			_, err := parseImmediateAckFrame(bytes.NewReader(nil), versionIETFFrames)
+			Expect(err).To(HaveOccurred())
+			_, err = parseImmediateAckFrame(bytes.NewReader([]byte{0x40}), versionIETFFrames)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a sample frame", func() {
+			b := &bytes.Buffer{}
+			frame := &ImmediateAckFrame{}
+			Expect(frame.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(b.Bytes()).To(Equal([]byte{0x40, 0xac}))
+			Expect(frame.Length(versionIETFFrames)).To(BeEquivalentTo(2))
+		})
+	})
+})
@@ -87,6 +87,8 @@ var _ = Describe("Transport Parameters", func() {
 			AckDelayExponent:                13,
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
+			GreaseQUICBit:                   true,
+			MaxUDPPayloadSize:               1300,
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -107,6 +109,49 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.AckDelayExponent).To(Equal(uint8(13)))
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		Expect(p.GreaseQUICBit).To(BeTrue())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.ByteCount(1300)))
+	})
+
+	It("marshals the default max_udp_payload_size, if not set", func() {
+		data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.MaxUDPPayloadSize).To(Equal(protocol.MaxReceivePacketSize))
+	})
+
+	It("doesn't marshal the grease_quic_bit, if not set", func() {
+		data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.GreaseQUICBit).To(BeFalse())
+	})
+
+	It("marshals and unmarshals the min_ack_delay", func() {
+		minAckDelay := 1337 * time.Microsecond
+		data := (&TransportParameters{MinAckDelay: &minAckDelay, MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal(protocol.PerspectiveClient)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.MinAckDelay).ToNot(BeNil())
+		Expect(*p.MinAckDelay).To(Equal(minAckDelay))
+		Expect(p.String()).To(ContainSubstring("MinAckDelay: 1.337ms"))
+	})
+
+	It("doesn't marshal the min_ack_delay, if not set", func() {
+		data := (&TransportParameters{}).Marshal(protocol.PerspectiveClient)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+		Expect(p.MinAckDelay).To(BeNil())
+	})
+
+	It("errors when the min_ack_delay is larger than the max_ack_delay", func() {
+		minAckDelay := 30 * time.Millisecond
+		data := (&TransportParameters{
+			MinAckDelay: &minAckDelay,
+			MaxAckDelay: 20 * time.Millisecond,
+		}).Marshal(protocol.PerspectiveClient)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: min_ack_delay (30ms) larger than max_ack_delay (20ms)"))
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -153,6 +198,14 @@ var _ = Describe("Transport Parameters", func() {
 		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_active_migration: 6 (expected empty)"))
 	})
 
+	It("errors when grease_quic_bit has content", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for grease_quic_bit: 6 (expected empty)"))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		utils.WriteVarInt(b, uint64(statelessResetTokenParameterID))
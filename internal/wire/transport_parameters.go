@@ -42,6 +42,10 @@ const (
 	activeConnectionIDLimitParameterID         transportParameterID = 0xe
 	initialSourceConnectionIDParameterID       transportParameterID = 0xf
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
+	// RFC 9287
+	greaseQUICBitParameterID transportParameterID = 0x2ab2
+	// draft-ietf-quic-ack-frequency-01
+	minAckDelayParameterID transportParameterID = 0xff04de1a
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -81,6 +85,10 @@ type TransportParameters struct {
 
 	StatelessResetToken     *protocol.StatelessResetToken
 	ActiveConnectionIDLimit uint64
+
+	GreaseQUICBit bool
+
+	MinAckDelay *time.Duration // nil if the ACK frequency extension is not supported
 }
 
 // Unmarshal the transport parameters
@@ -132,7 +140,8 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			initialMaxStreamsUniParameterID,
 			maxIdleTimeoutParameterID,
 			maxUDPPayloadSizeParameterID,
-			activeConnectionIDLimitParameterID:
+			activeConnectionIDLimitParameterID,
+			minAckDelayParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
 			}
@@ -153,6 +162,11 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 					return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 				}
 				p.DisableActiveMigration = true
+			case greaseQUICBitParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for grease_quic_bit: %d (expected empty)", paramLen)
+				}
+				p.GreaseQUICBit = true
 			case statelessResetTokenParameterID:
 				if sentBy == protocol.PerspectiveClient {
 					return errors.New("client sent a stateless_reset_token")
@@ -200,6 +214,9 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 		if !readInitialSourceConnectionID {
 			return errors.New("missing initial_source_connection_id")
 		}
+		if p.MinAckDelay != nil && *p.MinAckDelay > p.MaxAckDelay {
+			return fmt.Errorf("min_ack_delay (%s) larger than max_ack_delay (%s)", *p.MinAckDelay, p.MaxAckDelay)
+		}
 	}
 
 	// check that every transport parameter was sent at most once
@@ -307,6 +324,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.MaxAckDelay = maxAckDelay
 	case activeConnectionIDLimitParameterID:
 		p.ActiveConnectionIDLimit = val
+	case minAckDelayParameterID:
+		if val > uint64(protocol.MaxMaxAckDelay/time.Microsecond) {
+			return fmt.Errorf("invalid value for min_ack_delay: %dµs (maximum %dms)", val, protocol.MaxMaxAckDelay/time.Millisecond)
+		}
+		minAckDelay := time.Duration(val) * time.Microsecond
+		p.MinAckDelay = &minAckDelay
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -340,7 +363,11 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	// idle_timeout
 	p.marshalVarintParam(b, maxIdleTimeoutParameterID, uint64(p.MaxIdleTimeout/time.Millisecond))
 	// max_packet_size
-	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	maxUDPPayloadSize := protocol.MaxReceivePacketSize
+	if p.MaxUDPPayloadSize != 0 {
+		maxUDPPayloadSize = p.MaxUDPPayloadSize
+	}
+	p.marshalVarintParam(b, maxUDPPayloadSizeParameterID, uint64(maxUDPPayloadSize))
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
@@ -393,6 +420,15 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 		utils.WriteVarInt(b, uint64(p.RetrySourceConnectionID.Len()))
 		b.Write(p.RetrySourceConnectionID.Bytes())
 	}
+	// grease_quic_bit
+	if p.GreaseQUICBit {
+		utils.WriteVarInt(b, uint64(greaseQUICBitParameterID))
+		utils.WriteVarInt(b, 0)
+	}
+	// min_ack_delay
+	if p.MinAckDelay != nil {
+		p.marshalVarintParam(b, minAckDelayParameterID, uint64(*p.MinAckDelay/time.Microsecond))
+	}
 	return b.Bytes()
 }
 
@@ -465,6 +501,13 @@ func (p *TransportParameters) String() string {
 		logString += ", StatelessResetToken: %#x"
 		logParams = append(logParams, *p.StatelessResetToken)
 	}
+	if p.GreaseQUICBit {
+		logString += ", GreaseQUICBit: true"
+	}
+	if p.MinAckDelay != nil {
+		logString += ", MinAckDelay: %s"
+		logParams = append(logParams, *p.MinAckDelay)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -48,6 +48,8 @@ type (
 	TransportError = qerr.ErrorCode
 	// An ApplicationError is an application-defined error code.
 	ApplicationError = qerr.ErrorCode
+	// An ApplicationErrorCode is an application-defined error code, as used in RESET_STREAM and STOP_SENDING frames.
+	ApplicationErrorCode = protocol.ApplicationErrorCode
 
 	// The RTTStats contain statistics used by the congestion controller.
 	RTTStats = utils.RTTStats
@@ -98,23 +100,57 @@ type ConnectionTracer interface {
 	ClosedConnection(CloseReason)
 	SentTransportParameters(*TransportParameters)
 	ReceivedTransportParameters(*TransportParameters)
-	SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame)
+	SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame, reason PacketSendReason)
+	// SentDatagram is called when a UDP datagram is sent, after SentPacket was called for every packet it contains.
+	// During the handshake, multiple packets can be coalesced into a single datagram.
+	SentDatagram(size ByteCount, packets []*ExtendedHeader)
 	ReceivedVersionNegotiationPacket(*Header, []VersionNumber)
 	ReceivedRetry(*Header)
-	ReceivedPacket(hdr *ExtendedHeader, size ByteCount, frames []Frame)
+	// ReceivedPacket is called for every packet that was successfully decrypted.
+	// rcvTime is the receive timestamp reported by the kernel, if the platform supports it,
+	// and otherwise the time when the packet was read from the socket.
+	ReceivedPacket(hdr *ExtendedHeader, size ByteCount, rcvTime time.Time, frames []Frame)
 	BufferedPacket(PacketType)
 	DroppedPacket(PacketType, ByteCount, PacketDropReason)
 	UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFlight ByteCount, packetsInFlight int)
 	LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)
+	// SpuriousLoss is called when a packet that was previously declared lost is acknowledged.
+	SpuriousLoss(EncryptionLevel, PacketNumber)
 	UpdatedCongestionState(CongestionState)
+	// CongestionWindowUpdated is called when the congestion window changes, i.e. when packets are acknowledged or lost.
+	// It is not called for ACKs and losses that leave the congestion window unchanged.
+	CongestionWindowUpdated(cwnd, bytesInFlight ByteCount)
 	UpdatedPTOCount(value uint32)
 	UpdatedKeyFromTLS(EncryptionLevel, Perspective)
 	UpdatedKey(generation KeyPhase, remote bool)
 	DroppedEncryptionLevel(EncryptionLevel)
 	DroppedKey(generation KeyPhase)
+	// RejectedEarlyData is called for the client when the server rejects 0-RTT.
+	RejectedEarlyData()
 	SetLossTimer(TimerType, EncryptionLevel, time.Time)
 	LossTimerExpired(TimerType, EncryptionLevel)
 	LossTimerCanceled()
+	// BlockedByAmplificationLimit is called when the server is blocked from sending by the anti-amplification limit,
+	// i.e. when it already sent 3x the bytes it received from the client before the client's address was validated.
+	// It is called at most once until more bytes are received from the client.
+	BlockedByAmplificationLimit()
+	// SetPacingTimer is called when the pacer doesn't allow sending any more packets right now.
+	// nextSendTime is the time when the pacer will allow sending the next packet.
+	SetPacingTimer(nextSendTime time.Time)
+	// ECNStateUpdated is called when the state of the ECN validation changes.
+	// When the validation fails, ECN marking is disabled for the rest of the connection.
+	ECNStateUpdated(state ECNState, trigger ECNStateTrigger)
+	// NewConnectionIDReceived is called when the peer issues a new connection ID,
+	// either in a NEW_CONNECTION_ID frame or in the preferred_address transport parameter.
+	NewConnectionIDReceived(seq uint64, connID ConnectionID)
+	// RetiredConnectionID is called when we retire a connection ID issued by the peer.
+	RetiredConnectionID(seq uint64)
+	// PathValidated is called when the peer proved that it is reachable on a path, using PATH_CHALLENGE and PATH_RESPONSE frames.
+	PathValidated(local, remote net.Addr)
+	// ReceivedResetStream is called when the peer resets a stream using a RESET_STREAM frame.
+	ReceivedResetStream(id StreamID, code ApplicationErrorCode, finalSize ByteCount)
+	// ReceivedStopSending is called when the peer asks us to stop sending on a stream using a STOP_SENDING frame.
+	ReceivedStopSending(id StreamID, code ApplicationErrorCode)
 	// Close is called when the connection is closed.
 	Close()
 }
@@ -84,9 +84,15 @@ func (m *connTracerMultiplexer) ReceivedTransportParameters(tp *TransportParamet
 	}
 }
 
-func (m *connTracerMultiplexer) SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame) {
+func (m *connTracerMultiplexer) SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame, reason PacketSendReason) {
 	for _, t := range m.tracers {
-		t.SentPacket(hdr, size, ack, frames)
+		t.SentPacket(hdr, size, ack, frames, reason)
+	}
+}
+
+func (m *connTracerMultiplexer) SentDatagram(size ByteCount, packets []*ExtendedHeader) {
+	for _, t := range m.tracers {
+		t.SentDatagram(size, packets)
 	}
 }
 
@@ -102,9 +108,9 @@ func (m *connTracerMultiplexer) ReceivedRetry(hdr *Header) {
 	}
 }
 
-func (m *connTracerMultiplexer) ReceivedPacket(hdr *ExtendedHeader, size ByteCount, frames []Frame) {
+func (m *connTracerMultiplexer) ReceivedPacket(hdr *ExtendedHeader, size ByteCount, rcvTime time.Time, frames []Frame) {
 	for _, t := range m.tracers {
-		t.ReceivedPacket(hdr, size, frames)
+		t.ReceivedPacket(hdr, size, rcvTime, frames)
 	}
 }
 
@@ -126,6 +132,12 @@ func (m *connTracerMultiplexer) UpdatedCongestionState(state CongestionState) {
 	}
 }
 
+func (m *connTracerMultiplexer) CongestionWindowUpdated(cwnd, bytesInFlight ByteCount) {
+	for _, t := range m.tracers {
+		t.CongestionWindowUpdated(cwnd, bytesInFlight)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFLight ByteCount, packetsInFlight int) {
 	for _, t := range m.tracers {
 		t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight)
@@ -138,6 +150,12 @@ func (m *connTracerMultiplexer) LostPacket(encLevel EncryptionLevel, pn PacketNu
 	}
 }
 
+func (m *connTracerMultiplexer) SpuriousLoss(encLevel EncryptionLevel, pn PacketNumber) {
+	for _, t := range m.tracers {
+		t.SpuriousLoss(encLevel, pn)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedPTOCount(value uint32) {
 	for _, t := range m.tracers {
 		t.UpdatedPTOCount(value)
@@ -168,6 +186,12 @@ func (m *connTracerMultiplexer) DroppedKey(generation KeyPhase) {
 	}
 }
 
+func (m *connTracerMultiplexer) RejectedEarlyData() {
+	for _, t := range m.tracers {
+		t.RejectedEarlyData()
+	}
+}
+
 func (m *connTracerMultiplexer) SetLossTimer(typ TimerType, encLevel EncryptionLevel, exp time.Time) {
 	for _, t := range m.tracers {
 		t.SetLossTimer(typ, encLevel, exp)
@@ -186,6 +210,54 @@ func (m *connTracerMultiplexer) LossTimerCanceled() {
 	}
 }
 
+func (m *connTracerMultiplexer) BlockedByAmplificationLimit() {
+	for _, t := range m.tracers {
+		t.BlockedByAmplificationLimit()
+	}
+}
+
+func (m *connTracerMultiplexer) SetPacingTimer(nextSendTime time.Time) {
+	for _, t := range m.tracers {
+		t.SetPacingTimer(nextSendTime)
+	}
+}
+
+func (m *connTracerMultiplexer) ECNStateUpdated(state ECNState, trigger ECNStateTrigger) {
+	for _, t := range m.tracers {
+		t.ECNStateUpdated(state, trigger)
+	}
+}
+
+func (m *connTracerMultiplexer) NewConnectionIDReceived(seq uint64, connID ConnectionID) {
+	for _, t := range m.tracers {
+		t.NewConnectionIDReceived(seq, connID)
+	}
+}
+
+func (m *connTracerMultiplexer) RetiredConnectionID(seq uint64) {
+	for _, t := range m.tracers {
+		t.RetiredConnectionID(seq)
+	}
+}
+
+func (m *connTracerMultiplexer) PathValidated(local, remote net.Addr) {
+	for _, t := range m.tracers {
+		t.PathValidated(local, remote)
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedResetStream(id StreamID, code ApplicationErrorCode, finalSize ByteCount) {
+	for _, t := range m.tracers {
+		t.ReceivedResetStream(id, code, finalSize)
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedStopSending(id StreamID, code ApplicationErrorCode) {
+	for _, t := range m.tracers {
+		t.ReceivedStopSending(id, code)
+	}
+}
+
 func (m *connTracerMultiplexer) Close() {
 	for _, t := range m.tracers {
 		t.Close()
@@ -130,9 +130,19 @@ var _ = Describe("Tracing", func() {
 			hdr := &ExtendedHeader{Header: Header{DestConnectionID: ConnectionID{1, 2, 3}}}
 			ack := &AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 10}}}
 			ping := &PingFrame{}
-			tr1.EXPECT().SentPacket(hdr, ByteCount(1337), ack, []Frame{ping})
-			tr2.EXPECT().SentPacket(hdr, ByteCount(1337), ack, []Frame{ping})
-			tracer.SentPacket(hdr, 1337, ack, []Frame{ping})
+			tr1.EXPECT().SentPacket(hdr, ByteCount(1337), ack, []Frame{ping}, PacketSendReasonPTOProbe)
+			tr2.EXPECT().SentPacket(hdr, ByteCount(1337), ack, []Frame{ping}, PacketSendReasonPTOProbe)
+			tracer.SentPacket(hdr, 1337, ack, []Frame{ping}, PacketSendReasonPTOProbe)
+		})
+
+		It("traces the SentDatagram event", func() {
+			hdrs := []*ExtendedHeader{
+				{Header: Header{DestConnectionID: ConnectionID{1, 2, 3}}},
+				{Header: Header{DestConnectionID: ConnectionID{4, 5, 6}}},
+			}
+			tr1.EXPECT().SentDatagram(ByteCount(1252), hdrs)
+			tr2.EXPECT().SentDatagram(ByteCount(1252), hdrs)
+			tracer.SentDatagram(1252, hdrs)
 		})
 
 		It("traces the ReceivedVersionNegotiationPacket event", func() {
@@ -152,9 +162,10 @@ var _ = Describe("Tracing", func() {
 		It("traces the ReceivedPacket event", func() {
 			hdr := &ExtendedHeader{Header: Header{DestConnectionID: ConnectionID{1, 2, 3}}}
 			ping := &PingFrame{}
-			tr1.EXPECT().ReceivedPacket(hdr, ByteCount(1337), []Frame{ping})
-			tr2.EXPECT().ReceivedPacket(hdr, ByteCount(1337), []Frame{ping})
-			tracer.ReceivedPacket(hdr, 1337, []Frame{ping})
+			now := time.Now()
+			tr1.EXPECT().ReceivedPacket(hdr, ByteCount(1337), now, []Frame{ping})
+			tr2.EXPECT().ReceivedPacket(hdr, ByteCount(1337), now, []Frame{ping})
+			tracer.ReceivedPacket(hdr, 1337, now, []Frame{ping})
 		})
 
 		It("traces the BufferedPacket event", func() {
@@ -175,6 +186,12 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedCongestionState(CongestionStateRecovery)
 		})
 
+		It("traces the CongestionWindowUpdated event", func() {
+			tr1.EXPECT().CongestionWindowUpdated(ByteCount(1234), ByteCount(567))
+			tr2.EXPECT().CongestionWindowUpdated(ByteCount(1234), ByteCount(567))
+			tracer.CongestionWindowUpdated(1234, 567)
+		})
+
 		It("traces the UpdatedMetrics event", func() {
 			rttStats := &RTTStats{}
 			rttStats.UpdateRTT(time.Second, 0, time.Now())
@@ -189,6 +206,31 @@ var _ = Describe("Tracing", func() {
 			tracer.LostPacket(EncryptionHandshake, 42, PacketLossReorderingThreshold)
 		})
 
+		It("traces the SpuriousLoss event", func() {
+			tr1.EXPECT().SpuriousLoss(Encryption1RTT, PacketNumber(42))
+			tr2.EXPECT().SpuriousLoss(Encryption1RTT, PacketNumber(42))
+			tracer.SpuriousLoss(Encryption1RTT, 42)
+		})
+
+		It("traces the BlockedByAmplificationLimit event", func() {
+			tr1.EXPECT().BlockedByAmplificationLimit()
+			tr2.EXPECT().BlockedByAmplificationLimit()
+			tracer.BlockedByAmplificationLimit()
+		})
+
+		It("traces the SetPacingTimer event", func() {
+			now := time.Now()
+			tr1.EXPECT().SetPacingTimer(now)
+			tr2.EXPECT().SetPacingTimer(now)
+			tracer.SetPacingTimer(now)
+		})
+
+		It("traces the ECNStateUpdated event", func() {
+			tr1.EXPECT().ECNStateUpdated(ECNStateFailed, ECNFailedNoECNCounts)
+			tr2.EXPECT().ECNStateUpdated(ECNStateFailed, ECNFailedNoECNCounts)
+			tracer.ECNStateUpdated(ECNStateFailed, ECNFailedNoECNCounts)
+		})
+
 		It("traces the UpdatedPTOCount event", func() {
 			tr1.EXPECT().UpdatedPTOCount(uint32(88))
 			tr2.EXPECT().UpdatedPTOCount(uint32(88))
@@ -219,6 +261,12 @@ var _ = Describe("Tracing", func() {
 			tracer.DroppedKey(123)
 		})
 
+		It("traces the RejectedEarlyData event", func() {
+			tr1.EXPECT().RejectedEarlyData()
+			tr2.EXPECT().RejectedEarlyData()
+			tracer.RejectedEarlyData()
+		})
+
 		It("traces the SetLossTimer event", func() {
 			now := time.Now()
 			tr1.EXPECT().SetLossTimer(TimerTypePTO, EncryptionHandshake, now)
@@ -238,6 +286,38 @@ var _ = Describe("Tracing", func() {
 			tracer.LossTimerCanceled()
 		})
 
+		It("traces the NewConnectionIDReceived event", func() {
+			tr1.EXPECT().NewConnectionIDReceived(uint64(42), ConnectionID{1, 2, 3, 4})
+			tr2.EXPECT().NewConnectionIDReceived(uint64(42), ConnectionID{1, 2, 3, 4})
+			tracer.NewConnectionIDReceived(42, ConnectionID{1, 2, 3, 4})
+		})
+
+		It("traces the RetiredConnectionID event", func() {
+			tr1.EXPECT().RetiredConnectionID(uint64(42))
+			tr2.EXPECT().RetiredConnectionID(uint64(42))
+			tracer.RetiredConnectionID(42)
+		})
+
+		It("traces the PathValidated event", func() {
+			local := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+			remote := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4321}
+			tr1.EXPECT().PathValidated(local, remote)
+			tr2.EXPECT().PathValidated(local, remote)
+			tracer.PathValidated(local, remote)
+		})
+
+		It("traces the ReceivedResetStream event", func() {
+			tr1.EXPECT().ReceivedResetStream(StreamID(4), ApplicationErrorCode(42), ByteCount(1337))
+			tr2.EXPECT().ReceivedResetStream(StreamID(4), ApplicationErrorCode(42), ByteCount(1337))
+			tracer.ReceivedResetStream(4, 42, 1337)
+		})
+
+		It("traces the ReceivedStopSending event", func() {
+			tr1.EXPECT().ReceivedStopSending(StreamID(4), ApplicationErrorCode(42))
+			tr2.EXPECT().ReceivedStopSending(StreamID(4), ApplicationErrorCode(42))
+			tracer.ReceivedStopSending(4, 42)
+		})
+
 		It("traces the Close event", func() {
 			tr1.EXPECT().Close()
 			tr2.EXPECT().Close()
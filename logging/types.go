@@ -33,6 +33,22 @@ const (
 	PacketLossTimeThreshold
 )
 
+// PacketSendReason is the reason why a packet was sent
+type PacketSendReason uint8
+
+const (
+	// PacketSendReasonNew is used for packets that don't contain any retransmitted frames
+	PacketSendReasonNew PacketSendReason = iota
+	// PacketSendReasonRetransmission is used for packets containing frames that were declared lost
+	PacketSendReasonRetransmission
+	// PacketSendReasonPTOProbe is used for probe packets sent when the PTO timer fired
+	PacketSendReasonPTOProbe
+	// PacketSendReasonAckOnly is used for packets that only contain an ACK frame
+	PacketSendReasonAckOnly
+	// PacketSendReasonPathProbe is used for packets sent on a path other than the active path
+	PacketSendReasonPathProbe
+)
+
 type PacketDropReason uint8
 
 const (
@@ -94,3 +110,31 @@ const (
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
 )
+
+// ECNState is the state of the ECN validation
+type ECNState uint8
+
+const (
+	// ECNStateTesting means that packets are sent with ECT(0), and the ECN counts reported by the peer are being validated
+	ECNStateTesting ECNState = iota
+	// ECNStateCapable means that the ECN validation succeeded
+	ECNStateCapable
+	// ECNStateFailed means that the ECN validation failed, and ECN marking was disabled
+	ECNStateFailed
+)
+
+// ECNStateTrigger is the reason for an update of the ECN state
+type ECNStateTrigger uint8
+
+const (
+	// ECNTriggerNoTrigger is used for ECN state updates that are not caused by a validation failure
+	ECNTriggerNoTrigger ECNStateTrigger = iota
+	// ECNFailedNoECNCounts is used when an ACK acknowledges ECN-marked packets, but doesn't contain any ECN counts
+	ECNFailedNoECNCounts
+	// ECNFailedTooFewECNCounts is used when the ECN counts increased by less than the number of newly acknowledged ECN-marked packets
+	ECNFailedTooFewECNCounts
+	// ECNFailedMoreECNCountsThanSent is used when the peer reports more ECN-marked packets than were sent
+	ECNFailedMoreECNCountsThanSent
+	// ECNFailedLostAllTestingPackets is used when all ECN-marked packets sent while testing were declared lost
+	ECNFailedLostAllTestingPackets
+)
@@ -166,7 +166,7 @@ func (t *connTracer) ClosedConnection(r logging.CloseReason) {
 }
 func (t *connTracer) SentTransportParameters(*logging.TransportParameters)     {}
 func (t *connTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
-func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame) {
+func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ *logging.AckFrame, _ []logging.Frame, _ logging.PacketSendReason) {
 	typ := logging.PacketTypeFromHeader(&hdr.Header)
 	if typ == logging.PacketType1RTT {
 		t.handshakeComplete = true
@@ -179,14 +179,16 @@ func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, _ logging.ByteCount
 		sentPackets.M(1),
 	)
 }
+func (t *connTracer) SentDatagram(logging.ByteCount, []*logging.ExtendedHeader)                 {}
 func (t *connTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {}
 func (t *connTracer) ReceivedRetry(*logging.Header)                                             {}
-func (t *connTracer) ReceivedPacket(*logging.ExtendedHeader, logging.ByteCount, []logging.Frame) {
+func (t *connTracer) ReceivedPacket(*logging.ExtendedHeader, logging.ByteCount, time.Time, []logging.Frame) {
 }
 func (t *connTracer) BufferedPacket(logging.PacketType)                                             {}
 func (t *connTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {}
 func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                                {}
 func (t *connTracer) UpdatedMetrics(*logging.RTTStats, logging.ByteCount, logging.ByteCount, int)   {}
+func (t *connTracer) CongestionWindowUpdated(logging.ByteCount, logging.ByteCount)                  {}
 func (t *connTracer) LostPacket(encLevel logging.EncryptionLevel, _ logging.PacketNumber, reason logging.PacketLossReason) {
 	stats.RecordWithTags(
 		context.Background(),
@@ -197,6 +199,9 @@ func (t *connTracer) LostPacket(encLevel logging.EncryptionLevel, _ logging.Pack
 		lostPackets.M(1),
 	)
 }
+
+func (t *connTracer) SpuriousLoss(logging.EncryptionLevel, logging.PacketNumber) {}
+
 func (t *connTracer) UpdatedPTOCount(value uint32) {
 	if value == 0 {
 		return
@@ -215,7 +220,17 @@ func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspect
 func (t *connTracer) UpdatedKey(logging.KeyPhase, bool)                                  {}
 func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
 func (t *connTracer) DroppedKey(logging.KeyPhase)                                        {}
+func (t *connTracer) RejectedEarlyData()                                                 {}
 func (t *connTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
 func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
 func (t *connTracer) LossTimerCanceled()                                                 {}
+func (t *connTracer) BlockedByAmplificationLimit()                                       {}
+func (t *connTracer) SetPacingTimer(time.Time)                                           {}
+func (t *connTracer) ECNStateUpdated(logging.ECNState, logging.ECNStateTrigger)          {}
+func (t *connTracer) NewConnectionIDReceived(uint64, logging.ConnectionID)               {}
+func (t *connTracer) RetiredConnectionID(uint64)                                         {}
+func (t *connTracer) PathValidated(net.Addr, net.Addr)                                   {}
+func (t *connTracer) ReceivedResetStream(logging.StreamID, logging.ApplicationErrorCode, logging.ByteCount) {
+}
+func (t *connTracer) ReceivedStopSending(logging.StreamID, logging.ApplicationErrorCode) {}
 func (t *connTracer) Close()                                                             {}
@@ -0,0 +1,98 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/lucas-clemente/quic-go (interfaces: AddressTokenGenerator)
+
+// Package quic is a generated GoMock package.
+package quic
+
+import (
+	net "net"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// MockAddressTokenGenerator is a mock of AddressTokenGenerator interface
+type MockAddressTokenGenerator struct {
+	ctrl     *gomock.Controller
+	recorder *MockAddressTokenGeneratorMockRecorder
+}
+
+// MockAddressTokenGeneratorMockRecorder is the mock recorder for MockAddressTokenGenerator
+type MockAddressTokenGeneratorMockRecorder struct {
+	mock *MockAddressTokenGenerator
+}
+
+// NewMockAddressTokenGenerator creates a new mock instance
+func NewMockAddressTokenGenerator(ctrl *gomock.Controller) *MockAddressTokenGenerator {
+	mock := &MockAddressTokenGenerator{ctrl: ctrl}
+	mock.recorder = &MockAddressTokenGeneratorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAddressTokenGenerator) EXPECT() *MockAddressTokenGeneratorMockRecorder {
+	return m.recorder
+}
+
+// NewRetryToken mocks base method
+func (m *MockAddressTokenGenerator) NewRetryToken(arg0 net.Addr, arg1, arg2 protocol.ConnectionID) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRetryToken", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewRetryToken indicates an expected call of NewRetryToken
+func (mr *MockAddressTokenGeneratorMockRecorder) NewRetryToken(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRetryToken", reflect.TypeOf((*MockAddressTokenGenerator)(nil).NewRetryToken), arg0, arg1, arg2)
+}
+
+// NewToken mocks base method
+func (m *MockAddressTokenGenerator) NewToken(arg0 net.Addr) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewToken", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewToken indicates an expected call of NewToken
+func (mr *MockAddressTokenGeneratorMockRecorder) NewToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewToken", reflect.TypeOf((*MockAddressTokenGenerator)(nil).NewToken), arg0)
+}
+
+// ValidateRetryToken mocks base method
+func (m *MockAddressTokenGenerator) ValidateRetryToken(arg0 []byte, arg1 net.Addr) (bool, protocol.ConnectionID, protocol.ConnectionID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateRetryToken", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(protocol.ConnectionID)
+	ret2, _ := ret[2].(protocol.ConnectionID)
+	ret3, _ := ret[3].(error)
+	return ret0, ret1, ret2, ret3
+}
+
+// ValidateRetryToken indicates an expected call of ValidateRetryToken
+func (mr *MockAddressTokenGeneratorMockRecorder) ValidateRetryToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateRetryToken", reflect.TypeOf((*MockAddressTokenGenerator)(nil).ValidateRetryToken), arg0, arg1)
+}
+
+// ValidateToken mocks base method
+func (m *MockAddressTokenGenerator) ValidateToken(arg0 []byte, arg1 net.Addr) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateToken", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateToken indicates an expected call of ValidateToken
+func (mr *MockAddressTokenGeneratorMockRecorder) ValidateToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateToken", reflect.TypeOf((*MockAddressTokenGenerator)(nil).ValidateToken), arg0, arg1)
+}
@@ -114,6 +114,18 @@ func (mr *MockPacketHandlerManagerMockRecorder) GetStatelessResetToken(arg0 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatelessResetToken", reflect.TypeOf((*MockPacketHandlerManager)(nil).GetStatelessResetToken), arg0)
 }
 
+// HandlePacketOnPath mocks base method
+func (m *MockPacketHandlerManager) HandlePacketOnPath(arg0 *receivedPacket, arg1 packetHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "HandlePacketOnPath", arg0, arg1)
+}
+
+// HandlePacketOnPath indicates an expected call of HandlePacketOnPath
+func (mr *MockPacketHandlerManagerMockRecorder) HandlePacketOnPath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandlePacketOnPath", reflect.TypeOf((*MockPacketHandlerManager)(nil).HandlePacketOnPath), arg0, arg1)
+}
+
 // Remove mocks base method
 func (m *MockPacketHandlerManager) Remove(arg0 protocol.ConnectionID) {
 	m.ctrl.T.Helper()
@@ -8,9 +8,11 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
+	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
 
 // MockQuicSession is a mock of QuicSession interface
@@ -66,6 +68,20 @@ func (mr *MockQuicSessionMockRecorder) AcceptUniStream(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockQuicSession)(nil).AcceptUniStream), arg0)
 }
 
+// ActiveStreams mocks base method
+func (m *MockQuicSession) ActiveStreams() []StreamInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveStreams")
+	ret0, _ := ret[0].([]StreamInfo)
+	return ret0
+}
+
+// ActiveStreams indicates an expected call of ActiveStreams
+func (mr *MockQuicSessionMockRecorder) ActiveStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveStreams", reflect.TypeOf((*MockQuicSession)(nil).ActiveStreams))
+}
+
 // CloseWithError mocks base method
 func (m *MockQuicSession) CloseWithError(arg0 protocol.ApplicationErrorCode, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -80,6 +96,34 @@ func (mr *MockQuicSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithErrorSync mocks base method
+func (m *MockQuicSession) CloseWithErrorSync(arg0 context.Context, arg1 protocol.ApplicationErrorCode, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithErrorSync", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithErrorSync indicates an expected call of CloseWithErrorSync
+func (mr *MockQuicSessionMockRecorder) CloseWithErrorSync(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithErrorSync", reflect.TypeOf((*MockQuicSession)(nil).CloseWithErrorSync), arg0, arg1, arg2)
+}
+
+// CloseWithTransportError mocks base method
+func (m *MockQuicSession) CloseWithTransportError(arg0 qerr.ErrorCode, arg1 uint64, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithTransportError", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithTransportError indicates an expected call of CloseWithTransportError
+func (mr *MockQuicSessionMockRecorder) CloseWithTransportError(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithTransportError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithTransportError), arg0, arg1, arg2)
+}
+
 // ConnectionState mocks base method
 func (m *MockQuicSession) ConnectionState() ConnectionState {
 	m.ctrl.T.Helper()
@@ -108,6 +152,48 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// CurrentPath mocks base method
+func (m *MockQuicSession) CurrentPath() PathInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CurrentPath")
+	ret0, _ := ret[0].(PathInfo)
+	return ret0
+}
+
+// CurrentPath indicates an expected call of CurrentPath
+func (mr *MockQuicSessionMockRecorder) CurrentPath() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CurrentPath", reflect.TypeOf((*MockQuicSession)(nil).CurrentPath))
+}
+
+// EarlyDataAccepted mocks base method
+func (m *MockQuicSession) EarlyDataAccepted() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyDataAccepted")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// EarlyDataAccepted indicates an expected call of EarlyDataAccepted
+func (mr *MockQuicSessionMockRecorder) EarlyDataAccepted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyDataAccepted", reflect.TypeOf((*MockQuicSession)(nil).EarlyDataAccepted))
+}
+
+// ForceKeyUpdate mocks base method
+func (m *MockQuicSession) ForceKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForceKeyUpdate indicates an expected call of ForceKeyUpdate
+func (mr *MockQuicSessionMockRecorder) ForceKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceKeyUpdate", reflect.TypeOf((*MockQuicSession)(nil).ForceKeyUpdate))
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -136,6 +222,20 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method
+func (m *MockQuicSession) HandshakeConfirmed() context.Context {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed
+func (mr *MockQuicSessionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockQuicSession)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -150,6 +250,48 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// Max0RTTSize mocks base method
+func (m *MockQuicSession) Max0RTTSize() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Max0RTTSize")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// Max0RTTSize indicates an expected call of Max0RTTSize
+func (mr *MockQuicSessionMockRecorder) Max0RTTSize() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Max0RTTSize", reflect.TypeOf((*MockQuicSession)(nil).Max0RTTSize))
+}
+
+// MigratePath mocks base method
+func (m *MockQuicSession) MigratePath(arg0 context.Context, arg1 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigratePath", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigratePath indicates an expected call of MigratePath
+func (mr *MockQuicSessionMockRecorder) MigratePath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratePath", reflect.TypeOf((*MockQuicSession)(nil).MigratePath), arg0, arg1)
+}
+
+// NegotiatedProtocol mocks base method
+func (m *MockQuicSession) NegotiatedProtocol() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NegotiatedProtocol")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NegotiatedProtocol indicates an expected call of NegotiatedProtocol
+func (mr *MockQuicSessionMockRecorder) NegotiatedProtocol() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedProtocol", reflect.TypeOf((*MockQuicSession)(nil).NegotiatedProtocol))
+}
+
 // OpenStream mocks base method
 func (m *MockQuicSession) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -224,6 +366,46 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// SendPing mocks base method
+func (m *MockQuicSession) SendPing() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendPing")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendPing indicates an expected call of SendPing
+func (mr *MockQuicSessionMockRecorder) SendPing() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendPing", reflect.TypeOf((*MockQuicSession)(nil).SendPing))
+}
+
+// SetAcceptDeadline mocks base method
+func (m *MockQuicSession) SetAcceptDeadline(arg0 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAcceptDeadline", arg0)
+}
+
+// SetAcceptDeadline indicates an expected call of SetAcceptDeadline
+func (mr *MockQuicSessionMockRecorder) SetAcceptDeadline(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAcceptDeadline", reflect.TypeOf((*MockQuicSession)(nil).SetAcceptDeadline), arg0)
+}
+
+// Stats mocks base method
+func (m *MockQuicSession) Stats() ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(ConnectionStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats
+func (mr *MockQuicSessionMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockQuicSession)(nil).Stats))
+}
+
 // destroy mocks base method
 func (m *MockQuicSession) destroy(arg0 error) {
 	m.ctrl.T.Helper()
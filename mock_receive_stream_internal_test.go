@@ -63,6 +63,21 @@ func (mr *MockReceiveStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), arg0)
 }
 
+// ReadAvailable mocks base method
+func (m *MockReceiveStreamI) ReadAvailable(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAvailable", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAvailable indicates an expected call of ReadAvailable
+func (mr *MockReceiveStreamIMockRecorder) ReadAvailable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAvailable", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadAvailable), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockReceiveStreamI) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -103,6 +118,20 @@ func (mr *MockReceiveStreamIMockRecorder) closeForShutdown(arg0 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockReceiveStreamI)(nil).closeForShutdown), arg0)
 }
 
+// getBytesReceived mocks base method
+func (m *MockReceiveStreamI) getBytesReceived() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getBytesReceived")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// getBytesReceived indicates an expected call of getBytesReceived
+func (mr *MockReceiveStreamIMockRecorder) getBytesReceived() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBytesReceived", reflect.TypeOf((*MockReceiveStreamI)(nil).getBytesReceived))
+}
+
 // getWindowUpdate mocks base method
 func (m *MockReceiveStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
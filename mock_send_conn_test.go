@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockSendConn is a mock of SendConn interface
@@ -76,16 +77,44 @@ func (mr *MockSendConnMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockSendConn)(nil).RemoteAddr))
 }
 
+// SupportsECN mocks base method
+func (m *MockSendConn) SupportsECN() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SupportsECN")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// SupportsECN indicates an expected call of SupportsECN
+func (mr *MockSendConnMockRecorder) SupportsECN() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupportsECN", reflect.TypeOf((*MockSendConn)(nil).SupportsECN))
+}
+
+// WithRemoteAddr mocks base method
+func (m *MockSendConn) WithRemoteAddr(arg0 net.Addr) sendConn {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithRemoteAddr", arg0)
+	ret0, _ := ret[0].(sendConn)
+	return ret0
+}
+
+// WithRemoteAddr indicates an expected call of WithRemoteAddr
+func (mr *MockSendConnMockRecorder) WithRemoteAddr(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithRemoteAddr", reflect.TypeOf((*MockSendConn)(nil).WithRemoteAddr), arg0)
+}
+
 // Write mocks base method
-func (m *MockSendConn) Write(arg0 []byte) error {
+func (m *MockSendConn) Write(arg0 []byte, arg1 protocol.ECN) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Write", arg0)
+	ret := m.ctrl.Call(m, "Write", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Write indicates an expected call of Write
-func (mr *MockSendConnMockRecorder) Write(arg0 interface{}) *gomock.Call {
+func (mr *MockSendConnMockRecorder) Write(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendConn)(nil).Write), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendConn)(nil).Write), arg0, arg1)
 }
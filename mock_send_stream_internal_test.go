@@ -38,6 +38,20 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method
+func (m *MockSendStreamI) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes
+func (mr *MockSendStreamIMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockSendStreamI)(nil).BufferedBytes))
+}
+
 // CancelWrite mocks base method
 func (m *MockSendStreamI) CancelWrite(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -78,6 +92,44 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// Flush mocks base method
+func (m *MockSendStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush
+func (mr *MockSendStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockSendStreamI)(nil).Flush))
+}
+
+// SetNonBlocking mocks base method
+func (m *MockSendStreamI) SetNonBlocking(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNonBlocking", arg0)
+}
+
+// SetNonBlocking indicates an expected call of SetNonBlocking
+func (mr *MockSendStreamIMockRecorder) SetNonBlocking(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNonBlocking", reflect.TypeOf((*MockSendStreamI)(nil).SetNonBlocking), arg0)
+}
+
+// SetPriority mocks base method
+func (m *MockSendStreamI) SetPriority(arg0 byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockSendStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockSendStreamI)(nil).SetPriority), arg0)
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -106,6 +158,20 @@ func (mr *MockSendStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockSendStreamI)(nil).StreamID))
 }
 
+// WritableChan mocks base method
+func (m *MockSendStreamI) WritableChan() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WritableChan")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// WritableChan indicates an expected call of WritableChan
+func (mr *MockSendStreamIMockRecorder) WritableChan() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WritableChan", reflect.TypeOf((*MockSendStreamI)(nil).WritableChan))
+}
+
 // Write mocks base method
 func (m *MockSendStreamI) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -133,6 +199,20 @@ func (mr *MockSendStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockSendStreamI)(nil).closeForShutdown), arg0)
 }
 
+// getBytesSent mocks base method
+func (m *MockSendStreamI) getBytesSent() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getBytesSent")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// getBytesSent indicates an expected call of getBytesSent
+func (mr *MockSendStreamIMockRecorder) getBytesSent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBytesSent", reflect.TypeOf((*MockSendStreamI)(nil).getBytesSent))
+}
+
 // handleMaxStreamDataFrame mocks base method
 func (m *MockSendStreamI) handleMaxStreamDataFrame(arg0 *wire.MaxStreamDataFrame) {
 	m.ctrl.T.Helper()
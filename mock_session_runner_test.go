@@ -74,6 +74,18 @@ func (mr *MockSessionRunnerMockRecorder) GetStatelessResetToken(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatelessResetToken", reflect.TypeOf((*MockSessionRunner)(nil).GetStatelessResetToken), arg0)
 }
 
+// HandlePacketOnPath mocks base method
+func (m *MockSessionRunner) HandlePacketOnPath(arg0 *receivedPacket, arg1 packetHandler) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "HandlePacketOnPath", arg0, arg1)
+}
+
+// HandlePacketOnPath indicates an expected call of HandlePacketOnPath
+func (mr *MockSessionRunnerMockRecorder) HandlePacketOnPath(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandlePacketOnPath", reflect.TypeOf((*MockSessionRunner)(nil).HandlePacketOnPath), arg0, arg1)
+}
+
 // Remove mocks base method
 func (m *MockSessionRunner) Remove(arg0 protocol.ConnectionID) {
 	m.ctrl.T.Helper()
@@ -38,6 +38,20 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method
+func (m *MockStreamI) BufferedBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes
+func (mr *MockStreamIMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockStreamI)(nil).BufferedBytes))
+}
+
 // CancelRead mocks base method
 func (m *MockStreamI) CancelRead(arg0 protocol.ApplicationErrorCode) {
 	m.ctrl.T.Helper()
@@ -90,6 +104,20 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// Flush mocks base method
+func (m *MockStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush
+func (mr *MockStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStreamI)(nil).Flush))
+}
+
 // Read mocks base method
 func (m *MockStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +133,21 @@ func (mr *MockStreamIMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), arg0)
 }
 
+// ReadAvailable mocks base method
+func (m *MockStreamI) ReadAvailable(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAvailable", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadAvailable indicates an expected call of ReadAvailable
+func (mr *MockStreamIMockRecorder) ReadAvailable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAvailable", reflect.TypeOf((*MockStreamI)(nil).ReadAvailable), arg0)
+}
+
 // SetDeadline mocks base method
 func (m *MockStreamI) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -119,6 +162,30 @@ func (mr *MockStreamIMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), arg0)
 }
 
+// SetNonBlocking mocks base method
+func (m *MockStreamI) SetNonBlocking(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetNonBlocking", arg0)
+}
+
+// SetNonBlocking indicates an expected call of SetNonBlocking
+func (mr *MockStreamIMockRecorder) SetNonBlocking(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNonBlocking", reflect.TypeOf((*MockStreamI)(nil).SetNonBlocking), arg0)
+}
+
+// SetPriority mocks base method
+func (m *MockStreamI) SetPriority(arg0 byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority
+func (mr *MockStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStreamI)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method
 func (m *MockStreamI) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -161,6 +228,20 @@ func (mr *MockStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStreamI)(nil).StreamID))
 }
 
+// WritableChan mocks base method
+func (m *MockStreamI) WritableChan() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WritableChan")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// WritableChan indicates an expected call of WritableChan
+func (mr *MockStreamIMockRecorder) WritableChan() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WritableChan", reflect.TypeOf((*MockStreamI)(nil).WritableChan))
+}
+
 // Write mocks base method
 func (m *MockStreamI) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -188,6 +269,34 @@ func (mr *MockStreamIMockRecorder) closeForShutdown(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeForShutdown", reflect.TypeOf((*MockStreamI)(nil).closeForShutdown), arg0)
 }
 
+// getBytesReceived mocks base method
+func (m *MockStreamI) getBytesReceived() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getBytesReceived")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// getBytesReceived indicates an expected call of getBytesReceived
+func (mr *MockStreamIMockRecorder) getBytesReceived() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBytesReceived", reflect.TypeOf((*MockStreamI)(nil).getBytesReceived))
+}
+
+// getBytesSent mocks base method
+func (m *MockStreamI) getBytesSent() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "getBytesSent")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// getBytesSent indicates an expected call of getBytesSent
+func (mr *MockStreamIMockRecorder) getBytesSent() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "getBytesSent", reflect.TypeOf((*MockStreamI)(nil).getBytesSent))
+}
+
 // getWindowUpdate mocks base method
 func (m *MockStreamI) getWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
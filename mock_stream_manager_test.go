@@ -66,6 +66,20 @@ func (mr *MockStreamManagerMockRecorder) AcceptUniStream(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptUniStream", reflect.TypeOf((*MockStreamManager)(nil).AcceptUniStream), arg0)
 }
 
+// ActiveStreams mocks base method
+func (m *MockStreamManager) ActiveStreams() []StreamInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveStreams")
+	ret0, _ := ret[0].([]StreamInfo)
+	return ret0
+}
+
+// ActiveStreams indicates an expected call of ActiveStreams
+func (mr *MockStreamManagerMockRecorder) ActiveStreams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveStreams", reflect.TypeOf((*MockStreamManager)(nil).ActiveStreams))
+}
+
 // CloseWithError mocks base method
 func (m *MockStreamManager) CloseWithError(arg0 error) {
 	m.ctrl.T.Helper()
@@ -70,3 +70,15 @@ func (mr *MockStreamSenderMockRecorder) queueControlFrame(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "queueControlFrame", reflect.TypeOf((*MockStreamSender)(nil).queueControlFrame), arg0)
 }
+
+// setStreamPriority mocks base method
+func (m *MockStreamSender) setStreamPriority(arg0 protocol.StreamID, arg1 byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setStreamPriority", arg0, arg1)
+}
+
+// setStreamPriority indicates an expected call of setStreamPriority
+func (mr *MockStreamSenderMockRecorder) setStreamPriority(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setStreamPriority", reflect.TypeOf((*MockStreamSender)(nil).setStreamPriority), arg0, arg1)
+}
@@ -33,6 +33,18 @@ func (m *MockTokenStore) EXPECT() *MockTokenStoreMockRecorder {
 	return m.recorder
 }
 
+// Invalidate mocks base method
+func (m *MockTokenStore) Invalidate(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Invalidate", arg0)
+}
+
+// Invalidate indicates an expected call of Invalidate
+func (mr *MockTokenStoreMockRecorder) Invalidate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockTokenStore)(nil).Invalidate), arg0)
+}
+
 // Pop mocks base method
 func (m *MockTokenStore) Pop(arg0 string) *ClientToken {
 	m.ctrl.T.Helper()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"reflect"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -16,15 +17,16 @@ var (
 )
 
 type multiplexer interface {
-	AddConn(c net.PacketConn, connIDLen int, statelessResetKey []byte, tracer logging.Tracer) (packetHandlerManager, error)
+	AddConn(c net.PacketConn, connIDLen int, statelessResetKey []byte, statelessResetKeyFunc func([]byte) [32]byte, tracer logging.Tracer) (packetHandlerManager, error)
 	RemoveConn(net.PacketConn) error
 }
 
 type connManager struct {
-	connIDLen         int
-	statelessResetKey []byte
-	tracer            logging.Tracer
-	manager           packetHandlerManager
+	connIDLen             int
+	statelessResetKey     []byte
+	statelessResetKeyFunc func([]byte) [32]byte
+	tracer                logging.Tracer
+	manager               packetHandlerManager
 }
 
 // The connMultiplexer listens on multiple net.PacketConns and dispatches
@@ -33,7 +35,7 @@ type connMultiplexer struct {
 	mutex sync.Mutex
 
 	conns                   map[string] /* LocalAddr().String() */ connManager
-	newPacketHandlerManager func(net.PacketConn, int, []byte, logging.Tracer, utils.Logger) packetHandlerManager // so it can be replaced in the tests
+	newPacketHandlerManager func(net.PacketConn, int, []byte, func([]byte) [32]byte, logging.Tracer, utils.Logger) packetHandlerManager // so it can be replaced in the tests
 
 	logger utils.Logger
 }
@@ -55,6 +57,7 @@ func (m *connMultiplexer) AddConn(
 	c net.PacketConn,
 	connIDLen int,
 	statelessResetKey []byte,
+	statelessResetKeyFunc func([]byte) [32]byte,
 	tracer logging.Tracer,
 ) (packetHandlerManager, error) {
 	m.mutex.Lock()
@@ -63,12 +66,13 @@ func (m *connMultiplexer) AddConn(
 	connIndex := c.LocalAddr().Network() + " " + c.LocalAddr().String()
 	p, ok := m.conns[connIndex]
 	if !ok {
-		manager := m.newPacketHandlerManager(c, connIDLen, statelessResetKey, tracer, m.logger)
+		manager := m.newPacketHandlerManager(c, connIDLen, statelessResetKey, statelessResetKeyFunc, tracer, m.logger)
 		p = connManager{
-			connIDLen:         connIDLen,
-			statelessResetKey: statelessResetKey,
-			manager:           manager,
-			tracer:            tracer,
+			connIDLen:             connIDLen,
+			statelessResetKey:     statelessResetKey,
+			statelessResetKeyFunc: statelessResetKeyFunc,
+			manager:               manager,
+			tracer:                tracer,
 		}
 		m.conns[connIndex] = p
 	} else {
@@ -78,6 +82,10 @@ func (m *connMultiplexer) AddConn(
 		if statelessResetKey != nil && !bytes.Equal(p.statelessResetKey, statelessResetKey) {
 			return nil, fmt.Errorf("cannot use different stateless reset keys on the same packet conn")
 		}
+		// Functions can't be compared directly. This compares the code pointers.
+		if statelessResetKeyFunc != nil && reflect.ValueOf(statelessResetKeyFunc).Pointer() != reflect.ValueOf(p.statelessResetKeyFunc).Pointer() {
+			return nil, fmt.Errorf("cannot use different stateless reset key functions on the same packet conn")
+		}
 		if tracer != p.tracer {
 			return nil, fmt.Errorf("cannot use different tracers on the same packet conn")
 		}
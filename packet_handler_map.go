@@ -32,6 +32,7 @@ type packetHandlerMap struct {
 	mutex sync.RWMutex
 
 	conn      net.PacketConn
+	reader    packetReader
 	connIDLen int
 
 	handlers    map[string] /* string(ConnectionID)*/ packetHandler
@@ -46,6 +47,7 @@ type packetHandlerMap struct {
 	statelessResetEnabled bool
 	statelessResetMutex   sync.Mutex
 	statelessResetHasher  hash.Hash
+	statelessResetKeyFunc func([]byte) [32]byte
 
 	tracer logging.Tracer
 	logger utils.Logger
@@ -57,18 +59,21 @@ func newPacketHandlerMap(
 	conn net.PacketConn,
 	connIDLen int,
 	statelessResetKey []byte,
+	statelessResetKeyFunc func([]byte) [32]byte,
 	tracer logging.Tracer,
 	logger utils.Logger,
 ) packetHandlerManager {
 	m := &packetHandlerMap{
 		conn:                       conn,
+		reader:                     newPacketReader(conn),
 		connIDLen:                  connIDLen,
 		listening:                  make(chan struct{}),
 		handlers:                   make(map[string]packetHandler),
 		resetTokens:                make(map[protocol.StatelessResetToken]packetHandler),
 		deleteRetiredSessionsAfter: protocol.RetiredConnectionIDDeleteTimeout,
-		statelessResetEnabled:      len(statelessResetKey) > 0,
+		statelessResetEnabled:      len(statelessResetKey) > 0 || statelessResetKeyFunc != nil,
 		statelessResetHasher:       hmac.New(sha256.New, statelessResetKey),
+		statelessResetKeyFunc:      statelessResetKeyFunc,
 		tracer:                     tracer,
 		logger:                     logger,
 	}
@@ -257,17 +262,19 @@ func (h *packetHandlerMap) listen() {
 		data := buffer.Data[:protocol.MaxReceivePacketSize]
 		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err := h.conn.ReadFrom(data)
+		n, addr, ecn, timestamp, err := h.reader.ReadPacket(data)
 		if err != nil {
 			h.close(err)
 			return
 		}
-		h.handlePacket(addr, buffer, data[:n])
+		h.handlePacket(addr, ecn, timestamp, buffer, data[:n])
 	}
 }
 
 func (h *packetHandlerMap) handlePacket(
 	addr net.Addr,
+	ecn protocol.ECN,
+	timestamp time.Time,
 	buffer *packetBuffer,
 	data []byte,
 ) {
@@ -280,8 +287,6 @@ func (h *packetHandlerMap) handlePacket(
 		}
 		return
 	}
-	rcvTime := time.Now()
-
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
 
@@ -293,7 +298,8 @@ func (h *packetHandlerMap) handlePacket(
 
 	p := &receivedPacket{
 		remoteAddr: addr,
-		rcvTime:    rcvTime,
+		timestamp:  timestamp,
+		ecn:        ecn,
 		buffer:     buffer,
 		data:       data,
 	}
@@ -312,6 +318,34 @@ func (h *packetHandlerMap) handlePacket(
 	h.server.handlePacket(p)
 }
 
+// HandlePacketOnPath handles a packet that a session received on a path that doesn't use the packet conn
+// of this packet handler map, i.e. after it was migrated to a new net.PacketConn using Session.MigratePath.
+// Stateless resets are handled like for packets received on the packet conn,
+// but the packet is only passed to the session if it was sent to one of its connection IDs.
+func (h *packetHandlerMap) HandlePacketOnPath(p *receivedPacket, sess packetHandler) {
+	connID, err := wire.ParseConnectionID(p.data, h.connIDLen)
+	if err != nil {
+		p.buffer.MaybeRelease()
+		h.logger.Debugf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
+		if h.tracer != nil {
+			h.tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropHeaderParseError)
+		}
+		return
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if isStatelessReset := h.maybeHandleStatelessReset(p.data); isStatelessReset {
+		return
+	}
+	if handler, ok := h.handlers[string(connID)]; !ok || handler != sess {
+		p.buffer.MaybeRelease()
+		h.logger.Debugf("received a packet with an unexpected connection ID %s on a migrated path", connID)
+		return
+	}
+	sess.handlePacket(p)
+}
+
 func (h *packetHandlerMap) maybeHandleStatelessReset(data []byte) bool {
 	// stateless resets are always short header packets
 	if data[0]&0x80 != 0 {
@@ -340,6 +374,13 @@ func (h *packetHandlerMap) GetStatelessResetToken(connID protocol.ConnectionID)
 		rand.Read(token[:])
 		return token
 	}
+	if h.statelessResetKeyFunc != nil {
+		key := h.statelessResetKeyFunc(connID.Bytes())
+		hasher := hmac.New(sha256.New, key[:])
+		hasher.Write(connID.Bytes())
+		copy(token[:], hasher.Sum(nil))
+		return token
+	}
 	h.statelessResetMutex.Lock()
 	h.statelessResetHasher.Write(connID.Bytes())
 	copy(token[:], h.statelessResetHasher.Sum(nil))
@@ -25,8 +25,9 @@ var _ = Describe("Packet Handler Map", func() {
 		conn    *mockPacketConn
 		tracer  *mocklogging.MockTracer
 
-		connIDLen         int
-		statelessResetKey []byte
+		connIDLen             int
+		statelessResetKey     []byte
+		statelessResetKeyFunc func([]byte) [32]byte
 	)
 
 	getPacketWithLength := func(connID protocol.ConnectionID, length protocol.ByteCount) []byte {
@@ -50,13 +51,14 @@ var _ = Describe("Packet Handler Map", func() {
 
 	BeforeEach(func() {
 		statelessResetKey = nil
+		statelessResetKeyFunc = nil
 		connIDLen = 0
 		tracer = mocklogging.NewMockTracer(mockCtrl)
 	})
 
 	JustBeforeEach(func() {
 		conn = newMockPacketConn()
-		handler = newPacketHandlerMap(conn, connIDLen, statelessResetKey, tracer, utils.DefaultLogger).(*packetHandlerMap)
+		handler = newPacketHandlerMap(conn, connIDLen, statelessResetKey, statelessResetKeyFunc, tracer, utils.DefaultLogger).(*packetHandlerMap)
 	})
 
 	AfterEach(func() {
@@ -110,6 +112,7 @@ var _ = Describe("Packet Handler Map", func() {
 				connID, err := wire.ParseConnectionID(p.data, 0)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(connID).To(Equal(connID1))
+				Expect(p.timestamp).To(BeTemporally("~", time.Now(), time.Second))
 				close(handledPacket1)
 			})
 			packetHandler2.EXPECT().handlePacket(gomock.Any()).Do(func(p *receivedPacket) {
@@ -130,7 +133,61 @@ var _ = Describe("Packet Handler Map", func() {
 		It("drops unparseable packets", func() {
 			addr := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
 			tracer.EXPECT().DroppedPacket(addr, logging.PacketTypeNotDetermined, protocol.ByteCount(4), logging.PacketDropHeaderParseError)
-			handler.handlePacket(addr, getPacketBuffer(), []byte{0, 1, 2, 3})
+			handler.handlePacket(addr, protocol.ECNNon, time.Now(), getPacketBuffer(), []byte{0, 1, 2, 3})
+		})
+
+		Context("packets received on a migrated path", func() {
+			var path *MockSendConn
+
+			BeforeEach(func() {
+				path = NewMockSendConn(mockCtrl)
+			})
+
+			It("passes packets to the session", func() {
+				connID := protocol.ConnectionID{1, 2, 3, 4, 5}
+				packetHandler := NewMockPacketHandler(mockCtrl)
+				handler.Add(connID, packetHandler)
+				p := &receivedPacket{data: getPacket(connID), buffer: getPacketBuffer(), path: path}
+				packetHandler.EXPECT().handlePacket(p)
+				handler.HandlePacketOnPath(p, packetHandler)
+			})
+
+			It("drops packets for other connection IDs", func() {
+				packetHandler := NewMockPacketHandler(mockCtrl)
+				otherHandler := NewMockPacketHandler(mockCtrl)
+				handler.Add(protocol.ConnectionID{1, 2, 3, 4, 5}, packetHandler)
+				handler.Add(protocol.ConnectionID{5, 4, 3, 2, 1}, otherHandler)
+				// don't EXPECT any calls to handlePacket
+				handler.HandlePacketOnPath(&receivedPacket{data: getPacket(protocol.ConnectionID{5, 4, 3, 2, 1}), buffer: getPacketBuffer(), path: path}, packetHandler)
+				handler.HandlePacketOnPath(&receivedPacket{data: getPacket(protocol.ConnectionID{9, 9, 9, 9, 9}), buffer: getPacketBuffer(), path: path}, packetHandler)
+			})
+
+			It("drops unparseable packets", func() {
+				addr := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
+				tracer.EXPECT().DroppedPacket(addr, logging.PacketTypeNotDetermined, protocol.ByteCount(4), logging.PacketDropHeaderParseError)
+				handler.HandlePacketOnPath(&receivedPacket{remoteAddr: addr, data: []byte{0, 1, 2, 3}, buffer: getPacketBuffer(), path: path}, NewMockPacketHandler(mockCtrl))
+			})
+
+			It("handles stateless resets", func() {
+				connID := protocol.ConnectionID{1, 2, 3, 4, 5}
+				packetHandler := NewMockPacketHandler(mockCtrl)
+				handler.Add(connID, packetHandler)
+				token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+				handler.AddResetToken(token, packetHandler)
+				data := append([]byte{0x40} /* short header packet */, connID...)
+				data = append(data, make([]byte, 50)...)
+				data = append(data, token[:]...)
+				destroyed := make(chan struct{})
+				packetHandler.EXPECT().destroy(gomock.Any()).Do(func(err error) {
+					defer GinkgoRecover()
+					var resetErr statelessResetErr
+					Expect(errors.As(err, &resetErr)).To(BeTrue())
+					Expect(resetErr.token).To(Equal(token))
+					close(destroyed)
+				})
+				handler.HandlePacketOnPath(&receivedPacket{data: data, buffer: getPacketBuffer(), path: path}, packetHandler)
+				Eventually(destroyed).Should(BeClosed())
+			})
 		})
 
 		It("deletes removed sessions immediately", func() {
@@ -138,7 +195,7 @@ var _ = Describe("Packet Handler Map", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 			handler.Add(connID, NewMockPacketHandler(mockCtrl))
 			handler.Remove(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -149,7 +206,7 @@ var _ = Describe("Packet Handler Map", func() {
 			handler.Add(connID, sess)
 			handler.Retire(connID)
 			time.Sleep(scaleDuration(30 * time.Millisecond))
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -163,13 +220,13 @@ var _ = Describe("Packet Handler Map", func() {
 			})
 			handler.Add(connID, packetHandler)
 			handler.Retire(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, getPacket(connID))
 			Eventually(handled).Should(BeClosed())
 		})
 
 		It("drops packets for unknown receivers", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, getPacket(connID))
 		})
 
 		It("closes the packet handlers when reading from the conn fails", func() {
@@ -210,7 +267,7 @@ var _ = Describe("Packet Handler Map", func() {
 				Expect(cid).To(Equal(connID))
 			})
 			handler.SetServer(server)
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, p)
 		})
 
 		It("closes all server sessions", func() {
@@ -232,7 +289,7 @@ var _ = Describe("Packet Handler Map", func() {
 			// don't EXPECT any calls to server.handlePacket
 			handler.SetServer(server)
 			handler.CloseServer()
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, p)
 		})
 	})
 
@@ -297,7 +354,7 @@ var _ = Describe("Packet Handler Map", func() {
 				p = append(p, token[:]...)
 
 				time.Sleep(scaleDuration(30 * time.Millisecond))
-				handler.handlePacket(nil, nil, p)
+				handler.handlePacket(nil, protocol.ECNNon, time.Now(), nil, p)
 			})
 
 			It("ignores packets too small to contain a stateless reset", func() {
@@ -332,7 +389,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("sends stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, time.Now(), getPacketBuffer(), p)
 				var reset mockPacketConnWrite
 				Eventually(conn.dataWritten).Should(Receive(&reset))
 				Expect(reset.to).To(Equal(addr))
@@ -343,16 +400,49 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets for small packets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, protocol.MinStatelessResetSize-2)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, time.Now(), getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
 
+		Context("with a key function", func() {
+			BeforeEach(func() {
+				statelessResetKeyFunc = func(connID []byte) [32]byte {
+					var key [32]byte
+					copy(key[:], connID)
+					return key
+				}
+			})
+
+			It("generates stateless reset tokens", func() {
+				connID1 := []byte{0xde, 0xad, 0xbe, 0xef}
+				connID2 := []byte{0xde, 0xca, 0xfb, 0xad}
+				Expect(handler.GetStatelessResetToken(connID1)).ToNot(Equal(handler.GetStatelessResetToken(connID2)))
+			})
+
+			It("generates the same tokens on different packet handler maps", func() {
+				connID := []byte{0xde, 0xad, 0xbe, 0xef}
+				other := newPacketHandlerMap(newMockPacketConn(), connIDLen, nil, statelessResetKeyFunc, tracer, utils.DefaultLogger)
+				defer other.Destroy()
+				Expect(handler.GetStatelessResetToken(connID)).To(Equal(other.GetStatelessResetToken(connID)))
+			})
+
+			It("sends stateless resets", func() {
+				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+				p := append([]byte{40}, make([]byte, 100)...)
+				handler.handlePacket(addr, protocol.ECNNon, time.Now(), getPacketBuffer(), p)
+				var reset mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reset))
+				Expect(reset.to).To(Equal(addr))
+				Expect(reset.data).To(HaveLen(protocol.MinStatelessResetSize))
+			})
+		})
+
 		Context("if no key is configured", func() {
 			It("doesn't send stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, protocol.ECNNon, time.Now(), getPacketBuffer(), p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// A Header is the header of a QUIC packet, as far as it can be parsed without decryption keys.
+type Header struct {
+	// IsLongHeader says if this is a long header packet.
+	IsLongHeader bool
+	// Type is the packet type.
+	// For long header packets with a version that's not supported, it is logging.PacketTypeNotDetermined.
+	Type logging.PacketType
+	// Version is the version of a long header packet.
+	// It is 0 for Version Negotiation packets.
+	Version VersionNumber
+	// DestConnectionID is the destination connection ID.
+	DestConnectionID ConnectionID
+	// SrcConnectionID is the source connection ID of a long header packet.
+	SrcConnectionID ConnectionID
+	// Token is the token of an Initial or a Retry packet.
+	Token []byte
+	// SupportedVersions are the versions offered in a Version Negotiation packet.
+	SupportedVersions []VersionNumber
+}
+
+// ParseHeader parses the header of a QUIC packet.
+// The connIDLen is the length of the destination connection ID of short header packets, since it is not encoded on the wire.
+// For coalesced packets, only the header of the first packet is parsed.
+// For long header packets of a version that's not supported, only the version-independent fields are parsed.
+// The Header doesn't reference the data slice.
+func ParseHeader(data []byte, connIDLen int) (*Header, error) {
+	if len(data) == 0 {
+		return nil, io.EOF
+	}
+	if wire.IsVersionNegotiationPacket(data) {
+		hdr, versions, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return &Header{
+			IsLongHeader:      true,
+			Type:              logging.PacketTypeVersionNegotiation,
+			DestConnectionID:  hdr.DestConnectionID,
+			SrcConnectionID:   hdr.SrcConnectionID,
+			SupportedVersions: versions,
+		}, nil
+	}
+	hdr, _, _, err := wire.ParsePacket(data, connIDLen)
+	if err != nil && err != wire.ErrUnsupportedVersion {
+		return nil, err
+	}
+	h := &Header{
+		IsLongHeader:     hdr.IsLongHeader,
+		Version:          hdr.Version,
+		DestConnectionID: hdr.DestConnectionID,
+		SrcConnectionID:  hdr.SrcConnectionID,
+		Token:            hdr.Token,
+	}
+	if err == wire.ErrUnsupportedVersion {
+		h.Type = logging.PacketTypeNotDetermined
+	} else {
+		h.Type = logging.PacketTypeFromHeader(hdr)
+	}
+	return h, nil
+}
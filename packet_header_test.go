@@ -0,0 +1,178 @@
+package quic
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Packet Header parsing", func() {
+	destConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xca, 0xfe, 0x13, 0x37}
+	srcConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6}
+
+	composePacket := func(hdr *wire.ExtendedHeader, payload []byte) []byte {
+		buf := &bytes.Buffer{}
+		Expect(hdr.Write(buf, protocol.VersionTLS)).To(Succeed())
+		return append(buf.Bytes(), payload...)
+	}
+
+	It("parses Initial packets", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeInitial,
+				Version:          protocol.VersionTLS,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+				Token:            []byte("foobar"),
+				Length:           2 + 6,
+			},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		hdr, err := ParseHeader(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeTrue())
+		Expect(hdr.Type).To(Equal(logging.PacketTypeInitial))
+		Expect(hdr.Version).To(Equal(protocol.VersionTLS))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+		Expect(hdr.Token).To(Equal([]byte("foobar")))
+		Expect(hdr.SupportedVersions).To(BeEmpty())
+	})
+
+	It("parses Handshake packets", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				Version:          protocol.VersionTLS,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+				Length:           2 + 6,
+			},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		hdr, err := ParseHeader(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(logging.PacketTypeHandshake))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.Token).To(BeEmpty())
+	})
+
+	It("parses Retry packets", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeRetry,
+				Version:          protocol.VersionTLS,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+				Token:            []byte("token"),
+			},
+		}, make([]byte, 16) /* Retry integrity tag */)
+		hdr, err := ParseHeader(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(logging.PacketTypeRetry))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+		Expect(hdr.Token).To(Equal([]byte("token")))
+	})
+
+	It("parses Version Negotiation packets", func() {
+		versions := []protocol.VersionNumber{protocol.VersionTLS, 0x1337}
+		data, err := wire.ComposeVersionNegotiation(destConnID, srcConnID, versions)
+		Expect(err).ToNot(HaveOccurred())
+		hdr, err := ParseHeader(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeTrue())
+		Expect(hdr.Type).To(Equal(logging.PacketTypeVersionNegotiation))
+		Expect(hdr.Version).To(BeZero())
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+		// the list also contains a greased version
+		Expect(hdr.SupportedVersions).To(ContainElement(protocol.VersionTLS))
+		Expect(hdr.SupportedVersions).To(ContainElement(protocol.VersionNumber(0x1337)))
+	})
+
+	It("parses short header packets", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: destConnID},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		hdr, err := ParseHeader(data, destConnID.Len())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeFalse())
+		Expect(hdr.Type).To(Equal(logging.PacketType1RTT))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(BeEmpty())
+	})
+
+	It("parses the version-independent fields of packets with an unsupported version", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				Version:          0x1337,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+				Length:           2 + 6,
+			},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		hdr, err := ParseHeader(data, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeTrue())
+		Expect(hdr.Type).To(Equal(logging.PacketTypeNotDetermined))
+		Expect(hdr.Version).To(Equal(protocol.VersionNumber(0x1337)))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+	})
+
+	It("doesn't reference the data slice", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: destConnID},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		hdr, err := ParseHeader(data, destConnID.Len())
+		Expect(err).ToNot(HaveOccurred())
+		for i := range data {
+			data[i] = 0
+		}
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+	})
+
+	It("errors on empty packets", func() {
+		_, err := ParseHeader(nil, 0)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("errors on truncated packets", func() {
+		data := composePacket(&wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeInitial,
+				Version:          protocol.VersionTLS,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+				Length:           2 + 6,
+			},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}, []byte("foobar"))
+		for i := 1; i < len(data); i++ {
+			_, err := ParseHeader(data[:i], 0)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+})
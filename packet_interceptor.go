@@ -0,0 +1,36 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// An interceptingSendConn passes every outgoing packet to the Config.PacketInterceptor
+// before writing it to the underlying sendConn.
+type interceptingSendConn struct {
+	sendConn
+
+	intercept func(data []byte, addr net.Addr) (forward bool, rewritten []byte)
+}
+
+var _ sendConn = &interceptingSendConn{}
+
+func newInterceptingSendConn(c sendConn, intercept func([]byte, net.Addr) (bool, []byte)) sendConn {
+	return &interceptingSendConn{sendConn: c, intercept: intercept}
+}
+
+func (c *interceptingSendConn) Write(p []byte, ecn protocol.ECN) error {
+	forward, rewritten := c.intercept(p, c.RemoteAddr())
+	if !forward {
+		return nil
+	}
+	if rewritten != nil {
+		p = rewritten
+	}
+	return c.sendConn.Write(p, ecn)
+}
+
+func (c *interceptingSendConn) WithRemoteAddr(addr net.Addr) sendConn {
+	return newInterceptingSendConn(c.sendConn.WithRemoteAddr(addr), c.intercept)
+}
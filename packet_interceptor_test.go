@@ -0,0 +1,56 @@
+package quic
+
+import (
+	"errors"
+	"net"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Intercepting send conn", func() {
+	var (
+		mconn      *MockSendConn
+		remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+	)
+
+	BeforeEach(func() {
+		mconn = NewMockSendConn(mockCtrl)
+		mconn.EXPECT().RemoteAddr().Return(remoteAddr).AnyTimes()
+	})
+
+	It("forwards packets", func() {
+		var intercepted []byte
+		var interceptedAddr net.Addr
+		c := newInterceptingSendConn(mconn, func(data []byte, addr net.Addr) (bool, []byte) {
+			intercepted = data
+			interceptedAddr = addr
+			return true, nil
+		})
+		mconn.EXPECT().Write([]byte("foobar"), protocol.ECT0)
+		Expect(c.Write([]byte("foobar"), protocol.ECT0)).To(Succeed())
+		Expect(intercepted).To(Equal([]byte("foobar")))
+		Expect(interceptedAddr).To(Equal(remoteAddr))
+	})
+
+	It("drops packets", func() {
+		c := newInterceptingSendConn(mconn, func([]byte, net.Addr) (bool, []byte) { return false, nil })
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+	})
+
+	It("rewrites packets", func() {
+		c := newInterceptingSendConn(mconn, func([]byte, net.Addr) (bool, []byte) { return true, []byte("raboof") })
+		mconn.EXPECT().Write([]byte("raboof"), protocol.ECNNon)
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+	})
+
+	It("returns write errors", func() {
+		testErr := errors.New("test error")
+		c := newInterceptingSendConn(mconn, func([]byte, net.Addr) (bool, []byte) { return true, nil })
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Return(testErr)
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(MatchError(testErr))
+	})
+})
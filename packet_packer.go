@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 type packer interface {
@@ -22,6 +24,7 @@ type packer interface {
 	MaybePackProbePacket(protocol.EncryptionLevel) (*packedPacket, error)
 	MaybePackAckPacket(handshakeConfirmed bool) (*packedPacket, error)
 	PackConnectionClose(*qerr.QuicError) (*coalescedPacket, error)
+	PackPathProbePacket(frames []ackhandler.Frame, minSize protocol.ByteCount) (*packedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
@@ -35,6 +38,8 @@ type payload struct {
 	frames []ackhandler.Frame
 	ack    *wire.AckFrame
 	length protocol.ByteCount
+	// the minimum number of bytes of PADDING frames added to the packet
+	padding protocol.ByteCount
 }
 
 type packedPacket struct {
@@ -47,7 +52,8 @@ type packetContents struct {
 	ack    *wire.AckFrame
 	frames []ackhandler.Frame
 
-	length protocol.ByteCount
+	length     protocol.ByteCount
+	sendReason logging.PacketSendReason
 }
 
 type coalescedPacket struct {
@@ -76,6 +82,20 @@ func (p *packetContents) IsAckEliciting() bool {
 	return ackhandler.HasAckElicitingFrames(p.frames)
 }
 
+// sendReasonForPayload determines why a packet carrying the payload is sent.
+// Probe packets are marked by the caller.
+func sendReasonForPayload(pl payload) logging.PacketSendReason {
+	if len(pl.frames) == 0 {
+		return logging.PacketSendReasonAckOnly
+	}
+	for _, f := range pl.frames {
+		if f.IsRetransmission {
+			return logging.PacketSendReasonRetransmission
+		}
+	}
+	return logging.PacketSendReasonNew
+}
+
 func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueue) *ackhandler.Packet {
 	largestAcked := protocol.InvalidPacketNumber
 	if p.ack != nil {
@@ -164,6 +184,13 @@ type packetPacker struct {
 
 	maxPacketSize          protocol.ByteCount
 	numNonAckElicitingAcks int
+
+	// did we send the grease_quic_bit transport parameter
+	enableGreaseQUICBit bool
+	// set if both endpoints sent the grease_quic_bit transport parameter
+	greaseQUICBit bool
+	// if set, every packet is sent in its own datagram
+	disableCoalescing bool
 }
 
 var _ packer = &packetPacker{}
@@ -179,6 +206,8 @@ func newPacketPacker(
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
+	enableGreaseQUICBit bool,
+	disableCoalescing bool,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *packetPacker {
@@ -195,6 +224,8 @@ func newPacketPacker(
 		acks:                acks,
 		pnManager:           packetNumberManager,
 		maxPacketSize:       getMaxPacketSize(remoteAddr),
+		enableGreaseQUICBit: enableGreaseQUICBit,
+		disableCoalescing:   disableCoalescing,
 	}
 }
 
@@ -267,6 +298,10 @@ func (p *packetPacker) PackConnectionClose(quicErr *qerr.QuicError) (*coalescedP
 			return nil, err
 		}
 		contents = append(contents, c)
+		// If coalescing is disabled, only send the CONNECTION_CLOSE at the lowest encryption level available.
+		if p.disableCoalescing {
+			break
+		}
 	}
 
 	if p.perspective == protocol.PerspectiveClient && contents[0].header.Type == protocol.PacketTypeInitial {
@@ -364,7 +399,7 @@ func (p *packetPacker) packCoalescedPacket(buffer *packetBuffer, maxPacketSize p
 	if contents != nil {
 		packet.packets = append(packet.packets, contents)
 	}
-	if buffer.Len() >= maxPacketSize-protocol.MinCoalescedPacketSize {
+	if buffer.Len() >= maxPacketSize-protocol.MinCoalescedPacketSize || (p.disableCoalescing && len(packet.packets) > 0) {
 		return packet, nil
 	}
 
@@ -376,7 +411,7 @@ func (p *packetPacker) packCoalescedPacket(buffer *packetBuffer, maxPacketSize p
 	if contents != nil {
 		packet.packets = append(packet.packets, contents)
 	}
-	if buffer.Len() >= maxPacketSize-protocol.MinCoalescedPacketSize {
+	if buffer.Len() >= maxPacketSize-protocol.MinCoalescedPacketSize || (p.disableCoalescing && len(packet.packets) > 0) {
 		return packet, nil
 	}
 
@@ -464,7 +499,7 @@ func (p *packetPacker) maybeAppendCryptoPacket(buffer *packetBuffer, maxPacketSi
 			if f == nil {
 				break
 			}
-			payload.frames = append(payload.frames, ackhandler.Frame{Frame: f})
+			payload.frames = append(payload.frames, ackhandler.Frame{Frame: f, IsRetransmission: true})
 			frameLen := f.Length(p.version)
 			payload.length += frameLen
 			remainingLen -= frameLen
@@ -550,7 +585,7 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAll
 			if f == nil {
 				break
 			}
-			payload.frames = append(payload.frames, ackhandler.Frame{Frame: f})
+			payload.frames = append(payload.frames, ackhandler.Frame{Frame: f, IsRetransmission: true})
 			payload.length += f.Length(p.version)
 		}
 	}
@@ -586,12 +621,37 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel) (
 	if p.perspective == protocol.PerspectiveClient && encLevel == protocol.EncryptionInitial {
 		p.padPacket(buffer)
 	}
+	contents.sendReason = logging.PacketSendReasonPTOProbe
 	return &packedPacket{
 		buffer:         buffer,
 		packetContents: contents,
 	}, nil
 }
 
+// PackPathProbePacket packs a 1-RTT packet that only contains the frames passed in,
+// padded to minSize (or the maximum packet size, if that is smaller) using PADDING frames.
+// It is used for packets sent on a path other than the active path, e.g. for path validation.
+func (p *packetPacker) PackPathProbePacket(frames []ackhandler.Frame, minSize protocol.ByteCount) (*packedPacket, error) {
+	sealer, hdr, err := p.getSealerAndHeader(protocol.Encryption1RTT)
+	if err != nil {
+		return nil, err
+	}
+	pl := payload{frames: frames}
+	for _, f := range frames {
+		pl.length += f.Length(p.version)
+	}
+	minSize = utils.MinByteCount(minSize, p.maxPacketSize)
+	if size := hdr.GetLength(p.version) + protocol.ByteCount(sealer.Overhead()) + pl.length; size < minSize {
+		pl.padding = minSize - size
+	}
+	packet, err := p.writeSinglePacket(hdr, pl, protocol.Encryption1RTT, sealer)
+	if err != nil {
+		return nil, err
+	}
+	packet.sendReason = logging.PacketSendReasonPathProbe
+	return packet, nil
+}
+
 func (p *packetPacker) getSealerAndHeader(encLevel protocol.EncryptionLevel) (sealer, *wire.ExtendedHeader, error) {
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -634,6 +694,9 @@ func (p *packetPacker) getShortHeader(kp protocol.KeyPhaseBit) *wire.ExtendedHea
 	hdr.PacketNumberLen = pnLen
 	hdr.DestConnectionID = p.getDestConnID()
 	hdr.KeyPhase = kp
+	if p.greaseQUICBit {
+		hdr.ClearQUICBit = rand.Intn(2) == 0
+	}
 	return hdr
 }
 
@@ -695,6 +758,19 @@ func (p *packetPacker) appendPacket(
 	if payload.length < 4-pnLen {
 		paddingLen = 4 - pnLen - payload.length
 	}
+	if payload.padding > paddingLen {
+		paddingLen = payload.padding
+	}
+	if p.disableCoalescing && p.perspective == protocol.PerspectiveClient && encLevel == protocol.EncryptionInitial {
+		// Pad the Initial packet itself, instead of appending the padding to the datagram.
+		// Otherwise the padding would look like a coalesced packet.
+		// After padding, the length field will be encoded in 2 bytes.
+		header.Length = p.maxPacketSize
+		hdrLen := header.GetLength(p.version) - pnLen
+		if size := buffer.Len() + hdrLen + pnLen + protocol.ByteCount(sealer.Overhead()) + payload.length + paddingLen; size < p.maxPacketSize {
+			paddingLen += p.maxPacketSize - size
+		}
+	}
 	if header.IsLongHeader {
 		header.Length = pnLen + protocol.ByteCount(sealer.Overhead()) + payload.length + paddingLen
 	}
@@ -742,10 +818,11 @@ func (p *packetPacker) appendPacket(
 		return nil, errors.New("packetPacker BUG: Peeked and Popped packet numbers do not match")
 	}
 	return &packetContents{
-		header: header,
-		ack:    payload.ack,
-		frames: payload.frames,
-		length: buffer.Len() - hdrOffset,
+		header:     header,
+		ack:        payload.ack,
+		frames:     payload.frames,
+		length:     buffer.Len() - hdrOffset,
+		sendReason: sendReasonForPayload(payload),
 	}, nil
 }
 
@@ -757,4 +834,6 @@ func (p *packetPacker) HandleTransportParameters(params *wire.TransportParameter
 	if params.MaxUDPPayloadSize != 0 {
 		p.maxPacketSize = utils.MinByteCount(p.maxPacketSize, params.MaxUDPPayloadSize)
 	}
+	// Only grease the QUIC bit if the peer told us that it can handle it.
+	p.greaseQUICBit = p.enableGreaseQUICBit && params.GreaseQUICBit
 }
@@ -16,6 +16,7 @@ import (
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -89,6 +90,8 @@ var _ = Describe("Packet packer", func() {
 			sealingManager,
 			framer,
 			ackFramer,
+			false,
+			false,
 			protocol.PerspectiveServer,
 			version,
 		)
@@ -241,6 +244,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(p).ToNot(BeNil())
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(p.ack).To(Equal(ack))
+				Expect(p.sendReason).To(Equal(logging.PacketSendReasonAckOnly))
 			})
 		})
 
@@ -360,6 +364,20 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.ReasonPhrase).To(Equal("test error"))
 			})
 
+			It("packs a CONNECTION_CLOSE only in the lowest encryption level available, if coalescing is disabled", func() {
+				packer.disableCoalescing = true
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(2), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(2))
+				sealingManager.EXPECT().GetInitialSealer().Return(nil, handshake.ErrKeysDropped)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(getSealer(), nil)
+				p, err := packer.PackConnectionClose(qerr.NewError(qerr.ProtocolViolation, "test error"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].header.Type).To(Equal(protocol.PacketTypeHandshake))
+				Expect(p.packets[0].frames).To(HaveLen(1))
+				Expect(p.packets[0].frames[0].Frame).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
+			})
+
 			It("packs a CONNECTION_CLOSE in all available encryption levels, as a client", func() {
 				packer.perspective = protocol.PerspectiveClient
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(1), protocol.PacketNumberLen2)
@@ -505,6 +523,22 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.frames).To(Equal(frames))
 				Expect(p.buffer.Len()).ToNot(BeZero())
+				Expect(p.sendReason).To(Equal(logging.PacketSendReasonNew))
+			})
+
+			It("packs retransmissions", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				f := &wire.MaxDataFrame{MaximumData: 0x1337}
+				retransmissionQueue.AddAppData(f)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.frames).To(Equal([]ackhandler.Frame{{Frame: f, IsRetransmission: true}}))
+				Expect(p.sendReason).To(Equal(logging.PacketSendReasonRetransmission))
 			})
 
 			It("accounts for the space consumed by control frames", func() {
@@ -780,6 +814,44 @@ var _ = Describe("Packet packer", func() {
 					Expect(err).ToNot(HaveOccurred())
 				})
 			})
+
+			Context("greasing the QUIC bit", func() {
+				// getQUICBits packs a number of short headers and returns how often the QUIC bit was set and unset
+				getQUICBits := func() (set, unset int) {
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2).AnyTimes()
+					for i := 0; i < 100; i++ {
+						b := &bytes.Buffer{}
+						Expect(packer.getShortHeader(protocol.KeyPhaseZero).Write(b, packer.version)).To(Succeed())
+						if b.Bytes()[0]&0x40 > 0 {
+							set++
+						} else {
+							unset++
+						}
+					}
+					return
+				}
+
+				It("greases the QUIC bit, if both endpoints sent the grease_quic_bit transport parameter", func() {
+					packer.enableGreaseQUICBit = true
+					packer.HandleTransportParameters(&wire.TransportParameters{GreaseQUICBit: true})
+					set, unset := getQUICBits()
+					Expect(set).ToNot(BeZero())
+					Expect(unset).ToNot(BeZero())
+				})
+
+				It("doesn't grease the QUIC bit, if the peer didn't send the grease_quic_bit transport parameter", func() {
+					packer.enableGreaseQUICBit = true
+					packer.HandleTransportParameters(&wire.TransportParameters{})
+					_, unset := getQUICBits()
+					Expect(unset).To(BeZero())
+				})
+
+				It("doesn't grease the QUIC bit, if it is not enabled", func() {
+					packer.HandleTransportParameters(&wire.TransportParameters{GreaseQUICBit: true})
+					_, unset := getQUICBits()
+					Expect(unset).To(BeZero())
+				})
+			})
 		})
 
 		Context("packing crypto packets", func() {
@@ -862,6 +934,27 @@ var _ = Describe("Packet packer", func() {
 				Expect(rest).To(BeEmpty())
 			})
 
+			It("doesn't coalesce packets, if coalescing is disabled", func() {
+				packer.disableCoalescing = true
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x42, Data: []byte("initial")}
+				})
+				// don't EXPECT any calls for the Handshake packet number space
+				p, err := packer.PackCoalescedPacket(protocol.MaxByteCount)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				hdr, _, rest, err := wire.ParsePacket(p.buffer.Data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rest).To(BeEmpty())
+			})
+
 			It("packs a coalesced packet with Initial / 0-RTT, and pads it", func() {
 				packer.perspective = protocol.PerspectiveClient
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
@@ -1068,7 +1161,8 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(p.packets).To(HaveLen(1))
 				Expect(p.packets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
-				Expect(p.packets[0].frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				Expect(p.packets[0].frames).To(Equal([]ackhandler.Frame{{Frame: f, IsRetransmission: true}}))
+				Expect(p.packets[0].sendReason).To(Equal(logging.PacketSendReasonRetransmission))
 				Expect(p.packets[0].header.IsLongHeader).To(BeTrue())
 				checkLength(p.buffer.Data)
 			})
@@ -1141,6 +1235,27 @@ var _ = Describe("Packet packer", func() {
 				Expect(cf.Data).To(Equal([]byte("foobar")))
 			})
 
+			It("pads the Initial packet itself, if coalescing is disabled", func() {
+				packer.disableCoalescing = true
+				f := &wire.CryptoFrame{Data: []byte("foobar")}
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).Return(f)
+				packer.perspective = protocol.PerspectiveClient
+				p, err := packer.PackCoalescedPacket(protocol.MaxByteCount)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.buffer.Len()).To(BeEquivalentTo(maxPacketSize))
+				Expect(p.packets).To(HaveLen(1))
+				Expect(p.packets[0].length).To(BeEquivalentTo(maxPacketSize))
+				hdr, _, rest, err := wire.ParsePacket(p.buffer.Data, 0)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rest).To(BeEmpty())
+			})
+
 			It("adds an ACK frame", func() {
 				f := &wire.CryptoFrame{Data: []byte("foobar")}
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 42, Largest: 1337}}}
@@ -1180,6 +1295,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
 				Expect(packet.frames).To(HaveLen(1))
 				Expect(packet.frames[0].Frame).To(Equal(f))
+				Expect(packet.sendReason).To(Equal(logging.PacketSendReasonPTOProbe))
 				Expect(packet.buffer.Len()).To(BeNumerically("<", protocol.MinInitialPacketSize))
 				checkLength(packet.buffer.Data)
 			})
@@ -1252,6 +1368,32 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet).To(BeNil())
 			})
 		})
+
+		Context("packing path probe packets", func() {
+			It("packs a padded 1-RTT packet that only contains the frames passed in", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				packet, err := packer.PackPathProbePacket([]ackhandler.Frame{{Frame: f}}, 1200)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
+				Expect(packet.header.PacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+				Expect(packet.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				Expect(packet.sendReason).To(Equal(logging.PacketSendReasonPathProbe))
+				Expect(packet.buffer.Len()).To(BeEquivalentTo(1200))
+			})
+
+			It("doesn't pad packets if the minimum size is smaller than the packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				f := &wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				packet, err := packer.PackPathProbePacket([]ackhandler.Frame{{Frame: f}}, 10)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(packet.buffer.Len()).To(BeNumerically("<", 50))
+			})
+		})
 	})
 })
 
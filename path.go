@@ -0,0 +1,657 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// maxPathProbes is the number of PATH_CHALLENGE frames sent on a new path, before path validation fails.
+const maxPathProbes = 5
+
+// ErrPathValidationFailed is returned by Session.MigratePath when the peer didn't respond to any PATH_CHALLENGE sent on the new path.
+// When Config.RequireAddressValidationOnRebind is set, the server closes the connection with this error
+// if the validation of a new peer address fails.
+var ErrPathValidationFailed = errors.New("path validation failed")
+
+// A switchableSendConn is a sendConn that can be switched to a new path when the connection is migrated.
+type switchableSendConn struct {
+	mutex sync.RWMutex
+	conn  sendConn
+}
+
+var _ sendConn = &switchableSendConn{}
+
+func newSwitchableSendConn(c sendConn) *switchableSendConn {
+	return &switchableSendConn{conn: c}
+}
+
+func (c *switchableSendConn) get() sendConn {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.conn
+}
+
+func (c *switchableSendConn) Switch(conn sendConn) {
+	c.mutex.Lock()
+	c.conn = conn
+	c.mutex.Unlock()
+}
+
+func (c *switchableSendConn) Write(p []byte, ecn protocol.ECN) error { return c.get().Write(p, ecn) }
+func (c *switchableSendConn) SupportsECN() bool                      { return c.get().SupportsECN() }
+func (c *switchableSendConn) Close() error                           { return c.get().Close() }
+func (c *switchableSendConn) LocalAddr() net.Addr                    { return c.get().LocalAddr() }
+func (c *switchableSendConn) RemoteAddr() net.Addr                   { return c.get().RemoteAddr() }
+
+func (c *switchableSendConn) WithRemoteAddr(addr net.Addr) sendConn {
+	return c.get().WithRemoteAddr(addr)
+}
+
+// A pathProbe is a path that is being validated.
+// Except for validatedChan, it must only be accessed from the run loop.
+type pathProbe struct {
+	conn       sendConn
+	challenges [][8]byte
+	validated  bool
+	// set when migrating to the server's preferred address.
+	// This is allowed even if the server disabled active connection migration.
+	toPreferredAddress bool
+	// previous is the last validated path.
+	// It is set when the server switched to an unvalidated peer address (e.g. after a NAT rebinding),
+	// and is used again if the validation of the new address fails.
+	previous sendConn
+	// Until a new peer address is validated, the server must not send more than amplificationFactor times
+	// the number of bytes received from that address (anti-amplification limit).
+	// Only used by the server, for paths it didn't switch to yet.
+	amplificationFactor protocol.ByteCount
+	bytesReceived       protocol.ByteCount
+	bytesSent           protocol.ByteCount
+	// reads packets received on this path
+	// Only set for paths created by MigratePath.
+	reader *pathReader
+	// validatedChan is closed when the client migrated to the path,
+	// or when the server validated a new peer address
+	validatedChan chan struct{}
+}
+
+func newPathProbe(conn sendConn, amplificationFactor int) *pathProbe {
+	return &pathProbe{
+		conn:                conn,
+		amplificationFactor: protocol.ByteCount(amplificationFactor),
+		validatedChan:       make(chan struct{}),
+	}
+}
+
+// newChallenge generates the data for a new PATH_CHALLENGE frame.
+// The data is read from r. Only the data of the last maxPathProbes challenges is kept.
+// If reading from r fails, no challenge is added.
+// The data must not be predictable, so a PATH_CHALLENGE must not be sent in that case.
+func (p *pathProbe) newChallenge(r io.Reader) ([8]byte, error) {
+	var data [8]byte
+	if _, err := io.ReadFull(r, data[:]); err != nil {
+		return [8]byte{}, fmt.Errorf("generating PATH_CHALLENGE data failed: %w", err)
+	}
+	if len(p.challenges) >= maxPathProbes {
+		p.challenges = p.challenges[1:]
+	}
+	p.challenges = append(p.challenges, data)
+	return data, nil
+}
+
+// amplificationWindow returns the number of bytes that can be sent on the path
+// before the anti-amplification limit is reached.
+func (p *pathProbe) amplificationWindow() protocol.ByteCount {
+	if p.bytesSent >= p.amplificationFactor*p.bytesReceived {
+		return 0
+	}
+	return p.amplificationFactor*p.bytesReceived - p.bytesSent
+}
+
+func (p *pathProbe) hasChallenge(data [8]byte) bool {
+	for _, c := range p.challenges {
+		if c == data {
+			return true
+		}
+	}
+	return false
+}
+
+type pathProbeRequest struct {
+	probe  *pathProbe
+	cancel bool
+	// set by the server when the validation of a new peer address failed
+	failed  bool
+	errChan chan error
+}
+
+func (s *session) CurrentPath() PathInfo {
+	s.connStateMutex.Lock()
+	defer s.connStateMutex.Unlock()
+	conn := s.path.get()
+	return PathInfo{
+		LocalAddr:  conn.LocalAddr(),
+		RemoteAddr: conn.RemoteAddr(),
+		Validated:  s.pathValidated,
+		RTT:        s.rttStatsSnapshot.SmoothedRTT(),
+	}
+}
+
+func (s *session) MigratePath(ctx context.Context, conn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only the client can migrate a connection")
+	}
+	var path sendConn = newSendConn(conn, s.RemoteAddr(), 0)
+	if s.config.PacketInterceptor != nil {
+		path = newInterceptingSendConn(path, s.config.PacketInterceptor)
+	}
+	probe := newPathProbe(path, s.config.AmplificationFactor)
+	probe.reader = newPathReader(conn, path)
+	go s.readFromPath(probe.reader)
+
+	if err := s.validatePath(ctx, probe); err != nil {
+		// Make sure that a PATH_RESPONSE received later doesn't cause a migration.
+		if s.submitPathProbeRequest(pathProbeRequest{probe: probe, cancel: true}) == nil {
+			select {
+			case <-probe.validatedChan:
+				return nil
+			default:
+			}
+		}
+		probe.reader.stop()
+		return err
+	}
+	return nil
+}
+
+// validatePath sends PATH_CHALLENGE frames on the path, until the path is validated,
+// maxPathProbes PATH_CHALLENGE frames were sent, or the context is canceled.
+// When validating a peer address, the server pads the PATH_CHALLENGE frames only as far as
+// the anti-amplification limit of that address allows, and skips them once the limit is reached.
+func (s *session) validatePath(ctx context.Context, probe *pathProbe) error {
+	s.connStateMutex.Lock()
+	timeout := s.rttStatsSnapshot.PTO(true)
+	s.connStateMutex.Unlock()
+	for i := 0; i < maxPathProbes; i++ {
+		if err := s.submitPathProbeRequest(pathProbeRequest{probe: probe}); err != nil {
+			return err
+		}
+		timer := s.clock.NewTimer(timeout)
+		select {
+		case <-probe.validatedChan:
+			timer.Stop()
+			return nil
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.ctx.Done():
+			timer.Stop()
+			return errors.New("session closed")
+		case <-timer.Chan():
+			timeout *= 2
+		}
+	}
+	return ErrPathValidationFailed
+}
+
+// newPreferredAddressParameter issues a connection ID for use with the preferred address,
+// and returns the preferred_address transport parameter.
+func (s *session) newPreferredAddressParameter() *wire.PreferredAddress {
+	// A server that uses zero-length connection IDs must not send a preferred_address.
+	if s.config.ConnectionIDLength == 0 {
+		return nil
+	}
+	connID, token, err := s.connIDGenerator.IssuePreferredAddressConnID()
+	if err != nil {
+		s.logger.Errorf("Issuing a connection ID for the preferred address failed: %s", err)
+		return nil
+	}
+	pa := &wire.PreferredAddress{
+		IPv4:                net.IPv4zero.To4(),
+		IPv6:                net.IPv6zero,
+		ConnectionID:        connID,
+		StatelessResetToken: token,
+	}
+	if addr := s.config.PreferredAddress.IPv4; addr != nil {
+		pa.IPv4 = addr.IP.To4()
+		pa.IPv4Port = uint16(addr.Port)
+	}
+	if addr := s.config.PreferredAddress.IPv6; addr != nil {
+		pa.IPv6 = addr.IP.To16()
+		pa.IPv6Port = uint16(addr.Port)
+	}
+	return pa
+}
+
+// maybeMigrateToPreferredAddress is called when the handshake is confirmed.
+// If the server sent a preferred_address, the client validates the path to that address, and migrates to it.
+func (s *session) maybeMigrateToPreferredAddress() {
+	if s.perspective == protocol.PerspectiveServer || s.config.DisablePathMigrationToPreferredAddress ||
+		s.peerParams == nil || s.peerParams.PreferredAddress == nil {
+		return
+	}
+	addr := preferredAddressFor(s.conn.RemoteAddr(), s.peerParams.PreferredAddress)
+	if addr == nil {
+		s.logger.Debugf("Server sent a preferred_address, but none of the same IP version.")
+		return
+	}
+	s.logger.Debugf("Migrating to the server's preferred address %s", addr)
+	probe := newPathProbe(s.conn.WithRemoteAddr(addr), s.config.AmplificationFactor)
+	probe.toPreferredAddress = true
+	go func() {
+		if err := s.validatePath(s.ctx, probe); err != nil {
+			s.logger.Debugf("Migrating to the server's preferred address failed: %s", err)
+			// Make sure that a PATH_RESPONSE received later doesn't cause a migration.
+			_ = s.submitPathProbeRequest(pathProbeRequest{probe: probe, cancel: true})
+		}
+	}()
+}
+
+// preferredAddressFor returns the preferred address of the same IP version as the address currently used.
+// It returns nil if the server didn't send an address of that IP version.
+func preferredAddressFor(remoteAddr net.Addr, pa *wire.PreferredAddress) *net.UDPAddr {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	ip, port := pa.IPv6, pa.IPv6Port
+	if udpAddr.IP.To4() != nil {
+		ip, port = pa.IPv4, pa.IPv4Port
+	}
+	if port == 0 || ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
+
+func (s *session) submitPathProbeRequest(r pathProbeRequest) error {
+	r.errChan = make(chan error, 1)
+	select {
+	case s.pathProbeRequests <- r:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	return <-r.errChan
+}
+
+// handlePathProbeRequest is called from the run loop
+func (s *session) handlePathProbeRequest(r pathProbeRequest) error {
+	if r.cancel {
+		if s.probingPath == r.probe {
+			s.probingPath = nil
+		}
+		return nil
+	}
+	if r.probe.validated {
+		return nil
+	}
+	if s.perspective == protocol.PerspectiveServer && s.probingPath != r.probe {
+		// The peer's address changed again while this address was being validated.
+		return errors.New("peer address validation superseded")
+	}
+	if r.failed {
+		s.probingPath = nil
+		if r.probe.previous != nil {
+			s.logger.Debugf("Validation of peer address %s failed. Reverting to %s.", r.probe.conn.RemoteAddr(), r.probe.previous.RemoteAddr())
+			s.migrateTo(r.probe.previous, true)
+			return nil
+		}
+		s.closeLocal(ErrPathValidationFailed)
+		return nil
+	}
+	if s.probingPath != r.probe {
+		if !s.handshakeConfirmed {
+			return ErrHandshakeNotConfirmed
+		}
+		if s.peerParams.DisableActiveMigration && !r.probe.toPreferredAddress {
+			return errors.New("the peer disabled active connection migration")
+		}
+		if s.probingPath != nil {
+			return errors.New("migration to another path already in progress")
+		}
+		// Connection IDs must not be used on more than one path.
+		if !s.connIDManager.ChangeConnectionID() {
+			return errors.New("no unused connection ID available")
+		}
+		s.probingPath = r.probe
+	}
+	minSize := protocol.ByteCount(protocol.MinInitialPacketSize)
+	if s.perspective == protocol.PerspectiveServer {
+		// Respect the anti-amplification limit until the address is validated.
+		var window protocol.ByteCount
+		if r.probe.conn == s.path.get() {
+			// The server already switched to the peer's new address.
+			window = s.sentPacketHandler.AmplificationWindow()
+		} else {
+			window = r.probe.amplificationWindow()
+		}
+		if window == 0 {
+			return nil
+		}
+		minSize = utils.MinByteCount(minSize, window)
+	}
+	data, err := r.probe.newChallenge(s.config.Rand)
+	if err != nil {
+		s.closeLocal(err)
+		return err
+	}
+	s.logger.Debugf("Probing new path (local address: %s)", r.probe.conn.LocalAddr())
+	size, err := s.sendOnPath(r.probe.conn, minSize, &wire.PathChallengeFrame{Data: data})
+	if s.perspective == protocol.PerspectiveServer && r.probe.conn != s.path.get() {
+		r.probe.bytesSent += size
+	}
+	return err
+}
+
+// A pathReader reads packets from the net.PacketConn passed to MigratePath.
+// It is stopped when the path validation fails, when the client migrates away from the path, or when the session is closed.
+type pathReader struct {
+	conn net.PacketConn
+	path sendConn
+
+	stopOnce    sync.Once
+	stopReading chan struct{}
+	readingDone chan struct{}
+}
+
+func newPathReader(conn net.PacketConn, path sendConn) *pathReader {
+	return &pathReader{
+		conn:        conn,
+		path:        path,
+		stopReading: make(chan struct{}),
+		readingDone: make(chan struct{}),
+	}
+}
+
+// stop stops reading from the net.PacketConn, and blocks until the Go routine returned.
+// It is safe to call it multiple times.
+func (r *pathReader) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopReading)
+		// unblock the ReadFrom call
+		_ = r.conn.SetReadDeadline(time.Now())
+		<-r.readingDone
+		_ = r.conn.SetReadDeadline(time.Time{})
+	})
+}
+
+// readFromPath reads packets from the net.PacketConn of a new path.
+// Since these packets don't pass through the packet handler map of the original packet conn,
+// the packet handler map is asked to handle stateless resets and to check the connection ID.
+func (s *session) readFromPath(r *pathReader) {
+	defer close(r.readingDone)
+	reader := newPacketReader(r.conn)
+	for {
+		buffer := getPacketBuffer()
+		data := buffer.Data[:protocol.MaxReceivePacketSize]
+		n, addr, ecn, timestamp, err := reader.ReadPacket(data)
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		select {
+		case <-r.stopReading:
+			buffer.Release()
+			return
+		case <-s.ctx.Done():
+			buffer.Release()
+			return
+		default:
+		}
+		s.runner.HandlePacketOnPath(&receivedPacket{
+			remoteAddr: addr,
+			timestamp:  timestamp,
+			ecn:        ecn,
+			data:       data[:n],
+			buffer:     buffer,
+			path:       r.path,
+		}, s)
+	}
+}
+
+// newPathFor returns the path that a packet was received on, if that's not the active path.
+func (s *session) newPathFor(p *receivedPacket) sendConn {
+	if p.path != nil {
+		if p.path == s.path.get() {
+			return nil
+		}
+		return p.path
+	}
+	if s.perspective == protocol.PerspectiveClient || p.remoteAddr == nil {
+		return nil
+	}
+	if !s.config.AllowConnectionMigration && !s.config.RequireAddressValidationOnRebind {
+		return nil
+	}
+	addr := p.remoteAddr.String()
+	if addr == s.conn.RemoteAddr().String() {
+		return nil
+	}
+	if s.probingPath != nil && s.probingPath.conn.RemoteAddr().String() == addr {
+		return s.probingPath.conn
+	}
+	return s.conn.WithRemoteAddr(p.remoteAddr)
+}
+
+// handlePathChallengeOnNewPath answers a PATH_CHALLENGE received on a path other than the active path.
+// The server also starts validating the new peer address.
+func (s *session) handlePathChallengeOnNewPath(f *wire.PathChallengeFrame, path sendConn, rcvdSize protocol.ByteCount) {
+	if s.perspective == protocol.PerspectiveServer && s.config.DisableActiveMigration {
+		// We sent the disable_active_migration transport parameter, so the client must not probe new paths.
+		// Not answering the PATH_CHALLENGE makes the path validation fail.
+		s.logger.Debugf("Ignoring PATH_CHALLENGE on a new path, since active migration is disabled.")
+		return
+	}
+	frames := []wire.Frame{&wire.PathResponseFrame{Data: f.Data}}
+	// The new path hasn't been validated yet.
+	// Respect the anti-amplification limit.
+	minSize := utils.MinByteCount(protocol.MinInitialPacketSize, protocol.ByteCount(s.config.AmplificationFactor)*rcvdSize)
+	var probe *pathProbe
+	if s.perspective == protocol.PerspectiveServer {
+		if s.probingPath == nil || s.probingPath.conn != path {
+			s.probingPath = newPathProbe(path, s.config.AmplificationFactor)
+			// Packets received on a path are only attributed to it once the probe exists.
+			s.probingPath.bytesReceived = rcvdSize
+		}
+		probe = s.probingPath
+		if !probe.validated {
+			data, err := probe.newChallenge(s.config.Rand)
+			if err != nil {
+				s.closeLocal(err)
+				return
+			}
+			frames = append(frames, &wire.PathChallengeFrame{Data: data})
+		}
+		minSize = utils.MinByteCount(protocol.MinInitialPacketSize, probe.amplificationWindow())
+	}
+	size, err := s.sendOnPath(path, minSize, frames...)
+	if probe != nil {
+		probe.bytesSent += size
+	}
+	if err != nil {
+		s.logger.Debugf("Sending PATH_RESPONSE on new path failed: %s", err)
+	}
+}
+
+func (s *session) handlePathResponseFrame(f *wire.PathResponseFrame) {
+	p := s.probingPath
+	if p == nil || !p.hasChallenge(f.Data) {
+		s.logger.Debugf("Ignoring PATH_RESPONSE that doesn't match any PATH_CHALLENGE sent.")
+		return
+	}
+	if p.validated {
+		return
+	}
+	p.validated = true
+	if s.tracer != nil {
+		s.tracer.PathValidated(p.conn.LocalAddr(), p.conn.RemoteAddr())
+	}
+	if p.conn == s.path.get() {
+		// The server already switched to this path when the peer's address changed.
+		s.logger.Debugf("Validated peer address %s", p.conn.RemoteAddr())
+		s.probingPath = nil
+		s.sentPacketHandler.SetPathValidated()
+		s.connStateMutex.Lock()
+		s.pathValidated = true
+		s.connStateMutex.Unlock()
+		close(p.validatedChan)
+		return
+	}
+	if s.perspective == protocol.PerspectiveServer {
+		// The path is only used once the client sends a non-probing packet on it.
+		s.logger.Debugf("Validated new peer address %s", p.conn.RemoteAddr())
+		close(p.validatedChan)
+		return
+	}
+	s.logger.Debugf("Validated new path (local address: %s). Migrating.", p.conn.LocalAddr())
+	s.probingPath = nil
+	// No packets are received on the path that was used until now any more.
+	if s.pathReader != nil {
+		go s.pathReader.stop()
+	}
+	s.pathReader = p.reader
+	s.migrateTo(p.conn, true)
+	close(p.validatedChan)
+}
+
+// maybeMigratePeer is called by the server when a non-probing 1-RTT packet is received.
+// If the packet was sent from a validated new peer address, the server migrates to that address.
+// If the peer's address changed without a prior path validation (e.g. due to a NAT rebinding),
+// the server migrates to the new address right away, and starts validating it.
+// Until the new address is validated, the anti-amplification limit applies,
+// and if the validation fails, the server reverts to the last validated address.
+// If Config.RequireAddressValidationOnRebind is set, the new address is validated first,
+// and the connection is closed if that fails.
+func (s *session) maybeMigratePeer(p *receivedPacket, pn protocol.PacketNumber) {
+	if s.perspective == protocol.PerspectiveClient || p.remoteAddr == nil {
+		return
+	}
+	// Reordered packets must not cause a migration back to an old address.
+	if pn < s.largestNonProbingPacketNumber {
+		return
+	}
+	s.largestNonProbingPacketNumber = pn
+	addr := p.remoteAddr.String()
+	if addr == s.conn.RemoteAddr().String() {
+		return
+	}
+	probe := s.probingPath
+	if probe != nil && probe.validated && addr == probe.conn.RemoteAddr().String() {
+		s.logger.Debugf("Peer migrated to %s", p.remoteAddr)
+		s.probingPath = nil
+		s.migrateTo(probe.conn, true)
+		// Connection IDs must not be used on more than one path.
+		s.connIDManager.ChangeConnectionID()
+		return
+	}
+	if !s.handshakeConfirmed {
+		return
+	}
+	if s.config.RequireAddressValidationOnRebind {
+		if probe != nil && addr == probe.conn.RemoteAddr().String() {
+			// validation of this address is already in progress
+			return
+		}
+		s.logger.Debugf("Peer's address changed to %s. Validating the new address before using it.", p.remoteAddr)
+		probe = newPathProbe(s.conn.WithRemoteAddr(p.remoteAddr), s.config.AmplificationFactor)
+		// This packet was received on the new path.
+		probe.bytesReceived = p.Size()
+		s.probingPath = probe
+		go s.validatePeerAddress(probe)
+		return
+	}
+	if !s.config.AllowConnectionMigration {
+		return
+	}
+	s.logger.Debugf("Peer's address changed to %s. Validating the new address.", p.remoteAddr)
+	previous := s.path.get()
+	if probe != nil && probe.conn == previous && probe.previous != nil {
+		// The peer's address changed again before the current address was validated.
+		previous = probe.previous
+	}
+	if probe == nil || addr != probe.conn.RemoteAddr().String() {
+		probe = newPathProbe(s.conn.WithRemoteAddr(p.remoteAddr), s.config.AmplificationFactor)
+		s.probingPath = probe
+	}
+	probe.previous = previous
+	s.migrateTo(probe.conn, false)
+	// This packet was received on the new path.
+	s.sentPacketHandler.ReceivedBytes(p.Size())
+	go s.validatePeerAddress(probe)
+}
+
+// validatePeerAddress is run by the server in a separate Go routine.
+// If the new peer address can't be validated, the server reverts to the previous path,
+// or closes the connection if it didn't switch to the new address yet.
+func (s *session) validatePeerAddress(probe *pathProbe) {
+	if err := s.validatePath(s.ctx, probe); err == ErrPathValidationFailed {
+		s.submitPathProbeRequest(pathProbeRequest{probe: probe, failed: true})
+	}
+}
+
+func (s *session) migrateTo(conn sendConn, validated bool) {
+	// The RTT and the available bandwidth on the new path might be completely different,
+	// unless only the peer's port changed.
+	resetCongestion := !onlyPortChanged(s.path.get(), conn)
+	if resetCongestion {
+		s.rttStats.OnConnectionMigration()
+	}
+	s.sentPacketHandler.MigratedPath(validated, resetCongestion)
+	s.connStateMutex.Lock()
+	s.path.Switch(conn)
+	s.rttStatsSnapshot = *s.rttStats
+	s.pathValidated = validated
+	s.connStateMutex.Unlock()
+}
+
+// onlyPortChanged says if the only difference between two paths is the peer's port number.
+func onlyPortChanged(a, b sendConn) bool {
+	if a.LocalAddr().String() != b.LocalAddr().String() {
+		return false
+	}
+	addrA, okA := a.RemoteAddr().(*net.UDPAddr)
+	addrB, okB := b.RemoteAddr().(*net.UDPAddr)
+	return okA && okB && addrA.IP.Equal(addrB.IP)
+}
+
+// sendOnPath sends a packet containing frames on a path other than the active path.
+// The frames are not retransmitted when the packet is lost.
+// It returns the size of the packet.
+func (s *session) sendOnPath(conn sendConn, minSize protocol.ByteCount, frames ...wire.Frame) (protocol.ByteCount, error) {
+	fs := make([]ackhandler.Frame, 0, len(frames))
+	for _, f := range frames {
+		fs = append(fs, ackhandler.Frame{Frame: f, OnLost: func(wire.Frame) {}})
+	}
+	packet, err := s.packer.PackPathProbePacket(fs, minSize)
+	if err != nil {
+		return 0, err
+	}
+	now := s.clock.Now()
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
+	s.connIDManager.SentPacket()
+	s.logPacket(now, packet)
+	size := packet.buffer.Len()
+	s.stats.sentDatagram(1, size)
+	err = conn.Write(packet.buffer.Data, protocol.ECNNon)
+	packet.buffer.Release()
+	return size, err
+}
+
+// isProbingFrame says if a frame is a probing frame (see section 9.1 of the QUIC transport draft).
+// PADDING frames are probing frames as well, but they are not returned by the frame parser.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	}
+	return false
+}
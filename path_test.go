@@ -0,0 +1,109 @@
+package quic
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path", func() {
+	Context("switchable send conn", func() {
+		It("switches to a new path", func() {
+			addr1 := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1}
+			addr2 := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 2), Port: 2}
+			c1 := NewMockSendConn(mockCtrl)
+			c1.EXPECT().LocalAddr().Return(addr1).AnyTimes()
+			c2 := NewMockSendConn(mockCtrl)
+			c2.EXPECT().LocalAddr().Return(addr2).AnyTimes()
+			c := newSwitchableSendConn(c1)
+			Expect(c.LocalAddr()).To(Equal(addr1))
+			c1.EXPECT().Write([]byte("foo"), gomock.Any())
+			Expect(c.Write([]byte("foo"), 0)).To(Succeed())
+			c.Switch(c2)
+			Expect(c.LocalAddr()).To(Equal(addr2))
+			c2.EXPECT().Write([]byte("bar"), gomock.Any())
+			Expect(c.Write([]byte("bar"), 0)).To(Succeed())
+		})
+	})
+
+	Context("path probes", func() {
+		It("recognizes the data of PATH_CHALLENGEs sent", func() {
+			p := newPathProbe(nil, protocol.DefaultAmplificationFactor)
+			c1, err := p.newChallenge(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			c2, err := p.newChallenge(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c1).ToNot(Equal(c2))
+			Expect(p.hasChallenge(c1)).To(BeTrue())
+			Expect(p.hasChallenge(c2)).To(BeTrue())
+			Expect(p.hasChallenge([8]byte{1, 2, 3, 4, 5, 6, 7, 8})).To(BeFalse())
+		})
+
+		It("only keeps the data of the last PATH_CHALLENGEs", func() {
+			p := newPathProbe(nil, protocol.DefaultAmplificationFactor)
+			first, err := p.newChallenge(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < maxPathProbes; i++ {
+				_, err := p.newChallenge(rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(p.challenges).To(HaveLen(maxPathProbes))
+			Expect(p.hasChallenge(first)).To(BeFalse())
+		})
+
+		It("errors when reading the data for a PATH_CHALLENGE fails", func() {
+			p := newPathProbe(nil, protocol.DefaultAmplificationFactor)
+			_, err := p.newChallenge(bytes.NewReader([]byte{1, 2, 3}))
+			Expect(err).To(MatchError("generating PATH_CHALLENGE data failed: unexpected EOF"))
+			Expect(errors.Is(err, io.ErrUnexpectedEOF)).To(BeTrue())
+			Expect(p.challenges).To(BeEmpty())
+		})
+
+		It("calculates the anti-amplification window using the amplification factor", func() {
+			p := newPathProbe(nil, 2)
+			Expect(p.amplificationWindow()).To(BeZero())
+			p.bytesReceived = 500
+			Expect(p.amplificationWindow()).To(Equal(protocol.ByteCount(1000)))
+			p.bytesSent = 900
+			Expect(p.amplificationWindow()).To(Equal(protocol.ByteCount(100)))
+			p.bytesSent = 1000
+			Expect(p.amplificationWindow()).To(BeZero())
+		})
+	})
+
+	Context("preferred address", func() {
+		pa := &wire.PreferredAddress{
+			IPv4:     net.IPv4(192, 168, 0, 1).To4(),
+			IPv4Port: 42,
+			IPv6:     net.ParseIP("2001:db8::1"),
+			IPv6Port: 1337,
+		}
+
+		It("chooses the address of the same IP version", func() {
+			Expect(preferredAddressFor(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}, pa)).To(Equal(&net.UDPAddr{IP: pa.IPv4, Port: 42}))
+			Expect(preferredAddressFor(&net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443}, pa)).To(Equal(&net.UDPAddr{IP: pa.IPv6, Port: 1337}))
+		})
+
+		It("doesn't return an address if none of the same IP version was sent", func() {
+			ipv6Only := &wire.PreferredAddress{IPv4: net.IPv4zero.To4(), IPv6: pa.IPv6, IPv6Port: pa.IPv6Port}
+			Expect(preferredAddressFor(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}, ipv6Only)).To(BeNil())
+		})
+	})
+
+	It("identifies probing frames", func() {
+		Expect(isProbingFrame(&wire.PathChallengeFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.PathResponseFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.NewConnectionIDFrame{})).To(BeTrue())
+		Expect(isProbingFrame(&wire.PingFrame{})).To(BeFalse())
+		Expect(isProbingFrame(&wire.StreamFrame{})).To(BeFalse())
+	})
+})
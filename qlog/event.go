@@ -128,6 +128,21 @@ func (e eventPacketSent) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKeyOmitEmpty("trigger", e.Trigger)
 }
 
+type eventDatagramSent struct {
+	Size protocol.ByteCount
+}
+
+var _ eventDetails = eventDatagramSent{}
+
+func (e eventDatagramSent) Category() category { return categoryTransport }
+func (e eventDatagramSent) Name() string       { return "datagrams_sent" }
+func (e eventDatagramSent) IsNil() bool        { return false }
+
+func (e eventDatagramSent) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.IntKey("count", 1)
+	enc.Int64Key("byte_length", int64(e.Size))
+}
+
 type eventPacketReceived struct {
 	PacketType  packetType
 	Header      packetHeader
@@ -293,6 +308,20 @@ func (e eventPacketLost) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("trigger", e.Trigger.String())
 }
 
+type eventSpuriousLoss struct {
+	PacketType   packetType
+	PacketNumber protocol.PacketNumber
+}
+
+func (e eventSpuriousLoss) Category() category { return categoryRecovery }
+func (e eventSpuriousLoss) Name() string       { return "spurious_loss" }
+func (e eventSpuriousLoss) IsNil() bool        { return false }
+
+func (e eventSpuriousLoss) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("packet_type", e.PacketType.String())
+	enc.Int64Key("packet_number", int64(e.PacketNumber))
+}
+
 type eventKeyUpdated struct {
 	Trigger    keyUpdateTrigger
 	KeyType    keyType
@@ -346,6 +375,8 @@ type eventTransportParameters struct {
 	AckDelayExponent        uint8
 	MaxAckDelay             time.Duration
 	ActiveConnectionIDLimit uint64
+	GreaseQUICBit           bool
+	MinAckDelay             *time.Duration
 
 	InitialMaxData                 protocol.ByteCount
 	InitialMaxStreamDataBidiLocal  protocol.ByteCount
@@ -379,6 +410,12 @@ func (e eventTransportParameters) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.Uint8KeyOmitEmpty("ack_delay_exponent", e.AckDelayExponent)
 	enc.FloatKeyOmitEmpty("max_ack_delay", milliseconds(e.MaxAckDelay))
 	enc.Uint64KeyOmitEmpty("active_connection_id_limit", e.ActiveConnectionIDLimit)
+	if e.GreaseQUICBit {
+		enc.BoolKey("grease_quic_bit", true)
+	}
+	if e.MinAckDelay != nil {
+		enc.FloatKey("min_ack_delay", milliseconds(*e.MinAckDelay))
+	}
 
 	enc.Int64KeyOmitEmpty("initial_max_data", int64(e.InitialMaxData))
 	enc.Int64KeyOmitEmpty("initial_max_stream_data_bidi_local", int64(e.InitialMaxStreamDataBidiLocal))
@@ -430,6 +467,70 @@ func (e eventLossTimerCanceled) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("event_type", "cancelled")
 }
 
+type eventAmplificationLimited struct{}
+
+func (e eventAmplificationLimited) Category() category { return categoryRecovery }
+func (e eventAmplificationLimited) Name() string       { return "amplification_limited" }
+func (e eventAmplificationLimited) IsNil() bool        { return false }
+
+func (e eventAmplificationLimited) MarshalJSONObject(enc *gojay.Encoder) {}
+
+type eventECNStateUpdated struct {
+	state   ecnState
+	trigger ecnStateTrigger
+}
+
+func (e eventECNStateUpdated) Category() category { return categoryRecovery }
+func (e eventECNStateUpdated) Name() string       { return "ecn_state_updated" }
+func (e eventECNStateUpdated) IsNil() bool        { return false }
+
+func (e eventECNStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("new", e.state.String())
+	enc.StringKeyOmitEmpty("trigger", e.trigger.String())
+}
+
+type eventConnectionIDReceived struct {
+	SequenceNumber uint64
+	ConnectionID   protocol.ConnectionID
+}
+
+func (e eventConnectionIDReceived) Category() category { return categoryConnectivity }
+func (e eventConnectionIDReceived) Name() string       { return "connection_id_received" }
+func (e eventConnectionIDReceived) IsNil() bool        { return false }
+
+func (e eventConnectionIDReceived) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("sequence_number", e.SequenceNumber)
+	enc.StringKey("connection_id", connectionID(e.ConnectionID).String())
+}
+
+type eventConnectionIDRetired struct {
+	SequenceNumber uint64
+}
+
+func (e eventConnectionIDRetired) Category() category { return categoryConnectivity }
+func (e eventConnectionIDRetired) Name() string       { return "connection_id_retired" }
+func (e eventConnectionIDRetired) IsNil() bool        { return false }
+
+func (e eventConnectionIDRetired) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("sequence_number", e.SequenceNumber)
+}
+
+type eventPathValidated struct {
+	SrcAddr  *net.UDPAddr
+	DestAddr *net.UDPAddr
+}
+
+func (e eventPathValidated) Category() category { return categoryConnectivity }
+func (e eventPathValidated) Name() string       { return "path_validated" }
+func (e eventPathValidated) IsNil() bool        { return false }
+
+func (e eventPathValidated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("src_ip", e.SrcAddr.IP.String())
+	enc.IntKey("src_port", e.SrcAddr.Port)
+	enc.StringKey("dst_ip", e.DestAddr.IP.String())
+	enc.IntKey("dst_port", e.DestAddr.Port)
+}
+
 type eventCongestionStateUpdated struct {
 	state congestionState
 }
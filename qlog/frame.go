@@ -57,6 +57,10 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalConnectionCloseFrame(enc, frame)
 	case *logging.HandshakeDoneFrame:
 		marshalHandshakeDoneFrame(enc, frame)
+	case *logging.AckFrequencyFrame:
+		marshalAckFrequencyFrame(enc, frame)
+	case *logging.ImmediateAckFrame:
+		marshalImmediateAckFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -102,6 +106,9 @@ func marshalAckFrame(enc *gojay.Encoder, f *logging.AckFrame) {
 	enc.StringKey("frame_type", "ack")
 	enc.FloatKeyOmitEmpty("ack_delay", milliseconds(f.DelayTime))
 	enc.ArrayKey("acked_ranges", ackRanges(f.AckRanges))
+	enc.Uint64KeyOmitEmpty("ect0", f.ECT0)
+	enc.Uint64KeyOmitEmpty("ect1", f.ECT1)
+	enc.Uint64KeyOmitEmpty("ce", f.ECNCE)
 }
 
 func marshalResetStreamFrame(enc *gojay.Encoder, f *logging.ResetStreamFrame) {
@@ -214,3 +221,15 @@ func marshalConnectionCloseFrame(enc *gojay.Encoder, f *logging.ConnectionCloseF
 func marshalHandshakeDoneFrame(enc *gojay.Encoder, _ *logging.HandshakeDoneFrame) {
 	enc.StringKey("frame_type", "handshake_done")
 }
+
+func marshalAckFrequencyFrame(enc *gojay.Encoder, f *logging.AckFrequencyFrame) {
+	enc.StringKey("frame_type", "ack_frequency")
+	enc.Uint64Key("sequence_number", f.SequenceNumber)
+	enc.Uint64Key("packet_tolerance", f.PacketTolerance)
+	enc.FloatKey("update_max_ack_delay", milliseconds(f.UpdateMaxAckDelay))
+	enc.BoolKey("ignore_order", f.IgnoreOrder)
+}
+
+func marshalImmediateAckFrame(enc *gojay.Encoder, _ *logging.ImmediateAckFrame) {
+	enc.StringKey("frame_type", "immediate_ack")
+}
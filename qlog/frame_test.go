@@ -80,6 +80,24 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals ACK frames with ECN counts", func() {
+		check(
+			&logging.AckFrame{
+				AckRanges: []logging.AckRange{{Smallest: 120, Largest: 120}},
+				ECT0:      10,
+				ECT1:      100,
+				ECNCE:     1000,
+			},
+			map[string]interface{}{
+				"frame_type":   "ack",
+				"acked_ranges": [][]float64{{120}},
+				"ect0":         10,
+				"ect1":         100,
+				"ce":           1000,
+			},
+		)
+	})
+
 	It("marshals RESET_STREAM frames", func() {
 		check(
 			&logging.ResetStreamFrame{
@@ -347,4 +365,31 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals ACK_FREQUENCY frames", func() {
+		check(
+			&logging.AckFrequencyFrame{
+				SequenceNumber:    42,
+				PacketTolerance:   10,
+				UpdateMaxAckDelay: 5 * time.Millisecond,
+				IgnoreOrder:       true,
+			},
+			map[string]interface{}{
+				"frame_type":           "ack_frequency",
+				"sequence_number":      42,
+				"packet_tolerance":     10,
+				"update_max_ack_delay": 5,
+				"ignore_order":         true,
+			},
+		)
+	})
+
+	It("marshals IMMEDIATE_ACK frames", func() {
+		check(
+			&logging.ImmediateAckFrame{},
+			map[string]interface{}{
+				"frame_type": "immediate_ack",
+			},
+		)
+	})
 })
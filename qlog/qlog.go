@@ -27,6 +27,10 @@ type tracer struct {
 var _ logging.Tracer = &tracer{}
 
 // NewTracer creates a new qlog tracer.
+// getLogWriter is called for every new connection, and the qlog of that connection is written to the returned io.WriteCloser.
+// The qlog is a single JSON document, which is completed when the connection is closed.
+// If the io.WriteCloser has a Flush() error method (e.g. when it wraps a bufio.Writer),
+// it is flushed whenever all pending events have been written, such that events are persisted before the connection is closed.
 func NewTracer(getLogWriter func(p logging.Perspective, connectionID []byte) io.WriteCloser) logging.Tracer {
 	return &tracer{getLogWriter: getLogWriter}
 }
@@ -99,6 +103,7 @@ func (t *connectionTracer) run() {
 		t.encodeErr = err
 	}
 	enc = gojay.NewEncoder(t.w)
+	flusher, _ := t.w.(interface{ Flush() error })
 	isFirst := true
 	for ev := range t.events {
 		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
@@ -111,6 +116,12 @@ func (t *connectionTracer) run() {
 			t.encodeErr = err
 		}
 		isFirst = false
+		// Flush once the batch of pending events is written.
+		if flusher != nil && t.encodeErr == nil && len(t.events) == 0 {
+			if err := flusher.Flush(); err != nil {
+				t.encodeErr = err
+			}
+		}
 	}
 }
 
@@ -201,6 +212,8 @@ func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective
 		AckDelayExponent:                tp.AckDelayExponent,
 		MaxAckDelay:                     tp.MaxAckDelay,
 		ActiveConnectionIDLimit:         tp.ActiveConnectionIDLimit,
+		GreaseQUICBit:                   tp.GreaseQUICBit,
+		MinAckDelay:                     tp.MinAckDelay,
 		InitialMaxData:                  tp.InitialMaxData,
 		InitialMaxStreamDataBidiLocal:   tp.InitialMaxStreamDataBidiLocal,
 		InitialMaxStreamDataBidiRemote:  tp.InitialMaxStreamDataBidiRemote,
@@ -211,7 +224,7 @@ func (t *connectionTracer) recordTransportParameters(sentBy protocol.Perspective
 	t.mutex.Unlock()
 }
 
-func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *logging.AckFrame, frames []logging.Frame, reason logging.PacketSendReason) {
 	numFrames := len(frames)
 	if ack != nil {
 		numFrames++
@@ -230,11 +243,18 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize proto
 		PacketType: packetType(logging.PacketTypeFromHeader(&hdr.Header)),
 		Header:     header,
 		Frames:     fs,
+		Trigger:    packetSendReason(reason).String(),
 	})
 	t.mutex.Unlock()
 }
 
-func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []logging.Frame) {
+func (t *connectionTracer) SentDatagram(size protocol.ByteCount, _ []*wire.ExtendedHeader) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventDatagramSent{Size: size})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, _ time.Time, frames []logging.Frame) {
 	fs := make([]frame, len(frames))
 	for i, f := range frames {
 		fs[i] = frame{Frame: f}
@@ -316,12 +336,25 @@ func (t *connectionTracer) LostPacket(encLevel protocol.EncryptionLevel, pn prot
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) SpuriousLoss(encLevel protocol.EncryptionLevel, pn protocol.PacketNumber) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventSpuriousLoss{
+		PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
+		PacketNumber: pn,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedCongestionState(state logging.CongestionState) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventCongestionStateUpdated{state: congestionState(state)})
 	t.mutex.Unlock()
 }
 
+// CongestionWindowUpdated doesn't record anything.
+// The congestion window is already recorded in the metrics_updated event.
+func (t *connectionTracer) CongestionWindowUpdated(protocol.ByteCount, protocol.ByteCount) {}
+
 func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventUpdatedPTO{Value: value})
@@ -379,6 +412,9 @@ func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 	t.mutex.Unlock()
 }
 
+// RejectedEarlyData is not logged. The rejection is already visible in the qlog, since the 0-RTT keys are retired.
+func (t *connectionTracer) RejectedEarlyData() {}
+
 func (t *connectionTracer) SetLossTimer(tt logging.TimerType, encLevel protocol.EncryptionLevel, timeout time.Time) {
 	t.mutex.Lock()
 	now := time.Now()
@@ -404,3 +440,49 @@ func (t *connectionTracer) LossTimerCanceled() {
 	t.recordEvent(time.Now(), &eventLossTimerCanceled{})
 	t.mutex.Unlock()
 }
+
+func (t *connectionTracer) BlockedByAmplificationLimit() {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventAmplificationLimited{})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) SetPacingTimer(time.Time) {}
+
+func (t *connectionTracer) ECNStateUpdated(state logging.ECNState, trigger logging.ECNStateTrigger) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventECNStateUpdated{state: ecnState(state), trigger: ecnStateTrigger(trigger)})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) NewConnectionIDReceived(seq uint64, connID protocol.ConnectionID) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventConnectionIDReceived{SequenceNumber: seq, ConnectionID: connID})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) RetiredConnectionID(seq uint64) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventConnectionIDRetired{SequenceNumber: seq})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) PathValidated(local, remote net.Addr) {
+	// ignore this event if we're not dealing with UDP addresses here
+	localAddr, ok := local.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	remoteAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathValidated{SrcAddr: localAddr, DestAddr: remoteAddr})
+	t.mutex.Unlock()
+}
+
+// RESET_STREAM and STOP_SENDING frames are already logged as part of the packet_received event.
+func (t *connectionTracer) ReceivedResetStream(protocol.StreamID, protocol.ApplicationErrorCode, protocol.ByteCount) {
+}
+func (t *connectionTracer) ReceivedStopSending(protocol.StreamID, protocol.ApplicationErrorCode) {}
@@ -42,6 +42,17 @@ func (w *limitedWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+type flushingWriter struct {
+	io.WriteCloser
+	buf     *bytes.Buffer
+	flushed chan []byte
+}
+
+func (w *flushingWriter) Flush() error {
+	w.flushed <- append([]byte{}, w.buf.Bytes()...)
+	return nil
+}
+
 type entry struct {
 	Time     time.Time
 	Category string
@@ -113,6 +124,20 @@ var _ = Describe("Tracing", func() {
 			Expect(buf.String()).To(ContainSubstring("writer full"))
 		})
 
+		It("flushes the writer when all pending events are written", func() {
+			flushed := make(chan []byte, 100)
+			tracer = newConnectionTracer(
+				&flushingWriter{WriteCloser: nopWriteCloser(buf), buf: buf, flushed: flushed},
+				protocol.PerspectiveServer,
+				protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+			)
+			tracer.UpdatedPTOCount(42)
+			var data []byte
+			Eventually(flushed).Should(Receive(&data))
+			Expect(string(data)).To(ContainSubstring(`"pto_count":42`))
+			tracer.Close()
+		})
+
 		Context("Events", func() {
 			exportAndParse := func() []entry {
 				tracer.Close()
@@ -211,6 +236,7 @@ var _ = Describe("Tracing", func() {
 					InitialSourceConnectionID:       protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
 					RetrySourceConnectionID:         &protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
 					ActiveConnectionIDLimit:         7,
+					GreaseQUICBit:                   true,
 				})
 				entry := exportAndParseSingle()
 				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
@@ -218,6 +244,7 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Name).To(Equal("parameters_set"))
 				ev := entry.Event
 				Expect(ev).To(HaveKeyWithValue("owner", "local"))
+				Expect(ev).To(HaveKeyWithValue("grease_quic_bit", true))
 				Expect(ev).To(HaveKeyWithValue("original_destination_connection_id", "deadc0de"))
 				Expect(ev).To(HaveKeyWithValue("initial_source_connection_id", "deadbeef"))
 				Expect(ev).To(HaveKeyWithValue("retry_source_connection_id", "decafbad"))
@@ -245,6 +272,16 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Name).To(Equal("parameters_set"))
 				ev := entry.Event
 				Expect(ev).ToNot(HaveKey("stateless_reset_token"))
+				Expect(ev).ToNot(HaveKey("grease_quic_bit"))
+				Expect(ev).ToNot(HaveKey("min_ack_delay"))
+			})
+
+			It("records the min_ack_delay", func() {
+				minAckDelay := 1500 * time.Microsecond
+				tracer.SentTransportParameters(&logging.TransportParameters{MinAckDelay: &minAckDelay})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("parameters_set"))
+				Expect(entry.Event).To(HaveKeyWithValue("min_ack_delay", 1.5))
 			})
 
 			It("records transport parameters without retry_source_connection_id", func() {
@@ -289,6 +326,7 @@ var _ = Describe("Tracing", func() {
 						&logging.MaxStreamDataFrame{StreamID: 42, MaximumStreamData: 987},
 						&logging.StreamFrame{StreamID: 123, Offset: 1234, Length: 6, Fin: true},
 					},
+					logging.PacketSendReasonNew,
 				)
 				entry := exportAndParseSingle()
 				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
@@ -296,6 +334,7 @@ var _ = Describe("Tracing", func() {
 				Expect(entry.Name).To(Equal("packet_sent"))
 				ev := entry.Event
 				Expect(ev).To(HaveKeyWithValue("packet_type", "handshake"))
+				Expect(ev).ToNot(HaveKey("trigger"))
 				Expect(ev).To(HaveKey("header"))
 				hdr := ev["header"].(map[string]interface{})
 				Expect(hdr).To(HaveKeyWithValue("packet_size", float64(987)))
@@ -308,7 +347,7 @@ var _ = Describe("Tracing", func() {
 				Expect(frames[1].(map[string]interface{})).To(HaveKeyWithValue("frame_type", "stream"))
 			})
 
-			It("records a sent packet, without an ACK", func() {
+			It("records a sent packet, with an ACK", func() {
 				tracer.SentPacket(
 					&logging.ExtendedHeader{
 						Header:       logging.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
@@ -317,6 +356,7 @@ var _ = Describe("Tracing", func() {
 					123,
 					&logging.AckFrame{AckRanges: []logging.AckRange{{Smallest: 1, Largest: 10}}},
 					[]logging.Frame{&logging.MaxDataFrame{MaximumData: 987}},
+					logging.PacketSendReasonNew,
 				)
 				entry := exportAndParseSingle()
 				ev := entry.Event
@@ -329,6 +369,39 @@ var _ = Describe("Tracing", func() {
 				Expect(frames[1].(map[string]interface{})).To(HaveKeyWithValue("frame_type", "max_data"))
 			})
 
+			It("records the reason why a packet was sent", func() {
+				tracer.SentPacket(
+					&logging.ExtendedHeader{
+						Header:       logging.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+						PacketNumber: 1337,
+					},
+					123,
+					nil,
+					[]logging.Frame{&logging.PingFrame{}},
+					logging.PacketSendReasonPTOProbe,
+				)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("packet_sent"))
+				Expect(entry.Event).To(HaveKeyWithValue("trigger", "pto_probe"))
+			})
+
+			It("records a sent datagram", func() {
+				tracer.SentDatagram(
+					1252,
+					[]*logging.ExtendedHeader{
+						{Header: logging.Header{IsLongHeader: true, Type: protocol.PacketTypeInitial}},
+						{Header: logging.Header{IsLongHeader: true, Type: protocol.PacketTypeHandshake}},
+					},
+				)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("transport"))
+				Expect(entry.Name).To(Equal("datagrams_sent"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("count", float64(1)))
+				Expect(ev).To(HaveKeyWithValue("byte_length", float64(1252)))
+			})
+
 			It("records a received packet", func() {
 				tracer.ReceivedPacket(
 					&logging.ExtendedHeader{
@@ -342,6 +415,7 @@ var _ = Describe("Tracing", func() {
 						PacketNumber: 1337,
 					},
 					789,
+					time.Now(),
 					[]logging.Frame{
 						&logging.MaxStreamDataFrame{StreamID: 42, MaximumStreamData: 987},
 						&logging.StreamFrame{StreamID: 123, Offset: 1234, Length: 6, Fin: true},
@@ -527,6 +601,47 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "reordering_threshold"))
 			})
 
+			It("records spurious losses", func() {
+				tracer.SpuriousLoss(protocol.Encryption1RTT, 42)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("recovery"))
+				Expect(entry.Name).To(Equal("spurious_loss"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("packet_type", "1RTT"))
+				Expect(ev).To(HaveKeyWithValue("packet_number", float64(42)))
+			})
+
+			It("records when sending is blocked by the amplification limit", func() {
+				tracer.BlockedByAmplificationLimit()
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("recovery"))
+				Expect(entry.Name).To(Equal("amplification_limited"))
+				Expect(entry.Event).To(BeEmpty())
+			})
+
+			It("records ECN state updates", func() {
+				tracer.ECNStateUpdated(logging.ECNStateTesting, logging.ECNTriggerNoTrigger)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("recovery"))
+				Expect(entry.Name).To(Equal("ecn_state_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("new", "testing"))
+				Expect(ev).ToNot(HaveKey("trigger"))
+			})
+
+			It("records ECN validation failures", func() {
+				tracer.ECNStateUpdated(logging.ECNStateFailed, logging.ECNFailedLostAllTestingPackets)
+				entry := exportAndParseSingle()
+				Expect(entry.Category).To(Equal("recovery"))
+				Expect(entry.Name).To(Equal("ecn_state_updated"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("new", "failed"))
+				Expect(ev).To(HaveKeyWithValue("trigger", "all ECN testing packets declared lost"))
+			})
+
 			It("records congestion state updates", func() {
 				tracer.UpdatedCongestionState(logging.CongestionStateCongestionAvoidance)
 				entry := exportAndParseSingle()
@@ -630,6 +745,42 @@ var _ = Describe("Tracing", func() {
 				Expect(keyTypes).To(ContainElement("client_1rtt_secret"))
 			})
 
+			It("records new connection IDs", func() {
+				tracer.NewConnectionIDReceived(42, protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("connectivity"))
+				Expect(entry.Name).To(Equal("connection_id_received"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("sequence_number", float64(42)))
+				Expect(ev).To(HaveKeyWithValue("connection_id", "deadbeef"))
+			})
+
+			It("records retired connection IDs", func() {
+				tracer.RetiredConnectionID(42)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("connectivity"))
+				Expect(entry.Name).To(Equal("connection_id_retired"))
+				Expect(entry.Event).To(HaveKeyWithValue("sequence_number", float64(42)))
+			})
+
+			It("records validated paths", func() {
+				tracer.PathValidated(
+					&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 42},
+					&net.UDPAddr{IP: net.IPv4(192, 168, 12, 34), Port: 24},
+				)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Category).To(Equal("connectivity"))
+				Expect(entry.Name).To(Equal("path_validated"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("src_ip", "192.168.13.37"))
+				Expect(ev).To(HaveKeyWithValue("src_port", float64(42)))
+				Expect(ev).To(HaveKeyWithValue("dst_ip", "192.168.12.34"))
+				Expect(ev).To(HaveKeyWithValue("dst_port", float64(24)))
+			})
+
 			It("records when the timer is set", func() {
 				timeout := time.Now().Add(137 * time.Millisecond)
 				tracer.SetLossTimer(logging.TimerTypePTO, protocol.EncryptionHandshake, timeout)
@@ -252,6 +252,27 @@ func (r packetLossReason) String() string {
 	}
 }
 
+// packetSendReason is logged as the trigger of the packet_sent event.
+// Packets that are sent for the first time don't have a trigger.
+type packetSendReason logging.PacketSendReason
+
+func (r packetSendReason) String() string {
+	switch logging.PacketSendReason(r) {
+	case logging.PacketSendReasonNew:
+		return ""
+	case logging.PacketSendReasonRetransmission:
+		return "retransmit"
+	case logging.PacketSendReasonPTOProbe:
+		return "pto_probe"
+	case logging.PacketSendReasonAckOnly:
+		return "ack_only"
+	case logging.PacketSendReasonPathProbe:
+		return "path_probe"
+	default:
+		panic("unknown send reason")
+	}
+}
+
 type packetDropReason logging.PacketDropReason
 
 func (r packetDropReason) String() string {
@@ -325,3 +346,37 @@ func (s congestionState) String() string {
 		panic("unknown congestion state")
 	}
 }
+
+type ecnState logging.ECNState
+
+func (s ecnState) String() string {
+	switch logging.ECNState(s) {
+	case logging.ECNStateTesting:
+		return "testing"
+	case logging.ECNStateCapable:
+		return "capable"
+	case logging.ECNStateFailed:
+		return "failed"
+	default:
+		panic("unknown ECN state")
+	}
+}
+
+type ecnStateTrigger logging.ECNStateTrigger
+
+func (t ecnStateTrigger) String() string {
+	switch logging.ECNStateTrigger(t) {
+	case logging.ECNTriggerNoTrigger:
+		return ""
+	case logging.ECNFailedNoECNCounts:
+		return "ACK doesn't contain ECN marks"
+	case logging.ECNFailedTooFewECNCounts:
+		return "ACK contains fewer ECN marks than packets sent with ECN marks"
+	case logging.ECNFailedMoreECNCountsThanSent:
+		return "ACK contains more ECN marks than packets sent with ECN marks"
+	case logging.ECNFailedLostAllTestingPackets:
+		return "all ECN testing packets declared lost"
+	default:
+		panic("unknown ECN state trigger")
+	}
+}
@@ -40,6 +40,14 @@ var _ = Describe("Types", func() {
 		Expect(packetType(logging.PacketTypeNotDetermined).String()).To(BeEmpty())
 	})
 
+	It("has a string representation for the packet send reason", func() {
+		Expect(packetSendReason(logging.PacketSendReasonNew).String()).To(BeEmpty())
+		Expect(packetSendReason(logging.PacketSendReasonRetransmission).String()).To(Equal("retransmit"))
+		Expect(packetSendReason(logging.PacketSendReasonPTOProbe).String()).To(Equal("pto_probe"))
+		Expect(packetSendReason(logging.PacketSendReasonAckOnly).String()).To(Equal("ack_only"))
+		Expect(packetSendReason(logging.PacketSendReasonPathProbe).String()).To(Equal("path_probe"))
+	})
+
 	It("has a string representation for the packet drop reason", func() {
 		Expect(packetDropReason(logging.PacketDropKeyUnavailable).String()).To(Equal("key_unavailable"))
 		Expect(packetDropReason(logging.PacketDropUnknownConnectionID).String()).To(Equal("unknown_connection_id"))
@@ -19,6 +19,7 @@ type receiveStreamI interface {
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	getBytesReceived() protocol.ByteCount
 }
 
 type receiveStream struct {
@@ -28,8 +29,9 @@ type receiveStream struct {
 
 	sender streamSender
 
-	frameQueue  *frameSorter
-	finalOffset protocol.ByteCount
+	frameQueue      *frameSorter
+	finalOffset     protocol.ByteCount
+	highestReceived protocol.ByteCount // the highest offset of stream data received
 
 	currentFrame       []byte
 	currentFrameDone   func()
@@ -38,7 +40,7 @@ type receiveStream struct {
 
 	closeForShutdownErr error
 	cancelReadErr       error
-	resetRemotelyErr    StreamError
+	resetRemotelyErr    *StreamError
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finRead           bool // set once we read a frame with a Fin
@@ -48,6 +50,10 @@ type receiveStream struct {
 	readChan chan struct{}
 	deadline time.Time
 
+	idleTimer *streamIdleTimer
+	// clock is used for the deadline
+	clock utils.Clock
+
 	flowController flowcontrol.StreamFlowController
 	version        protocol.VersionNumber
 }
@@ -59,6 +65,8 @@ func newReceiveStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	idleTimer *streamIdleTimer,
+	clock utils.Clock,
 	version protocol.VersionNumber,
 ) *receiveStream {
 	return &receiveStream{
@@ -68,6 +76,8 @@ func newReceiveStream(
 		frameQueue:     newFrameSorter(),
 		readChan:       make(chan struct{}, 1),
 		finalOffset:    protocol.MaxByteCount,
+		idleTimer:      idleTimer,
+		clock:          clock,
 		version:        version,
 	}
 }
@@ -79,7 +89,19 @@ func (s *receiveStream) StreamID() protocol.StreamID {
 // Read implements io.Reader. It is not thread safe!
 func (s *receiveStream) Read(p []byte) (int, error) {
 	s.mutex.Lock()
-	completed, n, err := s.readImpl(p)
+	completed, n, err := s.readImpl(p, false)
+	s.mutex.Unlock()
+
+	if completed {
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return n, err
+}
+
+// ReadAvailable reads the data that is currently available, without blocking. It is not thread safe!
+func (s *receiveStream) ReadAvailable(p []byte) (int, error) {
+	s.mutex.Lock()
+	completed, n, err := s.readImpl(p, true)
 	s.mutex.Unlock()
 
 	if completed {
@@ -88,7 +110,7 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 	return n, err
 }
 
-func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
+func (s *receiveStream) readImpl(p []byte, nonBlocking bool) (bool /*stream completed */, int, error) {
 	if s.finRead {
 		return false, 0, io.EOF
 	}
@@ -126,11 +148,11 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 
 			deadline := s.deadline
 			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
+				if !s.clock.Now().Before(deadline) {
 					return false, bytesRead, errDeadline
 				}
 				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
+					deadlineTimer = utils.NewTimerWithClock(s.clock)
 					defer deadlineTimer.Stop()
 				}
 				deadlineTimer.Reset(deadline)
@@ -139,6 +161,9 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			if s.currentFrame != nil || s.currentFrameIsLast {
 				break
 			}
+			if nonBlocking {
+				return false, bytesRead, nil
+			}
 
 			s.mutex.Unlock()
 			if deadline.IsZero() {
@@ -190,6 +215,9 @@ func (s *receiveStream) dequeueNextFrame() {
 		s.currentFrameDone()
 	}
 	offset, s.currentFrame, s.currentFrameDone = s.frameQueue.Pop()
+	if s.currentFrame != nil {
+		_ = s.flowController.UpdateBufferedBytes(s.frameQueue.BufferedBytes()) // decreasing the number of buffered bytes never errors
+	}
 	s.currentFrameIsLast = offset+protocol.ByteCount(len(s.currentFrame)) >= s.finalOffset
 	s.readPosInFrame = 0
 }
@@ -210,7 +238,7 @@ func (s *receiveStream) cancelReadImpl(errorCode protocol.ApplicationErrorCode)
 		return false
 	}
 	s.canceledRead = true
-	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.cancelReadErr = &StreamError{StreamID: s.streamID, ErrorCode: errorCode}
 	s.signalRead()
 	s.sender.queueControlFrame(&wire.StopSendingFrame{
 		StreamID:  s.streamID,
@@ -221,6 +249,7 @@ func (s *receiveStream) cancelReadImpl(errorCode protocol.ApplicationErrorCode)
 }
 
 func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
+	s.idleTimer.activity()
 	s.mutex.Lock()
 	completed, err := s.handleStreamFrameImpl(frame)
 	s.mutex.Unlock()
@@ -237,6 +266,7 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.Fin); err != nil {
 		return false, err
 	}
+	s.highestReceived = utils.MaxByteCount(s.highestReceived, maxOffset)
 	var newlyRcvdFinalOffset bool
 	if frame.Fin {
 		newlyRcvdFinalOffset = s.finalOffset == protocol.MaxByteCount
@@ -248,6 +278,9 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 	if err := s.frameQueue.Push(frame.Data, frame.Offset, frame.PutBack); err != nil {
 		return false, err
 	}
+	if err := s.flowController.UpdateBufferedBytes(s.frameQueue.BufferedBytes()); err != nil {
+		return false, err
+	}
 	s.signalRead()
 	return false, nil
 }
@@ -279,9 +312,10 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 		return false, nil
 	}
 	s.resetRemotely = true
-	s.resetRemotelyErr = streamCanceledError{
-		errorCode: frame.ErrorCode,
-		error:     fmt.Errorf("stream %d was reset with error code %d", s.streamID, frame.ErrorCode),
+	s.resetRemotelyErr = &StreamError{
+		StreamID:  s.streamID,
+		ErrorCode: frame.ErrorCode,
+		Remote:    true,
 	}
 	s.signalRead()
 	return newlyRcvdFinalOffset, nil
@@ -314,6 +348,13 @@ func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
 
+// getBytesReceived returns the number of bytes of stream data received, i.e. the highest offset received.
+func (s *receiveStream) getBytesReceived() protocol.ByteCount {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.highestReceived
+}
+
 // signalRead performs a non-blocking send on the readChan
 func (s *receiveStream) signalRead() {
 	select {
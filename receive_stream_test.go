@@ -9,6 +9,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -29,7 +30,8 @@ var _ = Describe("Receive Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newReceiveStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		mockFC.EXPECT().UpdateBufferedBytes(gomock.Any()).AnyTimes()
+		str = newReceiveStream(streamID, mockSender, mockFC, nil, utils.DefaultClock{}, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutReader(str, timeout)
@@ -139,6 +141,55 @@ var _ = Describe("Receive Stream", func() {
 			Expect(n).To(Equal(2))
 		})
 
+		Context("reading without blocking", func() {
+			It("returns immediately if no data is available", func() {
+				b := make([]byte, 4)
+				n, err := str.ReadAvailable(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeZero())
+			})
+
+			It("returns the data that is currently available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD}})).To(Succeed())
+				b := make([]byte, 4)
+				n, err := str.ReadAvailable(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(2))
+				Expect(b[:n]).To(Equal([]byte{0xDE, 0xAD}))
+				n, err = str.ReadAvailable(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeZero())
+			})
+
+			It("doesn't return data if there's a gap", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xBE, 0xEF}})).To(Succeed())
+				n, err := str.ReadAvailable(make([]byte, 4))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeZero())
+			})
+
+			It("returns io.EOF", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD}, Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := make([]byte, 4)
+				n, err := str.ReadAvailable(b)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(Equal(2))
+			})
+
+			It("returns errors", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				_, err := str.ReadAvailable(make([]byte, 4))
+				Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
+			})
+		})
+
 		It("handles STREAM frames in wrong order", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
@@ -454,7 +505,7 @@ var _ = Describe("Receive Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Read([]byte{0})
-					Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+					Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
 					close(done)
 				}()
 				Consistently(done).ShouldNot(BeClosed())
@@ -466,7 +517,8 @@ var _ = Describe("Receive Stream", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
 				str.CancelRead(1234)
 				_, err := strWithTimeout.Read([]byte{0})
-				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+				Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 			})
 
 			It("does nothing when CancelRead is called twice", func() {
@@ -474,7 +526,7 @@ var _ = Describe("Receive Stream", func() {
 				str.CancelRead(1234)
 				str.CancelRead(1234)
 				_, err := strWithTimeout.Read([]byte{0})
-				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+				Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
 			})
 
 			It("queues a STOP_SENDING frame", func() {
@@ -572,9 +624,7 @@ var _ = Describe("Receive Stream", func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Read([]byte{0})
 					Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
-					Expect(err).To(BeAssignableToTypeOf(streamCanceledError{}))
-					Expect(err.(streamCanceledError).Canceled()).To(BeTrue())
-					Expect(err.(streamCanceledError).ErrorCode()).To(Equal(protocol.ApplicationErrorCode(1234)))
+					Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234, Remote: true}))
 					close(done)
 				}()
 				Consistently(done).ShouldNot(BeClosed())
@@ -596,9 +646,7 @@ var _ = Describe("Receive Stream", func() {
 				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
 				_, err := strWithTimeout.Read([]byte{0})
 				Expect(err).To(MatchError("stream 1337 was reset with error code 1234"))
-				Expect(err).To(BeAssignableToTypeOf(streamCanceledError{}))
-				Expect(err.(streamCanceledError).Canceled()).To(BeTrue())
-				Expect(err.(streamCanceledError).ErrorCode()).To(Equal(protocol.ApplicationErrorCode(1234)))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234, Remote: true}))
 			})
 
 			It("errors when receiving a RESET_STREAM with an inconsistent offset", func() {
@@ -638,6 +686,14 @@ var _ = Describe("Receive Stream", func() {
 		})
 	})
 
+	It("registers activity on the idle timer when a STREAM frame is received", func() {
+		idleTimer := newStreamIdleTimer(time.Hour, utils.DefaultClock{})
+		str = newReceiveStream(streamID, mockSender, mockFC, idleTimer, utils.DefaultClock{}, protocol.VersionWhatever)
+		mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+		Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+		Expect(idleTimer.lastActivity).ToNot(BeZero())
+	})
+
 	Context("flow control", func() {
 		It("errors when a STREAM frame causes a flow control violation", func() {
 			testErr := errors.New("flow control violation")
@@ -650,9 +706,55 @@ var _ = Describe("Receive Stream", func() {
 			Expect(err).To(MatchError(testErr))
 		})
 
+		It("reports the number of bytes received", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			Expect(str.getBytesReceived()).To(BeZero())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("bar!")})).To(Succeed())
+			Expect(str.getBytesReceived()).To(Equal(protocol.ByteCount(8)))
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foo!")})).To(Succeed())
+			Expect(str.getBytesReceived()).To(Equal(protocol.ByteCount(8)))
+		})
+
 		It("gets a window update", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
+
+		Context("buffered bytes", func() {
+			BeforeEach(func() {
+				// use a flow controller that doesn't accept arbitrary calls to UpdateBufferedBytes
+				mockFC = mocks.NewMockStreamFlowController(mockCtrl)
+				str = newReceiveStream(streamID, mockSender, mockFC, nil, utils.DefaultClock{}, protocol.VersionWhatever)
+			})
+
+			It("reports the number of buffered bytes", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(gomock.Any()).AnyTimes()
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateBufferedBytes(protocol.ByteCount(6)),
+					mockFC.EXPECT().UpdateBufferedBytes(protocol.ByteCount(6+4)),
+					mockFC.EXPECT().UpdateBufferedBytes(protocol.ByteCount(6)),
+					mockFC.EXPECT().UpdateBufferedBytes(protocol.ByteCount(0)),
+				)
+				// make sure that the capacity of the slices equals their length
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("foobar")[:6:6]})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("1234")[:4:4]})).To(Succeed())
+				b := make([]byte, 10)
+				n, err := str.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(10))
+				Expect(b).To(Equal([]byte("1234foobar")))
+			})
+
+			It("errors when too many bytes are buffered", func() {
+				testErr := errors.New("too many bytes buffered")
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateBufferedBytes(protocol.ByteCount(6)).Return(testErr)
+				err := str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("foobar")[:6:6]})
+				Expect(err).To(MatchError(testErr))
+			})
+		})
 	})
 })
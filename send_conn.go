@@ -2,33 +2,69 @@ package quic
 
 import (
 	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
 type sendConn interface {
-	Write([]byte) error
+	Write([]byte, protocol.ECN) error
+	// SupportsECN says if outgoing packets can be marked with ECN codepoints.
+	SupportsECN() bool
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
+	// WithRemoteAddr returns a sendConn that sends to a different remote address, using the same net.PacketConn.
+	WithRemoteAddr(net.Addr) sendConn
 }
 
 type conn struct {
 	net.PacketConn
 
 	remoteAddr net.Addr
+	dscp       uint8
+
+	// only set if the platform supports setting the ECN bits on outgoing packets
+	oobConn    oobConn
+	ecnMessage map[protocol.ECN][]byte
 }
 
 var _ sendConn = &conn{}
 
-func newSendConn(c net.PacketConn, remote net.Addr) sendConn {
-	return &conn{PacketConn: c, remoteAddr: remote}
+// The dscp is only used for packets marked with ECN, since the control message overrides the TOS set on the socket.
+func newSendConn(c net.PacketConn, remote net.Addr, dscp uint8) sendConn {
+	sc := &conn{PacketConn: c, remoteAddr: remote, dscp: dscp}
+	oc, ok := c.(oobConn)
+	addr, isUDPAddr := remote.(*net.UDPAddr)
+	if ok && isUDPAddr && ecnSupported {
+		isIPv4 := addr.IP.To4() != nil
+		sc.oobConn = oc
+		sc.ecnMessage = map[protocol.ECN][]byte{
+			protocol.ECT0:  ecnControlMessage(protocol.ECT0, dscp, isIPv4),
+			protocol.ECT1:  ecnControlMessage(protocol.ECT1, dscp, isIPv4),
+			protocol.ECNCE: ecnControlMessage(protocol.ECNCE, dscp, isIPv4),
+		}
+	}
+	return sc
 }
 
-func (c *conn) Write(p []byte) error {
+func (c *conn) Write(p []byte, ecn protocol.ECN) error {
+	if ecn != protocol.ECNNon && c.oobConn != nil {
+		_, _, err := c.oobConn.WriteMsgUDP(p, c.ecnMessage[ecn], c.remoteAddr.(*net.UDPAddr))
+		return err
+	}
 	_, err := c.PacketConn.WriteTo(p, c.remoteAddr)
 	return err
 }
 
+func (c *conn) SupportsECN() bool {
+	return c.oobConn != nil
+}
+
+func (c *conn) WithRemoteAddr(addr net.Addr) sendConn {
+	return newSendConn(c.PacketConn, addr, c.dscp)
+}
+
 func (c *conn) RemoteAddr() net.Addr {
 	return c.remoteAddr
 }
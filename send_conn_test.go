@@ -5,6 +5,8 @@ import (
 	"net"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -76,21 +78,40 @@ var _ = Describe("Send-Connection", func() {
 			Port: 1337,
 		}
 		packetConn = newMockPacketConn()
-		c = newSendConn(packetConn, addr)
+		c = newSendConn(packetConn, addr, 0)
 	})
 
 	It("writes", func() {
-		Expect(c.Write([]byte("foobar"))).To(Succeed())
+		Expect(c.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
 		var write mockPacketConnWrite
 		Expect(packetConn.dataWritten).To(Receive(&write))
 		Expect(write.to.String()).To(Equal("192.168.100.200:1337"))
 		Expect(write.data).To(Equal([]byte("foobar")))
 	})
 
+	It("doesn't support ECN on connections that don't allow writing out-of-band data", func() {
+		Expect(c.SupportsECN()).To(BeFalse())
+		Expect(c.Write([]byte("foobar"), protocol.ECT0)).To(Succeed())
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.data).To(Equal([]byte("foobar")))
+	})
+
 	It("gets the remote address", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
 
+	It("sends to a different remote address", func() {
+		addr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 1338}
+		c2 := c.WithRemoteAddr(addr)
+		Expect(c2.RemoteAddr()).To(Equal(addr))
+		Expect(c2.Write([]byte("foobar"), protocol.ECNNon)).To(Succeed())
+		var write mockPacketConnWrite
+		Expect(packetConn.dataWritten).To(Receive(&write))
+		Expect(write.to.String()).To(Equal("192.168.100.201:1338"))
+		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
+	})
+
 	It("gets the local address", func() {
 		addr := &net.UDPAddr{
 			IP:   net.IPv4(192, 168, 0, 1),
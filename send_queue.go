@@ -1,7 +1,14 @@
 package quic
 
+import "github.com/lucas-clemente/quic-go/internal/protocol"
+
+type queueEntry struct {
+	buffer *packetBuffer
+	ecn    protocol.ECN
+}
+
 type sendQueue struct {
-	queue       chan *packetBuffer
+	queue       chan queueEntry
 	closeCalled chan struct{} // runStopped when Close() is called
 	runStopped  chan struct{} // runStopped when the run loop returns
 	conn        sendConn
@@ -12,14 +19,14 @@ func newSendQueue(conn sendConn) *sendQueue {
 		conn:        conn,
 		runStopped:  make(chan struct{}),
 		closeCalled: make(chan struct{}),
-		queue:       make(chan *packetBuffer, 1),
+		queue:       make(chan queueEntry, 1),
 	}
 	return s
 }
 
-func (h *sendQueue) Send(p *packetBuffer) {
+func (h *sendQueue) Send(p *packetBuffer, ecn protocol.ECN) {
 	select {
-	case h.queue <- p:
+	case h.queue <- queueEntry{buffer: p, ecn: ecn}:
 	case <-h.runStopped:
 	}
 }
@@ -36,11 +43,11 @@ func (h *sendQueue) Run() error {
 			h.closeCalled = nil // prevent this case from being selected again
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
-		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
+		case e := <-h.queue:
+			if err := h.conn.Write(e.buffer.Data, e.ecn); err != nil {
 				return err
 			}
-			p.Release()
+			e.buffer.Release()
 		}
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -26,10 +27,10 @@ var _ = Describe("Send Queue", func() {
 
 	It("sends a packet", func() {
 		p := getPacket([]byte("foobar"))
-		q.Send(p)
+		q.Send(p, protocol.ECNNon)
 
 		written := make(chan struct{})
-		c.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(written) })
+		c.EXPECT().Write([]byte("foobar"), gomock.Any()).Do(func([]byte, protocol.ECN) { close(written) })
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
@@ -43,15 +44,15 @@ var _ = Describe("Send Queue", func() {
 	})
 
 	It("blocks sending when too many packets are queued", func() {
-		q.Send(getPacket([]byte("foobar")))
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 
 		written := make(chan []byte, 2)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p }).Times(2)
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func(p []byte, _ protocol.ECN) { written <- p }).Times(2)
 
 		sent := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			q.Send(getPacket([]byte("raboof")))
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
 			close(sent)
 		}()
 
@@ -80,15 +81,15 @@ var _ = Describe("Send Queue", func() {
 
 		// the run loop exits if there is a write error
 		testErr := errors.New("test error")
-		c.EXPECT().Write(gomock.Any()).Return(testErr)
-		q.Send(getPacket([]byte("foobar")))
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).Return(testErr)
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 		Eventually(done).Should(BeClosed())
 
 		sent := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
-			q.Send(getPacket([]byte("raboof")))
-			q.Send(getPacket([]byte("quux")))
+			q.Send(getPacket([]byte("raboof")), protocol.ECNNon)
+			q.Send(getPacket([]byte("quux")), protocol.ECNNon)
 			close(sent)
 		}()
 
@@ -97,7 +98,7 @@ var _ = Describe("Send Queue", func() {
 
 	It("blocks Close() until the packet has been sent out", func() {
 		written := make(chan []byte)
-		c.EXPECT().Write(gomock.Any()).Do(func(p []byte) { written <- p })
+		c.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func(p []byte, _ protocol.ECN) { written <- p })
 		done := make(chan struct{})
 		go func() {
 			defer GinkgoRecover()
@@ -105,7 +106,7 @@ var _ = Describe("Send Queue", func() {
 			close(done)
 		}()
 
-		q.Send(getPacket([]byte("foobar")))
+		q.Send(getPacket([]byte("foobar")), protocol.ECNNon)
 
 		closed := make(chan struct{})
 		go func() {
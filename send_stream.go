@@ -2,6 +2,7 @@ package quic
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -14,6 +15,9 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+// ErrWouldBlock is returned by Write on a non-blocking stream, if not all data could be accepted.
+var ErrWouldBlock = errors.New("write would block")
+
 type sendStreamI interface {
 	SendStream
 	handleStopSendingFrame(*wire.StopSendingFrame)
@@ -21,6 +25,7 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	getBytesSent() protocol.ByteCount
 }
 
 type sendStream struct {
@@ -52,6 +57,21 @@ type sendStream struct {
 	writeChan chan struct{}
 	deadline  time.Time
 
+	// In non-blocking mode, Write only accepts as much data as fits into nextFrame.
+	// If a Write returned ErrWouldBlock, writableChan is signaled once more data can be accepted.
+	nonBlocking  bool
+	writeBlocked bool
+	writableChan chan struct{}
+
+	// Small writes are delayed by up to writeCoalesceDelay, to coalesce them with subsequent writes.
+	// coalesceTimer is set while the sender hasn't been notified about buffered data yet.
+	writeCoalesceDelay time.Duration
+	coalesceTimer      utils.ClockTimer
+
+	idleTimer *streamIdleTimer
+	// clock is used for the deadline and the coalescing timer
+	clock utils.Clock
+
 	flowController flowcontrol.StreamFlowController
 
 	version protocol.VersionNumber
@@ -64,14 +84,21 @@ func newSendStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	writeCoalesceDelay time.Duration,
+	idleTimer *streamIdleTimer,
+	clock utils.Clock,
 	version protocol.VersionNumber,
 ) *sendStream {
 	s := &sendStream{
-		streamID:       streamID,
-		sender:         sender,
-		flowController: flowController,
-		writeChan:      make(chan struct{}, 1),
-		version:        version,
+		streamID:           streamID,
+		sender:             sender,
+		flowController:     flowController,
+		writeChan:          make(chan struct{}, 1),
+		writableChan:       make(chan struct{}, 1),
+		writeCoalesceDelay: writeCoalesceDelay,
+		idleTimer:          idleTimer,
+		clock:              clock,
+		version:            version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	return s
@@ -94,12 +121,21 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	if s.closeForShutdownErr != nil {
 		return 0, s.closeForShutdownErr
 	}
-	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+	if !s.deadline.IsZero() && !s.clock.Now().Before(s.deadline) {
 		return 0, errDeadline
 	}
 	if len(p) == 0 {
 		return 0, nil
 	}
+	if s.nonBlocking {
+		n, notifySender, err := s.writeNonBlocking(p)
+		if notifySender {
+			s.mutex.Unlock()
+			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+			s.mutex.Lock()
+		}
+		return n, err
+	}
 
 	s.dataForWriting = p
 
@@ -137,21 +173,33 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			bytesWritten = len(p) - len(s.dataForWriting)
 			deadline = s.deadline
 			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
+				if !s.clock.Now().Before(deadline) {
 					s.dataForWriting = nil
 					return bytesWritten, errDeadline
 				}
 				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
+					deadlineTimer = utils.NewTimerWithClock(s.clock)
 					defer deadlineTimer.Stop()
 				}
 				deadlineTimer.Reset(deadline)
 			}
 			if s.dataForWriting == nil || s.canceledWrite || s.closedForShutdown {
+				// the remaining data will never be sent
+				s.dataForWriting = nil
 				break
 			}
 		}
 
+		if !notifiedSender {
+			if copied && s.writeCoalesceDelay > 0 {
+				// The data fits into a single STREAM frame.
+				// Wait for more data, instead of sending the frame right away.
+				s.startCoalesceTimer()
+				notifiedSender = true
+			} else {
+				s.stopCoalesceTimer()
+			}
+		}
 		s.mutex.Unlock()
 		if !notifiedSender {
 			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
@@ -184,6 +232,79 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	return bytesWritten, nil
 }
 
+// writeNonBlocking copies as much of p into nextFrame as it can hold.
+// It returns if the sender needs to be notified about the new data.
+// must be called after locking the mutex
+func (s *sendStream) writeNonBlocking(p []byte) (int, bool /* notify sender */, error) {
+	var buffered protocol.ByteCount
+	if s.nextFrame != nil {
+		buffered = s.nextFrame.DataLen()
+	}
+	n := int(utils.MinByteCount(protocol.MaxReceivePacketSize-buffered, protocol.ByteCount(len(p))))
+	if n == 0 {
+		s.writeBlocked = true
+		return 0, false, ErrWouldBlock
+	}
+	if s.nextFrame == nil {
+		f := wire.GetStreamFrame()
+		f.Offset = s.writeOffset
+		f.StreamID = s.streamID
+		f.DataLenPresent = true
+		f.Data = f.Data[:0]
+		s.nextFrame = f
+	}
+	s.nextFrame.Data = append(s.nextFrame.Data, p[:n]...)
+
+	var notifySender bool
+	if s.writeCoalesceDelay > 0 && n == len(p) {
+		s.startCoalesceTimer()
+	} else {
+		s.stopCoalesceTimer()
+		notifySender = true
+	}
+	if n < len(p) {
+		s.writeBlocked = true
+		return n, notifySender, ErrWouldBlock
+	}
+	return n, notifySender, nil
+}
+
+// must be called after locking the mutex
+func (s *sendStream) startCoalesceTimer() {
+	if s.coalesceTimer != nil {
+		return
+	}
+	s.coalesceTimer = s.clock.AfterFunc(s.writeCoalesceDelay, func() { s.Flush() })
+}
+
+// must be called after locking the mutex
+func (s *sendStream) stopCoalesceTimer() {
+	if s.coalesceTimer != nil {
+		s.coalesceTimer.Stop()
+		s.coalesceTimer = nil
+	}
+}
+
+func (s *sendStream) Flush() error {
+	s.mutex.Lock()
+	if s.closeForShutdownErr != nil {
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
+	}
+	if s.canceledWrite {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
+	}
+	pending := s.coalesceTimer != nil
+	s.stopCoalesceTimer()
+	s.mutex.Unlock()
+
+	if pending {
+		s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	}
+	return nil
+}
+
 func (s *sendStream) canBufferStreamFrame() bool {
 	var l protocol.ByteCount
 	if s.nextFrame != nil {
@@ -196,6 +317,8 @@ func (s *sendStream) canBufferStreamFrame() bool {
 // maxBytes is the maximum length this frame (including frame header) will have.
 func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool /* has more data to send */) {
 	s.mutex.Lock()
+	// If there are frames queued for retransmission, the frame is taken from the retransmission queue.
+	isRetransmission := len(s.retransmissionQueue) > 0
 	f, hasMoreData := s.popNewOrRetransmittedStreamFrame(maxBytes)
 	if f != nil {
 		s.numOutstandingFrames++
@@ -205,7 +328,8 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Fr
 	if f == nil {
 		return nil, hasMoreData
 	}
-	return &ackhandler.Frame{Frame: f, OnLost: s.queueRetransmission, OnAcked: s.frameAcked}, hasMoreData
+	s.idleTimer.activity()
+	return &ackhandler.Frame{Frame: f, OnLost: s.queueRetransmission, OnAcked: s.frameAcked, IsRetransmission: isRetransmission}, hasMoreData
 }
 
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more data to send */) {
@@ -266,6 +390,7 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 	if s.nextFrame != nil {
 		nextFrame := s.nextFrame
 		s.nextFrame = nil
+		s.signalWritable()
 
 		maxDataLen := utils.MinByteCount(sendWindow, nextFrame.MaxDataLen(maxBytes, s.version))
 		if nextFrame.DataLen() > maxDataLen {
@@ -324,6 +449,13 @@ func (s *sendStream) hasData() bool {
 	return hasData
 }
 
+// getBytesSent returns the number of bytes of stream data sent, not counting retransmissions.
+func (s *sendStream) getBytesSent() protocol.ByteCount {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.writeOffset
+}
+
 func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.ByteCount) {
 	if protocol.ByteCount(len(s.dataForWriting)) <= maxBytes {
 		f.Data = f.Data[:len(s.dataForWriting)]
@@ -342,6 +474,7 @@ func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.By
 
 func (s *sendStream) frameAcked(f wire.Frame) {
 	f.(*wire.StreamFrame).PutBack()
+	s.idleTimer.activity()
 
 	s.mutex.Lock()
 	s.numOutstandingFrames--
@@ -369,11 +502,21 @@ func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
 	s.mutex.Lock()
-	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	if s.canceledWrite {
+		// there's no need to retransmit data on a stream that was reset
+		sf.PutBack()
+		newlyCompleted := s.isNewlyCompleted()
+		s.mutex.Unlock()
+		if newlyCompleted {
+			s.sender.onStreamCompleted(s.streamID)
+		}
+		return
+	}
+	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID)
@@ -391,6 +534,7 @@ func (s *sendStream) Close() error {
 	}
 	s.ctxCancel()
 	s.finishedWriting = true
+	s.stopCoalesceTimer()
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID) // need to send the FIN, must be called without holding the mutex
@@ -398,7 +542,7 @@ func (s *sendStream) Close() error {
 }
 
 func (s *sendStream) CancelWrite(errorCode protocol.ApplicationErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, &StreamError{StreamID: s.streamID, ErrorCode: errorCode})
 }
 
 // must be called after locking the mutex
@@ -411,7 +555,12 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
+	s.stopCoalesceTimer()
+	// Data that hasn't been sent yet will never be sent, and lost data won't be retransmitted.
+	// The final size is the amount of data sent so far.
+	s.dropBufferedData()
 	newlyCompleted := s.isNewlyCompleted()
+	s.signalWritable()
 	s.mutex.Unlock()
 
 	s.signalWrite()
@@ -425,7 +574,22 @@ func (s *sendStream) cancelWriteImpl(errorCode protocol.ApplicationErrorCode, wr
 	}
 }
 
+// dropBufferedData drops all data that was buffered for sending or retransmission.
+// Data passed to a Write call that's still blocked is dropped when that call returns.
+// must be called after locking the mutex
+func (s *sendStream) dropBufferedData() {
+	if s.nextFrame != nil {
+		s.nextFrame.PutBack()
+		s.nextFrame = nil
+	}
+	for _, f := range s.retransmissionQueue {
+		f.PutBack()
+	}
+	s.retransmissionQueue = nil
+}
+
 func (s *sendStream) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) {
+	s.idleTimer.activity()
 	s.mutex.Lock()
 	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
 	s.mutex.Unlock()
@@ -437,11 +601,11 @@ func (s *sendStream) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) {
 }
 
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
-	writeErr := streamCanceledError{
-		errorCode: frame.ErrorCode,
-		error:     fmt.Errorf("stream %d was reset with error code %d", s.streamID, frame.ErrorCode),
-	}
-	s.cancelWriteImpl(frame.ErrorCode, writeErr)
+	s.cancelWriteImpl(frame.ErrorCode, &StreamError{
+		StreamID:  s.streamID,
+		ErrorCode: frame.ErrorCode,
+		Remote:    true,
+	})
 }
 
 func (s *sendStream) Context() context.Context {
@@ -456,6 +620,22 @@ func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	return nil
 }
 
+// SetPriority passes the weight on to the session's send scheduler.
+func (s *sendStream) SetPriority(weight uint8) {
+	s.sender.setStreamPriority(s.streamID, weight)
+}
+
+func (s *sendStream) BufferedBytes() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	l := protocol.ByteCount(len(s.dataForWriting))
+	if s.nextFrame != nil {
+		l += s.nextFrame.DataLen()
+	}
+	return uint64(l)
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
@@ -464,10 +644,35 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.stopCoalesceTimer()
+	s.signalWritable()
 	s.mutex.Unlock()
 	s.signalWrite()
 }
 
+func (s *sendStream) SetNonBlocking(nonBlocking bool) {
+	s.mutex.Lock()
+	s.nonBlocking = nonBlocking
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) WritableChan() <-chan struct{} {
+	return s.writableChan
+}
+
+// signalWritable performs a non-blocking send on the writableChan, if the last Write returned ErrWouldBlock.
+// must be called after locking the mutex
+func (s *sendStream) signalWritable() {
+	if !s.writeBlocked {
+		return
+	}
+	s.writeBlocked = false
+	select {
+	case s.writableChan <- struct{}{}:
+	default:
+	}
+}
+
 // signalWrite performs a non-blocking send on the writeChan
 func (s *sendStream) signalWrite() {
 	select {
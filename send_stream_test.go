@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	mrand "math/rand"
+	"net"
 	"runtime"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -32,7 +34,7 @@ var _ = Describe("Send Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newSendStream(streamID, mockSender, mockFC, protocol.VersionWhatever)
+		str = newSendStream(streamID, mockSender, mockFC, 0, nil, utils.DefaultClock{}, protocol.VersionWhatever)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutWriter(str, timeout)
@@ -71,6 +73,11 @@ var _ = Describe("Send Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("tells the sender about the priority", func() {
+		mockSender.EXPECT().setStreamPriority(protocol.StreamID(1337), uint8(42))
+		str.SetPriority(42)
+	})
+
 	Context("writing", func() {
 		It("writes and gets all data at once", func() {
 			done := make(chan struct{})
@@ -122,6 +129,7 @@ var _ = Describe("Send Stream", func() {
 			Expect(f.Offset).To(Equal(protocol.ByteCount(3)))
 			Expect(f.DataLenPresent).To(BeTrue())
 			Expect(str.popStreamFrame(1000)).To(BeNil())
+			Expect(str.getBytesSent()).To(Equal(protocol.ByteCount(6)))
 			Eventually(done).Should(BeClosed())
 		})
 
@@ -148,6 +156,30 @@ var _ = Describe("Send Stream", func() {
 			Expect(f.Data).To(Equal([]byte("foobar")))
 		})
 
+		It("reports the number of buffered bytes", func() {
+			Expect(str.BufferedBytes()).To(BeZero())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write(getData(5000))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(5000))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(gomock.Any())
+			frame, _ := str.popStreamFrame(1000)
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(5000 - frame.Frame.(*wire.StreamFrame).DataLen()))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			for str.BufferedBytes() > 0 {
+				str.popStreamFrame(1000)
+			}
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("writes and gets data in multiple turns, for large writes", func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(5)
 			var totalBytesSent protocol.ByteCount
@@ -423,6 +455,61 @@ var _ = Describe("Send Stream", func() {
 				Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
 			})
 
+			It("continues at the right offset when the rest of the data is written after the deadline expired", func() {
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				data := getData(5000)
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				str.SetWriteDeadline(deadline)
+				var n int
+				writeReturned := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(writeReturned)
+					var err error
+					n, err = strWithTimeout.Write(data)
+					Expect(err).To(HaveOccurred())
+					nerr, ok := err.(net.Error)
+					Expect(ok).To(BeTrue())
+					Expect(nerr.Timeout()).To(BeTrue())
+				}()
+				waitForWrite()
+				var received []byte
+				frame, _ := str.popStreamFrame(1000)
+				Expect(frame).ToNot(BeNil())
+				received = append(received, frame.Frame.(*wire.StreamFrame).Data...)
+				Eventually(writeReturned, scaleDuration(80*time.Millisecond)).Should(BeClosed())
+				Expect(n).To(Equal(len(received)))
+				Expect(n).To(BeNumerically("<", len(data)))
+
+				// write the rest of the data
+				str.SetWriteDeadline(time.Time{})
+				writeReturned = make(chan struct{})
+				rest := data[n:]
+				go func() {
+					defer GinkgoRecover()
+					defer close(writeReturned)
+					n, err := strWithTimeout.Write(rest)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(len(rest)))
+				}()
+				waitForWrite()
+				for {
+					frame, hasMoreData := str.popStreamFrame(1000)
+					if frame != nil {
+						f := frame.Frame.(*wire.StreamFrame)
+						Expect(f.Offset).To(BeEquivalentTo(len(received)))
+						received = append(received, f.Data...)
+					}
+					if !hasMoreData {
+						break
+					}
+				}
+				Eventually(writeReturned).Should(BeClosed())
+				Expect(received).To(Equal(data))
+			})
+
 			It("doesn't pop any data after the deadline expired", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
 				mockFC.EXPECT().AddBytesSent(gomock.Any())
@@ -649,6 +736,175 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("non-blocking writes", func() {
+		BeforeEach(func() {
+			str.SetNonBlocking(true)
+		})
+
+		It("accepts data that can be buffered", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(6))
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(6))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Expect(str.WritableChan()).ToNot(Receive())
+		})
+
+		It("doesn't retain the slice passed to Write", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			data := []byte("foobar")
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			copy(data, "raboof")
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("returns ErrWouldBlock when the buffer is full, and signals when it can accept more data", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			data := getData(2 * protocol.MaxReceivePacketSize)
+			n, err := str.Write(data)
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeEquivalentTo(protocol.MaxReceivePacketSize))
+			n, err = str.Write(data[n:])
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeZero())
+			Expect(str.WritableChan()).ToNot(Receive())
+			// the stream is blocked by flow control
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
+			mockFC.EXPECT().IsNewlyBlocked()
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).To(BeNil())
+			Expect(str.WritableChan()).ToNot(Receive())
+			// the peer grants more flow control credit
+			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(10000))
+			str.handleMaxStreamDataFrame(&wire.MaxStreamDataFrame{StreamID: streamID, MaximumStreamData: 10000})
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(10000))
+			mockFC.EXPECT().AddBytesSent(gomock.Any())
+			frame, _ = str.popStreamFrame(500)
+			Expect(frame).ToNot(BeNil())
+			Expect(str.WritableChan()).To(Receive())
+			// the channel is edge-triggered
+			Expect(str.WritableChan()).ToNot(Receive())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			n, err = str.Write(data[protocol.MaxReceivePacketSize:])
+			Expect(err).To(MatchError(ErrWouldBlock))
+			Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
+		})
+
+		It("signals the writable channel when the stream is canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.Write(getData(2 * protocol.MaxReceivePacketSize))
+			Expect(err).To(MatchError(ErrWouldBlock))
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.WritableChan()).To(Receive())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+		})
+	})
+
+	Context("coalescing writes", func() {
+		delay := scaleDuration(50 * time.Millisecond)
+
+		BeforeEach(func() {
+			str = newSendStream(streamID, mockSender, mockFC, delay, nil, utils.DefaultClock{}, protocol.VersionWhatever)
+		})
+
+		It("delays sending small writes", func() {
+			called := make(chan struct{})
+			mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(called) })
+			start := time.Now()
+			_, err := str.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(called).Should(BeClosed())
+			Expect(time.Since(start)).To(BeNumerically(">=", delay))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+		})
+
+		It("uses the clock for the coalescing timer", func() {
+			clock := utils.NewManualClock(time.Now())
+			str = newSendStream(streamID, mockSender, mockFC, time.Second, nil, clock, protocol.VersionWhatever)
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			clock.Advance(time.Second - time.Nanosecond)
+			mockSender.EXPECT().onHasStreamData(streamID)
+			clock.Advance(time.Nanosecond)
+		})
+
+		It("sends right away when flushed", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Flush()).To(Succeed())
+			// make sure the coalescing timer doesn't fire any more
+			time.Sleep(2 * delay)
+		})
+
+		It("doesn't notify the sender when flushing, if no data is held back", func() {
+			Expect(str.Flush()).To(Succeed())
+		})
+
+		It("sends right away when the stream is closed", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			time.Sleep(2 * delay)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Expect(frame.Frame.(*wire.StreamFrame).Fin).To(BeTrue())
+		})
+
+		It("doesn't delay writes that don't fit into a single packet", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := str.Write(make([]byte, 2*protocol.MaxReceivePacketSize))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			for {
+				frame, hasMoreData := str.popStreamFrame(1000)
+				Expect(frame).ToNot(BeNil())
+				if !hasMoreData {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+			time.Sleep(2 * delay)
+		})
+
+		It("drops held back data when the stream is canceled", func() {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.Flush()).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+			time.Sleep(2 * delay)
+		})
+	})
+
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
@@ -680,6 +936,41 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("idle timer", func() {
+		var idleTimer *streamIdleTimer
+
+		BeforeEach(func() {
+			idleTimer = newStreamIdleTimer(time.Hour, utils.DefaultClock{})
+			str = newSendStream(streamID, mockSender, mockFC, 0, idleTimer, utils.DefaultClock{}, protocol.VersionWhatever)
+		})
+
+		It("registers activity when a STREAM frame is sent and acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			// writing data doesn't count as activity
+			Expect(idleTimer.lastActivity).To(BeZero())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			sent := idleTimer.lastActivity
+			Expect(sent).ToNot(BeZero())
+			time.Sleep(time.Millisecond)
+			frame.OnAcked(frame.Frame)
+			Expect(idleTimer.lastActivity).To(BeTemporally(">", sent))
+		})
+
+		It("registers activity when a MAX_STREAM_DATA frame is received", func() {
+			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
+			str.handleMaxStreamDataFrame(&wire.MaxStreamDataFrame{
+				StreamID:          streamID,
+				MaximumStreamData: 0x1337,
+			})
+			Expect(idleTimer.lastActivity).ToNot(BeZero())
+		})
+	})
+
 	Context("stream cancellations", func() {
 		Context("canceling writing", func() {
 			It("queues a RESET_STREAM frame", func() {
@@ -695,6 +986,50 @@ var _ = Describe("Send Stream", func() {
 				str.CancelWrite(9876)
 			})
 
+			It("uses the number of bytes sent as the final size, and drops buffered data", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+				Expect(frame).ToNot(BeNil())
+				Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foo")))
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 3,
+					ErrorCode: 1234,
+				})
+				str.CancelWrite(1234)
+				Expect(str.nextFrame).To(BeNil())
+				f, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(f).To(BeNil())
+				Expect(hasMoreData).To(BeFalse())
+				// the stream is completed once the outstanding frame is acknowledged
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				frame.OnAcked(frame.Frame)
+			})
+
+			It("doesn't retransmit lost frames after being canceled", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 6,
+					ErrorCode: 1234,
+				})
+				str.CancelWrite(1234)
+				// don't EXPECT any calls to onHasStreamData
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				frame.OnLost(frame.Frame)
+				Expect(str.retransmissionQueue).To(BeEmpty())
+			})
+
 			// This test is inherently racy, as it tests a concurrent call to Write() and CancelRead().
 			// A single successful run of this test therefore doesn't mean a lot,
 			// for reliable results it has to be run many times.
@@ -732,7 +1067,7 @@ var _ = Describe("Send Stream", func() {
 					defer GinkgoRecover()
 					var err error
 					n, err = strWithTimeout.Write(getData(5000))
-					Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+					Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
 					close(writeReturned)
 				}()
 				waitForWrite()
@@ -774,7 +1109,7 @@ var _ = Describe("Send Stream", func() {
 				go func() {
 					defer GinkgoRecover()
 					_, err := strWithTimeout.Write(getData(5000))
-					Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+					Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
 					close(writeReturned)
 				}()
 				waitForWrite()
@@ -801,7 +1136,8 @@ var _ = Describe("Send Stream", func() {
 				mockSender.EXPECT().onStreamCompleted(gomock.Any())
 				str.CancelWrite(1234)
 				_, err := strWithTimeout.Write([]byte("foobar"))
-				Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+				Expect(err).To(MatchError("stream 1337 canceled with error code 1234"))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
 			})
 
 			It("only cancels once", func() {
@@ -846,9 +1182,7 @@ var _ = Describe("Send Stream", func() {
 					defer GinkgoRecover()
 					_, err := str.Write(getData(5000))
 					Expect(err).To(MatchError("stream 1337 was reset with error code 123"))
-					Expect(err).To(BeAssignableToTypeOf(streamCanceledError{}))
-					Expect(err.(streamCanceledError).Canceled()).To(BeTrue())
-					Expect(err.(streamCanceledError).ErrorCode()).To(Equal(protocol.ApplicationErrorCode(123)))
+					Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 123, Remote: true}))
 					close(done)
 				}()
 				waitForWrite()
@@ -868,9 +1202,7 @@ var _ = Describe("Send Stream", func() {
 				})
 				_, err := str.Write([]byte("foobar"))
 				Expect(err).To(MatchError("stream 1337 was reset with error code 123"))
-				Expect(err).To(BeAssignableToTypeOf(streamCanceledError{}))
-				Expect(err.(streamCanceledError).Canceled()).To(BeTrue())
-				Expect(err.(streamCanceledError).ErrorCode()).To(Equal(protocol.ApplicationErrorCode(123)))
+				Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 123, Remote: true}))
 			})
 		})
 	})
@@ -947,6 +1279,7 @@ var _ = Describe("Send Stream", func() {
 			Eventually(done).Should(BeClosed())
 			Expect(frame).ToNot(BeNil())
 			Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Expect(frame.IsRetransmission).To(BeFalse())
 
 			// now lose the frame
 			mockSender.EXPECT().onHasStreamData(streamID)
@@ -954,6 +1287,7 @@ var _ = Describe("Send Stream", func() {
 			newFrame, _ := str.popStreamFrame(protocol.MaxByteCount)
 			Expect(newFrame).ToNot(BeNil())
 			Expect(newFrame.Frame.(*wire.StreamFrame).Data).To(Equal([]byte("foobar")))
+			Expect(newFrame.IsRetransmission).To(BeTrue())
 		})
 
 		It("doesn't get a retransmission after a stream was canceled", func() {
@@ -966,6 +1300,7 @@ var _ = Describe("Send Stream", func() {
 			mockSender.EXPECT().onHasStreamData(streamID)
 			str.queueRetransmission(f)
 			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
 			str.CancelWrite(0)
 			frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
 			Expect(hasMoreData).To(BeFalse())
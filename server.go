@@ -99,6 +99,14 @@ type baseServer struct {
 
 	sessionQueue    chan quicSession
 	sessionQueueLen int32 // to be used as an atomic
+	// the number of sessions that are currently running, used to enforce the MaxIncomingConnections.
+	// It is incremented on the run loop, and decremented when a session's run loop returns.
+	numSessions int32 // to be used as an atomic
+
+	// used to enforce the MaxIncomingHandshakesPerSecond.
+	// Only accessed from the run loop.
+	handshakeRateWindowStart time.Time
+	handshakesInRateWindow   int
 
 	logger utils.Logger
 }
@@ -135,40 +143,46 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 	if err != nil {
 		return nil, err
 	}
-	conn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
+	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
-	serv, err := listen(conn, tlsConf, config, acceptEarly)
+	conn, err := net.ListenUDP("udp", udpAddr)
 	if err != nil {
 		return nil, err
 	}
-	serv.createdPacketConn = true
-	return serv, nil
+	if config != nil {
+		if err := setDSCP(conn, uint8(config.DSCP)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return listen(conn, tlsConf, config, acceptEarly, true)
 }
 
 // Listen listens for QUIC connections on a given net.PacketConn.
 // A single net.PacketConn only be used for a single call to Listen.
 // The PacketConn can be used for simultaneous calls to Dial.
 // QUIC connection IDs are used for demultiplexing the different connections.
+// PacketConns are identified by their local address, so PacketConns that don't use UDP
+// must return a unique address from LocalAddr. NewDatagramConn takes care of this.
 // The tls.Config must not be nil and must contain a certificate configuration.
 // The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites.
 // Furthermore, it must define an application control (using NextProtos).
 // The quic.Config may be nil, in that case the default values will be used.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
-	return listen(conn, tlsConf, config, false)
+	return listen(conn, tlsConf, config, false, false)
 }
 
 // ListenEarly works like Listen, but it returns sessions before the handshake completes.
 func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
-	s, err := listen(conn, tlsConf, config, true)
+	s, err := listen(conn, tlsConf, config, true, false)
 	if err != nil {
 		return nil, err
 	}
 	return &earlyServer{s}, nil
 }
 
-func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
+func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly, createdPacketConn bool) (*baseServer, error) {
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
@@ -182,21 +196,22 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		}
 	}
 
-	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
+	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey, config.StatelessResetKeyFunc, config.Tracer)
 	if err != nil {
 		return nil, err
 	}
-	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
+	tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader, config.clock)
 	if err != nil {
 		return nil, err
 	}
 	s := &baseServer{
 		conn:                conn,
+		createdPacketConn:   createdPacketConn,
 		tlsConf:             tlsConf,
 		config:              config,
 		tokenGenerator:      tokenGenerator,
 		sessionHandler:      sessionHandler,
-		zeroRTTQueue:        newZeroRTTQueue(),
+		zeroRTTQueue:        newZeroRTTQueue(config.clock),
 		sessionQueue:        make(chan quicSession),
 		errorChan:           make(chan struct{}),
 		running:             make(chan struct{}),
@@ -230,24 +245,28 @@ func (s *baseServer) run() {
 	}
 }
 
-var defaultAcceptToken = func(clientAddr net.Addr, token *Token) bool {
-	if token == nil {
-		return false
-	}
-	validity := protocol.TokenValidity
-	if token.IsRetryToken {
-		validity = protocol.RetryTokenValidity
-	}
-	if time.Now().After(token.SentTime.Add(validity)) {
-		return false
-	}
-	var sourceAddr string
-	if udpAddr, ok := clientAddr.(*net.UDPAddr); ok {
-		sourceAddr = udpAddr.IP.String()
-	} else {
-		sourceAddr = clientAddr.String()
+// defaultAcceptToken returns the function used if Config.AcceptToken is not set.
+// The clock is used to check if the token has expired.
+func defaultAcceptToken(clock utils.Clock) func(clientAddr net.Addr, token *Token) bool {
+	return func(clientAddr net.Addr, token *Token) bool {
+		if token == nil {
+			return false
+		}
+		validity := protocol.TokenValidity
+		if token.IsRetryToken {
+			validity = protocol.RetryTokenValidity
+		}
+		if clock.Now().After(token.SentTime.Add(validity)) {
+			return false
+		}
+		var sourceAddr string
+		if udpAddr, ok := clientAddr.(*net.UDPAddr); ok {
+			sourceAddr = udpAddr.IP.String()
+		} else {
+			sourceAddr = clientAddr.String()
+		}
+		return sourceAddr == token.RemoteAddr
 	}
-	return sourceAddr == token.RemoteAddr
 }
 
 // Accept returns sessions that already completed the handshake.
@@ -383,11 +402,20 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 	var (
 		token                *Token
 		retrySrcConnectionID *protocol.ConnectionID
+		// set if the token was validated by the AddressTokenGenerator
+		addressValidated bool
 	)
 	origDestConnectionID := hdr.DestConnectionID
 	if len(hdr.Token) > 0 {
-		c, err := s.tokenGenerator.DecodeToken(hdr.Token)
-		if err == nil {
+		if s.config.AddressTokenGenerator != nil {
+			var isRetryToken bool
+			var odcid, rscid protocol.ConnectionID
+			addressValidated, isRetryToken, odcid, rscid = s.validateGeneratedToken(hdr.Token, p.remoteAddr)
+			if isRetryToken {
+				origDestConnectionID = odcid
+				retrySrcConnectionID = &rscid
+			}
+		} else if c, err := s.tokenGenerator.DecodeToken(hdr.Token); err == nil {
 			token = &Token{
 				IsRetryToken: c.IsRetryToken,
 				RemoteAddr:   c.RemoteAddr,
@@ -399,7 +427,17 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 			}
 		}
 	}
-	if !s.config.AcceptToken(p.remoteAddr, token) {
+	if token == nil && !addressValidated && s.handshakeRateLimitExceeded(s.config.clock.Now()) {
+		s.logger.Debugf("Rate of incoming handshakes exceeds the limit. Requesting address validation from %s.", p.remoteAddr)
+		go func() {
+			defer p.buffer.Release()
+			if err := s.sendRetry(p.remoteAddr, hdr); err != nil {
+				s.logger.Debugf("Error sending Retry: %s", err)
+			}
+		}()
+		return nil
+	}
+	if !addressValidated && !s.config.AcceptToken(p.remoteAddr, token) {
 		go func() {
 			defer p.buffer.Release()
 			if token != nil && token.IsRetryToken {
@@ -424,8 +462,19 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		}()
 		return nil
 	}
+	if s.config.MaxIncomingConnections > 0 {
+		if numSessions := atomic.LoadInt32(&s.numSessions); int(numSessions) >= s.config.MaxIncomingConnections {
+			s.logger.Debugf("Rejecting new connection. Maximum number of connections reached: %d (max %d)", numSessions, s.config.MaxIncomingConnections)
+			go func() {
+				if err := s.sendConnectionRefused(p.remoteAddr, hdr); err != nil {
+					s.logger.Debugf("Error rejecting connection: %s", err)
+				}
+			}()
+			return nil
+		}
+	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.config.ConnectionIDGenerator.GenerateConnectionID()
 	if err != nil {
 		return err
 	}
@@ -443,6 +492,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		p.buffer.Release()
 		return nil
 	}
+	s.countHandshake(s.config.clock.Now())
 	sess.handlePacket(p)
 	for {
 		p := s.zeroRTTQueue.Dequeue(hdr.DestConnectionID)
@@ -454,6 +504,23 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 	return nil
 }
 
+// handshakeRateLimitExceeded says if the number of handshakes started in the current one-second window
+// has reached the MaxIncomingHandshakesPerSecond.
+func (s *baseServer) handshakeRateLimitExceeded(now time.Time) bool {
+	if s.config.MaxIncomingHandshakesPerSecond <= 0 || now.Sub(s.handshakeRateWindowStart) >= time.Second {
+		return false
+	}
+	return s.handshakesInRateWindow >= s.config.MaxIncomingHandshakesPerSecond
+}
+
+func (s *baseServer) countHandshake(now time.Time) {
+	if now.Sub(s.handshakeRateWindowStart) >= time.Second {
+		s.handshakeRateWindowStart = now
+		s.handshakesInRateWindow = 0
+	}
+	s.handshakesInRateWindow++
+}
+
 func (s *baseServer) createNewSession(
 	remoteAddr net.Addr,
 	origDestConnID protocol.ConnectionID,
@@ -474,8 +541,12 @@ func (s *baseServer) createNewSession(
 			}
 			tracer = s.config.Tracer.TracerForConnection(protocol.PerspectiveServer, connID)
 		}
+		var dscp uint8
+		if s.createdPacketConn {
+			dscp = uint8(s.config.DSCP)
+		}
 		sess = s.newSession(
-			newSendConn(s.conn, remoteAddr),
+			newSendConn(s.conn, remoteAddr, dscp),
 			s.sessionHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -495,7 +566,11 @@ func (s *baseServer) createNewSession(
 	}); !added {
 		return nil
 	}
-	go sess.run()
+	atomic.AddInt32(&s.numSessions, 1)
+	go func() {
+		sess.run()
+		atomic.AddInt32(&s.numSessions, -1)
+	}()
 	go s.handleNewSession(sess)
 	return sess
 }
@@ -528,15 +603,34 @@ func (s *baseServer) handleNewSession(sess quicSession) {
 	}
 }
 
+// validateGeneratedToken validates a token using the AddressTokenGenerator.
+// The token might either be a Retry token, or a token sent in a NEW_TOKEN frame.
+func (s *baseServer) validateGeneratedToken(token []byte, remoteAddr net.Addr) (valid, isRetryToken bool, origDestConnID, retrySrcConnID protocol.ConnectionID) {
+	valid, origDestConnID, retrySrcConnID, err := s.config.AddressTokenGenerator.ValidateRetryToken(token, remoteAddr)
+	if err == nil && valid {
+		return true, true, origDestConnID, retrySrcConnID
+	}
+	valid, err = s.config.AddressTokenGenerator.ValidateToken(token, remoteAddr)
+	if err != nil {
+		s.logger.Debugf("Error validating token: %s", err)
+	}
+	return err == nil && valid, false, nil, nil
+}
+
 func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	srcConnID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	srcConnID, err := s.config.ConnectionIDGenerator.GenerateConnectionID()
 	if err != nil {
 		return err
 	}
-	token, err := s.tokenGenerator.NewRetryToken(remoteAddr, hdr.DestConnectionID, srcConnID)
+	var token []byte
+	if s.config.AddressTokenGenerator != nil {
+		token, err = s.config.AddressTokenGenerator.NewRetryToken(remoteAddr, hdr.DestConnectionID, srcConnID)
+	} else {
+		token, err = s.tokenGenerator.NewRetryToken(remoteAddr, hdr.DestConnectionID, srcConnID)
+	}
 	if err != nil {
 		return err
 	}
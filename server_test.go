@@ -126,7 +126,7 @@ var _ = Describe("Server", func() {
 		Expect(server.config.Versions).To(Equal(protocol.SupportedVersions))
 		Expect(server.config.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 		Expect(server.config.MaxIdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
-		Expect(reflect.ValueOf(server.config.AcceptToken)).To(Equal(reflect.ValueOf(defaultAcceptToken)))
+		Expect(server.config.AcceptToken).ToNot(BeNil())
 		Expect(server.config.KeepAlive).To(BeFalse())
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
@@ -289,6 +289,145 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("accepts tokens validated by the AddressTokenGenerator", func() {
+				serv.config.AcceptToken = func(net.Addr, *Token) bool {
+					Fail("AcceptToken shouldn't be called")
+					return false
+				}
+				raddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+				tokenGen := NewMockAddressTokenGenerator(mockCtrl)
+				serv.config.AddressTokenGenerator = tokenGen
+				tokenGen.EXPECT().ValidateRetryToken([]byte("foobar"), raddr).Return(false, nil, nil, errors.New("not a Retry token"))
+				tokenGen.EXPECT().ValidateToken([]byte("foobar"), raddr).Return(true, nil)
+				p := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+					Token:            []byte("foobar"),
+					Version:          serv.config.Versions[0],
+				}, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = raddr
+				// the duplicate check is the first step of creating a new session
+				phm.EXPECT().AddWithConnID(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}, gomock.Any(), gomock.Any()).Return(false)
+				Expect(serv.handlePacketImpl(p)).To(BeTrue())
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
+			It("sends a Retry if the AddressTokenGenerator rejects the token", func() {
+				done := make(chan struct{})
+				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool {
+					defer close(done)
+					Expect(token).To(BeNil())
+					return false
+				}
+				tokenGen := NewMockAddressTokenGenerator(mockCtrl)
+				serv.config.AddressTokenGenerator = tokenGen
+				tokenGen.EXPECT().ValidateRetryToken([]byte("foobar"), gomock.Any()).Return(false, nil, nil, nil)
+				tokenGen.EXPECT().ValidateToken([]byte("foobar"), gomock.Any()).Return(false, errors.New("invalid token"))
+				tokenGen.EXPECT().NewRetryToken(gomock.Any(), gomock.Any(), gomock.Any()).Return([]byte("retry"), nil)
+				p := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+					Token:            []byte("foobar"),
+					Version:          serv.config.Versions[0],
+				}, make([]byte, protocol.MinInitialPacketSize))
+				tracer.EXPECT().SentPacket(p.remoteAddr, gomock.Any(), gomock.Any(), nil)
+				serv.handlePacket(p)
+				Eventually(done).Should(BeClosed())
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				Expect(parseHeader(write.data).Type).To(Equal(protocol.PacketTypeRetry))
+			})
+
+			It("uses the AddressTokenGenerator to generate Retry tokens", func() {
+				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool { return false }
+				tokenGen := NewMockAddressTokenGenerator(mockCtrl)
+				serv.config.AddressTokenGenerator = tokenGen
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+					Version:          serv.config.Versions[0],
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				var retrySrcConnID protocol.ConnectionID
+				tokenGen.EXPECT().NewRetryToken(p.remoteAddr, hdr.DestConnectionID, gomock.Any()).DoAndReturn(func(_ net.Addr, _, c protocol.ConnectionID) ([]byte, error) {
+					retrySrcConnID = c
+					return []byte("retry token"), nil
+				})
+				tracer.EXPECT().SentPacket(p.remoteAddr, gomock.Any(), gomock.Any(), nil)
+				serv.handlePacket(p)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				replyHdr := parseHeader(write.data)
+				Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+				Expect(replyHdr.SrcConnectionID).To(Equal(retrySrcConnID))
+				Expect(replyHdr.Token).To(Equal([]byte("retry token")))
+			})
+
+			It("accepts Retry tokens validated by the AddressTokenGenerator", func() {
+				serv.config.AcceptToken = func(net.Addr, *Token) bool {
+					Fail("AcceptToken shouldn't be called")
+					return false
+				}
+				tokenGen := NewMockAddressTokenGenerator(mockCtrl)
+				serv.config.AddressTokenGenerator = tokenGen
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+					Token:            []byte("retry token"),
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				tokenGen.EXPECT().ValidateRetryToken([]byte("retry token"), p.remoteAddr).Return(
+					true,
+					protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde},
+					protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+					nil,
+				)
+				phm.EXPECT().AddWithConnID(hdr.DestConnectionID, gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				})
+				tracer.EXPECT().TracerForConnection(protocol.PerspectiveServer, protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde})
+				run := make(chan struct{})
+				sess := NewMockQuicSession(mockCtrl)
+				serv.newSession = func(
+					_ sendConn,
+					_ sessionRunner,
+					origDestConnID protocol.ConnectionID,
+					retrySrcConnID *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					Expect(origDestConnID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde}))
+					Expect(retrySrcConnID).To(Equal(&protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad}))
+					sess.EXPECT().handlePacket(p)
+					sess.EXPECT().run().Do(func() { close(run) })
+					sess.EXPECT().Context().Return(context.Background())
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					return sess
+				}
+				serv.handlePacket(p)
+				Eventually(run).Should(BeClosed())
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
 			It("creates a session when the token is accepted", func() {
 				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool { return true }
 				retryToken, err := serv.tokenGenerator.NewRetryToken(
@@ -427,6 +566,58 @@ var _ = Describe("Server", func() {
 				Expect(write.data[len(write.data)-16:]).To(Equal(handshake.GetRetryIntegrityTag(write.data[:len(write.data)-16], hdr.DestConnectionID)[:]))
 			})
 
+			It("replies with a Retry packet, if the handshake rate limit is exceeded", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.config.MaxIncomingHandshakesPerSecond = 2
+				serv.countHandshake(time.Now())
+				serv.countHandshake(time.Now())
+				hdr := &wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ConnectionID{5, 4, 3, 2, 1},
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+					Version:          protocol.VersionTLS,
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				tracer.EXPECT().SentPacket(packet.remoteAddr, gomock.Any(), gomock.Any(), nil)
+				serv.handlePacket(packet)
+				var write mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&write))
+				Expect(parseHeader(write.data).Type).To(Equal(protocol.PacketTypeRetry))
+			})
+
+			It("accepts clients that present a token, if the handshake rate limit is exceeded", func() {
+				serv.config.AcceptToken = func(_ net.Addr, token *Token) bool { return token != nil }
+				serv.config.MaxIncomingHandshakesPerSecond = 1
+				serv.countHandshake(time.Now())
+				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, protocol.ConnectionID{0xde, 0xad, 0xc0, 0xde}, protocol.ConnectionID{1, 2, 3, 4})
+				Expect(err).ToNot(HaveOccurred())
+				p := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeInitial,
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+					Token:            token,
+					Version:          serv.config.Versions[0],
+				}, make([]byte, protocol.MinInitialPacketSize))
+				// the duplicate check is the first step of creating a new session
+				phm.EXPECT().AddWithConnID(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}, gomock.Any(), gomock.Any()).Return(false)
+				Expect(serv.handlePacketImpl(p)).To(BeTrue())
+				Consistently(conn.dataWritten).ShouldNot(Receive())
+			})
+
+			It("limits the rate of handshakes per second", func() {
+				serv.config.MaxIncomingHandshakesPerSecond = 2
+				now := time.Now()
+				Expect(serv.handshakeRateLimitExceeded(now)).To(BeFalse())
+				serv.countHandshake(now)
+				Expect(serv.handshakeRateLimitExceeded(now)).To(BeFalse())
+				serv.countHandshake(now.Add(500 * time.Millisecond))
+				Expect(serv.handshakeRateLimitExceeded(now.Add(999 * time.Millisecond))).To(BeTrue())
+				Expect(serv.handshakeRateLimitExceeded(now.Add(time.Second))).To(BeFalse())
+				serv.countHandshake(now.Add(time.Second))
+				Expect(serv.handshakeRateLimitExceeded(now.Add(time.Second))).To(BeFalse())
+			})
+
 			It("sends an INVALID_TOKEN error, if an invalid retry token is received", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
 				token, err := serv.tokenGenerator.NewRetryToken(&net.UDPAddr{}, nil, nil)
@@ -775,6 +966,71 @@ var _ = Describe("Server", func() {
 				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
 			})
 
+			It("rejects new connection attempts if the maximum number of connections is reached", func() {
+				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+				serv.config.MaxIncomingConnections = 1
+
+				sessionCreated := make(chan struct{}, 2)
+				closeSession := make(chan struct{})
+				serv.newSession = func(
+					_ sendConn,
+					runner sessionRunner,
+					_ protocol.ConnectionID,
+					_ *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					_ bool,
+					_ logging.ConnectionTracer,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicSession {
+					ctx, cancel := context.WithCancel(context.Background())
+					sess := NewMockQuicSession(mockCtrl)
+					sess.EXPECT().handlePacket(gomock.Any())
+					sess.EXPECT().run().Do(func() {
+						<-closeSession
+						cancel()
+					})
+					sess.EXPECT().Context().Return(ctx)
+					sess.EXPECT().HandshakeComplete().Return(context.Background())
+					sessionCreated <- struct{}{}
+					return sess
+				}
+				phm.EXPECT().AddWithConnID(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_, _ protocol.ConnectionID, fn func() packetHandler) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					fn()
+					return true
+				}).Times(2)
+				tracer.EXPECT().TracerForConnection(protocol.PerspectiveServer, gomock.Any()).Times(2)
+
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(sessionCreated).Should(Receive())
+
+				p := getInitialWithRandomDestConnID()
+				hdr := parseHeader(p.data)
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				serv.handlePacket(p)
+				var reject mockPacketConnWrite
+				Eventually(conn.dataWritten).Should(Receive(&reject))
+				Expect(reject.to).To(Equal(p.remoteAddr))
+				rejectHdr := parseHeader(reject.data)
+				Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
+				Expect(sessionCreated).ToNot(Receive())
+
+				// once the session is closed, new connections are accepted again
+				close(closeSession)
+				Eventually(func() int32 { return atomic.LoadInt32(&serv.numSessions) }).Should(BeZero())
+				serv.handlePacket(getInitialWithRandomDestConnID())
+				Eventually(sessionCreated).Should(Receive())
+			})
+
 			It("doesn't accept new sessions if they were closed in the mean time", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
 
@@ -1110,6 +1366,8 @@ var _ = Describe("Server", func() {
 })
 
 var _ = Describe("default source address verification", func() {
+	acceptToken := defaultAcceptToken(utils.DefaultClock{})
+
 	It("accepts a token", func() {
 		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}
 		token := &Token{
@@ -1117,12 +1375,12 @@ var _ = Describe("default source address verification", func() {
 			RemoteAddr:   "192.168.0.1",
 			SentTime:     time.Now().Add(-protocol.RetryTokenValidity).Add(time.Second), // will expire in 1 second
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeTrue())
+		Expect(acceptToken(remoteAddr, token)).To(BeTrue())
 	})
 
 	It("requests verification if no token is provided", func() {
 		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}
-		Expect(defaultAcceptToken(remoteAddr, nil)).To(BeFalse())
+		Expect(acceptToken(remoteAddr, nil)).To(BeFalse())
 	})
 
 	It("rejects a token if the address doesn't match", func() {
@@ -1132,7 +1390,7 @@ var _ = Describe("default source address verification", func() {
 			RemoteAddr:   "127.0.0.1",
 			SentTime:     time.Now(),
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeFalse())
+		Expect(acceptToken(remoteAddr, token)).To(BeFalse())
 	})
 
 	It("accepts a token for a remote address is not a UDP address", func() {
@@ -1142,7 +1400,7 @@ var _ = Describe("default source address verification", func() {
 			RemoteAddr:   "192.168.0.1:1337",
 			SentTime:     time.Now(),
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeTrue())
+		Expect(acceptToken(remoteAddr, token)).To(BeTrue())
 	})
 
 	It("rejects an invalid token for a remote address is not a UDP address", func() {
@@ -1152,7 +1410,7 @@ var _ = Describe("default source address verification", func() {
 			RemoteAddr:   "192.168.0.1:7331", // mismatching port
 			SentTime:     time.Now(),
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeFalse())
+		Expect(acceptToken(remoteAddr, token)).To(BeFalse())
 	})
 
 	It("rejects an expired token", func() {
@@ -1162,7 +1420,7 @@ var _ = Describe("default source address verification", func() {
 			RemoteAddr:   "192.168.0.1",
 			SentTime:     time.Now().Add(-protocol.RetryTokenValidity).Add(-time.Second), // expired 1 second ago
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeFalse())
+		Expect(acceptToken(remoteAddr, token)).To(BeFalse())
 	})
 
 	It("accepts a non-retry token", func() {
@@ -1174,6 +1432,6 @@ var _ = Describe("default source address verification", func() {
 			// if this was a retry token, it would have expired one second ago
 			SentTime: time.Now().Add(-protocol.RetryTokenValidity).Add(-time.Second),
 		}
-		Expect(defaultAcceptToken(remoteAddr, token)).To(BeTrue())
+		Expect(acceptToken(remoteAddr, token)).To(BeTrue())
 	})
 })
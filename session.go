@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/logutils"
@@ -45,6 +46,7 @@ type streamManager interface {
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*wire.TransportParameters) error
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame) error
+	ActiveStreams() []StreamInfo
 	CloseWithError(error)
 }
 
@@ -52,18 +54,30 @@ type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	ForceKeyUpdate() error
 	DropHandshakeKeys()
 	GetSessionTicket() ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
+	NegotiatedProtocol() string
+	Used0RTT() bool
+	KeyExchangeGroup() tls.CurveID
 }
 
 type receivedPacket struct {
 	remoteAddr net.Addr
 	rcvTime    time.Time
+	ecn        protocol.ECN
 	data       []byte
 
+	// timestamp is the receive timestamp reported by the kernel, or the time the packet was read from the socket.
+	// It is only used for tracing: kernel timestamps don't contain a monotonic clock reading,
+	// so rcvTime is used for everything else.
+	timestamp time.Time
+
 	buffer *packetBuffer
+	// path is set for packets received on a path other than the one the session was created with
+	path sendConn
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
@@ -72,8 +86,11 @@ func (p *receivedPacket) Clone() *receivedPacket {
 	return &receivedPacket{
 		remoteAddr: p.remoteAddr,
 		rcvTime:    p.rcvTime,
+		timestamp:  p.timestamp,
+		ecn:        p.ecn,
 		data:       p.data,
 		buffer:     p.buffer,
+		path:       p.path,
 	}
 }
 
@@ -86,6 +103,7 @@ type sessionRunner interface {
 	AddResetToken(protocol.StatelessResetToken, packetHandler)
 	RemoveResetToken(protocol.StatelessResetToken)
 	RetireResetToken(protocol.StatelessResetToken)
+	HandlePacketOnPath(*receivedPacket, packetHandler)
 }
 
 type handshakeRunner struct {
@@ -125,7 +143,8 @@ type session struct {
 	// Destination connection ID used during the handshake.
 	// Used to check source connection ID on incoming packets.
 	handshakeDestConnID protocol.ConnectionID
-	// Set for the client. Destination connection ID used on the first Initial sent.
+	// Destination connection ID used on the first Initial sent by the client.
+	// If a Retry was performed, this is the connection ID used before the Retry.
 	origDestConnID protocol.ConnectionID
 	retrySrcConnID *protocol.ConnectionID // only set for the client (and if a Retry was performed)
 
@@ -136,7 +155,11 @@ type session struct {
 	version        protocol.VersionNumber
 	config         *Config
 
-	conn      sendConn
+	runner sessionRunner
+
+	conn sendConn
+	// path is the sendConn that the session sends on. It's switched when the connection is migrated.
+	path      *switchableSendConn
 	sendQueue *sendQueue
 
 	streamsMap      streamManager
@@ -144,6 +167,27 @@ type session struct {
 	connIDGenerator *connIDGenerator
 
 	rttStats *utils.RTTStats
+	stats    *connectionStats
+	// connStateMutex protects the parts of the connection state that are read from outside the run loop,
+	// by ConnectionState, Max0RTTSize, and by CurrentPath and the path validation in path.go.
+	// These are all fields from rttStatsSnapshot up to the ECN counts reported by the peer.
+	connStateMutex sync.Mutex
+	// rttStatsSnapshot is a copy of the rttStats
+	rttStatsSnapshot      utils.RTTStats
+	bandwidthEstimate     congestion.Bandwidth
+	negotiatedIdleTimeout time.Duration
+	usedRetry             bool
+	pathValidated         bool
+	max0RTTSize           protocol.ByteCount
+	// the transport parameters received from the peer
+	remoteTransportParams *wire.TransportParameters
+	// the ECN counts reported by the peer
+	peerECT0, peerECT1, peerECNCE uint64
+
+	acceptDeadlineMutex sync.Mutex
+	acceptDeadline      time.Time
+	// acceptDeadlineChanged is closed (and replaced) every time the accept deadline is changed
+	acceptDeadlineChanged chan struct{}
 
 	cryptoStreamManager   *cryptoStreamManager
 	sentPacketHandler     ackhandler.SentPacketHandler
@@ -153,6 +197,7 @@ type session struct {
 	windowUpdateQueue     *windowUpdateQueue
 	connFlowController    flowcontrol.ConnectionFlowController
 	tokenStoreKey         string                    // only set for the client
+	usedToken             bool                      // only set for the client, if a token from the TokenStore was used
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
 	unpacker    unpacker
@@ -164,15 +209,45 @@ type session struct {
 
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
+	// set when run() is called
+	// Until then, no CONNECTION_CLOSE can be written, so CloseWithErrorSync doesn't wait for it.
+	runStarted utils.AtomicBool
+	// used to pass key update requests to the run loop
+	keyUpdateRequests chan chan error
+	// used to pass PING requests to the run loop
+	// The channel is closed once a 1-RTT packet containing a PING frame was sent.
+	pingRequests chan chan struct{}
+	// only accessed from the run loop
+	pendingPings []chan struct{}
+	// used to pass path probing requests to the run loop
+	pathProbeRequests chan pathProbeRequest
+	// the path that is currently being validated
+	// For the client, this is the path passed to MigratePath.
+	// For the server, this is the path to a new peer address that sent a PATH_CHALLENGE.
+	probingPath *pathProbe
+	// reads packets from the net.PacketConn passed to MigratePath, if the client migrated to such a path
+	// Only accessed from the run loop.
+	pathReader *pathReader
+	// the highest packet number of all non-probing 1-RTT packets received
+	// Only this packet is allowed to cause a migration to a new peer address.
+	largestNonProbingPacketNumber protocol.PacketNumber
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan closeError
+	// connCloseWritten is closed once the CONNECTION_CLOSE was written to the connection,
+	// or when it is clear that no CONNECTION_CLOSE will be sent.
+	// connCloseWriteErr is the error returned when writing the CONNECTION_CLOSE.
+	connCloseWritten  chan struct{}
+	connCloseWriteErr error
 
 	ctx                context.Context
 	ctxCancel          context.CancelFunc
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
+	// canceled when the handshake is confirmed
+	handshakeConfirmedCtx       context.Context
+	handshakeConfirmedCtxCancel context.CancelFunc
 
 	undecryptablePackets []*receivedPacket
 
@@ -197,6 +272,12 @@ type session struct {
 
 	peerParams *wire.TransportParameters
 
+	// ACK frequency extension: the max_ack_delay requested in the last ACK_FREQUENCY frame we sent
+	sentAckFrequencyMaxAckDelay time.Duration
+	nextAckFrequencySeqNumber   uint64
+
+	// clock is used for all timers and timestamps of the connection
+	clock utils.Clock
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
@@ -233,13 +314,16 @@ var newSession = func(
 ) quicSession {
 	s := &session{
 		conn:                  conn,
+		runner:                runner,
 		config:                conf,
+		origDestConnID:        origDestConnID,
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		tokenGenerator:        tokenGenerator,
 		oneRTTStream:          newCryptoStream(),
 		perspective:           protocol.PerspectiveServer,
 		handshakeCompleteChan: make(chan struct{}),
+		usedRetry:             retrySrcConnID != nil,
 		tracer:                tracer,
 		logger:                logger,
 		version:               v,
@@ -251,14 +335,18 @@ var newSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ActiveConnectionIDLimit,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		runner.RetireResetToken,
 		s.queueControlFrame,
+		s.config.Rand,
+		s.tracer,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
+		s.config.ConnectionIDGenerator,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -271,6 +359,14 @@ var newSession = func(
 		0,
 		s.rttStats,
 		s.perspective,
+		protocol.ByteCount(s.config.MaxSendRate),
+		s.config.MaxProbeTimeout,
+		s.config.AmplificationFactor,
+		s.config.EnableECN && s.conn.SupportsECN(),
+		s.config.MaxAckRanges,
+		s.config.CongestionControlFactory,
+		s.config.Rand,
+		s.clock,
 		s.traceCallback,
 		s.tracer,
 		s.logger,
@@ -284,16 +380,25 @@ var newSession = func(
 		InitialMaxStreamDataUni:         protocol.InitialMaxStreamData,
 		InitialMaxData:                  protocol.InitialMaxData,
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
-		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
-		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
+		MaxBidiStreamNum:                protocol.StreamNum(s.config.InitialMaxIncomingStreams),
+		MaxUniStreamNum:                 protocol.StreamNum(s.config.InitialMaxIncomingUniStreams),
 		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:                protocol.AckDelayExponent,
-		DisableActiveMigration:          true,
+		MaxUDPPayloadSize:               s.maxUDPPayloadSize(),
+		DisableActiveMigration:          !s.config.AllowConnectionMigration || s.config.DisableActiveMigration,
 		StatelessResetToken:             &statelessResetToken,
 		OriginalDestinationConnectionID: origDestConnID,
-		ActiveConnectionIDLimit:         protocol.MaxActiveConnectionIDs,
+		ActiveConnectionIDLimit:         s.config.ActiveConnectionIDLimit,
 		InitialSourceConnectionID:       srcConnID,
 		RetrySourceConnectionID:         retrySrcConnID,
+		GreaseQUICBit:                   s.config.GREASEQUICBit,
+	}
+	if s.config.PreferredAddress != nil {
+		params.PreferredAddress = s.newPreferredAddressParameter()
+	}
+	if s.config.EnableACKFrequency {
+		minAckDelay := protocol.MinAckDelay
+		params.MinAckDelay = &minAckDelay
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
@@ -316,10 +421,14 @@ var newSession = func(
 		},
 		tlsConf,
 		enable0RTT,
+		s.config.Allow0RTT,
 		s.rttStats,
 		tracer,
 		logger,
 	)
+	if s.config.DisableHeaderProtection {
+		cs = handshake.MaybeDisableHeaderProtection(cs, s.logger)
+	}
 	s.cryptoStreamHandler = cs
 	s.packer = newPacketPacker(
 		srcConnID,
@@ -332,6 +441,8 @@ var newSession = func(
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
+		s.config.GREASEQUICBit,
+		s.config.DisablePacketCoalescing,
 		s.perspective,
 		s.version,
 	)
@@ -358,6 +469,7 @@ var newClientSession = func(
 ) quicSession {
 	s := &session{
 		conn:                  conn,
+		runner:                runner,
 		config:                conf,
 		origDestConnID:        destConnID,
 		handshakeDestConnID:   destConnID,
@@ -373,14 +485,18 @@ var newClientSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ActiveConnectionIDLimit,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		runner.RetireResetToken,
 		s.queueControlFrame,
+		s.config.Rand,
+		s.tracer,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
+		s.config.ConnectionIDGenerator,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -393,6 +509,14 @@ var newClientSession = func(
 		initialPacketNumber,
 		s.rttStats,
 		s.perspective,
+		protocol.ByteCount(s.config.MaxSendRate),
+		s.config.MaxProbeTimeout,
+		s.config.AmplificationFactor,
+		s.config.EnableECN && s.conn.SupportsECN(),
+		s.config.MaxAckRanges,
+		s.config.CongestionControlFactory,
+		s.config.Rand,
+		s.clock,
 		s.traceCallback,
 		s.tracer,
 		s.logger,
@@ -406,13 +530,19 @@ var newClientSession = func(
 		InitialMaxStreamDataUni:        protocol.InitialMaxStreamData,
 		InitialMaxData:                 protocol.InitialMaxData,
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
-		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
-		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
+		MaxBidiStreamNum:               protocol.StreamNum(s.config.InitialMaxIncomingStreams),
+		MaxUniStreamNum:                protocol.StreamNum(s.config.InitialMaxIncomingUniStreams),
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:               protocol.AckDelayExponent,
+		MaxUDPPayloadSize:              s.maxUDPPayloadSize(),
 		DisableActiveMigration:         true,
-		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		ActiveConnectionIDLimit:        s.config.ActiveConnectionIDLimit,
 		InitialSourceConnectionID:      srcConnID,
+		GreaseQUICBit:                  s.config.GREASEQUICBit,
+	}
+	if s.config.EnableACKFrequency {
+		minAckDelay := protocol.MinAckDelay
+		params.MinAckDelay = &minAckDelay
 	}
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
@@ -437,6 +567,9 @@ var newClientSession = func(
 		logger,
 	)
 	s.clientHelloWritten = clientHelloWritten
+	if s.config.DisableHeaderProtection {
+		cs = handshake.MaybeDisableHeaderProtection(cs, s.logger)
+	}
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, newCryptoStream())
 	s.unpacker = newPacketUnpacker(cs, s.version)
@@ -451,6 +584,8 @@ var newClientSession = func(
 		cs,
 		s.framer,
 		s.receivedPacketHandler,
+		s.config.GREASEQUICBit,
+		s.config.DisablePacketCoalescing,
 		s.perspective,
 		s.version,
 	)
@@ -462,41 +597,81 @@ var newClientSession = func(
 	if s.config.TokenStore != nil {
 		if token := s.config.TokenStore.Pop(s.tokenStoreKey); token != nil {
 			s.packer.SetToken(token.data)
+			s.usedToken = true
 		}
 	}
 	return s
 }
 
 func (s *session) preSetup() {
+	s.clock = s.config.clock
+	if s.config.PacketInterceptor != nil {
+		s.conn = newInterceptingSendConn(s.conn, s.config.PacketInterceptor)
+	}
+	s.path = newSwitchableSendConn(s.conn)
+	s.pathValidated = true
+	s.conn = s.path
 	s.sendQueue = newSendQueue(s.conn)
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
 	s.frameParser = wire.NewFrameParser(s.version)
 	s.rttStats = &utils.RTTStats{}
+	s.stats = &connectionStats{}
+	if s.config.InitialRTT != 0 {
+		s.rttStats.SetInitialRTT(s.config.InitialRTT)
+	}
+	maxReceiveConnectionWindow := protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow)
+	if s.config.DisableFlowControlAutoTuning {
+		maxReceiveConnectionWindow = protocol.InitialMaxData
+	}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.InitialMaxData,
-		protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow),
+		maxReceiveConnectionWindow,
+		protocol.ByteCount(s.config.MaxConnectionReceiveBuffer),
 		s.onHasConnectionWindowUpdate,
 		s.rttStats,
 		s.logger,
 	)
 	s.earlySessionReadyChan = make(chan struct{})
+	s.acceptDeadlineChanged = make(chan struct{})
+	var onStreamsNeeded func(protocol.StreamType, uint64) uint64
+	if f := s.config.OnStreamsNeeded; f != nil {
+		onStreamsNeeded = func(t protocol.StreamType, current uint64) uint64 {
+			if n := f(t, int64(current)); n > 0 {
+				return uint64(n)
+			}
+			return 0
+		}
+	}
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
+		s.config.OnIncomingStream,
+		s.config.StreamWriteCoalesceDelay,
+		s.config.MaxStreamIdleTimeout,
+		s.config.StreamIdleTimeoutErrorCode,
+		uint64(s.config.InitialMaxIncomingStreams),
 		uint64(s.config.MaxIncomingStreams),
+		uint64(s.config.InitialMaxIncomingUniStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		onStreamsNeeded,
+		s.clock,
 		s.perspective,
 		s.version,
 	)
 	s.framer = newFramer(s.streamsMap, s.version)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
+	s.connCloseWritten = make(chan struct{})
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.keyUpdateRequests = make(chan chan error)
+	s.pingRequests = make(chan chan struct{})
+	s.pathProbeRequests = make(chan pathProbeRequest)
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
+	s.handshakeConfirmedCtx, s.handshakeConfirmedCtxCancel = context.WithCancel(context.Background())
 
-	now := time.Now()
+	now := s.clock.Now()
 	s.lastPacketReceivedTime = now
 	s.sessionCreationTime = now
 
@@ -511,9 +686,10 @@ func (s *session) preSetup() {
 
 // run the session main loop
 func (s *session) run() error {
+	s.runStarted.Set(true)
 	defer s.ctxCancel()
 
-	s.timer = utils.NewTimer()
+	s.timer = utils.NewTimerWithClock(s.clock)
 
 	go s.cryptoStreamHandler.RunHandshake()
 	go func() {
@@ -576,9 +752,16 @@ runLoop:
 			}
 		case <-s.handshakeCompleteChan:
 			s.handleHandshakeComplete()
+		case errChan := <-s.keyUpdateRequests:
+			errChan <- s.forceKeyUpdate()
+		case sent := <-s.pingRequests:
+			s.pendingPings = append(s.pendingPings, sent)
+			s.queueControlFrame(&wire.PingFrame{})
+		case r := <-s.pathProbeRequests:
+			r.errChan <- s.handlePathProbeRequest(r)
 		}
 
-		now := time.Now()
+		now := s.clock.Now()
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
@@ -596,7 +779,13 @@ runLoop:
 			if s.tracer != nil {
 				s.tracer.ClosedConnection(logging.NewTimeoutCloseReason(logging.TimeoutReasonHandshake))
 			}
-			s.destroyImpl(qerr.NewTimeoutError("Handshake did not complete in time"))
+			s.destroyImpl(ErrHandshakeTimeout)
+			continue
+		} else if !s.handshakeComplete && s.config.HandshakeIdleTimeout > 0 && now.Sub(s.lastPacketReceivedTime) >= s.config.HandshakeIdleTimeout {
+			if s.tracer != nil {
+				s.tracer.ClosedConnection(logging.NewTimeoutCloseReason(logging.TimeoutReasonHandshake))
+			}
+			s.destroyImpl(qerr.NewTimeoutError("No recent network activity during the handshake"))
 			continue
 		} else if s.handshakeComplete && now.Sub(s.idleTimeoutStartTime()) >= s.idleTimeout {
 			if s.tracer != nil {
@@ -619,6 +808,9 @@ runLoop:
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close()
 	s.timer.Stop()
+	if s.pathReader != nil {
+		go s.pathReader.stop()
+	}
 	return closeErr.err
 }
 
@@ -631,12 +823,144 @@ func (s *session) HandshakeComplete() context.Context {
 	return s.handshakeCtx
 }
 
+func (s *session) HandshakeConfirmed() context.Context {
+	return s.handshakeConfirmedCtx
+}
+
 func (s *session) Context() context.Context {
 	return s.ctx
 }
 
+// ErrHandshakeTimeout is returned when the handshake didn't complete within the HandshakeTimeout.
+// It satisfies the net.Error interface, and Timeout() is true.
+var ErrHandshakeTimeout error = qerr.NewTimeoutError("Handshake did not complete in time")
+
+// ErrVersionNegotiationReceived is returned when a Version Negotiation packet is received,
+// and version negotiation was disabled using Config.DisableVersionNegotiation.
+var ErrVersionNegotiationReceived = errors.New("received a Version Negotiation packet, but version negotiation is disabled")
+
+// ErrHandshakeNotComplete is returned by Session.SendPing when the handshake hasn't completed yet.
+var ErrHandshakeNotComplete = errors.New("handshake not yet complete")
+
+func (s *session) SendPing() error {
+	select {
+	case <-s.handshakeCtx.Done():
+	default:
+		return ErrHandshakeNotComplete
+	}
+	sent := make(chan struct{})
+	select {
+	case s.pingRequests <- sent:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	select {
+	case <-sent:
+		return nil
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+}
+
+// maybeNotifyPingSent unblocks the pending SendPing calls, if the packet contains a PING frame.
+func (s *session) maybeNotifyPingSent(p *packetContents) {
+	if len(s.pendingPings) == 0 || p.EncryptionLevel() != protocol.Encryption1RTT {
+		return
+	}
+	for _, f := range p.frames {
+		if _, ok := f.Frame.(*wire.PingFrame); ok {
+			for _, sent := range s.pendingPings {
+				close(sent)
+			}
+			s.pendingPings = nil
+			return
+		}
+	}
+}
+
+var (
+	// ErrHandshakeNotConfirmed is returned by Session.ForceKeyUpdate when the handshake hasn't been confirmed yet.
+	ErrHandshakeNotConfirmed = errors.New("handshake not yet confirmed")
+	// ErrKeyUpdateInProgress is returned by Session.ForceKeyUpdate when the previous key update hasn't been acknowledged yet.
+	ErrKeyUpdateInProgress = handshake.ErrKeyUpdateInProgress
+)
+
+func (s *session) ForceKeyUpdate() error {
+	errChan := make(chan error, 1)
+	select {
+	case s.keyUpdateRequests <- errChan:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	return <-errChan
+}
+
+// forceKeyUpdate is called from the run loop
+func (s *session) forceKeyUpdate() error {
+	if !s.handshakeConfirmed {
+		return ErrHandshakeNotConfirmed
+	}
+	return s.cryptoStreamHandler.ForceKeyUpdate()
+}
+
 func (s *session) ConnectionState() ConnectionState {
-	return s.cryptoStreamHandler.ConnectionState()
+	cs := ConnectionState{
+		ConnectionState:                 s.cryptoStreamHandler.ConnectionState(),
+		KeyExchangeGroup:                s.cryptoStreamHandler.KeyExchangeGroup(),
+		OriginalDestinationConnectionID: s.origDestConnID,
+	}
+	s.connStateMutex.Lock()
+	cs.SmoothedRTT = s.rttStatsSnapshot.SmoothedRTT()
+	cs.RTTVar = s.rttStatsSnapshot.MeanDeviation()
+	cs.MinRTT = s.rttStatsSnapshot.MinRTT()
+	cs.BandwidthEstimate = uint64(s.bandwidthEstimate / congestion.BytesPerSecond)
+	cs.MaxIdleTimeout = s.negotiatedIdleTimeout
+	cs.UsedRetry = s.usedRetry
+	select {
+	case <-s.handshakeConfirmedCtx.Done():
+		cs.HandshakeConfirmed = true
+	default:
+	}
+	if s.remoteTransportParams != nil {
+		params := *s.remoteTransportParams
+		cs.RemoteTransportParameters = &params
+	}
+	cs.ECT0 = s.peerECT0
+	cs.ECT1 = s.peerECT1
+	cs.ECNCE = s.peerECNCE
+	s.connStateMutex.Unlock()
+	return cs
+}
+
+func (s *session) Stats() ConnectionStats {
+	stats := s.stats.get()
+	lostPackets, lostBytes := s.sentPacketHandler.PacketsLost()
+	stats.PacketsLost = lostPackets
+	stats.BytesLost = uint64(lostBytes)
+	return stats
+}
+
+func (s *session) ActiveStreams() []StreamInfo {
+	return s.streamsMap.ActiveStreams()
+}
+
+func (s *session) NegotiatedProtocol() string {
+	return s.cryptoStreamHandler.NegotiatedProtocol()
+}
+
+func (s *session) EarlyDataAccepted() bool {
+	select {
+	case <-s.handshakeCtx.Done():
+		return s.cryptoStreamHandler.Used0RTT()
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *session) Max0RTTSize() protocol.ByteCount {
+	s.connStateMutex.Lock()
+	defer s.connStateMutex.Unlock()
+	return s.max0RTTSize
 }
 
 // Time when the next keep-alive packet should be sent.
@@ -652,6 +976,9 @@ func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if !s.handshakeComplete {
 		deadline = s.sessionCreationTime.Add(s.config.HandshakeTimeout)
+		if s.config.HandshakeIdleTimeout > 0 {
+			deadline = utils.MinTime(deadline, s.lastPacketReceivedTime.Add(s.config.HandshakeIdleTimeout))
+		}
 	} else {
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() {
 			deadline = keepAliveTime
@@ -695,7 +1022,12 @@ func (s *session) handleHandshakeComplete() {
 				s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
 			}
 		}
-		token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+		var token []byte
+		if s.config.AddressTokenGenerator != nil {
+			token, err = s.config.AddressTokenGenerator.NewToken(s.conn.RemoteAddr())
+		} else {
+			token, err = s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+		}
 		if err != nil {
 			s.closeLocal(err)
 		}
@@ -705,7 +1037,32 @@ func (s *session) handleHandshakeComplete() {
 	}
 }
 
+// maxUDPPayloadSize is the value of the max_udp_payload_size transport parameter that we send.
+// We can't receive datagrams larger than protocol.MaxReceivePacketSize.
+func (s *session) maxUDPPayloadSize() protocol.ByteCount {
+	return utils.MinByteCount(s.config.MaxUDPPayloadSize, protocol.MaxReceivePacketSize)
+}
+
 func (s *session) handlePacketImpl(rp *receivedPacket) bool {
+	if s.config.IncomingPacketInterceptor != nil {
+		forward, rewritten := s.config.IncomingPacketInterceptor(rp.data, rp.remoteAddr)
+		if !forward {
+			rp.buffer.Release()
+			return false
+		}
+		if rewritten != nil {
+			rp.data = rewritten
+		}
+	}
+	// Once the handshake has completed, the peer knows the max_udp_payload_size that we advertised.
+	if s.handshakeComplete && rp.Size() > s.maxUDPPayloadSize() {
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, rp.Size(), logging.PacketDropProtocolViolation)
+		}
+		s.logger.Debugf("Dropping datagram (%d bytes) larger than the advertised max_udp_payload_size (%d bytes)", rp.Size(), s.maxUDPPayloadSize())
+		rp.buffer.Release()
+		return false
+	}
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
 		return false
@@ -716,14 +1073,24 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	var processed bool
 	data := rp.data
 	p := rp
-	s.sentPacketHandler.ReceivedBytes(protocol.ByteCount(len(data)))
+	// Bytes received on other paths don't count towards the anti-amplification limit of the active path.
+	if newPath := s.newPathFor(rp); newPath == nil {
+		s.sentPacketHandler.ReceivedBytes(protocol.ByteCount(len(data)))
+	} else if s.probingPath != nil && s.probingPath.conn == newPath {
+		s.probingPath.bytesReceived += protocol.ByteCount(len(data))
+	}
 	for len(data) > 0 {
 		if counter > 0 {
 			p = p.Clone()
 			p.data = data
 		}
 
-		hdr, packetData, rest, err := wire.ParsePacket(p.data, s.srcConnIDLen)
+		parsePacket := wire.ParsePacket
+		// We only accept short header packets with the QUIC bit unset if we sent the grease_quic_bit transport parameter.
+		if s.config.GREASEQUICBit {
+			parsePacket = wire.ParsePacketWithGreasedQUICBit
+		}
+		hdr, packetData, rest, err := parsePacket(p.data, s.srcConnIDLen)
 		if err != nil {
 			if s.tracer != nil {
 				dropReason := logging.PacketDropHeaderParseError
@@ -852,7 +1219,8 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.rcvTime, p.Size()); err != nil {
+	s.stats.receivedPacket(p.Size())
+	if err := s.handleUnpackedPacket(packet, p); err != nil {
 		s.closeLocal(err)
 		return false
 	}
@@ -886,6 +1254,9 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 	// If a token is already set, this means that we already received a Retry from the server.
 	// Ignore this Retry packet.
 	if s.receivedRetry {
+		if s.tracer != nil {
+			s.tracer.DroppedPacket(logging.PacketTypeRetry, protocol.ByteCount(len(data)), logging.PacketDropUnexpectedPacket)
+		}
 		s.logger.Debugf("Ignoring Retry, since a Retry was already received.")
 		return false
 	}
@@ -906,6 +1277,11 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 	}
 	newDestConnID := hdr.SrcConnectionID
 	s.receivedRetry = true
+	s.connStateMutex.Lock()
+	s.usedRetry = true
+	s.connStateMutex.Unlock()
+	// The server didn't accept our token, if we sent one.
+	s.invalidateToken()
 	if err := s.sentPacketHandler.ResetForRetry(); err != nil {
 		s.closeLocal(err)
 		return false
@@ -937,6 +1313,15 @@ func (s *session) handleVersionNegotiationPacket(p *receivedPacket) {
 		return
 	}
 
+	if s.config.DisableVersionNegotiation {
+		if s.tracer != nil {
+			s.tracer.ReceivedVersionNegotiationPacket(hdr, supportedVersions)
+		}
+		s.logger.Infof("Received a Version Negotiation packet, but version negotiation is disabled. Supported Versions: %s", supportedVersions)
+		s.destroyImpl(ErrVersionNegotiationReceived)
+		return
+	}
+
 	for _, v := range supportedVersions {
 		if v == s.version {
 			if s.tracer != nil {
@@ -970,9 +1355,9 @@ func (s *session) handleVersionNegotiationPacket(p *receivedPacket) {
 
 func (s *session) handleUnpackedPacket(
 	packet *unpackedPacket,
-	rcvTime time.Time,
-	packetSize protocol.ByteCount, // only for logging
+	rp *receivedPacket,
 ) error {
+	rcvTime := rp.rcvTime
 	if len(packet.data) == 0 {
 		return qerr.NewError(qerr.ProtocolViolation, "empty packet")
 	}
@@ -1017,6 +1402,12 @@ func (s *session) handleUnpackedPacket(
 	var frames []wire.Frame
 	var transportState *quictrace.TransportState
 
+	// If the packet was received on a path other than the active path,
+	// PATH_CHALLENGE frames need to be answered on that path.
+	newPath := s.newPathFor(rp)
+	var pathChallenge *wire.PathChallengeFrame
+	isProbingPacket := true
+
 	r := bytes.NewReader(packet.data)
 	var isAckEliciting bool
 	for {
@@ -1030,13 +1421,19 @@ func (s *session) handleUnpackedPacket(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isProbingPacket = false
+		}
 		if s.traceCallback != nil || s.tracer != nil {
 			frames = append(frames, frame)
 		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if s.tracer == nil {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if f, ok := frame.(*wire.PathChallengeFrame); ok && newPath != nil {
+				wire.LogFrame(s.logger, f, false)
+				pathChallenge = f
+			} else if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
 				return err
 			}
 		}
@@ -1059,15 +1456,24 @@ func (s *session) handleUnpackedPacket(
 		for i, frame := range frames {
 			fs[i] = logutils.ConvertFrame(frame)
 		}
-		s.tracer.ReceivedPacket(packet.hdr, packetSize, fs)
+		s.tracer.ReceivedPacket(packet.hdr, rp.Size(), rp.timestamp, fs)
 		for _, frame := range frames {
-			if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
+			if f, ok := frame.(*wire.PathChallengeFrame); ok && newPath != nil {
+				wire.LogFrame(s.logger, f, false)
+				pathChallenge = f
+			} else if err := s.handleFrame(frame, packet.encryptionLevel, packet.hdr.DestConnectionID); err != nil {
 				return err
 			}
 		}
 	}
 
-	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, packet.encryptionLevel, rcvTime, isAckEliciting)
+	if pathChallenge != nil {
+		s.handlePathChallengeOnNewPath(pathChallenge, newPath, rp.Size())
+	}
+	if !isProbingPacket && packet.encryptionLevel == protocol.Encryption1RTT {
+		s.maybeMigratePeer(rp, packet.packetNumber)
+	}
+	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, rp.ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
 func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, destConnID protocol.ConnectionID) error {
@@ -1099,8 +1505,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1109,6 +1514,10 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 		err = s.handleRetireConnectionIDFrame(frame, destConnID)
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
+	case *wire.AckFrequencyFrame:
+		err = s.handleAckFrequencyFrame(frame)
+	case *wire.ImmediateAckFrame:
+		err = s.handleImmediateAckFrame()
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1117,6 +1526,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 
 // handlePacket is called by the server with a new packet
 func (s *session) handlePacket(p *receivedPacket) {
+	p.rcvTime = s.clock.Now()
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxSessionUnprocessedPackets
 	select {
@@ -1126,15 +1536,29 @@ func (s *session) handlePacket(p *receivedPacket) {
 }
 
 func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
-	var e error
+	var e *qerr.QuicError
 	if frame.IsApplicationError {
 		e = qerr.NewApplicationError(frame.ErrorCode, frame.ReasonPhrase)
 	} else {
-		e = qerr.NewError(frame.ErrorCode, frame.ReasonPhrase)
+		e = qerr.NewErrorWithFrameType(frame.ErrorCode, frame.FrameType, frame.ReasonPhrase)
+		if frame.ErrorCode == qerr.InvalidToken {
+			s.invalidateToken()
+		}
 	}
+	e.Remote = true
 	s.closeRemote(e)
 }
 
+// invalidateToken is called when the server rejected the token we sent.
+func (s *session) invalidateToken() {
+	if !s.usedToken {
+		return
+	}
+	s.usedToken = false
+	s.logger.Debugf("Server rejected the token. Invalidating tokens for %s.", s.tokenStoreKey)
+	s.config.TokenStore.Invalidate(s.tokenStoreKey)
+}
+
 func (s *session) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel) error {
 	encLevelChanged, err := s.cryptoStreamManager.HandleCryptoFrame(frame, encLevel)
 	if err != nil {
@@ -1185,6 +1609,9 @@ func (s *session) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	if err != nil {
 		return err
 	}
+	if s.tracer != nil {
+		s.tracer.ReceivedResetStream(frame.StreamID, frame.ErrorCode, frame.FinalSize)
+	}
 	if str == nil {
 		// stream is closed and already garbage collected
 		return nil
@@ -1197,6 +1624,9 @@ func (s *session) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
 	if err != nil {
 		return err
 	}
+	if s.tracer != nil {
+		s.tracer.ReceivedStopSending(frame.StreamID, frame.ErrorCode)
+	}
 	if str == nil {
 		// stream is closed and already garbage collected
 		return nil
@@ -1235,16 +1665,71 @@ func (s *session) handleHandshakeDoneFrame() error {
 	return nil
 }
 
+func (s *session) handleAckFrequencyFrame(frame *wire.AckFrequencyFrame) error {
+	if !s.config.EnableACKFrequency {
+		return qerr.NewError(qerr.ProtocolViolation, "received an ACK_FREQUENCY frame, although the ACK frequency extension was not negotiated")
+	}
+	if frame.UpdateMaxAckDelay < protocol.MinAckDelay {
+		return qerr.NewError(qerr.ProtocolViolation, fmt.Sprintf("requested max_ack_delay (%s) is smaller than the min_ack_delay (%s)", frame.UpdateMaxAckDelay, protocol.MinAckDelay))
+	}
+	s.receivedPacketHandler.SetAckFrequency(frame)
+	return nil
+}
+
+func (s *session) handleImmediateAckFrame() error {
+	if !s.config.EnableACKFrequency {
+		return qerr.NewError(qerr.ProtocolViolation, "received an IMMEDIATE_ACK frame, although the ACK frequency extension was not negotiated")
+	}
+	s.receivedPacketHandler.QueueImmediateAck()
+	return nil
+}
+
 func (s *session) handleAckFrame(frame *wire.AckFrame, encLevel protocol.EncryptionLevel) error {
 	if err := s.sentPacketHandler.ReceivedAck(frame, encLevel, s.lastPacketReceivedTime); err != nil {
 		return err
 	}
+	// The RTT stats are only updated when an ACK is received.
+	s.connStateMutex.Lock()
+	s.rttStatsSnapshot = *s.rttStats
+	s.bandwidthEstimate = s.sentPacketHandler.BandwidthEstimate()
+	if encLevel == protocol.Encryption1RTT {
+		// The ECN counts only increase, so we can ignore the counts of reordered ACKs.
+		s.peerECT0 = utils.MaxUint64(s.peerECT0, frame.ECT0)
+		s.peerECT1 = utils.MaxUint64(s.peerECT1, frame.ECT1)
+		s.peerECNCE = utils.MaxUint64(s.peerECNCE, frame.ECNCE)
+	}
+	s.connStateMutex.Unlock()
 	if encLevel == protocol.Encryption1RTT {
 		s.cryptoStreamHandler.SetLargest1RTTAcked(frame.LargestAcked())
+		s.maybeQueueAckFrequencyFrame()
 	}
 	return nil
 }
 
+// maybeQueueAckFrequencyFrame asks the peer to adjust its ACK frequency to the current RTT,
+// if both endpoints support the ACK frequency extension.
+// The peer will acknowledge every protocol.AckFrequencyPacketTolerance ack-eliciting packets,
+// or after a quarter of the RTT (but not later than protocol.MaxAckDelay), whatever comes first.
+// A new ACK_FREQUENCY frame is only sent if the max_ack_delay changes significantly.
+func (s *session) maybeQueueAckFrequencyFrame() {
+	if !s.config.EnableACKFrequency || !s.handshakeConfirmed || s.peerParams == nil || s.peerParams.MinAckDelay == nil {
+		return
+	}
+	maxAckDelay := utils.MaxDuration(*s.peerParams.MinAckDelay, utils.MinDuration(s.rttStats.SmoothedRTT()/4, protocol.MaxAckDelay))
+	if last := s.sentAckFrequencyMaxAckDelay; last != 0 && utils.AbsDuration(maxAckDelay-last) < last/4 {
+		return
+	}
+	s.sentAckFrequencyMaxAckDelay = maxAckDelay
+	s.queueControlFrame(&wire.AckFrequencyFrame{
+		SequenceNumber:    s.nextAckFrequencySeqNumber,
+		PacketTolerance:   protocol.AckFrequencyPacketTolerance,
+		UpdateMaxAckDelay: maxAckDelay,
+	})
+	s.nextAckFrequencySeqNumber++
+	// The peer might now delay ACKs for longer than the max_ack_delay it advertised.
+	s.rttStats.SetMaxAckDelay(utils.MaxDuration(s.peerParams.MaxAckDelay, maxAckDelay))
+}
+
 // closeLocal closes the session and send a CONNECTION_CLOSE containing the error
 func (s *session) closeLocal(e error) {
 	s.closeOnce.Do(func() {
@@ -1294,7 +1779,28 @@ func (s *session) CloseWithError(code protocol.ApplicationErrorCode, desc string
 	return nil
 }
 
+func (s *session) CloseWithTransportError(code qerr.ErrorCode, frameType uint64, msg string) error {
+	s.closeLocal(qerr.NewErrorWithFrameType(code, frameType, msg))
+	<-s.ctx.Done()
+	return nil
+}
+
+func (s *session) CloseWithErrorSync(ctx context.Context, code protocol.ApplicationErrorCode, desc string) error {
+	s.closeLocal(qerr.NewApplicationError(qerr.ErrorCode(code), desc))
+	if !s.runStarted.Get() {
+		return nil
+	}
+	select {
+	case <-s.connCloseWritten:
+		return s.connCloseWriteErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *session) handleCloseError(closeErr closeError) {
+	defer close(s.connCloseWritten)
+
 	if closeErr.err == nil {
 		closeErr.err = qerr.NewApplicationError(0, "")
 	}
@@ -1334,6 +1840,7 @@ func (s *session) handleCloseError(closeErr closeError) {
 	connClosePacket, err := s.sendConnectionClose(quicErr)
 	if err != nil {
 		s.logger.Debugf("Error sending CONNECTION_CLOSE: %s", err)
+		s.connCloseWriteErr = err
 	}
 	cs := newClosedLocalSession(s.conn, connClosePacket, s.perspective, s.logger)
 	s.connIDGenerator.ReplaceWithClosed(cs)
@@ -1342,7 +1849,9 @@ func (s *session) handleCloseError(closeErr closeError) {
 func (s *session) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	if encLevel == protocol.EncryptionHandshake {
 		s.handshakeConfirmed = true
+		s.handshakeConfirmedCtxCancel()
 		s.sentPacketHandler.SetHandshakeConfirmed()
+		s.maybeMigrateToPreferredAddress()
 	}
 	s.sentPacketHandler.DropPackets(encLevel)
 	s.receivedPacketHandler.DropPackets(encLevel)
@@ -1358,6 +1867,9 @@ func (s *session) restoreTransportParameters(params *wire.TransportParameters) {
 	}
 
 	s.peerParams = params
+	s.connStateMutex.Lock()
+	s.max0RTTSize = params.InitialMaxData
+	s.connStateMutex.Unlock()
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	if err := s.streamsMap.UpdateLimits(params); err != nil {
@@ -1406,6 +1918,10 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
 	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
+	s.connStateMutex.Lock()
+	s.negotiatedIdleTimeout = s.idleTimeout
+	s.remoteTransportParams = params
+	s.connStateMutex.Unlock()
 	if err := s.streamsMap.UpdateLimits(params); err != nil {
 		return err
 	}
@@ -1417,10 +1933,8 @@ func (s *session) processTransportParametersImpl(params *wire.TransportParameter
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
 	if params.PreferredAddress != nil {
-		s.logger.Debugf("Server sent preferred_address. Retiring the preferred_address connection ID.")
-		// Retire the connection ID.
+		// The connection ID is used when migrating to the preferred address (see maybeMigrateToPreferredAddress).
 		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
 	// On the server side, the early session is ready as soon as we processed
@@ -1465,12 +1979,20 @@ func (s *session) sendPackets() error {
 		case ackhandler.SendAny:
 			if s.handshakeComplete && !s.sentPacketHandler.HasPacingBudget() {
 				s.pacingDeadline = s.sentPacketHandler.TimeUntilSend()
+				if s.tracer != nil {
+					s.tracer.SetPacingTimer(s.pacingDeadline)
+				}
 				return nil
 			}
 			sent, err := s.sendPacket()
-			if err != nil || !sent {
+			if err != nil {
 				return err
 			}
+			if !sent {
+				// We'd be allowed to send, but there's nothing to send.
+				s.sentPacketHandler.OnAppLimited()
+				return nil
+			}
 			sentPacket = true
 		default:
 			return fmt.Errorf("BUG: invalid send mode %d", sendMode)
@@ -1527,6 +2049,7 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 	if packet == nil || packet.packetContents == nil {
 		return fmt.Errorf("session BUG: couldn't pack %s probe packet", encLevel)
 	}
+	s.stats.sentProbePacket()
 	s.sendPackedPacket(packet)
 	return nil
 }
@@ -1538,7 +2061,7 @@ func (s *session) sendPacket() (bool, error) {
 	s.windowUpdateQueue.QueueAll()
 
 	if !s.handshakeConfirmed {
-		now := time.Now()
+		now := s.clock.Now()
 		packet, err := s.packer.PackCoalescedPacket(s.sentPacketHandler.AmplificationWindow())
 		if err != nil || packet == nil {
 			return false, err
@@ -1549,9 +2072,11 @@ func (s *session) sendPacket() (bool, error) {
 				s.firstAckElicitingPacketAfterIdleSentTime = now
 			}
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
+			s.maybeNotifyPingSent(p)
 		}
 		s.connIDManager.SentPacket()
-		s.sendQueue.Send(packet.buffer)
+		s.stats.sentDatagram(len(packet.packets), packet.buffer.Len())
+		s.sendQueue.Send(packet.buffer, protocol.ECNNon)
 		return true, nil
 	}
 	packet, err := s.packer.PackPacket()
@@ -1563,14 +2088,23 @@ func (s *session) sendPacket() (bool, error) {
 }
 
 func (s *session) sendPackedPacket(packet *packedPacket) {
-	now := time.Now()
+	now := s.clock.Now()
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(time.Now(), s.retransmissionQueue))
+	// Only 1-RTT packets are marked with ECN.
+	ecn := protocol.ECNNon
+	if s.config.EnableECN && packet.EncryptionLevel() == protocol.Encryption1RTT {
+		ecn = s.sentPacketHandler.ECNMode()
+	}
+	p := packet.ToAckHandlerPacket(now, s.retransmissionQueue)
+	p.ECN = ecn
+	s.sentPacketHandler.SentPacket(p)
+	s.maybeNotifyPingSent(packet.packetContents)
 	s.connIDManager.SentPacket()
 	s.logPacket(now, packet)
-	s.sendQueue.Send(packet.buffer)
+	s.stats.sentDatagram(1, packet.buffer.Len())
+	s.sendQueue.Send(packet.buffer, ecn)
 }
 
 func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
@@ -1578,8 +2112,9 @@ func (s *session) sendConnectionClose(quicErr *qerr.QuicError) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	s.logCoalescedPacket(time.Now(), packet)
-	return packet.buffer.Data, s.conn.Write(packet.buffer.Data)
+	s.logCoalescedPacket(s.clock.Now(), packet)
+	s.stats.sentDatagram(len(packet.packets), packet.buffer.Len())
+	return packet.buffer.Data, s.conn.Write(packet.buffer.Data, protocol.ECNNon)
 }
 
 func (s *session) logPacketContents(now time.Time, p *packetContents) {
@@ -1589,7 +2124,7 @@ func (s *session) logPacketContents(now time.Time, p *packetContents) {
 		for _, f := range p.frames {
 			frames = append(frames, logutils.ConvertFrame(f.Frame))
 		}
-		s.tracer.SentPacket(p.header, p.length, p.ack, frames)
+		s.tracer.SentPacket(p.header, p.length, p.ack, frames, p.sendReason)
 	}
 
 	// quic-trace
@@ -1633,6 +2168,13 @@ func (s *session) logCoalescedPacket(now time.Time, packet *coalescedPacket) {
 	for _, p := range packet.packets {
 		s.logPacketContents(now, p)
 	}
+	if s.tracer != nil {
+		headers := make([]*wire.ExtendedHeader, 0, len(packet.packets))
+		for _, p := range packet.packets {
+			headers = append(headers, p.header)
+		}
+		s.tracer.SentDatagram(packet.buffer.Len(), headers)
+	}
 }
 
 func (s *session) logPacket(now time.Time, packet *packedPacket) {
@@ -1640,15 +2182,78 @@ func (s *session) logPacket(now time.Time, packet *packedPacket) {
 		s.logger.Debugf("-> Sending packet %d (%d bytes) for connection %s, %s", packet.header.PacketNumber, packet.buffer.Len(), s.logID, packet.EncryptionLevel())
 	}
 	s.logPacketContents(now, packet.packetContents)
+	if s.tracer != nil {
+		s.tracer.SentDatagram(packet.buffer.Len(), []*wire.ExtendedHeader{packet.header})
+	}
 }
 
 // AcceptStream returns the next stream openend by the peer
 func (s *session) AcceptStream(ctx context.Context) (Stream, error) {
-	return s.streamsMap.AcceptStream(ctx)
+	for {
+		acceptCtx, cancel, err := s.acceptContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		str, err := s.streamsMap.AcceptStream(acceptCtx)
+		interrupted := acceptCtx.Err() != nil
+		cancel()
+		// If the deadline changed (or was reached), try again with the new deadline.
+		if err == nil || ctx.Err() != nil || !interrupted {
+			return str, err
+		}
+	}
 }
 
 func (s *session) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
-	return s.streamsMap.AcceptUniStream(ctx)
+	for {
+		acceptCtx, cancel, err := s.acceptContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		str, err := s.streamsMap.AcceptUniStream(acceptCtx)
+		interrupted := acceptCtx.Err() != nil
+		cancel()
+		// If the deadline changed (or was reached), try again with the new deadline.
+		if err == nil || ctx.Err() != nil || !interrupted {
+			return str, err
+		}
+	}
+}
+
+// acceptContext derives a context from ctx that is canceled when the accept deadline changes.
+// If the accept deadline has already passed, it returns a timeout error.
+func (s *session) acceptContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	s.acceptDeadlineMutex.Lock()
+	deadline := s.acceptDeadline
+	changed := s.acceptDeadlineChanged
+	s.acceptDeadlineMutex.Unlock()
+
+	if !deadline.IsZero() && !deadline.After(time.Now()) {
+		return nil, nil, errDeadline
+	}
+	var acceptCtx context.Context
+	var cancel context.CancelFunc
+	if deadline.IsZero() {
+		acceptCtx, cancel = context.WithCancel(ctx)
+	} else {
+		acceptCtx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	go func() {
+		select {
+		case <-changed:
+			cancel()
+		case <-acceptCtx.Done():
+		}
+	}()
+	return acceptCtx, cancel, nil
+}
+
+func (s *session) SetAcceptDeadline(t time.Time) {
+	s.acceptDeadlineMutex.Lock()
+	s.acceptDeadline = t
+	close(s.acceptDeadlineChanged)
+	s.acceptDeadlineChanged = make(chan struct{})
+	s.acceptDeadlineMutex.Unlock()
 }
 
 // OpenStream opens a stream
@@ -1681,13 +2286,34 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 			}
 		}
 	}
+	var onSendWindowUpdate func(protocol.StreamID, protocol.ByteCount)
+	if s.config.OnStreamFlowControlUpdate != nil {
+		onSendWindowUpdate = func(id protocol.StreamID, offset protocol.ByteCount) {
+			s.config.OnStreamFlowControlUpdate(id, uint64(offset))
+		}
+	}
+	receiveWindow := protocol.ByteCount(protocol.InitialMaxStreamData)
+	// Streams opened by us are send-only for unidirectional streams.
+	hasReceiveSide := id.Type() == protocol.StreamTypeBidi || id.InitiatedBy() != s.perspective
+	if s.config.StreamReceiveWindowFunc != nil && hasReceiveSide {
+		if w := protocol.ByteCount(s.config.StreamReceiveWindowFunc(id)); w > receiveWindow {
+			// The stream can never use more than the connection-level flow control window.
+			receiveWindow = utils.MinByteCount(w, protocol.ByteCount(s.config.MaxReceiveConnectionFlowControlWindow))
+		}
+	}
+	maxReceiveWindow := protocol.ByteCount(s.config.MaxReceiveStreamFlowControlWindow)
+	if s.config.DisableFlowControlAutoTuning {
+		maxReceiveWindow = receiveWindow
+	}
 	return flowcontrol.NewStreamFlowController(
 		id,
 		s.connFlowController,
 		protocol.InitialMaxStreamData,
-		protocol.ByteCount(s.config.MaxReceiveStreamFlowControlWindow),
+		receiveWindow,
+		maxReceiveWindow,
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
+		onSendWindowUpdate,
 		s.rttStats,
 		s.logger,
 	)
@@ -1743,7 +2369,13 @@ func (s *session) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *session) setStreamPriority(id protocol.StreamID, weight uint8) {
+	s.framer.SetStreamPriority(id, weight)
+}
+
 func (s *session) onStreamCompleted(id protocol.StreamID) {
+	// the framer doesn't need to remember the priority of completed streams
+	s.framer.SetStreamPriority(id, 0)
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
 	}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
@@ -87,12 +88,14 @@ var _ = Describe("Session", func() {
 		mconn = NewMockSendConn(mockCtrl)
 		mconn.EXPECT().RemoteAddr().Return(remoteAddr).AnyTimes()
 		mconn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
-		tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
+		tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader, utils.DefaultClock{})
 		Expect(err).ToNot(HaveOccurred())
 		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+		// The session didn't receive any bytes from the client, so it's blocked by the amplification limit.
+		tracer.EXPECT().BlockedByAmplificationLimit().AnyTimes()
 		sess = newSession(
 			mconn,
 			sessionRunner,
@@ -163,10 +166,192 @@ var _ = Describe("Session", func() {
 				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
 				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 				sph.EXPECT().ReceivedAck(f, protocol.EncryptionHandshake, gomock.Any())
+				sph.EXPECT().BandwidthEstimate()
 				sess.sentPacketHandler = sph
 				err := sess.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("updates the RTT estimates returned by ConnectionState", func() {
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any()).Do(func(*wire.AckFrame, protocol.EncryptionLevel, time.Time) {
+					sess.rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+					sess.rttStats.UpdateRTT(50*time.Millisecond, 0, time.Now())
+				})
+				sph.EXPECT().BandwidthEstimate().Return(congestion.Bandwidth(1000) * congestion.BytesPerSecond)
+				sess.sentPacketHandler = sph
+				cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).Times(2)
+				cryptoSetup.EXPECT().KeyExchangeGroup().Times(2)
+				cs := sess.ConnectionState()
+				Expect(cs.SmoothedRTT).To(BeZero())
+				Expect(cs.MinRTT).To(BeZero())
+				Expect(cs.BandwidthEstimate).To(BeZero())
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(protocol.PacketNumber(3))
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				cs = sess.ConnectionState()
+				Expect(cs.SmoothedRTT).To(Equal(sess.rttStats.SmoothedRTT()))
+				Expect(cs.RTTVar).To(Equal(sess.rttStats.MeanDeviation()))
+				Expect(cs.RTTVar).ToNot(BeZero())
+				Expect(cs.MinRTT).To(Equal(50 * time.Millisecond))
+				Expect(cs.BandwidthEstimate).To(BeEquivalentTo(1000))
+			})
+
+			It("updates the ECN counts returned by ConnectionState", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				sph.EXPECT().BandwidthEstimate().Times(3)
+				sess.sentPacketHandler = sph
+				cryptoSetup.EXPECT().SetLargest1RTTAcked(gomock.Any()).Times(2)
+				cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).AnyTimes()
+				cryptoSetup.EXPECT().KeyExchangeGroup().AnyTimes()
+				f := &wire.AckFrame{
+					AckRanges: []wire.AckRange{{Smallest: 2, Largest: 10}},
+					ECT0:      7,
+					ECT1:      1,
+					ECNCE:     2,
+				}
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				cs := sess.ConnectionState()
+				Expect(cs.ECT0).To(BeEquivalentTo(7))
+				Expect(cs.ECT1).To(BeEquivalentTo(1))
+				Expect(cs.ECNCE).To(BeEquivalentTo(2))
+				// a reordered ACK
+				f = &wire.AckFrame{
+					AckRanges: []wire.AckRange{{Smallest: 2, Largest: 8}},
+					ECT0:      5,
+					ECT1:      1,
+					ECNCE:     1,
+				}
+				Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				Expect(sess.ConnectionState().ECT0).To(BeEquivalentTo(7))
+				Expect(sess.ConnectionState().ECNCE).To(BeEquivalentTo(2))
+				// ACKs for Handshake packets are ignored
+				f = &wire.AckFrame{
+					AckRanges: []wire.AckRange{{Smallest: 0, Largest: 1}},
+					ECT0:      100,
+				}
+				Expect(sess.handleAckFrame(f, protocol.EncryptionHandshake)).To(Succeed())
+				Expect(sess.ConnectionState().ECT0).To(BeEquivalentTo(7))
+			})
+		})
+
+		Context("handling ACK_FREQUENCY and IMMEDIATE_ACK frames", func() {
+			It("passes ACK_FREQUENCY frames to the ReceivedPacketHandler", func() {
+				sess.config.EnableACKFrequency = true
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				f := &wire.AckFrequencyFrame{SequenceNumber: 1, PacketTolerance: 10, UpdateMaxAckDelay: 10 * time.Millisecond}
+				rph.EXPECT().SetAckFrequency(f)
+				Expect(sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("errors if the requested max_ack_delay is smaller than the min_ack_delay", func() {
+				sess.config.EnableACKFrequency = true
+				f := &wire.AckFrequencyFrame{SequenceNumber: 1, PacketTolerance: 10, UpdateMaxAckDelay: protocol.MinAckDelay - 1}
+				err := sess.handleFrame(f, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			})
+
+			It("passes IMMEDIATE_ACK frames to the ReceivedPacketHandler", func() {
+				sess.config.EnableACKFrequency = true
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.receivedPacketHandler = rph
+				rph.EXPECT().QueueImmediateAck()
+				Expect(sess.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("rejects the frames if the extension is not enabled", func() {
+				err := sess.handleFrame(&wire.AckFrequencyFrame{PacketTolerance: 1}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION: received an ACK_FREQUENCY frame, although the ACK frequency extension was not negotiated"))
+				err = sess.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION: received an IMMEDIATE_ACK frame, although the ACK frequency extension was not negotiated"))
+			})
+
+			Context("sending ACK_FREQUENCY frames", func() {
+				var sph *mockackhandler.MockSentPacketHandler
+
+				getAckFrequencyFrames := func() []*wire.AckFrequencyFrame {
+					var afs []*wire.AckFrequencyFrame
+					frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+					for _, f := range frames {
+						if af, ok := f.Frame.(*wire.AckFrequencyFrame); ok {
+							afs = append(afs, af)
+						}
+					}
+					return afs
+				}
+
+				receiveAck := func(rtt time.Duration) {
+					sph.EXPECT().ReceivedAck(gomock.Any(), protocol.Encryption1RTT, gomock.Any()).Do(func(*wire.AckFrame, protocol.EncryptionLevel, time.Time) {
+						*sess.rttStats = utils.RTTStats{}
+						sess.rttStats.UpdateRTT(rtt, 0, time.Now())
+					})
+					sph.EXPECT().BandwidthEstimate()
+					cryptoSetup.EXPECT().SetLargest1RTTAcked(gomock.Any())
+					f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+					Expect(sess.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				}
+
+				BeforeEach(func() {
+					sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+					sess.sentPacketHandler = sph
+					sess.config.EnableACKFrequency = true
+					sess.handshakeConfirmed = true
+					minAckDelay := time.Millisecond
+					sess.peerParams = &wire.TransportParameters{
+						MaxAckDelay: 5 * time.Millisecond,
+						MinAckDelay: &minAckDelay,
+					}
+				})
+
+				It("sends an ACK_FREQUENCY frame based on the RTT", func() {
+					receiveAck(40 * time.Millisecond)
+					Expect(getAckFrequencyFrames()).To(Equal([]*wire.AckFrequencyFrame{{
+						SequenceNumber:    0,
+						PacketTolerance:   protocol.AckFrequencyPacketTolerance,
+						UpdateMaxAckDelay: 10 * time.Millisecond,
+					}}))
+					Expect(sess.rttStats.MaxAckDelay()).To(Equal(10 * time.Millisecond))
+				})
+
+				It("limits the requested max_ack_delay", func() {
+					receiveAck(time.Second)
+					afs := getAckFrequencyFrames()
+					Expect(afs).To(HaveLen(1))
+					Expect(afs[0].UpdateMaxAckDelay).To(Equal(protocol.MaxAckDelay))
+					receiveAck(time.Millisecond)
+					afs = getAckFrequencyFrames()
+					Expect(afs).To(HaveLen(1))
+					Expect(afs[0].SequenceNumber).To(BeEquivalentTo(1))
+					Expect(afs[0].UpdateMaxAckDelay).To(Equal(time.Millisecond))
+				})
+
+				It("only sends a new ACK_FREQUENCY frame when the RTT changes significantly", func() {
+					receiveAck(40 * time.Millisecond)
+					Expect(getAckFrequencyFrames()).To(HaveLen(1))
+					receiveAck(44 * time.Millisecond)
+					Expect(getAckFrequencyFrames()).To(BeEmpty())
+					receiveAck(60 * time.Millisecond)
+					afs := getAckFrequencyFrames()
+					Expect(afs).To(HaveLen(1))
+					Expect(afs[0].SequenceNumber).To(BeEquivalentTo(1))
+					Expect(afs[0].UpdateMaxAckDelay).To(Equal(15 * time.Millisecond))
+				})
+
+				It("doesn't send ACK_FREQUENCY frames if the peer doesn't support the extension", func() {
+					sess.peerParams.MinAckDelay = nil
+					receiveAck(40 * time.Millisecond)
+					Expect(getAckFrequencyFrames()).To(BeEmpty())
+				})
+
+				It("doesn't send ACK_FREQUENCY frames before the handshake is confirmed", func() {
+					sess.handshakeConfirmed = false
+					receiveAck(40 * time.Millisecond)
+					Expect(getAckFrequencyFrames()).To(BeEmpty())
+				})
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -178,6 +363,7 @@ var _ = Describe("Session", func() {
 				}
 				str := NewMockReceiveStreamI(mockCtrl)
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(555)).Return(str, nil)
+				tracer.EXPECT().ReceivedResetStream(protocol.StreamID(555), protocol.ApplicationErrorCode(42), protocol.ByteCount(0x1337))
 				str.EXPECT().handleResetStreamFrame(f)
 				err := sess.handleResetStreamFrame(f)
 				Expect(err).ToNot(HaveOccurred())
@@ -191,6 +377,7 @@ var _ = Describe("Session", func() {
 				testErr := errors.New("flow control violation")
 				str := NewMockReceiveStreamI(mockCtrl)
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(7)).Return(str, nil)
+				tracer.EXPECT().ReceivedResetStream(protocol.StreamID(7), protocol.ApplicationErrorCode(0), protocol.ByteCount(0x1337))
 				str.EXPECT().handleResetStreamFrame(f).Return(testErr)
 				err := sess.handleResetStreamFrame(f)
 				Expect(err).To(MatchError(testErr))
@@ -198,6 +385,7 @@ var _ = Describe("Session", func() {
 
 			It("ignores RESET_STREAM frames for closed streams", func() {
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(3)).Return(nil, nil)
+				tracer.EXPECT().ReceivedResetStream(protocol.StreamID(3), protocol.ApplicationErrorCode(42), protocol.ByteCount(0))
 				Expect(sess.handleFrame(&wire.ResetStreamFrame{
 					StreamID:  3,
 					ErrorCode: 42,
@@ -268,6 +456,7 @@ var _ = Describe("Session", func() {
 				}
 				str := NewMockSendStreamI(mockCtrl)
 				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(5)).Return(str, nil)
+				tracer.EXPECT().ReceivedStopSending(protocol.StreamID(5), protocol.ApplicationErrorCode(10))
 				str.EXPECT().handleStopSendingFrame(f)
 				err := sess.handleStopSendingFrame(f)
 				Expect(err).ToNot(HaveOccurred())
@@ -275,6 +464,7 @@ var _ = Describe("Session", func() {
 
 			It("ignores STOP_SENDING frames for a closed stream", func() {
 				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(3)).Return(nil, nil)
+				tracer.EXPECT().ReceivedStopSending(protocol.StreamID(3), protocol.ApplicationErrorCode(1337))
 				Expect(sess.handleFrame(&wire.StopSendingFrame{
 					StreamID:  3,
 					ErrorCode: 1337,
@@ -283,6 +473,7 @@ var _ = Describe("Session", func() {
 		})
 
 		It("handles NEW_CONNECTION_ID frames", func() {
+			tracer.EXPECT().NewConnectionIDReceived(uint64(10), protocol.ConnectionID{1, 2, 3, 4})
 			Expect(sess.handleFrame(&wire.NewConnectionIDFrame{
 				SequenceNumber: 10,
 				ConnectionID:   protocol.ConnectionID{1, 2, 3, 4},
@@ -295,9 +486,9 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("rejects PATH_RESPONSE frames", func() {
+		It("ignores PATH_RESPONSE frames that don't match a PATH_CHALLENGE", func() {
 			err := sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.EncryptionUnspecified, protocol.ConnectionID{})
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+			Expect(err).ToNot(HaveOccurred())
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
@@ -331,7 +522,8 @@ var _ = Describe("Session", func() {
 		})
 
 		It("handles CONNECTION_CLOSE frames, with a transport error code", func() {
-			testErr := qerr.NewError(qerr.StreamLimitError, "foobar")
+			testErr := qerr.NewErrorWithFrameType(qerr.StreamLimitError, 0x42, "foobar")
+			testErr.Remote = true
 			streamManager.EXPECT().CloseWithError(testErr)
 			sessionRunner.EXPECT().ReplaceWithClosed(srcConnID, gomock.Any()).Do(func(_ protocol.ConnectionID, s packetHandler) {
 				Expect(s).To(BeAssignableToTypeOf(&closedRemoteSession{}))
@@ -353,17 +545,27 @@ var _ = Describe("Session", func() {
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
-				Expect(sess.run()).To(MatchError(testErr))
+				err := sess.run()
+				Expect(err).To(MatchError(testErr))
+				var transportErr *TransportError
+				Expect(errors.As(err, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(Equal(qerr.StreamLimitError))
+				Expect(transportErr.FrameType).To(BeEquivalentTo(0x42))
+				Expect(transportErr.ErrorMessage).To(Equal("foobar"))
+				Expect(transportErr.Remote).To(BeTrue())
+				Expect(transportErr.IsApplicationError()).To(BeFalse())
 			}()
-			Expect(sess.handleFrame(&wire.ConnectionCloseFrame{
-				ErrorCode:    qerr.StreamLimitError,
-				ReasonPhrase: "foobar",
-			}, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
+			// CONNECTION_CLOSE frame of type 0x1c: error code, frame type, reason phrase
+			data := append([]byte{0x1c, byte(qerr.StreamLimitError), 0x40, 0x42, 6}, []byte("foobar")...)
+			frame, err := wire.NewFrameParser(sess.version).ParseNext(bytes.NewReader(data), protocol.Encryption1RTT)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.handleFrame(frame, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
 		It("handles CONNECTION_CLOSE frames, with an application error code", func() {
 			testErr := qerr.NewApplicationError(0x1337, "foobar")
+			testErr.Remote = true
 			streamManager.EXPECT().CloseWithError(testErr)
 			sessionRunner.EXPECT().ReplaceWithClosed(srcConnID, gomock.Any()).Do(func(_ protocol.ConnectionID, s packetHandler) {
 				Expect(s).To(BeAssignableToTypeOf(&closedRemoteSession{}))
@@ -385,14 +587,20 @@ var _ = Describe("Session", func() {
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
-				Expect(sess.run()).To(MatchError(testErr))
+				err := sess.run()
+				Expect(err).To(MatchError(testErr))
+				var transportErr *TransportError
+				Expect(errors.As(err, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(BeEquivalentTo(0x1337))
+				Expect(transportErr.ErrorMessage).To(Equal("foobar"))
+				Expect(transportErr.Remote).To(BeTrue())
+				Expect(transportErr.IsApplicationError()).To(BeTrue())
 			}()
-			ccf := &wire.ConnectionCloseFrame{
-				ErrorCode:          0x1337,
-				ReasonPhrase:       "foobar",
-				IsApplicationError: true,
-			}
-			Expect(sess.handleFrame(ccf, protocol.EncryptionUnspecified, protocol.ConnectionID{})).To(Succeed())
+			// CONNECTION_CLOSE frame of type 0x1d: error code, reason phrase
+			data := append([]byte{0x1d, 0x53, 0x37, 6}, []byte("foobar")...)
+			frame, err := wire.NewFrameParser(sess.version).ParseNext(bytes.NewReader(data), protocol.Encryption1RTT)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.handleFrame(frame, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
@@ -406,6 +614,11 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("doesn't wait for the CONNECTION_CLOSE to be written when closing synchronously, if the session isn't running", func() {
+		Expect(sess.CloseWithErrorSync(context.Background(), 0x1337, "test error")).To(Succeed())
+		Expect(sess.closeChan).To(Receive())
+	})
+
 	Context("closing", func() {
 		var (
 			runErr         chan error
@@ -442,12 +655,13 @@ var _ = Describe("Session", func() {
 			cryptoSetup.EXPECT().Close()
 			buffer := getPacketBuffer()
 			buffer.Data = append(buffer.Data, []byte("connection close")...)
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
 				Expect(quicErr.ErrorCode).To(BeEquivalentTo(qerr.NoError))
 				Expect(quicErr.ErrorMessage).To(BeEmpty())
 				return &coalescedPacket{buffer: buffer}, nil
 			})
-			mconn.EXPECT().Write([]byte("connection close"))
+			mconn.EXPECT().Write([]byte("connection close"), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(reason logging.CloseReason) {
 					errorCode, remote, ok := reason.ApplicationError()
@@ -467,8 +681,9 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -482,13 +697,14 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(qerr.NewApplicationError(0x1337, "test error"))
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
 				Expect(quicErr.IsApplicationError()).To(BeTrue())
 				Expect(quicErr.ErrorCode).To(BeEquivalentTo(0x1337))
 				Expect(quicErr.ErrorMessage).To(Equal("test error"))
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
 			})
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(reason logging.CloseReason) {
 					errorCode, remote, ok := reason.ApplicationError()
@@ -503,12 +719,98 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("closes with a transport error", func() {
+			runSession()
+			streamManager.EXPECT().CloseWithError(qerr.NewErrorWithFrameType(qerr.ProtocolViolation, 0x42, "test error"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
+				Expect(quicErr.IsApplicationError()).To(BeFalse())
+				Expect(quicErr.ErrorCode).To(Equal(qerr.ProtocolViolation))
+				Expect(quicErr.FrameType).To(BeEquivalentTo(0x42))
+				Expect(quicErr.ErrorMessage).To(Equal("test error"))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(reason logging.CloseReason) {
+					errorCode, remote, ok := reason.TransportError()
+					Expect(ok).To(BeTrue())
+					Expect(remote).To(BeFalse())
+					Expect(errorCode).To(Equal(logging.TransportError(qerr.ProtocolViolation)))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			sess.CloseWithTransportError(qerr.ProtocolViolation, 0x42, "test error")
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
+		It("closes synchronously, waiting for the CONNECTION_CLOSE to be written", func() {
+			runSession()
+			streamManager.EXPECT().CloseWithError(qerr.NewApplicationError(0x1337, "test error"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			var written bool
+			testErr := errors.New("write error")
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).DoAndReturn(func([]byte, protocol.ECN) error {
+				time.Sleep(scaleDuration(10 * time.Millisecond))
+				written = true
+				return testErr
+			})
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			Expect(sess.CloseWithErrorSync(context.Background(), 0x1337, "test error")).To(MatchError(testErr))
+			Expect(written).To(BeTrue())
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
+		It("only uses the first error when closing synchronously multiple times", func() {
+			runSession()
+			streamManager.EXPECT().CloseWithError(qerr.NewApplicationError(0x1337, "first"))
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			Expect(sess.CloseWithErrorSync(context.Background(), 0x1337, "first")).To(Succeed())
+			Expect(sess.CloseWithErrorSync(context.Background(), 0x42, "second")).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
+		It("stops waiting for the CONNECTION_CLOSE to be written when the context is cancelled", func() {
+			runSession()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			unblock := make(chan struct{})
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).DoAndReturn(func([]byte, protocol.ECN) error {
+				<-unblock
+				return nil
+			})
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+			defer cancel()
+			Expect(sess.CloseWithErrorSync(ctx, 0x1337, "test error")).To(MatchError(context.DeadlineExceeded))
+			close(unblock)
+			Eventually(areSessionsRunning).Should(BeFalse())
+		})
+
 		It("includes the frame type in transport-level close frames", func() {
 			runSession()
 			testErr := qerr.NewErrorWithFrameType(0x1337, 0x42, "test error")
 			streamManager.EXPECT().CloseWithError(testErr)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
 				Expect(quicErr.IsApplicationError()).To(BeFalse())
 				Expect(quicErr.FrameType).To(BeEquivalentTo(0x42))
@@ -516,7 +818,7 @@ var _ = Describe("Session", func() {
 				Expect(quicErr.ErrorMessage).To(Equal("test error"))
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
 			})
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			gomock.InOrder(
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(reason logging.CloseReason) {
 					errorCode, remote, ok := reason.TransportError()
@@ -557,6 +859,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			returned := make(chan struct{})
 			go func() {
@@ -567,7 +870,7 @@ var _ = Describe("Session", func() {
 				close(returned)
 			}()
 			Consistently(returned).ShouldNot(BeClosed())
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -599,7 +902,7 @@ var _ = Describe("Session", func() {
 			})
 			gomock.InOrder(
 				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
-				tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any()),
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
 				tracer.EXPECT().ClosedConnection(gomock.Any()),
 				tracer.EXPECT().Close(),
 			)
@@ -617,16 +920,18 @@ var _ = Describe("Session", func() {
 		It("closes when the sendQueue encounters an error", func() {
 			sess.handshakeConfirmed = true
 			conn := NewMockSendConn(mockCtrl)
-			conn.EXPECT().Write(gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
+			conn.EXPECT().Write(gomock.Any(), gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
 			sess.sendQueue = newSendQueue(conn)
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().AmplificationWindow().Return(protocol.MaxByteCount).AnyTimes()
 			// only expect a single SentPacket() call
 			sph.EXPECT().SentPacket(gomock.Any())
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -700,6 +1005,58 @@ var _ = Describe("Session", func() {
 			})).To(BeFalse())
 		})
 
+		It("drops datagrams larger than the max_udp_payload_size we advertised", func() {
+			sess.config.MaxUDPPayloadSize = 1200
+			p := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, make([]byte, 1200))
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropProtocolViolation)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
+		It("drops packets rejected by the IncomingPacketInterceptor", func() {
+			var intercepted []byte
+			sess.config.IncomingPacketInterceptor = func(data []byte, _ net.Addr) (bool, []byte) {
+				intercepted = data
+				return false, nil
+			}
+			p := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			Expect(intercepted).To(Equal(p.data))
+		})
+
+		It("processes packets rewritten by the IncomingPacketInterceptor", func() {
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			rewritten := getPacket(hdr, []byte("foobar")).data
+			sess.config.IncomingPacketInterceptor = func([]byte, net.Addr) (bool, []byte) { return true, rewritten }
+			packet := getPacket(hdr, nil)
+			rcvTime := time.Now().Add(-10 * time.Second)
+			packet.rcvTime = rcvTime
+			unpacker.EXPECT().Unpack(gomock.Any(), rcvTime, rewritten).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.Encryption1RTT, rcvTime, false)
+			sess.receivedPacketHandler = rph
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(rewritten)), gomock.Any(), []logging.Frame{})
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+		})
+
 		It("drops packets for which header decryption fails", func() {
 			p := getPacket(&wire.ExtendedHeader{
 				Header: wire.Header{
@@ -749,6 +1106,37 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(p)).To(BeFalse())
 		})
 
+		It("drops short header packets with the QUIC bit unset, if greasing the QUIC bit is disabled", func() {
+			p := getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+				ClearQUICBit:    true,
+			}, nil)
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropHeaderParseError)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
+		It("accepts short header packets with the QUIC bit unset, if greasing the QUIC bit is enabled", func() {
+			sess.config.GREASEQUICBit = true
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+				ClearQUICBit:    true,
+			}
+			packet := getPacket(hdr, nil)
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), gomock.Any(), []logging.Frame{})
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+		})
+
 		It("informs the ReceivedPacketHandler about non-ack-eliciting packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -766,15 +1154,72 @@ var _ = Describe("Session", func() {
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 			gomock.InOrder(
 				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.EncryptionInitial),
-				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.EncryptionInitial, rcvTime, false),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.EncryptionInitial, rcvTime, false),
 			)
 			sess.receivedPacketHandler = rph
 			packet.rcvTime = rcvTime
 			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), []logging.Frame{})
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), gomock.Any(), []logging.Frame{})
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 		})
 
+		It("timestamps received packets using the clock from the config", func() {
+			clock := utils.NewManualClock(time.Now().Add(-time.Hour))
+			conf := populateServerConfig(&Config{})
+			conf.clock = clock
+			tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader, clock)
+			Expect(err).ToNot(HaveOccurred())
+			tracer.EXPECT().SentTransportParameters(gomock.Any())
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any())
+			sess = newSession(
+				mconn,
+				sessionRunner,
+				nil,
+				nil,
+				clientDestConnID,
+				destConnID,
+				srcConnID,
+				protocol.StatelessResetToken{},
+				conf,
+				nil, // tls.Config
+				tokenGenerator,
+				false,
+				tracer,
+				utils.DefaultLogger,
+				protocol.VersionTLS,
+			).(*session)
+			sess.unpacker = unpacker
+			sess.handshakeComplete = true
+			clock.Advance(time.Minute)
+			rcvTime := clock.Now()
+
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), rcvTime, gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			gomock.InOrder(
+				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.Encryption1RTT, rcvTime, false),
+			)
+			sess.receivedPacketHandler = rph
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(hdr, gomock.Any(), gomock.Any(), []logging.Frame{})
+			sess.handlePacket(getPacket(hdr, nil))
+			var p *receivedPacket
+			Expect(sess.receivedPackets).To(Receive(&p))
+			Expect(p.rcvTime).To(Equal(rcvTime))
+			Expect(sess.handlePacketImpl(p)).To(BeTrue())
+			Expect(sess.lastPacketReceivedTime).To(Equal(rcvTime))
+		})
+
 		It("informs the ReceivedPacketHandler about ack-eliciting packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
@@ -794,13 +1239,19 @@ var _ = Describe("Session", func() {
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 			gomock.InOrder(
 				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT),
-				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, true),
+				rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNCE, protocol.Encryption1RTT, rcvTime, true),
 			)
 			sess.receivedPacketHandler = rph
 			packet.rcvTime = rcvTime
+			// the receive timestamp is only passed to the tracer
+			packet.timestamp = rcvTime.Add(-time.Millisecond)
+			packet.ecn = protocol.ECNCE
 			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), []logging.Frame{&logging.PingFrame{}})
+			tracer.EXPECT().ReceivedPacket(hdr, protocol.ByteCount(len(packet.data)), rcvTime.Add(-time.Millisecond), []logging.Frame{&logging.PingFrame{}})
+			size := len(packet.data)
 			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			Expect(sess.stats.get().PacketsReceived).To(BeEquivalentTo(1))
+			Expect(sess.stats.get().BytesReceived).To(BeEquivalentTo(size))
 		})
 
 		It("drops duplicate packets", func() {
@@ -827,6 +1278,7 @@ var _ = Describe("Session", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			go func() {
 				defer GinkgoRecover()
@@ -851,7 +1303,7 @@ var _ = Describe("Session", func() {
 			// make the go routine return
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.closeLocal(errors.New("close"))
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -860,6 +1312,7 @@ var _ = Describe("Session", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, wire.ErrInvalidReservedBits)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			done := make(chan struct{})
 			go func() {
@@ -871,7 +1324,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			packet := getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
 				PacketNumberLen: protocol.PacketNumberLen1,
@@ -901,10 +1354,11 @@ var _ = Describe("Session", func() {
 			}, nil))
 			Consistently(runErr).ShouldNot(Receive())
 			// make the go routine return
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -913,6 +1367,7 @@ var _ = Describe("Session", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, qerr.ConnectionIDLimitError)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			done := make(chan struct{})
 			go func() {
@@ -924,7 +1379,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			packet := getPacket(&wire.ExtendedHeader{
 				Header:          wire.Header{DestConnectionID: srcConnID},
 				PacketNumberLen: protocol.PacketNumberLen1,
@@ -943,6 +1398,7 @@ var _ = Describe("Session", func() {
 			}, nil)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			cryptoSetup.EXPECT().Close()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			done := make(chan struct{})
 			go func() {
@@ -952,7 +1408,7 @@ var _ = Describe("Session", func() {
 				close(done)
 			}()
 			expectReplaceWithClosed()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.handlePacket(getPacket(&wire.ExtendedHeader{
@@ -997,7 +1453,7 @@ var _ = Describe("Session", func() {
 			}, nil)
 			p1 := getPacket(hdr1, nil)
 			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(p1.data)), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(p1.data)), gomock.Any(), gomock.Any())
 			Expect(sess.handlePacketImpl(p1)).To(BeTrue())
 			// The next packet has to be ignored, since the source connection ID doesn't match.
 			p2 := getPacket(hdr2, nil)
@@ -1039,7 +1495,7 @@ var _ = Describe("Session", func() {
 				}, nil)
 				packet.remoteAddr = &net.IPAddr{IP: net.IPv4(192, 168, 0, 100)}
 				tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any())
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any(), gomock.Any())
 				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 			})
 		})
@@ -1077,7 +1533,7 @@ var _ = Describe("Session", func() {
 						hdr:             &wire.ExtendedHeader{},
 					}, nil
 				})
-				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any())
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet.data)), gomock.Any(), gomock.Any())
 				Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 			})
 
@@ -1103,8 +1559,8 @@ var _ = Describe("Session", func() {
 					}, nil
 				})
 				gomock.InOrder(
-					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet1.data)), gomock.Any()),
-					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any()),
+					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet1.data)), gomock.Any(), gomock.Any()),
+					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any(), gomock.Any()),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
 				Expect(sess.handlePacketImpl(packet1)).To(BeTrue())
@@ -1127,7 +1583,7 @@ var _ = Describe("Session", func() {
 				)
 				gomock.InOrder(
 					tracer.EXPECT().BufferedPacket(gomock.Any()),
-					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any()),
+					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), gomock.Any(), gomock.Any()),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
 				Expect(sess.handlePacketImpl(packet1)).To(BeTrue())
@@ -1151,7 +1607,7 @@ var _ = Describe("Session", func() {
 				_, packet2 := getPacketWithLength(wrongConnID, 123)
 				// don't EXPECT any more calls to unpacker.Unpack()
 				gomock.InOrder(
-					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet1.data)), gomock.Any()),
+					tracer.EXPECT().ReceivedPacket(gomock.Any(), protocol.ByteCount(len(packet1.data)), gomock.Any(), gomock.Any()),
 					tracer.EXPECT().DroppedPacket(gomock.Any(), protocol.ByteCount(len(packet2.data)), logging.PacketDropUnknownConnectionID),
 				)
 				packet1.data = append(packet1.data, packet2.data...)
@@ -1169,10 +1625,11 @@ var _ = Describe("Session", func() {
 
 		AfterEach(func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -1195,6 +1652,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1203,17 +1661,67 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().Return(p, nil)
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
 			sent := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
-			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{})
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{}, gomock.Any())
+			tracer.EXPECT().SentDatagram(p.buffer.Len(), []*wire.ExtendedHeader{p.header})
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+			sph.EXPECT().PacketsLost().Return(uint64(2), protocol.ByteCount(2000))
+			stats := sess.Stats()
+			Expect(stats.PacketsSent).To(BeEquivalentTo(1))
+			Expect(stats.BytesSent).To(BeEquivalentTo(p.buffer.Len()))
+			Expect(stats.PacketsLost).To(BeEquivalentTo(2))
+			Expect(stats.BytesLost).To(BeEquivalentTo(2000))
+			Expect(stats.ProbePacketsSent).To(BeZero())
+		})
+
+		It("marks 1-RTT packets with ECN, if enabled", func() {
+			sess.config.EnableECN = true
+			sess.handshakeConfirmed = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().ECNMode().Return(protocol.ECT0)
+			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
+				Expect(p.ECN).To(Equal(protocol.ECT0))
+			})
+			sess.sentPacketHandler = sph
+			runSession()
+			p := getPacket(1)
+			packer.EXPECT().PackPacket().Return(p, nil)
+			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
+			sent := make(chan struct{})
+			mconn.EXPECT().Write(gomock.Any(), protocol.ECT0).Do(func([]byte, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.buffer.Len(), nil, []logging.Frame{}, gomock.Any())
+			tracer.EXPECT().SentDatagram(p.buffer.Len(), []*wire.ExtendedHeader{p.header})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("tells the sent packet handler when it runs out of data to send", func() {
+			sess.handshakeConfirmed = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			appLimited := make(chan struct{})
+			sph.EXPECT().OnAppLimited().Do(func() { close(appLimited) })
+			sess.sentPacketHandler = sph
+			packer.EXPECT().PackPacket().Return(nil, nil)
+			runSession()
+			sess.scheduleSending()
+			Eventually(appLimited).Should(BeClosed())
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
 			sess.handshakeConfirmed = true
 			runSession()
 			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
-			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)
 			sess.scheduleSending()
 			time.Sleep(50 * time.Millisecond) // make sure there are no calls to mconn.Write()
 		})
@@ -1237,6 +1745,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1249,8 +1758,9 @@ var _ = Describe("Session", func() {
 			sess.connFlowController = fc
 			runSession()
 			sent := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
-			tracer.EXPECT().SentPacket(p.header, p.length, nil, []logging.Frame{})
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentPacket(p.header, p.length, nil, []logging.Frame{}, gomock.Any())
+			tracer.EXPECT().SentDatagram(p.buffer.Len(), []*wire.ExtendedHeader{p.header})
 			sess.scheduleSending()
 			Eventually(sent).Should(BeClosed())
 			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
@@ -1304,10 +1814,12 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
-					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+					mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
+					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentDatagram(p.buffer.Len(), []*wire.ExtendedHeader{p.header})
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
+					Expect(sess.stats.get().ProbePacketsSent).To(BeEquivalentTo(1))
 				})
 
 				It("sends a PING as a probe packet", func() {
@@ -1325,8 +1837,9 @@ var _ = Describe("Session", func() {
 					sess.sentPacketHandler = sph
 					runSession()
 					sent := make(chan struct{})
-					mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(sent) })
-					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+					mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
+					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentDatagram(p.buffer.Len(), []*wire.ExtendedHeader{p.header})
 					sess.scheduleSending()
 					Eventually(sent).Should(BeClosed())
 					// We're using a mock packet packer in this test.
@@ -1341,7 +1854,8 @@ var _ = Describe("Session", func() {
 		var sph *mockackhandler.MockSentPacketHandler
 
 		BeforeEach(func() {
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any()).AnyTimes()
 			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sess.handshakeConfirmed = true
@@ -1355,7 +1869,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -1367,10 +1881,12 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().HasPacingBudget().Return(true).Times(2)
 			sph.EXPECT().HasPacingBudget()
 			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			tracer.EXPECT().SetPacingTimer(gomock.Any())
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(3)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
-			mconn.EXPECT().Write(gomock.Any()).Times(2)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Times(2)
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1386,9 +1902,10 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().SentPacket(gomock.Any())
 			sph.EXPECT().HasPacingBudget().Return(true)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAck)
 			packer.EXPECT().PackPacket().Return(getPacket(100), nil)
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1400,21 +1917,25 @@ var _ = Describe("Session", func() {
 
 		It("paces packets", func() {
 			pacingDelay := scaleDuration(100 * time.Millisecond)
+			nextSendTime := time.Now().Add(pacingDelay)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			gomock.InOrder(
 				sph.EXPECT().HasPacingBudget().Return(true),
 				packer.EXPECT().PackPacket().Return(getPacket(100), nil),
 				sph.EXPECT().SentPacket(gomock.Any()),
 				sph.EXPECT().HasPacingBudget(),
-				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(pacingDelay)),
+				sph.EXPECT().TimeUntilSend().Return(nextSendTime),
+				tracer.EXPECT().SetPacingTimer(nextSendTime),
 				sph.EXPECT().HasPacingBudget().Return(true),
 				packer.EXPECT().PackPacket().Return(getPacket(101), nil),
 				sph.EXPECT().SentPacket(gomock.Any()),
 				sph.EXPECT().HasPacingBudget(),
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour)),
+				tracer.EXPECT().SetPacingTimer(gomock.Any()),
 			)
 			written := make(chan struct{}, 2)
-			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).DoAndReturn(func(p []byte, _ protocol.ECN) (int, error) {
 				written <- struct{}{}
 				return len(p), nil
 			}).Times(2)
@@ -1434,12 +1955,14 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().HasPacingBudget().Return(true).Times(3)
 			sph.EXPECT().HasPacingBudget()
 			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			tracer.EXPECT().SetPacingTimer(gomock.Any())
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(4)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			packer.EXPECT().PackPacket().Return(getPacket(1000), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(1001), nil)
 			packer.EXPECT().PackPacket().Return(getPacket(1002), nil)
 			written := make(chan struct{}, 3)
-			mconn.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).DoAndReturn(func(p []byte, _ protocol.ECN) (int, error) {
 				written <- struct{}{}
 				return len(p), nil
 			}).Times(3)
@@ -1455,6 +1978,7 @@ var _ = Describe("Session", func() {
 		It("doesn't set a pacing timer when there is no data to send", func() {
 			sph.EXPECT().HasPacingBudget().Return(true)
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			packer.EXPECT().PackPacket()
 			// don't EXPECT any calls to mconn.Write()
 			go func() {
@@ -1478,7 +2002,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -1490,6 +2014,7 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any())
 			sess.sentPacketHandler = sph
@@ -1505,8 +2030,9 @@ var _ = Describe("Session", func() {
 			time.Sleep(50 * time.Millisecond)
 			// only EXPECT calls after scheduleSending is called
 			written := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(written) })
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(written) })
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any()).AnyTimes()
 			sess.scheduleSending()
 			Eventually(written).Should(BeClosed())
 		})
@@ -1517,6 +2043,7 @@ var _ = Describe("Session", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
 				Expect(p.PacketNumber).To(Equal(protocol.PacketNumber(1234)))
@@ -1529,8 +2056,9 @@ var _ = Describe("Session", func() {
 			sess.receivedPacketHandler = rph
 
 			written := make(chan struct{})
-			mconn.EXPECT().Write(gomock.Any()).Do(func([]byte) { close(written) })
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any()).Do(func([]byte, protocol.ECN) { close(written) })
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any()).AnyTimes()
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
@@ -1578,6 +2106,7 @@ var _ = Describe("Session", func() {
 
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 		sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().OnAppLimited().AnyTimes()
 		sph.EXPECT().TimeUntilSend().Return(time.Now()).AnyTimes()
 		gomock.InOrder(
 			sph.EXPECT().SentPacket(gomock.Any()).Do(func(p *ackhandler.Packet) {
@@ -1592,16 +2121,21 @@ var _ = Describe("Session", func() {
 			}),
 		)
 		gomock.InOrder(
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *wire.ExtendedHeader, _ protocol.ByteCount, _ *wire.AckFrame, _ []logging.Frame) {
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *wire.ExtendedHeader, _ protocol.ByteCount, _ *wire.AckFrame, _ []logging.Frame, _ logging.PacketSendReason) {
 				Expect(hdr.Type).To(Equal(protocol.PacketTypeInitial))
 			}),
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *wire.ExtendedHeader, _ protocol.ByteCount, _ *wire.AckFrame, _ []logging.Frame) {
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *wire.ExtendedHeader, _ protocol.ByteCount, _ *wire.AckFrame, _ []logging.Frame, _ logging.PacketSendReason) {
 				Expect(hdr.Type).To(Equal(protocol.PacketTypeHandshake))
 			}),
+			tracer.EXPECT().SentDatagram(protocol.ByteCount(6), gomock.Any()).Do(func(_ protocol.ByteCount, hdrs []*wire.ExtendedHeader) {
+				Expect(hdrs).To(HaveLen(2))
+				Expect(hdrs[0].PacketNumber).To(Equal(protocol.PacketNumber(13)))
+				Expect(hdrs[1].PacketNumber).To(Equal(protocol.PacketNumber(37)))
+			}),
 		)
 
 		sent := make(chan struct{})
-		mconn.EXPECT().Write([]byte("foobar")).Do(func([]byte) { close(sent) })
+		mconn.EXPECT().Write([]byte("foobar"), gomock.Any()).Do(func([]byte, protocol.ECN) { close(sent) })
 
 		go func() {
 			defer GinkgoRecover()
@@ -1615,9 +2149,10 @@ var _ = Describe("Session", func() {
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -1649,15 +2184,62 @@ var _ = Describe("Session", func() {
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("reports if 0-RTT was accepted, once the handshake completes", func() {
+		accepted := make(chan bool)
+		go func() {
+			defer GinkgoRecover()
+			accepted <- sess.EarlyDataAccepted()
+		}()
+		Consistently(accepted).ShouldNot(Receive())
+		cryptoSetup.EXPECT().Used0RTT().Return(true)
+		sess.handshakeCtxCancel()
+		Eventually(accepted).Should(Receive(BeTrue()))
+	})
+
+	It("reports that 0-RTT wasn't accepted if the session is closed before the handshake completes", func() {
+		sess.ctxCancel()
+		Expect(sess.EarlyDataAccepted()).To(BeFalse())
+	})
+
+	It("returns the key exchange group in the ConnectionState", func() {
+		cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+		cryptoSetup.EXPECT().KeyExchangeGroup().Return(tls.CurveP256)
+		Expect(sess.ConnectionState().KeyExchangeGroup).To(Equal(tls.CurveP256))
+	})
+
+	It("cancels the HandshakeConfirmed context when the handshake is confirmed", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sess.sentPacketHandler = sph
+		rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+		sess.receivedPacketHandler = rph
+		cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).Times(2)
+		cryptoSetup.EXPECT().KeyExchangeGroup().Times(2)
+		Expect(sess.ConnectionState().HandshakeConfirmed).To(BeFalse())
+		// dropping the Initial keys doesn't confirm the handshake
+		sph.EXPECT().DropPackets(protocol.EncryptionInitial)
+		rph.EXPECT().DropPackets(protocol.EncryptionInitial)
+		tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionInitial)
+		sess.dropEncryptionLevel(protocol.EncryptionInitial)
+		Expect(sess.HandshakeConfirmed().Done()).ToNot(BeClosed())
+		sph.EXPECT().SetHandshakeConfirmed()
+		sph.EXPECT().DropPackets(protocol.EncryptionHandshake)
+		rph.EXPECT().DropPackets(protocol.EncryptionHandshake)
+		tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionHandshake)
+		sess.dropEncryptionLevel(protocol.EncryptionHandshake)
+		Expect(sess.HandshakeConfirmed().Done()).To(BeClosed())
+		Expect(sess.ConnectionState().HandshakeConfirmed).To(BeTrue())
+	})
+
 	It("sends a session ticket when the handshake completes", func() {
 		const size = protocol.MaxPostHandshakeCryptoFrameSize * 3 / 2
 		packer.EXPECT().PackCoalescedPacket(protocol.MaxByteCount).AnyTimes()
@@ -1694,9 +2276,10 @@ var _ = Describe("Session", func() {
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -1707,6 +2290,7 @@ var _ = Describe("Session", func() {
 		packer.EXPECT().PackCoalescedPacket(protocol.MaxByteCount).AnyTimes()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
 		tracer.EXPECT().ClosedConnection(gomock.Any())
@@ -1718,7 +2302,7 @@ var _ = Describe("Session", func() {
 		}()
 		handshakeCtx := sess.HandshakeComplete()
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		sess.closeLocal(errors.New("handshake error"))
 		Consistently(handshakeCtx.Done()).ShouldNot(BeClosed())
 		Eventually(sess.Context().Done()).Should(BeClosed())
@@ -1727,6 +2311,7 @@ var _ = Describe("Session", func() {
 	It("sends a HANDSHAKE_DONE frame when the handshake completes", func() {
 		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 		sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+		sph.EXPECT().OnAppLimited().AnyTimes()
 		sph.EXPECT().AmplificationWindow().Return(protocol.MaxByteCount)
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 		sph.EXPECT().TimeUntilSend().AnyTimes()
@@ -1752,7 +2337,7 @@ var _ = Describe("Session", func() {
 			cryptoSetup.EXPECT().RunHandshake()
 			cryptoSetup.EXPECT().DropHandshakeKeys()
 			cryptoSetup.EXPECT().GetSessionTicket()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			close(sess.handshakeCompleteChan)
 			sess.run()
 		}()
@@ -1760,6 +2345,7 @@ var _ = Describe("Session", func() {
 		// make sure the go routine returns
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
 		tracer.EXPECT().ClosedConnection(gomock.Any())
@@ -1768,6 +2354,25 @@ var _ = Describe("Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("uses the AddressTokenGenerator to generate the token for the NEW_TOKEN frame", func() {
+		tokenGen := NewMockAddressTokenGenerator(mockCtrl)
+		sess.config.AddressTokenGenerator = tokenGen
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().GetSessionTicket()
+		cryptoSetup.EXPECT().DropHandshakeKeys()
+		tokenGen.EXPECT().NewToken(sess.RemoteAddr()).Return([]byte("foobar"), nil)
+		sess.handleHandshakeComplete()
+		frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		var newTokenFrame *wire.NewTokenFrame
+		for _, f := range frames {
+			if ntf, ok := f.Frame.(*wire.NewTokenFrame); ok {
+				newTokenFrame = ntf
+			}
+		}
+		Expect(newTokenFrame).ToNot(BeNil())
+		Expect(newTokenFrame.Token).To(Equal([]byte("foobar")))
+	})
+
 	It("doesn't return a run error when closing", func() {
 		done := make(chan struct{})
 		go func() {
@@ -1778,9 +2383,10 @@ var _ = Describe("Session", func() {
 		}()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -1799,9 +2405,10 @@ var _ = Describe("Session", func() {
 		}()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
 		expectReplaceWithClosed()
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		Expect(sess.CloseWithError(0x1337, testErr.Error())).To(Succeed())
@@ -1815,7 +2422,7 @@ var _ = Describe("Session", func() {
 				InitialMaxStreamDataBidiLocal: 0x5000,
 				InitialMaxData:                0x5000,
 				ActiveConnectionIDLimit:       3,
-				// marshaling always sets it to this value
+				// marshaling sets it to this value, if not set
 				MaxUDPPayloadSize:         protocol.MaxReceivePacketSize,
 				InitialSourceConnectionID: destConnID,
 			}
@@ -1853,16 +2460,17 @@ var _ = Describe("Session", func() {
 		BeforeEach(func() {
 			sess.config.MaxIdleTimeout = 30 * time.Second
 			sess.config.KeepAlive = true
-			sess.receivedPacketHandler.ReceivedPacket(0, protocol.EncryptionHandshake, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0, protocol.ECNNon, protocol.EncryptionHandshake, time.Now(), true)
 		})
 
 		AfterEach(func() {
 			// make the go routine return
 			expectReplaceWithClosed()
 			streamManager.EXPECT().CloseWithError(gomock.Any())
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -1903,16 +2511,460 @@ var _ = Describe("Session", func() {
 			time.Sleep(50 * time.Millisecond)
 		})
 
-		It("doesn't send a PING if the handshake isn't completed yet", func() {
-			sess.handshakeComplete = false
-			// Needs to be shorter than our idle timeout.
-			// Otherwise we'll try to send a CONNECTION_CLOSE.
-			sess.lastPacketReceivedTime = time.Now().Add(-20 * time.Second)
+		It("sends a PING when requested, and returns once it was sent", func() {
+			sess.handshakeCtxCancel()
+			sess.config.KeepAlive = false // only send the requested PING
+			done := make(chan struct{})
+			packer.EXPECT().PackCoalescedPacket(gomock.Any()).DoAndReturn(func(protocol.ByteCount) (*coalescedPacket, error) {
+				frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+				if len(frames) == 0 {
+					return nil, nil
+				}
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PingFrame{}}}))
+				// SendPing only returns after the packet was sent
+				Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+				buffer := getPacketBuffer()
+				buffer.Data = append(buffer.Data, []byte("foobar")...)
+				return &coalescedPacket{
+					buffer: buffer,
+					packets: []*packetContents{{
+						header: &wire.ExtendedHeader{PacketNumber: 1},
+						frames: frames,
+						length: 6,
+					}},
+				}, nil
+			}).AnyTimes()
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			mconn.EXPECT().Write([]byte("foobar"), gomock.Any())
 			runSession()
-			// don't EXPECT() any calls to mconn.Write()
-			time.Sleep(50 * time.Millisecond)
-		})
-	})
+			go func() {
+				defer GinkgoRecover()
+				Expect(sess.SendPing()).To(Succeed())
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("refuses to send a PING when requested before the handshake completes", func() {
+			Expect(sess.SendPing()).To(MatchError(ErrHandshakeNotComplete))
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(frames).To(BeEmpty())
+			runSession()
+		})
+
+		It("doesn't send a PING if the handshake isn't completed yet", func() {
+			sess.handshakeComplete = false
+			// Needs to be shorter than our idle timeout.
+			// Otherwise we'll try to send a CONNECTION_CLOSE.
+			sess.lastPacketReceivedTime = time.Now().Add(-20 * time.Second)
+			runSession()
+			// don't EXPECT() any calls to mconn.Write()
+			time.Sleep(50 * time.Millisecond)
+		})
+	})
+
+	Context("forcing key updates", func() {
+		runSession := func() {
+			packer.EXPECT().PackCoalescedPacket(gomock.Any()).AnyTimes()
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+		}
+
+		AfterEach(func() {
+			// make the go routine return
+			expectReplaceWithClosed()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("refuses to force a key update before the handshake is confirmed", func() {
+			runSession()
+			Expect(sess.ForceKeyUpdate()).To(MatchError(ErrHandshakeNotConfirmed))
+		})
+
+		It("forces a key update", func() {
+			sess.handshakeConfirmed = true
+			cryptoSetup.EXPECT().ForceKeyUpdate()
+			runSession()
+			Expect(sess.ForceKeyUpdate()).To(Succeed())
+		})
+
+		It("returns the error if a key update is already in progress", func() {
+			sess.handshakeConfirmed = true
+			cryptoSetup.EXPECT().ForceKeyUpdate().Return(ErrKeyUpdateInProgress)
+			runSession()
+			Expect(sess.ForceKeyUpdate()).To(MatchError(ErrKeyUpdateInProgress))
+		})
+	})
+
+	Context("path migration", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4242}
+
+		BeforeEach(func() {
+			// the congestion controller is reset when migrating
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+		})
+
+		It("only allows the client to migrate", func() {
+			Expect(sess.MigratePath(context.Background(), nil)).To(MatchError("only the client can migrate a connection"))
+		})
+
+		It("sends the disable_active_migration transport parameter", func() {
+			for _, c := range []struct {
+				conf     *Config
+				disabled bool
+			}{
+				{conf: &Config{}, disabled: true},
+				{conf: &Config{AllowConnectionMigration: true}, disabled: false},
+				{conf: &Config{AllowConnectionMigration: true, DisableActiveMigration: true}, disabled: true},
+			} {
+				var params *wire.TransportParameters
+				tracer.EXPECT().SentTransportParameters(gomock.Any()).Do(func(p *wire.TransportParameters) { params = p })
+				newSession(
+					mconn,
+					sessionRunner,
+					nil,
+					nil,
+					clientDestConnID,
+					destConnID,
+					srcConnID,
+					protocol.StatelessResetToken{},
+					populateServerConfig(c.conf),
+					nil, // tls.Config
+					nil, // token generator
+					false,
+					tracer,
+					utils.DefaultLogger,
+					protocol.VersionTLS,
+				)
+				Expect(params.DisableActiveMigration).To(Equal(c.disabled))
+			}
+		})
+
+		It("doesn't answer PATH_CHALLENGEs on a new path, if active migration is disabled", func() {
+			sess.config.AllowConnectionMigration = true
+			sess.config.DisableActiveMigration = true
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+			// don't EXPECT any calls to the packer
+			sess.handlePathChallengeOnNewPath(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, newConn, 1200)
+			Expect(sess.probingPath).To(BeNil())
+		})
+
+		It("closes the connection if generating the data for a PATH_CHALLENGE fails", func() {
+			sess.config.AllowConnectionMigration = true
+			sess.config.Rand = bytes.NewReader([]byte{1, 2, 3})
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+			// don't EXPECT any calls to the packer
+			sess.handlePathChallengeOnNewPath(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, newConn, 1200)
+			var closeErr closeError
+			Expect(sess.closeChan).To(Receive(&closeErr))
+			Expect(closeErr.err).To(MatchError("generating PATH_CHALLENGE data failed: unexpected EOF"))
+		})
+
+		It("closes the connection if generating the data for a PATH_CHALLENGE fails, when probing a new path", func() {
+			sess.config.RequireAddressValidationOnRebind = true
+			sess.config.Rand = bytes.NewReader(nil)
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+			newConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			probe := newPathProbe(newConn, protocol.DefaultAmplificationFactor)
+			probe.bytesReceived = 1200
+			sess.probingPath = probe
+			// don't EXPECT any calls to the packer
+			Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe})).To(MatchError("generating PATH_CHALLENGE data failed: EOF"))
+			var closeErr closeError
+			Expect(sess.closeChan).To(Receive(&closeErr))
+			Expect(closeErr.err).To(MatchError("generating PATH_CHALLENGE data failed: EOF"))
+		})
+
+		It("doesn't treat packets from a new address as a new path, if migration is not allowed", func() {
+			Expect(sess.newPathFor(&receivedPacket{remoteAddr: newAddr})).To(BeNil())
+		})
+
+		It("answers PATH_CHALLENGEs from a new address, and migrates once the address is validated", func() {
+			sess.config.AllowConnectionMigration = true
+			Expect(sess.newPathFor(&receivedPacket{remoteAddr: remoteAddr})).To(BeNil())
+			newConn := NewMockSendConn(mockCtrl)
+			newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+			mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+			rp := &receivedPacket{remoteAddr: newAddr, data: make([]byte, 100)}
+			path := sess.newPathFor(rp)
+			Expect(path).To(Equal(newConn))
+
+			var challenge [8]byte
+			packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(300)).DoAndReturn(func(frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+				Expect(frames).To(HaveLen(2))
+				Expect(frames[0].Frame).To(Equal(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}))
+				Expect(frames[1].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+				challenge = frames[1].Frame.(*wire.PathChallengeFrame).Data
+				return getPacket(1), nil
+			})
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			newConn.EXPECT().Write([]byte("foobar"), protocol.ECNNon)
+			sess.handlePathChallengeOnNewPath(&wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, path, rp.Size())
+			// the path is not used before it is validated
+			sess.maybeMigratePeer(rp, 1)
+			Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+
+			newConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			tracer.EXPECT().PathValidated(localAddr, newAddr)
+			sess.handlePathResponseFrame(&wire.PathResponseFrame{Data: challenge})
+			Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+			Expect(sess.newPathFor(rp)).To(Equal(newConn))
+			sess.maybeMigratePeer(rp, 2)
+			Expect(sess.RemoteAddr()).To(Equal(newAddr))
+			Expect(sess.newPathFor(rp)).To(BeNil())
+			Expect(sess.CurrentPath().Validated).To(BeTrue())
+		})
+
+		Context("peer address changes without path validation", func() {
+			var newConn *MockSendConn
+
+			BeforeEach(func() {
+				sess.config.AllowConnectionMigration = true
+				sess.handshakeConfirmed = true
+				newConn = NewMockSendConn(mockCtrl)
+				newConn.EXPECT().RemoteAddr().Return(newAddr).AnyTimes()
+				newConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			})
+
+			It("migrates right away, and validates the new address", func() {
+				mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr, data: make([]byte, 100)}, 10)
+				Expect(sess.RemoteAddr()).To(Equal(newAddr))
+				Expect(sess.CurrentPath()).To(Equal(PathInfo{LocalAddr: localAddr, RemoteAddr: newAddr}))
+				// the PATH_CHALLENGE is subject to the anti-amplification limit
+				var challenge [8]byte
+				packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(300)).DoAndReturn(func(frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+					Expect(frames).To(HaveLen(1))
+					Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+					challenge = frames[0].Frame.(*wire.PathChallengeFrame).Data
+					return getPacket(1), nil
+				})
+				tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				newConn.EXPECT().Write(gomock.Any(), protocol.ECNNon)
+				var r pathProbeRequest
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				r.errChan <- sess.handlePathProbeRequest(r)
+
+				tracer.EXPECT().PathValidated(localAddr, newAddr)
+				sess.handlePathResponseFrame(&wire.PathResponseFrame{Data: challenge})
+				Expect(sess.RemoteAddr()).To(Equal(newAddr))
+				Expect(sess.CurrentPath()).To(Equal(PathInfo{LocalAddr: localAddr, RemoteAddr: newAddr, Validated: true}))
+				Expect(sess.sentPacketHandler.AmplificationWindow()).To(Equal(protocol.MaxByteCount))
+			})
+
+			It("applies the anti-amplification limit to the new address", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sess.sentPacketHandler = sph
+				mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+				gomock.InOrder(
+					sph.EXPECT().MigratedPath(false, true),
+					sph.EXPECT().ReceivedBytes(protocol.ByteCount(100)),
+				)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr, data: make([]byte, 100)}, 10)
+				// no PATH_CHALLENGE is sent if the amplification window is exhausted
+				sph.EXPECT().AmplificationWindow().Return(protocol.ByteCount(0))
+				var r pathProbeRequest
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				Expect(sess.handlePathProbeRequest(r)).To(Succeed())
+				r.errChan <- errors.New("stop")
+			})
+
+			It("doesn't reset the congestion controller if only the peer's port changed", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sess.sentPacketHandler = sph
+				newPortAddr := &net.UDPAddr{IP: remoteAddr.IP, Port: remoteAddr.Port + 1}
+				newPortConn := NewMockSendConn(mockCtrl)
+				newPortConn.EXPECT().RemoteAddr().Return(newPortAddr).AnyTimes()
+				newPortConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+				mconn.EXPECT().WithRemoteAddr(newPortAddr).Return(newPortConn)
+				sph.EXPECT().MigratedPath(false, false)
+				sph.EXPECT().ReceivedBytes(gomock.Any())
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newPortAddr}, 10)
+				Expect(sess.RemoteAddr()).To(Equal(newPortAddr))
+				var r pathProbeRequest
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				r.errChan <- errors.New("stop")
+			})
+
+			It("reverts to the previous address if the validation fails", func() {
+				mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 10)
+				Expect(sess.RemoteAddr()).To(Equal(newAddr))
+				probe := sess.probingPath
+				var r pathProbeRequest
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				r.errChan <- errors.New("stop")
+				Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe, failed: true})).To(Succeed())
+				Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+				Expect(sess.CurrentPath().Validated).To(BeTrue())
+				Expect(sess.closeChan).ToNot(Receive())
+			})
+
+			It("reverts to the last validated address if the peer's address changed again", func() {
+				mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 10)
+				var r pathProbeRequest
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				r.errChan <- errors.New("stop")
+				otherAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4343}
+				otherConn := NewMockSendConn(mockCtrl)
+				otherConn.EXPECT().RemoteAddr().Return(otherAddr).AnyTimes()
+				otherConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+				newConn.EXPECT().WithRemoteAddr(otherAddr).Return(otherConn)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: otherAddr}, 11)
+				Expect(sess.RemoteAddr()).To(Equal(otherAddr))
+				Eventually(sess.pathProbeRequests).Should(Receive(&r))
+				r.errChan <- errors.New("stop")
+				Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: sess.probingPath, failed: true})).To(Succeed())
+				Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+			})
+
+			It("doesn't migrate for reordered packets", func() {
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: remoteAddr}, 10)
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 9)
+				Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+				Expect(sess.CurrentPath().Validated).To(BeTrue())
+			})
+
+			It("doesn't migrate before the handshake is confirmed", func() {
+				sess.handshakeConfirmed = false
+				sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 10)
+				Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+			})
+
+			Context("requiring address validation", func() {
+				BeforeEach(func() {
+					sess.config.AllowConnectionMigration = false
+					sess.config.RequireAddressValidationOnRebind = true
+				})
+
+				It("treats packets from a new address as a new path", func() {
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					Expect(sess.newPathFor(&receivedPacket{remoteAddr: newAddr})).To(Equal(newConn))
+				})
+
+				It("validates the new address before migrating", func() {
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					rp := &receivedPacket{remoteAddr: newAddr, data: make([]byte, 500)}
+					sess.maybeMigratePeer(rp, 10)
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+					// the PATH_CHALLENGE is sent on the new path
+					var challenge [8]byte
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(protocol.MinInitialPacketSize)).DoAndReturn(func(frames []ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+						Expect(frames).To(HaveLen(1))
+						Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+						challenge = frames[0].Frame.(*wire.PathChallengeFrame).Data
+						return getPacket(1), nil
+					})
+					tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					newConn.EXPECT().Write(gomock.Any(), protocol.ECNNon)
+					var r pathProbeRequest
+					Eventually(sess.pathProbeRequests).Should(Receive(&r))
+					r.errChan <- sess.handlePathProbeRequest(r)
+					Expect(sess.probingPath.bytesSent).To(Equal(getPacket(1).buffer.Len()))
+					// more packets from the new address don't restart the validation
+					sess.maybeMigratePeer(rp, 11)
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+
+					tracer.EXPECT().PathValidated(localAddr, newAddr)
+					sess.handlePathResponseFrame(&wire.PathResponseFrame{Data: challenge})
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+					sess.maybeMigratePeer(rp, 12)
+					Expect(sess.RemoteAddr()).To(Equal(newAddr))
+					Expect(sess.CurrentPath().Validated).To(BeTrue())
+				})
+
+				It("applies the anti-amplification limit to the new address", func() {
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr, data: make([]byte, 100)}, 10)
+					probe := sess.probingPath
+					Expect(probe.bytesReceived).To(Equal(protocol.ByteCount(100)))
+					// more packets received on the new path increase the limit
+					tracer.EXPECT().DroppedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+					Expect(sess.handlePacketImpl(&receivedPacket{remoteAddr: newAddr, data: make([]byte, 400), buffer: getPacketBuffer()})).To(BeFalse())
+					Expect(probe.bytesReceived).To(Equal(protocol.ByteCount(500)))
+					probe.bytesSent = 1200
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(300)).Return(getPacket(1), nil)
+					tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					newConn.EXPECT().Write(gomock.Any(), protocol.ECNNon)
+					Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe})).To(Succeed())
+					// once the limit is reached, no more PATH_CHALLENGEs are sent
+					probe.bytesSent = 1500
+					Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe})).To(Succeed())
+				})
+
+				It("uses the configured amplification factor for the new address", func() {
+					sess.config.AmplificationFactor = 2
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr, data: make([]byte, 500)}, 10)
+					probe := sess.probingPath
+					var r pathProbeRequest
+					Eventually(sess.pathProbeRequests).Should(Receive(&r))
+					r.errChan <- errors.New("stop")
+					probe.bytesSent = 700
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(300)).Return(getPacket(1), nil)
+					tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					newConn.EXPECT().Write(gomock.Any(), protocol.ECNNon)
+					Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe})).To(Succeed())
+				})
+
+				It("uses the configured amplification factor when answering a PATH_CHALLENGE on a new path", func() {
+					sess.config.AmplificationFactor = 1
+					packer.EXPECT().PackPathProbePacket(gomock.Any(), protocol.ByteCount(100)).Return(getPacket(1), nil)
+					tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
+					tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					newConn.EXPECT().Write(gomock.Any(), protocol.ECNNon)
+					sess.handlePathChallengeOnNewPath(&wire.PathChallengeFrame{}, newConn, 100)
+					Expect(sess.probingPath.bytesSent).To(Equal(getPacket(1).buffer.Len()))
+				})
+
+				It("closes the connection if the validation fails", func() {
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 10)
+					Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: sess.probingPath, failed: true})).To(Succeed())
+					var closeErr closeError
+					Expect(sess.closeChan).To(Receive(&closeErr))
+					Expect(closeErr.err).To(MatchError(ErrPathValidationFailed))
+					Expect(closeErr.immediate).To(BeFalse())
+				})
+
+				It("doesn't close the connection if the peer's address changed again", func() {
+					mconn.EXPECT().WithRemoteAddr(newAddr).Return(newConn)
+					sess.maybeMigratePeer(&receivedPacket{remoteAddr: newAddr}, 10)
+					probe := sess.probingPath
+					otherAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4343}
+					otherConn := NewMockSendConn(mockCtrl)
+					otherConn.EXPECT().RemoteAddr().Return(otherAddr).AnyTimes()
+					mconn.EXPECT().WithRemoteAddr(otherAddr).Return(otherConn)
+					sess.maybeMigratePeer(&receivedPacket{remoteAddr: otherAddr}, 11)
+					Expect(sess.handlePathProbeRequest(pathProbeRequest{probe: probe, failed: true})).ToNot(Succeed())
+					Expect(sess.closeChan).ToNot(Receive())
+					Expect(sess.RemoteAddr()).To(Equal(remoteAddr))
+				})
+			})
+		})
+	})
 
 	Context("timeouts", func() {
 		BeforeEach(func() {
@@ -1945,6 +2997,31 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("uses the clock for the idle timeout", func() {
+			clock := utils.NewManualClock(time.Now())
+			sess.clock = clock
+			sess.lastPacketReceivedTime = clock.Now()
+			sessionRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(MatchError(ContainSubstring("No recent network activity")))
+				close(done)
+			}()
+			Consistently(done, scaleDuration(50*time.Millisecond)).ShouldNot(BeClosed())
+			clock.Advance(sess.idleTimeout / 2)
+			Consistently(done, scaleDuration(50*time.Millisecond)).ShouldNot(BeClosed())
+			clock.Advance(sess.idleTimeout / 2)
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("times out due to non-completed handshake", func() {
 			sess.handshakeComplete = false
 			sess.sessionCreationTime = time.Now().Add(-protocol.DefaultHandshakeTimeout).Add(-time.Second)
@@ -1967,6 +3044,36 @@ var _ = Describe("Session", func() {
 				Expect(ok).To(BeTrue())
 				Expect(nerr.Timeout()).To(BeTrue())
 				Expect(err.Error()).To(ContainSubstring("Handshake did not complete in time"))
+				Expect(errors.Is(err, ErrHandshakeTimeout)).To(BeTrue())
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("times out due to the handshake idle timeout", func() {
+			sess.handshakeComplete = false
+			sess.config.HandshakeIdleTimeout = 5 * time.Second
+			sess.lastPacketReceivedTime = time.Now().Add(-6 * time.Second)
+			sessionRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(reason logging.CloseReason) {
+					timeout, ok := reason.Timeout()
+					Expect(ok).To(BeTrue())
+					Expect(timeout).To(Equal(logging.TimeoutReasonHandshake))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(errors.Is(err, ErrHandshakeTimeout)).To(BeFalse())
+				Expect(err.Error()).To(ContainSubstring("No recent network activity during the handshake"))
 				close(done)
 			}()
 			Eventually(done).Should(BeClosed())
@@ -1976,6 +3083,7 @@ var _ = Describe("Session", func() {
 			sess.handshakeComplete = false
 			sess.config.MaxIdleTimeout = 9999 * time.Second
 			sess.lastPacketReceivedTime = time.Now().Add(-time.Minute)
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).DoAndReturn(func(quicErr *qerr.QuicError) (*coalescedPacket, error) {
 				Expect(quicErr.ErrorCode).To(Equal(qerr.NoError))
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
@@ -1998,7 +3106,7 @@ var _ = Describe("Session", func() {
 			// make the go routine return
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			sess.shutdown()
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
@@ -2047,10 +3155,11 @@ var _ = Describe("Session", func() {
 			}()
 			Consistently(sess.Context().Done()).ShouldNot(BeClosed())
 			// make the go routine return
+			tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
 			cryptoSetup.EXPECT().Close()
-			mconn.EXPECT().Write(gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			sess.shutdown()
@@ -2103,7 +3212,12 @@ var _ = Describe("Session", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 			defer cancel()
 			mstr := NewMockStreamI(mockCtrl)
-			streamManager.EXPECT().AcceptStream(ctx).Return(mstr, nil)
+			streamManager.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(c context.Context) (Stream, error) {
+				d, ok := c.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(d).To(BeTemporally("~", time.Now().Add(time.Minute), time.Second))
+				return mstr, nil
+			})
 			str, err := sess.AcceptStream(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
@@ -2113,11 +3227,89 @@ var _ = Describe("Session", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 			mstr := NewMockReceiveStreamI(mockCtrl)
-			streamManager.EXPECT().AcceptUniStream(ctx).Return(mstr, nil)
+			streamManager.EXPECT().AcceptUniStream(gomock.Any()).Return(mstr, nil)
 			str, err := sess.AcceptUniStream(ctx)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		Context("accept deadlines", func() {
+			blockUntilDone := func(ctx context.Context) (Stream, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+
+			It("times out accepting streams", func() {
+				streamManager.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(blockUntilDone)
+				sess.SetAcceptDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				_, err := sess.AcceptStream(context.Background())
+				Expect(err).To(HaveOccurred())
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+			})
+
+			It("times out accepting unidirectional streams", func() {
+				streamManager.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(ctx context.Context) (ReceiveStream, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				})
+				sess.SetAcceptDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				_, err := sess.AcceptUniStream(context.Background())
+				Expect(err).To(MatchError(errDeadline))
+			})
+
+			It("returns errors from the streams map", func() {
+				testErr := errors.New("test error")
+				streamManager.EXPECT().AcceptStream(gomock.Any()).Return(nil, testErr)
+				sess.SetAcceptDeadline(time.Now().Add(time.Hour))
+				_, err := sess.AcceptStream(context.Background())
+				Expect(err).To(MatchError(testErr))
+			})
+
+			It("returns immediately if the deadline is in the past", func() {
+				sess.SetAcceptDeadline(time.Now().Add(-time.Second))
+				_, err := sess.AcceptStream(context.Background())
+				Expect(err).To(MatchError(errDeadline))
+				_, err = sess.AcceptUniStream(context.Background())
+				Expect(err).To(MatchError(errDeadline))
+			})
+
+			It("applies a new deadline to a blocked call", func() {
+				streamManager.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(blockUntilDone).Times(2)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					_, err := sess.AcceptStream(context.Background())
+					Expect(err).To(MatchError(errDeadline))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				sess.SetAcceptDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("accepts streams again after the deadline is removed", func() {
+				sess.SetAcceptDeadline(time.Now().Add(-time.Second))
+				_, err := sess.AcceptStream(context.Background())
+				Expect(err).To(MatchError(errDeadline))
+				sess.SetAcceptDeadline(time.Time{})
+				mstr := NewMockStreamI(mockCtrl)
+				streamManager.EXPECT().AcceptStream(gomock.Any()).Return(mstr, nil)
+				str, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str).To(Equal(mstr))
+			})
+
+			It("returns the context error when the context is canceled before the deadline", func() {
+				streamManager.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(blockUntilDone)
+				sess.SetAcceptDeadline(time.Now().Add(time.Hour))
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+				defer cancel()
+				_, err := sess.AcceptStream(ctx)
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+			})
+		})
 	})
 
 	It("returns the local address", func() {
@@ -2227,13 +3419,14 @@ var _ = Describe("Client Session", func() {
 			},
 			PacketNumberLen: protocol.PacketNumberLen2,
 		}, []byte("foobar"))
-		tracer.EXPECT().ReceivedPacket(gomock.Any(), p.Size(), []logging.Frame{})
+		tracer.EXPECT().ReceivedPacket(gomock.Any(), p.Size(), gomock.Any(), []logging.Frame{})
 		Expect(sess.handlePacketImpl(p)).To(BeTrue())
 		// make sure the go routine returns
+		tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any())
 		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		expectReplaceWithClosed()
 		cryptoSetup.EXPECT().Close()
-		mconn.EXPECT().Write(gomock.Any())
+		mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		sess.shutdown()
@@ -2244,6 +3437,8 @@ var _ = Describe("Client Session", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		sess.unpacker = unpacker
 		sessionRunner.EXPECT().AddResetToken(gomock.Any(), gomock.Any())
+		tracer.EXPECT().NewConnectionIDReceived(uint64(1), protocol.ConnectionID{1, 2, 3, 4, 5})
+		tracer.EXPECT().RetiredConnectionID(uint64(0))
 		sess.handleNewConnectionIDFrame(&wire.NewConnectionIDFrame{
 			SequenceNumber: 1,
 			ConnectionID:   protocol.ConnectionID{1, 2, 3, 4, 5},
@@ -2263,7 +3458,7 @@ var _ = Describe("Client Session", func() {
 			DestConnectionID: srcConnID,
 			SrcConnectionID:  destConnID,
 		}
-		tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+		tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 		Expect(sess.handleSinglePacket(&receivedPacket{buffer: getPacketBuffer()}, hdr)).To(BeTrue())
 	})
 
@@ -2272,6 +3467,77 @@ var _ = Describe("Client Session", func() {
 		Expect(sess.handleHandshakeDoneFrame()).To(Succeed())
 	})
 
+	It("reports the maximum 0-RTT size from the restored transport parameters", func() {
+		Expect(sess.Max0RTTSize()).To(BeZero())
+		sess.restoreTransportParameters(&wire.TransportParameters{
+			InitialMaxData:          1337,
+			ActiveConnectionIDLimit: 2,
+		})
+		Expect(sess.Max0RTTSize()).To(Equal(protocol.ByteCount(1337)))
+	})
+
+	Context("using a configured initial RTT", func() {
+		BeforeEach(func() {
+			quicConf.InitialRTT = 10 * time.Millisecond
+		})
+
+		It("uses the initial RTT before the first RTT sample", func() {
+			Expect(sess.rttStats.SmoothedRTT()).To(Equal(10 * time.Millisecond))
+			Expect(sess.rttStats.PTO(false)).To(Equal(10*time.Millisecond + protocol.TimerGranularity))
+		})
+	})
+
+	Context("flow control auto-tuning", func() {
+		// receive simulates a peer that sends data as fast as flow control allows, and an application that reads it right away.
+		// It returns the stream-level and connection-level receive windows after every window update.
+		receive := func() (streamWindows, connWindows []protocol.ByteCount) {
+			sess.rttStats.UpdateRTT(scaleDuration(20*time.Millisecond), 0, time.Now())
+			fc := sess.newFlowController(1)
+			var read protocol.ByteCount
+			streamOffset := protocol.ByteCount(protocol.InitialMaxStreamData)
+			connOffset := protocol.ByteCount(protocol.InitialMaxData)
+			for i := 0; i < 50; i++ {
+				highest := utils.MinByteCount(streamOffset, connOffset)
+				Expect(fc.UpdateHighestReceived(highest, false)).To(Succeed())
+				fc.AddBytesRead(highest - read)
+				read = highest
+				if offset := fc.GetWindowUpdate(); offset > 0 {
+					streamOffset = offset
+					streamWindows = append(streamWindows, offset-read)
+				}
+				if offset := sess.connFlowController.GetWindowUpdate(); offset > 0 {
+					connOffset = offset
+					connWindows = append(connWindows, offset-read)
+				}
+			}
+			return
+		}
+
+		It("auto-tunes the receive windows", func() {
+			streamWindows, connWindows := receive()
+			Expect(streamWindows[len(streamWindows)-1]).To(BeNumerically(">", protocol.InitialMaxStreamData))
+			Expect(connWindows[len(connWindows)-1]).To(BeNumerically(">", protocol.InitialMaxData))
+		})
+
+		Context("with auto-tuning disabled", func() {
+			BeforeEach(func() {
+				quicConf.DisableFlowControlAutoTuning = true
+			})
+
+			It("never grows the receive windows beyond their initial values", func() {
+				streamWindows, connWindows := receive()
+				Expect(streamWindows).ToNot(BeEmpty())
+				Expect(connWindows).ToNot(BeEmpty())
+				for _, w := range streamWindows {
+					Expect(w).To(BeEquivalentTo(protocol.InitialMaxStreamData))
+				}
+				for _, w := range connWindows {
+					Expect(w).To(BeEquivalentTo(protocol.InitialMaxData))
+				}
+			})
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
@@ -2287,6 +3553,17 @@ var _ = Describe("Client Session", func() {
 			mockTokenStore.EXPECT().Put("server", &ClientToken{data: []byte("foobar")})
 			Expect(sess.handleNewTokenFrame(&wire.NewTokenFrame{Token: []byte("foobar")})).To(Succeed())
 		})
+
+		It("invalidates the tokens when the server closes with INVALID_TOKEN, if a token was used", func() {
+			sess.usedToken = true
+			mockTokenStore.EXPECT().Invalidate("server")
+			sess.handleConnectionCloseFrame(&wire.ConnectionCloseFrame{ErrorCode: qerr.InvalidToken})
+		})
+
+		It("doesn't invalidate tokens when the server closes with INVALID_TOKEN, if no token was used", func() {
+			// don't EXPECT any calls to mockTokenStore.Invalidate
+			sess.handleConnectionCloseFrame(&wire.ConnectionCloseFrame{ErrorCode: qerr.InvalidToken})
+		})
 	})
 
 	Context("handling Version Negotiation", func() {
@@ -2352,6 +3629,28 @@ var _ = Describe("Client Session", func() {
 			Expect(err.Error()).To(ContainSubstring("No compatible QUIC version found"))
 		})
 
+		It("closes with ErrVersionNegotiationReceived if version negotiation is disabled", func() {
+			sess.config.DisableVersionNegotiation = true
+			sess.config.Versions = []protocol.VersionNumber{1234, 4321}
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				errChan <- sess.run()
+			}()
+			sessionRunner.EXPECT().Remove(srcConnID).MaxTimes(1)
+			gomock.InOrder(
+				tracer.EXPECT().ReceivedVersionNegotiationPacket(gomock.Any(), gomock.Any()).Do(func(_ *wire.Header, versions []logging.VersionNumber) {
+					Expect(versions).To(ContainElement(protocol.VersionNumber(4321)))
+				}),
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			cryptoSetup.EXPECT().Close()
+			Expect(sess.handlePacketImpl(getVNP(4321))).To(BeFalse())
+			Eventually(errChan).Should(Receive(Equal(ErrVersionNegotiationReceived)))
+		})
+
 		It("ignores Version Negotiation packets that offer the current version", func() {
 			p := getVNP(sess.version)
 			tracer.EXPECT().DroppedPacket(logging.PacketTypeVersionNegotiation, p.Size(), logging.PacketDropUnexpectedVersion)
@@ -2403,6 +3702,42 @@ var _ = Describe("Client Session", func() {
 				Expect(hdr.Token).To(Equal(retryHdr.Token))
 			})
 			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+			// the original destination connection ID is the one used before the Retry
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+			cryptoSetup.EXPECT().KeyExchangeGroup()
+			cs := sess.ConnectionState()
+			Expect(cs.OriginalDestinationConnectionID).To(Equal(origDestConnID))
+			Expect(cs.UsedRetry).To(BeTrue())
+		})
+
+		It("ignores a second Retry packet", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().ReceivedBytes(gomock.Any()).Times(2)
+			sph.EXPECT().ResetForRetry()
+			cryptoSetup.EXPECT().ChangeConnectionID(gomock.Any())
+			packer.EXPECT().SetToken([]byte("foobar"))
+			tracer.EXPECT().ReceivedRetry(gomock.Any())
+			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+			p := getPacket(retryHdr, getRetryTag(retryHdr))
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeRetry, p.Size(), logging.PacketDropUnexpectedPacket)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+		})
+
+		It("invalidates the tokens for this server when receiving a Retry, if a token was used", func() {
+			tokenStore := NewLRUTokenStore(10, 4)
+			tokenStore.Put(sess.tokenStoreKey, &ClientToken{data: []byte("token")})
+			sess.config.TokenStore = tokenStore
+			sess.usedToken = true
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sess.sentPacketHandler = sph
+			sph.EXPECT().ReceivedBytes(gomock.Any())
+			sph.EXPECT().ResetForRetry()
+			cryptoSetup.EXPECT().ChangeConnectionID(gomock.Any())
+			packer.EXPECT().SetToken([]byte("foobar"))
+			tracer.EXPECT().ReceivedRetry(gomock.Any())
+			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+			Expect(tokenStore.Pop(sess.tokenStoreKey)).To(BeNil())
 		})
 
 		It("ignores Retry packets after receiving a regular packet", func() {
@@ -2425,6 +3760,71 @@ var _ = Describe("Client Session", func() {
 			p := getPacket(retryHdr, tag)
 			tracer.EXPECT().DroppedPacket(logging.PacketTypeRetry, p.Size(), logging.PacketDropPayloadDecryptError)
 			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+			cryptoSetup.EXPECT().KeyExchangeGroup()
+			Expect(sess.ConnectionState().UsedRetry).To(BeFalse())
+		})
+	})
+
+	Context("reading from a migrated path", func() {
+		var packetConn *net.UDPConn
+
+		BeforeEach(func() {
+			addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+			Expect(err).ToNot(HaveOccurred())
+			packetConn, err = net.ListenUDP("udp", addr)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(packetConn.Close()).To(Succeed())
+		})
+
+		It("passes packets to the packet handler map, and stops reading when stopped", func() {
+			path := NewMockSendConn(mockCtrl)
+			r := newPathReader(packetConn, path)
+			handled := make(chan struct{})
+			sessionRunner.EXPECT().HandlePacketOnPath(gomock.Any(), sess).Do(func(p *receivedPacket, _ packetHandler) {
+				defer GinkgoRecover()
+				Expect(p.data).To(Equal([]byte("foobar")))
+				Expect(p.path).To(Equal(path))
+				close(handled)
+			})
+			go sess.readFromPath(r)
+			c, err := net.DialUDP("udp", nil, packetConn.LocalAddr().(*net.UDPAddr))
+			Expect(err).ToNot(HaveOccurred())
+			defer c.Close()
+			_, err = c.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(handled).Should(BeClosed())
+			r.stop()
+			Expect(r.readingDone).To(BeClosed())
+			// stop can be called multiple times
+			r.stop()
+		})
+
+		It("stops reading from the previous path when migrating to a new path", func() {
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			mconn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+			mconn.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+			oldPath := NewMockSendConn(mockCtrl)
+			oldReader := newPathReader(packetConn, oldPath)
+			go sess.readFromPath(oldReader)
+			sess.pathReader = oldReader
+
+			newPath := NewMockSendConn(mockCtrl)
+			newPath.EXPECT().LocalAddr().Return(&net.UDPAddr{Port: 1234}).AnyTimes()
+			newPath.EXPECT().RemoteAddr().Return(&net.UDPAddr{}).AnyTimes()
+			probe := newPathProbe(newPath, protocol.DefaultAmplificationFactor)
+			probe.reader = newPathReader(nil, newPath)
+			challenge, err := probe.newChallenge(rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			sess.probingPath = probe
+			tracer.EXPECT().PathValidated(gomock.Any(), gomock.Any())
+			sess.handlePathResponseFrame(&wire.PathResponseFrame{Data: challenge})
+			Expect(probe.validatedChan).To(BeClosed())
+			Expect(sess.pathReader).To(Equal(probe.reader))
+			Eventually(oldReader.readingDone).Should(BeClosed())
 		})
 	})
 
@@ -2451,9 +3851,10 @@ var _ = Describe("Client Session", func() {
 					Expect(s).To(BeAssignableToTypeOf(&closedLocalSession{}))
 					s.shutdown()
 				})
+				tracer.EXPECT().SentDatagram(gomock.Any(), gomock.Any()).MaxTimes(1)
 				packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil).MaxTimes(1)
 				cryptoSetup.EXPECT().Close()
-				mconn.EXPECT().Write(gomock.Any())
+				mconn.EXPECT().Write(gomock.Any(), gomock.Any())
 				gomock.InOrder(
 					tracer.EXPECT().ClosedConnection(gomock.Any()),
 					tracer.EXPECT().Close(),
@@ -2483,11 +3884,13 @@ var _ = Describe("Client Session", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			packer.EXPECT().PackCoalescedPacket(protocol.MaxByteCount).MaxTimes(1)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NewConnectionIDReceived(uint64(1), protocol.ConnectionID{1, 2, 3, 4})
 			sess.processTransportParameters(params)
 			// make sure the connection ID is not retired
 			cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
 			Expect(cf).To(BeEmpty())
 			sessionRunner.EXPECT().AddResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}, sess)
+			tracer.EXPECT().RetiredConnectionID(uint64(0))
 			Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
 			// shut down
 			sessionRunner.EXPECT().RemoveResetToken(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
@@ -2507,6 +3910,46 @@ var _ = Describe("Client Session", func() {
 			Expect(sess.idleTimeout).To(Equal(18 * time.Second))
 		})
 
+		It("returns the original destination connection ID in the ConnectionState", func() {
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+			cryptoSetup.EXPECT().KeyExchangeGroup()
+			Expect(sess.ConnectionState().OriginalDestinationConnectionID).To(Equal(destConnID))
+		})
+
+		It("returns the negotiated idle timeout in the ConnectionState", func() {
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).Times(2)
+			cryptoSetup.EXPECT().KeyExchangeGroup().Times(2)
+			Expect(sess.ConnectionState().MaxIdleTimeout).To(BeZero())
+			sess.config.MaxIdleTimeout = 17 * time.Second
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				MaxIdleTimeout:                  time.Minute,
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Expect(sess.ConnectionState().MaxIdleTimeout).To(Equal(17 * time.Second))
+		})
+
+		It("returns the peer's transport parameters in the ConnectionState", func() {
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).Times(2)
+			cryptoSetup.EXPECT().KeyExchangeGroup().Times(2)
+			Expect(sess.ConnectionState().RemoteTransportParameters).To(BeNil())
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				InitialMaxData:                  0x1337,
+				MaxUDPPayloadSize:               1234,
+				AckDelayExponent:                5,
+				MaxAckDelay:                     42 * time.Millisecond,
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.processTransportParameters(params)
+			Expect(sess.ConnectionState().RemoteTransportParameters).To(Equal(params))
+		})
+
 		It("errors if the TransportParameters contain a wrong initial_source_connection_id", func() {
 			sess.handshakeDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			params := &wire.TransportParameters{
@@ -2633,7 +4076,7 @@ var _ = Describe("Client Session", func() {
 				hdr:             hdr1,
 				data:            []byte{0}, // one PADDING frame
 			}, nil)
-			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			Expect(sess.handlePacketImpl(getPacket(hdr1, nil))).To(BeTrue())
 			// The next packet has to be ignored, since the source connection ID doesn't match.
 			tracer.EXPECT().DroppedPacket(gomock.Any(), gomock.Any(), gomock.Any())
@@ -2661,7 +4104,7 @@ var _ = Describe("Client Session", func() {
 		It("fails on Initial-level ACK for unsent packet", func() {
 			ackFrame := testutils.ComposeAckFrame(0, 0)
 			initialPacket := testutils.ComposeInitialPacket(destConnID, srcConnID, sess.version, destConnID, []wire.Frame{ackFrame})
-			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			Expect(sess.handlePacketImpl(wrapPacket(initialPacket))).To(BeFalse())
 		})
 
@@ -2670,7 +4113,7 @@ var _ = Describe("Client Session", func() {
 		It("fails on Initial-level CONNECTION_CLOSE frame", func() {
 			connCloseFrame := testutils.ComposeConnCloseFrame()
 			initialPacket := testutils.ComposeInitialPacket(destConnID, srcConnID, sess.version, destConnID, []wire.Frame{connCloseFrame})
-			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			Expect(sess.handlePacketImpl(wrapPacket(initialPacket))).To(BeTrue())
 		})
 
@@ -1,12 +1,14 @@
 package quic
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -14,6 +16,7 @@ import (
 type streamSender interface {
 	queueControlFrame(wire.Frame)
 	onHasStreamData(protocol.StreamID)
+	setStreamPriority(protocol.StreamID, uint8)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -33,6 +36,10 @@ func (s *uniStreamSender) onHasStreamData(id protocol.StreamID) {
 	s.streamSender.onHasStreamData(id)
 }
 
+func (s *uniStreamSender) setStreamPriority(id protocol.StreamID, weight uint8) {
+	s.streamSender.setStreamPriority(id, weight)
+}
+
 func (s *uniStreamSender) onStreamCompleted(protocol.StreamID) {
 	s.onStreamCompletedImpl()
 }
@@ -46,11 +53,13 @@ type streamI interface {
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	getWindowUpdate() protocol.ByteCount
+	getBytesReceived() protocol.ByteCount
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	handleMaxStreamDataFrame(*wire.MaxStreamDataFrame)
+	getBytesSent() protocol.ByteCount
 }
 
 var _ receiveStreamI = (streamI)(nil)
@@ -73,20 +82,20 @@ type stream struct {
 
 var _ Stream = &stream{}
 
-type streamCanceledError struct {
-	error
-	errorCode protocol.ApplicationErrorCode
+func (e *StreamError) Error() string {
+	if e.Remote {
+		return fmt.Sprintf("stream %d was reset with error code %d", e.StreamID, e.ErrorCode)
+	}
+	return fmt.Sprintf("stream %d canceled with error code %d", e.StreamID, e.ErrorCode)
 }
 
-func (streamCanceledError) Canceled() bool                             { return true }
-func (e streamCanceledError) ErrorCode() protocol.ApplicationErrorCode { return e.errorCode }
-
-var _ StreamError = &streamCanceledError{}
-
 // newStream creates a new Stream
 func newStream(streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	writeCoalesceDelay time.Duration,
+	idleTimer *streamIdleTimer,
+	clock utils.Clock,
 	version protocol.VersionNumber,
 ) *stream {
 	s := &stream{sender: sender, version: version}
@@ -99,7 +108,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, version)
+	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, writeCoalesceDelay, idleTimer, clock, version)
 	senderForReceiveStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -109,7 +118,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, version)
+	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, idleTimer, clock, version)
 	return s
 }
 
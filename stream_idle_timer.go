@@ -0,0 +1,80 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A streamIdleTimer fires when no frames were sent or received on a stream for the idle timeout.
+// Only activity on the wire resets the timer, reading from or writing to the stream doesn't.
+// All methods can be called on a nil streamIdleTimer, in which case they are no-ops.
+type streamIdleTimer struct {
+	mutex        sync.Mutex
+	clock        utils.Clock
+	timeout      time.Duration
+	lastActivity time.Time
+	timer        utils.ClockTimer
+	stopped      bool
+	onTimeout    func()
+}
+
+func newStreamIdleTimer(timeout time.Duration, clock utils.Clock) *streamIdleTimer {
+	return &streamIdleTimer{timeout: timeout, clock: clock}
+}
+
+// start starts the timer.
+// onTimeout is called (from a separate Go routine) when the timer fires.
+func (t *streamIdleTimer) start(onTimeout func()) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stopped {
+		return
+	}
+	t.onTimeout = onTimeout
+	t.lastActivity = t.clock.Now()
+	t.timer = t.clock.AfterFunc(t.timeout, t.fire)
+}
+
+// activity is called when a frame is sent or received on the stream.
+func (t *streamIdleTimer) activity() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	t.lastActivity = t.clock.Now()
+	t.mutex.Unlock()
+}
+
+func (t *streamIdleTimer) stop() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.mutex.Unlock()
+}
+
+func (t *streamIdleTimer) fire() {
+	t.mutex.Lock()
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+	// Resetting the timer on every activity would be expensive, so we check here if there was activity in the meantime.
+	if remaining := t.timeout - t.clock.Now().Sub(t.lastActivity); remaining > 0 {
+		t.timer.Reset(remaining)
+		t.mutex.Unlock()
+		return
+	}
+	t.stopped = true
+	t.mutex.Unlock()
+	t.onTimeout()
+}
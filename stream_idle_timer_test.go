@@ -0,0 +1,76 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Idle Timer", func() {
+	const timeout = 50 * time.Millisecond
+
+	It("fires when the stream is idle", func() {
+		fired := make(chan struct{})
+		start := time.Now()
+		t := newStreamIdleTimer(timeout, utils.DefaultClock{})
+		t.start(func() { close(fired) })
+		Eventually(fired).Should(BeClosed())
+		Expect(time.Since(start)).To(BeNumerically(">=", timeout))
+	})
+
+	It("doesn't fire as long as there's activity", func() {
+		fired := make(chan struct{})
+		t := newStreamIdleTimer(timeout, utils.DefaultClock{})
+		t.start(func() { close(fired) })
+		for i := 0; i < 6; i++ {
+			time.Sleep(timeout / 3)
+			t.activity()
+		}
+		Expect(fired).ToNot(BeClosed())
+		Eventually(fired).Should(BeClosed())
+	})
+
+	It("doesn't fire after it was stopped", func() {
+		fired := make(chan struct{})
+		t := newStreamIdleTimer(timeout, utils.DefaultClock{})
+		t.start(func() { close(fired) })
+		t.stop()
+		Consistently(fired, 2*timeout).ShouldNot(BeClosed())
+	})
+
+	It("doesn't start a stopped timer", func() {
+		fired := make(chan struct{})
+		t := newStreamIdleTimer(timeout, utils.DefaultClock{})
+		t.stop()
+		t.start(func() { close(fired) })
+		Consistently(fired, 2*timeout).ShouldNot(BeClosed())
+	})
+
+	It("uses the clock", func() {
+		clock := utils.NewManualClock(time.Now())
+		var fired bool
+		t := newStreamIdleTimer(time.Minute, clock)
+		t.start(func() { fired = true })
+		clock.Advance(time.Minute / 2)
+		t.activity()
+		clock.Advance(time.Minute / 2)
+		// the timer is rescheduled, since there was activity in the meantime
+		Expect(fired).To(BeFalse())
+		clock.Advance(time.Minute/2 - time.Nanosecond)
+		Expect(fired).To(BeFalse())
+		clock.Advance(time.Nanosecond)
+		Expect(fired).To(BeTrue())
+	})
+
+	It("can be used when nil", func() {
+		var t *streamIdleTimer
+		Expect(func() {
+			t.start(func() {})
+			t.activity()
+			t.stop()
+		}).ToNot(Panic())
+	})
+})
@@ -5,10 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -45,9 +49,20 @@ var errTooManyOpenStreams = errors.New("too many open streams")
 
 type streamsMap struct {
 	perspective protocol.Perspective
+	clock       utils.Clock
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	onIncomingStream  func(protocol.StreamID, protocol.StreamType) error
+
+	writeCoalesceDelay time.Duration
+
+	idleTimeout          time.Duration
+	idleTimeoutErrorCode protocol.ApplicationErrorCode
+	// Outgoing streams are opened from the application's Go routines,
+	// so the idle timers need to be protected by a mutex.
+	idleTimersMutex sync.Mutex
+	idleTimers      map[protocol.StreamID]*streamIdleTimer
 
 	outgoingBidiStreams *outgoingBidiStreamsMap
 	outgoingUniStreams  *outgoingUniStreamsMap
@@ -60,49 +75,138 @@ var _ streamManager = &streamsMap{}
 func newStreamsMap(
 	sender streamSender,
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
+	onIncomingStream func(protocol.StreamID, protocol.StreamType) error,
+	writeCoalesceDelay time.Duration,
+	idleTimeout time.Duration,
+	idleTimeoutErrorCode protocol.ApplicationErrorCode,
+	initialIncomingBidiStreams uint64,
 	maxIncomingBidiStreams uint64,
+	initialIncomingUniStreams uint64,
 	maxIncomingUniStreams uint64,
+	onStreamsNeeded func(protocol.StreamType, uint64) uint64,
+	clock utils.Clock,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
 	m := &streamsMap{
-		perspective:       perspective,
-		newFlowController: newFlowController,
-		sender:            sender,
+		perspective:          perspective,
+		clock:                clock,
+		newFlowController:    newFlowController,
+		onIncomingStream:     onIncomingStream,
+		writeCoalesceDelay:   writeCoalesceDelay,
+		idleTimeout:          idleTimeout,
+		idleTimeoutErrorCode: idleTimeoutErrorCode,
+		idleTimers:           make(map[protocol.StreamID]*streamIdleTimer),
+		sender:               sender,
+	}
+	if onStreamsNeeded == nil {
+		// By default, double the number of streams every time the peer uses up the limit.
+		onStreamsNeeded = func(_ protocol.StreamType, current uint64) uint64 { return 2 * current }
+	}
+	newBidiStream := func(id protocol.StreamID) streamI {
+		idleTimer := m.newIdleTimer(id)
+		str := newStream(id, m.sender, m.newFlowController(id), m.writeCoalesceDelay, idleTimer, m.clock, version)
+		idleTimer.start(func() {
+			str.CancelRead(m.idleTimeoutErrorCode)
+			str.CancelWrite(m.idleTimeoutErrorCode)
+		})
+		return str
 	}
 	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
-			id := num.StreamID(protocol.StreamTypeBidi, perspective)
-			return newStream(id, m.sender, m.newFlowController(id), version)
+			return newBidiStream(num.StreamID(protocol.StreamTypeBidi, perspective))
 		},
 		sender.queueControlFrame,
 	)
 	m.incomingBidiStreams = newIncomingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
-			id := num.StreamID(protocol.StreamTypeBidi, perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id), version)
+			return newBidiStream(num.StreamID(protocol.StreamTypeBidi, perspective.Opposite()))
 		},
+		func(str streamI) bool {
+			errorCode, reject := m.rejectIncomingStream(str.StreamID())
+			if reject {
+				str.CancelRead(errorCode)
+				str.CancelWrite(errorCode)
+			}
+			return reject
+		},
+		initialIncomingBidiStreams,
 		maxIncomingBidiStreams,
+		func(current uint64) uint64 { return onStreamsNeeded(protocol.StreamTypeBidi, current) },
 		sender.queueControlFrame,
 	)
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id), version)
+			idleTimer := m.newIdleTimer(id)
+			str := newSendStream(id, m.sender, m.newFlowController(id), m.writeCoalesceDelay, idleTimer, m.clock, version)
+			idleTimer.start(func() { str.CancelWrite(m.idleTimeoutErrorCode) })
+			return str
 		},
 		sender.queueControlFrame,
 	)
 	m.incomingUniStreams = newIncomingUniStreamsMap(
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, perspective.Opposite())
-			return newReceiveStream(id, m.sender, m.newFlowController(id), version)
+			idleTimer := m.newIdleTimer(id)
+			str := newReceiveStream(id, m.sender, m.newFlowController(id), idleTimer, m.clock, version)
+			idleTimer.start(func() { str.CancelRead(m.idleTimeoutErrorCode) })
+			return str
+		},
+		func(str receiveStreamI) bool {
+			errorCode, reject := m.rejectIncomingStream(str.StreamID())
+			if reject {
+				str.CancelRead(errorCode)
+			}
+			return reject
 		},
+		initialIncomingUniStreams,
 		maxIncomingUniStreams,
+		func(current uint64) uint64 { return onStreamsNeeded(protocol.StreamTypeUni, current) },
 		sender.queueControlFrame,
 	)
 	return m
 }
 
+// newIdleTimer creates the idle timer for a new stream.
+// It returns nil if no stream idle timeout is configured.
+func (m *streamsMap) newIdleTimer(id protocol.StreamID) *streamIdleTimer {
+	if m.idleTimeout <= 0 {
+		return nil
+	}
+	t := newStreamIdleTimer(m.idleTimeout, m.clock)
+	m.idleTimersMutex.Lock()
+	m.idleTimers[id] = t
+	m.idleTimersMutex.Unlock()
+	return t
+}
+
+func (m *streamsMap) stopIdleTimer(id protocol.StreamID) {
+	m.idleTimersMutex.Lock()
+	defer m.idleTimersMutex.Unlock()
+	if t, ok := m.idleTimers[id]; ok {
+		t.stop()
+		delete(m.idleTimers, id)
+	}
+}
+
+// rejectIncomingStream calls the OnIncomingStream callback for a stream opened by the peer.
+// It returns the error code to cancel the stream with, if the stream is rejected.
+func (m *streamsMap) rejectIncomingStream(id protocol.StreamID) (protocol.ApplicationErrorCode, bool) {
+	if m.onIncomingStream == nil {
+		return 0, false
+	}
+	err := m.onIncomingStream(id, id.Type())
+	if err == nil {
+		return 0, false
+	}
+	var streamErr *StreamError
+	if errors.As(err, &streamErr) {
+		return streamErr.ErrorCode, true
+	}
+	return 0, true
+}
+
 func (m *streamsMap) OpenStream() (Stream, error) {
 	str, err := m.outgoingBidiStreams.OpenStream()
 	return str, convertStreamError(err, protocol.StreamTypeBidi, m.perspective)
@@ -134,6 +238,7 @@ func (m *streamsMap) AcceptUniStream(ctx context.Context) (ReceiveStream, error)
 }
 
 func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
+	m.stopIdleTimer(id)
 	num := id.StreamNum()
 	switch id.Type() {
 	case protocol.StreamTypeUni:
@@ -233,7 +338,48 @@ func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) error {
 	return nil
 }
 
+func (m *streamsMap) ActiveStreams() []StreamInfo {
+	var infos []StreamInfo
+	for _, str := range m.outgoingBidiStreams.Streams() {
+		infos = append(infos, newBidiStreamInfo(str))
+	}
+	for _, str := range m.incomingBidiStreams.Streams() {
+		infos = append(infos, newBidiStreamInfo(str))
+	}
+	for _, str := range m.outgoingUniStreams.Streams() {
+		infos = append(infos, StreamInfo{
+			ID:        str.StreamID(),
+			Direction: StreamDirectionSend,
+			BytesSent: uint64(str.getBytesSent()),
+		})
+	}
+	for _, str := range m.incomingUniStreams.Streams() {
+		infos = append(infos, StreamInfo{
+			ID:            str.StreamID(),
+			Direction:     StreamDirectionReceive,
+			BytesReceived: uint64(str.getBytesReceived()),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+func newBidiStreamInfo(str streamI) StreamInfo {
+	return StreamInfo{
+		ID:            str.StreamID(),
+		Direction:     StreamDirectionBidirectional,
+		BytesSent:     uint64(str.getBytesSent()),
+		BytesReceived: uint64(str.getBytesReceived()),
+	}
+}
+
 func (m *streamsMap) CloseWithError(err error) {
+	m.idleTimersMutex.Lock()
+	for id, t := range m.idleTimers {
+		t.stop()
+		delete(m.idleTimers, id)
+	}
+	m.idleTimersMutex.Unlock()
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
 	m.incomingBidiStreams.CloseWithError(err)
@@ -20,13 +20,23 @@ type incomingBidiStreamsMap struct {
 	// When a stream is deleted before it was accepted, we can't delete it immediately.
 	// We need to wait until the application accepts it, and delete it immediately then.
 	streamsToDelete map[protocol.StreamNum]struct{} // used as a set
+	// Streams that were rejected when they were opened are never returned by AcceptStream.
+	rejectedStreams map[protocol.StreamNum]struct{} // used as a set
 
 	nextStreamToAccept protocol.StreamNum // the next stream that will be returned by AcceptStream()
 	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer openend
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
-	maxNumStreams      uint64             // maximum number of streams
+	maxNumStreams      uint64             // the current limit on the number of concurrent streams
+	maxNumStreamsLimit uint64             // the value that maxNumStreams can be raised to
 
-	newStream        func(protocol.StreamNum) streamI
+	// onStreamsNeeded is called when the peer opened all streams allowed by the current limit.
+	// It returns the new limit.
+	onStreamsNeeded func(current uint64) uint64
+
+	newStream func(protocol.StreamNum) streamI
+	// rejectStream is called for every stream opened by the peer.
+	// If it returns true, the stream was rejected, and won't be returned by AcceptStream.
+	rejectStream     func(streamI) bool
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
 	// streamNumToID    func(protocol.StreamNum) protocol.StreamID // only used for generating errors
 
@@ -35,16 +45,23 @@ type incomingBidiStreamsMap struct {
 
 func newIncomingBidiStreamsMap(
 	newStream func(protocol.StreamNum) streamI,
+	rejectStream func(streamI) bool,
+	initialStreams uint64,
 	maxStreams uint64,
+	onStreamsNeeded func(current uint64) uint64,
 	queueControlFrame func(wire.Frame),
 ) *incomingBidiStreamsMap {
 	return &incomingBidiStreamsMap{
 		newStreamChan:      make(chan struct{}),
 		streams:            make(map[protocol.StreamNum]streamI),
 		streamsToDelete:    make(map[protocol.StreamNum]struct{}),
-		maxStream:          protocol.StreamNum(maxStreams),
-		maxNumStreams:      maxStreams,
+		rejectedStreams:    make(map[protocol.StreamNum]struct{}),
+		maxStream:          protocol.StreamNum(initialStreams),
+		maxNumStreams:      initialStreams,
+		maxNumStreamsLimit: maxStreams,
+		onStreamsNeeded:    onStreamsNeeded,
 		newStream:          newStream,
+		rejectStream:       rejectStream,
 		nextStreamToOpen:   1,
 		nextStreamToAccept: 1,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
@@ -62,6 +79,11 @@ func (m *incomingBidiStreamsMap) AcceptStream(ctx context.Context) (streamI, err
 			m.mutex.Unlock()
 			return nil, m.closeErr
 		}
+		if _, ok := m.rejectedStreams[num]; ok {
+			delete(m.rejectedStreams, num)
+			m.nextStreamToAccept++
+			continue
+		}
 		var ok bool
 		str, ok = m.streams[num]
 		if ok {
@@ -116,7 +138,11 @@ func (m *incomingBidiStreamsMap) GetOrOpenStream(num protocol.StreamNum) (stream
 	// * maxStream can only increase, so if the id was valid before, it definitely is valid now
 	// * highestStream is only modified by this function
 	for newNum := m.nextStreamToOpen; newNum <= num; newNum++ {
-		m.streams[newNum] = m.newStream(newNum)
+		str := m.newStream(newNum)
+		m.streams[newNum] = str
+		if m.rejectStream != nil && m.rejectStream(str) {
+			m.rejectedStreams[newNum] = struct{}{}
+		}
 		select {
 		case m.newStreamChan <- struct{}{}:
 		default:
@@ -124,10 +150,34 @@ func (m *incomingBidiStreamsMap) GetOrOpenStream(num protocol.StreamNum) (stream
 	}
 	m.nextStreamToOpen = num + 1
 	s := m.streams[num]
+	needStreams := num == m.maxStream && m.maxNumStreams < m.maxNumStreamsLimit
 	m.mutex.Unlock()
+	if needStreams {
+		m.raiseLimit()
+	}
 	return s, nil
 }
 
+// raiseLimit is called when the peer opened all streams allowed by the current limit.
+// The callback is called without holding the mutex.
+func (m *incomingBidiStreamsMap) raiseLimit() {
+	m.mutex.RLock()
+	current := m.maxNumStreams
+	m.mutex.RUnlock()
+	newLimit := m.onStreamsNeeded(current)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if newLimit > m.maxNumStreamsLimit {
+		newLimit = m.maxNumStreamsLimit
+	}
+	if newLimit <= m.maxNumStreams {
+		return
+	}
+	m.maxNumStreams = newLimit
+	m.queueMaxStreams()
+}
+
 func (m *incomingBidiStreamsMap) DeleteStream(num protocol.StreamNum) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -145,7 +195,9 @@ func (m *incomingBidiStreamsMap) deleteStream(num protocol.StreamNum) error {
 
 	// Don't delete this stream yet, if it was not yet accepted.
 	// Just save it to streamsToDelete map, to make sure it is deleted as soon as it gets accepted.
-	if num >= m.nextStreamToAccept {
+	// Rejected streams are never accepted, so they can be deleted right away.
+	_, rejected := m.rejectedStreams[num]
+	if num >= m.nextStreamToAccept && !rejected {
 		if _, ok := m.streamsToDelete[num]; ok {
 			return streamError{
 				message: "Tried to delete incoming stream %d multiple times",
@@ -158,6 +210,11 @@ func (m *incomingBidiStreamsMap) deleteStream(num protocol.StreamNum) error {
 
 	delete(m.streams, num)
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
+	m.queueMaxStreams()
+	return nil
+}
+
+func (m *incomingBidiStreamsMap) queueMaxStreams() {
 	if m.maxNumStreams > uint64(len(m.streams)) {
 		maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
 		// Never send a value larger than protocol.MaxStreamCount.
@@ -169,7 +226,22 @@ func (m *incomingBidiStreamsMap) deleteStream(num protocol.StreamNum) error {
 			})
 		}
 	}
-	return nil
+}
+
+// Streams returns a snapshot of the streams that are currently open.
+// Streams that were already deleted, but not yet accepted, are not included.
+func (m *incomingBidiStreamsMap) Streams() []streamI {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]streamI, 0, len(m.streams))
+	for num, str := range m.streams {
+		if _, ok := m.streamsToDelete[num]; ok {
+			continue
+		}
+		streams = append(streams, str)
+	}
+	return streams
 }
 
 func (m *incomingBidiStreamsMap) CloseWithError(err error) {
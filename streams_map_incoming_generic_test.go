@@ -31,6 +31,10 @@ var _ = Describe("Streams Map (incoming)", func() {
 		newItemCounter int
 		mockSender     *MockStreamSender
 		maxNumStreams  uint64
+		rejectStream   func(item) bool
+
+		initialNumStreams uint64
+		onStreamsNeeded   func(uint64) uint64
 	)
 
 	// check that the frame can be serialized and deserialized
@@ -42,17 +46,28 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(f).To(Equal(frame))
 	}
 
-	BeforeEach(func() { maxNumStreams = 5 })
+	BeforeEach(func() {
+		maxNumStreams = 5
+		rejectStream = nil
+		initialNumStreams = 0
+		onStreamsNeeded = nil
+	})
 
 	JustBeforeEach(func() {
 		newItemCounter = 0
 		mockSender = NewMockStreamSender(mockCtrl)
+		if initialNumStreams == 0 {
+			initialNumStreams = maxNumStreams
+		}
 		m = newIncomingItemsMap(
 			func(num protocol.StreamNum) item {
 				newItemCounter++
 				return &mockGenericStream{num: num}
 			},
+			rejectStream,
+			initialNumStreams,
 			maxNumStreams,
+			onStreamsNeeded,
 			mockSender.queueControlFrame,
 		)
 	})
@@ -203,6 +218,64 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(str).ToNot(BeNil())
 	})
 
+	It("returns a snapshot of the open streams", func() {
+		Expect(m.Streams()).To(BeEmpty())
+		_, err := m.GetOrOpenStream(3)
+		Expect(err).ToNot(HaveOccurred())
+		// delete a stream that hasn't been accepted yet
+		Expect(m.DeleteStream(2)).To(Succeed())
+		var nums []protocol.StreamNum
+		for _, str := range m.Streams() {
+			nums = append(nums, str.(*mockGenericStream).num)
+		}
+		Expect(nums).To(ConsistOf(protocol.StreamNum(1), protocol.StreamNum(3)))
+	})
+
+	Context("rejecting streams", func() {
+		BeforeEach(func() {
+			rejectStream = func(str item) bool { return str.(*mockGenericStream).num == 2 }
+		})
+
+		It("doesn't accept rejected streams", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+		})
+
+		It("still returns rejected streams from GetOrOpenStream", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(2)))
+		})
+
+		It("deletes rejected streams right away", func() {
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(maxNumStreams + 1)))
+			})
+			Expect(m.DeleteStream(2)).To(Succeed())
+			str, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			_, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+		})
+	})
+
 	It("errors when deleting a non-existing stream", func() {
 		err := m.DeleteStream(1337)
 		Expect(err).To(HaveOccurred())
@@ -230,6 +303,75 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
 
+	Context("raising the stream limit", func() {
+		var calledWith []uint64
+
+		BeforeEach(func() {
+			initialNumStreams = 2
+			maxNumStreams = 5
+			calledWith = nil
+			onStreamsNeeded = func(current uint64) uint64 {
+				calledWith = append(calledWith, current)
+				return 2 * current
+			}
+		})
+
+		It("only allows opening streams up to the initial limit", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(streamError).TestError()).To(MatchError("peer tried to open stream 3 (current limit: 2)"))
+			Expect(calledWith).To(BeEmpty())
+		})
+
+		It("raises the limit when the peer opens the last allowed stream", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calledWith).To(BeEmpty())
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(4)))
+				checkFrameSerialization(f)
+			})
+			_, err = m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calledWith).To(Equal([]uint64{2}))
+			_, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("doesn't raise the limit above the maximum", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(4)))
+			})
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) {
+				Expect(f.(*wire.MaxStreamsFrame).MaxStreamNum).To(Equal(protocol.StreamNum(5)))
+			})
+			_, err = m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			// the limit is now at the maximum, so the callback isn't called any more
+			_, err = m.GetOrOpenStream(5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calledWith).To(Equal([]uint64{2, 4}))
+			_, err = m.GetOrOpenStream(6)
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("if the callback doesn't increase the limit", func() {
+			BeforeEach(func() {
+				onStreamsNeeded = func(current uint64) uint64 { return current }
+			})
+
+			It("keeps the limit", func() {
+				// don't expect any MAX_STREAMS frame
+				_, err := m.GetOrOpenStream(2)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenStream(3)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
 	Context("using high stream limits", func() {
 		BeforeEach(func() { maxNumStreams = uint64(protocol.MaxStreamCount) - 2 })
 
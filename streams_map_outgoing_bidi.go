@@ -190,6 +190,18 @@ func (m *outgoingBidiStreamsMap) unblockOpenSync() {
 	}
 }
 
+// Streams returns a snapshot of the streams that are currently open.
+func (m *outgoingBidiStreamsMap) Streams() []streamI {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]streamI, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	return streams
+}
+
 func (m *outgoingBidiStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -188,6 +188,18 @@ func (m *outgoingItemsMap) unblockOpenSync() {
 	}
 }
 
+// Streams returns a snapshot of the streams that are currently open.
+func (m *outgoingItemsMap) Streams() []item {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]item, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	return streams
+}
+
 func (m *outgoingItemsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
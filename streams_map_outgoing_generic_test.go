@@ -71,6 +71,20 @@ var _ = Describe("Streams Map (outgoing)", func() {
 			Expect(str).To(BeNil())
 		})
 
+		It("returns a snapshot of the open streams", func() {
+			Expect(m.Streams()).To(BeEmpty())
+			for i := 0; i < 3; i++ {
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(m.DeleteStream(2)).To(Succeed())
+			var nums []protocol.StreamNum
+			for _, str := range m.Streams() {
+				nums = append(nums, str.(*mockGenericStream).num)
+			}
+			Expect(nums).To(ConsistOf(protocol.StreamNum(1), protocol.StreamNum(3)))
+		})
+
 		It("errors when deleting a non-existing stream", func() {
 			err := m.DeleteStream(1337)
 			Expect(err).To(HaveOccurred())
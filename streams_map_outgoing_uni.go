@@ -190,6 +190,18 @@ func (m *outgoingUniStreamsMap) unblockOpenSync() {
 	}
 }
 
+// Streams returns a snapshot of the streams that are currently open.
+func (m *outgoingUniStreamsMap) Streams() []sendStreamI {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	streams := make([]sendStreamI, 0, len(m.streams))
+	for _, str := range m.streams {
+		streams = append(streams, str)
+	}
+	return streams
+}
+
 func (m *outgoingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -88,7 +90,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, nil, 0, 0, 0, MaxBidiStreamNum, MaxBidiStreamNum, MaxUniStreamNum, MaxUniStreamNum, nil, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -137,6 +139,28 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			It("returns the active streams", func() {
+				allowUnlimitedStreams()
+				_, err := m.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+				Expect(err).ToNot(HaveOccurred())
+				infos := m.ActiveStreams()
+				Expect(infos).To(ConsistOf(
+					StreamInfo{ID: ids.firstOutgoingBidiStream, Direction: StreamDirectionBidirectional},
+					StreamInfo{ID: ids.firstIncomingBidiStream, Direction: StreamDirectionBidirectional},
+					StreamInfo{ID: ids.firstOutgoingUniStream, Direction: StreamDirectionSend},
+					StreamInfo{ID: ids.firstIncomingUniStream, Direction: StreamDirectionReceive},
+				))
+				for i := 1; i < len(infos); i++ {
+					Expect(infos[i].ID).To(BeNumerically(">", infos[i-1].ID))
+				}
+			})
+
 			Context("deleting", func() {
 				BeforeEach(func() {
 					mockSender.EXPECT().queueControlFrame(gomock.Any()).AnyTimes()
@@ -428,6 +452,162 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("rejecting incoming streams", func() {
+				var (
+					rejectedIDs   []protocol.StreamID
+					rejectedTypes []protocol.StreamType
+				)
+
+				BeforeEach(func() {
+					rejectedIDs = nil
+					rejectedTypes = nil
+					m = newStreamsMap(
+						mockSender,
+						newFlowController,
+						func(id protocol.StreamID, t protocol.StreamType) error {
+							rejectedIDs = append(rejectedIDs, id)
+							rejectedTypes = append(rejectedTypes, t)
+							return &StreamError{StreamID: id, ErrorCode: 1337}
+						},
+						0,
+						0,
+						0,
+						MaxBidiStreamNum,
+						MaxBidiStreamNum,
+						MaxUniStreamNum,
+						MaxUniStreamNum,
+						nil,
+						utils.DefaultClock{},
+						perspective,
+						protocol.VersionWhatever,
+					).(*streamsMap)
+					mockSender.EXPECT().onStreamCompleted(gomock.Any()).AnyTimes()
+				})
+
+				It("rejects bidirectional streams", func() {
+					var frames []wire.Frame
+					mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) { frames = append(frames, f) }).Times(2)
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rejectedIDs).To(Equal([]protocol.StreamID{ids.firstIncomingBidiStream}))
+					Expect(rejectedTypes).To(Equal([]protocol.StreamType{protocol.StreamTypeBidi}))
+					Expect(frames).To(ContainElement(&wire.StopSendingFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 1337}))
+					Expect(frames).To(ContainElement(&wire.ResetStreamFrame{StreamID: ids.firstIncomingBidiStream, ErrorCode: 1337}))
+				})
+
+				It("rejects unidirectional streams", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: ids.firstIncomingUniStream, ErrorCode: 1337})
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rejectedIDs).To(Equal([]protocol.StreamID{ids.firstIncomingUniStream}))
+					Expect(rejectedTypes).To(Equal([]protocol.StreamType{protocol.StreamTypeUni}))
+				})
+
+				It("doesn't call the callback for outgoing streams", func() {
+					allowUnlimitedStreams()
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rejectedIDs).To(BeEmpty())
+				})
+			})
+
+			Context("raising the limit for incoming streams", func() {
+				It("doubles the limit by default", func() {
+					m = newStreamsMap(mockSender, newFlowController, nil, 0, 0, 0, 2, 10, 1, 10, nil, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: 4})
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream + 4)
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: 2})
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("uses the callback", func() {
+					var types []protocol.StreamType
+					onStreamsNeeded := func(t protocol.StreamType, current uint64) uint64 {
+						types = append(types, t)
+						return current + 3
+					}
+					m = newStreamsMap(mockSender, newFlowController, nil, 0, 0, 0, 1, 10, 1, 10, onStreamsNeeded, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: 4})
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: 4})
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(types).To(Equal([]protocol.StreamType{protocol.StreamTypeUni, protocol.StreamTypeBidi}))
+				})
+			})
+
+			Context("stream idle timeout", func() {
+				const idleTimeout = 50 * time.Millisecond
+
+				var frames chan wire.Frame
+
+				BeforeEach(func() {
+					frames = make(chan wire.Frame, 10)
+					m = newStreamsMap(mockSender, newFlowController, nil, 0, idleTimeout, 42, MaxBidiStreamNum, MaxBidiStreamNum, MaxUniStreamNum, MaxUniStreamNum, nil, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
+					allowUnlimitedStreams()
+					mockSender.EXPECT().queueControlFrame(gomock.Any()).Do(func(f wire.Frame) { frames <- f }).AnyTimes()
+					mockSender.EXPECT().onStreamCompleted(gomock.Any()).AnyTimes()
+				})
+
+				It("cancels idle bidirectional streams", func() {
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					var received []wire.Frame
+					for i := 0; i < 2; i++ {
+						var f wire.Frame
+						Eventually(frames).Should(Receive(&f))
+						received = append(received, f)
+					}
+					Expect(received).To(ContainElement(&wire.StopSendingFrame{StreamID: str.StreamID(), ErrorCode: 42}))
+					Expect(received).To(ContainElement(&wire.ResetStreamFrame{StreamID: str.StreamID(), ErrorCode: 42}))
+				})
+
+				It("cancels idle outgoing unidirectional streams", func() {
+					str, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Eventually(frames).Should(Receive(Equal(&wire.ResetStreamFrame{StreamID: str.StreamID(), ErrorCode: 42})))
+					Consistently(frames, idleTimeout).ShouldNot(Receive())
+				})
+
+				It("cancels idle incoming unidirectional streams", func() {
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					Eventually(frames).Should(Receive(Equal(&wire.StopSendingFrame{StreamID: ids.firstIncomingUniStream, ErrorCode: 42})))
+					Consistently(frames, idleTimeout).ShouldNot(Receive())
+				})
+
+				It("stops the timer when a stream is deleted", func() {
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(m.DeleteStream(str.StreamID())).To(Succeed())
+					Expect(m.idleTimers).To(BeEmpty())
+					Consistently(frames, 2*idleTimeout).ShouldNot(Receive())
+				})
+
+				It("stops all timers when closed", func() {
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					m.CloseWithError(errors.New("test error"))
+					Expect(m.idleTimers).To(BeEmpty())
+					Consistently(frames, 2*idleTimeout).ShouldNot(Receive())
+				})
+
+				It("doesn't use timers if no idle timeout is configured", func() {
+					m = newStreamsMap(mockSender, newFlowController, nil, 0, 0, 42, MaxBidiStreamNum, MaxBidiStreamNum, MaxUniStreamNum, MaxUniStreamNum, nil, utils.DefaultClock{}, perspective, protocol.VersionWhatever).(*streamsMap)
+					allowUnlimitedStreams()
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(m.idleTimers).To(BeEmpty())
+				})
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
@@ -99,6 +99,16 @@ func (s *lruTokenStore) Put(key string, token *ClientToken) {
 	s.m[key] = elem
 }
 
+func (s *lruTokenStore) Invalidate(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if el, ok := s.m[key]; ok {
+		s.q.Remove(el)
+		delete(s.m, key)
+	}
+}
+
 func (s *lruTokenStore) Pop(key string) *ClientToken {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
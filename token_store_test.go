@@ -104,5 +104,25 @@ var _ = Describe("Token Cache", func() {
 			Expect(s.Pop("host3")).To(Equal(mockToken(3)))
 			Expect(s.Pop("host4")).To(Equal(mockToken(4)))
 		})
+
+		It("invalidates all tokens for a host", func() {
+			s.Put("host1", mockToken(1))
+			s.Put("host1", mockToken(2))
+			s.Put("host2", mockToken(3))
+			s.Invalidate("host1")
+			Expect(s.Pop("host1")).To(BeNil())
+			Expect(s.Pop("host2")).To(Equal(mockToken(3)))
+			// host1 was deleted, making space for new hosts
+			s.Put("host3", mockToken(4))
+			s.Put("host4", mockToken(5))
+			Expect(s.Pop("host3")).To(Equal(mockToken(4)))
+			Expect(s.Pop("host4")).To(Equal(mockToken(5)))
+		})
+
+		It("ignores invalidations for unknown hosts", func() {
+			s.Put("host1", mockToken(1))
+			s.Invalidate("host2")
+			Expect(s.Pop("host1")).To(Equal(mockToken(1)))
+		})
 	})
 })
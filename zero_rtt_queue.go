@@ -5,10 +5,11 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type zeroRTTQueueEntry struct {
-	timer   *time.Timer
+	timer   utils.ClockTimer
 	packets []*receivedPacket
 }
 
@@ -16,12 +17,14 @@ type zeroRTTQueue struct {
 	mutex         sync.Mutex
 	queue         map[string]*zeroRTTQueueEntry
 	queueDuration time.Duration // so we can set it in tests
+	clock         utils.Clock
 }
 
-func newZeroRTTQueue() *zeroRTTQueue {
+func newZeroRTTQueue(clock utils.Clock) *zeroRTTQueue {
 	return &zeroRTTQueue{
 		queue:         make(map[string]*zeroRTTQueueEntry),
 		queueDuration: protocol.Max0RTTQueueingDuration,
+		clock:         clock,
 	}
 }
 
@@ -34,7 +37,7 @@ func (h *zeroRTTQueue) Enqueue(connID protocol.ConnectionID, p *receivedPacket)
 		if len(h.queue) >= protocol.Max0RTTQueues {
 			return
 		}
-		h.queue[cid] = &zeroRTTQueueEntry{timer: time.AfterFunc(h.queueDuration, func() {
+		h.queue[cid] = &zeroRTTQueueEntry{timer: h.clock.AfterFunc(h.queueDuration, func() {
 			h.deleteQueue(connID)
 		})}
 	}
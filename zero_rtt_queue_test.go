@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,7 +16,7 @@ var _ = Describe("0-RTT queue", func() {
 	queueDuration := scaleDuration(20 * time.Millisecond)
 
 	BeforeEach(func() {
-		q = newZeroRTTQueue()
+		q = newZeroRTTQueue(utils.DefaultClock{})
 		q.queueDuration = queueDuration
 	})
 